@@ -12,7 +12,8 @@ package main
 
 import (
     "context"
-    "log"
+    "errors"
+    "log/slog"
     "net/http"
     "os"
     "os/signal"
@@ -25,8 +26,13 @@ import (
     "scm/internal/config"
     "scm/internal/db"
     "scm/internal/db/migrations"
+    "scm/internal/logging"
+    "scm/internal/metrics"
+    "scm/internal/models"
     "scm/internal/repository"
     "scm/internal/routes"
+    "scm/internal/services"
+    "scm/internal/tracing"
 )
 
 func getEnv(key, defaultValue string) string {
@@ -38,9 +44,42 @@ func getEnv(key, defaultValue string) string {
     return v
 }
 
-func startScheduledCampaignCompleter(ctx context.Context, campaignRepo interface {
-	CompleteActiveEndedBefore(ctx context.Context, now time.Time, activeStatus string, completedStatus string, timeZone string) (int64, error)
-}) {
+// campaignEvent is a campaign a scheduler changed and the owner of its advertiser, who
+// is shown the event on the stream.
+type campaignEvent struct {
+	campaign *models.Campaign
+	owner    string
+}
+
+// queueCampaignEvents queues eventType for each of the campaigns a scheduler changed,
+// in the scheduler's transaction so the events are only sent if the change commits.
+// It returns the events, to be published to the event stream after the commit.
+func queueCampaignEvents(ctx context.Context, repos repository.Repositories, ids []string, eventType string) ([]campaignEvent, error) {
+	events := make([]campaignEvent, 0, len(ids))
+	for _, id := range ids {
+		campaign, err := repos.Campaigns.GetByID(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		owner, err := services.QueueAdvertiserEvent(ctx, repos, campaign.AdvertiserID, eventType, campaign)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, campaignEvent{campaign: campaign, owner: owner})
+	}
+	return events, nil
+}
+
+// publishCampaignEvents pushes the events a scheduler queued to the event stream.
+func publishCampaignEvents(ctx context.Context, stream *services.EventStream, events []campaignEvent, eventType string) {
+	for _, e := range events {
+		if err := stream.PublishTo(ctx, e.owner, eventType, e.campaign); err != nil {
+			slog.ErrorContext(ctx, "failed to publish event", "event_type", eventType, "campaign_id", e.campaign.ID, "error", err)
+		}
+	}
+}
+
+func startScheduledCampaignCompleter(ctx context.Context, uow repository.UnitOfWork, stream *services.EventStream) {
 	tzName := getEnv("CAMPAIGN_SCHEDULER_TZ", "UTC")
 	activeStatus := getEnv("CAMPAIGN_ACTIVE_STATUS", "active")
 	completedStatus := getEnv("CAMPAIGN_COMPLETED_STATUS", "completed")
@@ -49,7 +88,7 @@ func startScheduledCampaignCompleter(ctx context.Context, campaignRepo interface
 
 	loc, err := time.LoadLocation(tzName)
 	if err != nil {
-		log.Printf("Invalid CAMPAIGN_SCHEDULER_TZ=%q, falling back to UTC: %v", tzName, err)
+		slog.Warn("invalid CAMPAIGN_SCHEDULER_TZ, falling back to UTC", "tz", tzName, "error", err)
 		tzName = "UTC"
 		loc = time.UTC
 	}
@@ -68,15 +107,26 @@ func startScheduledCampaignCompleter(ctx context.Context, campaignRepo interface
 			case <-t.C:
 			}
 
+			start := time.Now()
 			runCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
-			rows, err := campaignRepo.CompleteActiveEndedBefore(runCtx, time.Now(), activeStatus, completedStatus, tzName)
+			var completed []campaignEvent
+			err := uow.Do(runCtx, nil, func(ctx context.Context, repos repository.Repositories) error {
+				ids, err := repos.Campaigns.CompleteActiveEndedBefore(ctx, time.Now(), activeStatus, completedStatus, tzName)
+				if err != nil {
+					return err
+				}
+				completed, err = queueCampaignEvents(ctx, repos, ids, models.WebhookEventCampaignCompleted)
+				return err
+			})
 			cancel()
+			metrics.ObserveJob("campaign_completer", start, len(completed), err)
 			if err != nil {
-				log.Printf("Failed to complete ended campaigns (active=%s completed=%s tz=%s): %v", activeStatus, completedStatus, tzName, err)
+				slog.Error("failed to complete ended campaigns", "active_status", activeStatus, "completed_status", completedStatus, "tz", tzName, "error", err)
 				continue
 			}
-			if rows > 0 {
-				log.Printf("Completed %d campaign(s) (active=%s completed=%s tz=%s)", rows, activeStatus, completedStatus, tzName)
+			publishCampaignEvents(ctx, stream, completed, models.WebhookEventCampaignCompleted)
+			if len(completed) > 0 {
+				slog.Info("completed campaigns", "count", len(completed), "active_status", activeStatus, "completed_status", completedStatus, "tz", tzName)
 			}
 		}
 	}()
@@ -110,9 +160,7 @@ func nextRunAt(now time.Time, loc *time.Location, hour int, minute int) time.Tim
     return run
 }
 
-func startScheduledCampaignActivator(ctx context.Context, campaignRepo interface {
-    ActivateScheduledStartingOn(ctx context.Context, startDate time.Time, scheduledStatus string, timeZone string) (int64, error)
-}) {
+func startScheduledCampaignActivator(ctx context.Context, uow repository.UnitOfWork, stream *services.EventStream) {
     tzName := getEnv("CAMPAIGN_SCHEDULER_TZ", "UTC")
     scheduledStatus := getEnv("CAMPAIGN_SCHEDULED_STATUS", "scheduled")
     hhmm := getEnv("CAMPAIGN_SCHEDULER_TIME", "00:01")
@@ -120,7 +168,7 @@ func startScheduledCampaignActivator(ctx context.Context, campaignRepo interface
 
     loc, err := time.LoadLocation(tzName)
     if err != nil {
-        log.Printf("Invalid CAMPAIGN_SCHEDULER_TZ=%q, falling back to UTC: %v", tzName, err)
+        slog.Warn("invalid CAMPAIGN_SCHEDULER_TZ, falling back to UTC", "tz", tzName, "error", err)
         tzName = "UTC"
         loc = time.UTC
     }
@@ -139,56 +187,295 @@ func startScheduledCampaignActivator(ctx context.Context, campaignRepo interface
             case <-t.C:
             }
 
+            start := time.Now()
             runCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
-            rows, err := campaignRepo.ActivateScheduledStartingOn(runCtx, time.Now(), scheduledStatus, tzName)
+            var activated []campaignEvent
+            err := uow.Do(runCtx, nil, func(ctx context.Context, repos repository.Repositories) error {
+                ids, err := repos.Campaigns.ActivateScheduledStartingOn(ctx, time.Now(), scheduledStatus, tzName)
+                if err != nil {
+                    return err
+                }
+                activated, err = queueCampaignEvents(ctx, repos, ids, models.WebhookEventCampaignActivated)
+                return err
+            })
             cancel()
+            metrics.ObserveJob("campaign_activator", start, len(activated), err)
             if err != nil {
-                log.Printf("Failed to activate scheduled campaigns (status=%s tz=%s): %v", scheduledStatus, tzName, err)
+                slog.Error("failed to activate scheduled campaigns", "status", scheduledStatus, "tz", tzName, "error", err)
                 continue
             }
-            if rows > 0 {
-                log.Printf("Activated %d scheduled campaign(s) for today (status=%s tz=%s)", rows, scheduledStatus, tzName)
+            publishCampaignEvents(ctx, stream, activated, models.WebhookEventCampaignActivated)
+            if len(activated) > 0 {
+                slog.Info("activated scheduled campaigns", "count", len(activated), "status", scheduledStatus, "tz", tzName)
             }
         }
     }()
 }
 
+func startAlertEvaluator(ctx context.Context, evaluator interface {
+    RetryNotifications(ctx context.Context) error
+    EvaluateVenueRules(ctx context.Context) error
+}) {
+    interval := 60 * time.Second
+    if v, err := strconv.Atoi(getEnv("ALERT_EVALUATION_INTERVAL_SECONDS", "60")); err == nil && v > 0 {
+        interval = time.Duration(v) * time.Second
+    }
+
+    go func() {
+        ticker := time.NewTicker(interval)
+        defer ticker.Stop()
+        for {
+            select {
+            case <-ctx.Done():
+                return
+            case <-ticker.C:
+            }
+
+            start := time.Now()
+            runCtx, cancel := context.WithTimeout(ctx, interval)
+            // Retry notifications that failed earlier before opening new alerts.
+            retryErr := evaluator.RetryNotifications(runCtx)
+            if retryErr != nil {
+                slog.Error("failed to retry alert notifications", "error", retryErr)
+            }
+            err := evaluator.EvaluateVenueRules(runCtx)
+            cancel()
+            metrics.ObserveJob("alert_evaluator", start, 0, errors.Join(retryErr, err))
+            if err != nil {
+                slog.Error("failed to evaluate venue alert rules", "error", err)
+            }
+        }
+    }()
+}
+
+// startWebhookDispatcher delivers due webhook deliveries every
+// WEBHOOK_DISPATCH_INTERVAL_SECONDS.
+func startWebhookDispatcher(ctx context.Context, dispatcher interface {
+    DeliverDue(ctx context.Context) (int, error)
+}) {
+    interval := 10 * time.Second
+    if v, err := strconv.Atoi(getEnv("WEBHOOK_DISPATCH_INTERVAL_SECONDS", "10")); err == nil && v > 0 {
+        interval = time.Duration(v) * time.Second
+    }
+
+    go func() {
+        ticker := time.NewTicker(interval)
+        defer ticker.Stop()
+        for {
+            select {
+            case <-ctx.Done():
+                return
+            case <-ticker.C:
+            }
+
+            start := time.Now()
+            delivered, err := dispatcher.DeliverDue(ctx)
+            metrics.ObserveJob("webhook_dispatcher", start, delivered, err)
+            if err != nil {
+                slog.Error("failed to deliver webhooks", "error", err)
+            }
+            if delivered > 0 {
+                slog.Info("delivered webhooks", "count", delivered)
+            }
+        }
+    }()
+}
+
+// startDeviceHealthWatcher reports devices going stale or offline every
+// DEVICE_HEALTH_WATCH_INTERVAL_SECONDS.
+func startDeviceHealthWatcher(ctx context.Context, watcher interface {
+    Check(ctx context.Context, now time.Time) error
+}) {
+    interval := 30 * time.Second
+    if v, err := strconv.Atoi(getEnv("DEVICE_HEALTH_WATCH_INTERVAL_SECONDS", "30")); err == nil && v > 0 {
+        interval = time.Duration(v) * time.Second
+    }
+
+    go func() {
+        ticker := time.NewTicker(interval)
+        defer ticker.Stop()
+        for {
+            select {
+            case <-ctx.Done():
+                return
+            case <-ticker.C:
+            }
+
+            start := time.Now()
+            runCtx, cancel := context.WithTimeout(ctx, interval)
+            err := watcher.Check(runCtx, start)
+            cancel()
+            metrics.ObserveJob("device_health_watcher", start, 0, err)
+            if err != nil {
+                slog.Error("failed to check device health", "error", err)
+            }
+        }
+    }()
+}
+
+type softDeletePurger struct {
+    name string
+    repo interface {
+        PurgeDeletedBefore(ctx context.Context, cutoff time.Time) (int64, []string, error)
+    }
+}
+
+// startSoftDeletePurger permanently removes soft-deleted rows once they are older than
+// SOFT_DELETE_RETENTION_DAYS, then deletes the stored files of the creatives removed with
+// them from storage. Purgers run in order, so pass children before parents.
+func startSoftDeletePurger(ctx context.Context, storage services.ObjectStorage, purgers []softDeletePurger) {
+    retentionDays, err := strconv.Atoi(getEnv("SOFT_DELETE_RETENTION_DAYS", "30"))
+    if err != nil || retentionDays <= 0 {
+        slog.Info("soft-delete purge disabled", "retention_days", getEnv("SOFT_DELETE_RETENTION_DAYS", "30"))
+        return
+    }
+    retention := time.Duration(retentionDays) * 24 * time.Hour
+
+    interval := time.Hour
+    if v, err := strconv.Atoi(getEnv("SOFT_DELETE_PURGE_INTERVAL_MINUTES", "60")); err == nil && v > 0 {
+        interval = time.Duration(v) * time.Minute
+    }
+
+    go func() {
+        ticker := time.NewTicker(interval)
+        defer ticker.Stop()
+        for {
+            cutoff := time.Now().UTC().Add(-retention)
+            for _, p := range purgers {
+                start := time.Now()
+                rows, keys, err := p.repo.PurgeDeletedBefore(ctx, cutoff)
+                metrics.ObserveJob("purge_deleted_"+p.name, start, int(rows), err)
+                if err != nil {
+                    slog.Error("failed to purge deleted records", "records", p.name, "error", err)
+                    continue
+                }
+                if rows > 0 {
+                    slog.Info("purged deleted records", "records", p.name, "count", rows, "retention_days", retentionDays)
+                }
+                if err := removePurgedObjects(ctx, storage, keys); err != nil {
+                    // The rows are gone, so log the keys for manual cleanup.
+                    slog.Error("failed to delete stored objects of purged records", "records", p.name, "keys", keys, "error", err)
+                }
+            }
+
+            select {
+            case <-ctx.Done():
+                return
+            case <-ticker.C:
+            }
+        }
+    }()
+}
+
+// removePurgedObjects deletes the stored files of purged creatives.
+func removePurgedObjects(ctx context.Context, storage services.ObjectStorage, keys []string) error {
+    if len(keys) == 0 {
+        return nil
+    }
+    if storage == nil {
+        return errors.New("object storage not configured")
+    }
+    return storage.DeleteObjects(ctx, keys)
+}
+
+// fatal logs err and exits.
+func fatal(msg string, err error) {
+    slog.Error(msg, "error", err)
+    os.Exit(1)
+}
+
 func main() {
     // Load configuration
     cfg := config.Load()
 
+    // Structured JSON logs (LOG_LEVEL=debug|info|warn|error)
+    logLevel, err := logging.ParseLevel(cfg.LogLevel)
+    if err != nil {
+        fatal("invalid LOG_LEVEL", err)
+    }
+    slog.SetDefault(logging.New(os.Stdout, logLevel))
+
+    // Tracing (OTEL_TRACES_EXPORTER=otlp|stdout|none)
+    shutdownTracing, err := tracing.Setup(context.Background(), cfg.TracesExporter, cfg.ServiceName)
+    if err != nil {
+        fatal("failed to set up tracing", err)
+    }
+
     // Create database if it doesn't exist
     if err := db.CreateDatabaseIfNotExists(cfg.DatabaseURL); err != nil {
-        log.Fatalf("Failed to ensure database exists: %v", err)
+        fatal("failed to ensure database exists", err)
     }
 
     // Initialize database
     database, err := db.New(cfg.DatabaseURL)
     if err != nil {
-        log.Fatalf("Failed to connect to database: %v", err)
+        fatal("failed to connect to database", err)
     }
     defer database.Close()
+    metrics.RegisterDB(database.DB, "scm")
 
-    // Run database migrations
-    if err := migrations.RunMigrations(database.DB); err != nil {
-        log.Fatalf("Failed to run migrations: %v", err)
+    // Run database migrations (disable with MIGRATE_ON_START=false and run cmd/migrate instead)
+    if migrateOnStart, err := strconv.ParseBool(getEnv("MIGRATE_ON_START", "true")); err != nil || migrateOnStart {
+        if err := migrations.RunMigrations(database.DB); err != nil {
+            fatal("failed to run migrations", err)
+        }
+    } else {
+        slog.Info("skipping migrations (MIGRATE_ON_START=false)")
     }
 
     // Background jobs
     jobsCtx, cancelJobs := context.WithCancel(context.Background())
     defer cancelJobs()
     campaignRepo := repository.NewCampaignRepository(database.DB)
-    startScheduledCampaignActivator(jobsCtx, campaignRepo)
-	startScheduledCampaignCompleter(jobsCtx, campaignRepo)
+    uow := repository.NewUnitOfWork(database.DB)
+    stream := services.NewEventStream(cfg.EventStreamBufferSize)
+    startScheduledCampaignActivator(jobsCtx, uow, stream)
+	startScheduledCampaignCompleter(jobsCtx, uow, stream)
+    alertMailer := &services.SMTPSender{
+        Host:   cfg.SMTPHost,
+        Port:   cfg.SMTPPort,
+        User:   cfg.SMTPUser,
+        Pass:   cfg.SMTPPassword,
+        From:   cfg.SMTPFrom,
+        UseTLS: cfg.SMTPUseTLS,
+    }
+    alertService := services.NewAlertService(
+        repository.NewAlertRepository(database.DB),
+        repository.NewDeviceHealthRepository(database.DB),
+        alertMailer,
+        cfg.DeviceHealthThresholds(),
+    )
+    startAlertEvaluator(jobsCtx, alertService)
+    startDeviceHealthWatcher(jobsCtx, services.NewDeviceHealthWatcher(
+        repository.NewDeviceHealthRepository(database.DB),
+        cfg.DeviceHealthThresholds(),
+        stream,
+        time.Now(),
+    ))
+    startWebhookDispatcher(jobsCtx, services.NewWebhookService(
+        repository.NewWebhookRepository(database.DB),
+        cfg.WebhookMaxAttempts,
+        time.Duration(cfg.WebhookBackoffSeconds)*time.Second,
+    ))
 
 	// Initialize S3 configuration
     s3Config, err := config.NewS3Config()
     if err != nil {
-        log.Fatalf("Failed to create S3 client: %v", err)
+        fatal("failed to create S3 client", err)
     }
 
+    var purgeStorage services.ObjectStorage
+    if s3Config.Client != nil {
+        purgeStorage = &services.S3ObjectStorage{Client: s3Config.Client, Bucket: s3Config.Bucket}
+    }
+    startSoftDeletePurger(jobsCtx, purgeStorage, []softDeletePurger{
+        {name: "creatives", repo: repository.NewCreativeRepository(database.DB)},
+        {name: "campaigns", repo: campaignRepo},
+        {name: "advertisers", repo: repository.NewAdvertiserRepository(database.DB)},
+    })
+
     // Create router and setup routes
-    router := routes.SetupRoutes(database.DB, cfg, s3Config)
+    router := routes.SetupRoutes(database.DB, cfg, s3Config, stream)
 
     // Create server
     server := &http.Server{
@@ -198,9 +485,9 @@ func main() {
 
     // Graceful shutdown
     go func() {
-        log.Printf("Server starting on port %s", cfg.Port)
+        slog.Info("server starting", "port", cfg.Port)
         if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-            log.Fatalf("Failed to start server: %v", err)
+            fatal("failed to start server", err)
         }
     }()
 
@@ -208,7 +495,7 @@ func main() {
     quit := make(chan os.Signal, 1)
     signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
     <-quit
-    log.Println("Shutting down server...")
+    slog.Info("shutting down server")
 
     cancelJobs()
 
@@ -217,8 +504,11 @@ func main() {
     defer cancel()
 
     if err := server.Shutdown(ctx); err != nil {
-        log.Fatalf("Server forced to shutdown: %v", err)
+        fatal("server forced to shutdown", err)
+    }
+    if err := shutdownTracing(ctx); err != nil {
+        slog.Error("failed to flush traces", "error", err)
     }
 
-    log.Println("Server exiting")
+    slog.Info("server exiting")
 }
\ No newline at end of file
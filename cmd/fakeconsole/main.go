@@ -0,0 +1,68 @@
+// cmd/fakeconsole/main.go
+//
+// fakeconsole serves a fake CityPost console for local development, so console syncs
+// run without access to consoleapi.citypost.us:
+//
+//	fakeconsole [-addr :8090] [-fixtures file.json] [-latency 200ms] [-page-size 2]
+//
+// Point the API at it with CITYPOST_CONSOLE_BASE_URL=http://localhost:8090 and the
+// same username and password. Without -fixtures it serves generated demo projects and
+// devices. A fixtures file holds "projects", "devices" (keyed by project name) and
+// optionally "failures" to inject, e.g. {"path": "/device/", "status": 503, "times": 2}.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"scm/internal/fakeconsole"
+)
+
+type fixturesFile struct {
+	fakeconsole.Fixtures
+	Failures []fakeconsole.Failure `json:"failures"`
+}
+
+func main() {
+	addr := flag.String("addr", ":8090", "address to listen on")
+	fixturesPath := flag.String("fixtures", "", "JSON file with projects, devices and failures (default: generated demo data)")
+	username := flag.String("username", "demo", "username /login/ accepts")
+	password := flag.String("password", "demo", "password /login/ accepts")
+	authScheme := flag.String("auth-scheme", "Token", "Authorization scheme /device/ expects")
+	latency := flag.Duration("latency", 0, "delay added to every response")
+	pageSize := flag.Int("page-size", 0, "items per page of project and device lists (0 disables pagination)")
+	projects := flag.Int("projects", 4, "demo projects to generate without -fixtures")
+	devices := flag.Int("devices", 5, "demo devices per project to generate without -fixtures")
+	flag.Parse()
+
+	cfg := fakeconsole.Config{
+		Username:   *username,
+		Password:   *password,
+		AuthScheme: *authScheme,
+		Latency:    *latency,
+		PageSize:   *pageSize,
+	}
+	if *fixturesPath != "" {
+		raw, err := os.ReadFile(*fixturesPath)
+		if err != nil {
+			log.Fatalf("Failed to read fixtures: %v", err)
+		}
+		var f fixturesFile
+		if err := json.Unmarshal(raw, &f); err != nil {
+			log.Fatalf("Failed to parse fixtures %s: %v", *fixturesPath, err)
+		}
+		cfg.Fixtures = f.Fixtures
+		cfg.Failures = f.Failures
+	} else {
+		cfg.Fixtures = fakeconsole.Demo(*projects, *devices, time.Now())
+	}
+
+	log.Printf("Fake CityPost console listening on %s (%d projects)", *addr, len(cfg.Fixtures.Projects))
+	if err := http.ListenAndServe(*addr, fakeconsole.New(cfg)); err != nil {
+		log.Fatalf("Fake console stopped: %v", err)
+	}
+}
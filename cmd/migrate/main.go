@@ -0,0 +1,143 @@
+// cmd/migrate/main.go
+//
+// migrate applies and rolls back the database migrations embedded in the binary:
+//
+//	migrate up [--dry-run]       apply every pending migration
+//	migrate down N [--dry-run]   roll back the N most recent migrations
+//	migrate redo [--dry-run]     roll back the most recent migration and apply it again
+//	migrate status               list migrations and whether they are applied
+//
+// Flags may come before or after the command. It connects with the same
+// DATABASE_URL / PSQL_* variables as the API and logs to stderr at LOG_LEVEL, so the
+// SQL of a dry run is all that goes to stdout.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"scm/internal/config"
+	"scm/internal/db"
+	"scm/internal/db/migrations"
+	"scm/internal/logging"
+)
+
+func usage() {
+	fmt.Fprintf(flag.CommandLine.Output(), "usage: migrate up | down N | redo | status [--dry-run]\n")
+	flag.PrintDefaults()
+}
+
+// fatal logs err and exits.
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
+}
+
+// parseArgs parses the flags in args wherever they appear, before, between or after
+// the command and its arguments, and returns the rest.
+func parseArgs(fs *flag.FlagSet, args []string) ([]string, error) {
+	var rest []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return rest, nil
+		}
+		rest = append(rest, args[0])
+		args = args[1:]
+	}
+}
+
+func main() {
+	dryRun := flag.Bool("dry-run", false, "print the SQL that would run instead of running it")
+	flag.Usage = usage
+	args, _ := parseArgs(flag.CommandLine, os.Args[1:]) // exits on errors
+	if len(args) == 0 {
+		usage()
+		os.Exit(2)
+	}
+
+	cfg := config.Load()
+	logLevel, err := logging.ParseLevel(cfg.LogLevel)
+	if err != nil {
+		fatal("invalid LOG_LEVEL", err)
+	}
+	slog.SetDefault(logging.New(os.Stderr, logLevel))
+
+	if err := db.CreateDatabaseIfNotExists(cfg.DatabaseURL); err != nil {
+		fatal("failed to ensure database exists", err)
+	}
+	database, err := db.New(cfg.DatabaseURL)
+	if err != nil {
+		fatal("failed to connect to database", err)
+	}
+	defer database.Close()
+
+	m, err := migrations.NewEmbeddedMigrator(database.DB)
+	if err != nil {
+		fatal("failed to load migrations", err)
+	}
+	m.DryRun = *dryRun
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	if err := run(ctx, m, args); err != nil {
+		database.Close()
+		fatal("migrate "+args[0]+" failed", err)
+	}
+}
+
+func run(ctx context.Context, m *migrations.Migrator, args []string) error {
+	switch args[0] {
+	case "up":
+		n, err := m.Up(ctx)
+		if err != nil {
+			return err
+		}
+		slog.Info("migrations applied", "count", n, "dry_run", m.DryRun)
+	case "down":
+		if len(args) != 2 {
+			return fmt.Errorf("usage: migrate down N")
+		}
+		steps, err := strconv.Atoi(args[1])
+		if err != nil || steps < 1 {
+			return fmt.Errorf("invalid number of migrations %q", args[1])
+		}
+		n, err := m.Down(ctx, steps)
+		if err != nil {
+			return err
+		}
+		slog.Info("migrations rolled back", "count", n, "dry_run", m.DryRun)
+	case "redo":
+		return m.Redo(ctx)
+	case "status":
+		statuses, err := m.Status(ctx)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tSTATE\tAPPLIED AT")
+		for _, s := range statuses {
+			appliedAt := "-"
+			if s.AppliedAt != nil {
+				appliedAt = s.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(w, "%04d\t%s\t%s\t%s\n", s.Version, s.Name, s.State, appliedAt)
+		}
+		return w.Flush()
+	default:
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+	return nil
+}
@@ -0,0 +1,272 @@
+// Package audit records who changed what. Repository decorators record entity-level
+// events with before/after diffs; Middleware records a generic event for any mutating
+// request that no decorator covered.
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	chimw "github.com/go-chi/chi/v5/middleware"
+	authmw "scm/internal/middleware"
+	"scm/internal/models"
+)
+
+// Recorder persists audit events; repository.AuditRepository implements it.
+type Recorder interface {
+	Record(ctx context.Context, event *models.AuditEvent) error
+}
+
+// Actor identifies who performed a request.
+type Actor struct {
+	UserID    string
+	Email     string
+	RequestID string
+	IP        string
+}
+
+type ctxKey struct{}
+
+type requestState struct {
+	ip       string
+	method   string
+	path     string
+	recorded bool
+}
+
+// ActorFromContext collects the actor from the JWT claims, chi's request ID and the
+// client IP captured by Middleware.
+func ActorFromContext(ctx context.Context) Actor {
+	actor := Actor{RequestID: chimw.GetReqID(ctx)}
+	actor.UserID, _ = ctx.Value(authmw.CtxUserID).(string)
+	actor.Email, _ = ctx.Value(authmw.CtxEmail).(string)
+	if st, ok := ctx.Value(ctxKey{}).(*requestState); ok {
+		actor.IP = st.ip
+	}
+	return actor
+}
+
+// Record writes an event for entityType/entityID with a diff of before and after
+// (either may be nil). Failures are logged rather than failing the operation.
+func Record(ctx context.Context, rec Recorder, action string, entityType string, entityID string, before any, after any) {
+	if rec == nil {
+		return
+	}
+
+	diff, err := Diff(before, after)
+	if err != nil {
+		slog.ErrorContext(ctx, "audit: diff failed", "action", action, "entity_type", entityType, "entity_id", entityID, "error", err)
+	}
+
+	event := newEvent(ctx, action, entityType, entityID)
+	event.Diff = diff
+	if err := rec.Record(ctx, event); err != nil {
+		slog.ErrorContext(ctx, "audit: record failed", "action", action, "entity_type", entityType, "entity_id", entityID, "error", err)
+		return
+	}
+	if st, ok := ctx.Value(ctxKey{}).(*requestState); ok {
+		st.recorded = true
+	}
+}
+
+func newEvent(ctx context.Context, action string, entityType string, entityID string) *models.AuditEvent {
+	actor := ActorFromContext(ctx)
+	event := &models.AuditEvent{
+		Action:     action,
+		EntityType: entityType,
+		EntityID:   entityID,
+		ActorID:    actor.UserID,
+		ActorEmail: actor.Email,
+		RequestID:  actor.RequestID,
+		IP:         actor.IP,
+	}
+	if st, ok := ctx.Value(ctxKey{}).(*requestState); ok {
+		event.Method = st.method
+		event.Path = st.path
+	}
+	return event
+}
+
+// Diff returns {"before", "after", "changes"} where changes maps each differing
+// top-level field to {"from", "to"}. updated_at is not reported as a change.
+func Diff(before any, after any) (json.RawMessage, error) {
+	beforeMap, err := toMap(before)
+	if err != nil {
+		return nil, err
+	}
+	afterMap, err := toMap(after)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := map[string]any{}
+	if beforeMap != nil && afterMap != nil {
+		for key, to := range afterMap {
+			if key == "updated_at" {
+				continue
+			}
+			if from, ok := beforeMap[key]; !ok || !reflect.DeepEqual(from, to) {
+				changes[key] = map[string]any{"from": beforeMap[key], "to": to}
+			}
+		}
+		for key, from := range beforeMap {
+			if _, ok := afterMap[key]; !ok {
+				changes[key] = map[string]any{"from": from, "to": nil}
+			}
+		}
+	}
+
+	return json.Marshal(map[string]any{
+		"before":  beforeMap,
+		"after":   afterMap,
+		"changes": changes,
+	})
+}
+
+// FieldChanges lists the fields that differ between before and after, sorted by name.
+// Nested objects are compared member by member, so a change is reported at its leaf
+// (e.g. "region.code"); arrays are compared whole. Top-level fields named in ignore are
+// skipped.
+func FieldChanges(before any, after any, ignore ...string) ([]models.FieldChange, error) {
+	beforeMap, err := toMap(before)
+	if err != nil {
+		return nil, err
+	}
+	afterMap, err := toMap(after)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, key := range ignore {
+		delete(beforeMap, key)
+		delete(afterMap, key)
+	}
+	changes := []models.FieldChange{}
+	diffFields("", beforeMap, afterMap, &changes)
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Field < changes[j].Field })
+	return changes, nil
+}
+
+func diffFields(prefix string, before map[string]any, after map[string]any, out *[]models.FieldChange) {
+	for key, to := range after {
+		from, ok := before[key]
+		fromObj, fromIsObj := from.(map[string]any)
+		toObj, toIsObj := to.(map[string]any)
+		switch {
+		case fromIsObj && toIsObj:
+			diffFields(prefix+key+".", fromObj, toObj, out)
+		case !ok || !reflect.DeepEqual(from, to):
+			*out = append(*out, models.FieldChange{Field: prefix + key, From: from, To: to})
+		}
+	}
+	for key, from := range before {
+		if _, ok := after[key]; !ok {
+			*out = append(*out, models.FieldChange{Field: prefix + key, From: from, To: nil})
+		}
+	}
+}
+
+func toMap(v any) (map[string]any, error) {
+	if v == nil {
+		return nil, nil
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Ptr && rv.IsNil() {
+		return nil, nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]any
+	if err := json.Unmarshal(b, &m); err != nil {
+		// Not an object: keep it under "value" so it still shows up in the diff.
+		var raw any
+		if err := json.Unmarshal(b, &raw); err != nil {
+			return nil, err
+		}
+		return map[string]any{"value": raw}, nil
+	}
+	return m, nil
+}
+
+// Middleware captures the client IP and request line for audit events, and records a
+// generic event for successful POST/PUT/PATCH/DELETE requests that no repository hook
+// recorded. Mount it after JWTAuth so the actor is known.
+func Middleware(rec Recorder) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			st := &requestState{ip: clientIP(r), method: r.Method, path: r.URL.Path}
+			ctx := context.WithValue(r.Context(), ctxKey{}, st)
+			r = r.WithContext(ctx)
+
+			ww := chimw.NewWrapResponseWriter(w, r.ProtoMajor)
+			next.ServeHTTP(ww, r)
+
+			if !isMutating(r.Method) || st.recorded {
+				return
+			}
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+			if status >= 400 {
+				return
+			}
+
+			entityType, entityID := routeEntity(r)
+			event := newEvent(ctx, strings.ToLower(r.Method), entityType, entityID)
+			if err := rec.Record(ctx, event); err != nil {
+				slog.ErrorContext(ctx, "audit: record failed", "method", r.Method, "path", r.URL.Path, "error", err)
+			}
+		})
+	}
+}
+
+func isMutating(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+// routeEntity derives the entity type from the static segments of the matched route
+// (e.g. "alerts/rules" for /api/v1/alerts/rules/{id}) and the entity ID from its first
+// URL parameter.
+func routeEntity(r *http.Request) (string, string) {
+	rctx := chi.RouteContext(r.Context())
+	if rctx == nil {
+		return strings.Trim(r.URL.Path, "/"), ""
+	}
+
+	var parts []string
+	for _, seg := range strings.Split(rctx.RoutePattern(), "/") {
+		if seg == "" || seg == "api" || seg == "v1" || seg == "*" || strings.HasPrefix(seg, "{") {
+			continue
+		}
+		parts = append(parts, seg)
+	}
+
+	entityID := ""
+	if len(rctx.URLParams.Values) > 0 {
+		entityID = rctx.URLParams.Values[0]
+	}
+	return strings.Join(parts, "/"), entityID
+}
+
+func clientIP(r *http.Request) string {
+	// chi's RealIP middleware has already applied X-Forwarded-For / X-Real-IP.
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
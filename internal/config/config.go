@@ -6,6 +6,9 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"time"
+
+	"scm/internal/models"
 )
 
 type Config struct {
@@ -20,6 +23,8 @@ type Config struct {
 	CityPostConsoleUsername  string
 	CityPostConsolePassword  string
 	CityPostConsoleAuthScheme string
+	// SyncConcurrency bounds the per-project device fetches a console sync runs at once.
+	SyncConcurrency int
 
 	JWTSecret           string
 	JWTExpiresInSeconds int64
@@ -30,6 +35,35 @@ type Config struct {
 	SMTPPassword string
 	SMTPFrom     string
 	SMTPUseTLS   bool
+
+	DeviceHeartbeatToken            string
+	DeviceHealthStaleAfterSeconds   int64
+	DeviceHealthOfflineAfterSeconds int64
+
+	// AdminEmails may perform admin-only operations such as cascade deletes.
+	AdminEmails []string
+
+	// WebhookMaxAttempts is how many times a webhook delivery is tried before it is
+	// dead; WebhookBackoffSeconds is the wait after the first failure, doubling after
+	// each further one.
+	WebhookMaxAttempts    int
+	WebhookBackoffSeconds int64
+
+	// EventStreamBufferSize is how many recent events a reconnecting dashboard can
+	// resume from.
+	EventStreamBufferSize int
+
+	// MetricsToken, when set, must be sent as a bearer token to scrape /metrics.
+	MetricsToken string
+
+	// LogLevel is the minimum level logged: debug, info, warn or error.
+	LogLevel string
+
+	// TracesExporter is where OpenTelemetry spans go: "otlp" (to
+	// OTEL_EXPORTER_OTLP_ENDPOINT), "stdout" or "none".
+	TracesExporter string
+	// ServiceName identifies the API in traces.
+	ServiceName string
 }
 
 func Load() *Config {
@@ -68,6 +102,7 @@ func Load() *Config {
 		CityPostConsoleUsername:   getEnv("CITYPOST_CONSOLE_USERNAME", "girish@smartcitymedia.us"),
 		CityPostConsolePassword:   getEnv("CITYPOST_CONSOLE_PASSWORD", "liv3wire"),
 		CityPostConsoleAuthScheme: getEnv("CITYPOST_CONSOLE_AUTH_SCHEME", "Token"),
+		SyncConcurrency:           int(getEnvInt64("SYNC_CONCURRENCY", 8)),
 
 		SMTPHost:     getEnv("SMTP_HOST", "smtp.gmail.com"),
 		SMTPPort:     getEnv("SMTP_PORT", "587"),
@@ -75,6 +110,24 @@ func Load() *Config {
 		SMTPPassword: getEnv("SMTP_PASSWORD", "iwud bnba gmpi dbct"),
 		SMTPFrom:     getEnv("SMTP_FROM", "citypost@smartcitymedia.us"),
 		SMTPUseTLS:   getEnvBool("SMTP_USE_TLS", true),
+
+		DeviceHeartbeatToken:            getEnv("DEVICE_HEARTBEAT_TOKEN", ""),
+		DeviceHealthStaleAfterSeconds:   getEnvInt64("DEVICE_HEALTH_STALE_AFTER_SECONDS", 300),
+		DeviceHealthOfflineAfterSeconds: getEnvInt64("DEVICE_HEALTH_OFFLINE_AFTER_SECONDS", 1800),
+
+		AdminEmails: getEnvList("ADMIN_EMAILS"),
+
+		WebhookMaxAttempts:    int(getEnvInt64("WEBHOOK_MAX_ATTEMPTS", 8)),
+		WebhookBackoffSeconds: getEnvInt64("WEBHOOK_BACKOFF_SECONDS", 30),
+
+		EventStreamBufferSize: int(getEnvInt64("EVENT_STREAM_BUFFER_SIZE", 1000)),
+
+		MetricsToken: getEnv("METRICS_TOKEN", ""),
+
+		LogLevel: getEnv("LOG_LEVEL", "info"),
+
+		TracesExporter: getEnv("OTEL_TRACES_EXPORTER", "none"),
+		ServiceName:    getEnv("OTEL_SERVICE_NAME", "scm-ads-api"),
 	}
 }
 
@@ -97,6 +150,17 @@ func getEnvInt64(key string, defaultValue int64) int64 {
 	return i
 }
 
+// getEnvList splits a comma-separated variable, dropping empty entries.
+func getEnvList(key string) []string {
+	var out []string
+	for _, part := range strings.Split(getEnv(key, ""), ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
+
 func getEnvBool(key string, defaultValue bool) bool {
 	value := strings.TrimSpace(getEnv(key, ""))
 	if value == "" {
@@ -107,4 +171,11 @@ func getEnvBool(key string, defaultValue bool) bool {
 		return defaultValue
 	}
 	return b
-}
\ No newline at end of file
+}
+// DeviceHealthThresholds returns the heartbeat ages at which a device becomes stale and offline.
+func (c *Config) DeviceHealthThresholds() models.HealthThresholds {
+	return models.HealthThresholds{
+		StaleAfter:   time.Duration(c.DeviceHealthStaleAfterSeconds) * time.Second,
+		OfflineAfter: time.Duration(c.DeviceHealthOfflineAfterSeconds) * time.Second,
+	}
+}
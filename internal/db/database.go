@@ -3,8 +3,12 @@ package db
 import (
 	"database/sql"
 	"fmt"
+	"log/slog"
+
+	"github.com/XSAM/otelsql"
 	_ "github.com/lib/pq"
-	"log"
+
+	"scm/internal/tracing"
 )
 
 type Database struct {
@@ -12,7 +16,8 @@ type Database struct {
 }
 
 func New(connectionString string) (*Database, error) {
-	db, err := sql.Open("postgres", connectionString)
+	// Statements are traced at the driver, since repositories take the *sql.DB itself.
+	db, err := otelsql.Open("postgres", connectionString, tracing.SQLOptions()...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
@@ -21,7 +26,7 @@ func New(connectionString string) (*Database, error) {
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
-	log.Println("Successfully connected to database")
+	slog.Info("connected to database")
 	return &Database{db}, nil
 }
 
@@ -5,7 +5,7 @@ import (
 	"database/sql"
 	"fmt"
 	_ "github.com/lib/pq"
-	"log"
+	"log/slog"
 	"net/url"
 	"strings"
 )
@@ -39,13 +39,13 @@ func CreateDatabaseIfNotExists(connString string) error {
 
 	// Create database if it doesn't exist
 	if !exists {
-		log.Printf("Creating database: %s", dbName)
+		slog.Info("creating database", "database", dbName)
 		// Disable prepared statements for database creation
 		_, err = db.Exec("CREATE DATABASE " + dbName)
 		if err != nil {
 			return fmt.Errorf("failed to create database: %w", err)
 		}
-		log.Printf("Database %s created successfully", dbName)
+		slog.Info("created database", "database", dbName)
 	}
 
 	return nil
@@ -2,119 +2,434 @@
 package migrations
 
 import (
+	"context"
+	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
+	"errors"
 	"fmt"
-	"io/ioutil"
-	"log"
+	"io"
+	"io/fs"
+	"log/slog"
 	"os"
-	"path/filepath"
+	"path"
 	"sort"
 	"strconv"
 	"strings"
+	"time"
+
+	sqlfiles "scm/migrations"
 )
 
-const migrationsDir = "migrations"
+// lockKey identifies the Postgres advisory lock held while migrating, so pods starting
+// at the same time (or a migrate run during a deploy) apply migrations one at a time.
+const lockKey int64 = 0x73636d5f6d6967 // "scm_mig"
+
+// Migration states reported by Status.
+const (
+	StateApplied  = "applied"
+	StatePending  = "pending"
+	StateModified = "modified"
+	StateMissing  = "missing"
+)
 
 type Migration struct {
 	Version int
 	Name    string
 	Up      string
 	Down    string
+	// Checksum is the SHA-256 of Up; an applied migration whose file no longer matches
+	// it has been edited after the fact.
+	Checksum string
 }
 
-func RunMigrations(db *sql.DB) error {
-	// Create migrations table if it doesn't exist
-	if err := createMigrationsTable(db); err != nil {
-		return fmt.Errorf("failed to create migrations table: %w", err)
+func (m Migration) String() string {
+	return fmt.Sprintf("%04d_%s", m.Version, m.Name)
+}
+
+// MigrationStatus is one line of Status: a migration file, or an applied version whose
+// file is missing.
+type MigrationStatus struct {
+	Version   int
+	Name      string
+	State     string
+	AppliedAt *time.Time
+}
+
+type appliedMigration struct {
+	name string
+	// checksum is empty for migrations applied before checksums were recorded.
+	checksum  string
+	appliedAt time.Time
+}
+
+// Migrator applies and rolls back the migrations in a file system.
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+	// DryRun writes the SQL Up, Down and Redo would run to Out instead of running it.
+	DryRun bool
+	Out    io.Writer
+}
+
+// NewMigrator loads the migrations in fsys, which holds NNNN_name.up.sql and the
+// matching NNNN_name.down.sql files.
+func NewMigrator(db *sql.DB, fsys fs.FS) (*Migrator, error) {
+	migrations, err := loadMigrations(fsys)
+	if err != nil {
+		return nil, err
 	}
+	return &Migrator{db: db, migrations: migrations, Out: os.Stdout}, nil
+}
+
+// NewEmbeddedMigrator loads the migrations embedded in the binary.
+func NewEmbeddedMigrator(db *sql.DB) (*Migrator, error) {
+	return NewMigrator(db, sqlfiles.Files)
+}
 
-	// Get applied migrations
-	applied, err := getAppliedMigrations(db)
+// RunMigrations applies the pending embedded migrations.
+func RunMigrations(db *sql.DB) error {
+	m, err := NewEmbeddedMigrator(db)
 	if err != nil {
-		return fmt.Errorf("failed to get applied migrations: %w", err)
+		return fmt.Errorf("failed to load migrations: %w", err)
+	}
+	_, err = m.Up(context.Background())
+	return err
+}
+
+// Up applies every pending migration in version order and returns how many it applied.
+// It refuses to run when an applied migration has been edited.
+func (m *Migrator) Up(ctx context.Context) (int, error) {
+	var count int
+	err := m.withLock(ctx, func(conn *sql.Conn, applied map[int]appliedMigration) error {
+		for _, migration := range m.migrations {
+			if _, ok := applied[migration.Version]; ok {
+				continue
+			}
+			if err := m.apply(ctx, conn, migration); err != nil {
+				return fmt.Errorf("failed to apply migration %s: %w", migration, err)
+			}
+			count++
+		}
+		return nil
+	})
+	return count, err
+}
+
+// Down rolls back the n most recently applied migrations, newest first, and returns
+// how many it rolled back.
+func (m *Migrator) Down(ctx context.Context, n int) (int, error) {
+	if n < 1 {
+		return 0, errors.New("number of migrations to roll back must be at least 1")
 	}
 
-	// Get migration files
-	files, err := getMigrationFiles()
+	var count int
+	err := m.withLock(ctx, func(conn *sql.Conn, applied map[int]appliedMigration) error {
+		for _, migration := range m.latestApplied(applied, n) {
+			if err := m.revert(ctx, conn, migration); err != nil {
+				return err
+			}
+			count++
+		}
+		return nil
+	})
+	return count, err
+}
+
+// Redo rolls back the most recently applied migration and applies it again.
+func (m *Migrator) Redo(ctx context.Context) error {
+	return m.withLock(ctx, func(conn *sql.Conn, applied map[int]appliedMigration) error {
+		latest := m.latestApplied(applied, 1)
+		if len(latest) == 0 {
+			return errors.New("no applied migration to redo")
+		}
+		if latest[0].Up == "" {
+			return fmt.Errorf("migration %04d is applied but its file is missing", latest[0].Version)
+		}
+		if err := m.revert(ctx, conn, latest[0]); err != nil {
+			return err
+		}
+		if err := m.apply(ctx, conn, latest[0]); err != nil {
+			return fmt.Errorf("failed to apply migration %s: %w", latest[0], err)
+		}
+		return nil
+	})
+}
+
+// Status lists every migration file and every applied version without a file, in
+// version order.
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	applied, err := readApplied(ctx, m.db)
 	if err != nil {
-		return fmt.Errorf("failed to get migration files: %w", err)
+		return nil, fmt.Errorf("failed to get applied migrations: %w", err)
 	}
 
-	// Apply pending migrations
-	for _, file := range files {
-		if _, exists := applied[file.Version]; !exists {
-			if err := applyMigration(db, file); err != nil {
-				return fmt.Errorf("failed to apply migration %s: %w", file.Name, err)
+	var out []MigrationStatus
+	for _, migration := range m.migrations {
+		status := MigrationStatus{Version: migration.Version, Name: migration.Name, State: StatePending}
+		if a, ok := applied[migration.Version]; ok {
+			status.State = StateApplied
+			if a.checksum != "" && a.checksum != migration.Checksum {
+				status.State = StateModified
+			}
+			status.AppliedAt = &a.appliedAt
+			delete(applied, migration.Version)
+		}
+		out = append(out, status)
+	}
+	for version, a := range applied {
+		out = append(out, MigrationStatus{Version: version, Name: a.name, State: StateMissing, AppliedAt: &a.appliedAt})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Version < out[j].Version })
+	return out, nil
+}
+
+// latestApplied returns the n most recently applied migrations, newest first. Applied
+// versions without a file come back with an empty Up and Down.
+func (m *Migrator) latestApplied(applied map[int]appliedMigration, n int) []Migration {
+	versions := make([]int, 0, len(applied))
+	for version := range applied {
+		versions = append(versions, version)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(versions)))
+	if len(versions) > n {
+		versions = versions[:n]
+	}
+
+	out := make([]Migration, 0, len(versions))
+	for _, version := range versions {
+		migration := Migration{Version: version, Name: applied[version].name}
+		for _, candidate := range m.migrations {
+			if candidate.Version == version {
+				migration = candidate
 			}
-			log.Printf("Applied migration: %s", file.Name)
 		}
+		out = append(out, migration)
+	}
+	return out
+}
+
+// inSession runs fn on a single connection holding the migration advisory lock. The
+// lock is per session, so everything that needs it has to run on conn.
+func (m *Migrator) inSession(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get connection: %w", err)
 	}
+	defer conn.Close()
 
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", lockKey); err != nil {
+		return fmt.Errorf("failed to acquire migration lock: %w", err)
+	}
+	defer func() {
+		if _, err := conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", lockKey); err != nil {
+			slog.Error("failed to release migration lock", "error", err)
+		}
+	}()
+
+	return fn(conn)
+}
+
+// withLock prepares schema_migrations, verifies the checksums of the applied
+// migrations and runs fn with them, all under the migration lock.
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn, applied map[int]appliedMigration) error) error {
+	return m.inSession(ctx, func(conn *sql.Conn) error {
+		if !m.DryRun {
+			if err := createMigrationsTable(ctx, conn); err != nil {
+				return fmt.Errorf("failed to create migrations table: %w", err)
+			}
+		}
+		applied, err := readApplied(ctx, conn)
+		if err != nil {
+			return fmt.Errorf("failed to get applied migrations: %w", err)
+		}
+		if err := m.verifyChecksums(ctx, conn, applied); err != nil {
+			return err
+		}
+		return fn(conn, applied)
+	})
+}
+
+// verifyChecksums fails when an applied migration's file has changed since it was
+// applied. Migrations applied before checksums were recorded get theirs stored now.
+func (m *Migrator) verifyChecksums(ctx context.Context, conn *sql.Conn, applied map[int]appliedMigration) error {
+	var modified []string
+	for _, migration := range m.migrations {
+		a, ok := applied[migration.Version]
+		if !ok {
+			continue
+		}
+		if a.checksum == "" {
+			if m.DryRun {
+				continue
+			}
+			if _, err := conn.ExecContext(ctx,
+				"UPDATE schema_migrations SET checksum = $2 WHERE version = $1", migration.Version, migration.Checksum,
+			); err != nil {
+				return fmt.Errorf("failed to record checksum of migration %s: %w", migration, err)
+			}
+			continue
+		}
+		if a.checksum != migration.Checksum {
+			modified = append(modified, migration.String())
+		}
+	}
+	if len(modified) > 0 {
+		return fmt.Errorf("applied migrations have been edited since they ran: %s", strings.Join(modified, ", "))
+	}
 	return nil
 }
 
-func createMigrationsTable(db *sql.DB) error {
-	_, err := db.Exec(`
+func createMigrationsTable(ctx context.Context, conn *sql.Conn) error {
+	_, err := conn.ExecContext(ctx, `
 		CREATE TABLE IF NOT EXISTS schema_migrations (
 			version INTEGER PRIMARY KEY,
 			name TEXT NOT NULL,
 			applied_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
-		)
+		);
+		ALTER TABLE schema_migrations ADD COLUMN IF NOT EXISTS checksum TEXT;
 	`)
 	return err
 }
 
-func getAppliedMigrations(db *sql.DB) (map[int]bool, error) {
-	rows, err := db.Query("SELECT version FROM schema_migrations ORDER BY version")
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// readApplied reads schema_migrations. It also works against a database whose
+// schema_migrations is missing or predates the checksum column, as in a dry run.
+func readApplied(ctx context.Context, conn queryer) (map[int]appliedMigration, error) {
+	applied := make(map[int]appliedMigration)
+
+	var exists bool
+	if err := conn.QueryRowContext(ctx, "SELECT to_regclass('schema_migrations') IS NOT NULL").Scan(&exists); err != nil {
+		return nil, err
+	}
+	if !exists {
+		return applied, nil
+	}
+
+	rows, err := conn.QueryContext(ctx, `
+		SELECT version, name, COALESCE(to_jsonb(m)->>'checksum', ''), COALESCE(applied_at, NOW())
+		FROM schema_migrations m
+		ORDER BY version
+	`)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	applied := make(map[int]bool)
 	for rows.Next() {
 		var version int
-		if err := rows.Scan(&version); err != nil {
+		var a appliedMigration
+		if err := rows.Scan(&version, &a.name, &a.checksum, &a.appliedAt); err != nil {
 			return nil, err
 		}
-		applied[version] = true
+		applied[version] = a
 	}
 	return applied, rows.Err()
 }
 
-func getMigrationFiles() ([]Migration, error) {
-	files, err := filepath.Glob(filepath.Join(migrationsDir, "*.up.sql"))
+func (m *Migrator) apply(ctx context.Context, conn *sql.Conn, migration Migration) error {
+	if m.DryRun {
+		fmt.Fprintf(m.Out, "-- apply %s\n%s\n", migration, strings.TrimSpace(migration.Up))
+		return nil
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Execute migration
+	if _, err := tx.ExecContext(ctx, migration.Up); err != nil {
+		return fmt.Errorf("failed to execute migration: %w", err)
+	}
+
+	// Record migration
+	if _, err := tx.ExecContext(ctx,
+		"INSERT INTO schema_migrations (version, name, checksum) VALUES ($1, $2, $3) ON CONFLICT (version) DO NOTHING",
+		migration.Version,
+		migration.Name,
+		migration.Checksum,
+	); err != nil {
+		return fmt.Errorf("failed to record migration: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	slog.Info("applied migration", "migration", migration)
+	return nil
+}
+
+func (m *Migrator) revert(ctx context.Context, conn *sql.Conn, migration Migration) error {
+	if strings.TrimSpace(migration.Down) == "" {
+		return fmt.Errorf("migration %s has no down migration", migration)
+	}
+	if m.DryRun {
+		fmt.Fprintf(m.Out, "-- revert %s\n%s\n", migration, strings.TrimSpace(migration.Down))
+		return nil
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, migration.Down); err != nil {
+		return fmt.Errorf("failed to revert migration %s: %w", migration, err)
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM schema_migrations WHERE version = $1", migration.Version); err != nil {
+		return fmt.Errorf("failed to unrecord migration %s: %w", migration, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	slog.Info("reverted migration", "migration", migration)
+	return nil
+}
+
+func loadMigrations(fsys fs.FS) ([]Migration, error) {
+	files, err := fs.Glob(fsys, "*.up.sql")
 	if err != nil {
 		return nil, err
 	}
 
 	var migrations []Migration
+	seen := make(map[int]string)
 	for _, file := range files {
-		version, name, err := parseMigrationFilename(filepath.Base(file))
+		version, name, err := parseMigrationFilename(file)
 		if err != nil {
 			return nil, err
 		}
+		if other, ok := seen[version]; ok {
+			return nil, fmt.Errorf("migrations %s and %s share version %d", other, file, version)
+		}
+		seen[version] = file
 
-		content, err := ioutil.ReadFile(file)
+		content, err := fs.ReadFile(fsys, file)
 		if err != nil {
 			return nil, err
 		}
 
 		// Find the down migration
-		downContent := []byte{}
-		downFile := filepath.Join(migrationsDir, fmt.Sprintf("%04d_%s.down.sql", version, name))
-		if _, err := os.Stat(downFile); err == nil {
-			downContent, err = ioutil.ReadFile(downFile)
-			if err != nil {
-				return nil, err
-			}
+		downContent, err := fs.ReadFile(fsys, fmt.Sprintf("%04d_%s.down.sql", version, name))
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
 		}
 
+		sum := sha256.Sum256(content)
 		migrations = append(migrations, Migration{
-			Version: version,
-			Name:    name,
-			Up:      string(content),
-			Down:    string(downContent),
+			Version:  version,
+			Name:     name,
+			Up:       string(content),
+			Down:     string(downContent),
+			Checksum: hex.EncodeToString(sum[:]),
 		})
 	}
 
@@ -127,7 +442,7 @@ func getMigrationFiles() ([]Migration, error) {
 
 func parseMigrationFilename(filename string) (int, string, error) {
 	// Expected format: 0001_name.up.sql
-	base := filepath.Base(filename)
+	base := path.Base(filename)
 	parts := strings.SplitN(base, "_", 2)
 	if len(parts) != 2 {
 		return 0, "", fmt.Errorf("invalid migration filename format: %s", filename)
@@ -143,27 +458,3 @@ func parseMigrationFilename(filename string) (int, string, error) {
 
 	return version, name, nil
 }
-
-func applyMigration(db *sql.DB, migration Migration) error {
-	tx, err := db.Begin()
-	if err != nil {
-		return err
-	}
-	defer tx.Rollback()
-
-	// Execute migration
-	if _, err := tx.Exec(migration.Up); err != nil {
-		return fmt.Errorf("failed to execute migration: %w", err)
-	}
-
-	// Record migration
-	if _, err := tx.Exec(
-		"INSERT INTO schema_migrations (version, name) VALUES ($1, $2) ON CONFLICT (version) DO NOTHING",
-		migration.Version,
-		migration.Name,
-	); err != nil {
-		return fmt.Errorf("failed to record migration: %w", err)
-	}
-
-	return tx.Commit()
-}
\ No newline at end of file
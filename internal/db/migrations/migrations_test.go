@@ -0,0 +1,180 @@
+package migrations
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func sqlFiles(files map[string]string) fstest.MapFS {
+	fsys := fstest.MapFS{}
+	for name, content := range files {
+		fsys[name] = &fstest.MapFile{Data: []byte(content)}
+	}
+	return fsys
+}
+
+func TestParseMigrationFilename(t *testing.T) {
+	version, name, err := parseMigrationFilename("0007_add_devices.up.sql")
+	if err != nil || version != 7 || name != "add_devices" {
+		t.Fatalf("got %d, %q, %v", version, name, err)
+	}
+
+	for _, bad := range []string{"add_devices.up.sql", "v7_add_devices.up.sql", "_add_devices.up.sql"} {
+		if _, _, err := parseMigrationFilename(bad); err == nil {
+			t.Errorf("%s: expected an error", bad)
+		}
+	}
+}
+
+func TestLoadMigrations(t *testing.T) {
+	migrations, err := loadMigrations(sqlFiles(map[string]string{
+		"0002_devices.up.sql":   "CREATE TABLE devices ();",
+		"0001_users.up.sql":     "CREATE TABLE users ();",
+		"0001_users.down.sql":   "DROP TABLE users;",
+		"0002_devices.down.sql": "DROP TABLE devices;",
+		"README.md":             "not a migration",
+	}))
+	if err != nil {
+		t.Fatalf("loadMigrations: %v", err)
+	}
+	if len(migrations) != 2 || migrations[0].String() != "0001_users" || migrations[1].Down != "DROP TABLE devices;" {
+		t.Fatalf("unexpected migrations %+v", migrations)
+	}
+	if migrations[0].Checksum == "" || migrations[0].Checksum == migrations[1].Checksum {
+		t.Fatalf("unexpected checksums %q and %q", migrations[0].Checksum, migrations[1].Checksum)
+	}
+}
+
+func TestLoadMigrationsRejectsDuplicateVersions(t *testing.T) {
+	_, err := loadMigrations(sqlFiles(map[string]string{
+		"0001_users.up.sql":   "CREATE TABLE users ();",
+		"0001_people.up.sql":  "CREATE TABLE people ();",
+		"0002_devices.up.sql": "CREATE TABLE devices ();",
+	}))
+	if err == nil || !strings.Contains(err.Error(), "share version 1") {
+		t.Fatalf("expected a duplicate version error, got %v", err)
+	}
+
+	if _, err := loadMigrations(sqlFiles(map[string]string{"users.up.sql": ""})); err == nil {
+		t.Fatal("expected an invalid filename error")
+	}
+}
+
+func TestMigrationWithoutDownFileCannotBeReverted(t *testing.T) {
+	migrations, err := loadMigrations(sqlFiles(map[string]string{"0001_users.up.sql": "CREATE TABLE users ();"}))
+	if err != nil {
+		t.Fatalf("loadMigrations: %v", err)
+	}
+	if len(migrations) != 1 || migrations[0].Down != "" {
+		t.Fatalf("unexpected migrations %+v", migrations)
+	}
+
+	m := &Migrator{migrations: migrations, DryRun: true, Out: &bytes.Buffer{}}
+	if err := m.revert(context.Background(), nil, migrations[0]); err == nil || !strings.Contains(err.Error(), "no down migration") {
+		t.Fatalf("expected a missing down migration error, got %v", err)
+	}
+}
+
+func TestVerifyChecksumsRejectsEditedMigrations(t *testing.T) {
+	migrations, err := loadMigrations(sqlFiles(map[string]string{
+		"0001_users.up.sql":   "CREATE TABLE users ();",
+		"0002_devices.up.sql": "CREATE TABLE devices ();",
+	}))
+	if err != nil {
+		t.Fatalf("loadMigrations: %v", err)
+	}
+	m := &Migrator{migrations: migrations}
+
+	applied := map[int]appliedMigration{
+		1: {name: "users", checksum: migrations[0].Checksum},
+		2: {name: "devices", checksum: "edited"},
+	}
+	err = m.verifyChecksums(context.Background(), nil, applied)
+	if err == nil || !strings.Contains(err.Error(), "0002_devices") || strings.Contains(err.Error(), "0001_users") {
+		t.Fatalf("expected 0002_devices reported as edited, got %v", err)
+	}
+
+	applied[2] = appliedMigration{name: "devices", checksum: migrations[1].Checksum}
+	if err := m.verifyChecksums(context.Background(), nil, applied); err != nil {
+		t.Fatalf("verifyChecksums: %v", err)
+	}
+}
+
+func TestLatestApplied(t *testing.T) {
+	m := &Migrator{migrations: []Migration{
+		{Version: 1, Name: "users", Up: "CREATE TABLE users ();"},
+		{Version: 2, Name: "devices", Up: "CREATE TABLE devices ();"},
+	}}
+	applied := map[int]appliedMigration{
+		1: {name: "users"},
+		2: {name: "devices"},
+		3: {name: "dropped"},
+	}
+
+	latest := m.latestApplied(applied, 2)
+	if len(latest) != 2 || latest[0].Version != 3 || latest[1].Version != 2 {
+		t.Fatalf("expected versions 3 and 2, got %+v", latest)
+	}
+	// Version 3 was applied but its file is gone.
+	if latest[0].Name != "dropped" || latest[0].Up != "" || latest[1].Up == "" {
+		t.Fatalf("unexpected migrations %+v", latest)
+	}
+	if all := m.latestApplied(applied, 10); len(all) != 3 || all[2].Version != 1 {
+		t.Fatalf("expected all three migrations, got %+v", all)
+	}
+}
+
+func TestDryRunPrintsSQLWithoutChangingTheDatabase(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	migrations, err := loadMigrations(sqlFiles(map[string]string{
+		"0001_users.up.sql":     "CREATE TABLE users ();",
+		"0001_users.down.sql":   "DROP TABLE users;",
+		"0002_devices.up.sql":   "CREATE TABLE devices ();\n",
+		"0002_devices.down.sql": "DROP TABLE devices;",
+	}))
+	if err != nil {
+		t.Fatalf("loadMigrations: %v", err)
+	}
+	var out bytes.Buffer
+	m := &Migrator{db: db, migrations: migrations, DryRun: true, Out: &out}
+
+	// Only the lock and the reads run: no CREATE TABLE schema_migrations, migration
+	// SQL or INSERT, which sqlmock would reject as unexpected.
+	mock.ExpectExec("SELECT pg_advisory_lock").WithArgs(lockKey).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT to_regclass").WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectQuery("FROM schema_migrations").WillReturnRows(
+		sqlmock.NewRows([]string{"version", "name", "checksum", "applied_at"}).
+			AddRow(1, "users", migrations[0].Checksum, time.Now()),
+	)
+	mock.ExpectExec("SELECT pg_advisory_unlock").WithArgs(lockKey).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	n, err := m.Up(context.Background())
+	if err != nil || n != 1 {
+		t.Fatalf("Up: %d, %v", n, err)
+	}
+	if want := "-- apply 0002_devices\nCREATE TABLE devices ();\n"; out.String() != want {
+		t.Fatalf("expected output %q, got %q", want, out.String())
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+
+	out.Reset()
+	if err := m.revert(context.Background(), nil, migrations[0]); err != nil {
+		t.Fatalf("revert: %v", err)
+	}
+	if want := "-- revert 0001_users\nDROP TABLE users;\n"; out.String() != want {
+		t.Fatalf("expected output %q, got %q", want, out.String())
+	}
+}
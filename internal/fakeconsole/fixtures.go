@@ -0,0 +1,52 @@
+package fakeconsole
+
+import (
+	"fmt"
+	"time"
+)
+
+// Demo returns fixtures with the given number of projects, alternating between
+// production and non-production, each with devicesPerProject devices last synced at
+// syncedAt. Project ids start at 1 and device ids at 1001.
+func Demo(projects, devicesPerProject int, syncedAt time.Time) Fixtures {
+	f := Fixtures{Devices: map[string][]map[string]any{}}
+	deviceID := 1000
+	for i := 1; i <= projects; i++ {
+		name := fmt.Sprintf("demo-project-%d", i)
+		f.Projects = append(f.Projects, map[string]any{
+			"id":          i,
+			"name":        name,
+			"company":     "CityPost",
+			"description": fmt.Sprintf("Demo project %d", i),
+			"max_devices": devicesPerProject * 2,
+			"production":  i%2 == 1,
+			"status":      "active",
+			"role":        "owner",
+			"latitude":    "40.7128",
+			"longitude":   "-74.0060",
+			"languages":   []string{"en"},
+			"region":      []int{1},
+			"owner":       map[string]any{"username": "demo"},
+		})
+
+		devices := make([]map[string]any, 0, devicesPerProject)
+		for j := 1; j <= devicesPerProject; j++ {
+			deviceID++
+			devices = append(devices, map[string]any{
+				"id":             deviceID,
+				"name":           fmt.Sprintf("Demo screen %d-%d", i, j),
+				"host_name":      fmt.Sprintf("demo-%d-%d", i, j),
+				"description":    "Demo device",
+				"project":        i,
+				"change":         false,
+				"last_synced_at": syncedAt.UTC().Format(time.RFC3339Nano),
+				"sync_status":    "synced",
+				"device_type":    map[string]any{"id": 1, "name": "Kiosk", "code": "kiosk"},
+				"region":         map[string]any{"id": 1, "code": "nyc", "name": "New York"},
+				"device_config":  map[string]any{"city": "New York", "screen": map[string]any{"width": 1080, "portrait": true}},
+			})
+		}
+		f.Devices[name] = devices
+	}
+	return f
+}
@@ -0,0 +1,323 @@
+// Package fakeconsole is an in-process stand-in for the CityPost console API. It serves
+// /login/, /projectsList and /device/ from fixtures, the way the real console answers
+// services.CityPostConsoleClient, with optional latency, pagination and injected failures.
+// cmd/fakeconsole runs it as a standalone server for local development.
+package fakeconsole
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Fixtures is the data the fake console serves.
+type Fixtures struct {
+	// Projects are returned by /projectsList, filtered on their "production" field.
+	Projects []map[string]any `json:"projects"`
+	// Devices maps a project name to the devices /device/ returns for it.
+	Devices map[string][]map[string]any `json:"devices"`
+}
+
+// Failure makes requests to Path answer with Status instead of their normal response.
+type Failure struct {
+	Path   string `json:"path"`
+	Status int    `json:"status"`
+	// Times is how many requests fail; 0 or less fails every request.
+	Times int `json:"times"`
+}
+
+// Config configures a Server.
+type Config struct {
+	Username string
+	Password string
+	// AuthScheme is the Authorization scheme /device/ expects (default "Token").
+	AuthScheme string
+	Fixtures   Fixtures
+	// Latency delays every response.
+	Latency time.Duration
+	// PageSize splits project and device lists into pages of this many items linked
+	// by "next"; 0 returns every item at once.
+	PageSize int
+	Failures []Failure
+}
+
+// Server is the fake console. It is safe for concurrent use; its fixtures and
+// failures can be changed while it serves requests.
+type Server struct {
+	username   string
+	password   string
+	authScheme string
+	latency    time.Duration
+	pageSize   int
+
+	mu       sync.Mutex
+	fixtures Fixtures
+	failures []Failure
+	tokens   map[string]bool
+	logins   int
+	requests map[string]int
+}
+
+// New creates a fake console serving cfg.
+func New(cfg Config) *Server {
+	s := &Server{
+		username:   cfg.Username,
+		password:   cfg.Password,
+		authScheme: cfg.AuthScheme,
+		latency:    cfg.Latency,
+		pageSize:   cfg.PageSize,
+		fixtures:   cfg.Fixtures,
+		failures:   append([]Failure(nil), cfg.Failures...),
+		tokens:     map[string]bool{},
+		requests:   map[string]int{},
+	}
+	if strings.TrimSpace(s.authScheme) == "" {
+		s.authScheme = "Token"
+	}
+	return s
+}
+
+// SetProjects replaces the projects the console lists.
+func (s *Server) SetProjects(projects []map[string]any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fixtures.Projects = projects
+}
+
+// SetDevices replaces the devices of the named project.
+func (s *Server) SetDevices(project string, devices []map[string]any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.fixtures.Devices == nil {
+		s.fixtures.Devices = map[string][]map[string]any{}
+	}
+	s.fixtures.Devices[project] = devices
+}
+
+// Fail makes the next times requests to path answer with status; times 0 or less
+// fails every request until ClearFailures.
+func (s *Server) Fail(path string, status int, times int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures = append(s.failures, Failure{Path: path, Status: status, Times: times})
+}
+
+// ClearFailures removes every injected failure.
+func (s *Server) ClearFailures() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures = nil
+}
+
+// ExpireTokens invalidates every token handed out so far, as the console does when a
+// session ends early.
+func (s *Server) ExpireTokens() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tokens = map[string]bool{}
+}
+
+// Logins returns how many successful logins the console has seen.
+func (s *Server) Logins() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.logins
+}
+
+// Requests returns how many requests were made to path, failed ones included.
+func (s *Server) Requests(path string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests[path]
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if s.latency > 0 {
+		select {
+		case <-time.After(s.latency):
+		case <-r.Context().Done():
+			return
+		}
+	}
+	if status, ok := s.injectedFailure(r.URL.Path); ok {
+		writeJSON(w, status, map[string]any{"detail": http.StatusText(status)})
+		return
+	}
+
+	switch r.URL.Path {
+	case "/login/":
+		s.login(w, r)
+	case "/projectsList":
+		s.listProjects(w, r)
+	case "/device/":
+		s.listDevices(w, r)
+	default:
+		writeJSON(w, http.StatusNotFound, map[string]any{"detail": "Not found."})
+	}
+}
+
+// injectedFailure counts the request and reports the status of the first failure
+// injected for path, using up one of its times.
+func (s *Server) injectedFailure(path string) (int, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests[path]++
+	for i, f := range s.failures {
+		if f.Path != path {
+			continue
+		}
+		if f.Times > 0 {
+			if f.Times == 1 {
+				s.failures = append(s.failures[:i], s.failures[i+1:]...)
+			} else {
+				s.failures[i].Times--
+			}
+		}
+		return f.Status, true
+	}
+	return 0, false
+}
+
+func (s *Server) login(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]any{"detail": fmt.Sprintf("Method %q not allowed.", r.Method)})
+		return
+	}
+	var creds struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&creds); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"detail": "JSON parse error."})
+		return
+	}
+	if creds.Username != s.username || creds.Password != s.password {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"non_field_errors": []string{"Unable to log in with provided credentials."}})
+		return
+	}
+
+	b := make([]byte, 20)
+	_, _ = rand.Read(b)
+	token := hex.EncodeToString(b)
+	s.mu.Lock()
+	s.tokens[token] = true
+	s.logins++
+	s.mu.Unlock()
+	writeJSON(w, http.StatusOK, map[string]any{"token": token})
+}
+
+func (s *Server) listProjects(w http.ResponseWriter, r *http.Request) {
+	var production *bool
+	if raw := r.URL.Query().Get("production"); raw != "" {
+		v, err := strconv.ParseBool(raw)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"detail": "production must be true or false."})
+			return
+		}
+		production = &v
+	}
+
+	s.mu.Lock()
+	var projects []map[string]any
+	for _, p := range s.fixtures.Projects {
+		isProduction, _ := p["production"].(bool)
+		if production == nil || isProduction == *production {
+			projects = append(projects, p)
+		}
+	}
+	s.mu.Unlock()
+	s.writePage(w, r, "projects", projects)
+}
+
+func (s *Server) listDevices(w http.ResponseWriter, r *http.Request) {
+	if !s.authorized(r) {
+		writeJSON(w, http.StatusUnauthorized, map[string]any{"detail": "Invalid token."})
+		return
+	}
+	project := r.URL.Query().Get("project")
+	if project == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"detail": "project is required."})
+		return
+	}
+	region := r.URL.Query().Get("region")
+
+	s.mu.Lock()
+	var devices []map[string]any
+	for _, d := range s.fixtures.Devices[project] {
+		if region == "" || inRegion(d, region) {
+			devices = append(devices, d)
+		}
+	}
+	s.mu.Unlock()
+	s.writePage(w, r, "devices", devices)
+}
+
+func (s *Server) authorized(r *http.Request) bool {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, s.authScheme) {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.tokens[strings.TrimSpace(token)]
+}
+
+// inRegion reports whether the device's region has the given id, code or name.
+func inRegion(device map[string]any, region string) bool {
+	r, ok := device["region"].(map[string]any)
+	if !ok {
+		return false
+	}
+	for _, k := range []string{"id", "code", "name"} {
+		if v, ok := r[k]; ok && fmt.Sprint(v) == region {
+			return true
+		}
+	}
+	return false
+}
+
+// writePage writes items under key, or with a page size the page the "page" query
+// parameter asks for, along with the total count and an absolute link to the next
+// page, as the console's paginated endpoints do.
+func (s *Server) writePage(w http.ResponseWriter, r *http.Request, key string, items []map[string]any) {
+	if items == nil {
+		items = []map[string]any{}
+	}
+	if s.pageSize <= 0 {
+		writeJSON(w, http.StatusOK, map[string]any{key: items})
+		return
+	}
+
+	page := 1
+	if raw := r.URL.Query().Get("page"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 {
+			writeJSON(w, http.StatusNotFound, map[string]any{"detail": "Invalid page."})
+			return
+		}
+		page = n
+	}
+	start := min((page-1)*s.pageSize, len(items))
+	end := min(start+s.pageSize, len(items))
+
+	var next any
+	if end < len(items) {
+		q := r.URL.Query()
+		q.Set("page", strconv.Itoa(page+1))
+		next = (&url.URL{Scheme: "http", Host: r.Host, Path: r.URL.Path, RawQuery: q.Encode()}).String()
+	}
+	writeJSON(w, http.StatusOK, map[string]any{key: items[start:end], "count": len(items), "next": next})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
@@ -2,26 +2,27 @@ package handlers
 
 import (
 	"errors"
-	"fmt"
     "encoding/json"
-    "log"
     "net/http"
     "database/sql"
     "github.com/go-chi/chi/v5"
     "github.com/go-playground/validator/v10"
     "scm/internal/interfaces"
     "scm/internal/models"
+    "scm/internal/repository"
 )
 
 type AdvertiserHandler struct {
     repo      interfaces.AdvertiserRepository
     validator *validator.Validate
+    cascade   *CascadeDeleter
 }
 
-func NewAdvertiserHandler(repo interfaces.AdvertiserRepository) *AdvertiserHandler {
+func NewAdvertiserHandler(repo interfaces.AdvertiserRepository, cascade *CascadeDeleter) *AdvertiserHandler {
     return &AdvertiserHandler{
         repo:      repo,
         validator: validator.New(),
+        cascade:   cascade,
     }
 }
 
@@ -36,8 +37,6 @@ func NewAdvertiserHandler(repo interfaces.AdvertiserRepository) *AdvertiserHandl
 // @Failure 500 {object} map[string]interface{}
 // @Router /api/v1/advertisers/ [post]
 func (h *AdvertiserHandler) CreateAdvertiser(w http.ResponseWriter, r *http.Request) {
-	log.Println("=== CreateAdvertiser handler called ===")
-	
 	var req models.CreateAdvertiserRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		writeJSONErrorResponse(w, http.StatusBadRequest, "invalid_request", "Invalid request body")
@@ -92,6 +91,7 @@ func (h *AdvertiserHandler) GetAdvertiser(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	setETag(w, advertiser.Version)
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(advertiser)
 }
@@ -100,23 +100,33 @@ func (h *AdvertiserHandler) GetAdvertiser(w http.ResponseWriter, r *http.Request
 // @Summary List advertisers
 // @Security BearerAuth
 // @Produce json
+// @Param include_deleted query bool false "Include soft-deleted advertisers"
+// @Param sort query string false "Sort fields, e.g. name,-created_at"
+// @Param cursor query string false "next_cursor or prev_cursor of a previous page"
 // @Success 200 {array} models.Advertiser
+// @Failure 400 {object} map[string]interface{}
 // @Failure 500 {object} map[string]interface{}
 // @Router /api/v1/advertisers/ [get]
 func (h *AdvertiserHandler) ListAdvertisers(w http.ResponseWriter, r *http.Request) {
-	p, err := parsePaginationParams(r, 50, 200)
+	p, err := parseListParams(r, 50, 200, repository.AdvertiserSort)
+	if err != nil {
+		writeJSONErrorResponse(w, http.StatusBadRequest, "validation_error", "invalid pagination parameters: "+err.Error())
+		return
+	}
+
+	includeDeleted, err := parseIncludeDeleted(r)
 	if err != nil {
-		writeJSONErrorResponse(w, http.StatusBadRequest, "validation_error", "invalid pagination parameters")
+		writeJSONErrorResponse(w, http.StatusBadRequest, "validation_error", "include_deleted must be a boolean")
 		return
 	}
 
-	total, err := h.repo.Count(r.Context())
+	total, err := h.repo.Count(r.Context(), includeDeleted)
 	if err != nil {
 		writeJSONErrorResponse(w, http.StatusInternalServerError, "list_advertisers_failed", "Failed to list advertisers")
 		return
 	}
 
-	advertisers, err := h.repo.List(r.Context(), p.limit, p.offset)
+	advertisers, err := h.repo.List(r.Context(), p.listPage(), includeDeleted)
 	if err != nil {
 		writeJSONErrorResponse(w, http.StatusInternalServerError, "list_advertisers_failed", "Failed to list advertisers")
 		return
@@ -126,55 +136,115 @@ func (h *AdvertiserHandler) ListAdvertisers(w http.ResponseWriter, r *http.Reque
 		advertisers = []models.Advertiser{} // Return empty array instead of null
 	}
 
-	writePaginatedResponse(w, http.StatusOK, advertisers, p.page, p.pageSize, total)
+	writeListResponse(w, p, advertisers, total, nil)
 }
 
 // @Tags Advertisers
-// @Summary Update advertiser
+// @Summary Replace advertiser
+// @Description Replaces the advertiser's editable fields; an omitted email is cleared.
 // @Security BearerAuth
 // @Accept json
 // @Produce json
 // @Param id path string true "Advertiser ID"
-// @Param body body models.UpdateAdvertiserRequest true "Update advertiser request"
+// @Param If-Match header string true "ETag from a previous GET"
+// @Param body body models.ReplaceAdvertiserRequest true "Advertiser"
 // @Success 200 {object} models.Advertiser
 // @Failure 400 {object} map[string]interface{}
 // @Failure 404 {object} map[string]interface{}
+// @Failure 412 {object} map[string]interface{}
+// @Failure 428 {object} map[string]interface{}
 // @Failure 500 {object} map[string]interface{}
 // @Router /api/v1/advertisers/{id}/ [put]
 func (h *AdvertiserHandler) UpdateAdvertiser(w http.ResponseWriter, r *http.Request) {
+	id, ifMatch, _, ok := h.loadForUpdate(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.ReplaceAdvertiserRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONErrorResponse(w, http.StatusBadRequest, "invalid_request", "Invalid request body")
+		return
+	}
+
+	h.replaceAdvertiser(w, r, id, req, ifMatch)
+}
+
+// @Tags Advertisers
+// @Summary Patch advertiser
+// @Description Applies a JSON merge patch (RFC 7396); "email": null clears the email.
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Advertiser ID"
+// @Param If-Match header string true "ETag from a previous GET"
+// @Param body body models.ReplaceAdvertiserRequest true "Merge patch"
+// @Success 200 {object} models.Advertiser
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 412 {object} map[string]interface{}
+// @Failure 415 {object} map[string]interface{}
+// @Failure 428 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/advertisers/{id}/ [patch]
+func (h *AdvertiserHandler) PatchAdvertiser(w http.ResponseWriter, r *http.Request) {
+	id, ifMatch, current, ok := h.loadForUpdate(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.ReplaceAdvertiserRequest
+	if err := decodeMergePatch(r, models.NewReplaceAdvertiserRequest(current), &req); err != nil {
+		writeMergePatchError(w, err)
+		return
+	}
+
+	h.replaceAdvertiser(w, r, id, req, ifMatch)
+}
+
+// loadForUpdate reads the id and If-Match of an update request and the advertiser it
+// targets, answering 404/412/428 itself.
+func (h *AdvertiserHandler) loadForUpdate(w http.ResponseWriter, r *http.Request) (string, int, *models.Advertiser, bool) {
 	id := chi.URLParam(r, "id")
 	if id == "" {
 		writeJSONErrorResponse(w, http.StatusBadRequest, "validation_error", "Advertiser ID is required")
-		return
+		return "", 0, nil, false
 	}
 
-	_, err := h.repo.GetByID(r.Context(), id)
+	ifMatch, ok := requireIfMatch(w, r)
+	if !ok {
+		return "", 0, nil, false
+	}
+
+	current, err := h.repo.GetByID(r.Context(), id)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			writeJSONErrorResponse(w, http.StatusNotFound, "advertiser_not_found", "Advertiser not found")
-			return
+			return "", 0, nil, false
 		}
 		writeJSONErrorResponse(w, http.StatusInternalServerError, "get_advertiser_failed", "Failed to get advertiser")
-		return
+		return "", 0, nil, false
 	}
-
-	var req models.UpdateAdvertiserRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeJSONErrorResponse(w, http.StatusBadRequest, "invalid_request", "Invalid request body")
-		return
-	}
-
-	if req.Name == nil && req.Email == nil {
-		writeJSONErrorResponse(w, http.StatusBadRequest, "validation_error", "No fields to update")
-		return
+	if ifMatchFails(ifMatch, current.Version) {
+		writePreconditionFailed(w, current, current.Version)
+		return "", 0, nil, false
 	}
+	return id, ifMatch, current, true
+}
 
+func (h *AdvertiserHandler) replaceAdvertiser(w http.ResponseWriter, r *http.Request, id string, req models.ReplaceAdvertiserRequest, ifMatch int) {
 	if err := h.validator.Struct(req); err != nil {
 		writeJSONErrorResponse(w, http.StatusBadRequest, "validation_error", err.Error())
 		return
 	}
 
-	if err := h.repo.Update(r.Context(), id, &req); err != nil {
+	if err := h.repo.Update(r.Context(), id, req.ToUpdate(), ifMatch); err != nil {
+		if errors.Is(err, interfaces.ErrVersionConflict) {
+			if current, err := h.repo.GetByID(r.Context(), id); err == nil {
+				writePreconditionFailed(w, current, current.Version)
+				return
+			}
+		}
 		writeJSONErrorResponse(w, http.StatusInternalServerError, "update_advertiser_failed", "Failed to update advertiser")
 		return
 	}
@@ -189,18 +259,22 @@ func (h *AdvertiserHandler) UpdateAdvertiser(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-
+	setETag(w, advertiser.Version)
 	w.Header().Set("Content-Type", "application/json")
-    json.NewEncoder(w).Encode(advertiser)
+	json.NewEncoder(w).Encode(advertiser)
 }
 
 // @Tags Advertisers
 // @Summary Delete advertiser
+// @Description Soft-deletes the advertiser. With cascade=true (admins only) the advertiser, its
+// @Description campaigns, their creatives and the creatives' stored files are removed permanently.
 // @Security BearerAuth
 // @Produce json
 // @Param id path string true "Advertiser ID"
+// @Param cascade query bool false "Permanently delete the advertiser and everything that depends on it"
 // @Success 200 {object} map[string]interface{}
 // @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
 // @Failure 404 {object} map[string]interface{}
 // @Failure 409 {object} map[string]interface{}
 // @Failure 500 {object} map[string]interface{}
@@ -212,14 +286,32 @@ func (h *AdvertiserHandler) DeleteAdvertiser(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
+	cascade, err := parseCascade(r)
+	if err != nil {
+		writeJSONErrorResponse(w, http.StatusBadRequest, "validation_error", "cascade must be a boolean")
+		return
+	}
+	if cascade {
+		if !h.cascade.allowed(w, r) {
+			return
+		}
+		deps, err := h.repo.DeleteCascade(r.Context(), id)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				writeJSONErrorResponse(w, http.StatusNotFound, "advertiser_not_found", "Advertiser not found")
+				return
+			}
+			writeJSONErrorResponse(w, http.StatusInternalServerError, "delete_advertiser_failed", "Failed to delete advertiser")
+			return
+		}
+		h.cascade.writeCascadeResult(w, r, deps)
+		return
+	}
+
 	if err := h.repo.Delete(r.Context(), id); err != nil {
 		var blocked *interfaces.DeletionBlockedError
 		if errors.As(err, &blocked) {
-			msg := fmt.Sprintf("Cannot delete %s: referenced by", blocked.Resource)
-			for k, v := range blocked.References {
-				msg += fmt.Sprintf(" %d %s", v, k)
-			}
-			writeJSONErrorResponse(w, http.StatusConflict, "delete_blocked", msg)
+			writeDeletionBlocked(w, blocked)
 			return
 		}
 		if err == sql.ErrNoRows {
@@ -232,3 +324,71 @@ func (h *AdvertiserHandler) DeleteAdvertiser(w http.ResponseWriter, r *http.Requ
 	// Success response
 	writeJSONMessage(w, http.StatusOK, "advertiser deleted successfully")
 }
+
+// @Tags Advertisers
+// @Summary List advertiser dependencies
+// @Description Lists the campaigns, creatives and stored files a cascade delete would remove,
+// @Description including soft-deleted ones.
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Advertiser ID"
+// @Success 200 {object} models.DeletionDependencies
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/advertisers/{id}/dependencies [get]
+func (h *AdvertiserHandler) GetAdvertiserDependencies(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		writeJSONErrorResponse(w, http.StatusBadRequest, "validation_error", "Advertiser ID is required")
+		return
+	}
+
+	deps, err := h.repo.Dependencies(r.Context(), id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			writeJSONErrorResponse(w, http.StatusNotFound, "advertiser_not_found", "Advertiser not found")
+			return
+		}
+		writeJSONErrorResponse(w, http.StatusInternalServerError, "get_dependencies_failed", "Failed to list advertiser dependencies")
+		return
+	}
+	writeDependencies(w, deps)
+}
+
+// @Tags Advertisers
+// @Summary Restore advertiser
+// @Description Restores a soft-deleted advertiser.
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Advertiser ID"
+// @Success 200 {object} models.Advertiser
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/advertisers/{id}/restore [post]
+func (h *AdvertiserHandler) RestoreAdvertiser(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		writeJSONErrorResponse(w, http.StatusBadRequest, "validation_error", "Advertiser ID is required")
+		return
+	}
+
+	if err := h.repo.Restore(r.Context(), id); err != nil {
+		if err == sql.ErrNoRows {
+			writeJSONErrorResponse(w, http.StatusNotFound, "advertiser_not_found", "Deleted advertiser not found")
+			return
+		}
+		writeJSONErrorResponse(w, http.StatusInternalServerError, "restore_advertiser_failed", "Failed to restore advertiser")
+		return
+	}
+
+	advertiser, err := h.repo.GetByID(r.Context(), id)
+	if err != nil {
+		writeJSONErrorResponse(w, http.StatusInternalServerError, "get_advertiser_failed", "Failed to get advertiser")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(advertiser)
+}
@@ -7,9 +7,11 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"scm/internal/interfaces"
+	authmw "scm/internal/middleware"
 	"scm/internal/models"
 )
 
@@ -21,17 +23,41 @@ func (m *mockAdvertiserRepo) Create(ctx context.Context, advertiser *models.Adve
 func (m *mockAdvertiserRepo) GetByID(ctx context.Context, id string) (*models.Advertiser, error) {
 	return nil, sql.ErrNoRows
 }
-func (m *mockAdvertiserRepo) List(ctx context.Context, limit int, offset int) ([]models.Advertiser, error) {
+func (m *mockAdvertiserRepo) List(ctx context.Context, page interfaces.ListPage, includeDeleted bool) ([]models.Advertiser, error) {
 	return []models.Advertiser{}, nil
 }
-func (m *mockAdvertiserRepo) Count(ctx context.Context) (int, error) { return 0, nil }
-func (m *mockAdvertiserRepo) Update(ctx context.Context, id string, req *models.UpdateAdvertiserRequest) error {
+func (m *mockAdvertiserRepo) Count(ctx context.Context, includeDeleted bool) (int, error) { return 0, nil }
+func (m *mockAdvertiserRepo) Update(ctx context.Context, id string, req *models.UpdateAdvertiserRequest, version int) error {
+	return nil
+}
+func (m *mockAdvertiserRepo) Delete(ctx context.Context, id string) error  { return nil }
+func (m *mockAdvertiserRepo) Restore(ctx context.Context, id string) error { return sql.ErrNoRows }
+func (m *mockAdvertiserRepo) PurgeDeletedBefore(ctx context.Context, cutoff time.Time) (int64, []string, error) {
+	return 0, nil, nil
+}
+func (m *mockAdvertiserRepo) Dependencies(ctx context.Context, id string) (*models.DeletionDependencies, error) {
+	return nil, sql.ErrNoRows
+}
+func (m *mockAdvertiserRepo) DeleteCascade(ctx context.Context, id string) (*models.DeletionDependencies, error) {
+	return &models.DeletionDependencies{
+		Resource:      "advertiser",
+		ID:            id,
+		References:    map[string]int64{"campaigns": 1, "creatives": 2, "stored_objects": 2},
+		StoredObjects: []string{"creatives/one.png", "creatives/two.mp4"},
+	}, nil
+}
+
+type recordingObjectStorage struct {
+	deleted []string
+}
+
+func (s *recordingObjectStorage) DeleteObjects(ctx context.Context, keys []string) error {
+	s.deleted = append(s.deleted, keys...)
 	return nil
 }
-func (m *mockAdvertiserRepo) Delete(ctx context.Context, id string) error { return nil }
 
 func TestGetAdvertiserNotFoundJSON(t *testing.T) {
-	h := NewAdvertiserHandler(&mockAdvertiserRepo{})
+	h := NewAdvertiserHandler(&mockAdvertiserRepo{}, nil)
 	r := chi.NewRouter()
 	r.Get("/advertisers/{id}", h.GetAdvertiser)
 
@@ -53,3 +79,82 @@ func TestGetAdvertiserNotFoundJSON(t *testing.T) {
 		t.Fatalf("expected error field, got %v", resp)
 	}
 }
+
+func TestRestoreAdvertiserNotDeletedReturns404(t *testing.T) {
+	h := NewAdvertiserHandler(&mockAdvertiserRepo{}, nil)
+	r := chi.NewRouter()
+	r.Post("/advertisers/{id}/restore", h.RestoreAdvertiser)
+
+	req := httptest.NewRequest(http.MethodPost, "/advertisers/a1/restore", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 got %d (%s)", w.Code, w.Body.String())
+	}
+}
+
+func TestListAdvertisersRejectsInvalidIncludeDeleted(t *testing.T) {
+	h := NewAdvertiserHandler(&mockAdvertiserRepo{}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/advertisers?include_deleted=maybe", nil)
+	w := httptest.NewRecorder()
+	h.ListAdvertisers(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 got %d (%s)", w.Code, w.Body.String())
+	}
+}
+
+func TestDeleteAdvertiserCascadeRequiresAdmin(t *testing.T) {
+	storage := &recordingObjectStorage{}
+	h := NewAdvertiserHandler(&mockAdvertiserRepo{}, &CascadeDeleter{
+		Admins:  authmw.NewAdmins([]string{"admin@example.com"}),
+		Storage: storage,
+	})
+	r := chi.NewRouter()
+	r.Delete("/advertisers/{id}", h.DeleteAdvertiser)
+
+	req := httptest.NewRequest(http.MethodDelete, "/advertisers/a1?cascade=true", nil)
+	req = req.WithContext(context.WithValue(req.Context(), authmw.CtxEmail, "user@example.com"))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 got %d (%s)", w.Code, w.Body.String())
+	}
+	if len(storage.deleted) != 0 {
+		t.Fatalf("expected no stored objects deleted, got %v", storage.deleted)
+	}
+}
+
+func TestDeleteAdvertiserCascadeDeletesStoredObjects(t *testing.T) {
+	storage := &recordingObjectStorage{}
+	h := NewAdvertiserHandler(&mockAdvertiserRepo{}, &CascadeDeleter{
+		Admins:  authmw.NewAdmins([]string{"Admin@Example.com"}),
+		Storage: storage,
+	})
+	r := chi.NewRouter()
+	r.Delete("/advertisers/{id}", h.DeleteAdvertiser)
+
+	req := httptest.NewRequest(http.MethodDelete, "/advertisers/a1?cascade=true", nil)
+	req = req.WithContext(context.WithValue(req.Context(), authmw.CtxEmail, "admin@example.com"))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 got %d (%s)", w.Code, w.Body.String())
+	}
+	if len(storage.deleted) != 2 {
+		t.Fatalf("expected 2 stored objects deleted, got %v", storage.deleted)
+	}
+	var resp struct {
+		Deleted models.DeletionDependencies `json:"deleted"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("invalid json: %v", err)
+	}
+	if resp.Deleted.References["campaigns"] != 1 {
+		t.Fatalf("expected deleted dependencies in response, got %s", w.Body.String())
+	}
+}
@@ -0,0 +1,412 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	authmw "scm/internal/middleware"
+	"scm/internal/models"
+	"scm/internal/repository"
+	"scm/internal/services"
+)
+
+type AlertHandler struct {
+	repo      repository.AlertRepository
+	validator *validator.Validate
+}
+
+func NewAlertHandler(repo repository.AlertRepository) *AlertHandler {
+	return &AlertHandler{
+		repo:      repo,
+		validator: validator.New(),
+	}
+}
+
+func parseIDParam(r *http.Request, name string) (int, bool) {
+	id, err := strconv.Atoi(chi.URLParam(r, name))
+	if err != nil || id <= 0 {
+		return 0, false
+	}
+	return id, true
+}
+
+// @Tags Alerts
+// @Summary List alert rules
+// @Security BearerAuth
+// @Produce json
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Page size" default(20)
+// @Param type query string false "Filter by rule type (venue_offline, sync_errors)"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/alerts/rules [get]
+func (h *AlertHandler) ListRules(w http.ResponseWriter, r *http.Request) {
+	pagination, err := parsePaginationParams(r, 20, 100)
+	if err != nil {
+		writeJSONErrorResponse(w, http.StatusBadRequest, "invalid_pagination", "invalid pagination: "+err.Error())
+		return
+	}
+
+	var ruleType *string
+	if v := r.URL.Query().Get("type"); v != "" {
+		ruleType = &v
+	}
+
+	rules, err := h.repo.ListRules(r.Context(), ruleType, pagination.limit, pagination.offset)
+	if err != nil {
+		writeJSONErrorResponse(w, http.StatusInternalServerError, "internal_error", "failed to list alert rules: "+err.Error())
+		return
+	}
+	total, err := h.repo.CountRules(r.Context(), ruleType)
+	if err != nil {
+		writeJSONErrorResponse(w, http.StatusInternalServerError, "internal_error", "failed to count alert rules: "+err.Error())
+		return
+	}
+
+	writePaginatedResponse(w, http.StatusOK, rules, pagination.page, pagination.pageSize, total)
+}
+
+// @Tags Alerts
+// @Summary Create alert rule
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param body body models.CreateAlertRuleRequest true "Create alert rule request"
+// @Success 201 {object} models.AlertRule
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/alerts/rules [post]
+func (h *AlertHandler) CreateRule(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateAlertRuleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONErrorResponse(w, http.StatusBadRequest, "invalid_json", "invalid JSON: "+err.Error())
+		return
+	}
+	if err := h.validator.Struct(req); err != nil {
+		writeJSONErrorResponse(w, http.StatusBadRequest, "validation_error", err.Error())
+		return
+	}
+	if req.WebhookURL != nil && *req.WebhookURL != "" {
+		if err := services.CheckWebhookURL(*req.WebhookURL); err != nil {
+			writeJSONErrorResponse(w, http.StatusBadRequest, "invalid_url", err.Error())
+			return
+		}
+	}
+
+	rule := &models.AlertRule{
+		Name:            req.Name,
+		Type:            req.Type,
+		VenueID:         req.VenueID,
+		OfflineDevices:  req.OfflineDevices,
+		OfflineMinutes:  req.OfflineMinutes,
+		EmailRecipients: req.EmailRecipients,
+		WebhookURL:      req.WebhookURL,
+		WebhookSecret:   req.WebhookSecret,
+		Enabled:         true,
+		CreatedBy:       authmw.UserIDFromContext(r.Context()),
+	}
+	if rule.EmailRecipients == nil {
+		rule.EmailRecipients = []string{}
+	}
+	if req.Enabled != nil {
+		rule.Enabled = *req.Enabled
+	}
+
+	if err := h.repo.CreateRule(r.Context(), rule); err != nil {
+		writeJSONErrorResponse(w, http.StatusInternalServerError, "internal_error", "failed to create alert rule: "+err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(rule)
+}
+
+// @Tags Alerts
+// @Summary Get alert rule
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Alert rule ID"
+// @Success 200 {object} models.AlertRule
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/alerts/rules/{id} [get]
+func (h *AlertHandler) GetRule(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseIDParam(r, "id")
+	if !ok {
+		writeJSONErrorResponse(w, http.StatusBadRequest, "invalid_request", "invalid alert rule ID")
+		return
+	}
+
+	rule, err := h.repo.GetRule(r.Context(), id)
+	if err != nil {
+		if err.Error() == "alert rule not found" {
+			writeJSONErrorResponse(w, http.StatusNotFound, "not_found", "alert rule not found")
+			return
+		}
+		writeJSONErrorResponse(w, http.StatusInternalServerError, "internal_error", "failed to get alert rule: "+err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(rule)
+}
+
+// @Tags Alerts
+// @Summary Update alert rule
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Alert rule ID"
+// @Param body body models.UpdateAlertRuleRequest true "Update alert rule request"
+// @Success 200 {object} models.AlertRule
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/alerts/rules/{id} [put]
+func (h *AlertHandler) UpdateRule(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseIDParam(r, "id")
+	if !ok {
+		writeJSONErrorResponse(w, http.StatusBadRequest, "invalid_request", "invalid alert rule ID")
+		return
+	}
+
+	var req models.UpdateAlertRuleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONErrorResponse(w, http.StatusBadRequest, "invalid_json", "invalid JSON: "+err.Error())
+		return
+	}
+	if err := h.validator.Struct(req); err != nil {
+		writeJSONErrorResponse(w, http.StatusBadRequest, "validation_error", err.Error())
+		return
+	}
+	if req.WebhookURL != nil && *req.WebhookURL != "" {
+		if err := services.CheckWebhookURL(*req.WebhookURL); err != nil {
+			writeJSONErrorResponse(w, http.StatusBadRequest, "invalid_url", err.Error())
+			return
+		}
+	}
+
+	rule, err := h.repo.GetRule(r.Context(), id)
+	if err != nil {
+		if err.Error() == "alert rule not found" {
+			writeJSONErrorResponse(w, http.StatusNotFound, "not_found", "alert rule not found")
+			return
+		}
+		writeJSONErrorResponse(w, http.StatusInternalServerError, "internal_error", "failed to get alert rule: "+err.Error())
+		return
+	}
+
+	if req.Name != nil {
+		rule.Name = *req.Name
+	}
+	if req.VenueID != nil {
+		rule.VenueID = req.VenueID
+	}
+	if req.OfflineDevices != nil {
+		rule.OfflineDevices = *req.OfflineDevices
+	}
+	if req.OfflineMinutes != nil {
+		rule.OfflineMinutes = *req.OfflineMinutes
+	}
+	if req.EmailRecipients != nil {
+		rule.EmailRecipients = *req.EmailRecipients
+	}
+	if req.WebhookURL != nil {
+		rule.WebhookURL = req.WebhookURL
+	}
+	if req.WebhookSecret != nil {
+		rule.WebhookSecret = req.WebhookSecret
+	}
+	if req.Enabled != nil {
+		rule.Enabled = *req.Enabled
+	}
+
+	if err := h.repo.UpdateRule(r.Context(), rule); err != nil {
+		if err.Error() == "alert rule not found" {
+			writeJSONErrorResponse(w, http.StatusNotFound, "not_found", "alert rule not found")
+			return
+		}
+		writeJSONErrorResponse(w, http.StatusInternalServerError, "internal_error", "failed to update alert rule: "+err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(rule)
+}
+
+// @Tags Alerts
+// @Summary Delete alert rule
+// @Description Deleting a rule also deletes its alert history.
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Alert rule ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/alerts/rules/{id} [delete]
+func (h *AlertHandler) DeleteRule(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseIDParam(r, "id")
+	if !ok {
+		writeJSONErrorResponse(w, http.StatusBadRequest, "invalid_request", "invalid alert rule ID")
+		return
+	}
+
+	if err := h.repo.DeleteRule(r.Context(), id); err != nil {
+		if err.Error() == "alert rule not found" {
+			writeJSONErrorResponse(w, http.StatusNotFound, "not_found", "alert rule not found")
+			return
+		}
+		writeJSONErrorResponse(w, http.StatusInternalServerError, "internal_error", "failed to delete alert rule: "+err.Error())
+		return
+	}
+
+	writeJSONMessage(w, http.StatusOK, "alert rule deleted successfully")
+}
+
+// @Tags Alerts
+// @Summary Alert history
+// @Security BearerAuth
+// @Produce json
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Page size" default(20)
+// @Param status query string false "Filter by status (open, acknowledged, resolved)"
+// @Param type query string false "Filter by rule type"
+// @Param rule_id query int false "Filter by rule ID"
+// @Param venue_id query int false "Filter by venue ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/alerts [get]
+func (h *AlertHandler) ListAlerts(w http.ResponseWriter, r *http.Request) {
+	pagination, err := parsePaginationParams(r, 20, 100)
+	if err != nil {
+		writeJSONErrorResponse(w, http.StatusBadRequest, "invalid_pagination", "invalid pagination: "+err.Error())
+		return
+	}
+
+	q := r.URL.Query()
+	filters := repository.AlertFilters{}
+	if v := q.Get("status"); v != "" {
+		switch v {
+		case models.AlertStatusOpen, models.AlertStatusAcknowledged, models.AlertStatusResolved:
+			filters.Status = &v
+		default:
+			writeJSONErrorResponse(w, http.StatusBadRequest, "invalid_request", "status must be one of open, acknowledged, resolved")
+			return
+		}
+	}
+	if v := q.Get("type"); v != "" {
+		filters.Type = &v
+	}
+	if v := q.Get("rule_id"); v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil {
+			writeJSONErrorResponse(w, http.StatusBadRequest, "invalid_request", "invalid rule_id")
+			return
+		}
+		filters.RuleID = &id
+	}
+	if v := q.Get("venue_id"); v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil {
+			writeJSONErrorResponse(w, http.StatusBadRequest, "invalid_request", "invalid venue_id")
+			return
+		}
+		filters.VenueID = &id
+	}
+
+	alerts, err := h.repo.ListAlerts(r.Context(), filters, pagination.limit, pagination.offset)
+	if err != nil {
+		writeJSONErrorResponse(w, http.StatusInternalServerError, "internal_error", "failed to list alerts: "+err.Error())
+		return
+	}
+	total, err := h.repo.CountAlerts(r.Context(), filters)
+	if err != nil {
+		writeJSONErrorResponse(w, http.StatusInternalServerError, "internal_error", "failed to count alerts: "+err.Error())
+		return
+	}
+
+	writePaginatedResponse(w, http.StatusOK, alerts, pagination.page, pagination.pageSize, total)
+}
+
+// @Tags Alerts
+// @Summary Get alert
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Alert ID"
+// @Success 200 {object} models.Alert
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/alerts/{id} [get]
+func (h *AlertHandler) GetAlert(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseIDParam(r, "id")
+	if !ok {
+		writeJSONErrorResponse(w, http.StatusBadRequest, "invalid_request", "invalid alert ID")
+		return
+	}
+
+	alert, err := h.repo.GetAlert(r.Context(), id)
+	if err != nil {
+		if err.Error() == "alert not found" {
+			writeJSONErrorResponse(w, http.StatusNotFound, "not_found", "alert not found")
+			return
+		}
+		writeJSONErrorResponse(w, http.StatusInternalServerError, "internal_error", "failed to get alert: "+err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(alert)
+}
+
+// @Tags Alerts
+// @Summary Acknowledge alert
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Alert ID"
+// @Success 200 {object} models.Alert
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/alerts/{id}/acknowledge [post]
+func (h *AlertHandler) Acknowledge(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseIDParam(r, "id")
+	if !ok {
+		writeJSONErrorResponse(w, http.StatusBadRequest, "invalid_request", "invalid alert ID")
+		return
+	}
+
+	alert, err := h.repo.Acknowledge(r.Context(), id, authmw.UserIDFromContext(r.Context()))
+	if err != nil {
+		switch err.Error() {
+		case "alert not found":
+			writeJSONErrorResponse(w, http.StatusNotFound, "not_found", "alert not found")
+		case "alert is not open":
+			writeJSONErrorResponse(w, http.StatusConflict, "alert_not_open", "only open alerts can be acknowledged")
+		default:
+			writeJSONErrorResponse(w, http.StatusInternalServerError, "internal_error", "failed to acknowledge alert: "+err.Error())
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(alert)
+}
@@ -0,0 +1,185 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"scm/internal/models"
+	"scm/internal/repository"
+)
+
+type mockAlertRepo struct {
+	rules  []*models.AlertRule
+	alerts map[int]*models.Alert
+}
+
+var _ repository.AlertRepository = (*mockAlertRepo)(nil)
+
+func (m *mockAlertRepo) CreateRule(ctx context.Context, rule *models.AlertRule) error {
+	rule.ID = len(m.rules) + 1
+	m.rules = append(m.rules, rule)
+	return nil
+}
+func (m *mockAlertRepo) GetRule(ctx context.Context, id int) (*models.AlertRule, error) {
+	for _, rule := range m.rules {
+		if rule.ID == id {
+			return rule, nil
+		}
+	}
+	return nil, errors.New("alert rule not found")
+}
+func (m *mockAlertRepo) ListRules(ctx context.Context, ruleType *string, limit int, offset int) ([]*models.AlertRule, error) {
+	return m.rules, nil
+}
+func (m *mockAlertRepo) CountRules(ctx context.Context, ruleType *string) (int, error) {
+	return len(m.rules), nil
+}
+func (m *mockAlertRepo) ListEnabledRules(ctx context.Context, ruleType string) ([]*models.AlertRule, error) {
+	return nil, nil
+}
+func (m *mockAlertRepo) UpdateRule(ctx context.Context, rule *models.AlertRule) error { return nil }
+func (m *mockAlertRepo) DeleteRule(ctx context.Context, id int) error                 { return nil }
+func (m *mockAlertRepo) Trigger(ctx context.Context, alert *models.Alert) (bool, error) {
+	return false, nil
+}
+func (m *mockAlertRepo) ResolveInactive(ctx context.Context, ruleID int, activeKeys []string) (int64, error) {
+	return 0, nil
+}
+func (m *mockAlertRepo) MarkNotified(ctx context.Context, id int) error { return nil }
+func (m *mockAlertRepo) ListUnnotified(ctx context.Context, limit int) ([]*models.Alert, error) {
+	return nil, nil
+}
+func (m *mockAlertRepo) GetAlert(ctx context.Context, id int) (*models.Alert, error) {
+	if alert, ok := m.alerts[id]; ok {
+		return alert, nil
+	}
+	return nil, errors.New("alert not found")
+}
+func (m *mockAlertRepo) ListAlerts(ctx context.Context, filters repository.AlertFilters, limit int, offset int) ([]*models.Alert, error) {
+	return []*models.Alert{}, nil
+}
+func (m *mockAlertRepo) CountAlerts(ctx context.Context, filters repository.AlertFilters) (int, error) {
+	return 0, nil
+}
+func (m *mockAlertRepo) Acknowledge(ctx context.Context, id int, by string) (*models.Alert, error) {
+	alert, err := m.GetAlert(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if alert.Status != models.AlertStatusOpen {
+		return nil, errors.New("alert is not open")
+	}
+	alert.Status = models.AlertStatusAcknowledged
+	alert.AcknowledgedBy = &by
+	return alert, nil
+}
+
+func newAlertRouter(repo *mockAlertRepo) http.Handler {
+	h := NewAlertHandler(repo)
+	r := chi.NewRouter()
+	r.Get("/alerts", h.ListAlerts)
+	r.Post("/alerts/rules", h.CreateRule)
+	r.Post("/alerts/{id}/acknowledge", h.Acknowledge)
+	return r
+}
+
+func TestCreateAlertRuleValidatesType(t *testing.T) {
+	r := newAlertRouter(&mockAlertRepo{})
+
+	req := httptest.NewRequest(http.MethodPost, "/alerts/rules", strings.NewReader(`{"name": "Lobby dark", "type": "cpu_hot"}`))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 got %d (%s)", w.Code, w.Body.String())
+	}
+}
+
+func TestCreateAlertRuleDefaultsEnabled(t *testing.T) {
+	repo := &mockAlertRepo{}
+	r := newAlertRouter(repo)
+
+	body := `{"name": "Lobby dark", "type": "venue_offline", "offline_devices": 2, "offline_minutes": 15, "email_recipients": ["ops@example.com"]}`
+	req := httptest.NewRequest(http.MethodPost, "/alerts/rules", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201 got %d (%s)", w.Code, w.Body.String())
+	}
+	if len(repo.rules) != 1 || !repo.rules[0].Enabled || repo.rules[0].OfflineDevices != 2 {
+		t.Fatalf("unexpected rule: %+v", repo.rules)
+	}
+}
+
+func TestCreateAlertRuleRejectsInternalWebhook(t *testing.T) {
+	repo := &mockAlertRepo{}
+	r := newAlertRouter(repo)
+
+	for _, url := range []string{"http://169.254.169.254/latest/meta-data", "http://127.0.0.1:8080/hook"} {
+		body := `{"name": "Lobby dark", "type": "venue_offline", "offline_devices": 2, "webhook_url": "` + url + `"}`
+		req := httptest.NewRequest(http.MethodPost, "/alerts/rules", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("%s: expected 400 got %d (%s)", url, w.Code, w.Body.String())
+		}
+	}
+	if len(repo.rules) != 0 {
+		t.Fatalf("expected no rules, got %+v", repo.rules)
+	}
+}
+
+func TestAcknowledgeAlert(t *testing.T) {
+	repo := &mockAlertRepo{alerts: map[int]*models.Alert{
+		1: {ID: 1, Status: models.AlertStatusOpen},
+		2: {ID: 2, Status: models.AlertStatusResolved},
+	}}
+	r := newAlertRouter(repo)
+
+	cases := []struct {
+		path string
+		want int
+	}{
+		{"/alerts/1/acknowledge", http.StatusOK},
+		{"/alerts/1/acknowledge", http.StatusConflict},
+		{"/alerts/2/acknowledge", http.StatusConflict},
+		{"/alerts/3/acknowledge", http.StatusNotFound},
+		{"/alerts/abc/acknowledge", http.StatusBadRequest},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest(http.MethodPost, c.path, nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != c.want {
+			t.Fatalf("%s: expected %d got %d (%s)", c.path, c.want, w.Code, w.Body.String())
+		}
+	}
+
+	if repo.alerts[1].Status != models.AlertStatusAcknowledged {
+		t.Fatalf("expected alert 1 acknowledged, got %q", repo.alerts[1].Status)
+	}
+}
+
+func TestListAlertsRejectsUnknownStatus(t *testing.T) {
+	r := newAlertRouter(&mockAlertRepo{})
+
+	req := httptest.NewRequest(http.MethodGet, "/alerts?status=snoozed", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 got %d (%s)", w.Code, w.Body.String())
+	}
+	var resp map[string]any
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("invalid json: %v", err)
+	}
+}
@@ -0,0 +1,276 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"scm/internal/interfaces"
+	"scm/internal/models"
+	"scm/internal/repository"
+)
+
+const (
+	maxAnnotationTags      = 50
+	maxAnnotationTagLength = 64
+	maxAnnotationNotes     = 10000
+)
+
+// AnnotationHandler serves the local annotations of devices and projects. They are
+// edited here only; console sync leaves them alone.
+type AnnotationHandler struct {
+	repo repository.AnnotationRepository
+}
+
+func NewAnnotationHandler(repo repository.AnnotationRepository) *AnnotationHandler {
+	return &AnnotationHandler{repo: repo}
+}
+
+// annotationTarget binds the handler to one kind of annotated record.
+type annotationTarget struct {
+	param    string
+	notFound string
+	get      func(r *http.Request, key string) (*models.Annotations, error)
+	update   func(r *http.Request, key string, a *models.Annotations, version int) error
+}
+
+func (h *AnnotationHandler) device() annotationTarget {
+	return annotationTarget{
+		param:    "hostName",
+		notFound: "device not found",
+		get: func(r *http.Request, key string) (*models.Annotations, error) {
+			return h.repo.GetDeviceAnnotations(r.Context(), key)
+		},
+		update: func(r *http.Request, key string, a *models.Annotations, version int) error {
+			return h.repo.UpdateDeviceAnnotations(r.Context(), key, a, version)
+		},
+	}
+}
+
+func (h *AnnotationHandler) project() annotationTarget {
+	return annotationTarget{
+		param:    "name",
+		notFound: "project not found",
+		get: func(r *http.Request, key string) (*models.Annotations, error) {
+			return h.repo.GetProjectAnnotations(r.Context(), key)
+		},
+		update: func(r *http.Request, key string, a *models.Annotations, version int) error {
+			return h.repo.UpdateProjectAnnotations(r.Context(), key, a, version)
+		},
+	}
+}
+
+// @Tags Devices
+// @Summary Get device annotations
+// @Security BearerAuth
+// @Produce json
+// @Param hostName path string true "Device host name"
+// @Success 200 {object} models.Annotations
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/devices/{hostName}/annotations [get]
+func (h *AnnotationHandler) GetDevice(w http.ResponseWriter, r *http.Request) {
+	h.get(w, r, h.device())
+}
+
+// @Tags Devices
+// @Summary Patch device annotations
+// @Description Applies a JSON merge patch (RFC 7396) to the device's tags, notes, internal_owner and attributes.
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param hostName path string true "Device host name"
+// @Param If-Match header string true "ETag from a previous GET"
+// @Param body body models.AnnotationsRequest true "Merge patch"
+// @Success 200 {object} models.Annotations
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 412 {object} map[string]interface{}
+// @Failure 415 {object} map[string]interface{}
+// @Failure 428 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/devices/{hostName}/annotations [patch]
+func (h *AnnotationHandler) PatchDevice(w http.ResponseWriter, r *http.Request) {
+	h.patch(w, r, h.device())
+}
+
+// @Tags Projects
+// @Summary Get project annotations
+// @Security BearerAuth
+// @Produce json
+// @Param name path string true "Project name"
+// @Success 200 {object} models.Annotations
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/projects/{name}/annotations [get]
+func (h *AnnotationHandler) GetProject(w http.ResponseWriter, r *http.Request) {
+	h.get(w, r, h.project())
+}
+
+// @Tags Projects
+// @Summary Patch project annotations
+// @Description Applies a JSON merge patch (RFC 7396) to the project's tags, notes, internal_owner and attributes.
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param name path string true "Project name"
+// @Param If-Match header string true "ETag from a previous GET"
+// @Param body body models.AnnotationsRequest true "Merge patch"
+// @Success 200 {object} models.Annotations
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 412 {object} map[string]interface{}
+// @Failure 415 {object} map[string]interface{}
+// @Failure 428 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/projects/{name}/annotations [patch]
+func (h *AnnotationHandler) PatchProject(w http.ResponseWriter, r *http.Request) {
+	h.patch(w, r, h.project())
+}
+
+func (h *AnnotationHandler) get(w http.ResponseWriter, r *http.Request, t annotationTarget) {
+	annotations, ok := h.read(w, r, t, chi.URLParam(r, t.param))
+	if !ok {
+		return
+	}
+
+	setETag(w, annotations.Version)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(annotations)
+}
+
+func (h *AnnotationHandler) patch(w http.ResponseWriter, r *http.Request, t annotationTarget) {
+	key := chi.URLParam(r, t.param)
+	ifMatch, ok := requireIfMatch(w, r)
+	if !ok {
+		return
+	}
+
+	current, ok := h.read(w, r, t, key)
+	if !ok {
+		return
+	}
+	if ifMatchFails(ifMatch, current.Version) {
+		writePreconditionFailed(w, current, current.Version)
+		return
+	}
+
+	var req models.AnnotationsRequest
+	if err := decodeMergePatch(r, models.NewAnnotationsRequest(current), &req); err != nil {
+		writeMergePatchError(w, err)
+		return
+	}
+	annotations, err := normalizeAnnotations(req)
+	if err != nil {
+		writeJSONErrorResponse(w, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+
+	if err := t.update(r, key, annotations, ifMatch); err != nil {
+		if errors.Is(err, interfaces.ErrVersionConflict) {
+			if current, err := t.get(r, key); err == nil {
+				writePreconditionFailed(w, current, current.Version)
+				return
+			}
+		}
+		if err.Error() == t.notFound {
+			writeJSONErrorResponse(w, http.StatusNotFound, "not_found", t.notFound)
+			return
+		}
+		writeJSONErrorResponse(w, http.StatusInternalServerError, "internal_error", "failed to update annotations: "+err.Error())
+		return
+	}
+
+	setETag(w, annotations.Version)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(annotations)
+}
+
+func (h *AnnotationHandler) read(w http.ResponseWriter, r *http.Request, t annotationTarget, key string) (*models.Annotations, bool) {
+	annotations, err := t.get(r, key)
+	if err != nil {
+		if err.Error() == t.notFound {
+			writeJSONErrorResponse(w, http.StatusNotFound, "not_found", t.notFound)
+			return nil, false
+		}
+		writeJSONErrorResponse(w, http.StatusInternalServerError, "internal_error", "failed to get annotations: "+err.Error())
+		return nil, false
+	}
+	return annotations, true
+}
+
+// normalizeAnnotations validates a patched request and turns it into annotations with
+// trimmed, de-duplicated and sorted tags.
+func normalizeAnnotations(req models.AnnotationsRequest) (*models.Annotations, error) {
+	tags := []string{}
+	for _, tag := range req.Tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" {
+			return nil, errors.New("tags must not be empty")
+		}
+		if len(tag) > maxAnnotationTagLength {
+			return nil, fmt.Errorf("tags must be at most %d characters", maxAnnotationTagLength)
+		}
+		if !slices.Contains(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	if len(tags) > maxAnnotationTags {
+		return nil, fmt.Errorf("at most %d tags are allowed", maxAnnotationTags)
+	}
+	slices.Sort(tags)
+
+	if len(req.Notes) > maxAnnotationNotes {
+		return nil, fmt.Errorf("notes must be at most %d characters", maxAnnotationNotes)
+	}
+
+	attributes := map[string]string{}
+	for k, v := range req.Attributes {
+		if strings.TrimSpace(k) == "" {
+			return nil, errors.New("attribute keys must not be empty")
+		}
+		attributes[k] = v
+	}
+
+	return &models.Annotations{
+		Tags:          tags,
+		Notes:         req.Notes,
+		InternalOwner: strings.TrimSpace(req.InternalOwner),
+		Attributes:    attributes,
+	}, nil
+}
+
+// parseAnnotationFilters reads the annotation filters shared by the device and project
+// lists: tag (repeatable, all must match), internal_owner and attribute.<key>=<value>.
+func parseAnnotationFilters(q url.Values) (repository.AnnotationFilters, error) {
+	var filters repository.AnnotationFilters
+	for _, tag := range q["tag"] {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			filters.Tags = append(filters.Tags, tag)
+		}
+	}
+	if owner := q.Get("internal_owner"); owner != "" {
+		filters.InternalOwner = &owner
+	}
+	for param, values := range q {
+		key, ok := strings.CutPrefix(param, "attribute.")
+		if !ok {
+			continue
+		}
+		if key == "" {
+			return filters, fmt.Errorf("invalid attribute filter %s", param)
+		}
+		if filters.Attributes == nil {
+			filters.Attributes = map[string]string{}
+		}
+		filters.Attributes[key] = values[0]
+	}
+	return filters, nil
+}
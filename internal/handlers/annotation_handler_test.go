@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"scm/internal/interfaces"
+	"scm/internal/models"
+	"scm/internal/repository"
+)
+
+type mockAnnotationRepo struct {
+	devices map[string]*models.Annotations
+}
+
+var _ repository.AnnotationRepository = (*mockAnnotationRepo)(nil)
+
+func (m *mockAnnotationRepo) GetDeviceAnnotations(ctx context.Context, hostName string) (*models.Annotations, error) {
+	a, ok := m.devices[hostName]
+	if !ok {
+		return nil, errors.New("device not found")
+	}
+	cp := *a
+	return &cp, nil
+}
+func (m *mockAnnotationRepo) UpdateDeviceAnnotations(ctx context.Context, hostName string, annotations *models.Annotations, version int) error {
+	current, ok := m.devices[hostName]
+	if !ok {
+		return errors.New("device not found")
+	}
+	if version != 0 && version != current.Version {
+		return interfaces.ErrVersionConflict
+	}
+	annotations.Version = current.Version + 1
+	cp := *annotations
+	m.devices[hostName] = &cp
+	return nil
+}
+func (m *mockAnnotationRepo) GetProjectAnnotations(ctx context.Context, name string) (*models.Annotations, error) {
+	return nil, errors.New("project not found")
+}
+func (m *mockAnnotationRepo) UpdateProjectAnnotations(ctx context.Context, name string, annotations *models.Annotations, version int) error {
+	return errors.New("project not found")
+}
+
+func TestPatchDeviceAnnotations(t *testing.T) {
+	repo := &mockAnnotationRepo{devices: map[string]*models.Annotations{
+		"kiosk-1": {Tags: []string{}, Attributes: map[string]string{}, Version: 1},
+	}}
+	h := NewAnnotationHandler(repo)
+	r := chi.NewRouter()
+	r.Get("/devices/{hostName}/annotations", h.GetDevice)
+	r.Patch("/devices/{hostName}/annotations", h.PatchDevice)
+
+	patch := func(host, body, ifMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPatch, "/devices/"+host+"/annotations", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/merge-patch+json")
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := patch("kiosk-1", `{"notes": "x"}`, ""); w.Code != http.StatusPreconditionRequired {
+		t.Fatalf("expected 428 got %d", w.Code)
+	}
+	if w := patch("kiosk-9", `{"notes": "x"}`, "*"); w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 got %d", w.Code)
+	}
+	if w := patch("kiosk-1", `{"tags": [" "]}`, `"1"`); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a blank tag, got %d", w.Code)
+	}
+
+	w := patch("kiosk-1", `{"tags": ["lobby", "beta", "lobby"], "internal_owner": "ops", "attributes": {"rack": "B2", "psu": "old"}}`, `"1"`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 got %d (%s)", w.Code, w.Body.String())
+	}
+	if etag := w.Header().Get("ETag"); etag != `"2"` {
+		t.Fatalf("expected ETag \"2\" got %s", etag)
+	}
+	if a := repo.devices["kiosk-1"]; strings.Join(a.Tags, ",") != "beta,lobby" || a.InternalOwner != "ops" || a.Attributes["rack"] != "B2" {
+		t.Fatalf("unexpected annotations %+v", a)
+	}
+
+	if w := patch("kiosk-1", `{"notes": "stale"}`, `"1"`); w.Code != http.StatusPreconditionFailed {
+		t.Fatalf("expected 412 got %d", w.Code)
+	}
+
+	w = patch("kiosk-1", `{"notes": "swap PSU", "attributes": {"psu": null}}`, `"2"`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 got %d (%s)", w.Code, w.Body.String())
+	}
+	var got models.Annotations
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatalf("invalid json: %v", err)
+	}
+	if got.Notes != "swap PSU" || len(got.Tags) != 2 || len(got.Attributes) != 1 || got.Attributes["rack"] != "B2" {
+		t.Fatalf("expected notes set, tags kept and psu removed, got %+v", got)
+	}
+}
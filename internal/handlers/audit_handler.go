@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"scm/internal/repository"
+)
+
+type AuditHandler struct {
+	repo repository.AuditRepository
+}
+
+func NewAuditHandler(repo repository.AuditRepository) *AuditHandler {
+	return &AuditHandler{repo: repo}
+}
+
+// @Tags Audit
+// @Summary List audit events
+// @Description Lists audit events, newest first. Admins only.
+// @Security BearerAuth
+// @Produce json
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Page size" default(20)
+// @Param actor_id query string false "Filter by actor (user ID)"
+// @Param action query string false "Filter by action (e.g. create, update, delete)"
+// @Param entity_type query string false "Filter by entity type (e.g. campaign, venue)"
+// @Param entity_id query string false "Filter by entity ID"
+// @Param request_id query string false "Filter by request ID"
+// @Param from query string false "Only events at or after this time (RFC3339)"
+// @Param to query string false "Only events before this time (RFC3339)"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/audit [get]
+func (h *AuditHandler) List(w http.ResponseWriter, r *http.Request) {
+	pagination, err := parsePaginationParams(r, 20, 100)
+	if err != nil {
+		writeJSONErrorResponse(w, http.StatusBadRequest, "invalid_pagination", "invalid pagination: "+err.Error())
+		return
+	}
+
+	q := r.URL.Query()
+	filters := repository.AuditFilters{}
+	for param, dst := range map[string]**string{
+		"actor_id":    &filters.ActorID,
+		"action":      &filters.Action,
+		"entity_type": &filters.EntityType,
+		"entity_id":   &filters.EntityID,
+		"request_id":  &filters.RequestID,
+	} {
+		if v := q.Get(param); v != "" {
+			*dst = &v
+		}
+	}
+	for param, dst := range map[string]**time.Time{
+		"from": &filters.From,
+		"to":   &filters.To,
+	} {
+		if v := q.Get(param); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				writeJSONErrorResponse(w, http.StatusBadRequest, "invalid_request", param+" must be an RFC3339 timestamp")
+				return
+			}
+			*dst = &t
+		}
+	}
+
+	events, err := h.repo.List(r.Context(), filters, pagination.limit, pagination.offset)
+	if err != nil {
+		writeJSONErrorResponse(w, http.StatusInternalServerError, "internal_error", "failed to list audit events: "+err.Error())
+		return
+	}
+	total, err := h.repo.Count(r.Context(), filters)
+	if err != nil {
+		writeJSONErrorResponse(w, http.StatusInternalServerError, "internal_error", "failed to count audit events: "+err.Error())
+		return
+	}
+
+	writePaginatedResponse(w, http.StatusOK, events, pagination.page, pagination.pageSize, total)
+}
@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"scm/internal/audit"
+	authmw "scm/internal/middleware"
+	"scm/internal/models"
+	"scm/internal/repository"
+)
+
+type mockAuditRepo struct {
+	events  []*models.AuditEvent
+	filters repository.AuditFilters
+}
+
+var _ repository.AuditRepository = (*mockAuditRepo)(nil)
+
+func (m *mockAuditRepo) Record(ctx context.Context, event *models.AuditEvent) error {
+	event.ID = int64(len(m.events) + 1)
+	m.events = append(m.events, event)
+	return nil
+}
+func (m *mockAuditRepo) List(ctx context.Context, filters repository.AuditFilters, limit int, offset int) ([]*models.AuditEvent, error) {
+	m.filters = filters
+	return m.events, nil
+}
+func (m *mockAuditRepo) Count(ctx context.Context, filters repository.AuditFilters) (int, error) {
+	return len(m.events), nil
+}
+
+func TestAuditListFilters(t *testing.T) {
+	repo := &mockAuditRepo{}
+	handler := NewAuditHandler(repo)
+
+	req := httptest.NewRequest(http.MethodGet, "/audit?entity_type=venue&entity_id=7&from=2024-01-01T00:00:00Z", nil)
+	w := httptest.NewRecorder()
+	handler.List(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if repo.filters.EntityType == nil || *repo.filters.EntityType != "venue" {
+		t.Fatalf("expected entity_type filter, got %+v", repo.filters)
+	}
+	if repo.filters.EntityID == nil || *repo.filters.EntityID != "7" {
+		t.Fatalf("expected entity_id filter, got %+v", repo.filters)
+	}
+	if repo.filters.From == nil || repo.filters.From.Year() != 2024 {
+		t.Fatalf("expected from filter, got %+v", repo.filters)
+	}
+	if repo.filters.ActorID != nil || repo.filters.To != nil {
+		t.Fatalf("unexpected filters: %+v", repo.filters)
+	}
+}
+
+func TestAuditListRejectsBadTimestamp(t *testing.T) {
+	handler := NewAuditHandler(&mockAuditRepo{})
+
+	req := httptest.NewRequest(http.MethodGet, "/audit?to=yesterday", nil)
+	w := httptest.NewRecorder()
+	handler.List(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", w.Code)
+	}
+}
+
+func TestAuditMiddlewareRecordsOnce(t *testing.T) {
+	repo := &mockAuditRepo{}
+
+	r := chi.NewRouter()
+	r.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := context.WithValue(r.Context(), authmw.CtxUserID, "user-1")
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	})
+	r.Use(audit.Middleware(repo))
+	r.Post("/widgets/{id}/publish", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	r.Put("/venues/{id}", func(w http.ResponseWriter, r *http.Request) {
+		audit.Record(r.Context(), repo, "update", "venue", chi.URLParam(r, "id"),
+			map[string]any{"name": "old"}, map[string]any{"name": "new"})
+		w.WriteHeader(http.StatusOK)
+	})
+	r.Delete("/venues/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	for _, tc := range []struct{ method, path string }{
+		{http.MethodPost, "/widgets/5/publish"},
+		{http.MethodPut, "/venues/9"},
+		{http.MethodDelete, "/venues/9"},
+	} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(tc.method, tc.path, nil))
+	}
+
+	if len(repo.events) != 2 {
+		t.Fatalf("expected 2 events, got %d", len(repo.events))
+	}
+
+	generic := repo.events[0]
+	if generic.Action != "post" || generic.EntityType != "widgets/publish" || generic.EntityID != "5" || generic.ActorID != "user-1" {
+		t.Fatalf("unexpected generic event: %+v", generic)
+	}
+
+	update := repo.events[1]
+	if update.Action != "update" || update.EntityID != "9" || update.Method != http.MethodPut {
+		t.Fatalf("unexpected update event: %+v", update)
+	}
+	var diff struct {
+		Changes map[string]map[string]any `json:"changes"`
+	}
+	if err := json.Unmarshal(update.Diff, &diff); err != nil {
+		t.Fatalf("invalid diff: %v", err)
+	}
+	if diff.Changes["name"]["to"] != "new" {
+		t.Fatalf("expected name change, got %s", update.Diff)
+	}
+}
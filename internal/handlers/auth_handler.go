@@ -7,7 +7,7 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"errors"
-	"log"
+	"log/slog"
 	"net/http"
 	"net/url"
 	"strconv"
@@ -232,7 +232,7 @@ func (h *AuthHandler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
 		CreatedAt: time.Now().UTC(),
 	}
 	if err := h.resets.Create(r.Context(), prt); err != nil {
-		log.Printf("forgot-password: failed to create reset token for user_id=%s: %v", u.ID, err)
+		slog.ErrorContext(r.Context(), "forgot-password: failed to create reset token", "reset_user_id", u.ID, "error", err)
 	}
 
 	subject := "Reset your password"
@@ -264,8 +264,8 @@ func (h *AuthHandler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
 		"<p style=\"margin:0 0 16px 0; font-family:ui-monospace, SFMono-Regular, Menlo, Monaco, Consolas, 'Liberation Mono', 'Courier New', monospace;\">" + rawToken + "</p>" +
 		"<p style=\"margin:0; color:#444;\">This token expires in " + strconv.FormatInt(expiresInMinutes, 10) + " minutes.</p>" +
 		"</body></html>"
-	if err := h.mailer.Send(u.Email, subject, body); err != nil {
-		log.Printf("forgot-password: failed to send email to %s: %v", u.Email, err)
+	if err := h.mailer.Send(r.Context(), u.Email, subject, body); err != nil {
+		slog.ErrorContext(r.Context(), "forgot-password: failed to send email", "to", u.Email, "error", err)
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"bytes"
+	"context"
 	"crypto/sha256"
 	"database/sql"
 	"encoding/hex"
@@ -105,7 +106,7 @@ func TestResetPasswordSuccess(t *testing.T) {
 	}
 }
 
-func (n *noopMailer) Send(to string, subject string, body string) error { return nil }
+func (n *noopMailer) Send(ctx context.Context, to string, subject string, body string) error { return nil }
 
 func TestSignupSuccess(t *testing.T) {
 	db, mock, err := sqlmock.New()
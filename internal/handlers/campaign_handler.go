@@ -2,20 +2,17 @@
 package handlers
 
 import (
-    "database/sql"
     "encoding/json"
     "errors"
     "fmt"
-    "log"
+    "log/slog"
     "net/http"
-    "time"
 
     "github.com/go-chi/chi/v5"
-    "github.com/go-playground/validator/v10"
-    "github.com/google/uuid"
-    "github.com/lib/pq"
     "scm/internal/interfaces"
     "scm/internal/models"
+    "scm/internal/repository"
+    "scm/internal/services"
 )
 
 func writeJSONErrorCampaign(w http.ResponseWriter, status int, code string, message string) {
@@ -27,15 +24,37 @@ func writeJSONErrorCampaign(w http.ResponseWriter, status int, code string, mess
     })
 }
 
+// CampaignHandler adapts CampaignService to HTTP.
 type CampaignHandler struct {
-    repo      interfaces.CampaignRepository
-    validator *validator.Validate
+    service *services.CampaignService
 }
 
-func NewCampaignHandler(repo interfaces.CampaignRepository) *CampaignHandler {
-    return &CampaignHandler{
-        repo:      repo,
-        validator: validator.New(),
+func NewCampaignHandler(service *services.CampaignService) *CampaignHandler {
+    return &CampaignHandler{service: service}
+}
+
+// writeCampaignError answers the errors CampaignService returns for every operation.
+func writeCampaignError(w http.ResponseWriter, r *http.Request, err error, code string, message string) {
+    var invalid *services.ValidationError
+    var transition *services.StatusTransitionError
+    var blocked *interfaces.DeletionBlockedError
+    var restoreBlocked *interfaces.RestoreBlockedError
+    switch {
+    case errors.As(err, &invalid):
+        writeJSONErrorResponse(w, http.StatusBadRequest, invalid.Code, invalid.Message)
+    case errors.Is(err, services.ErrCampaignNotFound):
+        writeJSONErrorResponse(w, http.StatusNotFound, "campaign_not_found", "Campaign not found")
+    case errors.Is(err, services.ErrForbidden):
+        writeJSONErrorResponse(w, http.StatusForbidden, "forbidden", "Cascade delete requires an admin")
+    case errors.As(err, &transition):
+        writeJSONErrorResponse(w, http.StatusConflict, "invalid_status_transition", fmt.Sprintf("Cannot change campaign status from %s to %s", transition.From, transition.To))
+    case errors.As(err, &blocked):
+        writeDeletionBlocked(w, blocked)
+    case errors.As(err, &restoreBlocked):
+        writeJSONErrorResponse(w, http.StatusConflict, "restore_blocked", fmt.Sprintf("Cannot restore %s: its %s is deleted", restoreBlocked.Resource, restoreBlocked.Parent))
+    default:
+        slog.ErrorContext(r.Context(), "campaign request failed", "error", err)
+        writeJSONErrorResponse(w, http.StatusInternalServerError, code, message)
     }
 }
 
@@ -50,45 +69,18 @@ func NewCampaignHandler(repo interfaces.CampaignRepository) *CampaignHandler {
 // @Failure 500 {object} map[string]interface{}
 // @Router /api/v1/campaigns/ [post]
 func (h *CampaignHandler) CreateCampaign(w http.ResponseWriter, r *http.Request) {
-    log.Println("=== CreateCampaign handler called ===")
     var req models.CreateCampaignRequest
     if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
         writeJSONErrorCampaign(w, http.StatusBadRequest, "invalid_request", "Invalid request body")
         return
     }
 
-    if err := h.validator.Struct(req); err != nil {
-        writeJSONErrorCampaign(w, http.StatusBadRequest, "validation_error", err.Error())
-        return
-    }
-
-    campaign := &models.Campaign{
-        Name:         req.Name,
-        Status:       models.CampaignStatusDraft,
-        Cities:       req.Cities,
-        StartDate:    req.StartDate,
-        EndDate:      req.EndDate,
-        Budget:       req.Budget,
-        AdvertiserID: req.AdvertiserID,
-        CreatedAt:    time.Now().UTC(),
-        UpdatedAt:    time.Now().UTC(),
-    }
-    log.Println("Campaign created:", campaign)
-    if err := h.repo.Create(r.Context(), campaign); err != nil {
-        var pqErr *pq.Error
-        if errors.As(err, &pqErr) {
-            if pqErr.Code == "23503" {
-                if pqErr.Constraint == "campaigns_advertiser_id_fkey" {
-                    writeJSONErrorCampaign(w, http.StatusBadRequest, "invalid_advertiser_id", "Advertiser not found")
-                    return
-                }
-                writeJSONErrorCampaign(w, http.StatusBadRequest, "foreign_key_violation", "Invalid reference")
-                return
-            }
-        }
-        writeJSONErrorCampaign(w, http.StatusInternalServerError, "create_campaign_failed", "Failed to create campaign")
+    campaign, err := h.service.Create(r.Context(), req)
+    if err != nil {
+        writeCampaignError(w, r, err, "create_campaign_failed", "Failed to create campaign")
         return
     }
+    slog.InfoContext(r.Context(), "campaign created", "campaign_id", campaign.ID)
 
     w.Header().Set("Content-Type", "application/json")
     w.WriteHeader(http.StatusCreated)
@@ -112,16 +104,13 @@ func (h *CampaignHandler) GetCampaign(w http.ResponseWriter, r *http.Request) {
         return
     }
 
-    campaign, err := h.repo.GetByID(r.Context(), campaignID)
+    campaign, err := h.service.Get(r.Context(), campaignID)
     if err != nil {
-        if err == sql.ErrNoRows {
-            writeJSONErrorResponse(w, http.StatusNotFound, "campaign_not_found", "Campaign not found")
-            return
-        }
-        writeJSONErrorResponse(w, http.StatusInternalServerError, "get_campaign_failed", "Failed to fetch campaign")
+        writeCampaignError(w, r, err, "get_campaign_failed", "Failed to fetch campaign")
         return
     }
 
+    setETag(w, campaign.Version)
     w.Header().Set("Content-Type", "application/json")
     json.NewEncoder(w).Encode(campaign)
 }
@@ -130,49 +119,27 @@ func (h *CampaignHandler) GetCampaign(w http.ResponseWriter, r *http.Request) {
 // @Summary List campaigns
 // @Security BearerAuth
 // @Produce json
+// @Param include_deleted query bool false "Include soft-deleted campaigns"
+// @Param sort query string false "Sort fields, e.g. -budget,name"
+// @Param cursor query string false "next_cursor or prev_cursor of a previous page"
 // @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
 // @Failure 500 {object} map[string]interface{}
 // @Router /api/v1/campaigns/ [get]
 func (h *CampaignHandler) ListCampaigns(w http.ResponseWriter, r *http.Request) {
-    log.Println("=== ListCampaigns handler called ===")
-
-	p, err := parsePaginationParams(r, 50, 200)
+	p, err := parseListParams(r, 50, 200, repository.CampaignSort)
 	if err != nil {
-		writeJSONErrorResponse(w, http.StatusBadRequest, "validation_error", "invalid pagination parameters")
+		writeJSONErrorResponse(w, http.StatusBadRequest, "validation_error", "invalid pagination parameters: "+err.Error())
 		return
 	}
 
-	filter := interfaces.CampaignFilter{Limit: p.limit, Offset: p.offset}
-
-	total, err := h.repo.Count(r.Context(), filter)
+	includeDeleted, err := parseIncludeDeleted(r)
 	if err != nil {
-		writeJSONErrorResponse(w, http.StatusInternalServerError, "list_campaigns_failed", "Failed to list campaigns")
+		writeJSONErrorResponse(w, http.StatusBadRequest, "validation_error", "include_deleted must be a boolean")
 		return
 	}
 
-    summary, err := h.repo.Summary(r.Context(), filter)
-    if err != nil {
-        writeJSONErrorResponse(w, http.StatusInternalServerError, "list_campaigns_failed", "Failed to list campaigns")
-        return
-    }
-    
-    campaigns, err := h.repo.List(r.Context(), filter)
-    if err != nil {
-        writeJSONErrorResponse(w, http.StatusInternalServerError, "list_campaigns_failed", "Failed to list campaigns")
-        return
-    }
-
-    if campaigns == nil {
-        campaigns = []*models.Campaign{} // Return empty array instead of null
-    }
-
-	data := map[string]any{
-		"active_campaign_count": summary.ActiveCampaignCount,
-		"total_budget":          summary.TotalBudget,
-		"total_impression":      summary.TotalImpression,
-		"campaigns":             campaigns,
-	}
-	writePaginatedResponse(w, http.StatusOK, data, p.page, p.pageSize, total)
+	h.listCampaigns(w, r, p, interfaces.CampaignFilter{Page: p.listPage(), IncludeDeleted: includeDeleted})
 }
 
 // @Tags Campaigns
@@ -180,6 +147,9 @@ func (h *CampaignHandler) ListCampaigns(w http.ResponseWriter, r *http.Request)
 // @Security BearerAuth
 // @Produce json
 // @Param advertiserID path string true "Advertiser ID"
+// @Param include_deleted query bool false "Include soft-deleted campaigns"
+// @Param sort query string false "Sort fields, e.g. -budget,name"
+// @Param cursor query string false "next_cursor or prev_cursor of a previous page"
 // @Success 200 {object} map[string]interface{}
 // @Failure 400 {object} map[string]interface{}
 // @Failure 500 {object} map[string]interface{}
@@ -191,174 +161,258 @@ func (h *CampaignHandler) ListCampaignsByAdvertiser(w http.ResponseWriter, r *ht
         return
     }
 
-    if _, err := uuid.Parse(advertiserID); err != nil {
-        writeJSONErrorResponse(w, http.StatusBadRequest, "validation_error", "advertiserID must be a valid UUID")
-        return
-    }
-
-	p, err := parsePaginationParams(r, 50, 200)
+	p, err := parseListParams(r, 50, 200, repository.CampaignSort)
 	if err != nil {
-		writeJSONErrorResponse(w, http.StatusBadRequest, "validation_error", "invalid pagination parameters")
+		writeJSONErrorResponse(w, http.StatusBadRequest, "validation_error", "invalid pagination parameters: "+err.Error())
 		return
 	}
 
-    filter := interfaces.CampaignFilter{
-        AdvertiserID: advertiserID,
-        Limit:        p.limit,
-        Offset:       p.offset,
-    }
-
-	total, err := h.repo.Count(r.Context(), filter)
+	includeDeleted, err := parseIncludeDeleted(r)
 	if err != nil {
-		writeJSONErrorResponse(w, http.StatusInternalServerError, "list_campaigns_failed", "Failed to list campaigns")
+		writeJSONErrorResponse(w, http.StatusBadRequest, "validation_error", "include_deleted must be a boolean")
 		return
 	}
 
-    summary, err := h.repo.Summary(r.Context(), filter)
-    if err != nil {
-        writeJSONErrorResponse(w, http.StatusInternalServerError, "list_campaigns_failed", "Failed to list campaigns")
-        return
-    }
+    h.listCampaigns(w, r, p, interfaces.CampaignFilter{
+        AdvertiserID:   advertiserID,
+        IncludeDeleted: includeDeleted,
+        Page:           p.listPage(),
+    })
+}
 
-    campaigns, err := h.repo.List(r.Context(), filter)
+func (h *CampaignHandler) listCampaigns(w http.ResponseWriter, r *http.Request, p listParams, filter interfaces.CampaignFilter) {
+    list, err := h.service.List(r.Context(), filter)
     if err != nil {
-        writeJSONErrorResponse(w, http.StatusInternalServerError, "list_campaigns_failed", "Failed to list campaigns")
+        writeCampaignError(w, r, err, "list_campaigns_failed", "Failed to list campaigns")
         return
     }
+    writeListResponse(w, p, list.Campaigns, list.Total, campaignListData(list.Summary))
+}
 
-    if campaigns == nil {
-        campaigns = []*models.Campaign{}
+// campaignListData wraps a page of campaigns with the summary of the whole list.
+func campaignListData(summary *models.CampaignSummary) func([]*models.Campaign) any {
+    return func(campaigns []*models.Campaign) any {
+        return map[string]any{
+            "active_campaign_count": summary.ActiveCampaignCount,
+            "total_budget":          summary.TotalBudget,
+            "total_impression":      summary.TotalImpression,
+            "campaigns":             campaigns,
+        }
     }
-
-	data := map[string]any{
-		"active_campaign_count": summary.ActiveCampaignCount,
-		"total_budget":          summary.TotalBudget,
-		"total_impression":      summary.TotalImpression,
-		"campaigns":             campaigns,
-	}
-	writePaginatedResponse(w, http.StatusOK, data, p.page, p.pageSize, total)
 }
 
 // @Tags Campaigns
-// @Summary Update campaign
+// @Summary Replace campaign
+// @Description Replaces the campaign's editable fields; all of them are required except cities.
+// @Description Status changes must follow the campaign lifecycle (see the README); completed
+// @Description campaigns cannot change status.
 // @Security BearerAuth
 // @Accept json
 // @Produce json
 // @Param id path string true "Campaign ID"
-// @Param body body models.UpdateCampaignRequest true "Update campaign request"
+// @Param If-Match header string true "ETag from a previous GET"
+// @Param body body models.ReplaceCampaignRequest true "Campaign"
 // @Success 200 {object} models.Campaign
 // @Failure 400 {object} map[string]interface{}
 // @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Failure 412 {object} map[string]interface{}
+// @Failure 428 {object} map[string]interface{}
 // @Failure 500 {object} map[string]interface{}
 // @Router /api/v1/campaigns/{id}/ [put]
 func (h *CampaignHandler) UpdateCampaign(w http.ResponseWriter, r *http.Request) {
-    id := chi.URLParam(r, "id")
-    if id == "" {
-        writeJSONErrorResponse(w, http.StatusBadRequest, "validation_error", "Campaign ID is required")
+    id, ifMatch, _, ok := h.loadForUpdate(w, r)
+    if !ok {
         return
     }
 
-    var req models.UpdateCampaignRequest
+    var req models.ReplaceCampaignRequest
     if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
         writeJSONErrorResponse(w, http.StatusBadRequest, "invalid_request", "Invalid request body")
         return
     }
 
-    if err := h.validator.Struct(req); err != nil {
-        writeJSONErrorResponse(w, http.StatusBadRequest, "validation_error", err.Error())
+    h.replaceCampaign(w, r, id, req, ifMatch)
+}
+
+// @Tags Campaigns
+// @Summary Patch campaign
+// @Description Applies a JSON merge patch (RFC 7396) to the campaign's editable fields.
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Campaign ID"
+// @Param If-Match header string true "ETag from a previous GET"
+// @Param body body models.ReplaceCampaignRequest true "Merge patch"
+// @Success 200 {object} models.Campaign
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Failure 412 {object} map[string]interface{}
+// @Failure 415 {object} map[string]interface{}
+// @Failure 428 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/campaigns/{id}/ [patch]
+func (h *CampaignHandler) PatchCampaign(w http.ResponseWriter, r *http.Request) {
+    id, ifMatch, current, ok := h.loadForUpdate(w, r)
+    if !ok {
         return
     }
 
-    // First, get the existing campaign
-    existingCampaign, err := h.repo.GetByID(r.Context(), id)
-    if err != nil {
-        if err == sql.ErrNoRows {
-            writeJSONErrorResponse(w, http.StatusNotFound, "campaign_not_found", "Campaign not found")
-            return
-        }
-        writeJSONErrorResponse(w, http.StatusInternalServerError, "get_campaign_failed", "Failed to get campaign")
+    var req models.ReplaceCampaignRequest
+    if err := decodeMergePatch(r, models.NewReplaceCampaignRequest(current), &req); err != nil {
+        writeMergePatchError(w, err)
         return
     }
-    // Update the existing campaign with the new values
-    if req.Name != nil {
-        existingCampaign.Name = *req.Name
-    }
-    if req.Status != nil {
-        existingCampaign.Status = models.CampaignStatus(*req.Status)
-    }
-    if req.Cities != nil {
-        existingCampaign.Cities = *req.Cities
-    }
-    if req.StartDate != nil {
-        existingCampaign.StartDate = *req.StartDate
-    }
-    if req.EndDate != nil {
-        existingCampaign.EndDate = *req.EndDate
+
+    h.replaceCampaign(w, r, id, req, ifMatch)
+}
+
+// loadForUpdate reads the id and If-Match of an update request and the campaign it
+// targets, answering 404/412/428 itself.
+func (h *CampaignHandler) loadForUpdate(w http.ResponseWriter, r *http.Request) (string, int, *models.Campaign, bool) {
+    id := chi.URLParam(r, "id")
+    if id == "" {
+        writeJSONErrorResponse(w, http.StatusBadRequest, "validation_error", "Campaign ID is required")
+        return "", 0, nil, false
     }
-    if req.Budget != nil {
-        existingCampaign.Budget = *req.Budget
+
+    ifMatch, ok := requireIfMatch(w, r)
+    if !ok {
+        return "", 0, nil, false
     }
 
-    // Update the campaign in the database
-    err = h.repo.Update(r.Context(), id, existingCampaign)
+    current, err := h.service.Get(r.Context(), id)
     if err != nil {
-        writeJSONErrorResponse(w, http.StatusInternalServerError, "update_campaign_failed", "Failed to update campaign")
-        return
+        writeCampaignError(w, r, err, "get_campaign_failed", "Failed to get campaign")
+        return "", 0, nil, false
+    }
+    if ifMatchFails(ifMatch, current.Version) {
+        writePreconditionFailed(w, current, current.Version)
+        return "", 0, nil, false
     }
+    return id, ifMatch, current, true
+}
 
-    // Get the updated campaign to return
-    updatedCampaign, err := h.repo.GetByID(r.Context(), id)
+func (h *CampaignHandler) replaceCampaign(w http.ResponseWriter, r *http.Request, id string, req models.ReplaceCampaignRequest, ifMatch int) {
+    updatedCampaign, err := h.service.Replace(r.Context(), id, req, ifMatch)
     if err != nil {
-        if err == sql.ErrNoRows {
-            writeJSONErrorResponse(w, http.StatusNotFound, "campaign_not_found", "Campaign not found")
-            return
+        if errors.Is(err, interfaces.ErrVersionConflict) {
+            if current, err := h.service.Get(r.Context(), id); err == nil {
+                writePreconditionFailed(w, current, current.Version)
+                return
+            }
         }
-        writeJSONErrorResponse(w, http.StatusInternalServerError, "get_campaign_failed", "Failed to get updated campaign")
+        writeCampaignError(w, r, err, "update_campaign_failed", "Failed to update campaign")
         return
     }
 
+    setETag(w, updatedCampaign.Version)
     w.Header().Set("Content-Type", "application/json")
     json.NewEncoder(w).Encode(updatedCampaign)
 }
 
 // @Tags Campaigns
 // @Summary Delete campaign
+// @Description Soft-deletes the campaign. With cascade=true (admins only) the campaign, its
+// @Description creatives and their stored files are removed permanently.
 // @Security BearerAuth
 // @Produce json
 // @Param id path string true "Campaign ID"
+// @Param cascade query bool false "Permanently delete the campaign and its creatives"
 // @Success 200 {object} map[string]interface{}
 // @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
 // @Failure 404 {object} map[string]interface{}
 // @Failure 409 {object} map[string]interface{}
 // @Failure 500 {object} map[string]interface{}
 // @Router /api/v1/campaigns/{id}/ [delete]
 func (h *CampaignHandler) DeleteCampaign(w http.ResponseWriter, r *http.Request) {
     campaignID := chi.URLParam(r, "id")
-    log.Println("Deleting campaign with ID:", campaignID)
-
     if campaignID == "" {
         writeJSONErrorResponse(w, http.StatusBadRequest, "validation_error", "Campaign ID is required")
         return
     }
 
-    err := h.repo.Delete(r.Context(), campaignID)
+    cascade, err := parseCascade(r)
     if err != nil {
-        var blocked *interfaces.DeletionBlockedError
-        if errors.As(err, &blocked) {
-            msg := fmt.Sprintf("Cannot delete %s: referenced by", blocked.Resource)
-            for k, v := range blocked.References {
-                msg += fmt.Sprintf(" %d %s", v, k)
-            }
-            writeJSONErrorResponse(w, http.StatusConflict, "delete_blocked", msg)
+        writeJSONErrorResponse(w, http.StatusBadRequest, "validation_error", "cascade must be a boolean")
+        return
+    }
+    if cascade {
+        result, err := h.service.DeleteCascade(r.Context(), campaignID)
+        if err != nil {
+            writeCampaignError(w, r, err, "delete_campaign_failed", "Failed to delete campaign")
             return
         }
-        if err == sql.ErrNoRows {
-            writeJSONErrorResponse(w, http.StatusNotFound, "campaign_not_found", "Campaign not found")
+        writeCascadeDeleted(w, result.Deleted, result.StorageErr)
+        return
+    }
+
+    if err := h.service.Delete(r.Context(), campaignID); err != nil {
+        writeCampaignError(w, r, err, "delete_campaign_failed", "Failed to delete campaign")
+        return
+    }
+
+    writeJSONMessage(w, http.StatusOK, "campaign deleted successfully")
+}
+
+// @Tags Campaigns
+// @Summary List campaign dependencies
+// @Description Lists the creatives and stored files a cascade delete would remove, including
+// @Description soft-deleted ones.
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Campaign ID"
+// @Success 200 {object} models.DeletionDependencies
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/campaigns/{id}/dependencies [get]
+func (h *CampaignHandler) GetCampaignDependencies(w http.ResponseWriter, r *http.Request) {
+    campaignID := chi.URLParam(r, "id")
+    if campaignID == "" {
+        writeJSONErrorResponse(w, http.StatusBadRequest, "validation_error", "Campaign ID is required")
+        return
+    }
+
+    deps, err := h.service.Dependencies(r.Context(), campaignID)
+    if err != nil {
+        writeCampaignError(w, r, err, "get_dependencies_failed", "Failed to list campaign dependencies")
+        return
+    }
+    writeDependencies(w, deps)
+}
+
+// @Tags Campaigns
+// @Summary Restore campaign
+// @Description Restores a soft-deleted campaign. Its advertiser must not be deleted.
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Campaign ID"
+// @Success 200 {object} models.Campaign
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/campaigns/{id}/restore [post]
+func (h *CampaignHandler) RestoreCampaign(w http.ResponseWriter, r *http.Request) {
+    campaignID := chi.URLParam(r, "id")
+    if campaignID == "" {
+        writeJSONErrorResponse(w, http.StatusBadRequest, "validation_error", "Campaign ID is required")
+        return
+    }
+
+    campaign, err := h.service.Restore(r.Context(), campaignID)
+    if err != nil {
+        if errors.Is(err, services.ErrCampaignNotFound) {
+            writeJSONErrorResponse(w, http.StatusNotFound, "campaign_not_found", "Deleted campaign not found")
             return
         }
-        writeJSONErrorResponse(w, http.StatusInternalServerError, "delete_campaign_failed", "Failed to delete campaign")
+        writeCampaignError(w, r, err, "restore_campaign_failed", "Failed to restore campaign")
         return
     }
 
-    writeJSONMessage(w, http.StatusOK, "campaign deleted successfully")
+    w.Header().Set("Content-Type", "application/json")
+    json.NewEncoder(w).Encode(campaign)
 }
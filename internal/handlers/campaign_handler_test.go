@@ -2,7 +2,6 @@ package handlers
 
 import (
 	"context"
-	"database/sql"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
@@ -10,19 +9,43 @@ import (
 	"time"
 
 	"github.com/go-chi/chi/v5"
-	"scm/internal/interfaces"
+	"github.com/google/uuid"
 	"scm/internal/models"
+	"scm/internal/repository/memory"
+	"scm/internal/services"
 )
 
-type mockCampaignRepo struct{}
+// newCampaignStore returns a memory store holding one advertiser and one of its campaigns.
+func newCampaignStore(t *testing.T) (*memory.Store, *models.Advertiser, *models.Campaign) {
+	t.Helper()
+	ctx := context.Background()
+	store := memory.NewStore()
+	owner := &models.User{ID: uuid.NewString(), Email: "owner@example.com", UserName: "owner"}
+	if err := memory.NewUserRepository(store).Create(ctx, owner); err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	advertiser := &models.Advertiser{Name: "Acme", CreatedBy: owner.ID}
+	if err := memory.NewAdvertiserRepository(store).Create(ctx, advertiser); err != nil {
+		t.Fatalf("create advertiser: %v", err)
+	}
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	campaign := &models.Campaign{
+		AdvertiserID: advertiser.ID, Name: "Spring", Status: models.CampaignStatusDraft,
+		StartDate: start, EndDate: start.AddDate(0, 1, 0), Budget: 100,
+	}
+	if err := memory.NewCampaignRepository(store).Create(ctx, campaign); err != nil {
+		t.Fatalf("create campaign: %v", err)
+	}
+	return store, advertiser, campaign
+}
 
 func TestListCampaignsByAdvertiserReturnsJSON(t *testing.T) {
-	h := NewCampaignHandler(&mockCampaignRepo{})
+	store, advertiser, campaign := newCampaignStore(t)
+	h := NewCampaignHandler(services.NewCampaignService(memory.NewCampaignRepository(store), nil, nil))
 	r := chi.NewRouter()
 	r.Get("/campaigns/advertiser/{advertiserID}", h.ListCampaignsByAdvertiser)
 
-	// valid UUID
-	req := httptest.NewRequest(http.MethodGet, "/campaigns/advertiser/550e8400-e29b-41d4-a716-446655440000", nil)
+	req := httptest.NewRequest(http.MethodGet, "/campaigns/advertiser/"+advertiser.ID, nil)
 	w := httptest.NewRecorder()
 	r.ServeHTTP(w, req)
 
@@ -32,47 +55,26 @@ func TestListCampaignsByAdvertiserReturnsJSON(t *testing.T) {
 	if ct := w.Header().Get("Content-Type"); ct != "application/json" {
 		t.Fatalf("expected application/json got %q", ct)
 	}
-	var resp map[string]any
+	var resp struct {
+		Data struct {
+			Campaigns []models.Campaign `json:"campaigns"`
+		} `json:"data"`
+	}
 	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
 		t.Fatalf("invalid json: %v", err)
 	}
-	data, ok := resp["data"].(map[string]any)
-	if !ok {
-		t.Fatalf("expected data object, got %v", resp)
-	}
-	if _, ok := data["campaigns"]; !ok {
-		t.Fatalf("expected campaigns field, got %v", resp)
+	if len(resp.Data.Campaigns) != 1 || resp.Data.Campaigns[0].ID != campaign.ID {
+		t.Fatalf("expected campaign %s, got %s", campaign.ID, w.Body.String())
 	}
 }
 
-var _ interfaces.CampaignRepository = (*mockCampaignRepo)(nil)
-
-func (m *mockCampaignRepo) Create(ctx context.Context, campaign *models.Campaign) error { return nil }
-func (m *mockCampaignRepo) GetByID(ctx context.Context, id string) (*models.Campaign, error) {
-	return nil, sql.ErrNoRows
-}
-func (m *mockCampaignRepo) List(ctx context.Context, filter interfaces.CampaignFilter) ([]*models.Campaign, error) {
-	return []*models.Campaign{}, nil
-}
-func (m *mockCampaignRepo) Count(ctx context.Context, filter interfaces.CampaignFilter) (int, error) { return 0, nil }
-func (m *mockCampaignRepo) Summary(ctx context.Context, filter interfaces.CampaignFilter) (*models.CampaignSummary, error) {
-	return &models.CampaignSummary{}, nil
-}
-func (m *mockCampaignRepo) ActivateScheduledStartingOn(ctx context.Context, startDate time.Time, scheduledStatus string, timeZone string) (int64, error) {
-	return 0, nil
-}
-func (m *mockCampaignRepo) CompleteActiveEndedBefore(ctx context.Context, now time.Time, activeStatus string, completedStatus string, timeZone string) (int64, error) {
-	return 0, nil
-}
-func (m *mockCampaignRepo) Update(ctx context.Context, id string, campaign *models.Campaign) error { return nil }
-func (m *mockCampaignRepo) Delete(ctx context.Context, id string) error                         { return nil }
-
 func TestGetCampaignNotFoundReturnsJSON(t *testing.T) {
-	h := NewCampaignHandler(&mockCampaignRepo{})
+	store, _, _ := newCampaignStore(t)
+	h := NewCampaignHandler(services.NewCampaignService(memory.NewCampaignRepository(store), nil, nil))
 	r := chi.NewRouter()
 	r.Get("/campaigns/{id}", h.GetCampaign)
 
-	req := httptest.NewRequest(http.MethodGet, "/campaigns/c1", nil)
+	req := httptest.NewRequest(http.MethodGet, "/campaigns/"+uuid.NewString(), nil)
 	w := httptest.NewRecorder()
 	r.ServeHTTP(w, req)
 
@@ -90,3 +92,33 @@ func TestGetCampaignNotFoundReturnsJSON(t *testing.T) {
 		t.Fatalf("expected error field, got %v", resp)
 	}
 }
+
+func TestRestoreCampaignWithDeletedAdvertiserReturns409(t *testing.T) {
+	ctx := context.Background()
+	store, advertiser, campaign := newCampaignStore(t)
+	campaigns := memory.NewCampaignRepository(store)
+	if err := campaigns.Delete(ctx, campaign.ID); err != nil {
+		t.Fatalf("delete campaign: %v", err)
+	}
+	if err := memory.NewAdvertiserRepository(store).Delete(ctx, advertiser.ID); err != nil {
+		t.Fatalf("delete advertiser: %v", err)
+	}
+	h := NewCampaignHandler(services.NewCampaignService(campaigns, nil, nil))
+	r := chi.NewRouter()
+	r.Post("/campaigns/{id}/restore", h.RestoreCampaign)
+
+	req := httptest.NewRequest(http.MethodPost, "/campaigns/"+campaign.ID+"/restore", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusConflict {
+		t.Fatalf("expected 409 got %d (%s)", w.Code, w.Body.String())
+	}
+	var resp map[string]any
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("invalid json: %v", err)
+	}
+	if resp["error"] == nil {
+		t.Fatalf("expected error field, got %v", resp)
+	}
+}
@@ -1,53 +1,64 @@
 package handlers
 
 import (
-	"database/sql"
 	"encoding/json"
-	"log"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
 	"mime/multipart"
 	"net/http"
-	"path/filepath"
 	"strings"
 	"time"
 
-    "github.com/aws/aws-sdk-go-v2/aws"
-    "github.com/aws/aws-sdk-go-v2/feature/s3/manager"
-    "github.com/aws/aws-sdk-go-v2/service/s3"
     "github.com/go-chi/chi/v5"
-    "github.com/go-playground/validator/v10"
-    "github.com/google/uuid"
-    "scm/internal/config"
     "scm/internal/interfaces"
     "scm/internal/models"
     "scm/internal/repository"
+    "scm/internal/services"
 )
 
 
 
+// CreativeHandler adapts CreativeService to HTTP.
 type CreativeHandler struct {
-    repo      repository.CreativeRepository
-    campaignRepo interfaces.CampaignRepository
-    s3Client  *s3.Client
-    validator *validator.Validate
-    bucket    string
-    publicBaseURL string
+    service *services.CreativeService
 }
 
 
-func NewCreativeHandler(repo repository.CreativeRepository, campaignRepo interfaces.CampaignRepository, s3Config *config.S3Config) *CreativeHandler {
-    return &CreativeHandler{
-        repo:      repo,
-        campaignRepo: campaignRepo,
-        s3Client:  s3Config.Client,
-        bucket:    s3Config.Bucket,
-        publicBaseURL: s3Config.PublicBaseURL,
-        validator: validator.New(),
+func NewCreativeHandler(service *services.CreativeService) *CreativeHandler {
+    return &CreativeHandler{service: service}
+}
+
+// writeCreativeError answers the errors CreativeService returns for every operation.
+func writeCreativeError(w http.ResponseWriter, r *http.Request, err error, code string, message string) {
+    var invalid *services.ValidationError
+    var storage *services.StorageError
+    var blocked *interfaces.RestoreBlockedError
+    switch {
+    case errors.As(err, &invalid):
+        writeJSONErrorResponse(w, http.StatusBadRequest, invalid.Code, invalid.Message)
+    case errors.Is(err, services.ErrCreativeNotFound):
+        writeJSONErrorResponse(w, http.StatusNotFound, "creative_not_found", "Creative not found")
+    case errors.As(err, &storage):
+        slog.ErrorContext(r.Context(), "failed to store creative file", "error", err)
+        writeJSONErrorResponse(w, http.StatusBadGateway, "upload_failed", "Failed to upload file")
+    case errors.As(err, &blocked):
+        writeJSONErrorResponse(w, http.StatusConflict, "restore_blocked", fmt.Sprintf("Cannot restore %s: its %s is deleted", blocked.Resource, blocked.Parent))
+    default:
+        slog.ErrorContext(r.Context(), "creative request failed", "error", err)
+        writeJSONErrorResponse(w, http.StatusInternalServerError, code, message)
     }
 }
 
-// generateUUID generates a new UUID
-func generateUUID() string {
-    return uuid.New().String()
+// creativeFile describes an uploaded multipart file to CreativeService.
+func creativeFile(fh *multipart.FileHeader) services.CreativeFile {
+    return services.CreativeFile{
+        Name:        fh.Filename,
+        ContentType: fh.Header.Get("Content-Type"),
+        Size:        fh.Size,
+        Open:        func() (io.ReadCloser, error) { return fh.Open() },
+    }
 }
 
 func parseFormList(r *http.Request, key string) []string {
@@ -93,130 +104,30 @@ func (h *CreativeHandler) UploadCreative(w http.ResponseWriter, r *http.Request)
         return
     }
 
-    campaignID := r.FormValue("campaign_id")
-    if campaignID == "" {
-        writeJSONErrorResponse(w, http.StatusBadRequest, "validation_error", "campaign_id is required")
-        return
-    }
-
-    if _, err := uuid.Parse(campaignID); err != nil {
-        writeJSONErrorResponse(w, http.StatusBadRequest, "validation_error", "campaign_id must be a valid UUID")
-        return
+    req := services.UploadCreativesRequest{
+        CampaignID:   r.FormValue("campaign_id"),
+        SelectedDays: parseFormList(r, "selected_days"),
+        TimeSlots:    parseFormList(r, "time_slots"),
+        Devices:      parseFormList(r, "devices"),
     }
-
-    if h.campaignRepo == nil {
-        writeJSONErrorResponse(w, http.StatusInternalServerError, "server_error", "campaign repository not configured")
-        return
+    for _, fh := range r.MultipartForm.File["files"] {
+        req.Files = append(req.Files, creativeFile(fh))
     }
 
-    if _, err := h.campaignRepo.GetByID(r.Context(), campaignID); err != nil {
-        if err == sql.ErrNoRows {
-            writeJSONErrorResponse(w, http.StatusBadRequest, "validation_error", "campaign_id not found")
+    uploadedCreatives, err := h.service.Upload(r.Context(), req)
+    if err != nil {
+        if errors.Is(err, services.ErrNothingUploaded) {
+            writeJSONErrorResponse(w, http.StatusInternalServerError, "upload_failed", "Failed to upload any files")
             return
         }
-        log.Printf("Failed to validate campaign %s: %v", campaignID, err)
-        writeJSONErrorResponse(w, http.StatusInternalServerError, "server_error", "Failed to validate campaign")
-        return
-    }
-
-    selectedDays := parseFormList(r, "selected_days")
-    if len(selectedDays) == 0 {
-        writeJSONErrorResponse(w, http.StatusBadRequest, "validation_error", "selected_days is required")
-        return
-    }
-
-    timeSlots := parseFormList(r, "time_slots")
-    if len(timeSlots) == 0 {
-        writeJSONErrorResponse(w, http.StatusBadRequest, "validation_error", "time_slots is required")
-        return
-    }
-
-    devices := parseFormList(r, "devices")
-
-    // 2. Get the files from the form
-    files := r.MultipartForm.File["files"]
-    if len(files) == 0 {
-        writeJSONErrorResponse(w, http.StatusBadRequest, "validation_error", "No files uploaded")
-        return
-    }
-
-    var uploadedCreatives []*models.Creative
-    uploader := manager.NewUploader(h.s3Client)
-
-    // 5. Process each file
-    for _, fileHeader := range files {
-        // Open the file
-        file, err := fileHeader.Open()
-        if err != nil {
-            log.Printf("Failed to open file %s: %v", fileHeader.Filename, err)
-            continue
-        }
-
-        // Create a new creative
-        creative := &models.Creative{
-            ID:           generateUUID(),
-            Name:         fileHeader.Filename,
-            Type:         getFileType(fileHeader),
-            Size:         fileHeader.Size,
-            CampaignID:   campaignID,
-            SelectedDays: selectedDays,
-            TimeSlots:    timeSlots,
-            Devices:      devices,
-            UploadedAt:   time.Now().UTC(),
-        }
-
-        // Upload to S3
-        key := filepath.Join("creatives", creative.ID+filepath.Ext(fileHeader.Filename))
-        
-        _, err = uploader.Upload(r.Context(), &s3.PutObjectInput{
-            Bucket: aws.String(h.bucket),
-            Key:    aws.String(key),
-            Body:   file,
-        })
-        file.Close() // Close the file when done
-
-        if err != nil {
-            log.Printf("Failed to upload file %s to S3: %v", fileHeader.Filename, err)
-            continue
-        }
-
-        // Set the URL
-        creative.URL = strings.TrimRight(h.publicBaseURL, "/") + "/" + key
-
-        // Store the object key internally
-        creative.FilePath = key
-
-        // Save to database
-        if err := h.repo.Create(r.Context(), creative); err != nil {
-            log.Printf("Failed to save creative %s: %v", fileHeader.Filename, err)
-            continue
-        }
-
-        uploadedCreatives = append(uploadedCreatives, creative)
-    }
-
-    // 6. Return the uploaded creatives
-    if len(uploadedCreatives) == 0 {
-        writeJSONErrorResponse(w, http.StatusInternalServerError, "upload_failed", "Failed to upload any files")
+        writeCreativeError(w, r, err, "server_error", "Failed to upload creatives")
         return
     }
 
     w.Header().Set("Content-Type", "application/json")
     w.WriteHeader(http.StatusCreated)
     if err := json.NewEncoder(w).Encode(uploadedCreatives); err != nil {
-        log.Printf("Error encoding response: %v", err)
-    }
-}
-
-// Helper function to determine file type
-func getFileType(header *multipart.FileHeader) models.CreativeType {
-    switch header.Header.Get("Content-Type") {
-    case "image/jpeg", "image/png", "image/gif":
-        return models.CreativeTypeImage
-    case "video/mp4", "video/quicktime":
-        return models.CreativeTypeVideo
-    default:
-        return models.CreativeTypeImage
+        slog.ErrorContext(r.Context(), "failed to encode response", "error", err)
     }
 }
 
@@ -225,6 +136,9 @@ func getFileType(header *multipart.FileHeader) models.CreativeType {
 // @Security BearerAuth
 // @Produce json
 // @Param campaignID path string true "Campaign ID"
+// @Param include_deleted query bool false "Include soft-deleted creatives"
+// @Param sort query string false "Sort fields, e.g. name,-uploaded_at"
+// @Param cursor query string false "next_cursor or prev_cursor of a previous page"
 // @Success 200 {array} models.Creative
 // @Failure 400 {object} map[string]interface{}
 // @Failure 500 {object} map[string]interface{}
@@ -236,66 +150,58 @@ func (h *CreativeHandler) ListCreativesByCampaign(w http.ResponseWriter, r *http
         return
     }
 
-	p, err := parsePaginationParams(r, 50, 200)
+	p, err := parseListParams(r, 50, 200, repository.CreativeSort)
 	if err != nil {
-		writeJSONErrorResponse(w, http.StatusBadRequest, "validation_error", "invalid pagination parameters")
+		writeJSONErrorResponse(w, http.StatusBadRequest, "validation_error", "invalid pagination parameters: "+err.Error())
 		return
 	}
 
-	total, err := h.repo.CountByCampaign(r.Context(), campaignID)
+	includeDeleted, err := parseIncludeDeleted(r)
 	if err != nil {
-		log.Printf("Failed to count creatives: %v", err)
-		writeJSONErrorResponse(w, http.StatusInternalServerError, "list_creatives_failed", "Failed to list creatives")
+		writeJSONErrorResponse(w, http.StatusBadRequest, "validation_error", "include_deleted must be a boolean")
 		return
 	}
 
-    creatives, err := h.repo.ListByCampaign(r.Context(), campaignID, p.limit, p.offset)
+    creatives, total, err := h.service.ListByCampaign(r.Context(), campaignID, p.listPage(), includeDeleted)
     if err != nil {
-        log.Printf("Failed to list creatives: %v", err)
-        writeJSONErrorResponse(w, http.StatusInternalServerError, "list_creatives_failed", "Failed to list creatives")
+        writeCreativeError(w, r, err, "list_creatives_failed", "Failed to list creatives")
         return
     }
 
-	if creatives == nil {
-		creatives = []*models.Creative{}
-	}
-
-	writePaginatedResponse(w, http.StatusOK, creatives, p.page, p.pageSize, total)
+	writeListResponse(w, p, creatives, total, nil)
 }
 
 // @Tags Creatives
 // @Summary List creatives
 // @Security BearerAuth
 // @Produce json
+// @Param include_deleted query bool false "Include soft-deleted creatives"
+// @Param sort query string false "Sort fields, e.g. name,-uploaded_at"
+// @Param cursor query string false "next_cursor or prev_cursor of a previous page"
 // @Success 200 {array} models.Creative
+// @Failure 400 {object} map[string]interface{}
 // @Failure 500 {object} map[string]interface{}
 // @Router /api/v1/creatives/ [get]
 func (h *CreativeHandler) ListCreatives(w http.ResponseWriter, r *http.Request) {
-	p, err := parsePaginationParams(r, 50, 200)
+	p, err := parseListParams(r, 50, 200, repository.CreativeSort)
 	if err != nil {
-		writeJSONErrorResponse(w, http.StatusBadRequest, "validation_error", "invalid pagination parameters")
+		writeJSONErrorResponse(w, http.StatusBadRequest, "validation_error", "invalid pagination parameters: "+err.Error())
 		return
 	}
 
-	total, err := h.repo.CountAll(r.Context())
+	includeDeleted, err := parseIncludeDeleted(r)
 	if err != nil {
-		log.Printf("Failed to count creatives: %v", err)
-		writeJSONErrorResponse(w, http.StatusInternalServerError, "list_creatives_failed", "Failed to list creatives")
+		writeJSONErrorResponse(w, http.StatusBadRequest, "validation_error", "include_deleted must be a boolean")
 		return
 	}
 
-    creatives, err := h.repo.ListAll(r.Context(), p.limit, p.offset)
+    creatives, total, err := h.service.List(r.Context(), p.listPage(), includeDeleted)
     if err != nil {
-        log.Printf("Failed to list creatives: %v", err)
-        writeJSONErrorResponse(w, http.StatusInternalServerError, "list_creatives_failed", "Failed to list creatives")
+        writeCreativeError(w, r, err, "list_creatives_failed", "Failed to list creatives")
         return
     }
 
-	if creatives == nil {
-		creatives = []*models.Creative{}
-	}
-
-	writePaginatedResponse(w, http.StatusOK, creatives, p.page, p.pageSize, total)
+	writeListResponse(w, p, creatives, total, nil)
 }
 
 // @Tags Creatives
@@ -303,6 +209,8 @@ func (h *CreativeHandler) ListCreatives(w http.ResponseWriter, r *http.Request)
 // @Produce json
 // @Param device path string true "Device name"
 // @Param active_now query bool false "Filter by current day and time"
+// @Param sort query string false "Sort fields, e.g. name,-uploaded_at"
+// @Param cursor query string false "next_cursor or prev_cursor of a previous page"
 // @Success 200 {array} models.Creative
 // @Failure 400 {object} map[string]interface{}
 // @Failure 500 {object} map[string]interface{}
@@ -314,35 +222,21 @@ func (h *CreativeHandler) ListCreativesByDevice(w http.ResponseWriter, r *http.R
 		return
 	}
 
-	p, err := parsePaginationParams(r, 50, 200)
+	p, err := parseListParams(r, 50, 200, repository.CreativeSort)
 	if err != nil {
-		writeJSONErrorResponse(w, http.StatusBadRequest, "validation_error", "invalid pagination parameters")
+		writeJSONErrorResponse(w, http.StatusBadRequest, "validation_error", "invalid pagination parameters: "+err.Error())
 		return
 	}
 
 	activeNow := strings.EqualFold(r.URL.Query().Get("active_now"), "true") || r.URL.Query().Get("active_now") == "1"
 
-	now := time.Now().UTC()
-
-	total, err := h.repo.CountByDevice(r.Context(), device, activeNow, now)
-	if err != nil {
-		log.Printf("Failed to count creatives by device: %v", err)
-		writeJSONErrorResponse(w, http.StatusInternalServerError, "list_creatives_failed", "Failed to list creatives")
-		return
-	}
-
-	creatives, err := h.repo.ListByDevice(r.Context(), device, activeNow, now, p.limit, p.offset)
+	creatives, total, err := h.service.ListByDevice(r.Context(), device, activeNow, time.Now().UTC(), p.listPage())
 	if err != nil {
-		log.Printf("Failed to list creatives by device: %v", err)
-		writeJSONErrorResponse(w, http.StatusInternalServerError, "list_creatives_failed", "Failed to list creatives")
+		writeCreativeError(w, r, err, "list_creatives_failed", "Failed to list creatives")
 		return
 	}
 
-	if creatives == nil {
-		creatives = []*models.Creative{}
-	}
-
-	writePaginatedResponse(w, http.StatusOK, creatives, p.page, p.pageSize, total)
+	writeListResponse(w, p, creatives, total, nil)
 }
 
 // GetCreative handles GET /creatives/{id}
@@ -363,41 +257,41 @@ func (h *CreativeHandler) GetCreative(w http.ResponseWriter, r *http.Request) {
         return
     }
 
-    creative, err := h.repo.GetByID(r.Context(), id)
+    creative, err := h.service.Get(r.Context(), id)
     if err != nil {
-        if err == sql.ErrNoRows {
-            writeJSONErrorResponse(w, http.StatusNotFound, "creative_not_found", "Creative not found")
-            return
-        }
-        log.Printf("Failed to get creative: %v", err)
-        writeJSONErrorResponse(w, http.StatusInternalServerError, "get_creative_failed", "Failed to get creative")
+        writeCreativeError(w, r, err, "get_creative_failed", "Failed to get creative")
         return
     }
 
+    setETag(w, creative.Version)
     w.Header().Set("Content-Type", "application/json")
     if err := json.NewEncoder(w).Encode(creative); err != nil {
-        log.Printf("Error encoding response: %v", err)
+        slog.ErrorContext(r.Context(), "failed to encode response", "error", err)
     }
 }
 
 // UpdateCreative handles PUT /creatives/{id}
 // @Tags Creatives
-// @Summary Update creative
+// @Summary Replace creative
+// @Description Replaces the creative's editable fields; omitted lists are cleared. A multipart
+// @Description request takes the same fields as form values and may also replace the file.
 // @Security BearerAuth
 // @Accept json
 // @Accept multipart/form-data
 // @Produce json
 // @Param id path string true "Creative ID"
-// @Param body body models.UpdateCreativeRequest false "Update creative request (JSON)"
+// @Param If-Match header string true "ETag from a previous GET"
+// @Param body body models.ReplaceCreativeRequest false "Creative (JSON)"
 // @Success 200 {object} map[string]interface{}
 // @Failure 400 {object} map[string]interface{}
 // @Failure 404 {object} map[string]interface{}
+// @Failure 412 {object} map[string]interface{}
+// @Failure 428 {object} map[string]interface{}
 // @Failure 500 {object} map[string]interface{}
 // @Router /api/v1/creatives/{id}/ [put]
 func (h *CreativeHandler) UpdateCreative(w http.ResponseWriter, r *http.Request) {
-    id := chi.URLParam(r, "id")
-    if id == "" {
-        writeJSONErrorResponse(w, http.StatusBadRequest, "validation_error", "Creative ID is required")
+    id, ifMatch, _, ok := h.loadForUpdate(w, r)
+    if !ok {
         return
     }
 
@@ -408,111 +302,111 @@ func (h *CreativeHandler) UpdateCreative(w http.ResponseWriter, r *http.Request)
             return
         }
 
-        var req models.UpdateCreativeRequest
-
-        if name := r.FormValue("name"); name != "" {
-            req.Name = &name
+        req := models.ReplaceCreativeRequest{
+            Name:         r.FormValue("name"),
+            SelectedDays: parseFormList(r, "selected_days"),
+            TimeSlots:    parseFormList(r, "time_slots"),
+            Devices:      parseFormList(r, "devices"),
         }
 
-        if r.MultipartForm != nil {
-            if _, ok := r.MultipartForm.Value["selected_days"]; ok {
-                v := parseFormList(r, "selected_days")
-                req.SelectedDays = &v
-            }
-            if _, ok := r.MultipartForm.Value["time_slots"]; ok {
-                v := parseFormList(r, "time_slots")
-                req.TimeSlots = &v
-            }
-            if _, ok := r.MultipartForm.Value["devices"]; ok {
-                v := parseFormList(r, "devices")
-                req.Devices = &v
-            }
-        }
-
-        var fileHeader *multipart.FileHeader
+        var file *services.CreativeFile
         if r.MultipartForm != nil {
             if fhs := r.MultipartForm.File["file"]; len(fhs) > 0 {
-                fileHeader = fhs[0]
+                f := creativeFile(fhs[0])
+                file = &f
             } else if fhs := r.MultipartForm.File["files"]; len(fhs) > 0 {
-                fileHeader = fhs[0]
+                f := creativeFile(fhs[0])
+                file = &f
             }
         }
 
-        if fileHeader != nil {
-            file, err := fileHeader.Open()
-            if err != nil {
-                writeJSONErrorResponse(w, http.StatusBadRequest, "invalid_request", "Failed to open uploaded file")
-                return
-            }
-            defer file.Close()
-
-            key := filepath.Join("creatives", id+filepath.Ext(fileHeader.Filename))
-            uploader := manager.NewUploader(h.s3Client)
-            _, err = uploader.Upload(r.Context(), &s3.PutObjectInput{
-                Bucket: aws.String(h.bucket),
-                Key:    aws.String(key),
-                Body:   file,
-            })
-            if err != nil {
-                log.Printf("Failed to upload file %s to S3: %v", fileHeader.Filename, err)
-                writeJSONErrorResponse(w, http.StatusBadGateway, "upload_failed", "Failed to upload file")
-                return
-            }
-
-            url := strings.TrimRight(h.publicBaseURL, "/") + "/" + key
-            req.URL = &url
-            req.FilePath = &key
-            size := fileHeader.Size
-            req.Size = &size
-            t := getFileType(fileHeader)
-            req.Type = &t
-
-            if req.Name == nil {
-                n := fileHeader.Filename
-                req.Name = &n
-            }
-        }
-
-        if err := h.validator.Struct(req); err != nil {
-            writeJSONErrorResponse(w, http.StatusBadRequest, "validation_error", err.Error())
-            return
-        }
-
-        if err := h.repo.Update(r.Context(), id, &req); err != nil {
-            if err == sql.ErrNoRows {
-                writeJSONErrorResponse(w, http.StatusNotFound, "creative_not_found", "Creative not found")
-                return
-            }
-            log.Printf("Failed to update creative: %v", err)
-            writeJSONErrorResponse(w, http.StatusInternalServerError, "update_creative_failed", "Failed to update creative")
-            return
-        }
-
-        writeJSONMessage(w, http.StatusOK, "creative updated successfully")
+        h.replaceCreative(w, r, id, req, ifMatch, file)
         return
     }
 
-    var req models.UpdateCreativeRequest
+    var req models.ReplaceCreativeRequest
     if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
         writeJSONErrorResponse(w, http.StatusBadRequest, "invalid_request", "Invalid request body")
         return
     }
 
-    if err := h.validator.Struct(req); err != nil {
-        writeJSONErrorResponse(w, http.StatusBadRequest, "validation_error", err.Error())
+    h.replaceCreative(w, r, id, req, ifMatch, nil)
+}
+
+// PatchCreative handles PATCH /creatives/{id}
+// @Tags Creatives
+// @Summary Patch creative
+// @Description Applies a JSON merge patch (RFC 7396) to the creative's editable fields.
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Creative ID"
+// @Param If-Match header string true "ETag from a previous GET"
+// @Param body body models.ReplaceCreativeRequest true "Merge patch"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 412 {object} map[string]interface{}
+// @Failure 415 {object} map[string]interface{}
+// @Failure 428 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/creatives/{id}/ [patch]
+func (h *CreativeHandler) PatchCreative(w http.ResponseWriter, r *http.Request) {
+    id, ifMatch, current, ok := h.loadForUpdate(w, r)
+    if !ok {
+        return
+    }
+
+    var req models.ReplaceCreativeRequest
+    if err := decodeMergePatch(r, models.NewReplaceCreativeRequest(current), &req); err != nil {
+        writeMergePatchError(w, err)
         return
     }
 
-    if err := h.repo.Update(r.Context(), id, &req); err != nil {
-        if err == sql.ErrNoRows {
-            writeJSONErrorResponse(w, http.StatusNotFound, "creative_not_found", "Creative not found")
-            return
+    h.replaceCreative(w, r, id, req, ifMatch, nil)
+}
+
+// loadForUpdate reads the id and If-Match of an update request and the creative it
+// targets, answering 404/412/428 itself. The precondition is checked up front because
+// a multipart update overwrites the stored file before the row is written.
+func (h *CreativeHandler) loadForUpdate(w http.ResponseWriter, r *http.Request) (string, int, *models.Creative, bool) {
+    id := chi.URLParam(r, "id")
+    if id == "" {
+        writeJSONErrorResponse(w, http.StatusBadRequest, "validation_error", "Creative ID is required")
+        return "", 0, nil, false
+    }
+
+    ifMatch, ok := requireIfMatch(w, r)
+    if !ok {
+        return "", 0, nil, false
+    }
+
+    current, err := h.service.Get(r.Context(), id)
+    if err != nil {
+        writeCreativeError(w, r, err, "get_creative_failed", "Failed to get creative")
+        return "", 0, nil, false
+    }
+    if ifMatchFails(ifMatch, current.Version) {
+        writePreconditionFailed(w, current, current.Version)
+        return "", 0, nil, false
+    }
+    return id, ifMatch, current, true
+}
+
+func (h *CreativeHandler) replaceCreative(w http.ResponseWriter, r *http.Request, id string, req models.ReplaceCreativeRequest, ifMatch int, file *services.CreativeFile) {
+    updated, err := h.service.Replace(r.Context(), id, req, ifMatch, file)
+    if err != nil {
+        if errors.Is(err, interfaces.ErrVersionConflict) {
+            if current, err := h.service.Get(r.Context(), id); err == nil {
+                writePreconditionFailed(w, current, current.Version)
+                return
+            }
         }
-        log.Printf("Failed to update creative: %v", err)
-        writeJSONErrorResponse(w, http.StatusInternalServerError, "update_creative_failed", "Failed to update creative")
+        writeCreativeError(w, r, err, "update_creative_failed", "Failed to update creative")
         return
     }
 
+    setETag(w, updated.Version)
     writeJSONMessage(w, http.StatusOK, "creative updated successfully")
 }
 // DeleteCreative handles DELETE /creatives/{id}
@@ -533,15 +427,43 @@ func (h *CreativeHandler) DeleteCreative(w http.ResponseWriter, r *http.Request)
         return
     }
 
-    if err := h.repo.Delete(r.Context(), id); err != nil {
-        if err == sql.ErrNoRows {
-            writeJSONErrorResponse(w, http.StatusNotFound, "creative_not_found", "Creative not found")
+    if err := h.service.Delete(r.Context(), id); err != nil {
+        writeCreativeError(w, r, err, "delete_creative_failed", "Failed to delete creative")
+        return
+    }
+
+    writeJSONMessage(w, http.StatusOK, "creative deleted successfully")
+}
+// RestoreCreative handles POST /creatives/{id}/restore
+// @Tags Creatives
+// @Summary Restore creative
+// @Description Restores a soft-deleted creative. Its campaign must not be deleted.
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Creative ID"
+// @Success 200 {object} models.Creative
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/creatives/{id}/restore [post]
+func (h *CreativeHandler) RestoreCreative(w http.ResponseWriter, r *http.Request) {
+    id := chi.URLParam(r, "id")
+    if id == "" {
+        writeJSONErrorResponse(w, http.StatusBadRequest, "validation_error", "Creative ID is required")
+        return
+    }
+
+    creative, err := h.service.Restore(r.Context(), id)
+    if err != nil {
+        if errors.Is(err, services.ErrCreativeNotFound) {
+            writeJSONErrorResponse(w, http.StatusNotFound, "creative_not_found", "Deleted creative not found")
             return
         }
-        log.Printf("Failed to delete creative: %v", err)
-        writeJSONErrorResponse(w, http.StatusInternalServerError, "delete_creative_failed", "Failed to delete creative")
+        writeCreativeError(w, r, err, "restore_creative_failed", "Failed to restore creative")
         return
     }
 
-    writeJSONMessage(w, http.StatusOK, "creative deleted successfully")
-}
\ No newline at end of file
+    w.Header().Set("Content-Type", "application/json")
+    json.NewEncoder(w).Encode(creative)
+}
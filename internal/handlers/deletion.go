@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"scm/internal/interfaces"
+	authmw "scm/internal/middleware"
+	"scm/internal/models"
+	"scm/internal/services"
+)
+
+// CascadeDeleter guards `DELETE ?cascade=true`: only admins may run it, and the stored
+// objects of deleted creatives are removed once the database transaction has committed.
+type CascadeDeleter struct {
+	Admins  authmw.Admins
+	Storage services.ObjectStorage
+}
+
+// parseCascade reads the cascade query flag of DELETE endpoints.
+func parseCascade(r *http.Request) (bool, error) {
+	raw := r.URL.Query().Get("cascade")
+	if raw == "" {
+		return false, nil
+	}
+	return strconv.ParseBool(raw)
+}
+
+// allowed writes the error response and returns false unless the caller may cascade.
+func (c *CascadeDeleter) allowed(w http.ResponseWriter, r *http.Request) bool {
+	if c == nil || !c.Admins.IsAdmin(r.Context()) {
+		writeJSONErrorResponse(w, http.StatusForbidden, "forbidden", "Cascade delete requires an admin")
+		return false
+	}
+	return true
+}
+
+func (c *CascadeDeleter) removeStoredObjects(ctx context.Context, keys []string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	if c.Storage == nil {
+		return fmt.Errorf("object storage not configured")
+	}
+	return c.Storage.DeleteObjects(ctx, keys)
+}
+
+// writeCascadeResult deletes the removed records' stored objects and reports what was
+// deleted. Storage failures are reported but do not fail the request: the database
+// changes are already committed.
+func (c *CascadeDeleter) writeCascadeResult(w http.ResponseWriter, r *http.Request, deps *models.DeletionDependencies) {
+	err := c.removeStoredObjects(r.Context(), deps.StoredObjects)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "failed to delete stored objects", "resource", deps.Resource, "id", deps.ID, "error", err)
+	}
+	writeCascadeDeleted(w, deps, err)
+}
+
+// writeCascadeDeleted reports what a cascade delete removed, and storageErr if their
+// stored objects could not be deleted.
+func writeCascadeDeleted(w http.ResponseWriter, deps *models.DeletionDependencies, storageErr error) {
+	resp := map[string]any{
+		"message": fmt.Sprintf("%s and its dependents deleted", deps.Resource),
+		"deleted": deps,
+	}
+	if storageErr != nil {
+		resp["storage_error"] = storageErr.Error()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// writeDeletionBlocked writes the 409 returned when a plain delete is blocked by dependents.
+func writeDeletionBlocked(w http.ResponseWriter, blocked *interfaces.DeletionBlockedError) {
+	msg := fmt.Sprintf("Cannot delete %s: referenced by", blocked.Resource)
+	for k, v := range blocked.References {
+		msg += fmt.Sprintf(" %d %s", v, k)
+	}
+	writeJSONErrorResponse(w, http.StatusConflict, "delete_blocked", msg)
+}
+
+func writeDependencies(w http.ResponseWriter, deps *models.DeletionDependencies) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(deps)
+}
@@ -2,20 +2,27 @@ package handlers
 
 import (
 	"encoding/json"
+	"fmt"
+	"maps"
 	"net/http"
+	"slices"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
+	"scm/internal/interfaces"
 	"scm/internal/models"
 	"scm/internal/repository"
 )
 
 type DeviceReadHandler struct {
-	repo repository.DeviceRepository
+	repo       repository.DeviceRepository
+	thresholds models.HealthThresholds
 }
 
-func NewDeviceReadHandler(repo repository.DeviceRepository) *DeviceReadHandler {
-	return &DeviceReadHandler{repo: repo}
+func NewDeviceReadHandler(repo repository.DeviceRepository, thresholds models.HealthThresholds) *DeviceReadHandler {
+	return &DeviceReadHandler{repo: repo, thresholds: thresholds}
 }
 
 // @Tags Devices
@@ -23,16 +30,21 @@ func NewDeviceReadHandler(repo repository.DeviceRepository) *DeviceReadHandler {
 // @Security BearerAuth
 // @Produce json
 // @Param city query string false "Filter by city"
+// @Param sync_status query string false "Filter by sync status"
+// @Param venue_id query int false "Only devices assigned to this venue"
+// @Param production query bool false "Filter by the region's production flag"
 // @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
 // @Failure 500 {object} map[string]interface{}
 // @Router /api/v1/devices/counts/regions [get]
 func (h *DeviceReadHandler) CountByRegion(w http.ResponseWriter, r *http.Request) {
-	var city *string
-	if v := r.URL.Query().Get("city"); v != "" {
-		city = &v
+	filters, err := parseDeviceFilters(r, h.thresholds)
+	if err != nil {
+		writeJSONErrorResponse(w, http.StatusBadRequest, "invalid_request", err.Error())
+		return
 	}
 
-	items, err := h.repo.CountByRegion(r.Context(), city)
+	items, err := h.repo.CountByRegion(r.Context(), filters)
 	if err != nil {
 		writeJSONErrorResponse(w, http.StatusInternalServerError, "internal_error", "failed to count devices by region: "+err.Error())
 		return
@@ -55,44 +67,53 @@ func (h *DeviceReadHandler) CountByRegion(w http.ResponseWriter, r *http.Request
 // @Param city query string false "Filter by city"
 // @Param region query string false "Filter by region"
 // @Param device_type query string false "Filter by device type"
+// @Param host_name_glob query string false "Filter by host name glob (* and ?)"
+// @Param health query string false "Filter by heartbeat health (online, stale, offline)"
+// @Param sync_status query string false "Filter by sync status"
+// @Param change query bool false "Filter by the change flag"
+// @Param synced_after query string false "Only devices last synced at or after this time (RFC3339)"
+// @Param synced_before query string false "Only devices last synced before this time (RFC3339)"
+// @Param venue_id query int false "Only devices assigned to this venue"
+// @Param production query bool false "Filter by the region's production flag"
+// @Param is_transit query bool false "Filter by the region's transit flag"
+// @Param config.path query string false "Filter by a device_config value, e.g. config.display.orientation=portrait"
+// @Param tag query []string false "Only devices annotated with all these tags" collectionFormat(multi)
+// @Param internal_owner query string false "Filter by the annotated internal owner"
+// @Param attribute.key query string false "Filter by an annotation attribute, e.g. attribute.rack=B2"
+// @Param facets query bool false "Include facet counts of the matching devices"
+// @Param sort query string false "Sort fields, e.g. host_name,-created_at"
+// @Param cursor query string false "next_cursor or prev_cursor of a previous page"
 // @Success 200 {object} map[string]interface{}
 // @Failure 400 {object} map[string]interface{}
 // @Failure 500 {object} map[string]interface{}
 // @Router /api/v1/devices [get]
 func (h *DeviceReadHandler) List(w http.ResponseWriter, r *http.Request) {
-	pagination, err := parsePaginationParams(r, 20, 100)
+	pagination, err := parseListParams(r, 20, 100, repository.DeviceSort)
 	if err != nil {
 		writeJSONErrorResponse(w, http.StatusBadRequest, "invalid_pagination", "invalid pagination: "+err.Error())
 		return
 	}
 
-	// Parse filter parameters
-	filters := repository.DeviceFilters{}
-
-	if projectIDStr := r.URL.Query().Get("project_id"); projectIDStr != "" {
-		if projectID, err := strconv.Atoi(projectIDStr); err == nil {
-			filters.ProjectID = &projectID
-		}
-	}
-
-	if city := r.URL.Query().Get("city"); city != "" {
-		filters.City = &city
-	}
-
-	if region := r.URL.Query().Get("region"); region != "" {
-		filters.Region = &region
+	filters, err := parseDeviceFilters(r, h.thresholds)
+	if err != nil {
+		writeJSONErrorResponse(w, http.StatusBadRequest, "invalid_request", err.Error())
+		return
 	}
 
-	if deviceType := r.URL.Query().Get("device_type"); deviceType != "" {
-		filters.DeviceType = &deviceType
+	withFacets := false
+	if raw := r.URL.Query().Get("facets"); raw != "" {
+		if withFacets, err = strconv.ParseBool(raw); err != nil {
+			writeJSONErrorResponse(w, http.StatusBadRequest, "invalid_request", "facets must be a boolean")
+			return
+		}
 	}
 
 	var devices []*models.Device
 	var total int
 
 	// Use filters if any are provided, otherwise use basic list
-	if filters.ProjectID != nil || filters.City != nil || filters.Region != nil || filters.DeviceType != nil {
-		devices, err = h.repo.ListWithFilters(r.Context(), filters, pagination.limit, pagination.offset)
+	if filters.HasAny() {
+		devices, err = h.repo.ListWithFilters(r.Context(), filters, pagination.listPage())
 		if err != nil {
 			writeJSONErrorResponse(w, http.StatusInternalServerError, "internal_error", "failed to list devices with filters: "+err.Error())
 			return
@@ -104,7 +125,7 @@ func (h *DeviceReadHandler) List(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 	} else {
-		devices, err = h.repo.List(r.Context(), pagination.limit, pagination.offset)
+		devices, err = h.repo.List(r.Context(), pagination.listPage())
 		if err != nil {
 			writeJSONErrorResponse(w, http.StatusInternalServerError, "internal_error", "failed to list devices: "+err.Error())
 			return
@@ -117,7 +138,74 @@ func (h *DeviceReadHandler) List(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	writePaginatedResponse(w, http.StatusOK, devices, pagination.page, pagination.pageSize, total)
+	resp, err := listResponse(pagination, devices, total, nil)
+	if err != nil {
+		writeJSONErrorResponse(w, http.StatusInternalServerError, "internal_error", "failed to build page cursor: "+err.Error())
+		return
+	}
+	if withFacets {
+		facets, err := h.repo.Facets(r.Context(), filters)
+		if err != nil {
+			writeJSONErrorResponse(w, http.StatusInternalServerError, "internal_error", "failed to count device facets: "+err.Error())
+			return
+		}
+		resp["facets"] = facets
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// @Tags Devices
+// @Summary Export devices as GeoJSON
+// @Description Returns every device matching the list filters as a FeatureCollection. Devices without a known location have a null geometry.
+// @Security BearerAuth
+// @Produce json
+// @Param project_id query int false "Filter by project ID"
+// @Param city query string false "Filter by city"
+// @Param region query string false "Filter by region"
+// @Param device_type query string false "Filter by device type"
+// @Param host_name_glob query string false "Filter by host name glob (* and ?)"
+// @Param health query string false "Filter by heartbeat health (online, stale, offline)"
+// @Param sync_status query string false "Filter by sync status"
+// @Param change query bool false "Filter by the change flag"
+// @Param synced_after query string false "Only devices last synced at or after this time (RFC3339)"
+// @Param synced_before query string false "Only devices last synced before this time (RFC3339)"
+// @Param venue_id query int false "Only devices assigned to this venue"
+// @Param production query bool false "Filter by the region's production flag"
+// @Param is_transit query bool false "Filter by the region's transit flag"
+// @Param config.path query string false "Filter by a device_config value, e.g. config.display.orientation=portrait"
+// @Param tag query []string false "Only devices annotated with all these tags" collectionFormat(multi)
+// @Param internal_owner query string false "Filter by the annotated internal owner"
+// @Param attribute.key query string false "Filter by an annotation attribute, e.g. attribute.rack=B2"
+// @Success 200 {object} models.GeoJSONFeatureCollection
+// @Failure 400 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/devices.geojson [get]
+func (h *DeviceReadHandler) GeoJSON(w http.ResponseWriter, r *http.Request) {
+	filters, err := parseDeviceFilters(r, h.thresholds)
+	if err != nil {
+		writeJSONErrorResponse(w, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+
+	collection := models.GeoJSONFeatureCollection{Type: "FeatureCollection", Features: []models.GeoJSONFeature{}}
+	for offset := 0; ; offset += geoJSONBatchSize {
+		devices, err := h.repo.ListWithFilters(r.Context(), filters, interfaces.ListPage{Limit: geoJSONBatchSize, Offset: offset})
+		if err != nil {
+			writeJSONErrorResponse(w, http.StatusInternalServerError, "internal_error", "failed to list devices: "+err.Error())
+			return
+		}
+		for _, d := range devices {
+			collection.Features = append(collection.Features, deviceFeature(d))
+		}
+		if len(devices) < geoJSONBatchSize {
+			break
+		}
+	}
+
+	writeGeoJSON(w, collection)
 }
 
 // @Tags Devices
@@ -164,13 +252,13 @@ func (h *DeviceReadHandler) ListByProject(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	pagination, err := parsePaginationParams(r, 20, 100)
+	pagination, err := parseListParams(r, 20, 100, repository.DeviceSort)
 	if err != nil {
 		writeJSONErrorResponse(w, http.StatusBadRequest, "invalid_pagination", "invalid pagination: "+err.Error())
 		return
 	}
 
-	devices, err := h.repo.ListByProject(r.Context(), projectID, pagination.limit, pagination.offset)
+	devices, err := h.repo.ListByProject(r.Context(), projectID, pagination.listPage())
 	if err != nil {
 		writeJSONErrorResponse(w, http.StatusInternalServerError, "internal_error", "failed to list devices by project: "+err.Error())
 		return
@@ -182,5 +270,112 @@ func (h *DeviceReadHandler) ListByProject(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	writePaginatedResponse(w, http.StatusOK, devices, pagination.page, pagination.pageSize, total)
+	writeListResponse(w, pagination, devices, total, nil)
+}
+
+func parseDeviceFilters(r *http.Request, thresholds models.HealthThresholds) (repository.DeviceFilters, error) {
+	filters := repository.DeviceFilters{HealthThresholds: thresholds}
+
+	if projectIDStr := r.URL.Query().Get("project_id"); projectIDStr != "" {
+		projectID, err := strconv.Atoi(projectIDStr)
+		if err != nil {
+			return filters, fmt.Errorf("project_id must be an integer")
+		}
+		filters.ProjectID = &projectID
+	}
+
+	if city := r.URL.Query().Get("city"); city != "" {
+		filters.City = &city
+	}
+
+	if region := r.URL.Query().Get("region"); region != "" {
+		filters.Region = &region
+	}
+
+	if deviceType := r.URL.Query().Get("device_type"); deviceType != "" {
+		filters.DeviceType = &deviceType
+	}
+
+	if glob := r.URL.Query().Get("host_name_glob"); glob != "" {
+		filters.HostNameGlob = &glob
+	}
+
+	if raw := r.URL.Query().Get("health"); raw != "" {
+		health := models.DeviceHealthStatus(raw)
+		if !health.Valid() {
+			return filters, fmt.Errorf("health must be one of online, stale, offline")
+		}
+		filters.Health = &health
+	}
+
+	if status := r.URL.Query().Get("sync_status"); status != "" {
+		filters.SyncStatus = &status
+	}
+
+	// Parameters are checked in a fixed order, so the same bad query always gets the same error
+	for _, p := range []struct {
+		param string
+		dst   **bool
+	}{
+		{"change", &filters.Change},
+		{"production", &filters.Production},
+		{"is_transit", &filters.IsTransit},
+	} {
+		if raw := r.URL.Query().Get(p.param); raw != "" {
+			v, err := strconv.ParseBool(raw)
+			if err != nil {
+				return filters, fmt.Errorf("%s must be a boolean", p.param)
+			}
+			*p.dst = &v
+		}
+	}
+
+	for _, p := range []struct {
+		param string
+		dst   **time.Time
+	}{
+		{"synced_after", &filters.SyncedAfter},
+		{"synced_before", &filters.SyncedBefore},
+	} {
+		if raw := r.URL.Query().Get(p.param); raw != "" {
+			t, err := time.Parse(time.RFC3339, raw)
+			if err != nil {
+				return filters, fmt.Errorf("%s must be an RFC3339 timestamp", p.param)
+			}
+			*p.dst = &t
+		}
+	}
+
+	if raw := r.URL.Query().Get("venue_id"); raw != "" {
+		venueID, err := strconv.Atoi(raw)
+		if err != nil {
+			return filters, fmt.Errorf("venue_id must be an integer")
+		}
+		filters.VenueID = &venueID
+	}
+
+	// config.<path>=<value>, with the device_config path separated by dots. Sorting the
+	// parameters keeps the generated SQL stable.
+	query := r.URL.Query()
+	for _, param := range slices.Sorted(maps.Keys(query)) {
+		rawPath, ok := strings.CutPrefix(param, "config.")
+		if !ok {
+			continue
+		}
+		path := strings.Split(rawPath, ".")
+		if slices.Contains(path, "") {
+			return filters, fmt.Errorf("invalid device_config path in %s", param)
+		}
+		for _, v := range query[param] {
+			filters.Config = append(filters.Config, repository.DeviceConfigFilter{Path: path, Value: v})
+		}
+	}
+
+	annotations, err := parseAnnotationFilters(query)
+	if err != nil {
+		return filters, err
+	}
+	filters.Annotations = annotations
+
+	return filters, nil
 }
@@ -0,0 +1,197 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"scm/internal/models"
+	"scm/internal/repository"
+	"scm/internal/repository/memory"
+)
+
+// newDeviceRepo returns a memory device repository holding devices, creating the
+// projects they belong to.
+func newDeviceRepo(t *testing.T, devices ...*models.Device) repository.DeviceRepository {
+	t.Helper()
+	ctx := context.Background()
+	store := memory.NewStore()
+	projects := memory.NewProjectRepository(store)
+	repo := memory.NewDeviceRepository(store)
+	seen := map[int]bool{}
+	for i, d := range devices {
+		if !seen[d.Project] {
+			seen[d.Project] = true
+			if err := projects.Upsert(ctx, &models.Project{ID: d.Project, Name: fmt.Sprintf("Project %d", d.Project)}); err != nil {
+				t.Fatalf("upsert project: %v", err)
+			}
+		}
+		d.ID = i + 1
+		if d.DeviceConfig == nil {
+			d.DeviceConfig = json.RawMessage(`{}`)
+		}
+		if err := repo.Upsert(ctx, d); err != nil {
+			t.Fatalf("upsert device: %v", err)
+		}
+	}
+	return repo
+}
+
+func TestListDevicesRichFilters(t *testing.T) {
+	synced := "synced"
+	req := httptest.NewRequest(http.MethodGet, "/devices?sync_status=synced&change=false&production=true"+
+		"&venue_id=3&synced_after=2026-01-02T00:00:00Z&config.display.orientation=portrait"+
+		"&tag=lobby&tag=beta&attribute.rack=B2", nil)
+	f, err := parseDeviceFilters(req, testThresholds)
+	if err != nil {
+		t.Fatalf("parseDeviceFilters: %v", err)
+	}
+	if f.SyncStatus == nil || *f.SyncStatus != "synced" || f.Change == nil || *f.Change ||
+		f.Production == nil || !*f.Production || f.IsTransit != nil || f.VenueID == nil || *f.VenueID != 3 {
+		t.Fatalf("unexpected filters %+v", f)
+	}
+	if f.SyncedAfter == nil || !f.SyncedAfter.Equal(time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC)) || f.SyncedBefore != nil {
+		t.Fatalf("unexpected synced range %+v", f)
+	}
+	if len(f.Config) != 1 || strings.Join(f.Config[0].Path, ".") != "display.orientation" || f.Config[0].Value != "portrait" {
+		t.Fatalf("unexpected config filters %+v", f.Config)
+	}
+	if ann := f.Annotations; strings.Join(ann.Tags, ",") != "lobby,beta" || ann.InternalOwner != nil || ann.Attributes["rack"] != "B2" {
+		t.Fatalf("unexpected annotation filters %+v", ann)
+	}
+
+	h := NewDeviceReadHandler(newDeviceRepo(t,
+		&models.Device{HostName: "kiosk-1", Project: 7, SyncStatus: &synced},
+		&models.Device{HostName: "kiosk-2", Project: 7, SyncStatus: &synced},
+		&models.Device{HostName: "kiosk-3", Project: 7, Change: true},
+	), testThresholds)
+	w := httptest.NewRecorder()
+	h.List(w, httptest.NewRequest(http.MethodGet, "/devices?sync_status=synced&change=false&facets=true&sort=host_name", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 got %d (%s)", w.Code, w.Body.String())
+	}
+
+	var resp struct {
+		Data   []models.Device     `json:"data"`
+		Facets models.DeviceFacets `json:"facets"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("invalid json: %v", err)
+	}
+	if len(resp.Data) != 2 || resp.Data[0].HostName != "kiosk-1" || resp.Data[1].HostName != "kiosk-2" {
+		t.Fatalf("unexpected devices %+v", resp.Data)
+	}
+	if got := resp.Facets["sync_status"]; len(got) != 1 || got[0].Value != "synced" || got[0].Count != 2 {
+		t.Fatalf("unexpected facets %+v", resp.Facets)
+	}
+
+	for _, query := range []string{"project_id=x", "change=maybe", "synced_before=yesterday", "venue_id=x", "config..a=1", "attribute.=x", "facets=sometimes"} {
+		w := httptest.NewRecorder()
+		h.List(w, httptest.NewRequest(http.MethodGet, "/devices?"+query, nil))
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("%s: expected 400 got %d", query, w.Code)
+		}
+	}
+
+	// Several bad parameters always report the same one.
+	for range 10 {
+		_, err := parseDeviceFilters(httptest.NewRequest(http.MethodGet,
+			"/devices?is_transit=x&production=x&change=x&synced_before=x&synced_after=x&config.b..c=1&config.a..b=1", nil), testThresholds)
+		if err == nil || err.Error() != "change must be a boolean" {
+			t.Fatalf("expected the change error, got %v", err)
+		}
+		_, err = parseDeviceFilters(httptest.NewRequest(http.MethodGet, "/devices?config.b..c=1&config.a..b=1", nil), testThresholds)
+		if err == nil || err.Error() != "invalid device_config path in config.a..b" {
+			t.Fatalf("expected the config.a..b error, got %v", err)
+		}
+	}
+}
+
+func TestDevicesGeoJSON(t *testing.T) {
+	synced := "synced"
+	h := NewDeviceReadHandler(newDeviceRepo(t,
+		&models.Device{
+			HostName:     "kiosk-1",
+			Project:      7,
+			Region:       models.Region{Code: "sf", Latitude: "37.77", Longitude: "-122.42"},
+			DeviceType:   models.DeviceType{Code: "kiosk"},
+			SyncStatus:   &synced,
+			DeviceConfig: json.RawMessage(`{"latitude": 37.8, "longitude": -122.4}`),
+		},
+		&models.Device{HostName: "kiosk-2", Project: 7, Region: models.Region{Code: "sf", Latitude: "37.77", Longitude: "-122.42"}},
+		&models.Device{HostName: "kiosk-3", Project: 9, Region: models.Region{Code: "nowhere"}},
+		&models.Device{HostName: "kiosk-4", Project: 9},
+	), testThresholds)
+	r := chi.NewRouter()
+	r.Get("/devices.geojson", h.GeoJSON)
+
+	req := httptest.NewRequest(http.MethodGet, "/devices.geojson?project_id=7", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 got %d (%s)", w.Code, w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/geo+json" {
+		t.Fatalf("expected geo+json content-type got %q", ct)
+	}
+
+	var fc models.GeoJSONFeatureCollection
+	if err := json.Unmarshal(w.Body.Bytes(), &fc); err != nil {
+		t.Fatalf("invalid json: %v", err)
+	}
+	if fc.Type != "FeatureCollection" || len(fc.Features) != 2 {
+		t.Fatalf("expected 2 features, got %+v", fc)
+	}
+	byHost := map[any]models.GeoJSONFeature{}
+	for _, f := range fc.Features {
+		byHost[f.Properties["host_name"]] = f
+	}
+
+	first := byHost["kiosk-1"]
+	if first.Geometry == nil || first.Geometry.Coordinates[0] != -122.4 || first.Geometry.Coordinates[1] != 37.8 {
+		t.Fatalf("expected device_config coordinates, got %+v", first.Geometry)
+	}
+	if first.Properties["region_code"] != "sf" ||
+		first.Properties["device_type"] != "kiosk" || first.Properties["sync_status"] != "synced" {
+		t.Fatalf("unexpected properties: %v", first.Properties)
+	}
+
+	second := byHost["kiosk-2"]
+	if second.Geometry == nil || second.Geometry.Coordinates[0] != -122.42 {
+		t.Fatalf("expected region fallback coordinates, got %+v", second.Geometry)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/devices.geojson?project_id=seven", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an invalid project_id got %d", w.Code)
+	}
+}
+
+func TestDevicesGeoJSONNullGeometry(t *testing.T) {
+	h := NewDeviceReadHandler(newDeviceRepo(t, &models.Device{HostName: "kiosk-4", Project: 9}), testThresholds)
+
+	req := httptest.NewRequest(http.MethodGet, "/devices.geojson", nil)
+	w := httptest.NewRecorder()
+	h.GeoJSON(w, req)
+
+	var resp map[string]any
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("invalid json: %v", err)
+	}
+	features := resp["features"].([]any)
+	if len(features) != 1 {
+		t.Fatalf("expected 1 feature, got %v", features)
+	}
+	feature := features[0].(map[string]any)
+	if g, ok := feature["geometry"]; !ok || g != nil {
+		t.Fatalf("expected explicit null geometry, got %v", feature)
+	}
+}
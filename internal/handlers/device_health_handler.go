@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"scm/internal/models"
+	"scm/internal/repository"
+	"scm/internal/services"
+)
+
+type DeviceHealthHandler struct {
+	repo       repository.DeviceHealthRepository
+	thresholds models.HealthThresholds
+	token      string
+	events     services.EventPublisher
+}
+
+// NewDeviceHealthHandler creates the heartbeat/health handler. When token is non-empty,
+// heartbeats must carry it in the X-Device-Token header. When events is non-nil, a
+// heartbeat from a stale or offline device raises device.health_changed.
+func NewDeviceHealthHandler(repo repository.DeviceHealthRepository, thresholds models.HealthThresholds, token string, events services.EventPublisher) *DeviceHealthHandler {
+	return &DeviceHealthHandler{repo: repo, thresholds: thresholds, token: token, events: events}
+}
+
+// @Tags Devices
+// @Summary Record device heartbeat
+// @Description Called by screens to report they are on and playing.
+// @Accept json
+// @Produce json
+// @Param hostName path string true "Device host name"
+// @Param X-Device-Token header string true "Device token (DEVICE_HEARTBEAT_TOKEN)"
+// @Param body body models.DeviceHeartbeatRequest true "Heartbeat"
+// @Success 200 {object} models.DeviceHealth
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Failure 503 {object} map[string]interface{}
+// @Router /api/v1/devices/{hostName}/heartbeat [post]
+func (h *DeviceHealthHandler) Heartbeat(w http.ResponseWriter, r *http.Request) {
+	if h.token == "" {
+		// Without a token anyone could report any device, so heartbeats are refused.
+		writeJSONErrorResponse(w, http.StatusServiceUnavailable, "heartbeats_disabled", "device heartbeats are disabled: DEVICE_HEARTBEAT_TOKEN is not set")
+		return
+	}
+	if subtle.ConstantTimeCompare([]byte(r.Header.Get("X-Device-Token")), []byte(h.token)) != 1 {
+		writeJSONErrorResponse(w, http.StatusUnauthorized, "unauthorized", "invalid device token")
+		return
+	}
+
+	hostName := chi.URLParam(r, "hostName")
+	if hostName == "" {
+		writeJSONErrorResponse(w, http.StatusBadRequest, "invalid_request", "device hostName is required")
+		return
+	}
+
+	var req models.DeviceHeartbeatRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONErrorResponse(w, http.StatusBadRequest, "invalid_json", "invalid JSON: "+err.Error())
+		return
+	}
+	if req.UptimeSeconds < 0 || req.FreeDiskBytes < 0 {
+		writeJSONErrorResponse(w, http.StatusBadRequest, "invalid_request", "uptime_seconds and free_disk_bytes must not be negative")
+		return
+	}
+
+	hb := &models.DeviceHeartbeat{
+		HostName:             hostName,
+		UptimeSeconds:        req.UptimeSeconds,
+		PlaylistVersion:      req.PlaylistVersion,
+		FreeDiskBytes:        req.FreeDiskBytes,
+		LastCreativeID:       req.LastCreativeID,
+		LastCreativePlayedAt: req.LastCreativePlayedAt,
+	}
+	var lastSeen *time.Time
+	if h.events != nil {
+		if prev, err := h.repo.GetLatestHeartbeat(r.Context(), hostName); err == nil {
+			receivedAt := prev.ReceivedAt
+			lastSeen = &receivedAt
+		}
+	}
+	if err := h.repo.RecordHeartbeat(r.Context(), hb); err != nil {
+		if err.Error() == "device not found" {
+			writeJSONErrorResponse(w, http.StatusNotFound, "not_found", "device not found")
+			return
+		}
+		writeJSONErrorResponse(w, http.StatusInternalServerError, "internal_error", "failed to record heartbeat: "+err.Error())
+		return
+	}
+
+	status := h.thresholds.Classify(&hb.ReceivedAt, hb.ReceivedAt)
+	if h.events != nil {
+		if from := h.thresholds.Classify(lastSeen, hb.ReceivedAt); from != status {
+			change := models.DeviceHealthChange{HostName: hostName, From: from, To: status, LastSeen: &hb.ReceivedAt}
+			if err := h.events.Publish(r.Context(), models.StreamEventDeviceHealthChanged, change); err != nil {
+				slog.ErrorContext(r.Context(), "failed to publish event", "event_type", models.StreamEventDeviceHealthChanged, "error", err)
+			}
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(models.DeviceHealth{
+		HostName:      hostName,
+		Status:        status,
+		LastHeartbeat: hb,
+	})
+}
+
+// @Tags Devices
+// @Summary Get device health
+// @Security BearerAuth
+// @Produce json
+// @Param hostName path string true "Device host name"
+// @Success 200 {object} models.DeviceHealth
+// @Failure 400 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/devices/{hostName}/health [get]
+func (h *DeviceHealthHandler) Get(w http.ResponseWriter, r *http.Request) {
+	hostName := chi.URLParam(r, "hostName")
+	if hostName == "" {
+		writeJSONErrorResponse(w, http.StatusBadRequest, "invalid_request", "device hostName is required")
+		return
+	}
+
+	health := models.DeviceHealth{HostName: hostName}
+	hb, err := h.repo.GetLatestHeartbeat(r.Context(), hostName)
+	if err != nil && err.Error() != "heartbeat not found" {
+		writeJSONErrorResponse(w, http.StatusInternalServerError, "internal_error", "failed to get heartbeat: "+err.Error())
+		return
+	}
+	var lastSeen *time.Time
+	if hb != nil {
+		health.LastHeartbeat = hb
+		lastSeen = &hb.ReceivedAt
+	}
+	health.Status = h.thresholds.Classify(lastSeen, time.Now())
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(health)
+}
+
+// @Tags Devices
+// @Summary Fleet health summary
+// @Security BearerAuth
+// @Produce json
+// @Param project_id query int false "Filter by project ID"
+// @Param city query string false "Filter by city"
+// @Param region query string false "Filter by region"
+// @Param device_type query string false "Filter by device type"
+// @Success 200 {object} models.DeviceHealthSummary
+// @Failure 400 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/devices/health/summary [get]
+func (h *DeviceHealthHandler) Summary(w http.ResponseWriter, r *http.Request) {
+	filters, err := parseDeviceFilters(r, h.thresholds)
+	if err != nil {
+		writeJSONErrorResponse(w, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+
+	summary, err := h.repo.Summary(r.Context(), filters)
+	if err != nil {
+		writeJSONErrorResponse(w, http.StatusInternalServerError, "internal_error", "failed to summarize device health: "+err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(summary)
+}
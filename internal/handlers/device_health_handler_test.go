@@ -0,0 +1,187 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"scm/internal/models"
+	"scm/internal/repository"
+)
+
+type mockDeviceHealthRepo struct {
+	known    map[string]bool
+	recorded *models.DeviceHeartbeat
+}
+
+var _ repository.DeviceHealthRepository = (*mockDeviceHealthRepo)(nil)
+
+func (m *mockDeviceHealthRepo) RecordHeartbeat(ctx context.Context, hb *models.DeviceHeartbeat) error {
+	if !m.known[hb.HostName] {
+		return errors.New("device not found")
+	}
+	hb.ReceivedAt = time.Now().UTC()
+	m.recorded = hb
+	return nil
+}
+func (m *mockDeviceHealthRepo) GetLatestHeartbeat(ctx context.Context, hostName string) (*models.DeviceHeartbeat, error) {
+	if m.recorded == nil || m.recorded.HostName != hostName {
+		return nil, errors.New("heartbeat not found")
+	}
+	return m.recorded, nil
+}
+func (m *mockDeviceHealthRepo) Summary(ctx context.Context, filters repository.DeviceFilters) (*models.DeviceHealthSummary, error) {
+	return &models.DeviceHealthSummary{}, nil
+}
+func (m *mockDeviceHealthRepo) OfflineCountsByVenue(ctx context.Context, venueID *int, offlineFor time.Duration) ([]models.VenueOfflineCount, error) {
+	return nil, nil
+}
+func (m *mockDeviceHealthRepo) ListReceivedBetween(ctx context.Context, from time.Time, to time.Time) ([]*models.DeviceHeartbeat, error) {
+	return nil, nil
+}
+
+var testThresholds = models.HealthThresholds{StaleAfter: 5 * time.Minute, OfflineAfter: 30 * time.Minute}
+
+func newHeartbeatRouter(repo *mockDeviceHealthRepo, token string) http.Handler {
+	h := NewDeviceHealthHandler(repo, testThresholds, token, nil)
+	r := chi.NewRouter()
+	r.Post("/devices/{hostName}/heartbeat", h.Heartbeat)
+	r.Get("/devices/{hostName}/health", h.Get)
+	return r
+}
+
+func TestHeartbeatRequiresToken(t *testing.T) {
+	r := newHeartbeatRouter(&mockDeviceHealthRepo{known: map[string]bool{"kiosk-1": true}}, "s3cret")
+
+	req := httptest.NewRequest(http.MethodPost, "/devices/kiosk-1/heartbeat", strings.NewReader(`{"uptime_seconds": 10}`))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 got %d (%s)", w.Code, w.Body.String())
+	}
+}
+
+func TestHeartbeatRecordsAndReportsOnline(t *testing.T) {
+	repo := &mockDeviceHealthRepo{known: map[string]bool{"kiosk-1": true}}
+	r := newHeartbeatRouter(repo, "s3cret")
+
+	body := `{"uptime_seconds": 3600, "playlist_version": "v42", "free_disk_bytes": 1024, "last_creative_id": "c1"}`
+	req := httptest.NewRequest(http.MethodPost, "/devices/kiosk-1/heartbeat", strings.NewReader(body))
+	req.Header.Set("X-Device-Token", "s3cret")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 got %d (%s)", w.Code, w.Body.String())
+	}
+	if repo.recorded == nil || repo.recorded.PlaylistVersion != "v42" || repo.recorded.UptimeSeconds != 3600 {
+		t.Fatalf("heartbeat not recorded: %+v", repo.recorded)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/devices/kiosk-1/health", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	var health models.DeviceHealth
+	if err := json.Unmarshal(w.Body.Bytes(), &health); err != nil {
+		t.Fatalf("invalid json: %v", err)
+	}
+	if health.Status != models.DeviceHealthOnline {
+		t.Fatalf("expected online, got %q", health.Status)
+	}
+}
+
+func TestHeartbeatPublishesHealthChange(t *testing.T) {
+	lastSeen := time.Now().UTC().Add(-time.Hour)
+	repo := &mockDeviceHealthRepo{
+		known:    map[string]bool{"kiosk-1": true},
+		recorded: &models.DeviceHeartbeat{HostName: "kiosk-1", ReceivedAt: lastSeen},
+	}
+	events := &recordingEvents{}
+	h := NewDeviceHealthHandler(repo, testThresholds, "s3cret", events)
+	r := chi.NewRouter()
+	r.Post("/devices/{hostName}/heartbeat", h.Heartbeat)
+
+	for range 2 {
+		req := httptest.NewRequest(http.MethodPost, "/devices/kiosk-1/heartbeat", strings.NewReader(`{"uptime_seconds": 10}`))
+		req.Header.Set("X-Device-Token", "s3cret")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected 200 got %d (%s)", w.Code, w.Body.String())
+		}
+	}
+
+	// Only the first heartbeat brings the device back; the second finds it online.
+	if len(events.types) != 1 || events.types[0] != models.StreamEventDeviceHealthChanged {
+		t.Fatalf("expected one device.health_changed, got %v", events.types)
+	}
+}
+
+func TestHeartbeatDisabledWithoutToken(t *testing.T) {
+	repo := &mockDeviceHealthRepo{known: map[string]bool{"kiosk-1": true}}
+	r := newHeartbeatRouter(repo, "")
+
+	req := httptest.NewRequest(http.MethodPost, "/devices/kiosk-1/heartbeat", strings.NewReader(`{}`))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 got %d (%s)", w.Code, w.Body.String())
+	}
+	if repo.recorded != nil {
+		t.Fatalf("expected nothing recorded, got %+v", repo.recorded)
+	}
+}
+
+func TestHeartbeatUnknownDevice(t *testing.T) {
+	r := newHeartbeatRouter(&mockDeviceHealthRepo{}, "s3cret")
+
+	req := httptest.NewRequest(http.MethodPost, "/devices/ghost/heartbeat", strings.NewReader(`{}`))
+	req.Header.Set("X-Device-Token", "s3cret")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 got %d (%s)", w.Code, w.Body.String())
+	}
+}
+
+func TestHealthThresholdsClassify(t *testing.T) {
+	now := time.Now()
+	at := func(ago time.Duration) *time.Time { ts := now.Add(-ago); return &ts }
+
+	cases := []struct {
+		lastSeen *time.Time
+		want     models.DeviceHealthStatus
+	}{
+		{at(time.Minute), models.DeviceHealthOnline},
+		{at(10 * time.Minute), models.DeviceHealthStale},
+		{at(time.Hour), models.DeviceHealthOffline},
+		{nil, models.DeviceHealthOffline},
+	}
+	for _, c := range cases {
+		if got := testThresholds.Classify(c.lastSeen, now); got != c.want {
+			t.Fatalf("Classify(%v) = %q, want %q", c.lastSeen, got, c.want)
+		}
+	}
+}
+
+func TestListDevicesRejectsUnknownHealth(t *testing.T) {
+	h := NewDeviceReadHandler(newDeviceRepo(t), testThresholds)
+
+	req := httptest.NewRequest(http.MethodGet, "/devices?health=sleepy", nil)
+	w := httptest.NewRecorder()
+	h.List(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 got %d (%s)", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Resources carry their row version as a strong ETag. Updates must send it back in
+// If-Match; a stale one gets 412 with the current representation so the client can
+// reconcile and retry.
+
+func formatETag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+func setETag(w http.ResponseWriter, version int) {
+	w.Header().Set("ETag", formatETag(version))
+}
+
+// requireIfMatch returns the version an update is conditional on, or writes 428 and
+// returns false when If-Match is missing. "*" matches any version and yields 0. Tags
+// that are not one of our ETags yield -1, which never matches.
+func requireIfMatch(w http.ResponseWriter, r *http.Request) (int, bool) {
+	raw := strings.TrimSpace(r.Header.Get("If-Match"))
+	if raw == "" {
+		writeJSONErrorResponse(w, http.StatusPreconditionRequired, "precondition_required", "If-Match header with the resource ETag is required")
+		return 0, false
+	}
+	if raw == "*" {
+		return 0, true
+	}
+	if len(raw) < 2 || raw[0] != '"' || raw[len(raw)-1] != '"' {
+		return -1, true
+	}
+	version, err := strconv.Atoi(raw[1 : len(raw)-1])
+	if err != nil || version < 1 {
+		return -1, true
+	}
+	return version, true
+}
+
+// ifMatchFails reports whether a version read before the update already rules out the
+// If-Match precondition, so handlers can refuse before doing side effects.
+func ifMatchFails(ifMatch, current int) bool {
+	return ifMatch != 0 && ifMatch != current
+}
+
+// writePreconditionFailed answers an update whose If-Match no longer matches with the
+// current representation and its ETag.
+func writePreconditionFailed(w http.ResponseWriter, current any, version int) {
+	setETag(w, version)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusPreconditionFailed)
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"error":   "precondition_failed",
+		"message": "Resource has been modified; re-read it and retry with the current ETag",
+		"current": current,
+	})
+}
@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	authmw "scm/internal/middleware"
+	"scm/internal/models"
+	"scm/internal/services"
+)
+
+// eventStreamKeepAlive is how often an idle stream sends a comment, so proxies do not
+// close the connection.
+const eventStreamKeepAlive = 25 * time.Second
+
+type EventStreamHandler struct {
+	stream    *services.EventStream
+	admins    services.AdminChecker
+	keepAlive time.Duration
+}
+
+// NewEventStreamHandler creates the dashboard event stream handler. admins see every
+// event; admins may be nil, in which case nobody does.
+func NewEventStreamHandler(stream *services.EventStream, admins services.AdminChecker) *EventStreamHandler {
+	return &EventStreamHandler{stream: stream, admins: admins, keepAlive: eventStreamKeepAlive}
+}
+
+// @Tags Events
+// @Summary Stream live events
+// @Description Server-Sent Events stream of campaigns being scheduled, activated, paused or completed, creative uploads, sync progress and device health changes. Reconnect with Last-Event-ID to receive the events missed in between; a "reset" event means they are no longer buffered and the client should reload. Campaign and creative events only reach the user who created the advertiser, and admins; sync.progress only reaches the user who started the sync, and admins.
+// @Security BearerAuth
+// @Produce text/event-stream
+// @Param types query string false "Comma-separated event types to receive (default: all)"
+// @Param Last-Event-ID header string false "ID of the last event received"
+// @Success 200 {string} string "event stream"
+// @Failure 400 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/events/stream [get]
+func (h *EventStreamHandler) Stream(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeJSONErrorResponse(w, http.StatusInternalServerError, "internal_error", "streaming is not supported")
+		return
+	}
+
+	viewer := services.StreamViewer{
+		UserID: authmw.UserIDFromContext(r.Context()),
+		Admin:  h.admins != nil && h.admins.IsAdmin(r.Context()),
+	}
+	if raw := r.URL.Query().Get("types"); raw != "" {
+		for _, t := range strings.Split(raw, ",") {
+			t = strings.TrimSpace(t)
+			if !slices.Contains(models.StreamEventTypes, t) {
+				writeJSONErrorResponse(w, http.StatusBadRequest, "invalid_request", "unknown event type: "+t)
+				return
+			}
+			viewer.Types = append(viewer.Types, t)
+		}
+	}
+
+	lastEventID := r.Header.Get("Last-Event-ID")
+	backlog, events, resumed, cancel := h.stream.Subscribe(viewer, lastEventID)
+	defer cancel()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	if lastEventID != "" && !resumed {
+		fmt.Fprint(w, "event: reset\ndata: {}\n\n")
+	}
+	for _, e := range backlog {
+		writeStreamEvent(w, e)
+	}
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(h.keepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case e, ok := <-events:
+			if !ok {
+				// Fell behind; the client reconnects and resumes from the buffer.
+				return
+			}
+			writeStreamEvent(w, e)
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+		}
+		flusher.Flush()
+	}
+}
+
+func writeStreamEvent(w http.ResponseWriter, e *services.StreamEvent) {
+	data, _ := json.Marshal(e)
+	fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", e.ID, e.Type, data)
+}
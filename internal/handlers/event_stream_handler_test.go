@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	authmw "scm/internal/middleware"
+	"scm/internal/models"
+	"scm/internal/services"
+)
+
+func newEventStreamServer(stream *services.EventStream) *httptest.Server {
+	h := NewEventStreamHandler(stream, nil)
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), authmw.CtxUserID, "user-1")
+		h.Stream(w, r.WithContext(ctx))
+	}))
+}
+
+// readStreamEvent reads one event from an SSE body and returns its fields.
+func readStreamEvent(t *testing.T, body *bufio.Reader) map[string]string {
+	t.Helper()
+	fields := map[string]string{}
+	for {
+		line, err := body.ReadString('\n')
+		if err != nil {
+			t.Fatalf("read stream: %v", err)
+		}
+		line = strings.TrimRight(line, "\n")
+		if line == "" {
+			if len(fields) > 0 {
+				return fields
+			}
+			continue
+		}
+		if name, value, ok := strings.Cut(line, ": "); ok && name != "" {
+			fields[name] = value
+		}
+	}
+}
+
+func TestEventStreamPushesAndResumes(t *testing.T) {
+	stream := services.NewEventStream(10)
+	srv := newEventStreamServer(stream)
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "?types=campaign.activated")
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("unexpected content type %q", ct)
+	}
+	_ = stream.Publish(context.Background(), models.WebhookEventCreativeUploaded, map[string]string{"id": "cr1"})
+	_ = stream.Publish(context.Background(), models.WebhookEventCampaignActivated, map[string]string{"id": "c1"})
+
+	first := readStreamEvent(t, bufio.NewReader(resp.Body))
+	resp.Body.Close()
+	if first["event"] != models.WebhookEventCampaignActivated || !strings.Contains(first["data"], `"id":"c1"`) {
+		t.Fatalf("unexpected event %v", first)
+	}
+
+	// The dashboard missed the completion while disconnected.
+	_ = stream.Publish(context.Background(), models.WebhookEventCampaignCompleted, map[string]string{"id": "c1"})
+	req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+	req.Header.Set("Last-Event-ID", first["id"])
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("reconnect: %v", err)
+	}
+	defer resp.Body.Close()
+	if missed := readStreamEvent(t, bufio.NewReader(resp.Body)); missed["event"] != models.WebhookEventCampaignCompleted {
+		t.Fatalf("expected the missed campaign.completed, got %v", missed)
+	}
+}
+
+func TestEventStreamSendsResetForUnknownID(t *testing.T) {
+	srv := newEventStreamServer(services.NewEventStream(10))
+	defer srv.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+	req.Header.Set("Last-Event-ID", "before-restart-42")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer resp.Body.Close()
+	if e := readStreamEvent(t, bufio.NewReader(resp.Body)); e["event"] != "reset" {
+		t.Fatalf("expected a reset event, got %v", e)
+	}
+}
+
+func TestEventStreamRejectsUnknownTypes(t *testing.T) {
+	h := NewEventStreamHandler(services.NewEventStream(10), nil)
+
+	w := httptest.NewRecorder()
+	h.Stream(w, httptest.NewRequest(http.MethodGet, "/events/stream?types=campaign.archived", nil))
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 got %d (%s)", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+
+	"scm/internal/models"
+)
+
+// geoJSONBatchSize is the page size used when walking a full result set for export.
+const geoJSONBatchSize = 500
+
+func writeGeoJSON(w http.ResponseWriter, collection models.GeoJSONFeatureCollection) {
+	w.Header().Set("Content-Type", "application/geo+json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(collection)
+}
+
+func deviceFeature(d *models.Device) models.GeoJSONFeature {
+	var geometry *models.GeoJSONGeometry
+	if lon, lat, ok := d.Coordinates(); ok {
+		geometry = models.NewGeoJSONPoint(lon, lat)
+	}
+
+	return models.GeoJSONFeature{
+		Type:     "Feature",
+		ID:       d.HostName,
+		Geometry: geometry,
+		Properties: map[string]any{
+			"id":          d.ID,
+			"host_name":   d.HostName,
+			"name":        d.Name,
+			"project":     d.Project,
+			"region_code": d.Region.Code,
+			"device_type": d.DeviceType.Code,
+			"sync_status": d.SyncStatus,
+		},
+	}
+}
+
+// venueFeature places a venue at the centroid of its located devices and
+// aggregates the device properties the map layers group by.
+func venueFeature(v *models.Venue, devices []*models.Device) models.GeoJSONFeature {
+	var sumLon, sumLat float64
+	located := 0
+
+	hostNames := make([]string, 0, len(devices))
+	projects := map[int]struct{}{}
+	regionCodes := map[string]struct{}{}
+	deviceTypes := map[string]struct{}{}
+	syncStatus := map[string]int{}
+
+	for _, d := range devices {
+		if lon, lat, ok := d.Coordinates(); ok {
+			sumLon += lon
+			sumLat += lat
+			located++
+		}
+		hostNames = append(hostNames, d.HostName)
+		projects[d.Project] = struct{}{}
+		if d.Region.Code != "" {
+			regionCodes[d.Region.Code] = struct{}{}
+		}
+		if d.DeviceType.Code != "" {
+			deviceTypes[d.DeviceType.Code] = struct{}{}
+		}
+		status := "unknown"
+		if d.SyncStatus != nil && *d.SyncStatus != "" {
+			status = *d.SyncStatus
+		}
+		syncStatus[status]++
+	}
+
+	var geometry *models.GeoJSONGeometry
+	if located > 0 {
+		geometry = models.NewGeoJSONPoint(sumLon/float64(located), sumLat/float64(located))
+	}
+
+	projectIDs := make([]int, 0, len(projects))
+	for id := range projects {
+		projectIDs = append(projectIDs, id)
+	}
+	sort.Ints(projectIDs)
+	sort.Strings(hostNames)
+
+	return models.GeoJSONFeature{
+		Type:     "Feature",
+		ID:       v.ID,
+		Geometry: geometry,
+		Properties: map[string]any{
+			"id":           v.ID,
+			"name":         v.Name,
+			"device_count": len(devices),
+			"host_names":   hostNames,
+			"projects":     projectIDs,
+			"region_codes": sortedKeys(regionCodes),
+			"device_types": sortedKeys(deviceTypes),
+			"sync_status":  syncStatus,
+		},
+	}
+}
+
+func sortedKeys(set map[string]struct{}) []string {
+	out := make([]string, 0, len(set))
+	for k := range set {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
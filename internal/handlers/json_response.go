@@ -8,10 +8,12 @@ import (
 )
 
 type Pagination struct {
-	Page       int `json:"page"`
-	PageSize   int `json:"page_size"`
-	Total      int `json:"total"`
-	TotalPages int `json:"total_pages"`
+	Page       int    `json:"page"`
+	PageSize   int    `json:"page_size"`
+	Total      int    `json:"total"`
+	TotalPages int    `json:"total_pages"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	PrevCursor string `json:"prev_cursor,omitempty"`
 }
 
 type paginationParams struct {
@@ -50,6 +52,16 @@ func parsePaginationParams(r *http.Request, defaultPageSize int, maxPageSize int
 	return paginationParams{page: page, pageSize: pageSize, limit: pageSize, offset: offset}, nil
 }
 
+// parseIncludeDeleted reads the include_deleted query flag used by list endpoints of
+// soft-deletable resources.
+func parseIncludeDeleted(r *http.Request) (bool, error) {
+	raw := r.URL.Query().Get("include_deleted")
+	if raw == "" {
+		return false, nil
+	}
+	return strconv.ParseBool(raw)
+}
+
 func buildPagination(page int, pageSize int, total int) Pagination {
 	totalPages := 0
 	if pageSize > 0 {
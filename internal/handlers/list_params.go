@@ -0,0 +1,216 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"scm/internal/interfaces"
+)
+
+// List endpoints page either by page/page_size (OFFSET) or, given a cursor, by keyset
+// from the row the cursor was taken at. Both modes accept sort=field,-field and return
+// next_cursor/prev_cursor, so a client can switch to cursors after any page.
+
+// CursorPagination is the pagination member of a list read with a cursor.
+type CursorPagination struct {
+	PageSize   int    `json:"page_size"`
+	Total      int    `json:"total"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	PrevCursor string `json:"prev_cursor,omitempty"`
+}
+
+type listParams struct {
+	paginationParams
+	sort       []interfaces.SortKey
+	cursorMode bool
+	backward   bool
+	position   []any
+}
+
+// listCursor is the decoded form of the opaque next_cursor/prev_cursor tokens: the sort
+// they belong to and the sort values of the row the page continues from.
+type listCursor struct {
+	Sort     string            `json:"s"`
+	Backward bool              `json:"b,omitempty"`
+	Values   []json.RawMessage `json:"v"`
+}
+
+func parseListParams(r *http.Request, defaultPageSize int, maxPageSize int, sortable interfaces.Sortable) (listParams, error) {
+	p, err := parsePaginationParams(r, defaultPageSize, maxPageSize)
+	if err != nil {
+		return listParams{}, err
+	}
+	lp := listParams{paginationParams: p}
+
+	q := r.URL.Query()
+	rawSort := q.Get("sort")
+	var cursor *listCursor
+	if raw := q.Get("cursor"); raw != "" {
+		if q.Get("page") != "" {
+			return listParams{}, errors.New("cursor cannot be combined with page")
+		}
+		if cursor, err = decodeCursor(raw); err != nil {
+			return listParams{}, errors.New("invalid cursor")
+		}
+		if rawSort == "" {
+			rawSort = cursor.Sort
+		}
+	}
+
+	if lp.sort, err = sortable.Parse(rawSort); err != nil {
+		return listParams{}, err
+	}
+
+	if cursor != nil {
+		if cursor.Sort != interfaces.FormatSort(lp.sort) || len(cursor.Values) != len(lp.sort) {
+			return listParams{}, errors.New("cursor does not match the requested sort")
+		}
+		for _, raw := range cursor.Values {
+			v, err := cursorValue(raw)
+			if err != nil {
+				return listParams{}, errors.New("invalid cursor")
+			}
+			lp.position = append(lp.position, v)
+		}
+		lp.cursorMode = true
+		lp.backward = cursor.Backward
+		lp.page, lp.offset = 0, 0
+	}
+	return lp, nil
+}
+
+// listPage is the page the repository should read. A cursor page asks for one row more
+// than it returns to find out whether another page follows.
+func (p listParams) listPage() interfaces.ListPage {
+	page := interfaces.ListPage{Sort: p.sort, Limit: p.limit, Offset: p.offset}
+	if p.cursorMode {
+		page.Limit = p.pageSize + 1
+		if p.backward {
+			page.Before = p.position
+		} else {
+			page.After = p.position
+		}
+	}
+	return page
+}
+
+// writeListResponse writes one page of items read with p.listPage(). data builds the
+// response's data member from the page's items; nil writes the items themselves.
+func writeListResponse[T any](w http.ResponseWriter, p listParams, items []T, total int, data func([]T) any) {
+	resp, err := listResponse(p, items, total, data)
+	if err != nil {
+		writeJSONErrorResponse(w, http.StatusInternalServerError, "internal_error", "failed to build page cursor: "+err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// listResponse builds the body writeListResponse writes, for handlers that add members.
+func listResponse[T any](p listParams, items []T, total int, data func([]T) any) (map[string]any, error) {
+	var hasNext, hasPrev bool
+	if p.cursorMode {
+		more := len(items) > p.pageSize
+		if more && p.backward {
+			items = items[1:]
+		} else if more {
+			items = items[:p.pageSize]
+		}
+		hasNext = more || p.backward
+		hasPrev = more || !p.backward
+	} else {
+		hasNext = p.offset+len(items) < total
+		hasPrev = p.page > 1
+	}
+
+	var next, prev string
+	var err error
+	if len(items) > 0 && hasNext {
+		next, err = encodeCursor(p.sort, items[len(items)-1], false)
+	}
+	if err == nil && len(items) > 0 && hasPrev {
+		prev, err = encodeCursor(p.sort, items[0], true)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var pagination any
+	if p.cursorMode {
+		pagination = CursorPagination{PageSize: p.pageSize, Total: total, NextCursor: next, PrevCursor: prev}
+	} else {
+		pg := buildPagination(p.page, p.pageSize, total)
+		pg.NextCursor, pg.PrevCursor = next, prev
+		pagination = pg
+	}
+
+	var body any = items
+	if data != nil {
+		body = data(items)
+	}
+	return map[string]any{"data": body, "pagination": pagination}, nil
+}
+
+// encodeCursor builds the cursor of the page after (or, backward, before) item. The sort
+// values are taken from item's JSON, whose field names the sort fields follow.
+func encodeCursor(keys []interfaces.SortKey, item any, backward bool) (string, error) {
+	raw, err := json.Marshal(item)
+	if err != nil {
+		return "", err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return "", err
+	}
+
+	c := listCursor{Sort: interfaces.FormatSort(keys), Backward: backward}
+	for _, k := range keys {
+		v, ok := fields[k.Field]
+		if !ok || string(v) == "null" {
+			return "", fmt.Errorf("item has no %s", k.Field)
+		}
+		c.Values = append(c.Values, v)
+	}
+
+	b, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func decodeCursor(raw string) (*listCursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return nil, err
+	}
+	var c listCursor
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// cursorValue decodes one sort value of a cursor into a query argument. Numbers are kept
+// as their decimal text so that Postgres, not float64, decides their precision.
+func cursorValue(raw json.RawMessage) (any, error) {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	switch v := v.(type) {
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	default:
+		return nil, fmt.Errorf("unsupported cursor value %s", raw)
+	}
+}
@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+)
+
+// PATCH endpoints take an RFC 7396 JSON merge patch. It is applied to the resource's
+// editable representation (the same document PUT replaces), so a null member clears
+// that field and nested objects are merged member by member.
+
+var errUnsupportedPatchType = errors.New("unsupported merge patch content type")
+
+// decodeMergePatch applies the merge patch in r's body to doc and decodes the result
+// into out, which must point to a zero value of doc's type.
+func decodeMergePatch(r *http.Request, doc any, out any) error {
+	if ct := r.Header.Get("Content-Type"); ct != "" {
+		mediaType, _, err := mime.ParseMediaType(ct)
+		if err != nil || (mediaType != "application/merge-patch+json" && mediaType != "application/json") {
+			return errUnsupportedPatchType
+		}
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return fmt.Errorf("read body: %w", err)
+	}
+	var patch any
+	if err := json.Unmarshal(body, &patch); err != nil {
+		return fmt.Errorf("invalid JSON: %w", err)
+	}
+	if _, ok := patch.(map[string]any); !ok {
+		return errors.New("merge patch must be a JSON object")
+	}
+
+	raw, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	var target any
+	if err := json.Unmarshal(raw, &target); err != nil {
+		return err
+	}
+
+	merged, err := json.Marshal(mergePatch(target, patch))
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(merged, out); err != nil {
+		return fmt.Errorf("patched document is invalid: %w", err)
+	}
+	return nil
+}
+
+// mergePatch implements the MergePatch algorithm from RFC 7396, section 2.
+func mergePatch(target, patch any) any {
+	patchObj, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+	targetObj, ok := target.(map[string]any)
+	if !ok {
+		targetObj = map[string]any{}
+	}
+	for name, value := range patchObj {
+		if value == nil {
+			delete(targetObj, name)
+			continue
+		}
+		targetObj[name] = mergePatch(targetObj[name], value)
+	}
+	return targetObj
+}
+
+func writeMergePatchError(w http.ResponseWriter, err error) {
+	if errors.Is(err, errUnsupportedPatchType) {
+		writeJSONErrorResponse(w, http.StatusUnsupportedMediaType, "unsupported_media_type", "PATCH requires Content-Type application/merge-patch+json")
+		return
+	}
+	writeJSONErrorResponse(w, http.StatusBadRequest, "invalid_request", "Invalid merge patch: "+err.Error())
+}
@@ -4,6 +4,7 @@ import (
 	"net/http"
 
 	"github.com/go-chi/chi/v5"
+	"scm/internal/models"
 	"scm/internal/repository"
 )
 
@@ -23,12 +24,17 @@ func NewProjectHandler(repo repository.ProjectRepository) *ProjectHandler {
 // @Param page_size query int false "Page size" default(20)
 // @Param city query string false "Filter by city"
 // @Param region query string false "Filter by region"
+// @Param tag query []string false "Only projects annotated with all these tags" collectionFormat(multi)
+// @Param internal_owner query string false "Filter by the annotated internal owner"
+// @Param attribute.key query string false "Filter by an annotation attribute, e.g. attribute.team=ops"
+// @Param sort query string false "Sort fields, e.g. name,-priority"
+// @Param cursor query string false "next_cursor or prev_cursor of a previous page"
 // @Success 200 {object} map[string]interface{}
 // @Failure 400 {object} map[string]interface{}
 // @Failure 500 {object} map[string]interface{}
 // @Router /api/v1/projects [get]
 func (h *ProjectHandler) List(w http.ResponseWriter, r *http.Request) {
-	pagination, err := parsePaginationParams(r, 20, 100)
+	pagination, err := parseListParams(r, 20, 100, repository.ProjectSort)
 	if err != nil {
 		writeJSONErrorResponse(w, http.StatusBadRequest, "invalid_pagination", "invalid pagination: "+err.Error())
 		return
@@ -41,11 +47,16 @@ func (h *ProjectHandler) List(w http.ResponseWriter, r *http.Request) {
 	if region := r.URL.Query().Get("region"); region != "" {
 		filters.Region = &region
 	}
+	filters.Annotations, err = parseAnnotationFilters(r.URL.Query())
+	if err != nil {
+		writeJSONErrorResponse(w, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
 
-	var projects any
+	var projects []*models.Project
 	var total int
-	if filters.City != nil || filters.Region != nil {
-		projects, err = h.repo.ListWithFilters(r.Context(), filters, pagination.limit, pagination.offset)
+	if filters.HasAny() {
+		projects, err = h.repo.ListWithFilters(r.Context(), filters, pagination.listPage())
 		if err != nil {
 			writeJSONErrorResponse(w, http.StatusInternalServerError, "internal_error", "failed to list projects with filters: "+err.Error())
 			return
@@ -56,7 +67,7 @@ func (h *ProjectHandler) List(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 	} else {
-		projects, err = h.repo.List(r.Context(), pagination.limit, pagination.offset)
+		projects, err = h.repo.List(r.Context(), pagination.listPage())
 		if err != nil {
 			writeJSONErrorResponse(w, http.StatusInternalServerError, "internal_error", "failed to list projects: "+err.Error())
 			return
@@ -68,7 +79,7 @@ func (h *ProjectHandler) List(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	writePaginatedResponse(w, http.StatusOK, projects, pagination.page, pagination.pageSize, total)
+	writeListResponse(w, pagination, projects, total, nil)
 }
 
 // @Tags Projects
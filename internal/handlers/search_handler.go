@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	authmw "scm/internal/middleware"
+	"scm/internal/models"
+	"scm/internal/repository"
+	"scm/internal/services"
+)
+
+const (
+	minSearchLength = 2
+	maxSearchLength = 200
+)
+
+type SearchHandler struct {
+	repo   repository.SearchRepository
+	admins services.AdminChecker
+}
+
+// NewSearchHandler creates the search handler. Admins search every record; admins may be
+// nil, in which case everyone is limited to their own advertisers.
+func NewSearchHandler(repo repository.SearchRepository, admins services.AdminChecker) *SearchHandler {
+	return &SearchHandler{repo: repo, admins: admins}
+}
+
+// @Tags Search
+// @Summary Search
+// @Description Searches advertiser names and emails, campaign names and cities, creative names, device names, host names and descriptions, and project and venue names. Results are ranked by trigram similarity, best first; soft-deleted records are not returned. Non-admins only find the advertisers they created and those advertisers' campaigns and creatives.
+// @Security BearerAuth
+// @Produce json
+// @Param q query string true "Search text (at least 2 characters)"
+// @Param types query string false "Comma-separated result types (advertiser, campaign, creative, device, project, venue); default all"
+// @Param limit query int false "Maximum number of results" default(20)
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/search [get]
+func (h *SearchHandler) Search(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	text := strings.TrimSpace(q.Get("q"))
+	if n := utf8.RuneCountInString(text); n < minSearchLength || n > maxSearchLength {
+		writeJSONErrorResponse(w, http.StatusBadRequest, "invalid_request",
+			fmt.Sprintf("q must be between %d and %d characters", minSearchLength, maxSearchLength))
+		return
+	}
+
+	types := models.SearchTypes
+	if raw := q.Get("types"); raw != "" {
+		types = nil
+		for _, t := range strings.Split(raw, ",") {
+			t = strings.TrimSpace(t)
+			if !slices.Contains(models.SearchTypes, t) {
+				writeJSONErrorResponse(w, http.StatusBadRequest, "invalid_request",
+					fmt.Sprintf("unknown type %q (allowed: %s)", t, strings.Join(models.SearchTypes, ", ")))
+				return
+			}
+			types = append(types, t)
+		}
+	}
+
+	limit := 20
+	if raw := q.Get("limit"); raw != "" {
+		v, err := strconv.Atoi(raw)
+		if err != nil || v <= 0 {
+			writeJSONErrorResponse(w, http.StatusBadRequest, "invalid_request", "limit must be a positive integer")
+			return
+		}
+		limit = min(v, 100)
+	}
+
+	viewer := repository.SearchViewer{
+		UserID: authmw.UserIDFromContext(r.Context()),
+		Admin:  h.admins != nil && h.admins.IsAdmin(r.Context()),
+	}
+	results, err := h.repo.Search(r.Context(), viewer, text, types, limit)
+	if err != nil {
+		writeJSONErrorResponse(w, http.StatusInternalServerError, "internal_error", "failed to search: "+err.Error())
+		return
+	}
+	if results == nil {
+		results = []models.SearchResult{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(map[string]any{"data": results})
+}
@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	authmw "scm/internal/middleware"
+	"scm/internal/models"
+	"scm/internal/repository"
+)
+
+type mockSearchRepo struct {
+	viewer repository.SearchViewer
+	query  string
+	types  []string
+	limit  int
+}
+
+var _ repository.SearchRepository = (*mockSearchRepo)(nil)
+
+func (m *mockSearchRepo) Search(ctx context.Context, viewer repository.SearchViewer, query string, types []string, limit int) ([]models.SearchResult, error) {
+	m.viewer, m.query, m.types, m.limit = viewer, query, types, limit
+	return []models.SearchResult{{Type: models.SearchTypeDevice, ID: "7", Title: "Lobby kiosk", Subtitle: "kiosk-7", Rank: 0.8}}, nil
+}
+
+func TestSearch(t *testing.T) {
+	repo := &mockSearchRepo{}
+	handler := NewSearchHandler(repo, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/search?q=+kiosk+&types=device,venue&limit=500", nil)
+	w := httptest.NewRecorder()
+	handler.Search(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if repo.query != "kiosk" || strings.Join(repo.types, ",") != "device,venue" || repo.limit != 100 {
+		t.Fatalf("unexpected search call: %+v", repo)
+	}
+	var resp struct {
+		Data []models.SearchResult `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("invalid json: %v", err)
+	}
+	if len(resp.Data) != 1 || resp.Data[0].Type != "device" || resp.Data[0].ID != "7" {
+		t.Fatalf("unexpected results: %+v", resp.Data)
+	}
+
+	if repo.viewer.Admin {
+		t.Fatalf("expected a non-admin viewer, got %+v", repo.viewer)
+	}
+
+	for _, query := range []string{"", "?q=k", "?q=kiosk&types=user", "?q=kiosk&limit=0"} {
+		w := httptest.NewRecorder()
+		handler.Search(w, httptest.NewRequest(http.MethodGet, "/search"+query, nil))
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("expected 400 for %q, got %d", query, w.Code)
+		}
+	}
+}
+
+func TestSearchPassesViewer(t *testing.T) {
+	repo := &mockSearchRepo{}
+	handler := NewSearchHandler(repo, authmw.NewAdmins([]string{"admin@example.com"}))
+
+	for _, c := range []struct {
+		email string
+		admin bool
+	}{{"admin@example.com", true}, {"user@example.com", false}} {
+		req := httptest.NewRequest(http.MethodGet, "/search?q=acme", nil)
+		ctx := context.WithValue(req.Context(), authmw.CtxUserID, "user-1")
+		ctx = context.WithValue(ctx, authmw.CtxEmail, c.email)
+		handler.Search(httptest.NewRecorder(), req.WithContext(ctx))
+
+		if repo.viewer.UserID != "user-1" || repo.viewer.Admin != c.admin {
+			t.Fatalf("%s: viewer = %+v, want admin %v", c.email, repo.viewer, c.admin)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"scm/internal/models"
+	"scm/internal/repository"
+)
+
+// SyncChangeHandler serves the change feed console sync records for devices and projects.
+type SyncChangeHandler struct {
+	repo       repository.SyncRepository
+	deviceRepo repository.DeviceRepository
+}
+
+func NewSyncChangeHandler(repo repository.SyncRepository, deviceRepo repository.DeviceRepository) *SyncChangeHandler {
+	return &SyncChangeHandler{repo: repo, deviceRepo: deviceRepo}
+}
+
+// @Tags Devices
+// @Summary Device sync history
+// @Description Lists the changes console sync made to a device, newest first.
+// @Security BearerAuth
+// @Produce json
+// @Param hostName path string true "Device host name"
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Page size" default(20)
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/devices/{hostName}/history [get]
+func (h *SyncChangeHandler) DeviceHistory(w http.ResponseWriter, r *http.Request) {
+	hostName := chi.URLParam(r, "hostName")
+	if _, err := h.deviceRepo.GetByHostName(r.Context(), hostName); err != nil {
+		if err.Error() == "device not found" {
+			writeJSONErrorResponse(w, http.StatusNotFound, "not_found", "device not found")
+			return
+		}
+		writeJSONErrorResponse(w, http.StatusInternalServerError, "internal_error", "failed to get device: "+err.Error())
+		return
+	}
+
+	entityType := models.SyncEntityDevice
+	h.list(w, r, repository.SyncChangeFilters{EntityType: &entityType, EntityKey: &hostName})
+}
+
+// @Tags Sync
+// @Summary Sync run changes
+// @Description Lists the device and project changes a sync run made, newest first.
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Sync run ID"
+// @Param entity_type query string false "Only changes to this entity type (device, project)"
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Page size" default(20)
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/sync/runs/{id}/changes [get]
+func (h *SyncChangeHandler) RunChanges(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		writeJSONErrorResponse(w, http.StatusBadRequest, "invalid_request", "invalid sync run ID")
+		return
+	}
+	if _, err := h.repo.GetRun(r.Context(), id); err != nil {
+		if err.Error() == "sync run not found" {
+			writeJSONErrorResponse(w, http.StatusNotFound, "not_found", "sync run not found")
+			return
+		}
+		writeJSONErrorResponse(w, http.StatusInternalServerError, "internal_error", "failed to get sync run: "+err.Error())
+		return
+	}
+
+	filters := repository.SyncChangeFilters{RunID: &id}
+	if entityType := r.URL.Query().Get("entity_type"); entityType != "" {
+		if entityType != models.SyncEntityDevice && entityType != models.SyncEntityProject {
+			writeJSONErrorResponse(w, http.StatusBadRequest, "invalid_request", "entity_type must be device or project")
+			return
+		}
+		filters.EntityType = &entityType
+	}
+	h.list(w, r, filters)
+}
+
+func (h *SyncChangeHandler) list(w http.ResponseWriter, r *http.Request, filters repository.SyncChangeFilters) {
+	pagination, err := parsePaginationParams(r, 20, 100)
+	if err != nil {
+		writeJSONErrorResponse(w, http.StatusBadRequest, "invalid_pagination", "invalid pagination: "+err.Error())
+		return
+	}
+
+	changes, err := h.repo.ListChanges(r.Context(), filters, pagination.limit, pagination.offset)
+	if err != nil {
+		writeJSONErrorResponse(w, http.StatusInternalServerError, "internal_error", "failed to list sync changes: "+err.Error())
+		return
+	}
+	total, err := h.repo.CountChanges(r.Context(), filters)
+	if err != nil {
+		writeJSONErrorResponse(w, http.StatusInternalServerError, "internal_error", "failed to count sync changes: "+err.Error())
+		return
+	}
+
+	writePaginatedResponse(w, http.StatusOK, changes, pagination.page, pagination.pageSize, total)
+}
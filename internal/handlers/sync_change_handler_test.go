@@ -0,0 +1,207 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"scm/internal/models"
+	"scm/internal/repository"
+)
+
+type mockSyncRepo struct {
+	runs    []*models.SyncRun
+	changes []*models.SyncChange
+	mark    *time.Time
+}
+
+var _ repository.SyncRepository = (*mockSyncRepo)(nil)
+
+func (m *mockSyncRepo) StartRun(ctx context.Context, mode string) (*models.SyncRun, error) {
+	run := &models.SyncRun{ID: int64(len(m.runs) + 1), Mode: mode, StartedAt: time.Now()}
+	m.runs = append(m.runs, run)
+	return run, nil
+}
+func (m *mockSyncRepo) FinishRun(ctx context.Context, run *models.SyncRun) error { return nil }
+func (m *mockSyncRepo) GetRun(ctx context.Context, id int64) (*models.SyncRun, error) {
+	for _, run := range m.runs {
+		if run.ID == id {
+			return run, nil
+		}
+	}
+	return nil, errors.New("sync run not found")
+}
+func (m *mockSyncRepo) LastHighWaterMark(ctx context.Context) (*time.Time, error) {
+	return m.mark, nil
+}
+func (m *mockSyncRepo) RecordChange(ctx context.Context, change *models.SyncChange) error {
+	change.ID = int64(len(m.changes) + 1)
+	m.changes = append(m.changes, change)
+	return nil
+}
+func (m *mockSyncRepo) ListChanges(ctx context.Context, filters repository.SyncChangeFilters, limit int, offset int) ([]*models.SyncChange, error) {
+	out := []*models.SyncChange{}
+	for _, c := range m.changes {
+		if filters.RunID != nil && c.RunID != *filters.RunID {
+			continue
+		}
+		if filters.EntityType != nil && c.EntityType != *filters.EntityType {
+			continue
+		}
+		if filters.EntityKey != nil && c.EntityKey != *filters.EntityKey {
+			continue
+		}
+		out = append(out, c)
+	}
+	return out, nil
+}
+func (m *mockSyncRepo) CountChanges(ctx context.Context, filters repository.SyncChangeFilters) (int, error) {
+	changes, err := m.ListChanges(ctx, filters, 0, 0)
+	return len(changes), err
+}
+
+func TestSyncRecordsFieldChanges(t *testing.T) {
+	syncRepo := &mockSyncRepo{}
+	h := NewSyncHandler(nil, nil, nil, syncRepo, nil, nil, nil, nil, 1)
+	run, _ := syncRepo.StartRun(context.Background(), models.SyncModeFull)
+	store := syncStore{changes: syncRepo}
+	var recorded int
+
+	earlier := time.Now().Add(-time.Hour)
+	now := time.Now()
+	before := &models.Device{
+		HostName: "kiosk-1", Name: "Kiosk", LastSyncedAt: &earlier,
+		Region:       models.Region{Code: "sf"},
+		DeviceConfig: json.RawMessage(`{"city": "San Francisco", "volume": 5}`),
+	}
+	after := &models.Device{
+		HostName: "kiosk-1", Name: "Kiosk", LastSyncedAt: &now,
+		Region:       models.Region{Code: "la"},
+		DeviceConfig: json.RawMessage(`{"city": "Los Angeles", "volume": 5}`),
+	}
+
+	for _, c := range []struct {
+		key           string
+		before, after *models.Device
+	}{
+		{"kiosk-1", before, after},
+		{"kiosk-1", after, after},
+		{"kiosk-2", nil, after},
+	} {
+		change, err := diffSyncedRecord(models.SyncEntityDevice, c.key, c.before, c.after)
+		if err != nil {
+			t.Fatalf("diff %s: %v", c.key, err)
+		}
+		n, err := h.recordChange(context.Background(), store, run, change)
+		if err != nil {
+			t.Fatalf("record %s: %v", c.key, err)
+		}
+		recorded += n
+	}
+
+	if recorded != 2 || len(syncRepo.changes) != 2 {
+		t.Fatalf("expected an update and a create, got %+v", syncRepo.changes)
+	}
+	update := syncRepo.changes[0]
+	if update.Action != models.SyncActionUpdated || len(update.Changes) != 2 {
+		t.Fatalf("unexpected update %+v", update)
+	}
+	if c := update.Changes[0]; c.Field != "device_config.city" || c.From != "San Francisco" || c.To != "Los Angeles" {
+		t.Fatalf("unexpected city change %+v", c)
+	}
+	if c := update.Changes[1]; c.Field != "region.code" || c.From != "sf" || c.To != "la" {
+		t.Fatalf("unexpected region change %+v", c)
+	}
+	if created := syncRepo.changes[1]; created.Action != models.SyncActionCreated || created.EntityKey != "kiosk-2" {
+		t.Fatalf("unexpected create %+v", created)
+	}
+}
+
+func TestDeviceHistoryAndRunChanges(t *testing.T) {
+	syncRepo := &mockSyncRepo{}
+	run, _ := syncRepo.StartRun(context.Background(), models.SyncModeFull)
+	syncRepo.changes = []*models.SyncChange{
+		{ID: 1, RunID: run.ID, EntityType: models.SyncEntityDevice, EntityKey: "kiosk-1", Action: models.SyncActionUpdated},
+		{ID: 2, RunID: run.ID, EntityType: models.SyncEntityProject, EntityKey: "Downtown", Action: models.SyncActionCreated},
+	}
+	deviceRepo := newDeviceRepo(t, &models.Device{HostName: "kiosk-1"})
+	h := NewSyncChangeHandler(syncRepo, deviceRepo)
+	r := chi.NewRouter()
+	r.Get("/devices/{hostName}/history", h.DeviceHistory)
+	r.Get("/sync/runs/{id}/changes", h.RunChanges)
+
+	cases := []struct {
+		path  string
+		want  int
+		count int
+	}{
+		{"/devices/kiosk-1/history", http.StatusOK, 1},
+		{"/devices/kiosk-9/history", http.StatusNotFound, 0},
+		{"/sync/runs/1/changes", http.StatusOK, 2},
+		{"/sync/runs/1/changes?entity_type=project", http.StatusOK, 1},
+		{"/sync/runs/1/changes?entity_type=venue", http.StatusBadRequest, 0},
+		{"/sync/runs/2/changes", http.StatusNotFound, 0},
+		{"/sync/runs/x/changes", http.StatusBadRequest, 0},
+	}
+	for _, c := range cases {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, c.path, nil))
+		if w.Code != c.want {
+			t.Fatalf("%s: expected %d got %d (%s)", c.path, c.want, w.Code, w.Body.String())
+		}
+		if c.want != http.StatusOK {
+			continue
+		}
+		var resp struct {
+			Data []models.SyncChange `json:"data"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("invalid json: %v", err)
+		}
+		if len(resp.Data) != c.count {
+			t.Fatalf("%s: expected %d changes got %d", c.path, c.count, len(resp.Data))
+		}
+	}
+}
+
+func TestIncrementalSyncDeviceChanged(t *testing.T) {
+	mark := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	before := mark.Add(-time.Hour)
+	after := mark.Add(time.Hour)
+	inc := &incrementalSync{since: mark, known: map[string]*time.Time{
+		"kiosk-1": &before,
+		"kiosk-2": nil,
+	}}
+
+	cases := []struct {
+		name   string
+		device *models.Device
+		want   bool
+	}{
+		{"unchanged", &models.Device{HostName: "kiosk-1", LastSyncedAt: &before}, false},
+		{"flagged", &models.Device{HostName: "kiosk-1", LastSyncedAt: &before, Change: true}, true},
+		{"synced since mark", &models.Device{HostName: "kiosk-1", LastSyncedAt: &after}, true},
+		{"stored without sync time", &models.Device{HostName: "kiosk-2", LastSyncedAt: &before}, true},
+		{"never synced", &models.Device{HostName: "kiosk-1"}, true},
+		{"new", &models.Device{HostName: "kiosk-3", LastSyncedAt: &before}, true},
+	}
+	for _, c := range cases {
+		if got := inc.deviceChanged(c.device); got != c.want {
+			t.Errorf("%s: expected %v got %v", c.name, c.want, got)
+		}
+	}
+}
+
+func TestSyncConsoleRejectsUnknownMode(t *testing.T) {
+	h := NewSyncHandler(nil, nil, nil, nil, nil, nil, nil, nil, 0)
+	w := httptest.NewRecorder()
+	h.SyncConsole(w, httptest.NewRequest(http.MethodPost, "/sync/console?mode=partial", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 got %d", w.Code)
+	}
+}
@@ -1,10 +1,17 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
+	"log/slog"
 	"net/http"
+	"reflect"
+	"sync"
 	"time"
 
+	"scm/internal/audit"
+	"scm/internal/metrics"
 	"scm/internal/models"
 	"scm/internal/repository"
 	"scm/internal/services"
@@ -13,96 +20,462 @@ import (
 type SyncHandler struct {
 	projectRepo repository.ProjectRepository
 	deviceRepo  repository.DeviceRepository
+	venueRepo   repository.VenueRepository
+	syncRepo    repository.SyncRepository
+	uow         repository.UnitOfWork
 	client      *services.CityPostConsoleClient
+	alerts      *services.AlertService
+	events      services.EventPublisher
+	concurrency int
 }
 
-func NewSyncHandler(projectRepo repository.ProjectRepository, deviceRepo repository.DeviceRepository, client *services.CityPostConsoleClient) *SyncHandler {
+// NewSyncHandler creates the sync handler. alerts may be nil, in which case sync
+// failures do not raise sync_errors alerts, and events may be nil, in which case
+// syncs do not raise sync.progress and sync.completed. syncRepo may be nil, in which case runs
+// and their changes are not recorded and every sync is a full one. concurrency bounds
+// the per-project device fetches running at once. uow may be nil, in which case each
+// project and each project's devices are stored without a shared transaction.
+func NewSyncHandler(projectRepo repository.ProjectRepository, deviceRepo repository.DeviceRepository, venueRepo repository.VenueRepository, syncRepo repository.SyncRepository, uow repository.UnitOfWork, client *services.CityPostConsoleClient, alerts *services.AlertService, events services.EventPublisher, concurrency int) *SyncHandler {
 	return &SyncHandler{
 		projectRepo: projectRepo,
 		deviceRepo:  deviceRepo,
+		venueRepo:   venueRepo,
+		syncRepo:    syncRepo,
+		uow:         uow,
 		client:      client,
+		alerts:      alerts,
+		events:      events,
+		concurrency: max(concurrency, 1),
 	}
 }
 
 type SyncConsoleResponse struct {
-	Synced SyncCounts `json:"synced"`
-	Errors []string   `json:"errors"`
+	RunID   int64      `json:"run_id,omitempty"`
+	Mode    string     `json:"mode"`
+	Synced  SyncCounts `json:"synced"`
+	Changes int        `json:"changes"`
+	Errors  []string   `json:"errors"`
 }
 
+// syncIgnoredFields are left out of the change feed: local timestamps and annotations,
+// and the device's last_synced_at, which moves on every console sync.
+var syncIgnoredFields = []string{"created_at", "updated_at", "annotations", "last_synced_at"}
+
 type SyncCounts struct {
-	Projects int `json:"projects"`
-	Devices  int `json:"devices"`
+	Projects    int `json:"projects"`
+	Devices     int `json:"devices"`
+	SmartVenues int `json:"smart_venues"`
+	// Unchanged counts the projects and devices an incremental sync left alone.
+	Unchanged int `json:"unchanged"`
+}
+
+// SyncProgressEvent is the data of a sync.progress event, raised as each project's
+// devices are done.
+type SyncProgressEvent struct {
+	RunID         int64      `json:"run_id,omitempty"`
+	Mode          string     `json:"mode"`
+	Project       string     `json:"project"`
+	ProjectsDone  int        `json:"projects_done"`
+	ProjectsTotal int        `json:"projects_total"`
+	Synced        SyncCounts `json:"synced"`
+	Errors        int        `json:"errors"`
+}
+
+// syncProgress collects the outcome of a sync run; the device workers share it.
+type syncProgress struct {
+	mu   sync.Mutex
+	resp SyncConsoleResponse
+	// projectsDone counts the projects whose devices are done.
+	projectsDone int
+	// lastSynced is the latest device last_synced_at the console reported.
+	lastSynced *time.Time
+}
+
+func (p *syncProgress) fail(msg string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.resp.Errors = append(p.resp.Errors, msg)
+}
+
+// failProject records an error syncing project or its devices.
+func (p *syncProgress) failProject(project string, msg string) {
+	metrics.SyncProjectError(project)
+	p.fail(msg)
+}
+
+func (p *syncProgress) update(fn func(resp *SyncConsoleResponse)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	fn(&p.resp)
+}
+
+// projectDone counts project as done and returns the progress so far.
+func (p *syncProgress) projectDone(project string, total int) SyncProgressEvent {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.projectsDone++
+	return SyncProgressEvent{
+		RunID:         p.resp.RunID,
+		Mode:          p.resp.Mode,
+		Project:       project,
+		ProjectsDone:  p.projectsDone,
+		ProjectsTotal: total,
+		Synced:        p.resp.Synced,
+		Errors:        len(p.resp.Errors),
+	}
 }
 
-// SyncConsole orchestrates fetching projects and devices from CityPost Console API and upserting them
+func (p *syncProgress) sawDevice(device *models.Device) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if device.LastSyncedAt != nil && (p.lastSynced == nil || device.LastSyncedAt.After(*p.lastSynced)) {
+		p.lastSynced = device.LastSyncedAt
+	}
+}
+
+// incrementalSync holds what an incremental run compares console devices against.
+type incrementalSync struct {
+	since time.Time
+	// known maps each stored device's host name to its stored last_synced_at.
+	known map[string]*time.Time
+}
+
+// deviceChanged reports whether an incremental sync has to store device: it is new, the
+// console flags a pending change, or it was synced after the last clean run or at
+// another time than the stored row says.
+func (inc *incrementalSync) deviceChanged(device *models.Device) bool {
+	stored, ok := inc.known[device.HostName]
+	switch {
+	case !ok || device.Change || device.LastSyncedAt == nil || stored == nil:
+		return true
+	case device.LastSyncedAt.After(inc.since):
+		return true
+	default:
+		return !stored.Equal(*device.LastSyncedAt)
+	}
+}
+
+// @Tags Sync
+// @Summary Sync projects and devices from the CityPost console
+// @Description A full sync fetches and stores every project and device. mode=incremental fetches the same data but only stores the projects that differ from the stored rows and the devices the console synced since the last clean run or flags as changed.
+// @Security BearerAuth
+// @Produce json
+// @Param mode query string false "full (default) or incremental"
+// @Success 200 {object} SyncConsoleResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/sync/console [post]
 func (h *SyncHandler) SyncConsole(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	resp := SyncConsoleResponse{
+	start := time.Now()
+	mode := r.URL.Query().Get("mode")
+	if mode == "" {
+		mode = models.SyncModeFull
+	}
+	if mode != models.SyncModeFull && mode != models.SyncModeIncremental {
+		writeJSONErrorResponse(w, http.StatusBadRequest, "invalid_request", "mode must be full or incremental")
+		return
+	}
+
+	p := &syncProgress{resp: SyncConsoleResponse{
+		Mode:   mode,
 		Synced: SyncCounts{},
 		Errors: []string{},
+	}}
+
+	var run *models.SyncRun
+	var inc *incrementalSync
+	if h.syncRepo != nil {
+		var err error
+		if run, err = h.syncRepo.StartRun(ctx, mode); err != nil {
+			p.fail("start sync run: " + err.Error())
+		} else {
+			p.resp.RunID = run.ID
+		}
+		if mode == models.SyncModeIncremental {
+			// Without a high-water mark from a clean run, everything is synced.
+			if inc, err = h.loadIncrementalSync(ctx); err != nil {
+				p.fail(err.Error())
+			} else if inc != nil {
+				// The mark never moves back, whatever the console reports.
+				p.lastSynced = &inc.since
+			}
+		}
 	}
 
 	// 1. Fetch projects (production + non-production)
 	projectsRaw, err := h.client.ListProjects(ctx)
 	if err != nil {
-		resp.Errors = append(resp.Errors, "fetch projects: "+err.Error())
+		p.fail("fetch projects: " + err.Error())
+		metrics.ObserveSync(mode, start, true)
+		h.finishRun(ctx, run, p)
+		h.recordSyncResult(ctx, p.resp.Errors)
 		writeJSONErrorResponse(w, http.StatusInternalServerError, "sync_failed", "sync failed: "+err.Error())
 		return
 	}
 
-	// 2. Upsert projects
+	// 2. Upsert projects, each with its change feed entry in one transaction
 	for _, pRaw := range projectsRaw {
 		project, err := mapRawToProject(pRaw)
 		if err != nil {
-			resp.Errors = append(resp.Errors, "map project: "+err.Error())
+			p.fail("map project: " + err.Error())
 			continue
 		}
-		if err := h.projectRepo.Upsert(ctx, project); err != nil {
-			resp.Errors = append(resp.Errors, "upsert project "+project.Name+": "+err.Error())
+
+		var stored bool
+		var recorded int
+		err = h.inTx(ctx, func(ctx context.Context, store syncStore) error {
+			stored, recorded = false, 0
+			before, err := store.projects.GetByName(ctx, project.Name)
+			if err != nil && err.Error() != "project not found" {
+				return fmt.Errorf("read project %s: %w", project.Name, err)
+			}
+			change, err := diffSyncedRecord(models.SyncEntityProject, project.Name, before, project)
+			if err != nil {
+				return err
+			}
+			if inc != nil && change == nil {
+				return nil
+			}
+			if err := store.projects.Upsert(ctx, project); err != nil {
+				return fmt.Errorf("upsert project %s: %w", project.Name, err)
+			}
+			stored = true
+			recorded, err = h.recordChange(ctx, store, run, change)
+			return err
+		})
+		if err != nil {
+			p.failProject(project.Name, err.Error())
 			continue
 		}
-		resp.Synced.Projects++
+		if !stored {
+			p.resp.Synced.Unchanged++
+			continue
+		}
+		p.resp.Synced.Projects++
+		p.resp.Changes += recorded
 	}
 
-	// 3. Login to console API (ensureToken is called per-project in ListDevicesByProject)
-
-	// 4. Loop projects → fetch devices
+	// 3. Fetch and upsert each project's devices, a bounded number of projects at a time
+	// (ensureToken logs in once and shares the token between them)
+	var projectNames []string
 	for _, pRaw := range projectsRaw {
 		projectNameRaw, ok := pRaw["name"]
 		if !ok {
-			resp.Errors = append(resp.Errors, "project missing 'name'")
+			p.fail("project missing 'name'")
 			continue
 		}
 		projectName, ok := projectNameRaw.(string)
 		if !ok {
-			resp.Errors = append(resp.Errors, "project 'name' not a string")
+			p.fail("project 'name' not a string")
 			continue
 		}
+		projectNames = append(projectNames, projectName)
+	}
+
+	jobs := make(chan string)
+	var wg sync.WaitGroup
+	for range min(h.concurrency, len(projectNames)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for projectName := range jobs {
+				h.syncProjectDevices(ctx, run, inc, p, projectName)
+				h.publish(ctx, models.StreamEventSyncProgress, p.projectDone(projectName, len(projectNames)))
+			}
+		}()
+	}
+	for _, projectName := range projectNames {
+		jobs <- projectName
+	}
+	close(jobs)
+	wg.Wait()
+
+	// 4. Re-apply smart venue filters to the freshly synced devices
+	if h.venueRepo != nil {
+		evaluated, errs := reevaluateSmartVenues(ctx, h.venueRepo)
+		p.resp.Synced.SmartVenues = evaluated
+		p.resp.Errors = append(p.resp.Errors, errs...)
+	}
+
+	metrics.ObserveSync(mode, start, len(p.resp.Errors) > 0)
+	h.finishRun(ctx, run, p)
+	h.recordSyncResult(ctx, p.resp.Errors)
+	h.publish(ctx, models.WebhookEventSyncCompleted, p.resp)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(p.resp)
+}
+
+func (h *SyncHandler) loadIncrementalSync(ctx context.Context) (*incrementalSync, error) {
+	since, err := h.syncRepo.LastHighWaterMark(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if since == nil {
+		return nil, nil
+	}
+	known, err := h.deviceRepo.ListSyncMarkers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &incrementalSync{since: *since, known: known}, nil
+}
+
+// syncProjectDevices fetches one project's devices and upserts them. An incremental
+// sync (inc != nil) still fetches every device, as the console cannot filter them, but
+// only upserts those changed since the high-water mark. The project's devices and their
+// change feed entries are stored in one transaction.
+func (h *SyncHandler) syncProjectDevices(ctx context.Context, run *models.SyncRun, inc *incrementalSync, p *syncProgress, projectName string) {
+	devicesRaw, err := h.client.ListDevicesByProject(ctx, projectName)
+	if err != nil {
+		p.failProject(projectName, "fetch devices for project "+projectName+": "+err.Error())
+		return
+	}
 
-		devicesRaw, err := h.client.ListDevicesByProject(ctx, projectName)
+	var pending []*models.Device
+	for _, dRaw := range devicesRaw {
+		device, err := mapRawToDevice(dRaw)
 		if err != nil {
-			resp.Errors = append(resp.Errors, "fetch devices for project "+projectName+": "+err.Error())
+			p.failProject(projectName, "map device for project "+projectName+": "+err.Error())
 			continue
 		}
+		p.sawDevice(device)
+		if inc != nil && !inc.deviceChanged(device) {
+			p.update(func(resp *SyncConsoleResponse) { resp.Synced.Unchanged++ })
+			continue
+		}
+		pending = append(pending, device)
+	}
+	if len(pending) == 0 {
+		return
+	}
 
-		// 5. Upsert devices
-		for _, dRaw := range devicesRaw {
-			device, err := mapRawToDevice(dRaw)
+	var stored, recorded int
+	err = h.inTx(ctx, func(ctx context.Context, store syncStore) error {
+		stored, recorded = 0, 0
+		for _, device := range pending {
+			var before *models.Device
+			if run != nil {
+				current, err := store.devices.GetByHostName(ctx, device.HostName)
+				if err != nil && err.Error() != "device not found" {
+					return fmt.Errorf("read device %s: %w", device.HostName, err)
+				}
+				before = current
+			}
+			change, err := diffSyncedRecord(models.SyncEntityDevice, device.HostName, before, device)
 			if err != nil {
-				resp.Errors = append(resp.Errors, "map device for project "+projectName+": "+err.Error())
-				continue
+				return err
 			}
-			if err := h.deviceRepo.Upsert(ctx, device); err != nil {
-				resp.Errors = append(resp.Errors, "upsert device "+device.HostName+": "+err.Error())
-				continue
+			if err := store.devices.Upsert(ctx, device); err != nil {
+				return fmt.Errorf("upsert device %s: %w", device.HostName, err)
+			}
+			stored++
+			n, err := h.recordChange(ctx, store, run, change)
+			if err != nil {
+				return err
 			}
-			resp.Synced.Devices++
+			recorded += n
 		}
+		return nil
+	})
+	if err != nil {
+		p.failProject(projectName, "sync devices for project "+projectName+": "+err.Error())
+		return
 	}
+	p.update(func(resp *SyncConsoleResponse) {
+		resp.Synced.Devices += stored
+		resp.Changes += recorded
+	})
+}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	_ = json.NewEncoder(w).Encode(resp)
+// syncStore holds the repositories a sync step writes to.
+type syncStore struct {
+	projects repository.ProjectRepository
+	devices  repository.DeviceRepository
+	changes  repository.SyncRepository
+}
+
+// inTx runs fn with the sync repositories bound to one transaction, retrying it on
+// serialization failures.
+func (h *SyncHandler) inTx(ctx context.Context, fn func(ctx context.Context, store syncStore) error) error {
+	if h.uow == nil {
+		return fn(ctx, syncStore{projects: h.projectRepo, devices: h.deviceRepo, changes: h.syncRepo})
+	}
+	return h.uow.Do(ctx, nil, func(ctx context.Context, repos repository.Repositories) error {
+		return fn(ctx, syncStore{projects: repos.Projects, devices: repos.Devices, changes: repos.Sync})
+	})
+}
+
+// diffSyncedRecord returns the change feed entry for storing after over before, the
+// stored record (nil when the sync creates it), or nil when no tracked field changed.
+func diffSyncedRecord(entityType string, key string, before any, after any) (*models.SyncChange, error) {
+	change := &models.SyncChange{EntityType: entityType, EntityKey: key, Action: models.SyncActionCreated, Changes: []models.FieldChange{}}
+	if reflect.ValueOf(before).IsNil() {
+		return change, nil
+	}
+
+	fields, err := audit.FieldChanges(before, after, syncIgnoredFields...)
+	if err != nil {
+		return nil, fmt.Errorf("diff %s %s: %w", entityType, key, err)
+	}
+	if len(fields) == 0 {
+		return nil, nil
+	}
+	change.Action = models.SyncActionUpdated
+	change.Changes = fields
+	return change, nil
+}
+
+// recordChange adds change to run's change feed and returns how many entries it added.
+func (h *SyncHandler) recordChange(ctx context.Context, store syncStore, run *models.SyncRun, change *models.SyncChange) (int, error) {
+	if run == nil || change == nil {
+		return 0, nil
+	}
+
+	change.RunID = run.ID
+	if err := store.changes.RecordChange(ctx, change); err != nil {
+		return 0, fmt.Errorf("record change of %s %s: %w", change.EntityType, change.EntityKey, err)
+	}
+	return 1, nil
+}
+
+// finishRun stores the run's outcome. Only a run without errors advances the
+// high-water mark incremental syncs start from.
+func (h *SyncHandler) finishRun(ctx context.Context, run *models.SyncRun, p *syncProgress) {
+	if run == nil {
+		return
+	}
+	run.ProjectsSynced = p.resp.Synced.Projects
+	run.DevicesSynced = p.resp.Synced.Devices
+	run.Changes = p.resp.Changes
+	run.Errors = p.resp.Errors
+	if len(run.Errors) == 0 {
+		run.HighWaterMark = p.lastSynced
+	}
+	if err := h.syncRepo.FinishRun(ctx, run); err != nil {
+		slog.ErrorContext(ctx, "failed to finish sync run", "sync_run_id", run.ID, "error", err)
+	}
+}
+
+// publish raises an event about the sync, logging rather than failing it on errors.
+func (h *SyncHandler) publish(ctx context.Context, eventType string, data any) {
+	if h.events == nil {
+		return
+	}
+	if err := h.events.Publish(ctx, eventType, data); err != nil {
+		slog.ErrorContext(ctx, "failed to publish event", "event_type", eventType, "error", err)
+	}
+}
+
+func (h *SyncHandler) recordSyncResult(ctx context.Context, syncErrors []string) {
+	if h.alerts == nil {
+		return
+	}
+	if err := h.alerts.RecordSyncResult(ctx, syncErrors); err != nil {
+		slog.ErrorContext(ctx, "failed to record sync alerts", "error", err)
+	}
 }
 
 // mapRawToProject converts a raw map from the console API to a Project model
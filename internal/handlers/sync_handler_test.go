@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"scm/internal/fakeconsole"
+	"scm/internal/models"
+	"scm/internal/repository/memory"
+	"scm/internal/services"
+)
+
+func runConsoleSync(t *testing.T, h *SyncHandler, mode string) (int, SyncConsoleResponse) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	h.SyncConsole(w, httptest.NewRequest(http.MethodPost, "/sync/console?mode="+mode, nil))
+	var resp SyncConsoleResponse
+	if w.Code == http.StatusOK {
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("invalid json: %v", err)
+		}
+	}
+	return w.Code, resp
+}
+
+type recordingEvents struct {
+	types []string
+}
+
+func (e *recordingEvents) Publish(ctx context.Context, eventType string, data any) error {
+	e.types = append(e.types, eventType)
+	return nil
+}
+
+func TestSyncConsoleAgainstFakeConsole(t *testing.T) {
+	ctx := context.Background()
+	synced := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	fake := fakeconsole.New(fakeconsole.Config{
+		Username: "demo", Password: "secret",
+		Fixtures: fakeconsole.Demo(3, 2, synced),
+		PageSize: 1,
+	})
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+	client := services.NewCityPostConsoleClient(srv.URL, "demo", "secret")
+	client.SetAuthScheme("Token")
+
+	store := memory.NewStore()
+	venueRepo := memory.NewVenueRepository(store)
+	project := 2
+	venue := &models.Venue{Name: "Project 2", SmartFilter: &models.DeviceSelector{ProjectID: &project}}
+	if err := venueRepo.Create(ctx, venue); err != nil {
+		t.Fatalf("create venue: %v", err)
+	}
+	syncRepo := &mockSyncRepo{}
+	events := &recordingEvents{}
+	h := NewSyncHandler(memory.NewProjectRepository(store), memory.NewDeviceRepository(store), venueRepo, syncRepo, nil, client, nil, events, 2)
+
+	code, resp := runConsoleSync(t, h, models.SyncModeFull)
+	if code != http.StatusOK || len(resp.Errors) != 0 {
+		t.Fatalf("full sync: %d %+v", code, resp)
+	}
+	wantEvents := []string{models.StreamEventSyncProgress, models.StreamEventSyncProgress, models.StreamEventSyncProgress, models.WebhookEventSyncCompleted}
+	if !slices.Equal(events.types, wantEvents) {
+		t.Fatalf("expected events %v, got %v", wantEvents, events.types)
+	}
+	if resp.Synced != (SyncCounts{Projects: 3, Devices: 6, SmartVenues: 1}) || resp.Changes != 9 {
+		t.Fatalf("unexpected full sync result %+v", resp)
+	}
+	members, err := venueRepo.GetDevicesByVenueID(ctx, venue.ID, 10, 0)
+	if err != nil || len(members) != 2 {
+		t.Fatalf("expected the smart venue to pick up project 2's devices, got %d, %v", len(members), err)
+	}
+
+	// The console flags one changed device; an incremental sync fetches everything but
+	// only stores that one, and counts the other projects and devices as unchanged.
+	syncRepo.mark = &synced
+	devices := fakeconsole.Demo(3, 2, synced).Devices["demo-project-1"]
+	changed := maps.Clone(devices[0])
+	changed["description"] = "Moved to the lobby"
+	changed["change"] = true
+	fake.SetDevices("demo-project-1", append([]map[string]any{changed}, devices[1:]...))
+
+	code, resp = runConsoleSync(t, h, models.SyncModeIncremental)
+	if code != http.StatusOK || len(resp.Errors) != 0 {
+		t.Fatalf("incremental sync: %d %+v", code, resp)
+	}
+	if resp.Synced.Projects != 0 || resp.Synced.Devices != 1 || resp.Synced.Unchanged != 8 || resp.Changes != 1 {
+		t.Fatalf("unexpected incremental sync result %+v", resp)
+	}
+	last := syncRepo.changes[len(syncRepo.changes)-1]
+	if last.EntityKey != "demo-1-1" || last.Action != models.SyncActionUpdated {
+		t.Fatalf("unexpected change %+v", last)
+	}
+
+	// A failing project fetch is reported and the other projects still sync.
+	fake.Fail("/device/", http.StatusServiceUnavailable, 1)
+	code, resp = runConsoleSync(t, h, models.SyncModeFull)
+	if code != http.StatusOK || len(resp.Errors) != 1 || !strings.Contains(resp.Errors[0], "status=503") || resp.Synced.Devices != 4 {
+		t.Fatalf("expected one failed project, got %d %+v", code, resp)
+	}
+
+	fake.Fail("/projectsList", http.StatusInternalServerError, 1)
+	if code, _ := runConsoleSync(t, h, models.SyncModeFull); code != http.StatusInternalServerError {
+		t.Fatalf("expected a failed project list to fail the sync, got %d", code)
+	}
+}
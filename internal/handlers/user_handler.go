@@ -22,13 +22,16 @@ func NewUserHandler(users repository.UserRepository) *UserHandler {
 // @Summary List users
 // @Security BearerAuth
 // @Produce json
+// @Param sort query string false "Sort fields, e.g. email"
+// @Param cursor query string false "next_cursor or prev_cursor of a previous page"
 // @Success 200 {array} models.User
+// @Failure 400 {object} map[string]interface{}
 // @Failure 500 {object} map[string]interface{}
 // @Router /api/v1/users/ [get]
 func (h *UserHandler) ListUsers(w http.ResponseWriter, r *http.Request) {
-	p, err := parsePaginationParams(r, 50, 200)
+	p, err := parseListParams(r, 50, 200, repository.UserSort)
 	if err != nil {
-		writeJSONErrorResponse(w, http.StatusBadRequest, "validation_error", "invalid pagination parameters")
+		writeJSONErrorResponse(w, http.StatusBadRequest, "validation_error", "invalid pagination parameters: "+err.Error())
 		return
 	}
 
@@ -38,7 +41,7 @@ func (h *UserHandler) ListUsers(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	users, err := h.users.List(r.Context(), p.limit, p.offset)
+	users, err := h.users.List(r.Context(), p.listPage())
 	if err != nil {
 		writeJSONErrorResponse(w, http.StatusInternalServerError, "list_users_failed", "Failed to list users")
 		return
@@ -48,7 +51,7 @@ func (h *UserHandler) ListUsers(w http.ResponseWriter, r *http.Request) {
 		users = []models.User{}
 	}
 
-	writePaginatedResponse(w, http.StatusOK, users, p.page, p.pageSize, total)
+	writeListResponse(w, p, users, total, nil)
 }
 
 // @Tags Account
@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/go-chi/chi/v5"
+	"scm/internal/interfaces"
 	"scm/internal/models"
 )
 
@@ -22,7 +23,7 @@ func (m *mockUserRepo) GetByEmail(ctx context.Context, email string) (*models.Us
 func (m *mockUserRepo) GetByIdentifier(ctx context.Context, identifier string) (*models.User, error) {
 	return nil, nil
 }
-func (m *mockUserRepo) List(ctx context.Context, limit int, offset int) ([]models.User, error) {
+func (m *mockUserRepo) List(ctx context.Context, page interfaces.ListPage) ([]models.User, error) {
 	return m.ListAll(ctx)
 }
 func (m *mockUserRepo) Count(ctx context.Context) (int, error) {
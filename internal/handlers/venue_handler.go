@@ -1,21 +1,38 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 
 	"github.com/go-chi/chi/v5"
+	"scm/internal/interfaces"
 	"scm/internal/models"
 	"scm/internal/repository"
 )
 
 type VenueHandler struct {
-	repo repository.VenueRepository
+	repo    repository.VenueRepository
+	uow     repository.UnitOfWork
+	cascade *CascadeDeleter
 }
 
-func NewVenueHandler(repo repository.VenueRepository) *VenueHandler {
-	return &VenueHandler{repo: repo}
+// NewVenueHandler creates the venue handler. uow may be nil, in which case bulk device
+// changes run on repo without a shared transaction.
+func NewVenueHandler(repo repository.VenueRepository, uow repository.UnitOfWork, cascade *CascadeDeleter) *VenueHandler {
+	return &VenueHandler{repo: repo, uow: uow, cascade: cascade}
+}
+
+// inTx runs fn with a venue repository bound to one transaction.
+func (h *VenueHandler) inTx(ctx context.Context, fn func(ctx context.Context, repo repository.VenueRepository) error) error {
+	if h.uow == nil {
+		return fn(ctx, h.repo)
+	}
+	return h.uow.Do(ctx, nil, func(ctx context.Context, repos repository.Repositories) error {
+		return fn(ctx, repos.Venues)
+	})
 }
 
 // @Tags Venues
@@ -46,6 +63,11 @@ func (h *VenueHandler) Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := applySmartFilter(r.Context(), h.repo, &venue); err != nil {
+		writeJSONErrorResponse(w, http.StatusInternalServerError, "internal_error", "Failed to apply smart filter: "+err.Error())
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(venue)
@@ -84,6 +106,7 @@ func (h *VenueHandler) Get(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	setETag(w, venue.Version)
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(venue)
@@ -95,18 +118,20 @@ func (h *VenueHandler) Get(w http.ResponseWriter, r *http.Request) {
 // @Produce json
 // @Param page query int false "Page number" default(1)
 // @Param page_size query int false "Page size" default(20)
+// @Param sort query string false "Sort fields, e.g. name,-created_at"
+// @Param cursor query string false "next_cursor or prev_cursor of a previous page"
 // @Success 200 {object} map[string]interface{}
 // @Failure 400 {object} map[string]interface{}
 // @Failure 500 {object} map[string]interface{}
 // @Router /api/v1/venues [get]
 func (h *VenueHandler) List(w http.ResponseWriter, r *http.Request) {
-	pagination, err := parsePaginationParams(r, 20, 100)
+	pagination, err := parseListParams(r, 20, 100, repository.VenueSort)
 	if err != nil {
 		writeJSONErrorResponse(w, http.StatusBadRequest, "invalid_pagination", "Invalid pagination: "+err.Error())
 		return
 	}
 
-	venues, err := h.repo.List(r.Context(), pagination.limit, pagination.offset)
+	venues, err := h.repo.List(r.Context(), pagination.listPage())
 	if err != nil {
 		writeJSONErrorResponse(w, http.StatusInternalServerError, "internal_error", "Failed to list venues: "+err.Error())
 		return
@@ -118,48 +143,160 @@ func (h *VenueHandler) List(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	writePaginatedResponse(w, http.StatusOK, venues, pagination.page, pagination.pageSize, total)
+	writeListResponse(w, pagination, venues, total, nil)
+}
+
+// @Tags Venues
+// @Summary Export venues as GeoJSON
+// @Description Returns every venue as a FeatureCollection, positioned at the centroid of its devices. Venues without located devices have a null geometry.
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} models.GeoJSONFeatureCollection
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/venues.geojson [get]
+func (h *VenueHandler) GeoJSON(w http.ResponseWriter, r *http.Request) {
+	collection := models.GeoJSONFeatureCollection{Type: "FeatureCollection", Features: []models.GeoJSONFeature{}}
+	for offset := 0; ; offset += geoJSONBatchSize {
+		venues, err := h.repo.List(r.Context(), interfaces.ListPage{Limit: geoJSONBatchSize, Offset: offset})
+		if err != nil {
+			writeJSONErrorResponse(w, http.StatusInternalServerError, "internal_error", "Failed to list venues: "+err.Error())
+			return
+		}
+
+		ids := make([]int, 0, len(venues))
+		for _, v := range venues {
+			ids = append(ids, v.ID)
+		}
+		devicesByVenue, err := h.repo.GetDevicesByVenueIDs(r.Context(), ids)
+		if err != nil {
+			writeJSONErrorResponse(w, http.StatusInternalServerError, "internal_error", "Failed to get venue devices: "+err.Error())
+			return
+		}
+
+		for _, v := range venues {
+			collection.Features = append(collection.Features, venueFeature(v, devicesByVenue[v.ID]))
+		}
+		if len(venues) < geoJSONBatchSize {
+			break
+		}
+	}
+
+	writeGeoJSON(w, collection)
 }
 
 // @Tags Venues
-// @Summary Update venue
+// @Summary Replace venue
+// @Description Replaces the venue's name and smart filter; an omitted smart_filter makes it a plain venue.
 // @Security BearerAuth
 // @Accept json
 // @Produce json
 // @Param id path int true "Venue ID"
-// @Param body body models.Venue true "Update venue request"
+// @Param If-Match header string true "ETag from a previous GET"
+// @Param body body models.ReplaceVenueRequest true "Venue"
 // @Success 200 {object} models.Venue
 // @Failure 400 {object} map[string]interface{}
 // @Failure 404 {object} map[string]interface{}
+// @Failure 412 {object} map[string]interface{}
+// @Failure 428 {object} map[string]interface{}
 // @Failure 500 {object} map[string]interface{}
 // @Router /api/v1/venues/{id} [put]
 func (h *VenueHandler) Update(w http.ResponseWriter, r *http.Request) {
+	id, ifMatch, ok := parseVenueUpdate(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.ReplaceVenueRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONErrorResponse(w, http.StatusBadRequest, "invalid_json", "Invalid JSON: "+err.Error())
+		return
+	}
+
+	h.replace(w, r, id, req, ifMatch)
+}
+
+// @Tags Venues
+// @Summary Patch venue
+// @Description Applies a JSON merge patch (RFC 7396); "smart_filter": null makes it a plain venue.
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Venue ID"
+// @Param If-Match header string true "ETag from a previous GET"
+// @Param body body models.ReplaceVenueRequest true "Merge patch"
+// @Success 200 {object} models.Venue
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 412 {object} map[string]interface{}
+// @Failure 415 {object} map[string]interface{}
+// @Failure 428 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/venues/{id} [patch]
+func (h *VenueHandler) Patch(w http.ResponseWriter, r *http.Request) {
+	id, ifMatch, ok := parseVenueUpdate(w, r)
+	if !ok {
+		return
+	}
+
+	current, err := h.repo.GetByID(r.Context(), id)
+	if err != nil {
+		if err.Error() == "venue not found" {
+			writeJSONErrorResponse(w, http.StatusNotFound, "not_found", "Venue not found")
+			return
+		}
+		writeJSONErrorResponse(w, http.StatusInternalServerError, "internal_error", "Failed to get venue: "+err.Error())
+		return
+	}
+	if ifMatchFails(ifMatch, current.Version) {
+		writePreconditionFailed(w, current, current.Version)
+		return
+	}
+
+	var req models.ReplaceVenueRequest
+	if err := decodeMergePatch(r, models.NewReplaceVenueRequest(current), &req); err != nil {
+		writeMergePatchError(w, err)
+		return
+	}
+
+	h.replace(w, r, id, req, ifMatch)
+}
+
+// parseVenueUpdate reads the venue ID and If-Match of an update request.
+func parseVenueUpdate(w http.ResponseWriter, r *http.Request) (int, int, bool) {
 	idStr := chi.URLParam(r, "id")
 	if idStr == "" {
 		writeJSONErrorResponse(w, http.StatusBadRequest, "invalid_request", "Venue ID is required")
-		return
+		return 0, 0, false
 	}
 
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
 		writeJSONErrorResponse(w, http.StatusBadRequest, "invalid_request", "Invalid venue ID")
-		return
+		return 0, 0, false
 	}
 
-	var venue models.Venue
-	if err := json.NewDecoder(r.Body).Decode(&venue); err != nil {
-		writeJSONErrorResponse(w, http.StatusBadRequest, "invalid_json", "Invalid JSON: "+err.Error())
-		return
+	ifMatch, ok := requireIfMatch(w, r)
+	if !ok {
+		return 0, 0, false
 	}
+	return id, ifMatch, true
+}
 
+func (h *VenueHandler) replace(w http.ResponseWriter, r *http.Request, id int, req models.ReplaceVenueRequest, ifMatch int) {
 	// Validate required fields
-	if venue.Name == "" {
+	if req.Name == "" {
 		writeJSONErrorResponse(w, http.StatusBadRequest, "invalid_request", "Venue name is required")
 		return
 	}
 
-	venue.ID = id
-	if err := h.repo.Update(r.Context(), &venue); err != nil {
+	venue := models.Venue{ID: id, Name: req.Name, SmartFilter: req.SmartFilter}
+	if err := h.repo.Update(r.Context(), &venue, ifMatch); err != nil {
+		if errors.Is(err, interfaces.ErrVersionConflict) {
+			if current, err := h.repo.GetByID(r.Context(), id); err == nil {
+				writePreconditionFailed(w, current, current.Version)
+				return
+			}
+		}
 		if err.Error() == "venue not found" {
 			writeJSONErrorResponse(w, http.StatusNotFound, "not_found", "Venue not found")
 			return
@@ -168,6 +305,12 @@ func (h *VenueHandler) Update(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := applySmartFilter(r.Context(), h.repo, &venue); err != nil {
+		writeJSONErrorResponse(w, http.StatusInternalServerError, "internal_error", "Failed to apply smart filter: "+err.Error())
+		return
+	}
+
+	setETag(w, venue.Version)
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(venue)
@@ -175,12 +318,18 @@ func (h *VenueHandler) Update(w http.ResponseWriter, r *http.Request) {
 
 // @Tags Venues
 // @Summary Delete venue
+// @Description Deletes the venue. Without cascade the delete is refused while devices are
+// @Description assigned; with cascade=true (admins only) device assignments and the venue's
+// @Description alert rules are removed too. The devices themselves are kept.
 // @Security BearerAuth
 // @Produce json
 // @Param id path int true "Venue ID"
-// @Success 200 {object} map[string]string
+// @Param cascade query bool false "Also remove device assignments and alert rules"
+// @Success 200 {object} map[string]interface{}
 // @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
 // @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
 // @Failure 500 {object} map[string]interface{}
 // @Router /api/v1/venues/{id} [delete]
 func (h *VenueHandler) Delete(w http.ResponseWriter, r *http.Request) {
@@ -196,7 +345,34 @@ func (h *VenueHandler) Delete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	cascade, err := parseCascade(r)
+	if err != nil {
+		writeJSONErrorResponse(w, http.StatusBadRequest, "invalid_request", "cascade must be a boolean")
+		return
+	}
+	if cascade {
+		if !h.cascade.allowed(w, r) {
+			return
+		}
+		deps, err := h.repo.DeleteCascade(r.Context(), id)
+		if err != nil {
+			if err.Error() == "venue not found" {
+				writeJSONErrorResponse(w, http.StatusNotFound, "not_found", "Venue not found")
+				return
+			}
+			writeJSONErrorResponse(w, http.StatusInternalServerError, "internal_error", "Failed to delete venue: "+err.Error())
+			return
+		}
+		h.cascade.writeCascadeResult(w, r, deps)
+		return
+	}
+
 	if err := h.repo.Delete(r.Context(), id); err != nil {
+		var blocked *interfaces.DeletionBlockedError
+		if errors.As(err, &blocked) {
+			writeDeletionBlocked(w, blocked)
+			return
+		}
 		if err.Error() == "venue not found" {
 			writeJSONErrorResponse(w, http.StatusNotFound, "not_found", "Venue not found")
 			return
@@ -210,6 +386,36 @@ func (h *VenueHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(map[string]string{"message": "Venue deleted successfully"})
 }
 
+// @Tags Venues
+// @Summary List venue dependencies
+// @Description Lists the device assignments and alert rules a cascade delete would remove.
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Venue ID"
+// @Success 200 {object} models.DeletionDependencies
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/venues/{id}/dependencies [get]
+func (h *VenueHandler) Dependencies(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		writeJSONErrorResponse(w, http.StatusBadRequest, "invalid_request", "Invalid venue ID")
+		return
+	}
+
+	deps, err := h.repo.Dependencies(r.Context(), id)
+	if err != nil {
+		if err.Error() == "venue not found" {
+			writeJSONErrorResponse(w, http.StatusNotFound, "not_found", "Venue not found")
+			return
+		}
+		writeJSONErrorResponse(w, http.StatusInternalServerError, "internal_error", "Failed to list venue dependencies: "+err.Error())
+		return
+	}
+	writeDependencies(w, deps)
+}
+
 // @Tags Venues
 // @Summary List venues by device
 // @Security BearerAuth
@@ -259,13 +465,13 @@ func (h *VenueHandler) ListByDevice(w http.ResponseWriter, r *http.Request) {
 
 // @Tags Venues
 // @Summary Add devices to venue
+// @Description Adds every device in one transaction; when a device is not found, none is added.
 // @Security BearerAuth
 // @Accept json
 // @Produce json
 // @Param id path int true "Venue ID"
 // @Param body body object{device_ids=[]int} true "Add devices to venue request"
 // @Success 200 {object} map[string]interface{}
-// @Success 207 {object} map[string]interface{}
 // @Failure 400 {object} map[string]interface{}
 // @Failure 404 {object} map[string]interface{}
 // @Failure 500 {object} map[string]interface{}
@@ -296,70 +502,116 @@ func (h *VenueHandler) AddDevicesToVenue(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	// Add each device to the venue
-	var errors []interface{}
-	successCount := 0
-	venueNotFoundError := false
-	deviceNotFoundError := false
-	for _, deviceID := range request.DeviceIDs {
-		if err := h.repo.AddDeviceToVenue(r.Context(), venueID, deviceID); err != nil {
-			if err.Error() == "venue not found" {
-				venueNotFoundError = true
-			} else if err.Error() == "device not found" {
-				deviceNotFoundError = true
-			}
-			errors = append(errors, map[string]interface{}{
-				"device_id": deviceID,
-				"error":     err.Error(),
-			})
-		} else {
-			successCount++
-		}
+	// Add the devices in one transaction: if any of them fails, none is added
+	failures, err := h.updateVenueDevices(r.Context(), request.DeviceIDs, func(ctx context.Context, repo repository.VenueRepository, deviceID int) error {
+		return repo.AddDeviceToVenue(ctx, venueID, deviceID)
+	})
+	if err != nil {
+		writeVenueDevicesError(w, err, failures, len(request.DeviceIDs), "No devices were added")
+		return
 	}
 
-	response := map[string]interface{}{
-		"added_count": successCount,
+	// Success - follow user_handler pattern
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"added_count": len(request.DeviceIDs),
 		"total_count": len(request.DeviceIDs),
+	})
+}
+
+// @Tags Venues
+// @Summary Assign devices to venue by filter
+// @Description Adds, removes or replaces (mode=replace) the venue's devices with every device matching the filter, in one transaction. Set dry_run to preview the change.
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Venue ID"
+// @Param body body models.AssignVenueDevicesRequest true "Assignment request"
+// @Success 200 {object} models.VenueAssignmentResult
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/venues/{id}/devices/assign [post]
+func (h *VenueHandler) AssignDevices(w http.ResponseWriter, r *http.Request) {
+	venueID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		writeJSONErrorResponse(w, http.StatusBadRequest, "invalid_request", "Invalid venue ID")
+		return
 	}
-	
-	if len(errors) > 0 {
-		response["errors"] = errors
-		if successCount == 0 {
-			// All failed - check for specific errors
-			if venueNotFoundError {
-				writeJSONErrorResponse(w, http.StatusNotFound, "venue_not_found", "Venue not found")
-				return
-			}
-			if deviceNotFoundError && len(request.DeviceIDs) == 1 {
-				// Single device not found
-				writeJSONErrorResponse(w, http.StatusNotFound, "device_not_found", "Device not found")
-				return
-			}
-			writeJSONErrorResponse(w, http.StatusBadRequest, "validation_failed", "All devices failed to be added")
-			return
-		} else {
-			// Partial success - use 207 Multi-Status
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusMultiStatus)
-			json.NewEncoder(w).Encode(response)
+
+	var request models.AssignVenueDevicesRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeJSONErrorResponse(w, http.StatusBadRequest, "invalid_json", "Invalid JSON: "+err.Error())
+		return
+	}
+
+	if request.Mode == "" {
+		request.Mode = models.VenueAssignAdd
+	}
+	switch request.Mode {
+	case models.VenueAssignAdd, models.VenueAssignRemove, models.VenueAssignReplace:
+	default:
+		writeJSONErrorResponse(w, http.StatusBadRequest, "invalid_request", "Mode must be one of add, remove, replace")
+		return
+	}
+	if request.Filter.IsEmpty() {
+		writeJSONErrorResponse(w, http.StatusBadRequest, "invalid_request", "At least one filter is required")
+		return
+	}
+
+	result, err := h.repo.AssignDevices(r.Context(), venueID, repository.DeviceFiltersFromSelector(request.Filter), request.Mode, request.DryRun)
+	if err != nil {
+		if err.Error() == "venue not found" {
+			writeJSONErrorResponse(w, http.StatusNotFound, "venue_not_found", "Venue not found")
 			return
 		}
+		writeJSONErrorResponse(w, http.StatusInternalServerError, "internal_error", "Failed to assign devices: "+err.Error())
+		return
 	}
 
-	// Success - follow user_handler pattern
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result)
+}
+
+// applySmartFilter makes a smart venue's membership match its filter.
+func applySmartFilter(ctx context.Context, repo repository.VenueRepository, venue *models.Venue) error {
+	if venue.SmartFilter == nil || venue.SmartFilter.IsEmpty() {
+		return nil
+	}
+	_, err := repo.AssignDevices(ctx, venue.ID, repository.DeviceFiltersFromSelector(*venue.SmartFilter), models.VenueAssignReplace, false)
+	return err
+}
+
+// reevaluateSmartVenues re-applies every smart venue's filter, returning how many venues
+// were evaluated and the errors of those that failed.
+func reevaluateSmartVenues(ctx context.Context, repo repository.VenueRepository) (int, []string) {
+	venues, err := repo.ListSmartVenues(ctx)
+	if err != nil {
+		return 0, []string{"list smart venues: " + err.Error()}
+	}
+
+	var errs []string
+	evaluated := 0
+	for _, venue := range venues {
+		if err := applySmartFilter(ctx, repo, venue); err != nil {
+			errs = append(errs, "smart venue "+venue.Name+": "+err.Error())
+			continue
+		}
+		evaluated++
+	}
+	return evaluated, errs
 }
 
 // @Tags Venues
 // @Summary Remove devices from venue
+// @Description Removes every device in one transaction; when a device is not found, none is removed.
 // @Security BearerAuth
 // @Accept json
 // @Produce json
 // @Param id path int true "Venue ID"
 // @Param body body object{device_ids=[]int} true "Remove devices from venue request"
 // @Success 200 {object} map[string]interface{}
-// @Success 207 {object} map[string]interface{}
 // @Failure 400 {object} map[string]interface{}
 // @Failure 404 {object} map[string]interface{}
 // @Failure 500 {object} map[string]interface{}
@@ -390,59 +642,79 @@ func (h *VenueHandler) RemoveDevicesFromVenue(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	// Remove each device from the venue
-	var errors []interface{}
-	successCount := 0
-	venueNotFoundError := false
-	deviceNotFoundError := false
-	for _, deviceID := range request.DeviceIDs {
-		if err := h.repo.RemoveDeviceFromVenue(r.Context(), venueID, deviceID); err != nil {
-			if err.Error() == "venue not found" {
-				venueNotFoundError = true
-			} else if err.Error() == "device not found" {
-				deviceNotFoundError = true
+	// Remove the devices in one transaction: if any of them fails, none is removed
+	failures, err := h.updateVenueDevices(r.Context(), request.DeviceIDs, func(ctx context.Context, repo repository.VenueRepository, deviceID int) error {
+		return repo.RemoveDeviceFromVenue(ctx, venueID, deviceID)
+	})
+	if err != nil {
+		writeVenueDevicesError(w, err, failures, len(request.DeviceIDs), "No devices were removed")
+		return
+	}
+
+	// Success - follow user_handler pattern
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"removed_count": len(request.DeviceIDs),
+		"total_count":   len(request.DeviceIDs),
+	})
+}
+
+// errVenueDevicesRejected rolls back a bulk venue device change when some devices failed.
+var errVenueDevicesRejected = errors.New("venue devices rejected")
+
+// updateVenueDevices applies op to every device in one transaction. When a venue or
+// device is not found, it rolls everything back and returns the failures with
+// errVenueDevicesRejected.
+func (h *VenueHandler) updateVenueDevices(ctx context.Context, deviceIDs []int, op func(ctx context.Context, repo repository.VenueRepository, deviceID int) error) ([]map[string]interface{}, error) {
+	var failures []map[string]interface{}
+	err := h.inTx(ctx, func(ctx context.Context, repo repository.VenueRepository) error {
+		failures = nil
+		for _, deviceID := range deviceIDs {
+			err := op(ctx, repo, deviceID)
+			if err == nil {
+				continue
+			}
+			if err.Error() != "venue not found" && err.Error() != "device not found" {
+				return err
 			}
-			errors = append(errors, map[string]interface{}{
+			failures = append(failures, map[string]interface{}{
 				"device_id": deviceID,
 				"error":     err.Error(),
 			})
-		} else {
-			successCount++
+			if err.Error() == "venue not found" {
+				break
+			}
 		}
-	}
+		if len(failures) > 0 {
+			return errVenueDevicesRejected
+		}
+		return nil
+	})
+	return failures, err
+}
 
-	response := map[string]interface{}{
-		"removed_count": successCount,
-		"total_count":   len(request.DeviceIDs),
+func writeVenueDevicesError(w http.ResponseWriter, err error, failures []map[string]interface{}, total int, message string) {
+	if !errors.Is(err, errVenueDevicesRejected) {
+		writeJSONErrorResponse(w, http.StatusInternalServerError, "internal_error", "failed to update venue devices: "+err.Error())
+		return
 	}
-	
-	if len(errors) > 0 {
-		response["errors"] = errors
-		if successCount == 0 {
-			// All failed - check for specific errors
-			if venueNotFoundError {
-				writeJSONErrorResponse(w, http.StatusNotFound, "venue_not_found", "Venue not found")
-				return
-			}
-			if deviceNotFoundError && len(request.DeviceIDs) == 1 {
-				// Single device not found
-				writeJSONErrorResponse(w, http.StatusNotFound, "device_not_found", "Device not found")
-				return
-			}
-			writeJSONErrorResponse(w, http.StatusBadRequest, "validation_failed", "All devices failed to be removed")
-			return
-		} else {
-			// Partial success - use 207 Multi-Status
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusMultiStatus)
-			json.NewEncoder(w).Encode(response)
-			return
-		}
+	if failures[0]["error"] == "venue not found" {
+		writeJSONErrorResponse(w, http.StatusNotFound, "venue_not_found", "Venue not found")
+		return
+	}
+	if total == 1 {
+		// Single device not found
+		writeJSONErrorResponse(w, http.StatusNotFound, "device_not_found", "Device not found")
+		return
 	}
 
-	// Success - follow user_handler pattern
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	w.WriteHeader(http.StatusBadRequest)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error":   "validation_failed",
+		"message": message,
+		"errors":  failures,
+	})
 }
 
 // @Tags Venues
@@ -0,0 +1,460 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"scm/internal/interfaces"
+	"scm/internal/models"
+	"scm/internal/repository"
+)
+
+type assignCall struct {
+	venueID int
+	filters repository.DeviceFilters
+	mode    string
+	dryRun  bool
+}
+
+type mockVenueRepo struct {
+	venues []*models.Venue
+	calls  []assignCall
+	// added holds the device IDs AddDeviceToVenue assigned; IDs above 100 do not exist.
+	added []int
+}
+
+var _ repository.VenueRepository = (*mockVenueRepo)(nil)
+
+func (m *mockVenueRepo) Create(ctx context.Context, venue *models.Venue) error {
+	venue.ID = len(m.venues) + 1
+	m.venues = append(m.venues, venue)
+	return nil
+}
+func (m *mockVenueRepo) GetByID(ctx context.Context, id int) (*models.Venue, error) {
+	for _, v := range m.venues {
+		if v.ID == id {
+			return v, nil
+		}
+	}
+	return nil, errors.New("venue not found")
+}
+func (m *mockVenueRepo) GetByIDWithDevices(ctx context.Context, id int) (*models.VenueWithDevices, error) {
+	return nil, errors.New("venue not found")
+}
+func (m *mockVenueRepo) GetByName(ctx context.Context, name string) (*models.Venue, error) {
+	return nil, errors.New("venue not found")
+}
+
+// List supports keyset pages sorted by id alone, which is what the cursor tests use.
+func (m *mockVenueRepo) List(ctx context.Context, page interfaces.ListPage) ([]*models.Venue, error) {
+	var out []*models.Venue
+	for _, v := range m.venues {
+		if page.After != nil && v.ID <= cursorID(page.After) {
+			continue
+		}
+		if page.Before != nil && v.ID >= cursorID(page.Before) {
+			continue
+		}
+		out = append(out, v)
+	}
+	if page.Offset < len(out) {
+		out = out[page.Offset:]
+	} else {
+		out = nil
+	}
+	if page.Limit > 0 && len(out) > page.Limit {
+		if page.Backward() {
+			out = out[len(out)-page.Limit:]
+		} else {
+			out = out[:page.Limit]
+		}
+	}
+	return out, nil
+}
+
+func cursorID(position []any) int {
+	id, _ := strconv.Atoi(fmt.Sprint(position[0]))
+	return id
+}
+func (m *mockVenueRepo) Count(ctx context.Context) (int, error) { return len(m.venues), nil }
+func (m *mockVenueRepo) Update(ctx context.Context, venue *models.Venue, version int) error {
+	current, err := m.GetByID(ctx, venue.ID)
+	if err != nil {
+		return err
+	}
+	if version != 0 && version != current.Version {
+		return interfaces.ErrVersionConflict
+	}
+	venue.Version = current.Version + 1
+	*current = *venue
+	return nil
+}
+func (m *mockVenueRepo) Delete(ctx context.Context, id int) error { return nil }
+func (m *mockVenueRepo) AddDeviceToVenue(ctx context.Context, venueID, deviceID int) error {
+	if _, err := m.GetByID(ctx, venueID); err != nil {
+		return err
+	}
+	if deviceID > 100 {
+		return errors.New("device not found")
+	}
+	m.added = append(m.added, deviceID)
+	return nil
+}
+func (m *mockVenueRepo) RemoveDeviceFromVenue(ctx context.Context, venueID, deviceID int) error {
+	return nil
+}
+func (m *mockVenueRepo) GetVenuesByDeviceID(ctx context.Context, deviceID int, limit int, offset int) ([]*models.Venue, error) {
+	return nil, nil
+}
+func (m *mockVenueRepo) CountVenuesByDeviceID(ctx context.Context, deviceID int) (int, error) {
+	return 0, nil
+}
+func (m *mockVenueRepo) GetDevicesByVenueID(ctx context.Context, venueID int, limit int, offset int) ([]*models.Device, error) {
+	return nil, nil
+}
+func (m *mockVenueRepo) CountDevicesByVenueID(ctx context.Context, venueID int) (int, error) {
+	return 0, nil
+}
+func (m *mockVenueRepo) GetDevicesByVenueIDs(ctx context.Context, venueIDs []int) (map[int][]*models.Device, error) {
+	return map[int][]*models.Device{}, nil
+}
+func (m *mockVenueRepo) AssignDevices(ctx context.Context, venueID int, filters repository.DeviceFilters, mode string, dryRun bool) (*models.VenueAssignmentResult, error) {
+	if _, err := m.GetByID(ctx, venueID); err != nil {
+		return nil, err
+	}
+	m.calls = append(m.calls, assignCall{venueID: venueID, filters: filters, mode: mode, dryRun: dryRun})
+	return &models.VenueAssignmentResult{VenueID: venueID, Mode: mode, DryRun: dryRun}, nil
+}
+func (m *mockVenueRepo) Dependencies(ctx context.Context, id int) (*models.DeletionDependencies, error) {
+	if _, err := m.GetByID(ctx, id); err != nil {
+		return nil, err
+	}
+	return &models.DeletionDependencies{Resource: "venue", References: map[string]int64{"devices": 0, "alert_rules": 0}}, nil
+}
+func (m *mockVenueRepo) DeleteCascade(ctx context.Context, id int) (*models.DeletionDependencies, error) {
+	return m.Dependencies(ctx, id)
+}
+func (m *mockVenueRepo) ListSmartVenues(ctx context.Context) ([]*models.Venue, error) {
+	var out []*models.Venue
+	for _, v := range m.venues {
+		if v.SmartFilter != nil {
+			out = append(out, v)
+		}
+	}
+	return out, nil
+}
+
+func newVenueRouter(repo *mockVenueRepo) http.Handler {
+	h := NewVenueHandler(repo, nil, nil)
+	r := chi.NewRouter()
+	r.Post("/venues", h.Create)
+	r.Post("/venues/{id}/devices/assign", h.AssignDevices)
+	r.Get("/venues", h.List)
+	r.Get("/venues/{id}", h.Get)
+	r.Put("/venues/{id}", h.Update)
+	r.Patch("/venues/{id}", h.Patch)
+	r.Get("/venues/{id}/dependencies", h.Dependencies)
+	r.Delete("/venues/{id}", h.Delete)
+	return r
+}
+
+// mockUnitOfWork runs fn on a copy of the venue repository's assignments and keeps
+// them only when fn succeeds.
+type mockUnitOfWork struct {
+	venues    *mockVenueRepo
+	rollbacks int
+}
+
+func (u *mockUnitOfWork) Do(ctx context.Context, opts *sql.TxOptions, fn func(ctx context.Context, repos repository.Repositories) error) error {
+	saved := append([]int(nil), u.venues.added...)
+	if err := fn(ctx, repository.Repositories{Venues: u.venues}); err != nil {
+		u.venues.added = saved
+		u.rollbacks++
+		return err
+	}
+	return nil
+}
+
+func TestAddDevicesToVenueIsAtomic(t *testing.T) {
+	repo := &mockVenueRepo{venues: []*models.Venue{{ID: 1, Name: "Airport"}}}
+	uow := &mockUnitOfWork{venues: repo}
+	h := NewVenueHandler(repo, uow, nil)
+	r := chi.NewRouter()
+	r.Post("/venues/{id}/devices", h.AddDevicesToVenue)
+
+	post := func(path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, path, strings.NewReader(body)))
+		return w
+	}
+
+	w := post("/venues/1/devices", `{"device_ids": [1, 2, 101, 102]}`)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 got %d (%s)", w.Code, w.Body.String())
+	}
+	var failed struct {
+		Errors []map[string]any `json:"errors"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &failed); err != nil {
+		t.Fatalf("invalid json: %v", err)
+	}
+	if len(failed.Errors) != 2 || len(repo.added) != 0 || uow.rollbacks != 1 {
+		t.Fatalf("expected both missing devices reported and nothing added, got %+v, added %v", failed.Errors, repo.added)
+	}
+
+	if w := post("/venues/1/devices", `{"device_ids": [101]}`); w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for a missing device, got %d", w.Code)
+	}
+	if w := post("/venues/9/devices", `{"device_ids": [1]}`); w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for a missing venue, got %d", w.Code)
+	}
+
+	if w := post("/venues/1/devices", `{"device_ids": [1, 2]}`); w.Code != http.StatusOK {
+		t.Fatalf("expected 200 got %d (%s)", w.Code, w.Body.String())
+	}
+	if len(repo.added) != 2 {
+		t.Fatalf("expected two devices added, got %v", repo.added)
+	}
+}
+
+func TestAssignDevicesByFilter(t *testing.T) {
+	repo := &mockVenueRepo{venues: []*models.Venue{{ID: 1, Name: "Airport"}}}
+	r := newVenueRouter(repo)
+
+	cases := []struct {
+		path string
+		body string
+		want int
+	}{
+		{"/venues/1/devices/assign", `{"filter": {}}`, http.StatusBadRequest},
+		{"/venues/1/devices/assign", `{"filter": {"region": "sfo"}, "mode": "merge"}`, http.StatusBadRequest},
+		{"/venues/9/devices/assign", `{"filter": {"region": "sfo"}}`, http.StatusNotFound},
+		{"/venues/1/devices/assign", `{"filter": {"region": "sfo", "host_name_glob": "kiosk-*"}, "dry_run": true}`, http.StatusOK},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest(http.MethodPost, c.path, strings.NewReader(c.body))
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != c.want {
+			t.Fatalf("%s %s: expected %d got %d (%s)", c.path, c.body, c.want, w.Code, w.Body.String())
+		}
+	}
+
+	if len(repo.calls) != 1 {
+		t.Fatalf("expected one assignment, got %+v", repo.calls)
+	}
+	call := repo.calls[0]
+	if call.mode != models.VenueAssignAdd || !call.dryRun || *call.filters.Region != "sfo" || *call.filters.HostNameGlob != "kiosk-*" {
+		t.Fatalf("unexpected assignment call: %+v", call)
+	}
+}
+
+func TestCreateSmartVenueAppliesFilter(t *testing.T) {
+	repo := &mockVenueRepo{}
+	r := newVenueRouter(repo)
+
+	req := httptest.NewRequest(http.MethodPost, "/venues", strings.NewReader(`{"name": "SF kiosks", "smart_filter": {"region": "sf"}}`))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201 got %d (%s)", w.Code, w.Body.String())
+	}
+	if len(repo.calls) != 1 || repo.calls[0].mode != models.VenueAssignReplace || repo.calls[0].dryRun {
+		t.Fatalf("expected smart filter to be applied, got %+v", repo.calls)
+	}
+
+	var venue models.Venue
+	if err := json.Unmarshal(w.Body.Bytes(), &venue); err != nil {
+		t.Fatalf("invalid json: %v", err)
+	}
+	if venue.SmartFilter == nil || *venue.SmartFilter.Region != "sf" {
+		t.Fatalf("expected smart_filter in response, got %+v", venue)
+	}
+}
+
+func TestVenueDependenciesAndCascadeDelete(t *testing.T) {
+	repo := &mockVenueRepo{venues: []*models.Venue{{ID: 1, Name: "Lobby"}}}
+	r := newVenueRouter(repo)
+
+	cases := []struct {
+		method string
+		path   string
+		want   int
+	}{
+		{http.MethodGet, "/venues/1/dependencies", http.StatusOK},
+		{http.MethodGet, "/venues/2/dependencies", http.StatusNotFound},
+		{http.MethodDelete, "/venues/1?cascade=maybe", http.StatusBadRequest},
+		{http.MethodDelete, "/venues/1?cascade=true", http.StatusForbidden},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest(c.method, c.path, nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != c.want {
+			t.Fatalf("%s %s: expected %d got %d (%s)", c.method, c.path, c.want, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestUpdateVenueRequiresMatchingETag(t *testing.T) {
+	repo := &mockVenueRepo{venues: []*models.Venue{{ID: 1, Name: "Lobby", Version: 3}}}
+	r := newVenueRouter(repo)
+
+	get := httptest.NewRequest(http.MethodGet, "/venues/1", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, get)
+	if etag := w.Header().Get("ETag"); etag != `"3"` {
+		t.Fatalf("expected ETag \"3\", got %q", etag)
+	}
+
+	put := func(ifMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPut, "/venues/1", strings.NewReader(`{"name": "Atrium"}`))
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := put(""); w.Code != http.StatusPreconditionRequired {
+		t.Fatalf("expected 428 without If-Match, got %d (%s)", w.Code, w.Body.String())
+	}
+
+	w = put(`"2"`)
+	if w.Code != http.StatusPreconditionFailed {
+		t.Fatalf("expected 412 for stale ETag, got %d (%s)", w.Code, w.Body.String())
+	}
+	var conflict struct {
+		Current models.Venue `json:"current"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &conflict); err != nil {
+		t.Fatalf("invalid json: %v", err)
+	}
+	if conflict.Current.Name != "Lobby" || w.Header().Get("ETag") != `"3"` {
+		t.Fatalf("expected current representation with ETag, got %s (ETag %q)", w.Body.String(), w.Header().Get("ETag"))
+	}
+
+	w = put(`"3"`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 for matching ETag, got %d (%s)", w.Code, w.Body.String())
+	}
+	if etag := w.Header().Get("ETag"); etag != `"4"` {
+		t.Fatalf("expected bumped ETag \"4\", got %q", etag)
+	}
+}
+
+func TestPatchVenueMergesAndClearsSmartFilter(t *testing.T) {
+	region := "sf"
+	repo := &mockVenueRepo{venues: []*models.Venue{{ID: 1, Name: "Lobby", Version: 1, SmartFilter: &models.DeviceSelector{Region: &region}}}}
+	r := newVenueRouter(repo)
+
+	patch := func(body, contentType string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPatch, "/venues/1", strings.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		req.Header.Set("If-Match", "*")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := patch(`{"name": "Atrium"}`, "text/plain"); w.Code != http.StatusUnsupportedMediaType {
+		t.Fatalf("expected 415 got %d (%s)", w.Code, w.Body.String())
+	}
+	if w := patch(`{"name": null}`, "application/merge-patch+json"); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 when clearing the name, got %d (%s)", w.Code, w.Body.String())
+	}
+
+	w := patch(`{"name": "Atrium"}`, "application/merge-patch+json")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 got %d (%s)", w.Code, w.Body.String())
+	}
+	if v := repo.venues[0]; v.Name != "Atrium" || v.SmartFilter == nil || *v.SmartFilter.Region != "sf" {
+		t.Fatalf("expected name patched and smart filter kept, got %+v", v)
+	}
+
+	w = patch(`{"smart_filter": null}`, "application/merge-patch+json")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 got %d (%s)", w.Code, w.Body.String())
+	}
+	if v := repo.venues[0]; v.Name != "Atrium" || v.SmartFilter != nil {
+		t.Fatalf("expected smart filter cleared, got %+v", v)
+	}
+}
+
+func TestListVenuesCursorPagination(t *testing.T) {
+	repo := &mockVenueRepo{}
+	for i := 1; i <= 5; i++ {
+		repo.venues = append(repo.venues, &models.Venue{ID: i, Name: fmt.Sprintf("Venue %d", i)})
+	}
+	r := newVenueRouter(repo)
+
+	type page struct {
+		Data       []models.Venue `json:"data"`
+		Pagination struct {
+			Page       int    `json:"page"`
+			NextCursor string `json:"next_cursor"`
+			PrevCursor string `json:"prev_cursor"`
+		} `json:"pagination"`
+	}
+	list := func(query string) (page, int) {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/venues"+query, nil))
+		var p page
+		if w.Code == http.StatusOK {
+			if err := json.Unmarshal(w.Body.Bytes(), &p); err != nil {
+				t.Fatalf("invalid json: %v", err)
+			}
+		}
+		return p, w.Code
+	}
+	ids := func(p page) string {
+		var out []string
+		for _, v := range p.Data {
+			out = append(out, strconv.Itoa(v.ID))
+		}
+		return strings.Join(out, ",")
+	}
+
+	first, _ := list("?sort=id&page_size=2")
+	if ids(first) != "1,2" || first.Pagination.NextCursor == "" || first.Pagination.PrevCursor != "" {
+		t.Fatalf("unexpected first page: %+v", first)
+	}
+
+	second, _ := list("?page_size=2&cursor=" + first.Pagination.NextCursor)
+	if ids(second) != "3,4" || second.Pagination.NextCursor == "" || second.Pagination.PrevCursor == "" {
+		t.Fatalf("unexpected second page: %+v", second)
+	}
+
+	last, _ := list("?page_size=2&cursor=" + second.Pagination.NextCursor)
+	if ids(last) != "5" || last.Pagination.NextCursor != "" {
+		t.Fatalf("unexpected last page: %+v", last)
+	}
+
+	back, _ := list("?page_size=2&cursor=" + second.Pagination.PrevCursor)
+	if ids(back) != "1,2" || back.Pagination.PrevCursor != "" || back.Pagination.NextCursor == "" {
+		t.Fatalf("unexpected page before the second: %+v", back)
+	}
+
+	for _, query := range []string{
+		"?sort=bogus",
+		"?sort=id,id",
+		"?cursor=not-a-cursor",
+		"?page=2&cursor=" + first.Pagination.NextCursor,
+		"?sort=-id&cursor=" + first.Pagination.NextCursor,
+	} {
+		if _, code := list(query); code != http.StatusBadRequest {
+			t.Fatalf("expected 400 for %s, got %d", query, code)
+		}
+	}
+}
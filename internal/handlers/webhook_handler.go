@@ -0,0 +1,445 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	authmw "scm/internal/middleware"
+	"scm/internal/models"
+	"scm/internal/repository"
+	"scm/internal/services"
+)
+
+type WebhookHandler struct {
+	repo      repository.WebhookRepository
+	validator *validator.Validate
+}
+
+func NewWebhookHandler(repo repository.WebhookRepository) *WebhookHandler {
+	return &WebhookHandler{
+		repo:      repo,
+		validator: validator.New(),
+	}
+}
+
+// webhookSubscriptionWithSecret is returned when a subscription is created or its
+// secret changes, the only times the secret is shown.
+type webhookSubscriptionWithSecret struct {
+	*models.WebhookSubscription
+	Secret string `json:"secret"`
+}
+
+func newWebhookSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func parseDeliveryID(r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil || id <= 0 {
+		return 0, false
+	}
+	return id, true
+}
+
+// @Tags Webhooks
+// @Summary List webhook event types
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/webhooks/event-types [get]
+func (h *WebhookHandler) ListEventTypes(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(map[string]any{"data": models.WebhookEventTypes})
+}
+
+// @Tags Webhooks
+// @Summary List webhook subscriptions
+// @Security BearerAuth
+// @Produce json
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Page size" default(20)
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/webhooks/subscriptions [get]
+func (h *WebhookHandler) ListSubscriptions(w http.ResponseWriter, r *http.Request) {
+	pagination, err := parsePaginationParams(r, 20, 100)
+	if err != nil {
+		writeJSONErrorResponse(w, http.StatusBadRequest, "invalid_pagination", "invalid pagination: "+err.Error())
+		return
+	}
+
+	subs, err := h.repo.ListSubscriptions(r.Context(), pagination.limit, pagination.offset)
+	if err != nil {
+		writeJSONErrorResponse(w, http.StatusInternalServerError, "internal_error", "failed to list webhook subscriptions: "+err.Error())
+		return
+	}
+	total, err := h.repo.CountSubscriptions(r.Context())
+	if err != nil {
+		writeJSONErrorResponse(w, http.StatusInternalServerError, "internal_error", "failed to count webhook subscriptions: "+err.Error())
+		return
+	}
+
+	writePaginatedResponse(w, http.StatusOK, subs, pagination.page, pagination.pageSize, total)
+}
+
+// @Tags Webhooks
+// @Summary Create webhook subscription
+// @Description The response includes the secret payloads are signed with; it is not shown again. A secret is generated when none is given.
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param body body models.CreateWebhookSubscriptionRequest true "Create webhook subscription request"
+// @Success 201 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/webhooks/subscriptions [post]
+func (h *WebhookHandler) CreateSubscription(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateWebhookSubscriptionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONErrorResponse(w, http.StatusBadRequest, "invalid_json", "invalid JSON: "+err.Error())
+		return
+	}
+	if err := h.validator.Struct(req); err != nil {
+		writeJSONErrorResponse(w, http.StatusBadRequest, "validation_error", err.Error())
+		return
+	}
+	if err := services.CheckWebhookURL(req.URL); err != nil {
+		writeJSONErrorResponse(w, http.StatusBadRequest, "invalid_url", err.Error())
+		return
+	}
+
+	sub := &models.WebhookSubscription{
+		URL:        req.URL,
+		EventTypes: slices.Compact(slices.Sorted(slices.Values(req.EventTypes))),
+		Enabled:    true,
+		CreatedBy:  authmw.UserIDFromContext(r.Context()),
+	}
+	if req.Enabled != nil {
+		sub.Enabled = *req.Enabled
+	}
+	if req.Secret != nil {
+		sub.Secret = *req.Secret
+	} else {
+		secret, err := newWebhookSecret()
+		if err != nil {
+			writeJSONErrorResponse(w, http.StatusInternalServerError, "internal_error", "failed to generate webhook secret: "+err.Error())
+			return
+		}
+		sub.Secret = secret
+	}
+
+	if err := h.repo.CreateSubscription(r.Context(), sub); err != nil {
+		writeJSONErrorResponse(w, http.StatusInternalServerError, "internal_error", "failed to create webhook subscription: "+err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(webhookSubscriptionWithSecret{WebhookSubscription: sub, Secret: sub.Secret})
+}
+
+// @Tags Webhooks
+// @Summary Get webhook subscription
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Webhook subscription ID"
+// @Success 200 {object} models.WebhookSubscription
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/webhooks/subscriptions/{id} [get]
+func (h *WebhookHandler) GetSubscription(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseIDParam(r, "id")
+	if !ok {
+		writeJSONErrorResponse(w, http.StatusBadRequest, "invalid_request", "invalid webhook subscription ID")
+		return
+	}
+
+	sub, err := h.repo.GetSubscription(r.Context(), id)
+	if err != nil {
+		if err.Error() == "webhook subscription not found" {
+			writeJSONErrorResponse(w, http.StatusNotFound, "not_found", "webhook subscription not found")
+			return
+		}
+		writeJSONErrorResponse(w, http.StatusInternalServerError, "internal_error", "failed to get webhook subscription: "+err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(sub)
+}
+
+// @Tags Webhooks
+// @Summary Update webhook subscription
+// @Description Pending deliveries keep the URL and secret of the subscription at the time they are sent.
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Webhook subscription ID"
+// @Param body body models.UpdateWebhookSubscriptionRequest true "Update webhook subscription request"
+// @Success 200 {object} models.WebhookSubscription
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/webhooks/subscriptions/{id} [put]
+func (h *WebhookHandler) UpdateSubscription(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseIDParam(r, "id")
+	if !ok {
+		writeJSONErrorResponse(w, http.StatusBadRequest, "invalid_request", "invalid webhook subscription ID")
+		return
+	}
+
+	var req models.UpdateWebhookSubscriptionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONErrorResponse(w, http.StatusBadRequest, "invalid_json", "invalid JSON: "+err.Error())
+		return
+	}
+	if err := h.validator.Struct(req); err != nil {
+		writeJSONErrorResponse(w, http.StatusBadRequest, "validation_error", err.Error())
+		return
+	}
+	if req.URL != nil {
+		if err := services.CheckWebhookURL(*req.URL); err != nil {
+			writeJSONErrorResponse(w, http.StatusBadRequest, "invalid_url", err.Error())
+			return
+		}
+	}
+
+	sub, err := h.repo.GetSubscription(r.Context(), id)
+	if err != nil {
+		if err.Error() == "webhook subscription not found" {
+			writeJSONErrorResponse(w, http.StatusNotFound, "not_found", "webhook subscription not found")
+			return
+		}
+		writeJSONErrorResponse(w, http.StatusInternalServerError, "internal_error", "failed to get webhook subscription: "+err.Error())
+		return
+	}
+
+	if req.URL != nil {
+		sub.URL = *req.URL
+	}
+	if req.EventTypes != nil {
+		sub.EventTypes = slices.Compact(slices.Sorted(slices.Values(*req.EventTypes)))
+	}
+	if req.Secret != nil {
+		sub.Secret = *req.Secret
+	}
+	if req.Enabled != nil {
+		sub.Enabled = *req.Enabled
+	}
+
+	if err := h.repo.UpdateSubscription(r.Context(), sub); err != nil {
+		if err.Error() == "webhook subscription not found" {
+			writeJSONErrorResponse(w, http.StatusNotFound, "not_found", "webhook subscription not found")
+			return
+		}
+		writeJSONErrorResponse(w, http.StatusInternalServerError, "internal_error", "failed to update webhook subscription: "+err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(sub)
+}
+
+// @Tags Webhooks
+// @Summary Delete webhook subscription
+// @Description Deleting a subscription also deletes its deliveries.
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Webhook subscription ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/webhooks/subscriptions/{id} [delete]
+func (h *WebhookHandler) DeleteSubscription(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseIDParam(r, "id")
+	if !ok {
+		writeJSONErrorResponse(w, http.StatusBadRequest, "invalid_request", "invalid webhook subscription ID")
+		return
+	}
+
+	if err := h.repo.DeleteSubscription(r.Context(), id); err != nil {
+		if err.Error() == "webhook subscription not found" {
+			writeJSONErrorResponse(w, http.StatusNotFound, "not_found", "webhook subscription not found")
+			return
+		}
+		writeJSONErrorResponse(w, http.StatusInternalServerError, "internal_error", "failed to delete webhook subscription: "+err.Error())
+		return
+	}
+
+	writeJSONMessage(w, http.StatusOK, "webhook subscription deleted successfully")
+}
+
+// @Tags Webhooks
+// @Summary List webhook deliveries
+// @Description status=dead lists the dead-letter queue: deliveries that ran out of attempts.
+// @Security BearerAuth
+// @Produce json
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Page size" default(20)
+// @Param status query string false "Filter by status (pending, delivered, dead)"
+// @Param subscription_id query int false "Filter by subscription ID"
+// @Param event_type query string false "Filter by event type"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/webhooks/deliveries [get]
+func (h *WebhookHandler) ListDeliveries(w http.ResponseWriter, r *http.Request) {
+	pagination, err := parsePaginationParams(r, 20, 100)
+	if err != nil {
+		writeJSONErrorResponse(w, http.StatusBadRequest, "invalid_pagination", "invalid pagination: "+err.Error())
+		return
+	}
+
+	q := r.URL.Query()
+	filters := repository.WebhookDeliveryFilters{}
+	if v := q.Get("status"); v != "" {
+		switch v {
+		case models.WebhookDeliveryPending, models.WebhookDeliveryDelivered, models.WebhookDeliveryDead:
+			filters.Status = &v
+		default:
+			writeJSONErrorResponse(w, http.StatusBadRequest, "invalid_request", "status must be one of pending, delivered, dead")
+			return
+		}
+	}
+	if v := q.Get("subscription_id"); v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil {
+			writeJSONErrorResponse(w, http.StatusBadRequest, "invalid_request", "invalid subscription_id")
+			return
+		}
+		filters.SubscriptionID = &id
+	}
+	if v := q.Get("event_type"); v != "" {
+		filters.EventType = &v
+	}
+
+	deliveries, err := h.repo.ListDeliveries(r.Context(), filters, pagination.limit, pagination.offset)
+	if err != nil {
+		writeJSONErrorResponse(w, http.StatusInternalServerError, "internal_error", "failed to list webhook deliveries: "+err.Error())
+		return
+	}
+	total, err := h.repo.CountDeliveries(r.Context(), filters)
+	if err != nil {
+		writeJSONErrorResponse(w, http.StatusInternalServerError, "internal_error", "failed to count webhook deliveries: "+err.Error())
+		return
+	}
+
+	writePaginatedResponse(w, http.StatusOK, deliveries, pagination.page, pagination.pageSize, total)
+}
+
+// @Tags Webhooks
+// @Summary Get webhook delivery
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Webhook delivery ID"
+// @Success 200 {object} models.WebhookDelivery
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/webhooks/deliveries/{id} [get]
+func (h *WebhookHandler) GetDelivery(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseDeliveryID(r)
+	if !ok {
+		writeJSONErrorResponse(w, http.StatusBadRequest, "invalid_request", "invalid webhook delivery ID")
+		return
+	}
+
+	delivery, err := h.repo.GetDelivery(r.Context(), id)
+	if err != nil {
+		if err.Error() == "webhook delivery not found" {
+			writeJSONErrorResponse(w, http.StatusNotFound, "not_found", "webhook delivery not found")
+			return
+		}
+		writeJSONErrorResponse(w, http.StatusInternalServerError, "internal_error", "failed to get webhook delivery: "+err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(delivery)
+}
+
+// @Tags Webhooks
+// @Summary Replay webhook delivery
+// @Description Queues a delivered or dead delivery again with a fresh set of attempts. The payload is sent unchanged, with the same event ID.
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Webhook delivery ID"
+// @Success 200 {object} models.WebhookDelivery
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/webhooks/deliveries/{id}/replay [post]
+func (h *WebhookHandler) ReplayDelivery(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseDeliveryID(r)
+	if !ok {
+		writeJSONErrorResponse(w, http.StatusBadRequest, "invalid_request", "invalid webhook delivery ID")
+		return
+	}
+
+	delivery, err := h.repo.Replay(r.Context(), id)
+	if err != nil {
+		switch err.Error() {
+		case "webhook delivery not found":
+			writeJSONErrorResponse(w, http.StatusNotFound, "not_found", "webhook delivery not found")
+		case "webhook delivery is pending":
+			writeJSONErrorResponse(w, http.StatusConflict, "delivery_pending", "the delivery is already queued")
+		default:
+			writeJSONErrorResponse(w, http.StatusInternalServerError, "internal_error", "failed to replay webhook delivery: "+err.Error())
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(delivery)
+}
+
+// @Tags Webhooks
+// @Summary Replay dead webhook deliveries
+// @Description Queues every delivery in the dead-letter queue again, or only those of one subscription.
+// @Security BearerAuth
+// @Produce json
+// @Param subscription_id query int false "Only replay this subscription's deliveries"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/webhooks/deliveries/replay [post]
+func (h *WebhookHandler) ReplayDead(w http.ResponseWriter, r *http.Request) {
+	var subscriptionID *int
+	if v := r.URL.Query().Get("subscription_id"); v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil {
+			writeJSONErrorResponse(w, http.StatusBadRequest, "invalid_request", "invalid subscription_id")
+			return
+		}
+		subscriptionID = &id
+	}
+
+	replayed, err := h.repo.ReplayDead(r.Context(), subscriptionID)
+	if err != nil {
+		writeJSONErrorResponse(w, http.StatusInternalServerError, "internal_error", "failed to replay webhook deliveries: "+err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(map[string]any{"replayed": replayed})
+}
@@ -0,0 +1,215 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"scm/internal/models"
+	"scm/internal/repository"
+)
+
+type mockWebhookRepo struct {
+	subs       []*models.WebhookSubscription
+	deliveries map[int64]*models.WebhookDelivery
+	replayDead *int
+}
+
+var _ repository.WebhookRepository = (*mockWebhookRepo)(nil)
+
+func (m *mockWebhookRepo) CreateSubscription(ctx context.Context, sub *models.WebhookSubscription) error {
+	sub.ID = len(m.subs) + 1
+	m.subs = append(m.subs, sub)
+	return nil
+}
+func (m *mockWebhookRepo) GetSubscription(ctx context.Context, id int) (*models.WebhookSubscription, error) {
+	for _, sub := range m.subs {
+		if sub.ID == id {
+			return sub, nil
+		}
+	}
+	return nil, errors.New("webhook subscription not found")
+}
+func (m *mockWebhookRepo) ListSubscriptions(ctx context.Context, limit int, offset int) ([]*models.WebhookSubscription, error) {
+	return m.subs, nil
+}
+func (m *mockWebhookRepo) CountSubscriptions(ctx context.Context) (int, error) {
+	return len(m.subs), nil
+}
+func (m *mockWebhookRepo) UpdateSubscription(ctx context.Context, sub *models.WebhookSubscription) error {
+	return nil
+}
+func (m *mockWebhookRepo) DeleteSubscription(ctx context.Context, id int) error { return nil }
+func (m *mockWebhookRepo) Enqueue(ctx context.Context, event *models.WebhookEvent) (int, error) {
+	return 0, nil
+}
+func (m *mockWebhookRepo) ClaimDue(ctx context.Context, now time.Time, limit int, lease time.Duration) ([]*models.WebhookDelivery, error) {
+	return nil, nil
+}
+func (m *mockWebhookRepo) MarkDelivered(ctx context.Context, id int64, statusCode int) error {
+	return nil
+}
+func (m *mockWebhookRepo) MarkFailed(ctx context.Context, id int64, statusCode *int, lastError string, retryAt *time.Time) error {
+	return nil
+}
+func (m *mockWebhookRepo) GetDelivery(ctx context.Context, id int64) (*models.WebhookDelivery, error) {
+	if d, ok := m.deliveries[id]; ok {
+		return d, nil
+	}
+	return nil, errors.New("webhook delivery not found")
+}
+func (m *mockWebhookRepo) ListDeliveries(ctx context.Context, filters repository.WebhookDeliveryFilters, limit int, offset int) ([]*models.WebhookDelivery, error) {
+	return []*models.WebhookDelivery{}, nil
+}
+func (m *mockWebhookRepo) CountDeliveries(ctx context.Context, filters repository.WebhookDeliveryFilters) (int, error) {
+	return 0, nil
+}
+func (m *mockWebhookRepo) Replay(ctx context.Context, id int64) (*models.WebhookDelivery, error) {
+	d, err := m.GetDelivery(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if d.Status == models.WebhookDeliveryPending {
+		return nil, errors.New("webhook delivery is pending")
+	}
+	d.Status = models.WebhookDeliveryPending
+	d.Attempts = 0
+	return d, nil
+}
+func (m *mockWebhookRepo) ReplayDead(ctx context.Context, subscriptionID *int) (int64, error) {
+	m.replayDead = subscriptionID
+	return 2, nil
+}
+
+func newWebhookRouter(repo *mockWebhookRepo) http.Handler {
+	h := NewWebhookHandler(repo)
+	r := chi.NewRouter()
+	r.Post("/webhooks/subscriptions", h.CreateSubscription)
+	r.Get("/webhooks/subscriptions/{id}", h.GetSubscription)
+	r.Get("/webhooks/deliveries", h.ListDeliveries)
+	r.Post("/webhooks/deliveries/replay", h.ReplayDead)
+	r.Post("/webhooks/deliveries/{id}/replay", h.ReplayDelivery)
+	return r
+}
+
+func TestCreateWebhookSubscriptionValidatesEventTypes(t *testing.T) {
+	r := newWebhookRouter(&mockWebhookRepo{})
+
+	body := `{"url": "https://hooks.example.com/scm", "event_types": ["campaign.archived"]}`
+	req := httptest.NewRequest(http.MethodPost, "/webhooks/subscriptions", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 got %d (%s)", w.Code, w.Body.String())
+	}
+}
+
+func TestCreateWebhookSubscriptionShowsSecretOnce(t *testing.T) {
+	repo := &mockWebhookRepo{}
+	r := newWebhookRouter(repo)
+
+	body := `{"url": "https://hooks.example.com/scm", "event_types": ["sync.completed", "campaign.activated", "sync.completed"]}`
+	req := httptest.NewRequest(http.MethodPost, "/webhooks/subscriptions", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201 got %d (%s)", w.Code, w.Body.String())
+	}
+	var created map[string]any
+	if err := json.Unmarshal(w.Body.Bytes(), &created); err != nil {
+		t.Fatalf("invalid json: %v", err)
+	}
+	secret, _ := created["secret"].(string)
+	if len(secret) != 64 || secret != repo.subs[0].Secret {
+		t.Fatalf("expected the generated secret in the response, got %q", secret)
+	}
+	if !repo.subs[0].Enabled || strings.Join(repo.subs[0].EventTypes, ",") != "campaign.activated,sync.completed" {
+		t.Fatalf("unexpected subscription: %+v", repo.subs[0])
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/webhooks/subscriptions/1", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 got %d (%s)", w.Code, w.Body.String())
+	}
+	if strings.Contains(w.Body.String(), secret) {
+		t.Fatalf("secret leaked on read: %s", w.Body.String())
+	}
+}
+
+func TestReplayWebhookDelivery(t *testing.T) {
+	repo := &mockWebhookRepo{deliveries: map[int64]*models.WebhookDelivery{
+		1: {ID: 1, Status: models.WebhookDeliveryDead, Attempts: 8},
+		2: {ID: 2, Status: models.WebhookDeliveryPending},
+	}}
+	r := newWebhookRouter(repo)
+
+	cases := []struct {
+		path string
+		want int
+	}{
+		{"/webhooks/deliveries/1/replay", http.StatusOK},
+		{"/webhooks/deliveries/1/replay", http.StatusConflict},
+		{"/webhooks/deliveries/2/replay", http.StatusConflict},
+		{"/webhooks/deliveries/3/replay", http.StatusNotFound},
+		{"/webhooks/deliveries/abc/replay", http.StatusBadRequest},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest(http.MethodPost, c.path, nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != c.want {
+			t.Fatalf("%s: expected %d got %d (%s)", c.path, c.want, w.Code, w.Body.String())
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/webhooks/deliveries/replay?subscription_id=4", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"replayed":2`) {
+		t.Fatalf("expected 2 replayed, got %d (%s)", w.Code, w.Body.String())
+	}
+	if repo.replayDead == nil || *repo.replayDead != 4 {
+		t.Fatalf("expected replay scoped to subscription 4, got %v", repo.replayDead)
+	}
+}
+
+func TestListWebhookDeliveriesRejectsUnknownStatus(t *testing.T) {
+	r := newWebhookRouter(&mockWebhookRepo{})
+
+	req := httptest.NewRequest(http.MethodGet, "/webhooks/deliveries?status=failed", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 got %d (%s)", w.Code, w.Body.String())
+	}
+}
+
+func TestCreateWebhookSubscriptionRejectsInternalHosts(t *testing.T) {
+	repo := &mockWebhookRepo{}
+	r := newWebhookRouter(repo)
+
+	for _, url := range []string{"http://169.254.169.254/latest/meta-data", "http://localhost:9000/hook", "http://10.1.2.3/hook"} {
+		body := `{"url": "` + url + `", "event_types": ["sync.completed"]}`
+		req := httptest.NewRequest(http.MethodPost, "/webhooks/subscriptions", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("%s: expected 400 got %d (%s)", url, w.Code, w.Body.String())
+		}
+	}
+	if len(repo.subs) != 0 {
+		t.Fatalf("expected no subscriptions, got %+v", repo.subs)
+	}
+}
@@ -2,6 +2,8 @@ package interfaces
 
 import (
 	"context"
+	"time"
+
 	"scm/internal/models"
 )
 
@@ -9,8 +11,18 @@ import (
 type AdvertiserRepository interface {
 	Create(ctx context.Context, advertiser *models.Advertiser) error
 	GetByID(ctx context.Context, id string) (*models.Advertiser, error)
-	List(ctx context.Context, limit int, offset int) ([]models.Advertiser, error)
-	Count(ctx context.Context) (int, error)
-	Update(ctx context.Context, id string, req *models.UpdateAdvertiserRequest) error
+	List(ctx context.Context, page ListPage, includeDeleted bool) ([]models.Advertiser, error)
+	Count(ctx context.Context, includeDeleted bool) (int, error)
+	// Update applies req if the advertiser is still at version (0 skips the check) and
+	// returns ErrVersionConflict if it has changed since.
+	Update(ctx context.Context, id string, req *models.UpdateAdvertiserRequest, version int) error
+	// Delete soft-deletes the advertiser; Restore undoes it.
 	Delete(ctx context.Context, id string) error
+	Restore(ctx context.Context, id string) error
+	// PurgeDeletedBefore permanently removes advertisers soft-deleted before cutoff and
+	// returns the S3 keys of the creatives removed with them, which the caller deletes.
+	PurgeDeletedBefore(ctx context.Context, cutoff time.Time) (int64, []string, error)
+	Dependencies(ctx context.Context, id string) (*models.DeletionDependencies, error)
+	// DeleteCascade permanently deletes the advertiser and everything that depends on it.
+	DeleteCascade(ctx context.Context, id string) (*models.DeletionDependencies, error)
 }
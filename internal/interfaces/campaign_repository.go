@@ -13,8 +13,8 @@ type CampaignFilter struct {
     Status       string
     StartDate    time.Time
     EndDate      time.Time
-    Limit        int
-    Offset       int
+    IncludeDeleted bool
+    Page         ListPage // used by List only
 }
 
 // CampaignRepository defines the interface for campaign data operations
@@ -24,8 +24,20 @@ type CampaignRepository interface {
     List(ctx context.Context, filter CampaignFilter) ([]*models.Campaign, error)
     Count(ctx context.Context, filter CampaignFilter) (int, error)
     Summary(ctx context.Context, filter CampaignFilter) (*models.CampaignSummary, error)
-    ActivateScheduledStartingOn(ctx context.Context, startDate time.Time, scheduledStatus string, timeZone string) (int64, error)
-    CompleteActiveEndedBefore(ctx context.Context, now time.Time, activeStatus string, completedStatus string, timeZone string) (int64, error)
-    Update(ctx context.Context, id string, campaign *models.Campaign) error
+    // ActivateScheduledStartingOn and CompleteActiveEndedBefore return the IDs of the
+    // campaigns whose status they changed.
+    ActivateScheduledStartingOn(ctx context.Context, startDate time.Time, scheduledStatus string, timeZone string) ([]string, error)
+    CompleteActiveEndedBefore(ctx context.Context, now time.Time, activeStatus string, completedStatus string, timeZone string) ([]string, error)
+    // Update saves campaign if it is still at version (0 skips the check) and returns
+    // ErrVersionConflict if it has changed since.
+    Update(ctx context.Context, id string, campaign *models.Campaign, version int) error
+    // Delete soft-deletes the campaign; Restore undoes it.
     Delete(ctx context.Context, id string) error
+    Restore(ctx context.Context, id string) error
+    // PurgeDeletedBefore permanently removes campaigns soft-deleted before cutoff and
+    // returns the S3 keys of the creatives removed with them, which the caller deletes.
+    PurgeDeletedBefore(ctx context.Context, cutoff time.Time) (int64, []string, error)
+    Dependencies(ctx context.Context, id string) (*models.DeletionDependencies, error)
+    // DeleteCascade permanently deletes the campaign and its creatives.
+    DeleteCascade(ctx context.Context, id string) (*models.DeletionDependencies, error)
 }
\ No newline at end of file
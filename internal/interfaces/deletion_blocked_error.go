@@ -1,5 +1,7 @@
 package interfaces
 
+import "errors"
+
 type DeletionBlockedError struct {
 	Resource   string
 	References map[string]int64
@@ -8,3 +10,18 @@ type DeletionBlockedError struct {
 func (e *DeletionBlockedError) Error() string {
 	return "deletion blocked"
 }
+
+// RestoreBlockedError is returned when a soft-deleted record cannot be restored
+// because the record it belongs to is itself deleted.
+type RestoreBlockedError struct {
+	Resource string
+	Parent   string
+}
+
+func (e *RestoreBlockedError) Error() string {
+	return "restore blocked"
+}
+
+// ErrVersionConflict is returned by conditional updates when the record exists but
+// its version no longer matches the one the caller read.
+var ErrVersionConflict = errors.New("version conflict")
@@ -0,0 +1,87 @@
+package interfaces
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// SortKey is one term of a list's validated sort order.
+type SortKey struct {
+	Field  string // name used by the sort parameter and in the item's JSON
+	Column string // SQL expression the rows are ordered by
+	Desc   bool
+}
+
+// Sortable describes the fields a list endpoint may be sorted by.
+type Sortable struct {
+	Columns map[string]string // sort field -> SQL expression; values must never be NULL
+	Default string            // sort used when the request has none
+}
+
+// Parse validates a `field,-field` sort parameter. The empty string selects the default
+// sort, and id is appended as a tiebreaker so the order is total, which keyset
+// pagination relies on.
+func (s Sortable) Parse(raw string) ([]SortKey, error) {
+	if strings.TrimSpace(raw) == "" {
+		raw = s.Default
+	}
+
+	var keys []SortKey
+	seen := map[string]bool{}
+	for _, term := range strings.Split(raw, ",") {
+		term = strings.TrimSpace(term)
+		desc := strings.HasPrefix(term, "-")
+		field := strings.TrimPrefix(term, "-")
+		column, ok := s.Columns[field]
+		if !ok {
+			return nil, fmt.Errorf("cannot sort by %q (allowed: %s)", field, strings.Join(s.fields(), ", "))
+		}
+		if seen[field] {
+			return nil, fmt.Errorf("sort field %q given more than once", field)
+		}
+		seen[field] = true
+		keys = append(keys, SortKey{Field: field, Column: column, Desc: desc})
+	}
+	if !seen["id"] {
+		keys = append(keys, SortKey{Field: "id", Column: s.Columns["id"]})
+	}
+	return keys, nil
+}
+
+func (s Sortable) fields() []string {
+	fields := make([]string, 0, len(s.Columns))
+	for f := range s.Columns {
+		fields = append(fields, f)
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// FormatSort renders keys back into the sort parameter syntax.
+func FormatSort(keys []SortKey) string {
+	terms := make([]string, len(keys))
+	for i, k := range keys {
+		terms[i] = k.Field
+		if k.Desc {
+			terms[i] = "-" + k.Field
+		}
+	}
+	return strings.Join(terms, ",")
+}
+
+// ListPage selects one page of a sorted list, either by offset or by keyset. After holds
+// the sort values of the row the page continues after; Before, when set instead, those
+// of the row it ends before.
+type ListPage struct {
+	Sort   []SortKey
+	Limit  int
+	Offset int
+	After  []any
+	Before []any
+}
+
+// Backward reports whether the page is read backwards from Before.
+func (p ListPage) Backward() bool {
+	return p.Before != nil
+}
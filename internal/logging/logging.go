@@ -0,0 +1,122 @@
+// Package logging sets up the API's structured JSON logs. Lines logged with a request's
+// context carry its request ID, route, user and latency so far, and secrets are
+// redacted wherever they appear.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	chimw "github.com/go-chi/chi/v5/middleware"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ParseLevel parses a LOG_LEVEL value: debug, info, warn or error, case-insensitively.
+func ParseLevel(s string) (slog.Level, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(strings.TrimSpace(s))); err != nil {
+		return 0, fmt.Errorf("invalid log level %q (want debug, info, warn or error)", s)
+	}
+	return level, nil
+}
+
+// New returns a logger writing JSON lines at level and above to w.
+func New(w io.Writer, level slog.Leveler) *slog.Logger {
+	return slog.New(contextHandler{slog.NewJSONHandler(w, &slog.HandlerOptions{
+		Level:       level,
+		ReplaceAttr: redactAttr,
+	})})
+}
+
+// requestFields are the request attributes that only become known after the logging
+// middleware has run, e.g. the user, which JWTAuth sets further down the chain.
+type requestFields struct {
+	start  time.Time
+	userID atomic.Value // string
+}
+
+type fieldsKey struct{}
+
+func fieldsFrom(ctx context.Context) *requestFields {
+	f, _ := ctx.Value(fieldsKey{}).(*requestFields)
+	return f
+}
+
+// SetUserID attaches the authenticated user to the request's log lines, including the
+// access log line written once the request completes.
+func SetUserID(ctx context.Context, userID string) {
+	if f := fieldsFrom(ctx); f != nil {
+		f.userID.Store(userID)
+	}
+}
+
+// contextHandler adds the request attributes found in the context to each record.
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if id := chimw.GetReqID(ctx); id != "" {
+		r.AddAttrs(slog.String("request_id", id))
+	}
+	if rctx := chi.RouteContext(ctx); rctx != nil && rctx.RoutePattern() != "" {
+		r.AddAttrs(slog.String("route", rctx.RoutePattern()))
+	}
+	if f := fieldsFrom(ctx); f != nil {
+		if userID, _ := f.userID.Load().(string); userID != "" {
+			r.AddAttrs(slog.String("user_id", userID))
+		}
+		r.AddAttrs(latency(time.Since(f.start)))
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		r.AddAttrs(slog.String("trace_id", sc.TraceID().String()))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
+
+func latency(d time.Duration) slog.Attr {
+	return slog.Float64("latency_ms", float64(d.Microseconds())/1000)
+}
+
+// Middleware replaces chi's text access log: it writes a "request" line when each
+// request completes, at error level for 5xx responses, and makes the request's
+// attributes available to everything logged with its context. It must run after chi's
+// RequestID and RealIP middleware.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), fieldsKey{}, &requestFields{start: time.Now()})
+		ww := chimw.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r.WithContext(ctx))
+
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		level := slog.LevelInfo
+		if status >= http.StatusInternalServerError {
+			level = slog.LevelError
+		}
+		slog.LogAttrs(ctx, level, "request",
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.Int("status", status),
+			slog.Int("bytes", ww.BytesWritten()),
+			slog.String("remote_addr", r.RemoteAddr),
+		)
+	})
+}
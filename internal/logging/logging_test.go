@@ -0,0 +1,150 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	chimw "github.com/go-chi/chi/v5/middleware"
+)
+
+// captureLogs makes the default logger write to a buffer for the test.
+func captureLogs(t *testing.T, level slog.Level) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	prev := slog.Default()
+	slog.SetDefault(New(&buf, level))
+	t.Cleanup(func() { slog.SetDefault(prev) })
+	return &buf
+}
+
+func decodeLines(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var lines []map[string]any
+	for _, raw := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if raw == "" {
+			continue
+		}
+		var line map[string]any
+		if err := json.Unmarshal([]byte(raw), &line); err != nil {
+			t.Fatalf("log line is not JSON: %q: %v", raw, err)
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func TestMiddlewareAddsRequestAttributes(t *testing.T) {
+	buf := captureLogs(t, slog.LevelInfo)
+
+	r := chi.NewRouter()
+	r.Use(chimw.RequestID)
+	r.Use(Middleware)
+	r.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			SetUserID(r.Context(), "user-1")
+			next.ServeHTTP(w, r)
+		})
+	})
+	r.Get("/campaigns/{id}", func(w http.ResponseWriter, r *http.Request) {
+		slog.InfoContext(r.Context(), "loading campaign")
+		w.WriteHeader(http.StatusTeapot)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/campaigns/42", nil)
+	req.Header.Set(chimw.RequestIDHeader, "req-1")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	lines := decodeLines(t, buf)
+	if len(lines) != 2 {
+		t.Fatalf("got %d log lines, want 2: %s", len(lines), buf)
+	}
+	for _, line := range lines {
+		if line["request_id"] != "req-1" || line["user_id"] != "user-1" || line["route"] != "/campaigns/{id}" {
+			t.Errorf("line missing request attributes: %v", line)
+		}
+		if _, ok := line["latency_ms"].(float64); !ok {
+			t.Errorf("line missing latency_ms: %v", line)
+		}
+	}
+	access := lines[1]
+	if access["msg"] != "request" || access["status"] != float64(http.StatusTeapot) || access["method"] != http.MethodGet || access["path"] != "/campaigns/42" {
+		t.Errorf("access line = %v", access)
+	}
+}
+
+func TestMiddlewareLogsServerErrorsAtErrorLevel(t *testing.T) {
+	buf := captureLogs(t, slog.LevelInfo)
+
+	h := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	lines := decodeLines(t, buf)
+	if len(lines) != 1 || lines[0]["level"] != "ERROR" {
+		t.Fatalf("lines = %v, want one ERROR line", lines)
+	}
+}
+
+func TestLevelFiltersLines(t *testing.T) {
+	buf := captureLogs(t, slog.LevelWarn)
+
+	slog.Info("hidden")
+	slog.Warn("shown")
+
+	lines := decodeLines(t, buf)
+	if len(lines) != 1 || lines[0]["msg"] != "shown" {
+		t.Fatalf("lines = %v, want only the warning", lines)
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	for in, want := range map[string]slog.Level{"debug": slog.LevelDebug, "INFO": slog.LevelInfo, " warn ": slog.LevelWarn, "error": slog.LevelError} {
+		got, err := ParseLevel(in)
+		if err != nil || got != want {
+			t.Errorf("ParseLevel(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+	if _, err := ParseLevel("verbose"); err == nil {
+		t.Error("ParseLevel(verbose) succeeded, want error")
+	}
+}
+
+func TestRedactsSecrets(t *testing.T) {
+	buf := captureLogs(t, slog.LevelInfo)
+
+	slog.Info("connecting to postgres://scm:hunter2@db:5432/scm",
+		"password", "hunter2",
+		"SMTPPassword", "hunter2",
+		"jwt_secret", "hunter2",
+		"reset_token", "hunter2",
+		"dsn", "host=db user=scm",
+		slog.Group("db", "database_url", "postgres://scm@db/scm"),
+		"error", errors.New("dial host=db user=scm password=hunter2: refused"),
+		"header", "Bearer abc.def.ghi",
+		"jwt", "eyJhbGciOiJIUzI1NiJ9.eyJzdWIiOiIxIn0.c2ln",
+		"email", "ops@example.com",
+	)
+
+	out := buf.String()
+	if strings.Contains(out, "hunter2") || strings.Contains(out, "abc.def.ghi") || strings.Contains(out, "eyJ") {
+		t.Fatalf("secret leaked: %s", out)
+	}
+	line := decodeLines(t, buf)[0]
+	if line["msg"] != "connecting to postgres://scm:[REDACTED]@db:5432/scm" {
+		t.Errorf("msg = %q", line["msg"])
+	}
+	if line["error"] != "dial host=db user=scm password=[REDACTED] refused" {
+		t.Errorf("error = %q", line["error"])
+	}
+	if line["email"] != "ops@example.com" {
+		t.Errorf("email = %q, want it untouched", line["email"])
+	}
+}
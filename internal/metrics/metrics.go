@@ -0,0 +1,167 @@
+// Package metrics holds the Prometheus metrics the API exposes on /metrics.
+package metrics
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	chimw "github.com/go-chi/chi/v5/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "scm"
+
+// Registry holds the API's metrics, alongside the Go runtime and process collectors.
+var Registry = prometheus.NewRegistry()
+
+var (
+	httpRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by method, chi route pattern and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	jobRuns = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "job_runs_total",
+		Help:      "Background job runs by job and result (success or error).",
+	}, []string{"job", "result"})
+	jobDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "job_duration_seconds",
+		Help:      "Background job run duration by job.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"job"})
+	jobItems = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "job_items_total",
+		Help:      "Records background jobs changed, e.g. campaigns activated, by job.",
+	}, []string{"job"})
+
+	syncDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "console_sync_duration_seconds",
+		Help:      "CityPost console sync duration by mode and result (success or error).",
+		Buckets:   []float64{1, 2.5, 5, 10, 30, 60, 120, 300, 600},
+	}, []string{"mode", "result"})
+	syncProjectErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "console_sync_project_errors_total",
+		Help:      "Errors syncing a project or its devices from the CityPost console, by project.",
+	}, []string{"project"})
+
+	storageUploadBytes = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "storage_upload_bytes_total",
+		Help:      "Bytes uploaded to object storage.",
+	})
+	storageUploadDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "storage_upload_duration_seconds",
+		Help:      "Object storage upload latency by result (success or error).",
+		Buckets:   []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60},
+	}, []string{"result"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequestDuration,
+		jobRuns, jobDuration, jobItems,
+		syncDuration, syncProjectErrors,
+		storageUploadBytes, storageUploadDuration,
+	)
+}
+
+// RegisterDB exposes db's connection pool statistics (sql.DBStats) as go_sql_* gauges
+// and counters labelled db_name.
+func RegisterDB(db *sql.DB, name string) {
+	Registry.MustRegister(collectors.NewDBStatsCollector(db, name))
+}
+
+// Handler serves the metrics. When token is non-empty, scrapes must send it as a
+// bearer token.
+func Handler(token string) http.Handler {
+	h := promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+	if token == "" {
+		return h
+	}
+	want := []byte("Bearer " + token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), want) != 1 {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// Middleware records each request's latency under its chi route pattern, so
+// /campaigns/{id} is one series however many campaigns there are. Requests that match
+// no route are recorded as "unmatched".
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ww := chimw.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r)
+
+		route := "unmatched"
+		if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+			route = rctx.RoutePattern()
+		}
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		httpRequestDuration.WithLabelValues(r.Method, route, strconv.Itoa(status)).Observe(time.Since(start).Seconds())
+	})
+}
+
+func result(err error) string {
+	if err != nil {
+		return "error"
+	}
+	return "success"
+}
+
+// ObserveJob records a background job run that started at start, changed items
+// records and failed with err, if not nil.
+func ObserveJob(job string, start time.Time, items int, err error) {
+	jobRuns.WithLabelValues(job, result(err)).Inc()
+	jobDuration.WithLabelValues(job).Observe(time.Since(start).Seconds())
+	if items > 0 {
+		jobItems.WithLabelValues(job).Add(float64(items))
+	}
+}
+
+// ObserveSync records a console sync run that started at start; failed says whether
+// it reported errors.
+func ObserveSync(mode string, start time.Time, failed bool) {
+	res := "success"
+	if failed {
+		res = "error"
+	}
+	syncDuration.WithLabelValues(mode, res).Observe(time.Since(start).Seconds())
+}
+
+// SyncProjectError counts an error syncing project or its devices.
+func SyncProjectError(project string) {
+	syncProjectErrors.WithLabelValues(project).Inc()
+}
+
+// ObserveUpload records an object storage upload of size bytes that started at start.
+// Only successful uploads count towards the bytes uploaded.
+func ObserveUpload(size int64, start time.Time, err error) {
+	storageUploadDuration.WithLabelValues(result(err)).Observe(time.Since(start).Seconds())
+	if err == nil {
+		storageUploadBytes.Add(float64(size))
+	}
+}
@@ -0,0 +1,82 @@
+package metrics
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func scrape(t *testing.T, h http.Handler, auth string) (int, string) {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	if auth != "" {
+		req.Header.Set("Authorization", auth)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	return w.Code, w.Body.String()
+}
+
+func TestMiddlewareLabelsByRoutePattern(t *testing.T) {
+	r := chi.NewRouter()
+	r.Use(Middleware)
+	r.Route("/api/v1/campaigns", func(r chi.Router) {
+		r.Get("/{id}", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+		})
+	})
+
+	for _, path := range []string{"/api/v1/campaigns/a", "/api/v1/campaigns/b", "/nowhere"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	_, body := scrape(t, Handler(""), "")
+	for _, want := range []string{
+		`scm_http_request_duration_seconds_count{method="GET",route="/api/v1/campaigns/{id}",status="404"} 2`,
+		`scm_http_request_duration_seconds_count{method="GET",route="unmatched",status="404"} 1`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("missing %s", want)
+		}
+	}
+}
+
+func TestObserveJobAndUpload(t *testing.T) {
+	start := time.Now()
+	ObserveJob("test_job", start, 3, nil)
+	ObserveJob("test_job", start, 0, errors.New("boom"))
+	ObserveUpload(2048, start, nil)
+	ObserveUpload(4096, start, errors.New("denied"))
+
+	_, body := scrape(t, Handler(""), "")
+	for _, want := range []string{
+		`scm_job_runs_total{job="test_job",result="success"} 1`,
+		`scm_job_runs_total{job="test_job",result="error"} 1`,
+		`scm_job_items_total{job="test_job"} 3`,
+		`scm_storage_upload_bytes_total 2048`,
+		`scm_storage_upload_duration_seconds_count{result="error"} 1`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("missing %s", want)
+		}
+	}
+}
+
+func TestHandlerRequiresToken(t *testing.T) {
+	h := Handler("scrape-me")
+
+	if code, _ := scrape(t, h, ""); code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without a token, got %d", code)
+	}
+	if code, _ := scrape(t, h, "Bearer wrong"); code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 with a wrong token, got %d", code)
+	}
+	if code, body := scrape(t, h, "Bearer scrape-me"); code != http.StatusOK || !strings.Contains(body, "go_goroutines") {
+		t.Fatalf("expected metrics, got %d", code)
+	}
+}
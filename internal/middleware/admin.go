@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// Admins is the set of email addresses allowed to perform admin-only operations
+// such as cascade deletes.
+type Admins map[string]struct{}
+
+func NewAdmins(emails []string) Admins {
+	admins := Admins{}
+	for _, email := range emails {
+		if email = strings.ToLower(strings.TrimSpace(email)); email != "" {
+			admins[email] = struct{}{}
+		}
+	}
+	return admins
+}
+
+// IsAdmin reports whether the user authenticated by JWTAuth is an admin.
+func (a Admins) IsAdmin(ctx context.Context) bool {
+	email, _ := ctx.Value(CtxEmail).(string)
+	if email == "" {
+		return false
+	}
+	_, ok := a[strings.ToLower(email)]
+	return ok
+}
+
+// RequireAdmin rejects callers that are not admins with 403. It must run after JWTAuth.
+func (a Admins) RequireAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !a.IsAdmin(r.Context()) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusForbidden)
+			_ = json.NewEncoder(w).Encode(map[string]any{"error": "forbidden", "message": "This endpoint requires an admin"})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
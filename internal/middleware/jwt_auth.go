@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"scm/internal/logging"
 )
 
 type ctxKey string
@@ -55,9 +56,16 @@ func JWTAuth(secret string) func(http.Handler) http.Handler {
 				return
 			}
 
+			logging.SetUserID(r.Context(), sub)
 			ctx := context.WithValue(r.Context(), CtxUserID, sub)
 			ctx = context.WithValue(ctx, CtxEmail, email)
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
+
+// UserIDFromContext returns the authenticated user's ID, or "" outside JWTAuth.
+func UserIDFromContext(ctx context.Context) string {
+	v, _ := ctx.Value(CtxUserID).(string)
+	return v
+}
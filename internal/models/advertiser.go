@@ -5,12 +5,14 @@ import (
 )
 
 type Advertiser struct {
-	ID        string    `json:"id"`
-	Name      string    `json:"name" validate:"required,min=3,max=255"`
-	Email     string    `json:"email,omitempty" validate:"omitempty,email"`
-	CreatedBy string    `json:"created_by"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID        string     `json:"id"`
+	Name      string     `json:"name" validate:"required,min=3,max=255"`
+	Email     string     `json:"email,omitempty" validate:"omitempty,email"`
+	CreatedBy string     `json:"created_by"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+	Version   int        `json:"version"`
 }
 
 type CreateAdvertiserRequest struct {
@@ -23,3 +25,19 @@ type UpdateAdvertiserRequest struct {
 	Name  *string `json:"name,omitempty" validate:"omitempty,min=3,max=255"`
 	Email *string `json:"email,omitempty" validate:"omitempty,email"`
 }
+
+// ReplaceAdvertiserRequest is the editable representation of an advertiser: the body
+// of PUT and the document PATCH merge patches are applied to. An empty email clears it.
+type ReplaceAdvertiserRequest struct {
+	Name  string `json:"name" validate:"required,min=3,max=255"`
+	Email string `json:"email,omitempty" validate:"omitempty,email"`
+}
+
+func NewReplaceAdvertiserRequest(a *Advertiser) ReplaceAdvertiserRequest {
+	return ReplaceAdvertiserRequest{Name: a.Name, Email: a.Email}
+}
+
+// ToUpdate returns the repository update that sets every editable field.
+func (r ReplaceAdvertiserRequest) ToUpdate() *UpdateAdvertiserRequest {
+	return &UpdateAdvertiserRequest{Name: &r.Name, Email: &r.Email}
+}
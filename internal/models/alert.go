@@ -0,0 +1,87 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	AlertRuleVenueOffline = "venue_offline"
+	AlertRuleSyncErrors   = "sync_errors"
+
+	AlertStatusOpen         = "open"
+	AlertStatusAcknowledged = "acknowledged"
+	AlertStatusResolved     = "resolved"
+)
+
+// AlertRule describes when an alert is raised and where it is delivered.
+//
+// venue_offline rules fire when more than OfflineDevices devices of a venue (or of any
+// venue when VenueID is nil) have been offline for more than OfflineMinutes.
+// sync_errors rules fire when a console sync run reports errors.
+type AlertRule struct {
+	ID              int       `json:"id"`
+	Name            string    `json:"name"`
+	Type            string    `json:"type"`
+	VenueID         *int      `json:"venue_id,omitempty"`
+	OfflineDevices  int       `json:"offline_devices"`
+	OfflineMinutes  int       `json:"offline_minutes"`
+	EmailRecipients []string  `json:"email_recipients"`
+	WebhookURL      *string   `json:"webhook_url,omitempty"`
+	WebhookSecret   *string   `json:"-"`
+	Enabled         bool      `json:"enabled"`
+	CreatedBy       string    `json:"created_by"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+type CreateAlertRuleRequest struct {
+	Name            string   `json:"name" validate:"required,min=3,max=255"`
+	Type            string   `json:"type" validate:"required,oneof=venue_offline sync_errors"`
+	VenueID         *int     `json:"venue_id,omitempty"`
+	OfflineDevices  int      `json:"offline_devices" validate:"gte=0"`
+	OfflineMinutes  int      `json:"offline_minutes" validate:"gte=0"`
+	EmailRecipients []string `json:"email_recipients" validate:"omitempty,dive,email"`
+	WebhookURL      *string  `json:"webhook_url,omitempty" validate:"omitempty,url"`
+	WebhookSecret   *string  `json:"webhook_secret,omitempty"`
+	Enabled         *bool    `json:"enabled,omitempty"`
+}
+
+type UpdateAlertRuleRequest struct {
+	Name            *string   `json:"name,omitempty" validate:"omitempty,min=3,max=255"`
+	VenueID         *int      `json:"venue_id,omitempty"`
+	OfflineDevices  *int      `json:"offline_devices,omitempty" validate:"omitempty,gte=0"`
+	OfflineMinutes  *int      `json:"offline_minutes,omitempty" validate:"omitempty,gte=0"`
+	EmailRecipients *[]string `json:"email_recipients,omitempty" validate:"omitempty,dive,email"`
+	WebhookURL      *string   `json:"webhook_url,omitempty" validate:"omitempty,url"`
+	WebhookSecret   *string   `json:"webhook_secret,omitempty"`
+	Enabled         *bool     `json:"enabled,omitempty"`
+}
+
+// Alert is one occurrence of a rule firing. While an alert is open or acknowledged,
+// further triggers with the same DedupKey update it instead of raising a new one.
+type Alert struct {
+	ID               int             `json:"id"`
+	RuleID           int             `json:"rule_id"`
+	RuleType         string          `json:"rule_type"`
+	DedupKey         string          `json:"dedup_key"`
+	Status           string          `json:"status"`
+	Title            string          `json:"title"`
+	Details          json.RawMessage `json:"details" swaggertype:"object"`
+	VenueID          *int            `json:"venue_id,omitempty"`
+	TriggerCount     int             `json:"trigger_count"`
+	FirstTriggeredAt time.Time       `json:"first_triggered_at"`
+	LastTriggeredAt  time.Time       `json:"last_triggered_at"`
+	AcknowledgedAt   *time.Time      `json:"acknowledged_at,omitempty"`
+	AcknowledgedBy   *string         `json:"acknowledged_by,omitempty"`
+	ResolvedAt       *time.Time      `json:"resolved_at,omitempty"`
+	NotifiedAt       *time.Time      `json:"notified_at,omitempty"`
+}
+
+// VenueOfflineCount is the number of long-offline devices in a venue.
+type VenueOfflineCount struct {
+	VenueID   int    `json:"venue_id"`
+	VenueName string `json:"venue_name"`
+	Offline   int    `json:"offline"`
+	Total     int    `json:"total"`
+}
@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// Annotations is the local metadata of a console-synced device or project: tags, notes,
+// an internal owner and custom key/values. It is stored apart from the synced record, so
+// console sync never overwrites it.
+type Annotations struct {
+	Tags          []string          `json:"tags"`
+	Notes         string            `json:"notes"`
+	InternalOwner string            `json:"internal_owner"`
+	Attributes    map[string]string `json:"attributes"`
+	Version       int               `json:"version"`
+	UpdatedAt     *time.Time        `json:"updated_at,omitempty"`
+}
+
+// AnnotationsRequest is the editable representation of Annotations; PATCH merges into it.
+type AnnotationsRequest struct {
+	Tags          []string          `json:"tags"`
+	Notes         string            `json:"notes"`
+	InternalOwner string            `json:"internal_owner"`
+	Attributes    map[string]string `json:"attributes"`
+}
+
+func NewAnnotationsRequest(a *Annotations) AnnotationsRequest {
+	return AnnotationsRequest{Tags: a.Tags, Notes: a.Notes, InternalOwner: a.InternalOwner, Attributes: a.Attributes}
+}
@@ -28,6 +28,8 @@ type Campaign struct {
     AdvertiserID string        `json:"advertiser_id" validate:"required,uuid4"`
     CreatedAt    time.Time     `json:"created_at"`
     UpdatedAt    time.Time     `json:"updated_at"`
+    DeletedAt    *time.Time    `json:"deleted_at,omitempty"`
+    Version      int           `json:"version"`
 }
 
 type CreateCampaignRequest struct {
@@ -39,13 +41,36 @@ type CreateCampaignRequest struct {
     AdvertiserID string    `json:"advertiser_id" validate:"required,uuid4"`
 }
 
-type UpdateCampaignRequest struct {
-    Name      *string    `json:"name,omitempty"`
-    Status    *string    `json:"status,omitempty" validate:"omitempty,oneof=draft active paused scheduled completed"`
-    Cities    *[]string  `json:"cities,omitempty"`
-    StartDate *time.Time `json:"start_date,omitempty"`
-    EndDate   *time.Time `json:"end_date,omitempty" validate:"omitempty,gtfield=StartDate"`
-    Budget    *float64   `json:"budget,omitempty" validate:"omitempty,gt=0"`
+// ReplaceCampaignRequest is the editable representation of a campaign: the body of PUT
+// and the document PATCH merge patches are applied to.
+type ReplaceCampaignRequest struct {
+    Name      string    `json:"name" validate:"required"`
+    Status    string    `json:"status" validate:"required,oneof=draft active paused scheduled completed"`
+    Cities    []string  `json:"cities"`
+    StartDate time.Time `json:"start_date" validate:"required"`
+    EndDate   time.Time `json:"end_date" validate:"required,gtfield=StartDate"`
+    Budget    float64   `json:"budget" validate:"required,gt=0"`
+}
+
+func NewReplaceCampaignRequest(c *Campaign) ReplaceCampaignRequest {
+    return ReplaceCampaignRequest{
+        Name:      c.Name,
+        Status:    string(c.Status),
+        Cities:    c.Cities,
+        StartDate: c.StartDate,
+        EndDate:   c.EndDate,
+        Budget:    c.Budget,
+    }
+}
+
+// ApplyTo overwrites c's editable fields.
+func (r ReplaceCampaignRequest) ApplyTo(c *Campaign) {
+    c.Name = r.Name
+    c.Status = CampaignStatus(r.Status)
+    c.Cities = r.Cities
+    c.StartDate = r.StartDate
+    c.EndDate = r.EndDate
+    c.Budget = r.Budget
 }
 
 type CampaignSummary struct {
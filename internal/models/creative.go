@@ -22,6 +22,8 @@ type Creative struct {
     TimeSlots    []string    `json:"time_slots"`
     Devices      []string    `json:"devices"`
     UploadedAt   time.Time   `json:"uploaded_at"`
+    DeletedAt    *time.Time  `json:"deleted_at,omitempty"`
+    Version      int         `json:"version"`
 }
 
 type CreateCreativeRequest struct {
@@ -38,4 +40,40 @@ type UpdateCreativeRequest struct {
     SelectedDays *[]string    `json:"selected_days,omitempty"`
     TimeSlots    *[]string    `json:"time_slots,omitempty"`
     Devices      *[]string    `json:"devices,omitempty"`
-}
\ No newline at end of file
+}
+
+// ReplaceCreativeRequest is the editable representation of a creative: the body of a
+// JSON PUT and the document PATCH merge patches are applied to. The file, and with it
+// type, url and size, is replaced by a multipart PUT.
+type ReplaceCreativeRequest struct {
+    Name         string   `json:"name" validate:"required"`
+    SelectedDays []string `json:"selected_days"`
+    TimeSlots    []string `json:"time_slots"`
+    Devices      []string `json:"devices"`
+}
+
+func NewReplaceCreativeRequest(c *Creative) ReplaceCreativeRequest {
+    return ReplaceCreativeRequest{
+        Name:         c.Name,
+        SelectedDays: c.SelectedDays,
+        TimeSlots:    c.TimeSlots,
+        Devices:      c.Devices,
+    }
+}
+
+// ToUpdate returns the repository update that sets every editable field; missing
+// lists are cleared.
+func (r ReplaceCreativeRequest) ToUpdate() *UpdateCreativeRequest {
+    orEmpty := func(v []string) *[]string {
+        if v == nil {
+            v = []string{}
+        }
+        return &v
+    }
+    return &UpdateCreativeRequest{
+        Name:         &r.Name,
+        SelectedDays: orEmpty(r.SelectedDays),
+        TimeSlots:    orEmpty(r.TimeSlots),
+        Devices:      orEmpty(r.Devices),
+    }
+}
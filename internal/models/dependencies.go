@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// DependentRecord is a record that would be removed along with its parent by a cascade delete.
+type DependentRecord struct {
+	ID        string     `json:"id"`
+	Name      string     `json:"name"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+}
+
+// DeletionDependencies lists what depends on a record. References holds the counts
+// per dependent kind; StoredObjects are the S3 keys of dependent creatives.
+type DeletionDependencies struct {
+	Resource      string            `json:"resource"`
+	ID            string            `json:"id"`
+	References    map[string]int64  `json:"references"`
+	Campaigns     []DependentRecord `json:"campaigns,omitempty"`
+	Creatives     []DependentRecord `json:"creatives,omitempty"`
+	Devices       []DependentRecord `json:"devices,omitempty"`
+	AlertRules    []DependentRecord `json:"alert_rules,omitempty"`
+	StoredObjects []string          `json:"stored_objects,omitempty"`
+}
@@ -3,6 +3,8 @@ package models
 import (
 	"database/sql/driver"
 	"encoding/json"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -23,6 +25,54 @@ type Device struct {
 	// Local fields
 	CreatedAt time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+
+	// Annotations is the local metadata kept apart from the console record; nil
+	// until it is first annotated.
+	Annotations *Annotations `json:"annotations,omitempty" db:"-"`
+}
+
+// Coordinates returns the device location as (longitude, latitude).
+// Coordinates in device_config win over the region centre, which is the only
+// location the console guarantees.
+func (d *Device) Coordinates() (float64, float64, bool) {
+	if len(d.DeviceConfig) > 0 {
+		var cfg map[string]any
+		if err := json.Unmarshal(d.DeviceConfig, &cfg); err == nil {
+			lat, latOK := parseCoordinate(cfg["latitude"])
+			if !latOK {
+				lat, latOK = parseCoordinate(cfg["lat"])
+			}
+			lon, lonOK := parseCoordinate(cfg["longitude"])
+			if !lonOK {
+				lon, lonOK = parseCoordinate(cfg["lng"])
+			}
+			if latOK && lonOK {
+				return lon, lat, true
+			}
+		}
+	}
+
+	lat, latOK := parseCoordinate(d.Region.Latitude)
+	lon, lonOK := parseCoordinate(d.Region.Longitude)
+	if latOK && lonOK {
+		return lon, lat, true
+	}
+	return 0, 0, false
+}
+
+func parseCoordinate(v any) (float64, bool) {
+	switch t := v.(type) {
+	case float64:
+		return t, true
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(t), 64)
+		if err != nil {
+			return 0, false
+		}
+		return f, true
+	default:
+		return 0, false
+	}
 }
 
 type DeviceType struct {
@@ -115,3 +165,12 @@ type UpdateDeviceRequest struct {
 	Description *string `json:"description,omitempty"`
 	// Add other updatable fields as needed
 }
+
+// FacetCount is the number of devices sharing one value of a facet.
+type FacetCount struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+// DeviceFacets holds the counts of each facet of a device list, by facet name.
+type DeviceFacets map[string][]FacetCount
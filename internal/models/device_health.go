@@ -0,0 +1,75 @@
+package models
+
+import "time"
+
+type DeviceHealthStatus string
+
+const (
+	DeviceHealthOnline  DeviceHealthStatus = "online"
+	DeviceHealthStale   DeviceHealthStatus = "stale"
+	DeviceHealthOffline DeviceHealthStatus = "offline"
+)
+
+func (s DeviceHealthStatus) Valid() bool {
+	switch s {
+	case DeviceHealthOnline, DeviceHealthStale, DeviceHealthOffline:
+		return true
+	default:
+		return false
+	}
+}
+
+// HealthThresholds decide how old a heartbeat may be before a device is stale or offline.
+type HealthThresholds struct {
+	StaleAfter   time.Duration
+	OfflineAfter time.Duration
+}
+
+// Classify returns the health of a device whose last heartbeat was received at lastSeen.
+// Devices that never sent a heartbeat are offline.
+func (t HealthThresholds) Classify(lastSeen *time.Time, now time.Time) DeviceHealthStatus {
+	if lastSeen == nil {
+		return DeviceHealthOffline
+	}
+	age := now.Sub(*lastSeen)
+	switch {
+	case age < t.StaleAfter:
+		return DeviceHealthOnline
+	case age < t.OfflineAfter:
+		return DeviceHealthStale
+	default:
+		return DeviceHealthOffline
+	}
+}
+
+// DeviceHeartbeat is the latest heartbeat reported by a screen.
+type DeviceHeartbeat struct {
+	HostName             string     `json:"host_name" db:"host_name"`
+	UptimeSeconds        int64      `json:"uptime_seconds" db:"uptime_seconds"`
+	PlaylistVersion      string     `json:"playlist_version" db:"playlist_version"`
+	FreeDiskBytes        int64      `json:"free_disk_bytes" db:"free_disk_bytes"`
+	LastCreativeID       *string    `json:"last_creative_id,omitempty" db:"last_creative_id"`
+	LastCreativePlayedAt *time.Time `json:"last_creative_played_at,omitempty" db:"last_creative_played_at"`
+	ReceivedAt           time.Time  `json:"received_at" db:"received_at"`
+}
+
+type DeviceHeartbeatRequest struct {
+	UptimeSeconds        int64      `json:"uptime_seconds"`
+	PlaylistVersion      string     `json:"playlist_version"`
+	FreeDiskBytes        int64      `json:"free_disk_bytes"`
+	LastCreativeID       *string    `json:"last_creative_id,omitempty"`
+	LastCreativePlayedAt *time.Time `json:"last_creative_played_at,omitempty"`
+}
+
+type DeviceHealth struct {
+	HostName      string             `json:"host_name"`
+	Status        DeviceHealthStatus `json:"status"`
+	LastHeartbeat *DeviceHeartbeat   `json:"last_heartbeat"`
+}
+
+type DeviceHealthSummary struct {
+	Online  int `json:"online"`
+	Stale   int `json:"stale"`
+	Offline int `json:"offline"`
+	Total   int `json:"total"`
+}
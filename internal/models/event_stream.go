@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// Event types only the dashboard event stream carries. The stream also carries the
+// webhook events that are raised.
+const (
+	// StreamEventSyncProgress reports each project a console sync has finished; only
+	// the user who started the sync receives it.
+	StreamEventSyncProgress = "sync.progress"
+	// StreamEventDeviceHealthChanged reports a device moving between online, stale
+	// and offline.
+	StreamEventDeviceHealthChanged = "device.health_changed"
+)
+
+// StreamEventTypes lists the event types the dashboard stream sends.
+var StreamEventTypes = []string{
+	WebhookEventCampaignScheduled,
+	WebhookEventCampaignActivated,
+	WebhookEventCampaignPaused,
+	WebhookEventCampaignCompleted,
+	WebhookEventCreativeUploaded,
+	WebhookEventSyncCompleted,
+	StreamEventSyncProgress,
+	StreamEventDeviceHealthChanged,
+}
+
+// DeviceHealthChange is the data of a device.health_changed event.
+type DeviceHealthChange struct {
+	HostName string             `json:"host_name"`
+	From     DeviceHealthStatus `json:"from"`
+	To       DeviceHealthStatus `json:"to"`
+	LastSeen *time.Time         `json:"last_seen,omitempty"`
+}
@@ -0,0 +1,25 @@
+package models
+
+// GeoJSON types (RFC 7946) used by the map dashboard exports.
+
+type GeoJSONFeatureCollection struct {
+	Type     string           `json:"type"`
+	Features []GeoJSONFeature `json:"features"`
+}
+
+type GeoJSONFeature struct {
+	Type       string           `json:"type"`
+	ID         any              `json:"id,omitempty"`
+	Geometry   *GeoJSONGeometry `json:"geometry"`
+	Properties map[string]any   `json:"properties"`
+}
+
+type GeoJSONGeometry struct {
+	Type        string    `json:"type"`
+	Coordinates []float64 `json:"coordinates"`
+}
+
+// NewGeoJSONPoint builds a Point geometry. GeoJSON orders coordinates as [longitude, latitude].
+func NewGeoJSONPoint(longitude, latitude float64) *GeoJSONGeometry {
+	return &GeoJSONGeometry{Type: "Point", Coordinates: []float64{longitude, latitude}}
+}
@@ -34,6 +34,10 @@ type Project struct {
 	// Local fields
 	CreatedAt time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+
+	// Annotations is the local metadata kept apart from the console record; nil
+	// until it is first annotated.
+	Annotations *Annotations `json:"annotations,omitempty" db:"-"`
 }
 
 type Owner struct {
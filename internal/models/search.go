@@ -0,0 +1,31 @@
+package models
+
+// Search result types, also accepted by the types filter of GET /search.
+const (
+	SearchTypeAdvertiser = "advertiser"
+	SearchTypeCampaign   = "campaign"
+	SearchTypeCreative   = "creative"
+	SearchTypeDevice     = "device"
+	SearchTypeProject    = "project"
+	SearchTypeVenue      = "venue"
+)
+
+// SearchTypes lists every searchable type.
+var SearchTypes = []string{
+	SearchTypeAdvertiser,
+	SearchTypeCampaign,
+	SearchTypeCreative,
+	SearchTypeDevice,
+	SearchTypeProject,
+	SearchTypeVenue,
+}
+
+// SearchResult is one match of GET /search. ID is the record's ID as text, so it can be
+// used with the type's own endpoints; Rank is the trigram word similarity (0-1).
+type SearchResult struct {
+	Type     string  `json:"type"`
+	ID       string  `json:"id"`
+	Title    string  `json:"title"`
+	Subtitle string  `json:"subtitle,omitempty"`
+	Rank     float64 `json:"rank"`
+}
@@ -0,0 +1,49 @@
+package models
+
+import "time"
+
+// Sync run modes, change actions and entity types.
+const (
+	SyncModeFull        = "full"
+	SyncModeIncremental = "incremental"
+
+	SyncActionCreated = "created"
+	SyncActionUpdated = "updated"
+
+	SyncEntityDevice  = "device"
+	SyncEntityProject = "project"
+)
+
+// SyncRun is one run of POST /sync/console.
+type SyncRun struct {
+	ID             int64      `json:"id"`
+	Mode           string     `json:"mode"`
+	StartedAt      time.Time  `json:"started_at"`
+	FinishedAt     *time.Time `json:"finished_at,omitempty"`
+	ProjectsSynced int        `json:"projects_synced"`
+	DevicesSynced  int        `json:"devices_synced"`
+	Changes        int        `json:"changes"`
+	Errors         []string   `json:"errors"`
+	// HighWaterMark is the latest device last_synced_at a run without errors saw.
+	HighWaterMark *time.Time `json:"high_water_mark,omitempty"`
+}
+
+// FieldChange is one changed field; nested object fields are named by their dotted
+// path, e.g. "region.code" or "device_config.city".
+type FieldChange struct {
+	Field string `json:"field"`
+	From  any    `json:"from"`
+	To    any    `json:"to"`
+}
+
+// SyncChange records how a sync run changed one device or project. EntityKey is the
+// host name of a device or the name of a project.
+type SyncChange struct {
+	ID         int64         `json:"id"`
+	RunID      int64         `json:"run_id"`
+	OccurredAt time.Time     `json:"occurred_at"`
+	EntityType string        `json:"entity_type"`
+	EntityKey  string        `json:"entity_key"`
+	Action     string        `json:"action"`
+	Changes    []FieldChange `json:"changes"`
+}
@@ -3,10 +3,67 @@ package models
 import "time"
 
 type Venue struct {
-	ID        int       `json:"id" db:"id"`
-	Name      string    `json:"name" db:"name"`
-	CreatedAt time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+	ID          int             `json:"id" db:"id"`
+	Name        string          `json:"name" db:"name"`
+	SmartFilter *DeviceSelector `json:"smart_filter,omitempty" db:"smart_filter"` // smart venues follow this filter after every sync
+	CreatedAt   time.Time       `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at" db:"updated_at"`
+	Version     int             `json:"version" db:"version"`
+}
+
+// ReplaceVenueRequest is the editable representation of a venue: the body of PUT and
+// the document PATCH merge patches are applied to. Omitting smart_filter makes the
+// venue a plain one again.
+type ReplaceVenueRequest struct {
+	Name        string          `json:"name"`
+	SmartFilter *DeviceSelector `json:"smart_filter,omitempty"`
+}
+
+func NewReplaceVenueRequest(v *Venue) ReplaceVenueRequest {
+	return ReplaceVenueRequest{Name: v.Name, SmartFilter: v.SmartFilter}
+}
+
+// DeviceSelector picks devices by the same criteria as the device list filters,
+// plus a host-name glob where "*" matches any run of characters and "?" one character.
+type DeviceSelector struct {
+	ProjectID    *int    `json:"project_id,omitempty"`
+	City         *string `json:"city,omitempty"`
+	Region       *string `json:"region,omitempty"`
+	DeviceType   *string `json:"device_type,omitempty"`
+	HostNameGlob *string `json:"host_name_glob,omitempty"`
+}
+
+func (s DeviceSelector) IsEmpty() bool {
+	return s.ProjectID == nil && s.City == nil && s.Region == nil && s.DeviceType == nil && s.HostNameGlob == nil
+}
+
+const (
+	VenueAssignAdd     = "add"
+	VenueAssignRemove  = "remove"
+	VenueAssignReplace = "replace"
+)
+
+type AssignVenueDevicesRequest struct {
+	Filter DeviceSelector `json:"filter"`
+	Mode   string         `json:"mode,omitempty"` // add (default), remove or replace
+	DryRun bool           `json:"dry_run"`
+}
+
+type VenueDeviceRef struct {
+	ID       int    `json:"id"`
+	HostName string `json:"host_name"`
+}
+
+// VenueAssignmentResult describes the membership change of a filter-based assignment.
+// With DryRun set, nothing was written.
+type VenueAssignmentResult struct {
+	VenueID  int              `json:"venue_id"`
+	Mode     string           `json:"mode"`
+	DryRun   bool             `json:"dry_run"`
+	Matched  int              `json:"matched"`
+	Added    []VenueDeviceRef `json:"added"`
+	Removed  []VenueDeviceRef `json:"removed"`
+	Retained int              `json:"retained"`
 }
 
 // VenueWithDevices includes the associated devices information
@@ -0,0 +1,94 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Webhook event types subscribers can register for. creative.approved and
+// device.retired are accepted for subscriptions, but nothing raises them until
+// creatives have an approval step and devices can be retired.
+const (
+	WebhookEventCampaignScheduled = "campaign.scheduled"
+	WebhookEventCampaignActivated = "campaign.activated"
+	WebhookEventCampaignPaused    = "campaign.paused"
+	WebhookEventCampaignCompleted = "campaign.completed"
+	WebhookEventCreativeUploaded  = "creative.uploaded"
+	WebhookEventCreativeApproved  = "creative.approved"
+	WebhookEventSyncCompleted     = "sync.completed"
+	WebhookEventDeviceRetired     = "device.retired"
+
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliveryDelivered = "delivered"
+	WebhookDeliveryDead      = "dead"
+)
+
+// WebhookEventTypes lists every event type, in the order the API documents them.
+var WebhookEventTypes = []string{
+	WebhookEventCampaignScheduled,
+	WebhookEventCampaignActivated,
+	WebhookEventCampaignPaused,
+	WebhookEventCampaignCompleted,
+	WebhookEventCreativeUploaded,
+	WebhookEventCreativeApproved,
+	WebhookEventSyncCompleted,
+	WebhookEventDeviceRetired,
+}
+
+// WebhookSubscription is a URL that receives the events it subscribes to, signed with
+// its secret.
+type WebhookSubscription struct {
+	ID         int       `json:"id"`
+	URL        string    `json:"url"`
+	Secret     string    `json:"-"`
+	EventTypes []string  `json:"event_types"`
+	Enabled    bool      `json:"enabled"`
+	CreatedBy  string    `json:"created_by"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+type CreateWebhookSubscriptionRequest struct {
+	URL        string   `json:"url" validate:"required,url"`
+	EventTypes []string `json:"event_types" validate:"required,min=1,dive,oneof=campaign.scheduled campaign.activated campaign.paused campaign.completed creative.uploaded creative.approved sync.completed device.retired"`
+	// Secret signs the payloads; one is generated when it is left out.
+	Secret  *string `json:"secret,omitempty" validate:"omitempty,min=16"`
+	Enabled *bool   `json:"enabled,omitempty"`
+}
+
+type UpdateWebhookSubscriptionRequest struct {
+	URL        *string   `json:"url,omitempty" validate:"omitempty,url"`
+	EventTypes *[]string `json:"event_types,omitempty" validate:"omitempty,min=1,dive,oneof=campaign.scheduled campaign.activated campaign.paused campaign.completed creative.uploaded creative.approved sync.completed device.retired"`
+	Secret     *string   `json:"secret,omitempty" validate:"omitempty,min=16"`
+	Enabled    *bool     `json:"enabled,omitempty"`
+}
+
+// WebhookEvent is the JSON body POSTed to subscribers.
+type WebhookEvent struct {
+	ID         string          `json:"id"`
+	Type       string          `json:"type"`
+	OccurredAt time.Time       `json:"occurred_at"`
+	Data       json.RawMessage `json:"data" swaggertype:"object"`
+}
+
+// WebhookDelivery is an event queued for one subscriber. A pending delivery is retried
+// with backoff until it succeeds or runs out of attempts and is dead.
+type WebhookDelivery struct {
+	ID             int64           `json:"id"`
+	SubscriptionID int             `json:"subscription_id"`
+	EventID        string          `json:"event_id"`
+	EventType      string          `json:"event_type"`
+	Payload        json.RawMessage `json:"payload" swaggertype:"object"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	NextAttemptAt  time.Time       `json:"next_attempt_at"`
+	LastAttemptAt  *time.Time      `json:"last_attempt_at,omitempty"`
+	LastStatusCode *int            `json:"last_status_code,omitempty"`
+	LastError      *string         `json:"last_error,omitempty"`
+	DeliveredAt    *time.Time      `json:"delivered_at,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+
+	// URL and Secret are the subscription's, filled in for the dispatcher.
+	URL    string `json:"-"`
+	Secret string `json:"-"`
+}
@@ -4,26 +4,33 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
-	"log"
+	"log/slog"
+	"slices"
 	"strings"
+	"time"
 
+	"github.com/lib/pq"
 	"scm/internal/interfaces"
 	"scm/internal/models"
+	"scm/internal/tracing"
 )
 
 type advertiserRepository struct {
-	db *sql.DB
+	db DBTX
 }
 
-func NewAdvertiserRepository(db *sql.DB) interfaces.AdvertiserRepository {
+func NewAdvertiserRepository(db DBTX) interfaces.AdvertiserRepository {
 	return &advertiserRepository{db: db}
 }
 
 func (r *advertiserRepository) Create(ctx context.Context, advertiser *models.Advertiser) error {
+	ctx, span := tracing.Repository(ctx, "advertiserRepository.Create")
+	defer span.End()
+
 	query := `
 		INSERT INTO advertisers (name, email, created_by)
-		VALUES ($1, $2, $3)
-		RETURNING id, created_by, created_at, updated_at
+		VALUES ($1, NULLIF($2, ''), $3)
+		RETURNING id, created_by, created_at, updated_at, version
 	`
 
 	var createdBy sql.NullString
@@ -39,6 +46,7 @@ func (r *advertiserRepository) Create(ctx context.Context, advertiser *models.Ad
 		&createdBy,
 		&advertiser.CreatedAt,
 		&advertiser.UpdatedAt,
+		&advertiser.Version,
 	)
 	if createdBy.Valid {
 		advertiser.CreatedBy = createdBy.String
@@ -47,7 +55,7 @@ func (r *advertiserRepository) Create(ctx context.Context, advertiser *models.Ad
 	}
 
 	if err != nil {
-		log.Printf("Error creating advertiser: %v", err)
+		slog.ErrorContext(ctx, "error creating advertiser", "error", err)
 		return fmt.Errorf("failed to create advertiser: %w", err)
 	}
 
@@ -55,10 +63,13 @@ func (r *advertiserRepository) Create(ctx context.Context, advertiser *models.Ad
 }
 
 func (r *advertiserRepository) GetByID(ctx context.Context, id string) (*models.Advertiser, error) {
+	ctx, span := tracing.Repository(ctx, "advertiserRepository.GetByID")
+	defer span.End()
+
 	query := `
-		SELECT id, name, email, created_by, created_at, updated_at
+		SELECT id, name, COALESCE(email, ''), created_by, created_at, updated_at, deleted_at, version
 		FROM advertisers
-		WHERE id = $1
+		WHERE id = $1 AND deleted_at IS NULL
 	`
 
 	var advertiser models.Advertiser
@@ -70,6 +81,8 @@ func (r *advertiserRepository) GetByID(ctx context.Context, id string) (*models.
 		&createdBy,
 		&advertiser.CreatedAt,
 		&advertiser.UpdatedAt,
+		&advertiser.DeletedAt,
+		&advertiser.Version,
 	)
 	if createdBy.Valid {
 		advertiser.CreatedBy = createdBy.String
@@ -81,35 +94,40 @@ func (r *advertiserRepository) GetByID(ctx context.Context, id string) (*models.
 		if err == sql.ErrNoRows {
 			return nil, sql.ErrNoRows
 		}
-		log.Printf("Error getting advertiser: %v", err)
+		slog.ErrorContext(ctx, "error getting advertiser", "error", err)
 		return nil, fmt.Errorf("failed to get advertiser: %w", err)
 	}
 
 	return &advertiser, nil
 }
 
-func (r *advertiserRepository) List(ctx context.Context, limit int, offset int) ([]models.Advertiser, error) {
+// AdvertiserSort lists the fields advertiser lists can be sorted by.
+var AdvertiserSort = interfaces.Sortable{
+	Columns: map[string]string{
+		"id":         "id",
+		"name":       "name",
+		"created_at": "created_at",
+		"updated_at": "updated_at",
+	},
+	Default: "name",
+}
+
+func (r *advertiserRepository) List(ctx context.Context, page interfaces.ListPage, includeDeleted bool) ([]models.Advertiser, error) {
+	ctx, span := tracing.Repository(ctx, "advertiserRepository.List")
+	defer span.End()
+
 	query := `
-		SELECT id, name, email, created_by, created_at, updated_at
+		SELECT id, name, COALESCE(email, ''), created_by, created_at, updated_at, deleted_at, version
 		FROM advertisers
-		ORDER BY name
 	`
-
-	args := make([]any, 0, 2)
-	argPos := 1
-	if limit > 0 {
-		query += fmt.Sprintf(" LIMIT $%d", argPos)
-		args = append(args, limit)
-		argPos++
-	}
-	if offset > 0 {
-		query += fmt.Sprintf(" OFFSET $%d", argPos)
-		args = append(args, offset)
+	if !includeDeleted {
+		query += " WHERE deleted_at IS NULL"
 	}
+	query, args := appendListPage(query, nil, !includeDeleted, page, AdvertiserSort)
 
 	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
-		log.Printf("Error listing advertisers: %v", err)
+		slog.ErrorContext(ctx, "error listing advertisers", "error", err)
 		return nil, fmt.Errorf("failed to list advertisers: %w", err)
 	}
 	defer rows.Close()
@@ -125,8 +143,10 @@ func (r *advertiserRepository) List(ctx context.Context, limit int, offset int)
 			&createdBy,
 			&adv.CreatedAt,
 			&adv.UpdatedAt,
+			&adv.DeletedAt,
+			&adv.Version,
 		); err != nil {
-			log.Printf("Error scanning advertiser: %v", err)
+			slog.ErrorContext(ctx, "error scanning advertiser", "error", err)
 			return nil, fmt.Errorf("failed to scan advertiser: %w", err)
 		}
 		if createdBy.Valid {
@@ -138,24 +158,36 @@ func (r *advertiserRepository) List(ctx context.Context, limit int, offset int)
 	}
 
 	if err = rows.Err(); err != nil {
-		log.Printf("Error iterating advertisers: %v", err)
+		slog.ErrorContext(ctx, "error iterating advertisers", "error", err)
 		return nil, fmt.Errorf("error iterating advertisers: %w", err)
 	}
 
+	if page.Backward() {
+		slices.Reverse(advertisers)
+	}
 	return advertisers, nil
 }
 
-func (r *advertiserRepository) Count(ctx context.Context) (int, error) {
+func (r *advertiserRepository) Count(ctx context.Context, includeDeleted bool) (int, error) {
+	ctx, span := tracing.Repository(ctx, "advertiserRepository.Count")
+	defer span.End()
+
 	query := `SELECT COUNT(*) FROM advertisers`
+	if !includeDeleted {
+		query += " WHERE deleted_at IS NULL"
+	}
 	var total int
 	if err := r.db.QueryRowContext(ctx, query).Scan(&total); err != nil {
-		log.Printf("Error counting advertisers: %v", err)
+		slog.ErrorContext(ctx, "error counting advertisers", "error", err)
 		return 0, fmt.Errorf("failed to count advertisers: %w", err)
 	}
 	return total, nil
 }
 
-func (r *advertiserRepository) Update(ctx context.Context, id string, req *models.UpdateAdvertiserRequest) error {
+func (r *advertiserRepository) Update(ctx context.Context, id string, req *models.UpdateAdvertiserRequest, version int) error {
+	ctx, span := tracing.Repository(ctx, "advertiserRepository.Update")
+	defer span.End()
+
 	setValues := []string{}
 	args := []interface{}{}
 	argId := 1
@@ -167,7 +199,8 @@ func (r *advertiserRepository) Update(ctx context.Context, id string, req *model
 	}
 
 	if req.Email != nil {
-		setValues = append(setValues, fmt.Sprintf("email = $%d", argId))
+		// An empty email clears it.
+		setValues = append(setValues, fmt.Sprintf("email = NULLIF($%d, '')", argId))
 		args = append(args, *req.Email)
 		argId++
 	}
@@ -179,38 +212,46 @@ func (r *advertiserRepository) Update(ctx context.Context, id string, req *model
 	// Add updated_at
 	setValues = append(setValues, "updated_at = NOW() AT TIME ZONE 'UTC'")
 
-	// Add ID to args
-	args = append(args, id)
+	// Add ID and expected version to args
+	args = append(args, id, version)
 
 	query := fmt.Sprintf(
-		"UPDATE advertisers SET %s WHERE id = $%d",
+		"UPDATE advertisers SET %s WHERE id = $%d AND deleted_at IS NULL AND ($%d = 0 OR version = $%d)",
 		strings.Join(setValues, ", "),
 		argId,
+		argId+1,
+		argId+1,
 	)
 
 	result, err := r.db.ExecContext(ctx, query, args...)
 	if err != nil {
-		log.Printf("Error updating advertiser: %v", err)
+		slog.ErrorContext(ctx, "error updating advertiser", "error", err)
 		return fmt.Errorf("failed to update advertiser: %w", err)
 	}
 
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
-		log.Printf("Error getting rows affected: %v", err)
+		slog.ErrorContext(ctx, "error getting rows affected", "error", err)
 		return fmt.Errorf("failed to update advertiser: %w", err)
 	}
 
 	if rowsAffected == 0 {
-		return fmt.Errorf("advertiser not found")
+		return missedUpdateError(ctx, r.db,
+			`SELECT EXISTS (SELECT 1 FROM advertisers WHERE id = $1 AND deleted_at IS NULL)`, id,
+			fmt.Errorf("advertiser not found"))
 	}
 
 	return nil
 }
 
+// Delete soft-deletes an advertiser. It is blocked while the advertiser has live campaigns.
 func (r *advertiserRepository) Delete(ctx context.Context, id string) error {
+	ctx, span := tracing.Repository(ctx, "advertiserRepository.Delete")
+	defer span.End()
+
 	var campaignCount int64
-	if err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM campaigns WHERE advertiser_id = $1`, id).Scan(&campaignCount); err != nil {
-		log.Printf("Error checking advertiser references: %v", err)
+	if err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM campaigns WHERE advertiser_id = $1 AND deleted_at IS NULL`, id).Scan(&campaignCount); err != nil {
+		slog.ErrorContext(ctx, "error checking advertiser references", "error", err)
 		return fmt.Errorf("failed to delete advertiser: %w", err)
 	}
 	if campaignCount > 0 {
@@ -222,17 +263,17 @@ func (r *advertiserRepository) Delete(ctx context.Context, id string) error {
 		}
 	}
 
-	query := `DELETE FROM advertisers WHERE id = $1`
+	query := `UPDATE advertisers SET deleted_at = NOW() AT TIME ZONE 'UTC' WHERE id = $1 AND deleted_at IS NULL`
 
 	result, err := r.db.ExecContext(ctx, query, id)
 	if err != nil {
-		log.Printf("Error deleting advertiser: %v", err)
+		slog.ErrorContext(ctx, "error deleting advertiser", "error", err)
 		return fmt.Errorf("failed to delete advertiser: %w", err)
 	}
 
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
-		log.Printf("Error getting rows affected: %v", err)
+		slog.ErrorContext(ctx, "error getting rows affected", "error", err)
 		return fmt.Errorf("failed to delete advertiser: %w", err)
 	}
 
@@ -242,3 +283,132 @@ func (r *advertiserRepository) Delete(ctx context.Context, id string) error {
 
 	return nil
 }
+
+// Restore undeletes a soft-deleted advertiser. It returns sql.ErrNoRows when no
+// deleted advertiser has that ID.
+func (r *advertiserRepository) Restore(ctx context.Context, id string) error {
+	ctx, span := tracing.Repository(ctx, "advertiserRepository.Restore")
+	defer span.End()
+
+	query := `UPDATE advertisers SET deleted_at = NULL WHERE id = $1 AND deleted_at IS NOT NULL`
+
+	result, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		slog.ErrorContext(ctx, "error restoring advertiser", "error", err)
+		return fmt.Errorf("failed to restore advertiser: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		slog.ErrorContext(ctx, "error getting rows affected", "error", err)
+		return fmt.Errorf("failed to restore advertiser: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// PurgeDeletedBefore permanently removes advertisers soft-deleted before cutoff, together
+// with their (already deleted) campaigns and creatives, in one transaction. It returns the
+// S3 keys of the creatives' files, which the caller deletes.
+func (r *advertiserRepository) PurgeDeletedBefore(ctx context.Context, cutoff time.Time) (int64, []string, error) {
+	ctx, span := tracing.Repository(ctx, "advertiserRepository.PurgeDeletedBefore")
+	defer span.End()
+
+	tx, err := beginTx(ctx, r.db)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	ids, err := lockDeletedBefore(ctx, tx, "advertisers", cutoff)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to lock purged advertisers: %w", err)
+	}
+	if len(ids) == 0 {
+		return 0, nil, nil
+	}
+
+	_, keys, err := deleteCreatives(ctx, tx,
+		`cr.campaign_id IN (SELECT id FROM campaigns WHERE advertiser_id = ANY($1::uuid[]))`, pq.Array(ids))
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to purge creatives: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM campaigns WHERE advertiser_id = ANY($1::uuid[])`, pq.Array(ids)); err != nil {
+		return 0, nil, fmt.Errorf("failed to purge campaigns: %w", err)
+	}
+	result, err := tx.ExecContext(ctx, `DELETE FROM advertisers WHERE id = ANY($1::uuid[])`, pq.Array(ids))
+	if err != nil {
+		slog.ErrorContext(ctx, "error purging advertisers", "error", err)
+		return 0, nil, fmt.Errorf("failed to purge advertisers: %w", err)
+	}
+	purged, err := result.RowsAffected()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, nil, fmt.Errorf("failed to commit purge: %w", err)
+	}
+	return purged, keys, nil
+}
+
+// Dependencies lists the campaigns, creatives and stored objects (including soft-deleted
+// ones) that a cascade delete of the advertiser would remove.
+func (r *advertiserRepository) Dependencies(ctx context.Context, id string) (*models.DeletionDependencies, error) {
+	ctx, span := tracing.Repository(ctx, "advertiserRepository.Dependencies")
+	defer span.End()
+
+	var exists bool
+	if err := r.db.QueryRowContext(ctx, `SELECT true FROM advertisers WHERE id = $1`, id).Scan(&exists); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, sql.ErrNoRows
+		}
+		return nil, fmt.Errorf("failed to get advertiser: %w", err)
+	}
+	return advertiserDependencies(ctx, r.db, id)
+}
+
+// DeleteCascade permanently deletes the advertiser with its campaigns and creatives in one
+// transaction and returns what was removed. Stored objects are left for the caller to delete.
+func (r *advertiserRepository) DeleteCascade(ctx context.Context, id string) (*models.DeletionDependencies, error) {
+	ctx, span := tracing.Repository(ctx, "advertiserRepository.DeleteCascade")
+	defer span.End()
+
+	tx, err := beginTx(ctx, r.db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var locked string
+	if err := tx.QueryRowContext(ctx, `SELECT id FROM advertisers WHERE id = $1 FOR UPDATE`, id).Scan(&locked); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, sql.ErrNoRows
+		}
+		return nil, fmt.Errorf("failed to lock advertiser: %w", err)
+	}
+
+	deps, err := advertiserDependencies(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM creatives WHERE campaign_id IN (SELECT id FROM campaigns WHERE advertiser_id = $1)`, id); err != nil {
+		return nil, fmt.Errorf("failed to delete creatives: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM campaigns WHERE advertiser_id = $1`, id); err != nil {
+		return nil, fmt.Errorf("failed to delete campaigns: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM advertisers WHERE id = $1`, id); err != nil {
+		return nil, fmt.Errorf("failed to delete advertiser: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit cascade delete: %w", err)
+	}
+	return deps, nil
+}
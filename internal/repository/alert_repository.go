@@ -0,0 +1,431 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/lib/pq"
+	"scm/internal/models"
+	"scm/internal/tracing"
+)
+
+type AlertFilters struct {
+	Status  *string
+	RuleID  *int
+	Type    *string
+	VenueID *int
+}
+
+type AlertRepository interface {
+	CreateRule(ctx context.Context, rule *models.AlertRule) error
+	GetRule(ctx context.Context, id int) (*models.AlertRule, error)
+	ListRules(ctx context.Context, ruleType *string, limit int, offset int) ([]*models.AlertRule, error)
+	CountRules(ctx context.Context, ruleType *string) (int, error)
+	ListEnabledRules(ctx context.Context, ruleType string) ([]*models.AlertRule, error)
+	UpdateRule(ctx context.Context, rule *models.AlertRule) error
+	DeleteRule(ctx context.Context, id int) error
+
+	// Trigger opens an alert, or bumps the unresolved alert with the same dedup key.
+	// It reports whether a new alert was opened.
+	Trigger(ctx context.Context, alert *models.Alert) (bool, error)
+	// ResolveInactive resolves the rule's unresolved alerts whose dedup key is not in activeKeys.
+	ResolveInactive(ctx context.Context, ruleID int, activeKeys []string) (int64, error)
+	MarkNotified(ctx context.Context, id int) error
+	// ListUnnotified lists open alerts whose notification has not been delivered yet,
+	// oldest first.
+	ListUnnotified(ctx context.Context, limit int) ([]*models.Alert, error)
+	GetAlert(ctx context.Context, id int) (*models.Alert, error)
+	ListAlerts(ctx context.Context, filters AlertFilters, limit int, offset int) ([]*models.Alert, error)
+	CountAlerts(ctx context.Context, filters AlertFilters) (int, error)
+	Acknowledge(ctx context.Context, id int, by string) (*models.Alert, error)
+}
+
+type alertRepository struct {
+	db DBTX
+}
+
+func NewAlertRepository(db DBTX) AlertRepository {
+	return &alertRepository{db: db}
+}
+
+const alertRuleColumns = `id, name, type, venue_id, offline_devices, offline_minutes, email_recipients,
+	webhook_url, webhook_secret, enabled, created_by, created_at, updated_at`
+
+func scanAlertRule(row interface{ Scan(dest ...any) error }) (*models.AlertRule, error) {
+	var rule models.AlertRule
+	var recipients pq.StringArray
+	if err := row.Scan(
+		&rule.ID, &rule.Name, &rule.Type, &rule.VenueID, &rule.OfflineDevices, &rule.OfflineMinutes, &recipients,
+		&rule.WebhookURL, &rule.WebhookSecret, &rule.Enabled, &rule.CreatedBy, &rule.CreatedAt, &rule.UpdatedAt,
+	); err != nil {
+		return nil, err
+	}
+	rule.EmailRecipients = []string(recipients)
+	return &rule, nil
+}
+
+func (r *alertRepository) CreateRule(ctx context.Context, rule *models.AlertRule) error {
+	ctx, span := tracing.Repository(ctx, "alertRepository.CreateRule")
+	defer span.End()
+
+	query := `
+		INSERT INTO alert_rules (
+			name, type, venue_id, offline_devices, offline_minutes, email_recipients,
+			webhook_url, webhook_secret, enabled, created_by
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING id, created_at, updated_at
+	`
+
+	err := r.db.QueryRowContext(ctx, query,
+		rule.Name, rule.Type, rule.VenueID, rule.OfflineDevices, rule.OfflineMinutes, pq.Array(rule.EmailRecipients),
+		rule.WebhookURL, rule.WebhookSecret, rule.Enabled, rule.CreatedBy,
+	).Scan(&rule.ID, &rule.CreatedAt, &rule.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("create alert rule: %w", err)
+	}
+	return nil
+}
+
+func (r *alertRepository) GetRule(ctx context.Context, id int) (*models.AlertRule, error) {
+	ctx, span := tracing.Repository(ctx, "alertRepository.GetRule")
+	defer span.End()
+
+	query := "SELECT " + alertRuleColumns + " FROM alert_rules WHERE id = $1"
+
+	rule, err := scanAlertRule(r.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("alert rule not found")
+		}
+		return nil, fmt.Errorf("get alert rule: %w", err)
+	}
+	return rule, nil
+}
+
+func (r *alertRepository) ListRules(ctx context.Context, ruleType *string, limit int, offset int) ([]*models.AlertRule, error) {
+	ctx, span := tracing.Repository(ctx, "alertRepository.ListRules")
+	defer span.End()
+
+	query := "SELECT " + alertRuleColumns + " FROM alert_rules WHERE 1=1"
+	var args []any
+	argIndex := 1
+
+	if ruleType != nil {
+		query += fmt.Sprintf(" AND type = $%d", argIndex)
+		args = append(args, *ruleType)
+		argIndex++
+	}
+
+	query += fmt.Sprintf(" ORDER BY id LIMIT $%d OFFSET $%d", argIndex, argIndex+1)
+	args = append(args, limit, offset)
+
+	return r.queryRules(ctx, query, args...)
+}
+
+func (r *alertRepository) CountRules(ctx context.Context, ruleType *string) (int, error) {
+	ctx, span := tracing.Repository(ctx, "alertRepository.CountRules")
+	defer span.End()
+
+	query := "SELECT COUNT(*) FROM alert_rules WHERE 1=1"
+	var args []any
+	if ruleType != nil {
+		query += " AND type = $1"
+		args = append(args, *ruleType)
+	}
+
+	var count int
+	if err := r.db.QueryRowContext(ctx, query, args...).Scan(&count); err != nil {
+		return 0, fmt.Errorf("count alert rules: %w", err)
+	}
+	return count, nil
+}
+
+func (r *alertRepository) ListEnabledRules(ctx context.Context, ruleType string) ([]*models.AlertRule, error) {
+	ctx, span := tracing.Repository(ctx, "alertRepository.ListEnabledRules")
+	defer span.End()
+
+	query := "SELECT " + alertRuleColumns + " FROM alert_rules WHERE enabled = TRUE AND type = $1 ORDER BY id"
+	return r.queryRules(ctx, query, ruleType)
+}
+
+func (r *alertRepository) queryRules(ctx context.Context, query string, args ...any) ([]*models.AlertRule, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list alert rules: %w", err)
+	}
+	defer rows.Close()
+
+	rules := []*models.AlertRule{}
+	for rows.Next() {
+		rule, err := scanAlertRule(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan alert rule: %w", err)
+		}
+		rules = append(rules, rule)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate alert rules: %w", err)
+	}
+	return rules, nil
+}
+
+func (r *alertRepository) UpdateRule(ctx context.Context, rule *models.AlertRule) error {
+	ctx, span := tracing.Repository(ctx, "alertRepository.UpdateRule")
+	defer span.End()
+
+	query := `
+		UPDATE alert_rules SET
+			name = $2, venue_id = $3, offline_devices = $4, offline_minutes = $5, email_recipients = $6,
+			webhook_url = $7, webhook_secret = $8, enabled = $9
+		WHERE id = $1
+		RETURNING updated_at
+	`
+
+	err := r.db.QueryRowContext(ctx, query,
+		rule.ID, rule.Name, rule.VenueID, rule.OfflineDevices, rule.OfflineMinutes, pq.Array(rule.EmailRecipients),
+		rule.WebhookURL, rule.WebhookSecret, rule.Enabled,
+	).Scan(&rule.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("alert rule not found")
+		}
+		return fmt.Errorf("update alert rule: %w", err)
+	}
+	return nil
+}
+
+func (r *alertRepository) DeleteRule(ctx context.Context, id int) error {
+	ctx, span := tracing.Repository(ctx, "alertRepository.DeleteRule")
+	defer span.End()
+
+	result, err := r.db.ExecContext(ctx, "DELETE FROM alert_rules WHERE id = $1", id)
+	if err != nil {
+		return fmt.Errorf("delete alert rule: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return fmt.Errorf("alert rule not found")
+	}
+	return nil
+}
+
+const alertColumns = `a.id, a.rule_id, ar.type, a.dedup_key, a.status, a.title, a.details, a.venue_id, a.trigger_count,
+	a.first_triggered_at, a.last_triggered_at, a.acknowledged_at, a.acknowledged_by, a.resolved_at, a.notified_at`
+
+func scanAlert(row interface{ Scan(dest ...any) error }) (*models.Alert, error) {
+	var alert models.Alert
+	var details []byte
+	if err := row.Scan(
+		&alert.ID, &alert.RuleID, &alert.RuleType, &alert.DedupKey, &alert.Status, &alert.Title, &details, &alert.VenueID, &alert.TriggerCount,
+		&alert.FirstTriggeredAt, &alert.LastTriggeredAt, &alert.AcknowledgedAt, &alert.AcknowledgedBy, &alert.ResolvedAt, &alert.NotifiedAt,
+	); err != nil {
+		return nil, err
+	}
+	alert.Details = details
+	return &alert, nil
+}
+
+func (r *alertRepository) Trigger(ctx context.Context, alert *models.Alert) (bool, error) {
+	ctx, span := tracing.Repository(ctx, "alertRepository.Trigger")
+	defer span.End()
+
+	details := alert.Details
+	if len(details) == 0 {
+		details = []byte("{}")
+	}
+
+	query := `
+		INSERT INTO alerts (rule_id, dedup_key, title, details, venue_id)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (dedup_key) WHERE status <> 'resolved' DO UPDATE SET
+			title = EXCLUDED.title,
+			details = EXCLUDED.details,
+			trigger_count = alerts.trigger_count + 1,
+			last_triggered_at = NOW()
+		RETURNING id, status, trigger_count, first_triggered_at, last_triggered_at, notified_at, (xmax = 0) AS inserted
+	`
+
+	var inserted bool
+	err := r.db.QueryRowContext(ctx, query, alert.RuleID, alert.DedupKey, alert.Title, details, alert.VenueID).Scan(
+		&alert.ID, &alert.Status, &alert.TriggerCount, &alert.FirstTriggeredAt, &alert.LastTriggeredAt, &alert.NotifiedAt, &inserted,
+	)
+	if err != nil {
+		return false, fmt.Errorf("trigger alert: %w", err)
+	}
+	alert.Details = details
+	return inserted, nil
+}
+
+func (r *alertRepository) ResolveInactive(ctx context.Context, ruleID int, activeKeys []string) (int64, error) {
+	ctx, span := tracing.Repository(ctx, "alertRepository.ResolveInactive")
+	defer span.End()
+
+	if activeKeys == nil {
+		activeKeys = []string{}
+	}
+	query := `
+		UPDATE alerts SET status = 'resolved', resolved_at = NOW()
+		WHERE rule_id = $1 AND status <> 'resolved' AND NOT (dedup_key = ANY($2))
+	`
+	result, err := r.db.ExecContext(ctx, query, ruleID, pq.Array(activeKeys))
+	if err != nil {
+		return 0, fmt.Errorf("resolve alerts: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+func (r *alertRepository) MarkNotified(ctx context.Context, id int) error {
+	ctx, span := tracing.Repository(ctx, "alertRepository.MarkNotified")
+	defer span.End()
+
+	if _, err := r.db.ExecContext(ctx, "UPDATE alerts SET notified_at = NOW() WHERE id = $1", id); err != nil {
+		return fmt.Errorf("mark alert notified: %w", err)
+	}
+	return nil
+}
+
+func (r *alertRepository) ListUnnotified(ctx context.Context, limit int) ([]*models.Alert, error) {
+	ctx, span := tracing.Repository(ctx, "alertRepository.ListUnnotified")
+	defer span.End()
+
+	query := "SELECT " + alertColumns + ` FROM alerts a JOIN alert_rules ar ON ar.id = a.rule_id
+		WHERE a.status = 'open' AND a.notified_at IS NULL
+		ORDER BY a.first_triggered_at, a.id
+		LIMIT $1`
+
+	rows, err := r.db.QueryContext(ctx, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list unnotified alerts: %w", err)
+	}
+	defer rows.Close()
+
+	alerts := []*models.Alert{}
+	for rows.Next() {
+		alert, err := scanAlert(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan alert: %w", err)
+		}
+		alerts = append(alerts, alert)
+	}
+	return alerts, rows.Err()
+}
+
+func (r *alertRepository) GetAlert(ctx context.Context, id int) (*models.Alert, error) {
+	ctx, span := tracing.Repository(ctx, "alertRepository.GetAlert")
+	defer span.End()
+
+	query := "SELECT " + alertColumns + " FROM alerts a JOIN alert_rules ar ON ar.id = a.rule_id WHERE a.id = $1"
+
+	alert, err := scanAlert(r.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("alert not found")
+		}
+		return nil, fmt.Errorf("get alert: %w", err)
+	}
+	return alert, nil
+}
+
+func buildAlertFilterClause(filters AlertFilters) (string, []any, int) {
+	var clause string
+	var args []any
+	argIndex := 1
+
+	if filters.Status != nil {
+		clause += fmt.Sprintf(" AND a.status = $%d", argIndex)
+		args = append(args, *filters.Status)
+		argIndex++
+	}
+	if filters.RuleID != nil {
+		clause += fmt.Sprintf(" AND a.rule_id = $%d", argIndex)
+		args = append(args, *filters.RuleID)
+		argIndex++
+	}
+	if filters.Type != nil {
+		clause += fmt.Sprintf(" AND ar.type = $%d", argIndex)
+		args = append(args, *filters.Type)
+		argIndex++
+	}
+	if filters.VenueID != nil {
+		clause += fmt.Sprintf(" AND a.venue_id = $%d", argIndex)
+		args = append(args, *filters.VenueID)
+		argIndex++
+	}
+
+	return clause, args, argIndex
+}
+
+func (r *alertRepository) ListAlerts(ctx context.Context, filters AlertFilters, limit int, offset int) ([]*models.Alert, error) {
+	ctx, span := tracing.Repository(ctx, "alertRepository.ListAlerts")
+	defer span.End()
+
+	clause, args, argIndex := buildAlertFilterClause(filters)
+	query := "SELECT " + alertColumns + " FROM alerts a JOIN alert_rules ar ON ar.id = a.rule_id WHERE 1=1" + clause
+	query += fmt.Sprintf(" ORDER BY a.last_triggered_at DESC, a.id DESC LIMIT $%d OFFSET $%d", argIndex, argIndex+1)
+	args = append(args, limit, offset)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list alerts: %w", err)
+	}
+	defer rows.Close()
+
+	alerts := []*models.Alert{}
+	for rows.Next() {
+		alert, err := scanAlert(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan alert: %w", err)
+		}
+		alerts = append(alerts, alert)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate alerts: %w", err)
+	}
+	return alerts, nil
+}
+
+func (r *alertRepository) CountAlerts(ctx context.Context, filters AlertFilters) (int, error) {
+	ctx, span := tracing.Repository(ctx, "alertRepository.CountAlerts")
+	defer span.End()
+
+	clause, args, _ := buildAlertFilterClause(filters)
+	query := "SELECT COUNT(*) FROM alerts a JOIN alert_rules ar ON ar.id = a.rule_id WHERE 1=1" + clause
+
+	var count int
+	if err := r.db.QueryRowContext(ctx, query, args...).Scan(&count); err != nil {
+		return 0, fmt.Errorf("count alerts: %w", err)
+	}
+	return count, nil
+}
+
+// Acknowledge marks an open alert as acknowledged. It returns "alert not found" or
+// "alert is not open" when the alert cannot be acknowledged.
+func (r *alertRepository) Acknowledge(ctx context.Context, id int, by string) (*models.Alert, error) {
+	ctx, span := tracing.Repository(ctx, "alertRepository.Acknowledge")
+	defer span.End()
+
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE alerts SET status = 'acknowledged', acknowledged_at = NOW(), acknowledged_by = $2
+		WHERE id = $1 AND status = 'open'
+	`, id, by)
+	if err != nil {
+		return nil, fmt.Errorf("acknowledge alert: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("get rows affected: %w", err)
+	}
+
+	alert, err := r.GetAlert(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if rows == 0 {
+		return nil, fmt.Errorf("alert is not open")
+	}
+	return alert, nil
+}
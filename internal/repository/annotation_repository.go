@@ -0,0 +1,208 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"scm/internal/models"
+	"scm/internal/tracing"
+)
+
+// AnnotationRepository stores the local annotations of devices (by host name) and
+// projects (by name). A record that was never annotated has empty annotations at
+// version 1; its first update creates the row at version 2.
+type AnnotationRepository interface {
+	GetDeviceAnnotations(ctx context.Context, hostName string) (*models.Annotations, error)
+	UpdateDeviceAnnotations(ctx context.Context, hostName string, annotations *models.Annotations, version int) error
+	GetProjectAnnotations(ctx context.Context, name string) (*models.Annotations, error)
+	UpdateProjectAnnotations(ctx context.Context, name string, annotations *models.Annotations, version int) error
+}
+
+// AnnotationFilters match devices or projects by their annotations. Every tag and every
+// attribute must be present.
+type AnnotationFilters struct {
+	Tags          []string
+	InternalOwner *string
+	Attributes    map[string]string
+}
+
+// HasAny reports whether at least one filter is set.
+func (f AnnotationFilters) HasAny() bool {
+	return len(f.Tags) > 0 || f.InternalOwner != nil || len(f.Attributes) > 0
+}
+
+// annotationTable describes where one kind of record keeps its annotations.
+type annotationTable struct {
+	table    string // annotation table
+	key      string // its column holding the record's business key
+	exists   string // condition on $1 that holds while the annotated record exists
+	notFound string
+}
+
+var (
+	deviceAnnotations = annotationTable{
+		table:    "device_annotations",
+		key:      "host_name",
+		exists:   "EXISTS (SELECT 1 FROM devices WHERE host_name = $1)",
+		notFound: "device not found",
+	}
+	projectAnnotations = annotationTable{
+		table:    "project_annotations",
+		key:      "project_name",
+		exists:   "EXISTS (SELECT 1 FROM projects WHERE name = $1)",
+		notFound: "project not found",
+	}
+)
+
+// filterClause returns an " AND EXISTS (...)" condition matching f against the annotation
+// row of the record whose business key is keyExpr, or "" when f is empty.
+func (t annotationTable) filterClause(keyExpr string, f AnnotationFilters, args []any, argIndex int) (string, []any, int) {
+	if !f.HasAny() {
+		return "", args, argIndex
+	}
+
+	clause := fmt.Sprintf(" AND EXISTS (SELECT 1 FROM %s a WHERE a.%s = %s", t.table, t.key, keyExpr)
+	if len(f.Tags) > 0 {
+		clause += fmt.Sprintf(" AND a.tags @> $%d::text[]", argIndex)
+		args = append(args, pq.Array(f.Tags))
+		argIndex++
+	}
+	if f.InternalOwner != nil {
+		clause += fmt.Sprintf(" AND a.internal_owner = $%d", argIndex)
+		args = append(args, *f.InternalOwner)
+		argIndex++
+	}
+	if len(f.Attributes) > 0 {
+		attrs, _ := json.Marshal(f.Attributes)
+		clause += fmt.Sprintf(" AND a.attributes @> $%d::jsonb", argIndex)
+		args = append(args, string(attrs))
+		argIndex++
+	}
+	return clause + ")", args, argIndex
+}
+
+// scanAnnotations decodes the annotations column of a device or project query: the
+// record's annotation row as JSON, or NULL.
+func scanAnnotations(raw []byte) (*models.Annotations, error) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	var a models.Annotations
+	if err := json.Unmarshal(raw, &a); err != nil {
+		return nil, fmt.Errorf("unmarshal annotations: %w", err)
+	}
+	return &a, nil
+}
+
+type annotationRepository struct {
+	db DBTX
+}
+
+func NewAnnotationRepository(db DBTX) AnnotationRepository {
+	return &annotationRepository{db: db}
+}
+
+func (r *annotationRepository) GetDeviceAnnotations(ctx context.Context, hostName string) (*models.Annotations, error) {
+	ctx, span := tracing.Repository(ctx, "annotationRepository.GetDeviceAnnotations")
+	defer span.End()
+
+	return r.get(ctx, deviceAnnotations, hostName)
+}
+
+func (r *annotationRepository) UpdateDeviceAnnotations(ctx context.Context, hostName string, annotations *models.Annotations, version int) error {
+	ctx, span := tracing.Repository(ctx, "annotationRepository.UpdateDeviceAnnotations")
+	defer span.End()
+
+	return r.update(ctx, deviceAnnotations, hostName, annotations, version)
+}
+
+func (r *annotationRepository) GetProjectAnnotations(ctx context.Context, name string) (*models.Annotations, error) {
+	ctx, span := tracing.Repository(ctx, "annotationRepository.GetProjectAnnotations")
+	defer span.End()
+
+	return r.get(ctx, projectAnnotations, name)
+}
+
+func (r *annotationRepository) UpdateProjectAnnotations(ctx context.Context, name string, annotations *models.Annotations, version int) error {
+	ctx, span := tracing.Repository(ctx, "annotationRepository.UpdateProjectAnnotations")
+	defer span.End()
+
+	return r.update(ctx, projectAnnotations, name, annotations, version)
+}
+
+func (r *annotationRepository) get(ctx context.Context, t annotationTable, key string) (*models.Annotations, error) {
+	query := fmt.Sprintf(`
+		SELECT tags, notes, internal_owner, attributes, version, updated_at
+		FROM %s
+		WHERE %s = $1
+	`, t.table, t.key)
+
+	var a models.Annotations
+	var attrsJSON []byte
+	var updatedAt time.Time
+	err := r.db.QueryRowContext(ctx, query, key).Scan(
+		pq.Array(&a.Tags), &a.Notes, &a.InternalOwner, &attrsJSON, &a.Version, &updatedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		var exists bool
+		if err := r.db.QueryRowContext(ctx, "SELECT "+t.exists, key).Scan(&exists); err != nil {
+			return nil, fmt.Errorf("get annotations: %w", err)
+		}
+		if !exists {
+			return nil, errors.New(t.notFound)
+		}
+		return &models.Annotations{Tags: []string{}, Attributes: map[string]string{}, Version: 1}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get annotations: %w", err)
+	}
+
+	if err := json.Unmarshal(attrsJSON, &a.Attributes); err != nil {
+		return nil, fmt.Errorf("unmarshal attributes: %w", err)
+	}
+	a.UpdatedAt = &updatedAt
+	return &a, nil
+}
+
+// update replaces the annotations if their version is still version (0 skips the
+// check). The first update of a record inserts its row, which only matches version 1.
+func (r *annotationRepository) update(ctx context.Context, t annotationTable, key string, annotations *models.Annotations, version int) error {
+	attrsJSON, err := json.Marshal(annotations.Attributes)
+	if err != nil {
+		return fmt.Errorf("marshal attributes: %w", err)
+	}
+	args := []any{key, pq.Array(annotations.Tags), annotations.Notes, annotations.InternalOwner, string(attrsJSON), version}
+
+	var updatedAt time.Time
+	query := fmt.Sprintf(`
+		UPDATE %s
+		SET tags = $2::text[], notes = $3, internal_owner = $4, attributes = $5::jsonb
+		WHERE %s = $1 AND ($6 = 0 OR version = $6)
+		RETURNING version, updated_at
+	`, t.table, t.key)
+	err = r.db.QueryRowContext(ctx, query, args...).Scan(&annotations.Version, &updatedAt)
+	if errors.Is(err, sql.ErrNoRows) && (version == 0 || version == 1) {
+		query = fmt.Sprintf(`
+			INSERT INTO %s (%s, tags, notes, internal_owner, attributes, version)
+			SELECT $1, $2::text[], $3, $4, $5::jsonb, 2
+			WHERE $6::int IN (0, 1) AND %s
+			ON CONFLICT (%s) DO NOTHING
+			RETURNING version, updated_at
+		`, t.table, t.key, t.exists, t.key)
+		err = r.db.QueryRowContext(ctx, query, args...).Scan(&annotations.Version, &updatedAt)
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return missedUpdateError(ctx, r.db, "SELECT "+t.exists, key, errors.New(t.notFound))
+	}
+	if err != nil {
+		return fmt.Errorf("update annotations: %w", err)
+	}
+
+	annotations.UpdatedAt = &updatedAt
+	return nil
+}
@@ -0,0 +1,143 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"scm/internal/models"
+	"scm/internal/tracing"
+)
+
+type AuditFilters struct {
+	ActorID    *string
+	Action     *string
+	EntityType *string
+	EntityID   *string
+	RequestID  *string
+	From       *time.Time
+	To         *time.Time
+}
+
+type AuditRepository interface {
+	Record(ctx context.Context, event *models.AuditEvent) error
+	List(ctx context.Context, filters AuditFilters, limit int, offset int) ([]*models.AuditEvent, error)
+	Count(ctx context.Context, filters AuditFilters) (int, error)
+}
+
+type auditRepository struct {
+	db DBTX
+}
+
+func NewAuditRepository(db DBTX) AuditRepository {
+	return &auditRepository{db: db}
+}
+
+func (r *auditRepository) Record(ctx context.Context, event *models.AuditEvent) error {
+	ctx, span := tracing.Repository(ctx, "auditRepository.Record")
+	defer span.End()
+
+	query := `
+		INSERT INTO audit_events (
+			actor_id, actor_email, action, entity_type, entity_id, diff, request_id, ip, method, path
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING id, occurred_at
+	`
+
+	var diff any
+	if len(event.Diff) > 0 {
+		diff = []byte(event.Diff)
+	}
+	err := r.db.QueryRowContext(ctx, query,
+		event.ActorID, event.ActorEmail, event.Action, event.EntityType, event.EntityID, diff,
+		event.RequestID, event.IP, event.Method, event.Path,
+	).Scan(&event.ID, &event.OccurredAt)
+	if err != nil {
+		return fmt.Errorf("record audit event: %w", err)
+	}
+	return nil
+}
+
+func buildAuditFilterClause(filters AuditFilters) (string, []any, int) {
+	var clause string
+	var args []any
+	argIndex := 1
+
+	add := func(cond string, v any) {
+		clause += fmt.Sprintf(" AND "+cond, argIndex)
+		args = append(args, v)
+		argIndex++
+	}
+
+	if filters.ActorID != nil {
+		add("actor_id = $%d", *filters.ActorID)
+	}
+	if filters.Action != nil {
+		add("action = $%d", *filters.Action)
+	}
+	if filters.EntityType != nil {
+		add("entity_type = $%d", *filters.EntityType)
+	}
+	if filters.EntityID != nil {
+		add("entity_id = $%d", *filters.EntityID)
+	}
+	if filters.RequestID != nil {
+		add("request_id = $%d", *filters.RequestID)
+	}
+	if filters.From != nil {
+		add("occurred_at >= $%d", *filters.From)
+	}
+	if filters.To != nil {
+		add("occurred_at < $%d", *filters.To)
+	}
+
+	return clause, args, argIndex
+}
+
+func (r *auditRepository) List(ctx context.Context, filters AuditFilters, limit int, offset int) ([]*models.AuditEvent, error) {
+	ctx, span := tracing.Repository(ctx, "auditRepository.List")
+	defer span.End()
+
+	clause, args, argIndex := buildAuditFilterClause(filters)
+	query := `SELECT id, occurred_at, actor_id, actor_email, action, entity_type, entity_id, diff,
+		request_id, ip, method, path FROM audit_events WHERE 1=1` + clause
+	query += fmt.Sprintf(" ORDER BY occurred_at DESC, id DESC LIMIT $%d OFFSET $%d", argIndex, argIndex+1)
+	args = append(args, limit, offset)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list audit events: %w", err)
+	}
+	defer rows.Close()
+
+	events := []*models.AuditEvent{}
+	for rows.Next() {
+		var event models.AuditEvent
+		var diff []byte
+		if err := rows.Scan(
+			&event.ID, &event.OccurredAt, &event.ActorID, &event.ActorEmail, &event.Action, &event.EntityType, &event.EntityID, &diff,
+			&event.RequestID, &event.IP, &event.Method, &event.Path,
+		); err != nil {
+			return nil, fmt.Errorf("scan audit event: %w", err)
+		}
+		event.Diff = diff
+		events = append(events, &event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate audit events: %w", err)
+	}
+	return events, nil
+}
+
+func (r *auditRepository) Count(ctx context.Context, filters AuditFilters) (int, error) {
+	ctx, span := tracing.Repository(ctx, "auditRepository.Count")
+	defer span.End()
+
+	clause, args, _ := buildAuditFilterClause(filters)
+
+	var count int
+	if err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM audit_events WHERE 1=1"+clause, args...).Scan(&count); err != nil {
+		return 0, fmt.Errorf("count audit events: %w", err)
+	}
+	return count, nil
+}
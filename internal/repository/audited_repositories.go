@@ -0,0 +1,306 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"strconv"
+
+	"scm/internal/audit"
+	"scm/internal/interfaces"
+	"scm/internal/models"
+)
+
+// The audited repositories wrap a repository and record an audit event with a
+// before/after diff for every successful mutation. Reads pass straight through.
+
+// auditReadFailed logs err, a failed read of an entity's before or after state, and
+// reports whether there was one. The entity event is then skipped rather than recorded
+// with a partial diff; audit.Middleware records a generic event for the request instead.
+func auditReadFailed(ctx context.Context, action string, entityType string, entityID string, err error) bool {
+	if err == nil {
+		return false
+	}
+	slog.ErrorContext(ctx, "audit: read entity state failed", "action", action, "entity_type", entityType, "entity_id", entityID, "error", err)
+	return true
+}
+
+// cascadeReadError drops sql.ErrNoRows from the read of a cascade-deleted record's state:
+// soft-deleted records can be cascade deleted, and have no live state to read.
+func cascadeReadError(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	return err
+}
+
+type auditedAdvertiserRepository struct {
+	interfaces.AdvertiserRepository
+	audit AuditRepository
+}
+
+func NewAuditedAdvertiserRepository(inner interfaces.AdvertiserRepository, auditRepo AuditRepository) interfaces.AdvertiserRepository {
+	return &auditedAdvertiserRepository{AdvertiserRepository: inner, audit: auditRepo}
+}
+
+func (r *auditedAdvertiserRepository) Create(ctx context.Context, advertiser *models.Advertiser) error {
+	if err := r.AdvertiserRepository.Create(ctx, advertiser); err != nil {
+		return err
+	}
+	audit.Record(ctx, r.audit, "create", "advertiser", advertiser.ID, nil, advertiser)
+	return nil
+}
+
+func (r *auditedAdvertiserRepository) Update(ctx context.Context, id string, req *models.UpdateAdvertiserRequest, version int) error {
+	before, beforeErr := r.AdvertiserRepository.GetByID(ctx, id)
+	if err := r.AdvertiserRepository.Update(ctx, id, req, version); err != nil {
+		return err
+	}
+	after, afterErr := r.AdvertiserRepository.GetByID(ctx, id)
+	if auditReadFailed(ctx, "update", "advertiser", id, errors.Join(beforeErr, afterErr)) {
+		return nil
+	}
+	audit.Record(ctx, r.audit, "update", "advertiser", id, before, after)
+	return nil
+}
+
+func (r *auditedAdvertiserRepository) Delete(ctx context.Context, id string) error {
+	before, beforeErr := r.AdvertiserRepository.GetByID(ctx, id)
+	if err := r.AdvertiserRepository.Delete(ctx, id); err != nil {
+		return err
+	}
+	if auditReadFailed(ctx, "delete", "advertiser", id, beforeErr) {
+		return nil
+	}
+	audit.Record(ctx, r.audit, "delete", "advertiser", id, before, nil)
+	return nil
+}
+
+func (r *auditedAdvertiserRepository) Restore(ctx context.Context, id string) error {
+	if err := r.AdvertiserRepository.Restore(ctx, id); err != nil {
+		return err
+	}
+	after, err := r.AdvertiserRepository.GetByID(ctx, id)
+	if auditReadFailed(ctx, "restore", "advertiser", id, err) {
+		return nil
+	}
+	audit.Record(ctx, r.audit, "restore", "advertiser", id, nil, after)
+	return nil
+}
+
+func (r *auditedAdvertiserRepository) DeleteCascade(ctx context.Context, id string) (*models.DeletionDependencies, error) {
+	before, beforeErr := r.AdvertiserRepository.GetByID(ctx, id)
+	deps, err := r.AdvertiserRepository.DeleteCascade(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if auditReadFailed(ctx, "cascade_delete", "advertiser", id, cascadeReadError(beforeErr)) {
+		return deps, nil
+	}
+	audit.Record(ctx, r.audit, "cascade_delete", "advertiser", id, map[string]any{"advertiser": before, "dependencies": deps}, nil)
+	return deps, nil
+}
+
+type auditedCampaignRepository struct {
+	interfaces.CampaignRepository
+	audit AuditRepository
+}
+
+func NewAuditedCampaignRepository(inner interfaces.CampaignRepository, auditRepo AuditRepository) interfaces.CampaignRepository {
+	return &auditedCampaignRepository{CampaignRepository: inner, audit: auditRepo}
+}
+
+func (r *auditedCampaignRepository) Create(ctx context.Context, campaign *models.Campaign) error {
+	if err := r.CampaignRepository.Create(ctx, campaign); err != nil {
+		return err
+	}
+	audit.Record(ctx, r.audit, "create", "campaign", campaign.ID, nil, campaign)
+	return nil
+}
+
+func (r *auditedCampaignRepository) Update(ctx context.Context, id string, campaign *models.Campaign, version int) error {
+	before, beforeErr := r.CampaignRepository.GetByID(ctx, id)
+	if err := r.CampaignRepository.Update(ctx, id, campaign, version); err != nil {
+		return err
+	}
+	after, afterErr := r.CampaignRepository.GetByID(ctx, id)
+	if auditReadFailed(ctx, "update", "campaign", id, errors.Join(beforeErr, afterErr)) {
+		return nil
+	}
+	audit.Record(ctx, r.audit, "update", "campaign", id, before, after)
+	return nil
+}
+
+func (r *auditedCampaignRepository) Delete(ctx context.Context, id string) error {
+	before, beforeErr := r.CampaignRepository.GetByID(ctx, id)
+	if err := r.CampaignRepository.Delete(ctx, id); err != nil {
+		return err
+	}
+	if auditReadFailed(ctx, "delete", "campaign", id, beforeErr) {
+		return nil
+	}
+	audit.Record(ctx, r.audit, "delete", "campaign", id, before, nil)
+	return nil
+}
+
+func (r *auditedCampaignRepository) Restore(ctx context.Context, id string) error {
+	if err := r.CampaignRepository.Restore(ctx, id); err != nil {
+		return err
+	}
+	after, err := r.CampaignRepository.GetByID(ctx, id)
+	if auditReadFailed(ctx, "restore", "campaign", id, err) {
+		return nil
+	}
+	audit.Record(ctx, r.audit, "restore", "campaign", id, nil, after)
+	return nil
+}
+
+func (r *auditedCampaignRepository) DeleteCascade(ctx context.Context, id string) (*models.DeletionDependencies, error) {
+	before, beforeErr := r.CampaignRepository.GetByID(ctx, id)
+	deps, err := r.CampaignRepository.DeleteCascade(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if auditReadFailed(ctx, "cascade_delete", "campaign", id, cascadeReadError(beforeErr)) {
+		return deps, nil
+	}
+	audit.Record(ctx, r.audit, "cascade_delete", "campaign", id, map[string]any{"campaign": before, "dependencies": deps}, nil)
+	return deps, nil
+}
+
+type auditedCreativeRepository struct {
+	CreativeRepository
+	audit AuditRepository
+}
+
+func NewAuditedCreativeRepository(inner CreativeRepository, auditRepo AuditRepository) CreativeRepository {
+	return &auditedCreativeRepository{CreativeRepository: inner, audit: auditRepo}
+}
+
+func (r *auditedCreativeRepository) Create(ctx context.Context, creative *models.Creative) error {
+	if err := r.CreativeRepository.Create(ctx, creative); err != nil {
+		return err
+	}
+	audit.Record(ctx, r.audit, "create", "creative", creative.ID, nil, creative)
+	return nil
+}
+
+func (r *auditedCreativeRepository) Update(ctx context.Context, id string, req *models.UpdateCreativeRequest, version int) error {
+	before, beforeErr := r.CreativeRepository.GetByID(ctx, id)
+	if err := r.CreativeRepository.Update(ctx, id, req, version); err != nil {
+		return err
+	}
+	after, afterErr := r.CreativeRepository.GetByID(ctx, id)
+	if auditReadFailed(ctx, "update", "creative", id, errors.Join(beforeErr, afterErr)) {
+		return nil
+	}
+	audit.Record(ctx, r.audit, "update", "creative", id, before, after)
+	return nil
+}
+
+func (r *auditedCreativeRepository) Delete(ctx context.Context, id string) error {
+	before, beforeErr := r.CreativeRepository.GetByID(ctx, id)
+	if err := r.CreativeRepository.Delete(ctx, id); err != nil {
+		return err
+	}
+	if auditReadFailed(ctx, "delete", "creative", id, beforeErr) {
+		return nil
+	}
+	audit.Record(ctx, r.audit, "delete", "creative", id, before, nil)
+	return nil
+}
+
+func (r *auditedCreativeRepository) Restore(ctx context.Context, id string) error {
+	if err := r.CreativeRepository.Restore(ctx, id); err != nil {
+		return err
+	}
+	after, err := r.CreativeRepository.GetByID(ctx, id)
+	if auditReadFailed(ctx, "restore", "creative", id, err) {
+		return nil
+	}
+	audit.Record(ctx, r.audit, "restore", "creative", id, nil, after)
+	return nil
+}
+
+type auditedVenueRepository struct {
+	VenueRepository
+	audit AuditRepository
+}
+
+func NewAuditedVenueRepository(inner VenueRepository, auditRepo AuditRepository) VenueRepository {
+	return &auditedVenueRepository{VenueRepository: inner, audit: auditRepo}
+}
+
+func (r *auditedVenueRepository) Create(ctx context.Context, venue *models.Venue) error {
+	if err := r.VenueRepository.Create(ctx, venue); err != nil {
+		return err
+	}
+	audit.Record(ctx, r.audit, "create", "venue", strconv.Itoa(venue.ID), nil, venue)
+	return nil
+}
+
+func (r *auditedVenueRepository) Update(ctx context.Context, venue *models.Venue, version int) error {
+	before, beforeErr := r.VenueRepository.GetByID(ctx, venue.ID)
+	if err := r.VenueRepository.Update(ctx, venue, version); err != nil {
+		return err
+	}
+	after, afterErr := r.VenueRepository.GetByID(ctx, venue.ID)
+	if auditReadFailed(ctx, "update", "venue", strconv.Itoa(venue.ID), errors.Join(beforeErr, afterErr)) {
+		return nil
+	}
+	audit.Record(ctx, r.audit, "update", "venue", strconv.Itoa(venue.ID), before, after)
+	return nil
+}
+
+func (r *auditedVenueRepository) Delete(ctx context.Context, id int) error {
+	before, beforeErr := r.VenueRepository.GetByID(ctx, id)
+	if err := r.VenueRepository.Delete(ctx, id); err != nil {
+		return err
+	}
+	if auditReadFailed(ctx, "delete", "venue", strconv.Itoa(id), beforeErr) {
+		return nil
+	}
+	audit.Record(ctx, r.audit, "delete", "venue", strconv.Itoa(id), before, nil)
+	return nil
+}
+
+func (r *auditedVenueRepository) DeleteCascade(ctx context.Context, id int) (*models.DeletionDependencies, error) {
+	before, beforeErr := r.VenueRepository.GetByID(ctx, id)
+	deps, err := r.VenueRepository.DeleteCascade(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if auditReadFailed(ctx, "cascade_delete", "venue", strconv.Itoa(id), beforeErr) {
+		return deps, nil
+	}
+	audit.Record(ctx, r.audit, "cascade_delete", "venue", strconv.Itoa(id), map[string]any{"venue": before, "dependencies": deps}, nil)
+	return deps, nil
+}
+
+func (r *auditedVenueRepository) AddDeviceToVenue(ctx context.Context, venueID, deviceID int) error {
+	if err := r.VenueRepository.AddDeviceToVenue(ctx, venueID, deviceID); err != nil {
+		return err
+	}
+	audit.Record(ctx, r.audit, "add_device", "venue", strconv.Itoa(venueID), nil, map[string]any{"device_id": deviceID})
+	return nil
+}
+
+func (r *auditedVenueRepository) RemoveDeviceFromVenue(ctx context.Context, venueID, deviceID int) error {
+	if err := r.VenueRepository.RemoveDeviceFromVenue(ctx, venueID, deviceID); err != nil {
+		return err
+	}
+	audit.Record(ctx, r.audit, "remove_device", "venue", strconv.Itoa(venueID), map[string]any{"device_id": deviceID}, nil)
+	return nil
+}
+
+func (r *auditedVenueRepository) AssignDevices(ctx context.Context, venueID int, filters DeviceFilters, mode string, dryRun bool) (*models.VenueAssignmentResult, error) {
+	result, err := r.VenueRepository.AssignDevices(ctx, venueID, filters, mode, dryRun)
+	if err != nil || dryRun {
+		return result, err
+	}
+	if len(result.Added) > 0 || len(result.Removed) > 0 {
+		audit.Record(ctx, r.audit, "assign_devices", "venue", strconv.Itoa(venueID), nil, result)
+	}
+	return result, nil
+}
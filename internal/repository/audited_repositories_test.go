@@ -0,0 +1,69 @@
+package repository_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"scm/internal/interfaces"
+	"scm/internal/models"
+	"scm/internal/repository"
+)
+
+type recordingAudit struct {
+	repository.AuditRepository
+	events []*models.AuditEvent
+}
+
+func (r *recordingAudit) Record(ctx context.Context, event *models.AuditEvent) error {
+	r.events = append(r.events, event)
+	return nil
+}
+
+// unreadableAdvertisers updates advertisers but cannot read them back.
+type unreadableAdvertisers struct {
+	interfaces.AdvertiserRepository
+	readErr error
+}
+
+func (r *unreadableAdvertisers) GetByID(ctx context.Context, id string) (*models.Advertiser, error) {
+	if r.readErr != nil {
+		return nil, r.readErr
+	}
+	return &models.Advertiser{ID: id, Name: "acme"}, nil
+}
+
+func (r *unreadableAdvertisers) Update(ctx context.Context, id string, req *models.UpdateAdvertiserRequest, version int) error {
+	return nil
+}
+
+func (r *unreadableAdvertisers) DeleteCascade(ctx context.Context, id string) (*models.DeletionDependencies, error) {
+	return &models.DeletionDependencies{Resource: "advertiser", ID: id}, nil
+}
+
+func TestAuditedRepositorySkipsEventsWithUnreadableState(t *testing.T) {
+	ctx := context.Background()
+	audit := &recordingAudit{}
+	repo := repository.NewAuditedAdvertiserRepository(&unreadableAdvertisers{readErr: errors.New("connection reset")}, audit)
+
+	if err := repo.Update(ctx, "a1", &models.UpdateAdvertiserRequest{}, 0); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if len(audit.events) != 0 {
+		t.Fatalf("recorded %d events with unreadable state, want none", len(audit.events))
+	}
+}
+
+func TestAuditedRepositoryRecordsCascadeOfSoftDeletedRecord(t *testing.T) {
+	ctx := context.Background()
+	audit := &recordingAudit{}
+	repo := repository.NewAuditedAdvertiserRepository(&unreadableAdvertisers{readErr: sql.ErrNoRows}, audit)
+
+	if _, err := repo.DeleteCascade(ctx, "a1"); err != nil {
+		t.Fatalf("DeleteCascade: %v", err)
+	}
+	if len(audit.events) != 1 || audit.events[0].Action != "cascade_delete" {
+		t.Fatalf("events = %+v, want one cascade_delete", audit.events)
+	}
+}
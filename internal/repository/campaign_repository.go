@@ -7,24 +7,28 @@ import (
     "fmt"
 	"time"
     "strings"
-	"log"
+	"log/slog"
 
     "github.com/lib/pq"
     "scm/internal/interfaces"
     "scm/internal/models"
+    "scm/internal/tracing"
 )
 
 type campaignRepository struct {
-    db *sql.DB
+    db DBTX
 }
 
 // Remove the CampaignFilter type from here since it's now in the interfaces package
 
-func NewCampaignRepository(db *sql.DB) interfaces.CampaignRepository {
+func NewCampaignRepository(db DBTX) interfaces.CampaignRepository {
     return &campaignRepository{db: db}
 }
 
 func (r *campaignRepository) Create(ctx context.Context, campaign *models.Campaign) error {
+    ctx, span := tracing.Repository(ctx, "campaignRepository.Create")
+    defer span.End()
+
     cities := campaign.Cities
     if cities == nil {
         cities = []string{}
@@ -35,7 +39,7 @@ func (r *campaignRepository) Create(ctx context.Context, campaign *models.Campai
             name, status, cities, start_date, end_date, budget, 
             spent, impressions, clicks, ctr, advertiser_id
         ) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
-        RETURNING id, created_at, updated_at
+        RETURNING id, created_at, updated_at, version
     `
     
     err := r.db.QueryRowContext(
@@ -52,19 +56,25 @@ func (r *campaignRepository) Create(ctx context.Context, campaign *models.Campai
         campaign.Clicks,
         campaign.CTR,
         campaign.AdvertiserID,
-    ).Scan(&campaign.ID, &campaign.CreatedAt, &campaign.UpdatedAt)
-    fmt.Println("Campaign created:", campaign)
-    return err
+    ).Scan(&campaign.ID, &campaign.CreatedAt, &campaign.UpdatedAt, &campaign.Version)
+    if err != nil {
+        return err
+    }
+    slog.DebugContext(ctx, "campaign created", "campaign_id", campaign.ID, "advertiser_id", campaign.AdvertiserID)
+    return nil
 }
 
 func (r *campaignRepository) GetByID(ctx context.Context, id string) (*models.Campaign, error) {
+    ctx, span := tracing.Repository(ctx, "campaignRepository.GetByID")
+    defer span.End()
+
     query := `
         SELECT 
             id, name, status, cities, start_date, end_date, budget,
-            spent, impressions, clicks, ctr, advertiser_id,
-            created_at, updated_at
+            COALESCE(spent, 0), COALESCE(impressions, 0), clicks, ctr, advertiser_id,
+            created_at, updated_at, deleted_at, version
         FROM campaigns 
-        WHERE id = $1
+        WHERE id = $1 AND deleted_at IS NULL
     `
     
     var campaign models.Campaign
@@ -83,14 +93,16 @@ func (r *campaignRepository) GetByID(ctx context.Context, id string) (*models.Ca
         &campaign.AdvertiserID,
         &campaign.CreatedAt,
         &campaign.UpdatedAt,
+        &campaign.DeletedAt,
+        &campaign.Version,
     )
     
     if err != nil {
-		log.Println("Error fetching campaign with ID:", id, "Error:", err)
         if errors.Is(err, sql.ErrNoRows) {
-			log.Println("Campaign not found with ID:", id)
+			slog.DebugContext(ctx, "campaign not found", "campaign_id", id)
             return nil, sql.ErrNoRows
         }
+		slog.ErrorContext(ctx, "error fetching campaign", "campaign_id", id, "error", err)
         return nil, err
     }
     
@@ -98,6 +110,9 @@ func (r *campaignRepository) GetByID(ctx context.Context, id string) (*models.Ca
 }
 
 func (r *campaignRepository) Summary(ctx context.Context, filter interfaces.CampaignFilter) (*models.CampaignSummary, error) {
+    ctx, span := tracing.Repository(ctx, "campaignRepository.Summary")
+    defer span.End()
+
     query := `
         SELECT
             COALESCE(SUM(CASE WHEN status = 'active' THEN 1 ELSE 0 END), 0) AS active_campaign_count,
@@ -111,6 +126,10 @@ func (r *campaignRepository) Summary(ctx context.Context, filter interfaces.Camp
     var whereClauses []string
     argPos := 1
 
+    if !filter.IncludeDeleted {
+        whereClauses = append(whereClauses, "deleted_at IS NULL")
+    }
+
     if filter.AdvertiserID != "" {
         whereClauses = append(whereClauses, fmt.Sprintf("advertiser_id = $%d", argPos))
         args = append(args, filter.AdvertiserID)
@@ -151,7 +170,10 @@ func (r *campaignRepository) Summary(ctx context.Context, filter interfaces.Camp
     return &summary, nil
 }
 
-func (r *campaignRepository) ActivateScheduledStartingOn(ctx context.Context, startDate time.Time, scheduledStatus string, timeZone string) (int64, error) {
+func (r *campaignRepository) ActivateScheduledStartingOn(ctx context.Context, startDate time.Time, scheduledStatus string, timeZone string) ([]string, error) {
+    ctx, span := tracing.Repository(ctx, "campaignRepository.ActivateScheduledStartingOn")
+    defer span.End()
+
     if scheduledStatus == "" {
         scheduledStatus = "scheduled"
     }
@@ -165,22 +187,18 @@ func (r *campaignRepository) ActivateScheduledStartingOn(ctx context.Context, st
         SET status = 'active',
             updated_at = NOW() AT TIME ZONE 'UTC'
         WHERE status = $1
+          AND deleted_at IS NULL
           AND DATE(start_date AT TIME ZONE $3) = DATE($2 AT TIME ZONE $3)
+        RETURNING id
     `
 
-    res, err := r.db.ExecContext(ctx, query, scheduledStatus, startDate, timeZone)
-    if err != nil {
-        return 0, err
-    }
-
-    rows, err := res.RowsAffected()
-    if err != nil {
-        return 0, err
-    }
-    return rows, nil
+    return r.queryIDs(ctx, query, scheduledStatus, startDate, timeZone)
 }
 
-func (r *campaignRepository) CompleteActiveEndedBefore(ctx context.Context, now time.Time, activeStatus string, completedStatus string, timeZone string) (int64, error) {
+func (r *campaignRepository) CompleteActiveEndedBefore(ctx context.Context, now time.Time, activeStatus string, completedStatus string, timeZone string) ([]string, error) {
+    ctx, span := tracing.Repository(ctx, "campaignRepository.CompleteActiveEndedBefore")
+    defer span.End()
+
     if activeStatus == "" {
         activeStatus = "active"
     }
@@ -198,22 +216,37 @@ func (r *campaignRepository) CompleteActiveEndedBefore(ctx context.Context, now
         SET status = $2,
             updated_at = NOW() AT TIME ZONE 'UTC'
         WHERE status = $1
+          AND deleted_at IS NULL
           AND DATE(end_date AT TIME ZONE $4) < DATE($3 AT TIME ZONE $4)
+        RETURNING id
     `
 
-    res, err := r.db.ExecContext(ctx, query, activeStatus, completedStatus, now, timeZone)
+    return r.queryIDs(ctx, query, activeStatus, completedStatus, now, timeZone)
+}
+
+// queryIDs runs an UPDATE ... RETURNING id and collects the IDs.
+func (r *campaignRepository) queryIDs(ctx context.Context, query string, args ...any) ([]string, error) {
+    rows, err := r.db.QueryContext(ctx, query, args...)
     if err != nil {
-        return 0, err
+        return nil, err
     }
+    defer rows.Close()
 
-    rows, err := res.RowsAffected()
-    if err != nil {
-        return 0, err
+    var ids []string
+    for rows.Next() {
+        var id string
+        if err := rows.Scan(&id); err != nil {
+            return nil, err
+        }
+        ids = append(ids, id)
     }
-    return rows, nil
+    return ids, rows.Err()
 }
 
 func (r *campaignRepository) Count(ctx context.Context, filter interfaces.CampaignFilter) (int, error) {
+    ctx, span := tracing.Repository(ctx, "campaignRepository.Count")
+    defer span.End()
+
     query := `
         SELECT COUNT(*)
         FROM campaigns
@@ -224,6 +257,10 @@ func (r *campaignRepository) Count(ctx context.Context, filter interfaces.Campai
     var whereClauses []string
     argPos := 1
 
+    if !filter.IncludeDeleted {
+        whereClauses = append(whereClauses, "deleted_at IS NULL")
+    }
+
     if filter.AdvertiserID != "" {
         whereClauses = append(whereClauses, fmt.Sprintf("advertiser_id = $%d", argPos))
         args = append(args, filter.AdvertiserID)
@@ -259,13 +296,33 @@ func (r *campaignRepository) Count(ctx context.Context, filter interfaces.Campai
     return total, nil
 }
 
+// CampaignSort lists the fields campaign lists can be sorted by.
+var CampaignSort = interfaces.Sortable{
+    Columns: map[string]string{
+        "id":          "id",
+        "name":        "name",
+        "status":      "status",
+        "start_date":  "start_date",
+        "end_date":    "end_date",
+        "budget":      "budget",
+        "spent":       "COALESCE(spent, 0)",
+        "impressions": "COALESCE(impressions, 0)",
+        "created_at":  "created_at",
+        "updated_at":  "updated_at",
+    },
+    Default: "-created_at",
+}
+
 // List retrieves a list of campaigns based on the provided filter
 func (r *campaignRepository) List(ctx context.Context, filter interfaces.CampaignFilter) ([]*models.Campaign, error) {
+    ctx, span := tracing.Repository(ctx, "campaignRepository.List")
+    defer span.End()
+
     query := `
         SELECT 
             id, name, status, cities, start_date, end_date, budget,
-            spent, impressions, clicks, ctr, advertiser_id,
-            created_at, updated_at
+            COALESCE(spent, 0), COALESCE(impressions, 0), clicks, ctr, advertiser_id,
+            created_at, updated_at, deleted_at, version
         FROM campaigns
         WHERE 1=1
     `
@@ -274,6 +331,10 @@ func (r *campaignRepository) List(ctx context.Context, filter interfaces.Campaig
     var whereClauses []string
     argPos := 1
 
+    if !filter.IncludeDeleted {
+        whereClauses = append(whereClauses, "deleted_at IS NULL")
+    }
+
     if filter.AdvertiserID != "" {
         whereClauses = append(whereClauses, fmt.Sprintf("advertiser_id = $%d", argPos))
         args = append(args, filter.AdvertiserID)
@@ -303,18 +364,7 @@ func (r *campaignRepository) List(ctx context.Context, filter interfaces.Campaig
     }
 
     // Add ordering and pagination
-    query += " ORDER BY created_at DESC"
-
-    if filter.Limit > 0 {
-        query += fmt.Sprintf(" LIMIT $%d", argPos)
-        args = append(args, filter.Limit)
-        argPos++
-    }
-
-    if filter.Offset > 0 {
-        query += fmt.Sprintf(" OFFSET $%d", argPos)
-        args = append(args, filter.Offset)
-    }
+    query, args = appendListPage(query, args, true, filter.Page, CampaignSort)
 
     rows, err := r.db.QueryContext(ctx, query, args...)
     if err != nil {
@@ -340,6 +390,8 @@ func (r *campaignRepository) List(ctx context.Context, filter interfaces.Campaig
             &campaign.AdvertiserID,
             &campaign.CreatedAt,
             &campaign.UpdatedAt,
+            &campaign.DeletedAt,
+            &campaign.Version,
         )
         if err != nil {
             return nil, err
@@ -347,11 +399,14 @@ func (r *campaignRepository) List(ctx context.Context, filter interfaces.Campaig
         campaigns = append(campaigns, &campaign)
     }
 
-    return campaigns, rows.Err()
+    return pageRows(campaigns, rows.Err(), filter.Page)
 }
 
 // Update updates a campaign with the given ID
-func (r *campaignRepository) Update(ctx context.Context, id string, campaign *models.Campaign) error {
+func (r *campaignRepository) Update(ctx context.Context, id string, campaign *models.Campaign, version int) error {
+    ctx, span := tracing.Repository(ctx, "campaignRepository.Update")
+    defer span.End()
+
     cities := campaign.Cities
     if cities == nil {
         cities = []string{}
@@ -371,8 +426,8 @@ func (r *campaignRepository) Update(ctx context.Context, id string, campaign *mo
             ctr = $10, 
             advertiser_id = $11,
             updated_at = NOW() AT TIME ZONE 'UTC'
-        WHERE id = $12
-        RETURNING updated_at
+        WHERE id = $12 AND deleted_at IS NULL AND ($13 = 0 OR version = $13)
+        RETURNING updated_at, version
     `
 
     err := r.db.QueryRowContext(
@@ -390,11 +445,14 @@ func (r *campaignRepository) Update(ctx context.Context, id string, campaign *mo
         campaign.CTR,
         campaign.AdvertiserID,
         id,
-    ).Scan(&campaign.UpdatedAt)
+        version,
+    ).Scan(&campaign.UpdatedAt, &campaign.Version)
 
     if err != nil {
         if err == sql.ErrNoRows {
-            return fmt.Errorf("campaign not found")
+            return missedUpdateError(ctx, r.db,
+                `SELECT EXISTS (SELECT 1 FROM campaigns WHERE id = $1 AND deleted_at IS NULL)`, id,
+                fmt.Errorf("campaign not found"))
         }
         return fmt.Errorf("failed to update campaign: %w", err)
     }
@@ -402,10 +460,13 @@ func (r *campaignRepository) Update(ctx context.Context, id string, campaign *mo
     return nil
 }
 
-// Delete removes a campaign by ID
+// Delete soft-deletes a campaign by ID. It is blocked while the campaign has live creatives.
 func (r *campaignRepository) Delete(ctx context.Context, id string) error {
+    ctx, span := tracing.Repository(ctx, "campaignRepository.Delete")
+    defer span.End()
+
     var creativeCount int64
-    if err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM creatives WHERE campaign_id = $1`, id).Scan(&creativeCount); err != nil {
+    if err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM creatives WHERE campaign_id = $1 AND deleted_at IS NULL`, id).Scan(&creativeCount); err != nil {
         return err
     }
     if creativeCount > 0 {
@@ -417,7 +478,44 @@ func (r *campaignRepository) Delete(ctx context.Context, id string) error {
         }
     }
 
-    result, err := r.db.ExecContext(ctx, "DELETE FROM campaigns WHERE id = $1", id)
+    result, err := r.db.ExecContext(ctx, "UPDATE campaigns SET deleted_at = NOW() AT TIME ZONE 'UTC' WHERE id = $1 AND deleted_at IS NULL", id)
+    if err != nil {
+        return err
+    }
+
+    rowsAffected, err := result.RowsAffected()
+    if err != nil {
+        return err
+    }
+
+    if rowsAffected == 0 {
+        return sql.ErrNoRows
+    }
+
+    return nil
+}
+
+// Restore undeletes a soft-deleted campaign. It returns sql.ErrNoRows when no deleted
+// campaign has that ID, and RestoreBlockedError while its advertiser is still deleted.
+func (r *campaignRepository) Restore(ctx context.Context, id string) error {
+    ctx, span := tracing.Repository(ctx, "campaignRepository.Restore")
+    defer span.End()
+
+    var advertiserDeleted bool
+    err := r.db.QueryRowContext(ctx, `
+        SELECT a.deleted_at IS NOT NULL
+        FROM campaigns c
+        JOIN advertisers a ON a.id = c.advertiser_id
+        WHERE c.id = $1 AND c.deleted_at IS NOT NULL
+    `, id).Scan(&advertiserDeleted)
+    if err != nil {
+        return err
+    }
+    if advertiserDeleted {
+        return &interfaces.RestoreBlockedError{Resource: "campaign", Parent: "advertiser"}
+    }
+
+    result, err := r.db.ExecContext(ctx, "UPDATE campaigns SET deleted_at = NULL WHERE id = $1 AND deleted_at IS NOT NULL", id)
     if err != nil {
         return err
     }
@@ -432,4 +530,92 @@ func (r *campaignRepository) Delete(ctx context.Context, id string) error {
     }
 
     return nil
-}
\ No newline at end of file
+}
+
+// PurgeDeletedBefore permanently removes campaigns soft-deleted before cutoff, together
+// with their (already deleted) creatives, in one transaction. It returns the S3 keys of
+// the creatives' files, which the caller deletes.
+func (r *campaignRepository) PurgeDeletedBefore(ctx context.Context, cutoff time.Time) (int64, []string, error) {
+    ctx, span := tracing.Repository(ctx, "campaignRepository.PurgeDeletedBefore")
+    defer span.End()
+
+    tx, err := beginTx(ctx, r.db)
+    if err != nil {
+        return 0, nil, err
+    }
+    defer tx.Rollback()
+
+    ids, err := lockDeletedBefore(ctx, tx, "campaigns", cutoff)
+    if err != nil {
+        return 0, nil, fmt.Errorf("failed to lock purged campaigns: %w", err)
+    }
+    if len(ids) == 0 {
+        return 0, nil, nil
+    }
+
+    _, keys, err := deleteCreatives(ctx, tx, "cr.campaign_id = ANY($1::uuid[])", pq.Array(ids))
+    if err != nil {
+        return 0, nil, fmt.Errorf("failed to purge creatives: %w", err)
+    }
+    result, err := tx.ExecContext(ctx, "DELETE FROM campaigns WHERE id = ANY($1::uuid[])", pq.Array(ids))
+    if err != nil {
+        return 0, nil, fmt.Errorf("failed to purge campaigns: %w", err)
+    }
+    purged, err := result.RowsAffected()
+    if err != nil {
+        return 0, nil, err
+    }
+
+    if err := tx.Commit(); err != nil {
+        return 0, nil, err
+    }
+    return purged, keys, nil
+}
+
+// Dependencies lists the creatives and stored objects (including soft-deleted ones) that a
+// cascade delete of the campaign would remove.
+func (r *campaignRepository) Dependencies(ctx context.Context, id string) (*models.DeletionDependencies, error) {
+    ctx, span := tracing.Repository(ctx, "campaignRepository.Dependencies")
+    defer span.End()
+
+    var exists bool
+    if err := r.db.QueryRowContext(ctx, "SELECT true FROM campaigns WHERE id = $1", id).Scan(&exists); err != nil {
+        return nil, err
+    }
+    return campaignDependencies(ctx, r.db, id)
+}
+
+// DeleteCascade permanently deletes the campaign and its creatives in one transaction and
+// returns what was removed. Stored objects are left for the caller to delete.
+func (r *campaignRepository) DeleteCascade(ctx context.Context, id string) (*models.DeletionDependencies, error) {
+    ctx, span := tracing.Repository(ctx, "campaignRepository.DeleteCascade")
+    defer span.End()
+
+    tx, err := beginTx(ctx, r.db)
+    if err != nil {
+        return nil, err
+    }
+    defer tx.Rollback()
+
+    var locked string
+    if err := tx.QueryRowContext(ctx, "SELECT id FROM campaigns WHERE id = $1 FOR UPDATE", id).Scan(&locked); err != nil {
+        return nil, err
+    }
+
+    deps, err := campaignDependencies(ctx, tx, id)
+    if err != nil {
+        return nil, err
+    }
+
+    if _, err := tx.ExecContext(ctx, "DELETE FROM creatives WHERE campaign_id = $1", id); err != nil {
+        return nil, fmt.Errorf("failed to delete creatives: %w", err)
+    }
+    if _, err := tx.ExecContext(ctx, "DELETE FROM campaigns WHERE id = $1", id); err != nil {
+        return nil, fmt.Errorf("failed to delete campaign: %w", err)
+    }
+
+    if err := tx.Commit(); err != nil {
+        return nil, err
+    }
+    return deps, nil
+}
@@ -0,0 +1,47 @@
+package repository_test
+
+import (
+	"database/sql"
+	"os"
+	"testing"
+
+	_ "github.com/lib/pq"
+	"scm/internal/db/migrations"
+	"scm/internal/repository"
+	"scm/internal/repository/repotest"
+)
+
+// TestConformance runs the repository conformance suite against the PostgreSQL database
+// at TEST_DATABASE_URL. The database is migrated and its tables are emptied before each
+// test, so it must not hold anything worth keeping.
+func TestConformance(t *testing.T) {
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	db, err := sql.Open("postgres", url)
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := migrations.RunMigrations(db); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	repotest.Run(t, func(t *testing.T) repotest.Repos {
+		_, err := db.Exec(`TRUNCATE users, advertisers, campaigns, creatives, projects, devices,
+			venues, venue_devices RESTART IDENTITY CASCADE`)
+		if err != nil {
+			t.Fatalf("truncate: %v", err)
+		}
+		return repotest.Repos{
+			Users:       repository.NewUserRepository(db),
+			Advertisers: repository.NewAdvertiserRepository(db),
+			Campaigns:   repository.NewCampaignRepository(db),
+			Creatives:   repository.NewCreativeRepository(db),
+			Projects:    repository.NewProjectRepository(db),
+			Devices:     repository.NewDeviceRepository(db),
+			Venues:      repository.NewVenueRepository(db),
+		}
+	})
+}
@@ -6,36 +6,56 @@ import (
 	"fmt"
 	"time"
 	"github.com/lib/pq"
+	"scm/internal/interfaces"
 	"scm/internal/models"
+	"scm/internal/tracing"
 )
 
 type CreativeRepository interface {
 	Create(ctx context.Context, creative *models.Creative) error
 	GetByID(ctx context.Context, id string) (*models.Creative, error)
-	ListAll(ctx context.Context, limit int, offset int) ([]*models.Creative, error)
-	CountAll(ctx context.Context) (int, error)
-	ListByCampaign(ctx context.Context, campaignID string, limit int, offset int) ([]*models.Creative, error)
-	CountByCampaign(ctx context.Context, campaignID string) (int, error)
-	ListByDevice(ctx context.Context, device string, activeNow bool, now time.Time, limit int, offset int) ([]*models.Creative, error)
+	ListAll(ctx context.Context, page interfaces.ListPage, includeDeleted bool) ([]*models.Creative, error)
+	CountAll(ctx context.Context, includeDeleted bool) (int, error)
+	ListByCampaign(ctx context.Context, campaignID string, page interfaces.ListPage, includeDeleted bool) ([]*models.Creative, error)
+	CountByCampaign(ctx context.Context, campaignID string, includeDeleted bool) (int, error)
+	ListByDevice(ctx context.Context, device string, activeNow bool, now time.Time, page interfaces.ListPage) ([]*models.Creative, error)
 	CountByDevice(ctx context.Context, device string, activeNow bool, now time.Time) (int, error)
-	Update(ctx context.Context, id string, req *models.UpdateCreativeRequest) error
+	// Update applies req if the creative is still at version (0 skips the check) and
+	// returns interfaces.ErrVersionConflict if it has changed since.
+	Update(ctx context.Context, id string, req *models.UpdateCreativeRequest, version int) error
+	// Delete soft-deletes the creative; Restore undoes it.
 	Delete(ctx context.Context, id string) error
+	Restore(ctx context.Context, id string) error
+	// PurgeDeletedBefore permanently removes creatives soft-deleted before cutoff and
+	// returns the S3 keys of their files, which the caller deletes.
+	PurgeDeletedBefore(ctx context.Context, cutoff time.Time) (int64, []string, error)
 }
 
 type creativeRepository struct {
-    db *sql.DB
+    db DBTX
 }
 
-func NewCreativeRepository(db *sql.DB) CreativeRepository {
+func NewCreativeRepository(db DBTX) CreativeRepository {
     return &creativeRepository{db: db}
 }
 
 func (r *creativeRepository) Create(ctx context.Context, creative *models.Creative) error {
+    ctx, span := tracing.Repository(ctx, "creativeRepository.Create")
+    defer span.End()
+
+    // The array columns are NOT NULL, and pq sends a nil slice as NULL.
+    orEmpty := func(v []string) []string {
+        if v == nil {
+            return []string{}
+        }
+        return v
+    }
+
     query := `
         INSERT INTO creatives (
             id, name, type, url, file_path, size, campaign_id, selected_days, time_slots, devices, uploaded_at
         ) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
-        RETURNING uploaded_at
+        RETURNING uploaded_at, version
     `
     
     err := r.db.QueryRowContext(
@@ -48,20 +68,23 @@ func (r *creativeRepository) Create(ctx context.Context, creative *models.Creati
         creative.FilePath,
         creative.Size,
         creative.CampaignID,
-        pq.Array(creative.SelectedDays),
-        pq.Array(creative.TimeSlots),
-        pq.Array(creative.Devices),
+        pq.Array(orEmpty(creative.SelectedDays)),
+        pq.Array(orEmpty(creative.TimeSlots)),
+        pq.Array(orEmpty(creative.Devices)),
         creative.UploadedAt,
-    ).Scan(&creative.UploadedAt)
+    ).Scan(&creative.UploadedAt, &creative.Version)
     
     return err
 }
 
 func (r *creativeRepository) GetByID(ctx context.Context, id string) (*models.Creative, error) {
+    ctx, span := tracing.Repository(ctx, "creativeRepository.GetByID")
+    defer span.End()
+
     query := `
-        SELECT id, name, type, url, file_path, size, campaign_id, selected_days, time_slots, devices, uploaded_at
+        SELECT id, name, type, url, file_path, size, campaign_id, selected_days, time_slots, devices, uploaded_at, deleted_at, version
         FROM creatives
-        WHERE id = $1
+        WHERE id = $1 AND deleted_at IS NULL
     `
     
     var creative models.Creative
@@ -77,6 +100,8 @@ func (r *creativeRepository) GetByID(ctx context.Context, id string) (*models.Cr
         pq.Array(&creative.TimeSlots),
         pq.Array(&creative.Devices),
         &creative.UploadedAt,
+        &creative.DeletedAt,
+        &creative.Version,
     )
     
     if err != nil {
@@ -89,25 +114,30 @@ func (r *creativeRepository) GetByID(ctx context.Context, id string) (*models.Cr
     return &creative, nil
 }
 
-func (r *creativeRepository) ListAll(ctx context.Context, limit int, offset int) ([]*models.Creative, error) {
+// CreativeSort lists the fields creative lists can be sorted by.
+var CreativeSort = interfaces.Sortable{
+	Columns: map[string]string{
+		"id":          "id",
+		"name":        "name",
+		"size":        "size",
+		"uploaded_at": "uploaded_at",
+	},
+	Default: "-uploaded_at",
+}
+
+func (r *creativeRepository) ListAll(ctx context.Context, page interfaces.ListPage, includeDeleted bool) ([]*models.Creative, error) {
+	ctx, span := tracing.Repository(ctx, "creativeRepository.ListAll")
+	defer span.End()
+
 	query := `
 		SELECT
-			id, name, type, url, file_path, size, campaign_id, selected_days, time_slots, devices, uploaded_at
+			id, name, type, url, file_path, size, campaign_id, selected_days, time_slots, devices, uploaded_at, deleted_at, version
 		FROM creatives
-		ORDER BY uploaded_at DESC
 	`
-
-	args := make([]any, 0, 2)
-	argPos := 1
-	if limit > 0 {
-		query += fmt.Sprintf(" LIMIT $%d", argPos)
-		args = append(args, limit)
-		argPos++
-	}
-	if offset > 0 {
-		query += fmt.Sprintf(" OFFSET $%d", argPos)
-		args = append(args, offset)
+	if !includeDeleted {
+		query += " WHERE deleted_at IS NULL"
 	}
+	query, args := appendListPage(query, nil, !includeDeleted, page, CreativeSort)
 
 	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
@@ -130,17 +160,25 @@ func (r *creativeRepository) ListAll(ctx context.Context, limit int, offset int)
             pq.Array(&creative.TimeSlots),
             pq.Array(&creative.Devices),
             &creative.UploadedAt,
+            &creative.DeletedAt,
+            &creative.Version,
         ); err != nil {
             return nil, err
         }
         creatives = append(creatives, &creative)
     }
 
-    	return creatives, rows.Err()
+    	return pageRows(creatives, rows.Err(), page)
 }
 
-func (r *creativeRepository) CountAll(ctx context.Context) (int, error) {
+func (r *creativeRepository) CountAll(ctx context.Context, includeDeleted bool) (int, error) {
+	ctx, span := tracing.Repository(ctx, "creativeRepository.CountAll")
+	defer span.End()
+
 	query := `SELECT COUNT(*) FROM creatives`
+	if !includeDeleted {
+		query += " WHERE deleted_at IS NULL"
+	}
 	var total int
 	if err := r.db.QueryRowContext(ctx, query).Scan(&total); err != nil {
 		return 0, err
@@ -148,27 +186,20 @@ func (r *creativeRepository) CountAll(ctx context.Context) (int, error) {
 	return total, nil
 }
 
-func (r *creativeRepository) ListByCampaign(ctx context.Context, campaignID string, limit int, offset int) ([]*models.Creative, error) {
+func (r *creativeRepository) ListByCampaign(ctx context.Context, campaignID string, page interfaces.ListPage, includeDeleted bool) ([]*models.Creative, error) {
+	ctx, span := tracing.Repository(ctx, "creativeRepository.ListByCampaign")
+	defer span.End()
+
 	query := `
 		SELECT
-			id, name, type, url, file_path, size, campaign_id, selected_days, time_slots, devices, uploaded_at
+			id, name, type, url, file_path, size, campaign_id, selected_days, time_slots, devices, uploaded_at, deleted_at, version
 		FROM creatives
 		WHERE campaign_id = $1
-		ORDER BY uploaded_at DESC
 	`
-
-	args := make([]any, 0, 3)
-	args = append(args, campaignID)
-	argPos := 2
-	if limit > 0 {
-		query += fmt.Sprintf(" LIMIT $%d", argPos)
-		args = append(args, limit)
-		argPos++
-	}
-	if offset > 0 {
-		query += fmt.Sprintf(" OFFSET $%d", argPos)
-		args = append(args, offset)
+	if !includeDeleted {
+		query += " AND deleted_at IS NULL"
 	}
+	query, args := appendListPage(query, []any{campaignID}, true, page, CreativeSort)
 
 	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
@@ -191,17 +222,25 @@ func (r *creativeRepository) ListByCampaign(ctx context.Context, campaignID stri
             pq.Array(&creative.TimeSlots),
             pq.Array(&creative.Devices),
             &creative.UploadedAt,
+            &creative.DeletedAt,
+            &creative.Version,
         ); err != nil {
             return nil, err
         }
         creatives = append(creatives, &creative)
     }
     
-    	return creatives, rows.Err()
+    	return pageRows(creatives, rows.Err(), page)
 }
 
-func (r *creativeRepository) CountByCampaign(ctx context.Context, campaignID string) (int, error) {
+func (r *creativeRepository) CountByCampaign(ctx context.Context, campaignID string, includeDeleted bool) (int, error) {
+	ctx, span := tracing.Repository(ctx, "creativeRepository.CountByCampaign")
+	defer span.End()
+
 	query := `SELECT COUNT(*) FROM creatives WHERE campaign_id = $1`
+	if !includeDeleted {
+		query += " AND deleted_at IS NULL"
+	}
 	var total int
 	if err := r.db.QueryRowContext(ctx, query, campaignID).Scan(&total); err != nil {
 		return 0, err
@@ -209,12 +248,16 @@ func (r *creativeRepository) CountByCampaign(ctx context.Context, campaignID str
 	return total, nil
 }
 
-func (r *creativeRepository) ListByDevice(ctx context.Context, device string, activeNow bool, now time.Time, limit int, offset int) ([]*models.Creative, error) {
+func (r *creativeRepository) ListByDevice(ctx context.Context, device string, activeNow bool, now time.Time, page interfaces.ListPage) ([]*models.Creative, error) {
+	ctx, span := tracing.Repository(ctx, "creativeRepository.ListByDevice")
+	defer span.End()
+
 	query := `
 		SELECT
-			id, name, type, url, file_path, size, campaign_id, selected_days, time_slots, devices, uploaded_at
+			id, name, type, url, file_path, size, campaign_id, selected_days, time_slots, devices, uploaded_at, deleted_at, version
 		FROM creatives
-		WHERE EXISTS (
+		WHERE deleted_at IS NULL
+		AND EXISTS (
 			SELECT 1
 			FROM unnest(devices) dv
 			WHERE lower(trim(dv)) = lower($1)
@@ -222,7 +265,6 @@ func (r *creativeRepository) ListByDevice(ctx context.Context, device string, ac
 	`
 
 	args := []any{device}
-	argPos := 2
 
 	if activeNow {
 		day := now.Weekday().String()
@@ -250,22 +292,9 @@ func (r *creativeRepository) ListByDevice(ctx context.Context, device string, ac
 		`
 
 		args = append(args, day, tm, tm)
-		argPos = 5
 	}
 
-	query += `
-		ORDER BY uploaded_at DESC
-	`
-
-	if limit > 0 {
-		query += fmt.Sprintf(" LIMIT $%d", argPos)
-		args = append(args, limit)
-		argPos++
-	}
-	if offset > 0 {
-		query += fmt.Sprintf(" OFFSET $%d", argPos)
-		args = append(args, offset)
-	}
+	query, args = appendListPage(query, args, true, page, CreativeSort)
 
 	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
@@ -288,20 +317,26 @@ func (r *creativeRepository) ListByDevice(ctx context.Context, device string, ac
 			pq.Array(&creative.TimeSlots),
 			pq.Array(&creative.Devices),
 			&creative.UploadedAt,
+			&creative.DeletedAt,
+&creative.Version,
 		); err != nil {
 			return nil, err
 		}
 		creatives = append(creatives, &creative)
 	}
 
-	return creatives, rows.Err()
+	return pageRows(creatives, rows.Err(), page)
 }
 
 func (r *creativeRepository) CountByDevice(ctx context.Context, device string, activeNow bool, now time.Time) (int, error) {
+	ctx, span := tracing.Repository(ctx, "creativeRepository.CountByDevice")
+	defer span.End()
+
 	query := `
 		SELECT COUNT(*)
 		FROM creatives
-		WHERE EXISTS (
+		WHERE deleted_at IS NULL
+		AND EXISTS (
 			SELECT 1
 			FROM unnest(devices) dv
 			WHERE lower(trim(dv)) = lower($1)
@@ -343,7 +378,10 @@ func (r *creativeRepository) CountByDevice(ctx context.Context, device string, a
 	return total, nil
 }
 
-func (r *creativeRepository) Update(ctx context.Context, id string, req *models.UpdateCreativeRequest) error {
+func (r *creativeRepository) Update(ctx context.Context, id string, req *models.UpdateCreativeRequest, version int) error {
+    ctx, span := tracing.Repository(ctx, "creativeRepository.Update")
+    defer span.End()
+
     query := `
         UPDATE creatives
         SET name = COALESCE($1, name),
@@ -354,7 +392,7 @@ func (r *creativeRepository) Update(ctx context.Context, id string, req *models.
             selected_days = COALESCE($6::text[], selected_days),
             time_slots = COALESCE($7::text[], time_slots),
             devices = COALESCE($8::text[], devices)
-        WHERE id = $9
+        WHERE id = $9 AND deleted_at IS NULL AND ($10 = 0 OR version = $10)
         RETURNING id
     `
 
@@ -383,13 +421,21 @@ func (r *creativeRepository) Update(ctx context.Context, id string, req *models.
         timeSlots,
         devices,
         id,
+        version,
     ).Scan(&id)
-    
+    if err == sql.ErrNoRows {
+        return missedUpdateError(ctx, r.db,
+            `SELECT EXISTS (SELECT 1 FROM creatives WHERE id = $1 AND deleted_at IS NULL)`, id,
+            sql.ErrNoRows)
+    }
     return err
 }
 
 func (r *creativeRepository) Delete(ctx context.Context, id string) error {
-    query := `DELETE FROM creatives WHERE id = $1`
+    ctx, span := tracing.Repository(ctx, "creativeRepository.Delete")
+    defer span.End()
+
+    query := `UPDATE creatives SET deleted_at = NOW() AT TIME ZONE 'UTC' WHERE id = $1 AND deleted_at IS NULL`
     result, err := r.db.ExecContext(ctx, query, id)
     if err != nil {
         return err
@@ -405,4 +451,52 @@ func (r *creativeRepository) Delete(ctx context.Context, id string) error {
     }
     
     return nil
-}
\ No newline at end of file
+}
+
+// Restore undeletes a soft-deleted creative. It returns sql.ErrNoRows when no deleted
+// creative has that ID, and RestoreBlockedError while its campaign is still deleted.
+func (r *creativeRepository) Restore(ctx context.Context, id string) error {
+    ctx, span := tracing.Repository(ctx, "creativeRepository.Restore")
+    defer span.End()
+
+    var campaignDeleted bool
+    err := r.db.QueryRowContext(ctx, `
+        SELECT c.deleted_at IS NOT NULL
+        FROM creatives cr
+        JOIN campaigns c ON c.id = cr.campaign_id
+        WHERE cr.id = $1 AND cr.deleted_at IS NOT NULL
+    `, id).Scan(&campaignDeleted)
+    if err != nil {
+        return err
+    }
+    if campaignDeleted {
+        return &interfaces.RestoreBlockedError{Resource: "creative", Parent: "campaign"}
+    }
+
+    result, err := r.db.ExecContext(ctx, `UPDATE creatives SET deleted_at = NULL WHERE id = $1 AND deleted_at IS NOT NULL`, id)
+    if err != nil {
+        return err
+    }
+
+    rowsAffected, err := result.RowsAffected()
+    if err != nil {
+        return err
+    }
+
+    if rowsAffected == 0 {
+        return sql.ErrNoRows
+    }
+
+    return nil
+}
+
+func (r *creativeRepository) PurgeDeletedBefore(ctx context.Context, cutoff time.Time) (int64, []string, error) {
+    ctx, span := tracing.Repository(ctx, "creativeRepository.PurgeDeletedBefore")
+    defer span.End()
+
+    purged, keys, err := deleteCreatives(ctx, r.db, "cr.deleted_at < $1", cutoff)
+    if err != nil {
+        return 0, nil, fmt.Errorf("failed to purge creatives: %w", err)
+    }
+    return purged, keys, nil
+}
@@ -0,0 +1,182 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"time"
+
+	"scm/internal/models"
+)
+
+// queryer is satisfied by both *sql.DB and *sql.Tx, so dependency lookups can run
+// standalone for previews or inside the cascade-delete transaction.
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// queryDependents runs a query returning (id, name, deleted_at) rows.
+func queryDependents(ctx context.Context, q queryer, query string, args ...any) ([]models.DependentRecord, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	records := []models.DependentRecord{}
+	for rows.Next() {
+		var rec models.DependentRecord
+		if err := rows.Scan(&rec.ID, &rec.Name, &rec.DeletedAt); err != nil {
+			return nil, err
+		}
+		records = append(records, rec)
+	}
+	return records, rows.Err()
+}
+
+// queryCreativeDependents returns the creatives matched by where (which may reference
+// creatives as cr and campaigns as c) and the S3 keys of their files.
+func queryCreativeDependents(ctx context.Context, q queryer, where string, args ...any) ([]models.DependentRecord, []string, error) {
+	rows, err := q.QueryContext(ctx, `
+		SELECT cr.id::text, cr.name, cr.deleted_at, cr.file_path
+		FROM creatives cr
+		JOIN campaigns c ON c.id = cr.campaign_id
+		WHERE `+where+`
+		ORDER BY cr.uploaded_at
+	`, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	records := []models.DependentRecord{}
+	keys := []string{}
+	for rows.Next() {
+		var rec models.DependentRecord
+		var key string
+		if err := rows.Scan(&rec.ID, &rec.Name, &rec.DeletedAt, &key); err != nil {
+			return nil, nil, err
+		}
+		records = append(records, rec)
+		if key != "" {
+			keys = append(keys, key)
+		}
+	}
+	return records, keys, rows.Err()
+}
+
+func advertiserDependencies(ctx context.Context, q queryer, id string) (*models.DeletionDependencies, error) {
+	campaigns, err := queryDependents(ctx, q,
+		`SELECT id::text, name, deleted_at FROM campaigns WHERE advertiser_id = $1 ORDER BY created_at`, id)
+	if err != nil {
+		return nil, fmt.Errorf("list dependent campaigns: %w", err)
+	}
+	creatives, keys, err := queryCreativeDependents(ctx, q, "c.advertiser_id = $1", id)
+	if err != nil {
+		return nil, fmt.Errorf("list dependent creatives: %w", err)
+	}
+
+	return &models.DeletionDependencies{
+		Resource: "advertiser",
+		ID:       id,
+		References: map[string]int64{
+			"campaigns":      int64(len(campaigns)),
+			"creatives":      int64(len(creatives)),
+			"stored_objects": int64(len(keys)),
+		},
+		Campaigns:     campaigns,
+		Creatives:     creatives,
+		StoredObjects: keys,
+	}, nil
+}
+
+func campaignDependencies(ctx context.Context, q queryer, id string) (*models.DeletionDependencies, error) {
+	creatives, keys, err := queryCreativeDependents(ctx, q, "cr.campaign_id = $1", id)
+	if err != nil {
+		return nil, fmt.Errorf("list dependent creatives: %w", err)
+	}
+
+	return &models.DeletionDependencies{
+		Resource: "campaign",
+		ID:       id,
+		References: map[string]int64{
+			"creatives":      int64(len(creatives)),
+			"stored_objects": int64(len(keys)),
+		},
+		Creatives:     creatives,
+		StoredObjects: keys,
+	}, nil
+}
+
+func venueDependencies(ctx context.Context, q queryer, id int) (*models.DeletionDependencies, error) {
+	devices, err := queryDependents(ctx, q, `
+		SELECT d.id::text, d.host_name, NULL::timestamptz
+		FROM devices d
+		JOIN venue_devices vd ON vd.device_id = d.id
+		WHERE vd.venue_id = $1
+		ORDER BY d.host_name
+	`, id)
+	if err != nil {
+		return nil, fmt.Errorf("list dependent devices: %w", err)
+	}
+	rules, err := queryDependents(ctx, q,
+		`SELECT id::text, name, NULL::timestamptz FROM alert_rules WHERE venue_id = $1 ORDER BY id`, id)
+	if err != nil {
+		return nil, fmt.Errorf("list dependent alert rules: %w", err)
+	}
+
+	return &models.DeletionDependencies{
+		Resource: "venue",
+		ID:       strconv.Itoa(id),
+		References: map[string]int64{
+			"devices":     int64(len(devices)),
+			"alert_rules": int64(len(rules)),
+		},
+		Devices:    devices,
+		AlertRules: rules,
+	}, nil
+}
+
+// deleteCreatives permanently deletes the creatives matched by where (which may reference
+// creatives as cr) and returns how many were removed and the S3 keys of their files.
+func deleteCreatives(ctx context.Context, q queryer, where string, args ...any) (int64, []string, error) {
+	rows, err := q.QueryContext(ctx, `DELETE FROM creatives cr WHERE `+where+` RETURNING cr.file_path`, args...)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer rows.Close()
+
+	var removed int64
+	keys := []string{}
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return 0, nil, err
+		}
+		removed++
+		if key != "" {
+			keys = append(keys, key)
+		}
+	}
+	return removed, keys, rows.Err()
+}
+
+// lockDeletedBefore locks the rows of table soft-deleted before cutoff and returns their ids.
+func lockDeletedBefore(ctx context.Context, q queryer, table string, cutoff time.Time) ([]string, error) {
+	rows, err := q.QueryContext(ctx, `SELECT id::text FROM `+table+` WHERE deleted_at < $1 FOR UPDATE`, cutoff)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
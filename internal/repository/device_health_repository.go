@@ -0,0 +1,199 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"scm/internal/models"
+	"scm/internal/tracing"
+)
+
+type DeviceHealthRepository interface {
+	RecordHeartbeat(ctx context.Context, hb *models.DeviceHeartbeat) error
+	GetLatestHeartbeat(ctx context.Context, hostName string) (*models.DeviceHeartbeat, error)
+	Summary(ctx context.Context, filters DeviceFilters) (*models.DeviceHealthSummary, error)
+	OfflineCountsByVenue(ctx context.Context, venueID *int, offlineFor time.Duration) ([]models.VenueOfflineCount, error)
+	// ListReceivedBetween returns the latest heartbeats received after from and at or
+	// before to.
+	ListReceivedBetween(ctx context.Context, from time.Time, to time.Time) ([]*models.DeviceHeartbeat, error)
+}
+
+type deviceHealthRepository struct {
+	db DBTX
+}
+
+func NewDeviceHealthRepository(db DBTX) DeviceHealthRepository {
+	return &deviceHealthRepository{db: db}
+}
+
+// RecordHeartbeat stores hb as the device's latest heartbeat and sets ReceivedAt.
+// It returns "device not found" when no synced device has hb.HostName.
+func (r *deviceHealthRepository) RecordHeartbeat(ctx context.Context, hb *models.DeviceHeartbeat) error {
+	ctx, span := tracing.Repository(ctx, "deviceHealthRepository.RecordHeartbeat")
+	defer span.End()
+
+	query := `
+		INSERT INTO device_heartbeats (
+			host_name, uptime_seconds, playlist_version, free_disk_bytes,
+			last_creative_id, last_creative_played_at, received_at
+		)
+		SELECT host_name, $2, $3, $4, $5, $6, NOW()
+		FROM devices
+		WHERE host_name = $1
+		ON CONFLICT (host_name) DO UPDATE SET
+			uptime_seconds = EXCLUDED.uptime_seconds,
+			playlist_version = EXCLUDED.playlist_version,
+			free_disk_bytes = EXCLUDED.free_disk_bytes,
+			last_creative_id = EXCLUDED.last_creative_id,
+			last_creative_played_at = EXCLUDED.last_creative_played_at,
+			received_at = EXCLUDED.received_at
+		RETURNING received_at
+	`
+
+	err := r.db.QueryRowContext(ctx, query,
+		hb.HostName, hb.UptimeSeconds, hb.PlaylistVersion, hb.FreeDiskBytes,
+		hb.LastCreativeID, hb.LastCreativePlayedAt,
+	).Scan(&hb.ReceivedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("device not found")
+		}
+		return fmt.Errorf("record heartbeat: %w", err)
+	}
+	return nil
+}
+
+func (r *deviceHealthRepository) GetLatestHeartbeat(ctx context.Context, hostName string) (*models.DeviceHeartbeat, error) {
+	ctx, span := tracing.Repository(ctx, "deviceHealthRepository.GetLatestHeartbeat")
+	defer span.End()
+
+	query := `
+		SELECT host_name, uptime_seconds, playlist_version, free_disk_bytes,
+			last_creative_id, last_creative_played_at, received_at
+		FROM device_heartbeats
+		WHERE host_name = $1
+	`
+
+	var hb models.DeviceHeartbeat
+	err := r.db.QueryRowContext(ctx, query, hostName).Scan(
+		&hb.HostName, &hb.UptimeSeconds, &hb.PlaylistVersion, &hb.FreeDiskBytes,
+		&hb.LastCreativeID, &hb.LastCreativePlayedAt, &hb.ReceivedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("heartbeat not found")
+		}
+		return nil, fmt.Errorf("get heartbeat: %w", err)
+	}
+	return &hb, nil
+}
+
+// Summary counts devices matching filters by health, using filters.HealthThresholds.
+func (r *deviceHealthRepository) Summary(ctx context.Context, filters DeviceFilters) (*models.DeviceHealthSummary, error) {
+	ctx, span := tracing.Repository(ctx, "deviceHealthRepository.Summary")
+	defer span.End()
+
+	query := `
+		SELECT
+			COUNT(*) FILTER (WHERE hb.received_at >= NOW() - make_interval(secs => $1))::int AS online,
+			COUNT(*) FILTER (WHERE hb.received_at < NOW() - make_interval(secs => $1)
+				AND hb.received_at >= NOW() - make_interval(secs => $2))::int AS stale,
+			COUNT(*) FILTER (WHERE hb.received_at IS NULL
+				OR hb.received_at < NOW() - make_interval(secs => $2))::int AS offline,
+			COUNT(*)::int AS total
+		FROM devices
+		LEFT JOIN device_heartbeats hb ON hb.host_name = devices.host_name
+		WHERE 1=1
+	`
+	args := []any{filters.HealthThresholds.StaleAfter.Seconds(), filters.HealthThresholds.OfflineAfter.Seconds()}
+
+	clause, args, _ := buildDeviceFilterClause(filters, args, 3)
+	query += clause
+
+	var summary models.DeviceHealthSummary
+	err := r.db.QueryRowContext(ctx, query, args...).Scan(&summary.Online, &summary.Stale, &summary.Offline, &summary.Total)
+	if err != nil {
+		return nil, fmt.Errorf("device health summary: %w", err)
+	}
+	return &summary, nil
+}
+
+// OfflineCountsByVenue counts, per venue, the devices without a heartbeat for at least
+// offlineFor. Devices that never sent a heartbeat are measured from when they were first synced.
+func (r *deviceHealthRepository) OfflineCountsByVenue(ctx context.Context, venueID *int, offlineFor time.Duration) ([]models.VenueOfflineCount, error) {
+	ctx, span := tracing.Repository(ctx, "deviceHealthRepository.OfflineCountsByVenue")
+	defer span.End()
+
+	query := `
+		SELECT v.id, v.name,
+			COUNT(*) FILTER (WHERE COALESCE(hb.received_at, d.created_at) < NOW() - make_interval(secs => $1))::int AS offline,
+			COUNT(*)::int AS total
+		FROM venues v
+		JOIN venue_devices vd ON vd.venue_id = v.id
+		JOIN devices d ON d.id = vd.device_id
+		LEFT JOIN device_heartbeats hb ON hb.host_name = d.host_name
+		WHERE 1=1
+	`
+	args := []any{offlineFor.Seconds()}
+	if venueID != nil {
+		query += " AND v.id = $2"
+		args = append(args, *venueID)
+	}
+	query += " GROUP BY v.id, v.name ORDER BY v.id"
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("offline counts by venue: %w", err)
+	}
+	defer rows.Close()
+
+	var out []models.VenueOfflineCount
+	for rows.Next() {
+		var row models.VenueOfflineCount
+		if err := rows.Scan(&row.VenueID, &row.VenueName, &row.Offline, &row.Total); err != nil {
+			return nil, fmt.Errorf("scan venue offline count: %w", err)
+		}
+		out = append(out, row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows venue offline count: %w", err)
+	}
+	return out, nil
+}
+
+func (r *deviceHealthRepository) ListReceivedBetween(ctx context.Context, from time.Time, to time.Time) ([]*models.DeviceHeartbeat, error) {
+	ctx, span := tracing.Repository(ctx, "deviceHealthRepository.ListReceivedBetween")
+	defer span.End()
+
+	query := `
+		SELECT host_name, uptime_seconds, playlist_version, free_disk_bytes,
+			last_creative_id, last_creative_played_at, received_at
+		FROM device_heartbeats
+		WHERE received_at > $1 AND received_at <= $2
+		ORDER BY received_at, host_name
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("list heartbeats: %w", err)
+	}
+	defer rows.Close()
+
+	var out []*models.DeviceHeartbeat
+	for rows.Next() {
+		var hb models.DeviceHeartbeat
+		if err := rows.Scan(
+			&hb.HostName, &hb.UptimeSeconds, &hb.PlaylistVersion, &hb.FreeDiskBytes,
+			&hb.LastCreativeID, &hb.LastCreativePlayedAt, &hb.ReceivedAt,
+		); err != nil {
+			return nil, fmt.Errorf("scan heartbeat: %w", err)
+		}
+		out = append(out, &hb)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows heartbeats: %w", err)
+	}
+	return out, nil
+}
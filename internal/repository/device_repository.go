@@ -5,21 +5,31 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"maps"
+	"slices"
+	"strings"
 	"time"
 
+	"github.com/lib/pq"
+	"scm/internal/interfaces"
 	"scm/internal/models"
+	"scm/internal/tracing"
 )
 
 type DeviceRepository interface {
 	Upsert(ctx context.Context, device *models.Device) error
 	GetByHostName(ctx context.Context, hostName string) (*models.Device, error)
-	List(ctx context.Context, limit int, offset int) ([]*models.Device, error)
+	List(ctx context.Context, page interfaces.ListPage) ([]*models.Device, error)
 	Count(ctx context.Context) (int, error)
-	ListByProject(ctx context.Context, projectID int, limit int, offset int) ([]*models.Device, error)
+	ListByProject(ctx context.Context, projectID int, page interfaces.ListPage) ([]*models.Device, error)
 	CountByProject(ctx context.Context, projectID int) (int, error)
-	ListWithFilters(ctx context.Context, filters DeviceFilters, limit int, offset int) ([]*models.Device, error)
+	ListWithFilters(ctx context.Context, filters DeviceFilters, page interfaces.ListPage) ([]*models.Device, error)
 	CountWithFilters(ctx context.Context, filters DeviceFilters) (int, error)
-	CountByRegion(ctx context.Context, city *string) ([]RegionDeviceCount, error)
+	CountByRegion(ctx context.Context, filters DeviceFilters) ([]RegionDeviceCount, error)
+	// Facets counts the devices matching filters by each of DeviceFacetFields.
+	Facets(ctx context.Context, filters DeviceFilters) (models.DeviceFacets, error)
+	// ListSyncMarkers maps the host name of every stored device to its last_synced_at.
+	ListSyncMarkers(ctx context.Context) (map[string]*time.Time, error)
 }
 
 type RegionDeviceCount struct {
@@ -33,17 +43,194 @@ type DeviceFilters struct {
 	City        *string
 	Region      *string
 	DeviceType  *string
+	// HostNameGlob matches host_name with "*" and "?" wildcards.
+	HostNameGlob *string
+
+	// Health filters on heartbeat age; HealthThresholds must be set alongside it.
+	Health           *models.DeviceHealthStatus
+	HealthThresholds models.HealthThresholds
+
+	SyncStatus *string
+	Change     *bool
+	// SyncedAfter and SyncedBefore bound last_synced_at (inclusive, exclusive).
+	SyncedAfter  *time.Time
+	SyncedBefore *time.Time
+	VenueID      *int
+	// Production and IsTransit match the flags of the device's region.
+	Production *bool
+	IsTransit  *bool
+	Config     []DeviceConfigFilter
+	// Annotations matches the device's local annotations.
+	Annotations AnnotationFilters
+}
+
+// DeviceConfigFilter matches devices whose device_config has Value (compared as text) at Path.
+type DeviceConfigFilter struct {
+	Path  []string
+	Value string
+}
+
+// HasAny reports whether at least one filter is set.
+func (f DeviceFilters) HasAny() bool {
+	return f.ProjectID != nil || f.City != nil || f.Region != nil || f.DeviceType != nil || f.HostNameGlob != nil || f.Health != nil ||
+		f.SyncStatus != nil || f.Change != nil || f.SyncedAfter != nil || f.SyncedBefore != nil || f.VenueID != nil ||
+		f.Production != nil || f.IsTransit != nil || len(f.Config) > 0 || f.Annotations.HasAny()
+}
+
+// DeviceFiltersFromSelector converts a stored or requested device selector into list filters.
+func DeviceFiltersFromSelector(s models.DeviceSelector) DeviceFilters {
+	return DeviceFilters{
+		ProjectID:    s.ProjectID,
+		City:         s.City,
+		Region:       s.Region,
+		DeviceType:   s.DeviceType,
+		HostNameGlob: s.HostNameGlob,
+	}
+}
+
+// globToLike translates a "*"/"?" glob into a LIKE pattern, escaping LIKE metacharacters.
+func globToLike(glob string) string {
+	var b strings.Builder
+	for _, r := range glob {
+		switch r {
+		case '*':
+			b.WriteByte('%')
+		case '?':
+			b.WriteByte('_')
+		case '%', '_', '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// buildDeviceFilterClause appends the AND conditions for filters to a query over devices
+// and returns the clause, the extended args and the next placeholder index.
+func buildDeviceFilterClause(filters DeviceFilters, args []any, argIndex int) (string, []any, int) {
+	var clause string
+
+	if filters.ProjectID != nil {
+		clause += fmt.Sprintf(" AND devices.project = $%d", argIndex)
+		args = append(args, *filters.ProjectID)
+		argIndex++
+	}
+
+	if filters.City != nil {
+		// Search for city in the device_config JSONB field
+		clause += fmt.Sprintf(" AND devices.device_config->>'city' = $%d", argIndex)
+		args = append(args, *filters.City)
+		argIndex++
+	}
+
+	if filters.Region != nil {
+		// Filter by region code in the region JSONB field
+		clause += fmt.Sprintf(" AND devices.region->>'code' = $%d", argIndex)
+		args = append(args, *filters.Region)
+		argIndex++
+	}
+
+	if filters.DeviceType != nil {
+		// Search for device_type in the device_type JSONB field
+		clause += fmt.Sprintf(" AND devices.device_type::text LIKE $%d", argIndex)
+		args = append(args, "%"+*filters.DeviceType+"%")
+		argIndex++
+	}
+
+	if filters.HostNameGlob != nil {
+		clause += fmt.Sprintf(" AND devices.host_name LIKE $%d", argIndex)
+		args = append(args, globToLike(*filters.HostNameGlob))
+		argIndex++
+	}
+
+	if filters.Health != nil {
+		stale := filters.HealthThresholds.StaleAfter.Seconds()
+		offline := filters.HealthThresholds.OfflineAfter.Seconds()
+		switch *filters.Health {
+		case models.DeviceHealthOnline:
+			clause += fmt.Sprintf(" AND EXISTS (SELECT 1 FROM device_heartbeats hb WHERE hb.host_name = devices.host_name AND hb.received_at >= NOW() - make_interval(secs => $%d))", argIndex)
+			args = append(args, stale)
+			argIndex++
+		case models.DeviceHealthStale:
+			clause += fmt.Sprintf(" AND EXISTS (SELECT 1 FROM device_heartbeats hb WHERE hb.host_name = devices.host_name AND hb.received_at < NOW() - make_interval(secs => $%d) AND hb.received_at >= NOW() - make_interval(secs => $%d))", argIndex, argIndex+1)
+			args = append(args, stale, offline)
+			argIndex += 2
+		case models.DeviceHealthOffline:
+			clause += fmt.Sprintf(" AND NOT EXISTS (SELECT 1 FROM device_heartbeats hb WHERE hb.host_name = devices.host_name AND hb.received_at >= NOW() - make_interval(secs => $%d))", argIndex)
+			args = append(args, offline)
+			argIndex++
+		}
+	}
+
+	if filters.SyncStatus != nil {
+		clause += fmt.Sprintf(" AND devices.sync_status = $%d", argIndex)
+		args = append(args, *filters.SyncStatus)
+		argIndex++
+	}
+
+	if filters.Change != nil {
+		clause += fmt.Sprintf(" AND devices.change = $%d", argIndex)
+		args = append(args, *filters.Change)
+		argIndex++
+	}
+
+	if filters.SyncedAfter != nil {
+		clause += fmt.Sprintf(" AND devices.last_synced_at >= $%d", argIndex)
+		args = append(args, *filters.SyncedAfter)
+		argIndex++
+	}
+
+	if filters.SyncedBefore != nil {
+		clause += fmt.Sprintf(" AND devices.last_synced_at < $%d", argIndex)
+		args = append(args, *filters.SyncedBefore)
+		argIndex++
+	}
+
+	if filters.VenueID != nil {
+		clause += fmt.Sprintf(" AND EXISTS (SELECT 1 FROM venue_devices vd WHERE vd.device_id = devices.id AND vd.venue_id = $%d)", argIndex)
+		args = append(args, *filters.VenueID)
+		argIndex++
+	}
+
+	// Region flags missing from the console payload count as false, as they do in models.Region
+	if filters.Production != nil {
+		clause += fmt.Sprintf(" AND COALESCE(devices.region->'production', 'false') = to_jsonb($%d::boolean)", argIndex)
+		args = append(args, *filters.Production)
+		argIndex++
+	}
+
+	if filters.IsTransit != nil {
+		clause += fmt.Sprintf(" AND COALESCE(devices.region->'is_transit', 'false') = to_jsonb($%d::boolean)", argIndex)
+		args = append(args, *filters.IsTransit)
+		argIndex++
+	}
+
+	for _, cf := range filters.Config {
+		clause += fmt.Sprintf(" AND devices.device_config #>> $%d = $%d", argIndex, argIndex+1)
+		args = append(args, pq.Array(cf.Path), cf.Value)
+		argIndex += 2
+	}
+
+	annotationClause, args, argIndex := deviceAnnotations.filterClause("devices.host_name", filters.Annotations, args, argIndex)
+	clause += annotationClause
+
+	return clause, args, argIndex
 }
 
 type deviceRepository struct {
-	db *sql.DB
+	db DBTX
 }
 
-func NewDeviceRepository(db *sql.DB) DeviceRepository {
+func NewDeviceRepository(db DBTX) DeviceRepository {
 	return &deviceRepository{db: db}
 }
 
 func (r *deviceRepository) Upsert(ctx context.Context, device *models.Device) error {
+	ctx, span := tracing.Repository(ctx, "deviceRepository.Upsert")
+	defer span.End()
+
 	deviceTypeJSON, err := json.Marshal(device.DeviceType)
 	if err != nil {
 		return fmt.Errorf("marshal device_type: %w", err)
@@ -88,21 +275,25 @@ func (r *deviceRepository) Upsert(ctx context.Context, device *models.Device) er
 }
 
 func (r *deviceRepository) GetByHostName(ctx context.Context, hostName string) (*models.Device, error) {
+	ctx, span := tracing.Repository(ctx, "deviceRepository.GetByHostName")
+	defer span.End()
+
 	query := `
 		SELECT id, device_type, region, name, host_name, description, change,
 			last_synced_at, sync_status, project, device_config, rtty_data,
-			created_at, updated_at
+			created_at, updated_at,
+			(SELECT to_jsonb(a) FROM device_annotations a WHERE a.host_name = devices.host_name) AS annotations
 		FROM devices
 		WHERE host_name = $1
 	`
 
 	var device models.Device
-	var deviceTypeJSON, regionJSON []byte
+	var deviceTypeJSON, regionJSON, annotationsJSON []byte
 	err := r.db.QueryRowContext(ctx, query, hostName).Scan(
 		&device.ID, &deviceTypeJSON, &regionJSON, &device.Name, &device.HostName,
 		&device.Description, &device.Change, &device.LastSyncedAt, &device.SyncStatus,
 		&device.Project, &device.DeviceConfig, &device.RttyData,
-		&device.CreatedAt, &device.UpdatedAt,
+		&device.CreatedAt, &device.UpdatedAt, &annotationsJSON,
 	)
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -117,30 +308,38 @@ func (r *deviceRepository) GetByHostName(ctx context.Context, hostName string) (
 	if err := json.Unmarshal(regionJSON, &device.Region); err != nil {
 		return nil, fmt.Errorf("unmarshal region: %w", err)
 	}
+	if device.Annotations, err = scanAnnotations(annotationsJSON); err != nil {
+		return nil, err
+	}
 
 	return &device, nil
 }
 
-func (r *deviceRepository) List(ctx context.Context, limit int, offset int) ([]*models.Device, error) {
+// DeviceSort lists the fields device lists can be sorted by.
+var DeviceSort = interfaces.Sortable{
+	Columns: map[string]string{
+		"id":         "id",
+		"name":       "name",
+		"host_name":  "host_name",
+		"project":    "project",
+		"created_at": "created_at",
+		"updated_at": "updated_at",
+	},
+	Default: "-created_at",
+}
+
+func (r *deviceRepository) List(ctx context.Context, page interfaces.ListPage) ([]*models.Device, error) {
+	ctx, span := tracing.Repository(ctx, "deviceRepository.List")
+	defer span.End()
+
 	query := `
 		SELECT id, device_type, region, name, host_name, description, change,
 			last_synced_at, sync_status, project, device_config, rtty_data,
-			created_at, updated_at
+			created_at, updated_at,
+			(SELECT to_jsonb(a) FROM device_annotations a WHERE a.host_name = devices.host_name) AS annotations
 		FROM devices
-		ORDER BY created_at DESC
 	`
-
-	args := []interface{}{}
-	argPos := 1
-	if limit > 0 {
-		query += fmt.Sprintf(" LIMIT $%d", argPos)
-		args = append(args, limit)
-		argPos++
-	}
-	if offset > 0 {
-		query += fmt.Sprintf(" OFFSET $%d", argPos)
-		args = append(args, offset)
-	}
+	query, args := appendListPage(query, nil, false, page, DeviceSort)
 
 	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
@@ -151,12 +350,12 @@ func (r *deviceRepository) List(ctx context.Context, limit int, offset int) ([]*
 	var devices []*models.Device
 	for rows.Next() {
 		var device models.Device
-		var deviceTypeJSON, regionJSON []byte
+		var deviceTypeJSON, regionJSON, annotationsJSON []byte
 		if err := rows.Scan(
 			&device.ID, &deviceTypeJSON, &regionJSON, &device.Name, &device.HostName,
 			&device.Description, &device.Change, &device.LastSyncedAt, &device.SyncStatus,
 			&device.Project, &device.DeviceConfig, &device.RttyData,
-			&device.CreatedAt, &device.UpdatedAt,
+			&device.CreatedAt, &device.UpdatedAt, &annotationsJSON,
 		); err != nil {
 			return nil, fmt.Errorf("scan device: %w", err)
 		}
@@ -167,14 +366,20 @@ func (r *deviceRepository) List(ctx context.Context, limit int, offset int) ([]*
 		if err := json.Unmarshal(regionJSON, &device.Region); err != nil {
 			return nil, fmt.Errorf("unmarshal region: %w", err)
 		}
+		if device.Annotations, err = scanAnnotations(annotationsJSON); err != nil {
+			return nil, err
+		}
 
 		devices = append(devices, &device)
 	}
 
-	return devices, rows.Err()
+	return pageRows(devices, rows.Err(), page)
 }
 
 func (r *deviceRepository) Count(ctx context.Context) (int, error) {
+	ctx, span := tracing.Repository(ctx, "deviceRepository.Count")
+	defer span.End()
+
 	var count int
 	err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM devices").Scan(&count)
 	if err != nil {
@@ -183,27 +388,19 @@ func (r *deviceRepository) Count(ctx context.Context) (int, error) {
 	return count, nil
 }
 
-func (r *deviceRepository) ListByProject(ctx context.Context, projectID int, limit int, offset int) ([]*models.Device, error) {
+func (r *deviceRepository) ListByProject(ctx context.Context, projectID int, page interfaces.ListPage) ([]*models.Device, error) {
+	ctx, span := tracing.Repository(ctx, "deviceRepository.ListByProject")
+	defer span.End()
+
 	query := `
 		SELECT id, device_type, region, name, host_name, description, change,
 			last_synced_at, sync_status, project, device_config, rtty_data,
-			created_at, updated_at
+			created_at, updated_at,
+			(SELECT to_jsonb(a) FROM device_annotations a WHERE a.host_name = devices.host_name) AS annotations
 		FROM devices
 		WHERE project = $1
-		ORDER BY created_at DESC
 	`
-
-	args := []interface{}{projectID}
-	argPos := 2
-	if limit > 0 {
-		query += fmt.Sprintf(" LIMIT $%d", argPos)
-		args = append(args, limit)
-		argPos++
-	}
-	if offset > 0 {
-		query += fmt.Sprintf(" OFFSET $%d", argPos)
-		args = append(args, offset)
-	}
+	query, args := appendListPage(query, []any{projectID}, true, page, DeviceSort)
 
 	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
@@ -214,12 +411,12 @@ func (r *deviceRepository) ListByProject(ctx context.Context, projectID int, lim
 	var devices []*models.Device
 	for rows.Next() {
 		var device models.Device
-		var deviceTypeJSON, regionJSON []byte
+		var deviceTypeJSON, regionJSON, annotationsJSON []byte
 		if err := rows.Scan(
 			&device.ID, &deviceTypeJSON, &regionJSON, &device.Name, &device.HostName,
 			&device.Description, &device.Change, &device.LastSyncedAt, &device.SyncStatus,
 			&device.Project, &device.DeviceConfig, &device.RttyData,
-			&device.CreatedAt, &device.UpdatedAt,
+			&device.CreatedAt, &device.UpdatedAt, &annotationsJSON,
 		); err != nil {
 			return nil, fmt.Errorf("scan device: %w", err)
 		}
@@ -230,14 +427,20 @@ func (r *deviceRepository) ListByProject(ctx context.Context, projectID int, lim
 		if err := json.Unmarshal(regionJSON, &device.Region); err != nil {
 			return nil, fmt.Errorf("unmarshal region: %w", err)
 		}
+		if device.Annotations, err = scanAnnotations(annotationsJSON); err != nil {
+			return nil, err
+		}
 
 		devices = append(devices, &device)
 	}
 
-	return devices, rows.Err()
+	return pageRows(devices, rows.Err(), page)
 }
 
 func (r *deviceRepository) CountByProject(ctx context.Context, projectID int) (int, error) {
+	ctx, span := tracing.Repository(ctx, "deviceRepository.CountByProject")
+	defer span.End()
+
 	var count int
 	err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM devices WHERE project = $1", projectID).Scan(&count)
 	if err != nil {
@@ -246,40 +449,15 @@ func (r *deviceRepository) CountByProject(ctx context.Context, projectID int) (i
 	return count, nil
 }
 
-func (r *deviceRepository) ListWithFilters(ctx context.Context, filters DeviceFilters, limit int, offset int) ([]*models.Device, error) {
-	query := "SELECT id, device_type, region, name, host_name, description, change, last_synced_at, sync_status, project, device_config, rtty_data, created_at, updated_at FROM devices WHERE 1=1"
-	var args []any
-	argIndex := 1
-
-	if filters.ProjectID != nil {
-		query += fmt.Sprintf(" AND project = $%d", argIndex)
-		args = append(args, *filters.ProjectID)
-		argIndex++
-	}
-
-	if filters.City != nil {
-		// Search for city in the device_config JSONB field
-		query += fmt.Sprintf(" AND device_config->>'city' = $%d", argIndex)
-		args = append(args, *filters.City)
-		argIndex++
-	}
-
-	if filters.Region != nil {
-		// Filter by region code in the region JSONB field
-		query += fmt.Sprintf(" AND region->>'code' = $%d", argIndex)
-		args = append(args, *filters.Region)
-		argIndex++
-	}
-
-	if filters.DeviceType != nil {
-		// Search for device_type in the device_type JSONB field
-		query += fmt.Sprintf(" AND device_type::text LIKE $%d", argIndex)
-		args = append(args, "%"+*filters.DeviceType+"%")
-		argIndex++
-	}
+func (r *deviceRepository) ListWithFilters(ctx context.Context, filters DeviceFilters, page interfaces.ListPage) ([]*models.Device, error) {
+	ctx, span := tracing.Repository(ctx, "deviceRepository.ListWithFilters")
+	defer span.End()
 
-	query += fmt.Sprintf(" ORDER BY created_at DESC LIMIT $%d OFFSET $%d", argIndex, argIndex+1)
-	args = append(args, limit, offset)
+	query := "SELECT id, device_type, region, name, host_name, description, change, last_synced_at, sync_status, project, device_config, rtty_data, created_at, updated_at, " +
+		"(SELECT to_jsonb(a) FROM device_annotations a WHERE a.host_name = devices.host_name) AS annotations FROM devices WHERE 1=1"
+	clause, args, _ := buildDeviceFilterClause(filters, nil, 1)
+	query += clause
+	query, args = appendListPage(query, args, true, page, DeviceSort)
 
 	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
@@ -290,12 +468,12 @@ func (r *deviceRepository) ListWithFilters(ctx context.Context, filters DeviceFi
 	var devices []*models.Device
 	for rows.Next() {
 		var device models.Device
-		var deviceTypeJSON, regionJSON []byte
+		var deviceTypeJSON, regionJSON, annotationsJSON []byte
 		if err := rows.Scan(
 			&device.ID, &deviceTypeJSON, &regionJSON, &device.Name, &device.HostName,
 			&device.Description, &device.Change, &device.LastSyncedAt, &device.SyncStatus,
 			&device.Project, &device.DeviceConfig, &device.RttyData,
-			&device.CreatedAt, &device.UpdatedAt,
+			&device.CreatedAt, &device.UpdatedAt, &annotationsJSON,
 		); err != nil {
 			return nil, fmt.Errorf("scan device: %w", err)
 		}
@@ -306,41 +484,23 @@ func (r *deviceRepository) ListWithFilters(ctx context.Context, filters DeviceFi
 		if err := json.Unmarshal(regionJSON, &device.Region); err != nil {
 			return nil, fmt.Errorf("unmarshal region: %w", err)
 		}
+		if device.Annotations, err = scanAnnotations(annotationsJSON); err != nil {
+			return nil, err
+		}
 
 		devices = append(devices, &device)
 	}
 
-	return devices, nil
+	return pageRows(devices, rows.Err(), page)
 }
 
 func (r *deviceRepository) CountWithFilters(ctx context.Context, filters DeviceFilters) (int, error) {
-	query := "SELECT COUNT(*) FROM devices WHERE 1=1"
-	var args []any
-	argIndex := 1
-
-	if filters.ProjectID != nil {
-		query += fmt.Sprintf(" AND project = $%d", argIndex)
-		args = append(args, *filters.ProjectID)
-		argIndex++
-	}
-
-	if filters.City != nil {
-		query += fmt.Sprintf(" AND device_config->>'city' = $%d", argIndex)
-		args = append(args, *filters.City)
-		argIndex++
-	}
-
-	if filters.Region != nil {
-		query += fmt.Sprintf(" AND region->>'code' = $%d", argIndex)
-		args = append(args, *filters.Region)
-		argIndex++
-	}
+	ctx, span := tracing.Repository(ctx, "deviceRepository.CountWithFilters")
+	defer span.End()
 
-	if filters.DeviceType != nil {
-		query += fmt.Sprintf(" AND device_type::text LIKE $%d", argIndex)
-		args = append(args, "%"+*filters.DeviceType+"%")
-		argIndex++
-	}
+	query := "SELECT COUNT(*) FROM devices WHERE 1=1"
+	clause, args, _ := buildDeviceFilterClause(filters, nil, 1)
+	query += clause
 
 	var count int
 	err := r.db.QueryRowContext(ctx, query, args...).Scan(&count)
@@ -350,7 +510,10 @@ func (r *deviceRepository) CountWithFilters(ctx context.Context, filters DeviceF
 	return count, nil
 }
 
-func (r *deviceRepository) CountByRegion(ctx context.Context, city *string) ([]RegionDeviceCount, error) {
+func (r *deviceRepository) CountByRegion(ctx context.Context, filters DeviceFilters) ([]RegionDeviceCount, error) {
+	ctx, span := tracing.Repository(ctx, "deviceRepository.CountByRegion")
+	defer span.End()
+
 	query := `
 		SELECT
 			NULLIF(device_config->>'city', '') AS city,
@@ -359,14 +522,8 @@ func (r *deviceRepository) CountByRegion(ctx context.Context, city *string) ([]R
 		FROM devices
 		WHERE 1=1
 	`
-
-	var args []any
-	argIndex := 1
-	if city != nil {
-		query += fmt.Sprintf(" AND device_config->>'city' = $%d", argIndex)
-		args = append(args, *city)
-		argIndex++
-	}
+	clause, args, _ := buildDeviceFilterClause(filters, nil, 1)
+	query += clause
 
 	query += " GROUP BY 1, 2 ORDER BY device_count DESC"
 
@@ -395,3 +552,91 @@ func (r *deviceRepository) CountByRegion(ctx context.Context, city *string) ([]R
 
 	return out, nil
 }
+
+func (r *deviceRepository) ListSyncMarkers(ctx context.Context) (map[string]*time.Time, error) {
+	ctx, span := tracing.Repository(ctx, "deviceRepository.ListSyncMarkers")
+	defer span.End()
+
+	rows, err := r.db.QueryContext(ctx, "SELECT host_name, last_synced_at FROM devices")
+	if err != nil {
+		return nil, fmt.Errorf("list device sync markers: %w", err)
+	}
+	defer rows.Close()
+
+	markers := map[string]*time.Time{}
+	for rows.Next() {
+		var hostName string
+		var lastSynced sql.NullTime
+		if err := rows.Scan(&hostName, &lastSynced); err != nil {
+			return nil, fmt.Errorf("scan device sync marker: %w", err)
+		}
+		markers[hostName] = nil
+		if lastSynced.Valid {
+			markers[hostName] = &lastSynced.Time
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows device sync markers: %w", err)
+	}
+	return markers, nil
+}
+
+// DeviceFacetFields maps each facet of the device list to the value it groups by.
+var DeviceFacetFields = map[string]string{
+	"sync_status": "COALESCE(devices.sync_status, '')",
+	"device_type": "COALESCE(devices.device_type->>'code', '')",
+	"region":      "COALESCE(devices.region->>'code', '')",
+	"production":  "COALESCE(devices.region->>'production', 'false')",
+	"is_transit":  "COALESCE(devices.region->>'is_transit', 'false')",
+	"change":      "devices.change::text",
+}
+
+// Facets counts every facet in one query, with a grouping set per facet. GROUPING()
+// tells which facet a row counts.
+func (r *deviceRepository) Facets(ctx context.Context, filters DeviceFilters) (models.DeviceFacets, error) {
+	ctx, span := tracing.Repository(ctx, "deviceRepository.Facets")
+	defer span.End()
+
+	names := slices.Sorted(maps.Keys(DeviceFacetFields))
+	var facetCase, valueCase, sets strings.Builder
+	for i, name := range names {
+		expr := DeviceFacetFields[name]
+		fmt.Fprintf(&facetCase, " WHEN GROUPING(%s) = 0 THEN '%s'", expr, name)
+		fmt.Fprintf(&valueCase, " WHEN GROUPING(%s) = 0 THEN %s", expr, expr)
+		if i > 0 {
+			sets.WriteString(", ")
+		}
+		fmt.Fprintf(&sets, "(%s)", expr)
+	}
+	clause, args, _ := buildDeviceFilterClause(filters, nil, 1)
+	query := fmt.Sprintf(`
+		SELECT CASE%s END AS facet, CASE%s END AS value, COUNT(*)::int AS device_count
+		FROM devices
+		WHERE 1=1%s
+		GROUP BY GROUPING SETS (%s)
+		ORDER BY facet, device_count DESC, value
+	`, facetCase.String(), valueCase.String(), clause, sets.String())
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("count device facets: %w", err)
+	}
+	defer rows.Close()
+
+	facets := models.DeviceFacets{}
+	for _, name := range names {
+		facets[name] = []models.FacetCount{}
+	}
+	for rows.Next() {
+		var name string
+		var c models.FacetCount
+		if err := rows.Scan(&name, &c.Value, &c.Count); err != nil {
+			return nil, fmt.Errorf("scan device facet: %w", err)
+		}
+		facets[name] = append(facets[name], c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows device facets: %w", err)
+	}
+	return facets, nil
+}
@@ -0,0 +1,45 @@
+package repository
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"scm/internal/models"
+)
+
+func TestDeviceFacetsRunsOneQuery(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	city := "berlin"
+	mock.ExpectQuery(`GROUP BY GROUPING SETS \(\(devices.change::text\), `).
+		WithArgs(city).
+		WillReturnRows(sqlmock.NewRows([]string{"facet", "value", "device_count"}).
+			AddRow("device_type", "kiosk", 3).
+			AddRow("device_type", "totem", 1).
+			AddRow("sync_status", "synced", 4))
+
+	facets, err := NewDeviceRepository(db).Facets(context.Background(), DeviceFilters{City: &city})
+	if err != nil {
+		t.Fatalf("Facets: %v", err)
+	}
+	want := models.DeviceFacets{
+		"change":      {},
+		"device_type": {{Value: "kiosk", Count: 3}, {Value: "totem", Count: 1}},
+		"is_transit":  {},
+		"production":  {},
+		"region":      {},
+		"sync_status": {{Value: "synced", Count: 4}},
+	}
+	if !reflect.DeepEqual(facets, want) {
+		t.Fatalf("Facets = %+v, want %+v", facets, want)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
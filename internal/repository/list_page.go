@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"scm/internal/interfaces"
+)
+
+// appendListPage adds page's keyset condition, ORDER BY, LIMIT and OFFSET to query, whose
+// arguments so far are args. hasWhere reports whether query already has a WHERE clause.
+// A page without a sort uses sortable's default. Backward pages are read in reverse
+// order, so List methods flip their rows back before returning them.
+func appendListPage(query string, args []any, hasWhere bool, page interfaces.ListPage, sortable interfaces.Sortable) (string, []any) {
+	keys := page.Sort
+	if len(keys) == 0 {
+		keys, _ = sortable.Parse("")
+	}
+
+	backward := page.Backward()
+	position := page.After
+	if backward {
+		position = page.Before
+	}
+
+	if len(position) == len(keys) {
+		base := len(args)
+		args = append(args, position...)
+
+		// (a > $1) OR (a = $1 AND b > $2) OR ..., with the comparison reversed for
+		// descending keys and again for backward pages.
+		terms := make([]string, len(keys))
+		for i, k := range keys {
+			var parts []string
+			for j := 0; j < i; j++ {
+				parts = append(parts, fmt.Sprintf("%s = $%d", keys[j].Column, base+j+1))
+			}
+			op := ">"
+			if k.Desc != backward {
+				op = "<"
+			}
+			parts = append(parts, fmt.Sprintf("%s %s $%d", k.Column, op, base+i+1))
+			terms[i] = "(" + strings.Join(parts, " AND ") + ")"
+		}
+
+		if hasWhere {
+			query += " AND "
+		} else {
+			query += " WHERE "
+		}
+		query += "(" + strings.Join(terms, " OR ") + ")"
+	}
+
+	order := make([]string, len(keys))
+	for i, k := range keys {
+		dir := "ASC"
+		if k.Desc != backward {
+			dir = "DESC"
+		}
+		order[i] = k.Column + " " + dir
+	}
+	query += " ORDER BY " + strings.Join(order, ", ")
+
+	if page.Limit > 0 {
+		args = append(args, page.Limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+	if page.Offset > 0 {
+		args = append(args, page.Offset)
+		query += fmt.Sprintf(" OFFSET $%d", len(args))
+	}
+	return query, args
+}
+
+// pageRows finishes a List method: it returns err if iterating the rows failed and
+// otherwise puts the rows of a backward page back into the requested order.
+func pageRows[T any](items []T, err error, page interfaces.ListPage) ([]T, error) {
+	if err != nil {
+		return nil, err
+	}
+	if page.Backward() {
+		slices.Reverse(items)
+	}
+	return items, nil
+}
@@ -0,0 +1,275 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"scm/internal/interfaces"
+	"scm/internal/models"
+	"scm/internal/repository"
+)
+
+type advertiserRepository struct {
+	s *Store
+}
+
+func NewAdvertiserRepository(s *Store) interfaces.AdvertiserRepository {
+	return &advertiserRepository{s: s}
+}
+
+// nameTaken reports whether a live advertiser other than skipID is called name.
+func (r *advertiserRepository) nameTaken(name, skipID string) bool {
+	for id, a := range r.s.advertisers {
+		if id != skipID && a.DeletedAt == nil && a.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *advertiserRepository) Create(ctx context.Context, advertiser *models.Advertiser) error {
+	createdBy, err := parseUUID(advertiser.CreatedBy)
+	if err != nil {
+		return fmt.Errorf("failed to create advertiser: %w", err)
+	}
+
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.users[createdBy]; !ok {
+		return fmt.Errorf("failed to create advertiser: %w", foreignKeyViolation("advertisers", "advertisers_created_by_fkey"))
+	}
+	if r.nameTaken(advertiser.Name, "") {
+		return fmt.Errorf("failed to create advertiser: %w", uniqueViolation("idx_advertisers_name_live"))
+	}
+
+	now := r.s.now()
+	stored := &models.Advertiser{
+		ID:        uuid.New().String(),
+		Name:      advertiser.Name,
+		Email:     advertiser.Email,
+		CreatedBy: createdBy,
+		CreatedAt: now,
+		UpdatedAt: now,
+		Version:   1,
+	}
+	r.s.advertisers[stored.ID] = stored
+
+	advertiser.ID = stored.ID
+	advertiser.CreatedBy = stored.CreatedBy
+	advertiser.CreatedAt = stored.CreatedAt
+	advertiser.UpdatedAt = stored.UpdatedAt
+	advertiser.Version = stored.Version
+	return nil
+}
+
+func (r *advertiserRepository) GetByID(ctx context.Context, id string) (*models.Advertiser, error) {
+	key, err := parseUUID(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get advertiser: %w", err)
+	}
+
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	a, ok := r.s.advertisers[key]
+	if !ok || a.DeletedAt != nil {
+		return nil, sql.ErrNoRows
+	}
+	return copyAdvertiser(a), nil
+}
+
+func copyAdvertiser(a *models.Advertiser) *models.Advertiser {
+	copied := *a
+	copied.DeletedAt = timePtr(a.DeletedAt)
+	return &copied
+}
+
+func (r *advertiserRepository) List(ctx context.Context, page interfaces.ListPage, includeDeleted bool) ([]models.Advertiser, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	var advertisers []models.Advertiser
+	for _, a := range r.s.advertisers {
+		if includeDeleted || a.DeletedAt == nil {
+			advertisers = append(advertisers, *copyAdvertiser(a))
+		}
+	}
+	advertisers, err := listPage(advertisers, page, repository.AdvertiserSort)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list advertisers: %w", err)
+	}
+	return advertisers, nil
+}
+
+func (r *advertiserRepository) Count(ctx context.Context, includeDeleted bool) (int, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	total := 0
+	for _, a := range r.s.advertisers {
+		if includeDeleted || a.DeletedAt == nil {
+			total++
+		}
+	}
+	return total, nil
+}
+
+func (r *advertiserRepository) Update(ctx context.Context, id string, req *models.UpdateAdvertiserRequest, version int) error {
+	if req.Name == nil && req.Email == nil {
+		return fmt.Errorf("no fields to update")
+	}
+	key, err := parseUUID(id)
+	if err != nil {
+		return fmt.Errorf("failed to update advertiser: %w", err)
+	}
+
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	a, ok := r.s.advertisers[key]
+	if !ok || a.DeletedAt != nil {
+		return fmt.Errorf("advertiser not found")
+	}
+	if version != 0 && version != a.Version {
+		return interfaces.ErrVersionConflict
+	}
+	if req.Name != nil && r.nameTaken(*req.Name, key) {
+		return fmt.Errorf("failed to update advertiser: %w", uniqueViolation("idx_advertisers_name_live"))
+	}
+
+	if req.Name != nil {
+		a.Name = *req.Name
+	}
+	if req.Email != nil {
+		a.Email = *req.Email
+	}
+	a.UpdatedAt = r.s.now()
+	a.Version++
+	return nil
+}
+
+// Delete soft-deletes an advertiser. It is blocked while the advertiser has live campaigns.
+func (r *advertiserRepository) Delete(ctx context.Context, id string) error {
+	key, err := parseUUID(id)
+	if err != nil {
+		return fmt.Errorf("failed to delete advertiser: %w", err)
+	}
+
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	var campaignCount int64
+	for _, c := range r.s.campaigns {
+		if c.AdvertiserID == key && c.DeletedAt == nil {
+			campaignCount++
+		}
+	}
+	if campaignCount > 0 {
+		return &interfaces.DeletionBlockedError{
+			Resource:   "advertiser",
+			References: map[string]int64{"campaigns": campaignCount},
+		}
+	}
+
+	a, ok := r.s.advertisers[key]
+	if !ok || a.DeletedAt != nil {
+		return sql.ErrNoRows
+	}
+	now := r.s.now()
+	a.DeletedAt = &now
+	a.Version++
+	return nil
+}
+
+// Restore undeletes a soft-deleted advertiser. It returns sql.ErrNoRows when no
+// deleted advertiser has that ID.
+func (r *advertiserRepository) Restore(ctx context.Context, id string) error {
+	key, err := parseUUID(id)
+	if err != nil {
+		return fmt.Errorf("failed to restore advertiser: %w", err)
+	}
+
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	a, ok := r.s.advertisers[key]
+	if !ok || a.DeletedAt == nil {
+		return sql.ErrNoRows
+	}
+	if r.nameTaken(a.Name, key) {
+		return fmt.Errorf("failed to restore advertiser: %w", uniqueViolation("idx_advertisers_name_live"))
+	}
+	a.DeletedAt = nil
+	a.Version++
+	return nil
+}
+
+// PurgeDeletedBefore permanently removes advertisers soft-deleted before cutoff, together
+// with their (already deleted) campaigns and creatives, and returns the creatives' stored
+// object keys.
+func (r *advertiserRepository) PurgeDeletedBefore(ctx context.Context, cutoff time.Time) (int64, []string, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	var purged int64
+	keys := []string{}
+	for id, a := range r.s.advertisers {
+		if a.DeletedAt != nil && a.DeletedAt.Before(cutoff) {
+			keys = append(keys, r.s.advertiserDependencies(id).StoredObjects...)
+			r.s.deleteAdvertiser(id)
+			purged++
+		}
+	}
+	return purged, keys, nil
+}
+
+// deleteAdvertiser removes the advertiser with its campaigns and creatives, as the
+// ON DELETE CASCADE foreign keys do.
+func (s *Store) deleteAdvertiser(id string) {
+	for campaignID, c := range s.campaigns {
+		if c.AdvertiserID == id {
+			s.deleteCampaign(campaignID)
+		}
+	}
+	delete(s.advertisers, id)
+}
+
+// Dependencies lists the campaigns, creatives and stored objects (including soft-deleted
+// ones) that a cascade delete of the advertiser would remove.
+func (r *advertiserRepository) Dependencies(ctx context.Context, id string) (*models.DeletionDependencies, error) {
+	key, err := parseUUID(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get advertiser: %w", err)
+	}
+
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.advertisers[key]; !ok {
+		return nil, sql.ErrNoRows
+	}
+	return r.s.advertiserDependencies(key), nil
+}
+
+// DeleteCascade permanently deletes the advertiser with its campaigns and creatives and
+// returns what was removed.
+func (r *advertiserRepository) DeleteCascade(ctx context.Context, id string) (*models.DeletionDependencies, error) {
+	key, err := parseUUID(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock advertiser: %w", err)
+	}
+
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.advertisers[key]; !ok {
+		return nil, sql.ErrNoRows
+	}
+	deps := r.s.advertiserDependencies(key)
+	r.s.deleteAdvertiser(key)
+	return deps, nil
+}
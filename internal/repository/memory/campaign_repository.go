@@ -0,0 +1,398 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"scm/internal/interfaces"
+	"scm/internal/models"
+	"scm/internal/repository"
+)
+
+type campaignRepository struct {
+	s *Store
+}
+
+func NewCampaignRepository(s *Store) interfaces.CampaignRepository {
+	return &campaignRepository{s: s}
+}
+
+// decimal rounds v to the two decimal places of the campaigns' DECIMAL columns. Like
+// PostgreSQL it rounds the decimal text pq sends, half away from zero, so 1.005 becomes
+// 1.01 even though the float is just below it.
+func decimal(v float64) float64 {
+	whole, frac, _ := strings.Cut(strconv.FormatFloat(math.Abs(v), 'f', -1, 64), ".")
+	frac += "000"
+	cents, _ := strconv.ParseInt(whole+frac[:2], 10, 64)
+	if frac[2] >= '5' {
+		cents++
+	}
+	return math.Copysign(float64(cents)/100, v)
+}
+
+// stored returns the row campaign is saved as, or the error PostgreSQL would report
+// for it.
+func (r *campaignRepository) stored(campaign *models.Campaign) (*models.Campaign, error) {
+	advertiserID, err := parseUUID(campaign.AdvertiserID)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := r.s.advertisers[advertiserID]; !ok {
+		return nil, foreignKeyViolation("campaigns", "campaigns_advertiser_id_fkey")
+	}
+
+	row := *campaign
+	row.AdvertiserID = advertiserID
+	row.Cities = stringArray(campaign.Cities)
+	row.StartDate = campaign.StartDate.UTC().Truncate(time.Microsecond)
+	row.EndDate = campaign.EndDate.UTC().Truncate(time.Microsecond)
+	row.Budget = decimal(campaign.Budget)
+	row.Spent = decimal(campaign.Spent)
+	row.CTR = decimal(campaign.CTR)
+	return &row, nil
+}
+
+func (r *campaignRepository) Create(ctx context.Context, campaign *models.Campaign) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	row, err := r.stored(campaign)
+	if err != nil {
+		return err
+	}
+	now := r.s.now()
+	row.ID = uuid.New().String()
+	row.CreatedAt = now
+	row.UpdatedAt = now
+	row.DeletedAt = nil
+	row.Version = 1
+	r.s.campaigns[row.ID] = row
+
+	campaign.ID = row.ID
+	campaign.CreatedAt = row.CreatedAt
+	campaign.UpdatedAt = row.UpdatedAt
+	campaign.Version = row.Version
+	return nil
+}
+
+func copyCampaign(c *models.Campaign) *models.Campaign {
+	copied := *c
+	copied.Cities = stringArray(c.Cities)
+	copied.DeletedAt = timePtr(c.DeletedAt)
+	return &copied
+}
+
+func (r *campaignRepository) GetByID(ctx context.Context, id string) (*models.Campaign, error) {
+	key, err := parseUUID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	c, ok := r.s.campaigns[key]
+	if !ok || c.DeletedAt != nil {
+		return nil, sql.ErrNoRows
+	}
+	return copyCampaign(c), nil
+}
+
+// matching returns the campaigns filter selects, ignoring its page.
+func (r *campaignRepository) matching(filter interfaces.CampaignFilter) ([]*models.Campaign, error) {
+	advertiserID := filter.AdvertiserID
+	if advertiserID != "" {
+		var err error
+		if advertiserID, err = parseUUID(advertiserID); err != nil {
+			return nil, err
+		}
+	}
+
+	var campaigns []*models.Campaign
+	for _, c := range r.s.campaigns {
+		switch {
+		case !filter.IncludeDeleted && c.DeletedAt != nil:
+		case advertiserID != "" && c.AdvertiserID != advertiserID:
+		case filter.Status != "" && string(c.Status) != filter.Status:
+		case !filter.StartDate.IsZero() && c.StartDate.Before(filter.StartDate):
+		case !filter.EndDate.IsZero() && c.EndDate.After(filter.EndDate):
+		default:
+			campaigns = append(campaigns, copyCampaign(c))
+		}
+	}
+	return campaigns, nil
+}
+
+func (r *campaignRepository) Summary(ctx context.Context, filter interfaces.CampaignFilter) (*models.CampaignSummary, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	campaigns, err := r.matching(filter)
+	if err != nil {
+		return nil, err
+	}
+	var summary models.CampaignSummary
+	for _, c := range campaigns {
+		if c.Status == models.CampaignStatusActive {
+			summary.ActiveCampaignCount++
+		}
+		summary.TotalBudget += c.Budget
+		summary.TotalImpression += int64(c.Impressions)
+	}
+	summary.TotalBudget = decimal(summary.TotalBudget)
+	return &summary, nil
+}
+
+// sameDay reports whether a and b fall on the same date in timeZone.
+func sameDay(a, b time.Time, loc *time.Location) bool {
+	ay, am, ad := a.In(loc).Date()
+	by, bm, bd := b.In(loc).Date()
+	return ay == by && am == bm && ad == bd
+}
+
+func dateIn(t time.Time, loc *time.Location) time.Time {
+	y, m, d := t.In(loc).Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+func (r *campaignRepository) ActivateScheduledStartingOn(ctx context.Context, startDate time.Time, scheduledStatus string, timeZone string) ([]string, error) {
+	if scheduledStatus == "" {
+		scheduledStatus = "scheduled"
+	}
+	if timeZone == "" {
+		timeZone = "UTC"
+	}
+	loc, err := time.LoadLocation(timeZone)
+	if err != nil {
+		return nil, fmt.Errorf("time zone %q not recognized", timeZone)
+	}
+
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	var ids []string
+	now := r.s.now()
+	for _, c := range r.s.campaigns {
+		if string(c.Status) == scheduledStatus && c.DeletedAt == nil && sameDay(c.StartDate, startDate, loc) {
+			c.Status = models.CampaignStatusActive
+			c.UpdatedAt = now
+			c.Version++
+			ids = append(ids, c.ID)
+		}
+	}
+	slices.Sort(ids)
+	return ids, nil
+}
+
+func (r *campaignRepository) CompleteActiveEndedBefore(ctx context.Context, now time.Time, activeStatus string, completedStatus string, timeZone string) ([]string, error) {
+	if activeStatus == "" {
+		activeStatus = "active"
+	}
+	if completedStatus == "" {
+		completedStatus = "completed"
+	}
+	if timeZone == "" {
+		timeZone = "UTC"
+	}
+	loc, err := time.LoadLocation(timeZone)
+	if err != nil {
+		return nil, fmt.Errorf("time zone %q not recognized", timeZone)
+	}
+
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	var ids []string
+	updatedAt := r.s.now()
+	for _, c := range r.s.campaigns {
+		if string(c.Status) == activeStatus && c.DeletedAt == nil && dateIn(c.EndDate, loc).Before(dateIn(now, loc)) {
+			c.Status = models.CampaignStatus(completedStatus)
+			c.UpdatedAt = updatedAt
+			c.Version++
+			ids = append(ids, c.ID)
+		}
+	}
+	slices.Sort(ids)
+	return ids, nil
+}
+
+func (r *campaignRepository) Count(ctx context.Context, filter interfaces.CampaignFilter) (int, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	campaigns, err := r.matching(filter)
+	return len(campaigns), err
+}
+
+func (r *campaignRepository) List(ctx context.Context, filter interfaces.CampaignFilter) ([]*models.Campaign, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	campaigns, err := r.matching(filter)
+	if err != nil {
+		return nil, err
+	}
+	return listPage(campaigns, filter.Page, repository.CampaignSort)
+}
+
+// Update updates a campaign with the given ID
+func (r *campaignRepository) Update(ctx context.Context, id string, campaign *models.Campaign, version int) error {
+	key, err := parseUUID(id)
+	if err != nil {
+		return fmt.Errorf("failed to update campaign: %w", err)
+	}
+
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	current, ok := r.s.campaigns[key]
+	if !ok || current.DeletedAt != nil {
+		return fmt.Errorf("campaign not found")
+	}
+	if version != 0 && version != current.Version {
+		return interfaces.ErrVersionConflict
+	}
+	row, err := r.stored(campaign)
+	if err != nil {
+		return fmt.Errorf("failed to update campaign: %w", err)
+	}
+
+	row.ID = current.ID
+	row.CreatedAt = current.CreatedAt
+	row.DeletedAt = nil
+	row.UpdatedAt = r.s.now()
+	row.Version = current.Version + 1
+	r.s.campaigns[key] = row
+
+	campaign.UpdatedAt = row.UpdatedAt
+	campaign.Version = row.Version
+	return nil
+}
+
+// Delete soft-deletes a campaign by ID. It is blocked while the campaign has live creatives.
+func (r *campaignRepository) Delete(ctx context.Context, id string) error {
+	key, err := parseUUID(id)
+	if err != nil {
+		return err
+	}
+
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	var creativeCount int64
+	for _, cr := range r.s.creatives {
+		if cr.CampaignID == key && cr.DeletedAt == nil {
+			creativeCount++
+		}
+	}
+	if creativeCount > 0 {
+		return &interfaces.DeletionBlockedError{
+			Resource:   "campaign",
+			References: map[string]int64{"creatives": creativeCount},
+		}
+	}
+
+	c, ok := r.s.campaigns[key]
+	if !ok || c.DeletedAt != nil {
+		return sql.ErrNoRows
+	}
+	now := r.s.now()
+	c.DeletedAt = &now
+	c.Version++
+	return nil
+}
+
+// Restore undeletes a soft-deleted campaign. It returns sql.ErrNoRows when no deleted
+// campaign has that ID, and RestoreBlockedError while its advertiser is still deleted.
+func (r *campaignRepository) Restore(ctx context.Context, id string) error {
+	key, err := parseUUID(id)
+	if err != nil {
+		return err
+	}
+
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	c, ok := r.s.campaigns[key]
+	if !ok || c.DeletedAt == nil {
+		return sql.ErrNoRows
+	}
+	if r.s.advertisers[c.AdvertiserID].DeletedAt != nil {
+		return &interfaces.RestoreBlockedError{Resource: "campaign", Parent: "advertiser"}
+	}
+	c.DeletedAt = nil
+	c.Version++
+	return nil
+}
+
+// PurgeDeletedBefore permanently removes campaigns soft-deleted before cutoff, together
+// with their (already deleted) creatives, and returns the creatives' stored object keys.
+func (r *campaignRepository) PurgeDeletedBefore(ctx context.Context, cutoff time.Time) (int64, []string, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	var purged int64
+	keys := []string{}
+	for id, c := range r.s.campaigns {
+		if c.DeletedAt != nil && c.DeletedAt.Before(cutoff) {
+			keys = append(keys, r.s.campaignDependencies(id).StoredObjects...)
+			r.s.deleteCampaign(id)
+			purged++
+		}
+	}
+	return purged, keys, nil
+}
+
+// deleteCampaign removes the campaign with its creatives, as the ON DELETE CASCADE
+// foreign key does.
+func (s *Store) deleteCampaign(id string) {
+	for creativeID, cr := range s.creatives {
+		if cr.CampaignID == id {
+			delete(s.creatives, creativeID)
+		}
+	}
+	delete(s.campaigns, id)
+}
+
+// Dependencies lists the creatives and stored objects (including soft-deleted ones) that a
+// cascade delete of the campaign would remove.
+func (r *campaignRepository) Dependencies(ctx context.Context, id string) (*models.DeletionDependencies, error) {
+	key, err := parseUUID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.campaigns[key]; !ok {
+		return nil, sql.ErrNoRows
+	}
+	return r.s.campaignDependencies(key), nil
+}
+
+// DeleteCascade permanently deletes the campaign and its creatives and returns what was
+// removed. Stored objects are left for the caller to delete.
+func (r *campaignRepository) DeleteCascade(ctx context.Context, id string) (*models.DeletionDependencies, error) {
+	key, err := parseUUID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.campaigns[key]; !ok {
+		return nil, sql.ErrNoRows
+	}
+	deps := r.s.campaignDependencies(key)
+	r.s.deleteCampaign(key)
+	return deps, nil
+}
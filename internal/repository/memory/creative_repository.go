@@ -0,0 +1,329 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"scm/internal/interfaces"
+	"scm/internal/models"
+	"scm/internal/repository"
+)
+
+type creativeRepository struct {
+	s *Store
+}
+
+func NewCreativeRepository(s *Store) repository.CreativeRepository {
+	return &creativeRepository{s: s}
+}
+
+func (r *creativeRepository) Create(ctx context.Context, creative *models.Creative) error {
+	id, err := parseUUID(creative.ID)
+	if err != nil {
+		return err
+	}
+	campaignID, err := parseUUID(creative.CampaignID)
+	if err != nil {
+		return err
+	}
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.creatives[id]; ok {
+		return uniqueViolation("creatives_pkey")
+	}
+	if _, ok := r.s.campaigns[campaignID]; !ok {
+		return foreignKeyViolation("creatives", "creatives_campaign_id_fkey")
+	}
+
+	row := copyCreative(creative)
+	row.ID = id
+	row.CampaignID = campaignID
+	row.UploadedAt = creative.UploadedAt.UTC().Truncate(time.Microsecond)
+	row.DeletedAt = nil
+	row.Version = 1
+	r.s.creatives[id] = row
+
+	creative.UploadedAt = row.UploadedAt
+	creative.Version = row.Version
+	return nil
+}
+
+func copyCreative(c *models.Creative) *models.Creative {
+	copied := *c
+	copied.SelectedDays = stringArray(c.SelectedDays)
+	copied.TimeSlots = stringArray(c.TimeSlots)
+	copied.Devices = stringArray(c.Devices)
+	copied.DeletedAt = timePtr(c.DeletedAt)
+	return &copied
+}
+
+func (r *creativeRepository) GetByID(ctx context.Context, id string) (*models.Creative, error) {
+	key, err := parseUUID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	c, ok := r.s.creatives[key]
+	if !ok || c.DeletedAt != nil {
+		return nil, sql.ErrNoRows
+	}
+	return copyCreative(c), nil
+}
+
+// matching returns copies of the creatives match accepts.
+func (r *creativeRepository) matching(match func(c *models.Creative) (bool, error)) ([]*models.Creative, error) {
+	var creatives []*models.Creative
+	for _, c := range r.s.creatives {
+		ok, err := match(c)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			creatives = append(creatives, copyCreative(c))
+		}
+	}
+	return creatives, nil
+}
+
+func (r *creativeRepository) ListAll(ctx context.Context, page interfaces.ListPage, includeDeleted bool) ([]*models.Creative, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	creatives, _ := r.matching(func(c *models.Creative) (bool, error) {
+		return includeDeleted || c.DeletedAt == nil, nil
+	})
+	return listPage(creatives, page, repository.CreativeSort)
+}
+
+func (r *creativeRepository) CountAll(ctx context.Context, includeDeleted bool) (int, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	creatives, _ := r.matching(func(c *models.Creative) (bool, error) {
+		return includeDeleted || c.DeletedAt == nil, nil
+	})
+	return len(creatives), nil
+}
+
+func (r *creativeRepository) byCampaign(campaignID string, includeDeleted bool) ([]*models.Creative, error) {
+	key, err := parseUUID(campaignID)
+	if err != nil {
+		return nil, err
+	}
+	return r.matching(func(c *models.Creative) (bool, error) {
+		return c.CampaignID == key && (includeDeleted || c.DeletedAt == nil), nil
+	})
+}
+
+func (r *creativeRepository) ListByCampaign(ctx context.Context, campaignID string, page interfaces.ListPage, includeDeleted bool) ([]*models.Creative, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	creatives, err := r.byCampaign(campaignID, includeDeleted)
+	if err != nil {
+		return nil, err
+	}
+	return listPage(creatives, page, repository.CreativeSort)
+}
+
+func (r *creativeRepository) CountByCampaign(ctx context.Context, campaignID string, includeDeleted bool) (int, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	creatives, err := r.byCampaign(campaignID, includeDeleted)
+	return len(creatives), err
+}
+
+// parseClock parses a time of day the way a ::time cast does.
+func parseClock(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	for _, layout := range []string{"15:04:05", "15:04"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid input syntax for type time: %q", s)
+}
+
+// scheduledOn reports whether c is scheduled on device and, with activeNow, for the
+// weekday and time of now.
+func scheduledOn(c *models.Creative, device string, activeNow bool, now time.Time) (bool, error) {
+	if c.DeletedAt != nil {
+		return false, nil
+	}
+	if !slices.ContainsFunc(c.Devices, func(d string) bool {
+		return strings.EqualFold(strings.TrimSpace(d), device)
+	}) {
+		return false, nil
+	}
+	if !activeNow {
+		return true, nil
+	}
+
+	day := now.Weekday().String()
+	if !slices.ContainsFunc(c.SelectedDays, func(d string) bool {
+		return strings.EqualFold(strings.TrimSpace(d), day)
+	}) {
+		return false, nil
+	}
+
+	tm := now.Format("15:04")
+	clock, _ := parseClock(tm)
+	for _, ts := range c.TimeSlots {
+		parts := strings.Split(ts, "-")
+		if len(parts) == 1 {
+			if strings.EqualFold(strings.TrimSpace(ts), tm) {
+				return true, nil
+			}
+			continue
+		}
+		start, err := parseClock(parts[0])
+		if err != nil {
+			return false, err
+		}
+		end, err := parseClock(parts[1])
+		if err != nil {
+			return false, err
+		}
+		if !clock.Before(start) && !clock.After(end) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *creativeRepository) ListByDevice(ctx context.Context, device string, activeNow bool, now time.Time, page interfaces.ListPage) ([]*models.Creative, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	creatives, err := r.matching(func(c *models.Creative) (bool, error) {
+		return scheduledOn(c, device, activeNow, now)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return listPage(creatives, page, repository.CreativeSort)
+}
+
+func (r *creativeRepository) CountByDevice(ctx context.Context, device string, activeNow bool, now time.Time) (int, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	creatives, err := r.matching(func(c *models.Creative) (bool, error) {
+		return scheduledOn(c, device, activeNow, now)
+	})
+	return len(creatives), err
+}
+
+func (r *creativeRepository) Update(ctx context.Context, id string, req *models.UpdateCreativeRequest, version int) error {
+	key, err := parseUUID(id)
+	if err != nil {
+		return err
+	}
+
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	c, ok := r.s.creatives[key]
+	if !ok || c.DeletedAt != nil {
+		return sql.ErrNoRows
+	}
+	if version != 0 && version != c.Version {
+		return interfaces.ErrVersionConflict
+	}
+
+	if req.Name != nil {
+		c.Name = *req.Name
+	}
+	if req.Type != nil {
+		c.Type = *req.Type
+	}
+	if req.URL != nil {
+		c.URL = *req.URL
+	}
+	if req.FilePath != nil {
+		c.FilePath = *req.FilePath
+	}
+	if req.Size != nil {
+		c.Size = *req.Size
+	}
+	if req.SelectedDays != nil {
+		c.SelectedDays = stringArray(*req.SelectedDays)
+	}
+	if req.TimeSlots != nil {
+		c.TimeSlots = stringArray(*req.TimeSlots)
+	}
+	if req.Devices != nil {
+		c.Devices = stringArray(*req.Devices)
+	}
+	c.Version++
+	return nil
+}
+
+func (r *creativeRepository) Delete(ctx context.Context, id string) error {
+	key, err := parseUUID(id)
+	if err != nil {
+		return err
+	}
+
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	c, ok := r.s.creatives[key]
+	if !ok || c.DeletedAt != nil {
+		return sql.ErrNoRows
+	}
+	now := r.s.now()
+	c.DeletedAt = &now
+	c.Version++
+	return nil
+}
+
+// Restore undeletes a soft-deleted creative. It returns sql.ErrNoRows when no deleted
+// creative has that ID, and RestoreBlockedError while its campaign is still deleted.
+func (r *creativeRepository) Restore(ctx context.Context, id string) error {
+	key, err := parseUUID(id)
+	if err != nil {
+		return err
+	}
+
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	c, ok := r.s.creatives[key]
+	if !ok || c.DeletedAt == nil {
+		return sql.ErrNoRows
+	}
+	if r.s.campaigns[c.CampaignID].DeletedAt != nil {
+		return &interfaces.RestoreBlockedError{Resource: "creative", Parent: "campaign"}
+	}
+	c.DeletedAt = nil
+	c.Version++
+	return nil
+}
+
+func (r *creativeRepository) PurgeDeletedBefore(ctx context.Context, cutoff time.Time) (int64, []string, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	var purged int64
+	keys := []string{}
+	for id, c := range r.s.creatives {
+		if c.DeletedAt != nil && c.DeletedAt.Before(cutoff) {
+			if c.FilePath != "" {
+				keys = append(keys, c.FilePath)
+			}
+			delete(r.s.creatives, id)
+			purged++
+		}
+	}
+	return purged, keys, nil
+}
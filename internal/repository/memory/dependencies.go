@@ -0,0 +1,99 @@
+package memory
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+
+	"scm/internal/models"
+)
+
+func (s *Store) advertiserDependencies(id string) *models.DeletionDependencies {
+	var campaigns []*models.Campaign
+	for _, c := range s.campaigns {
+		if c.AdvertiserID == id {
+			campaigns = append(campaigns, c)
+		}
+	}
+	slices.SortFunc(campaigns, func(a, b *models.Campaign) int { return a.CreatedAt.Compare(b.CreatedAt) })
+
+	records := []models.DependentRecord{}
+	for _, c := range campaigns {
+		records = append(records, models.DependentRecord{ID: c.ID, Name: c.Name, DeletedAt: timePtr(c.DeletedAt)})
+	}
+	creatives, keys := s.creativeDependents(func(c *models.Campaign) bool { return c.AdvertiserID == id })
+
+	return &models.DeletionDependencies{
+		Resource: "advertiser",
+		ID:       id,
+		References: map[string]int64{
+			"campaigns":      int64(len(records)),
+			"creatives":      int64(len(creatives)),
+			"stored_objects": int64(len(keys)),
+		},
+		Campaigns:     records,
+		Creatives:     creatives,
+		StoredObjects: keys,
+	}
+}
+
+func (s *Store) campaignDependencies(id string) *models.DeletionDependencies {
+	creatives, keys := s.creativeDependents(func(c *models.Campaign) bool { return c.ID == id })
+
+	return &models.DeletionDependencies{
+		Resource: "campaign",
+		ID:       id,
+		References: map[string]int64{
+			"creatives":      int64(len(creatives)),
+			"stored_objects": int64(len(keys)),
+		},
+		Creatives:     creatives,
+		StoredObjects: keys,
+	}
+}
+
+// creativeDependents returns the creatives of the campaigns match selects, oldest
+// upload first, and the object keys of their files.
+func (s *Store) creativeDependents(match func(c *models.Campaign) bool) ([]models.DependentRecord, []string) {
+	var creatives []*models.Creative
+	for _, cr := range s.creatives {
+		if c, ok := s.campaigns[cr.CampaignID]; ok && match(c) {
+			creatives = append(creatives, cr)
+		}
+	}
+	slices.SortFunc(creatives, func(a, b *models.Creative) int { return a.UploadedAt.Compare(b.UploadedAt) })
+
+	records := []models.DependentRecord{}
+	keys := []string{}
+	for _, cr := range creatives {
+		records = append(records, models.DependentRecord{ID: cr.ID, Name: cr.Name, DeletedAt: timePtr(cr.DeletedAt)})
+		if cr.FilePath != "" {
+			keys = append(keys, cr.FilePath)
+		}
+	}
+	return records, keys
+}
+
+func (s *Store) venueDependencies(id int) *models.DeletionDependencies {
+	var devices []*models.Device
+	for deviceID := range s.venueDevices[id] {
+		devices = append(devices, s.devices[deviceID])
+	}
+	slices.SortFunc(devices, func(a, b *models.Device) int { return strings.Compare(a.HostName, b.HostName) })
+
+	records := []models.DependentRecord{}
+	for _, d := range devices {
+		records = append(records, models.DependentRecord{ID: strconv.Itoa(d.ID), Name: d.HostName})
+	}
+
+	return &models.DeletionDependencies{
+		Resource: "venue",
+		ID:       strconv.Itoa(id),
+		References: map[string]int64{
+			"devices":     int64(len(records)),
+			"alert_rules": 0,
+		},
+		Devices:    records,
+		AlertRules: []models.DependentRecord{},
+	}
+}
@@ -0,0 +1,285 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+
+	"scm/internal/interfaces"
+	"scm/internal/models"
+	"scm/internal/repository"
+)
+
+type deviceRepository struct {
+	s *Store
+}
+
+func NewDeviceRepository(s *Store) repository.DeviceRepository {
+	return &deviceRepository{s: s}
+}
+
+func copyDevice(d *models.Device) *models.Device {
+	copied := *d
+	copied.DeviceType.Description = stringPtr(d.DeviceType.Description)
+	copied.Region = jsonRoundTrip(d.Region)
+	copied.LastSyncedAt = timePtr(d.LastSyncedAt)
+	copied.SyncStatus = stringPtr(d.SyncStatus)
+	copied.DeviceConfig = slices.Clone(d.DeviceConfig)
+	copied.Annotations = nil
+	return &copied
+}
+
+// jsonRoundTrip returns v as it comes back from a jsonb column, sharing no memory with it.
+func jsonRoundTrip[T any](v T) T {
+	var out T
+	raw, err := json.Marshal(v)
+	if err == nil {
+		json.Unmarshal(raw, &out)
+	}
+	return out
+}
+
+// Upsert inserts the device or, if one with its host name exists, overwrites it, ID
+// included.
+func (r *deviceRepository) Upsert(ctx context.Context, device *models.Device) error {
+	if len(device.DeviceConfig) == 0 {
+		return fmt.Errorf("upsert device: %w", notNullViolation("devices", "device_config"))
+	}
+	config, err := decodeJSON(device.DeviceConfig)
+	if err != nil {
+		return fmt.Errorf("upsert device: invalid input syntax for type json: %w", err)
+	}
+
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.projects[device.Project]; !ok {
+		return fmt.Errorf("upsert device: %w", foreignKeyViolation("devices", "devices_project_fkey"))
+	}
+
+	now := r.s.now()
+	row := copyDevice(device)
+	row.DeviceConfig = json.RawMessage(jsonbText(config))
+	row.CreatedAt = now
+	row.UpdatedAt = now
+
+	current := r.s.deviceByHostName(device.HostName)
+	if current == nil {
+		if _, ok := r.s.devices[row.ID]; ok {
+			return fmt.Errorf("upsert device: %w", uniqueViolation("devices_pkey"))
+		}
+		r.s.devices[row.ID] = row
+		return nil
+	}
+
+	if current.ID != row.ID {
+		if _, ok := r.s.devices[row.ID]; ok {
+			return fmt.Errorf("upsert device: %w", uniqueViolation("devices_pkey"))
+		}
+		for _, members := range r.s.venueDevices {
+			if members[current.ID] {
+				return fmt.Errorf("upsert device: %w", foreignKeyViolation("venue_devices", "venue_devices_device_id_fkey"))
+			}
+		}
+	}
+	row.CreatedAt = current.CreatedAt
+	delete(r.s.devices, current.ID)
+	r.s.devices[row.ID] = row
+	return nil
+}
+
+func (s *Store) deviceByHostName(hostName string) *models.Device {
+	for _, d := range s.devices {
+		if d.HostName == hostName {
+			return d
+		}
+	}
+	return nil
+}
+
+// deviceList returns the stored devices in no particular order.
+func (s *Store) deviceList() []*models.Device {
+	devices := make([]*models.Device, 0, len(s.devices))
+	for _, d := range s.devices {
+		devices = append(devices, d)
+	}
+	return devices
+}
+
+func (r *deviceRepository) GetByHostName(ctx context.Context, hostName string) (*models.Device, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	d := r.s.deviceByHostName(hostName)
+	if d == nil {
+		return nil, fmt.Errorf("device not found")
+	}
+	return copyDevice(d), nil
+}
+
+// deviceMatches reports whether d passes filters, as buildDeviceFilterClause does.
+func (s *Store) deviceMatches(d *models.Device, f repository.DeviceFilters) bool {
+	textIs := func(path []string, want string) bool {
+		got, ok := jsonPathText(d.DeviceConfig, path...)
+		return ok && got == want
+	}
+
+	switch {
+	case f.ProjectID != nil && d.Project != *f.ProjectID:
+	case f.City != nil && !textIs([]string{"city"}, *f.City):
+	case f.Region != nil && d.Region.Code != *f.Region:
+	case f.DeviceType != nil && !likeMatch("%"+*f.DeviceType+"%", jsonbOf(d.DeviceType)):
+	case f.HostNameGlob != nil && !likeMatch(globToLike(*f.HostNameGlob), d.HostName):
+	// No heartbeats are kept, so every device is offline.
+	case f.Health != nil && *f.Health != models.DeviceHealthOffline:
+	case f.SyncStatus != nil && (d.SyncStatus == nil || *d.SyncStatus != *f.SyncStatus):
+	case f.Change != nil && d.Change != *f.Change:
+	case f.SyncedAfter != nil && (d.LastSyncedAt == nil || d.LastSyncedAt.Before(*f.SyncedAfter)):
+	case f.SyncedBefore != nil && (d.LastSyncedAt == nil || !d.LastSyncedAt.Before(*f.SyncedBefore)):
+	case f.VenueID != nil && !s.venueDevices[*f.VenueID][d.ID]:
+	case f.Production != nil && d.Region.Production != *f.Production:
+	case f.IsTransit != nil && d.Region.IsTransit != *f.IsTransit:
+	case slices.ContainsFunc(f.Config, func(cf repository.DeviceConfigFilter) bool { return !textIs(cf.Path, cf.Value) }):
+	// No annotations are kept, so annotation filters match nothing.
+	case f.Annotations.HasAny():
+	default:
+		return true
+	}
+	return false
+}
+
+// matching returns copies of the devices filters select.
+func (r *deviceRepository) matching(filters repository.DeviceFilters) []*models.Device {
+	var devices []*models.Device
+	for _, d := range r.s.devices {
+		if r.s.deviceMatches(d, filters) {
+			devices = append(devices, copyDevice(d))
+		}
+	}
+	return devices
+}
+
+func (r *deviceRepository) List(ctx context.Context, page interfaces.ListPage) ([]*models.Device, error) {
+	return r.ListWithFilters(ctx, repository.DeviceFilters{}, page)
+}
+
+func (r *deviceRepository) Count(ctx context.Context) (int, error) {
+	return r.CountWithFilters(ctx, repository.DeviceFilters{})
+}
+
+func (r *deviceRepository) ListByProject(ctx context.Context, projectID int, page interfaces.ListPage) ([]*models.Device, error) {
+	return r.ListWithFilters(ctx, repository.DeviceFilters{ProjectID: &projectID}, page)
+}
+
+func (r *deviceRepository) CountByProject(ctx context.Context, projectID int) (int, error) {
+	return r.CountWithFilters(ctx, repository.DeviceFilters{ProjectID: &projectID})
+}
+
+func (r *deviceRepository) ListWithFilters(ctx context.Context, filters repository.DeviceFilters, page interfaces.ListPage) ([]*models.Device, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	devices, err := listPage(r.matching(filters), page, repository.DeviceSort)
+	if err != nil {
+		return nil, fmt.Errorf("list devices with filters: %w", err)
+	}
+	return devices, nil
+}
+
+func (r *deviceRepository) CountWithFilters(ctx context.Context, filters repository.DeviceFilters) (int, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	return len(r.matching(filters)), nil
+}
+
+// CountByRegion counts the matching devices per city and region code, largest group first.
+func (r *deviceRepository) CountByRegion(ctx context.Context, filters repository.DeviceFilters) ([]repository.RegionDeviceCount, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	type group struct{ city, region string }
+	counts := map[group]int{}
+	var order []group
+	for _, d := range r.matching(filters) {
+		city, _ := jsonPathText(d.DeviceConfig, "city")
+		g := group{city: city, region: d.Region.Code}
+		if counts[g] == 0 {
+			order = append(order, g)
+		}
+		counts[g]++
+	}
+	slices.SortStableFunc(order, func(a, b group) int { return counts[b] - counts[a] })
+
+	var out []repository.RegionDeviceCount
+	for _, g := range order {
+		row := repository.RegionDeviceCount{Region: g.region, Count: counts[g]}
+		if g.city != "" {
+			city := g.city
+			row.City = &city
+		}
+		out = append(out, row)
+	}
+	return out, nil
+}
+
+func (r *deviceRepository) ListSyncMarkers(ctx context.Context) (map[string]*time.Time, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	markers := map[string]*time.Time{}
+	for _, d := range r.s.devices {
+		markers[d.HostName] = timePtr(d.LastSyncedAt)
+	}
+	return markers, nil
+}
+
+// deviceFacetValues gives the value each of repository.DeviceFacetFields groups a
+// device by.
+var deviceFacetValues = map[string]func(d *models.Device) string{
+	"sync_status": func(d *models.Device) string {
+		if d.SyncStatus == nil {
+			return ""
+		}
+		return *d.SyncStatus
+	},
+	"device_type": func(d *models.Device) string { return d.DeviceType.Code },
+	"region":      func(d *models.Device) string { return d.Region.Code },
+	"production":  func(d *models.Device) string { return strconv.FormatBool(d.Region.Production) },
+	"is_transit":  func(d *models.Device) string { return strconv.FormatBool(d.Region.IsTransit) },
+	"change":      func(d *models.Device) string { return strconv.FormatBool(d.Change) },
+}
+
+func (r *deviceRepository) Facets(ctx context.Context, filters repository.DeviceFilters) (models.DeviceFacets, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	devices := r.matching(filters)
+	facets := models.DeviceFacets{}
+	for name := range repository.DeviceFacetFields {
+		value, ok := deviceFacetValues[name]
+		if !ok {
+			return nil, fmt.Errorf("count devices by %s: facet not supported", name)
+		}
+		byValue := map[string]int{}
+		for _, d := range devices {
+			byValue[value(d)]++
+		}
+		counts := []models.FacetCount{}
+		for v, n := range byValue {
+			counts = append(counts, models.FacetCount{Value: v, Count: n})
+		}
+		slices.SortFunc(counts, func(a, b models.FacetCount) int {
+			if a.Count != b.Count {
+				return b.Count - a.Count
+			}
+			return cmp.Compare(a.Value, b.Value)
+		})
+		facets[name] = counts
+	}
+	return facets, nil
+}
@@ -0,0 +1,170 @@
+package memory
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// decodeJSON decodes a JSON document keeping numbers as written.
+func decodeJSON(raw []byte) (any, error) {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// jsonbText renders v the way PostgreSQL prints a jsonb value: object keys ordered by
+// length and then bytewise, with ", " and ": " separators.
+func jsonbText(v any) string {
+	var b strings.Builder
+	writeJSONB(&b, v)
+	return b.String()
+}
+
+func writeJSONB(b *strings.Builder, v any) {
+	switch v := v.(type) {
+	case nil:
+		b.WriteString("null")
+	case bool:
+		b.WriteString(strconv.FormatBool(v))
+	case json.Number:
+		b.WriteString(v.String())
+	case string:
+		var quoted bytes.Buffer
+		enc := json.NewEncoder(&quoted)
+		enc.SetEscapeHTML(false)
+		enc.Encode(v)
+		b.Write(bytes.TrimSuffix(quoted.Bytes(), []byte("\n")))
+	case []any:
+		b.WriteByte('[')
+		for i, e := range v {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			writeJSONB(b, e)
+		}
+		b.WriteByte(']')
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		slices.SortFunc(keys, func(x, y string) int {
+			if len(x) != len(y) {
+				return len(x) - len(y)
+			}
+			return strings.Compare(x, y)
+		})
+		b.WriteByte('{')
+		for i, k := range keys {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			writeJSONB(b, k)
+			b.WriteString(": ")
+			writeJSONB(b, v[k])
+		}
+		b.WriteByte('}')
+	}
+}
+
+// jsonbOf renders a Go value as jsonb text.
+func jsonbOf(v any) string {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return ""
+	}
+	decoded, err := decodeJSON(raw)
+	if err != nil {
+		return ""
+	}
+	return jsonbText(decoded)
+}
+
+// jsonPathText is doc #>> path: the value at path as text, and false where the path is
+// missing or the value is null.
+func jsonPathText(doc []byte, path ...string) (string, bool) {
+	v, err := decodeJSON(doc)
+	if err != nil {
+		return "", false
+	}
+	for _, p := range path {
+		switch node := v.(type) {
+		case map[string]any:
+			v = node[p]
+		case []any:
+			i, err := strconv.Atoi(p)
+			if err != nil {
+				return "", false
+			}
+			if i < 0 {
+				i += len(node)
+			}
+			if i < 0 || i >= len(node) {
+				return "", false
+			}
+			v = node[i]
+		default:
+			return "", false
+		}
+	}
+	switch v := v.(type) {
+	case nil:
+		return "", false
+	case string:
+		return v, true
+	default:
+		return jsonbText(v), true
+	}
+}
+
+// likeMatch reports whether s matches the LIKE pattern, where % matches any run of
+// characters, _ one character and a backslash escapes the next character.
+func likeMatch(pattern, s string) bool {
+	var re strings.Builder
+	re.WriteString(`(?s)^`)
+	escaped := false
+	for _, r := range pattern {
+		switch {
+		case escaped:
+			re.WriteString(regexp.QuoteMeta(string(r)))
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '%':
+			re.WriteString(`.*`)
+		case r == '_':
+			re.WriteString(`.`)
+		default:
+			re.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	re.WriteString(`$`)
+	return regexp.MustCompile(re.String()).MatchString(s)
+}
+
+// globToLike translates a "*"/"?" glob into a LIKE pattern, as the device filters do.
+func globToLike(glob string) string {
+	var b strings.Builder
+	for _, r := range glob {
+		switch r {
+		case '*':
+			b.WriteByte('%')
+		case '?':
+			b.WriteByte('_')
+		case '%', '_', '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
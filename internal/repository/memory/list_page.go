@@ -0,0 +1,175 @@
+package memory
+
+import (
+	"cmp"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"scm/internal/interfaces"
+)
+
+// listPage returns the page of items that page selects, in the order appendListPage
+// gives the same rows in PostgreSQL. Sort keys name the items' JSON fields, and cursor
+// values are compared as the type of the field they belong to.
+func listPage[T any](items []T, page interfaces.ListPage, sortable interfaces.Sortable) ([]T, error) {
+	keys := page.Sort
+	if len(keys) == 0 {
+		keys, _ = sortable.Parse("")
+	}
+
+	backward := page.Backward()
+	position := page.After
+	if backward {
+		position = page.Before
+	}
+
+	// compare orders a before b, both given as their sort values, in reading order.
+	compare := func(a, b []any) (int, error) {
+		for i, k := range keys {
+			c, err := compareValues(a[i], b[i])
+			if err != nil {
+				return 0, fmt.Errorf("sort by %s: %w", k.Field, err)
+			}
+			if k.Desc != backward {
+				c = -c
+			}
+			if c != 0 {
+				return c, nil
+			}
+		}
+		return 0, nil
+	}
+
+	type row struct {
+		item   T
+		values []any
+	}
+	rows := make([]row, 0, len(items))
+	for _, item := range items {
+		values := make([]any, len(keys))
+		for i, k := range keys {
+			v, ok := fieldValue(item, k.Field)
+			if !ok {
+				return nil, fmt.Errorf("cannot sort by %q", k.Field)
+			}
+			values[i] = v
+		}
+		if len(position) == len(keys) {
+			c, err := compare(values, position)
+			if err != nil {
+				return nil, err
+			}
+			if c <= 0 {
+				continue
+			}
+		}
+		rows = append(rows, row{item: item, values: values})
+	}
+
+	var sortErr error
+	slices.SortStableFunc(rows, func(a, b row) int {
+		c, err := compare(a.values, b.values)
+		if err != nil && sortErr == nil {
+			sortErr = err
+		}
+		return c
+	})
+	if sortErr != nil {
+		return nil, sortErr
+	}
+
+	if page.Offset > 0 {
+		rows = rows[min(page.Offset, len(rows)):]
+	}
+	if page.Limit > 0 && len(rows) > page.Limit {
+		rows = rows[:page.Limit]
+	}
+
+	var out []T
+	for _, r := range rows {
+		out = append(out, r.item)
+	}
+	if backward {
+		slices.Reverse(out)
+	}
+	return out, nil
+}
+
+// fieldValue returns the field of item whose JSON name is name.
+func fieldValue(item any, name string) (any, bool) {
+	v := reflect.Indirect(reflect.ValueOf(item))
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		tag, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if tag == name {
+			return v.Field(i).Interface(), true
+		}
+	}
+	return nil, false
+}
+
+// compareValues compares a field value with another field value or with a cursor value,
+// which arrives as text.
+func compareValues(field, other any) (int, error) {
+	fv := reflect.ValueOf(field)
+	if t, ok := field.(time.Time); ok {
+		o, err := asTime(other)
+		if err != nil {
+			return 0, err
+		}
+		return t.Compare(o), nil
+	}
+	switch fv.Kind() {
+	case reflect.String:
+		return cmp.Compare(fv.String(), fmt.Sprint(other)), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Float32, reflect.Float64:
+		o, err := asFloat(other)
+		if err != nil {
+			return 0, err
+		}
+		return cmp.Compare(asNumber(fv), o), nil
+	}
+	return 0, fmt.Errorf("unsupported sort value %T", field)
+}
+
+func asNumber(v reflect.Value) float64 {
+	if v.CanInt() {
+		return float64(v.Int())
+	}
+	return v.Float()
+}
+
+func asFloat(v any) (float64, error) {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.String:
+		f, err := strconv.ParseFloat(rv.String(), 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid input syntax for a number: %q", rv.String())
+		}
+		return f, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Float32, reflect.Float64:
+		return asNumber(rv), nil
+	}
+	return 0, fmt.Errorf("cannot compare %T with a number", v)
+}
+
+func asTime(v any) (time.Time, error) {
+	switch v := v.(type) {
+	case time.Time:
+		return v, nil
+	case string:
+		t, err := time.Parse(time.RFC3339Nano, v)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid input syntax for type timestamp with time zone: %q", v)
+		}
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("cannot compare %T with a timestamp", v)
+}
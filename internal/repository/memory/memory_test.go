@@ -0,0 +1,23 @@
+package memory_test
+
+import (
+	"testing"
+
+	"scm/internal/repository/memory"
+	"scm/internal/repository/repotest"
+)
+
+func TestConformance(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) repotest.Repos {
+		s := memory.NewStore()
+		return repotest.Repos{
+			Users:       memory.NewUserRepository(s),
+			Advertisers: memory.NewAdvertiserRepository(s),
+			Campaigns:   memory.NewCampaignRepository(s),
+			Creatives:   memory.NewCreativeRepository(s),
+			Projects:    memory.NewProjectRepository(s),
+			Devices:     memory.NewDeviceRepository(s),
+			Venues:      memory.NewVenueRepository(s),
+		}
+	})
+}
@@ -0,0 +1,140 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"scm/internal/interfaces"
+	"scm/internal/models"
+	"scm/internal/repository"
+)
+
+type projectRepository struct {
+	s *Store
+}
+
+func NewProjectRepository(s *Store) repository.ProjectRepository {
+	return &projectRepository{s: s}
+}
+
+func copyProject(p *models.Project) *models.Project {
+	copied := *p
+	copied.Languages = slices.Clone(p.Languages)
+	copied.Region = slices.Clone(p.Region)
+	copied.Company = stringPtr(p.Company)
+	copied.Description = stringPtr(p.Description)
+	copied.Proxy = stringPtr(p.Proxy)
+	copied.Address = stringPtr(p.Address)
+	copied.Annotations = nil
+	return &copied
+}
+
+// Upsert inserts the project or, if one with its name exists, overwrites it, ID
+// included, as the console sync expects.
+func (r *projectRepository) Upsert(ctx context.Context, project *models.Project) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	now := r.s.now()
+	row := copyProject(project)
+	row.CreatedAt = now
+	row.UpdatedAt = now
+
+	current := r.s.projectByName(project.Name)
+	if current == nil {
+		if _, ok := r.s.projects[project.ID]; ok {
+			return fmt.Errorf("upsert project: %w", uniqueViolation("projects_pkey"))
+		}
+		r.s.projects[row.ID] = row
+		return nil
+	}
+
+	if current.ID != row.ID {
+		if _, ok := r.s.projects[row.ID]; ok {
+			return fmt.Errorf("upsert project: %w", uniqueViolation("projects_pkey"))
+		}
+		for _, d := range r.s.devices {
+			if d.Project == current.ID {
+				return fmt.Errorf("upsert project: %w", foreignKeyViolation("devices", "devices_project_fkey"))
+			}
+		}
+	}
+	row.CreatedAt = current.CreatedAt
+	delete(r.s.projects, current.ID)
+	r.s.projects[row.ID] = row
+	return nil
+}
+
+func (s *Store) projectByName(name string) *models.Project {
+	for _, p := range s.projects {
+		if p.Name == name {
+			return p
+		}
+	}
+	return nil
+}
+
+func (r *projectRepository) GetByName(ctx context.Context, name string) (*models.Project, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	p := r.s.projectByName(name)
+	if p == nil {
+		return nil, fmt.Errorf("project not found")
+	}
+	return copyProject(p), nil
+}
+
+// matching returns copies of the projects filters select.
+func (r *projectRepository) matching(filters repository.ProjectFilters) []*models.Project {
+	var projects []*models.Project
+	for _, p := range r.s.projects {
+		if filters.Annotations.HasAny() {
+			continue
+		}
+		if filters.City != nil || filters.Region != nil {
+			if !slices.ContainsFunc(r.s.deviceList(), func(d *models.Device) bool {
+				if d.Project != p.ID {
+					return false
+				}
+				if filters.City != nil {
+					if city, ok := jsonPathText(d.DeviceConfig, "city"); !ok || city != *filters.City {
+						return false
+					}
+				}
+				return filters.Region == nil || d.Region.Code == *filters.Region
+			}) {
+				continue
+			}
+		}
+		projects = append(projects, copyProject(p))
+	}
+	return projects
+}
+
+func (r *projectRepository) List(ctx context.Context, page interfaces.ListPage) ([]*models.Project, error) {
+	return r.ListWithFilters(ctx, repository.ProjectFilters{}, page)
+}
+
+func (r *projectRepository) Count(ctx context.Context) (int, error) {
+	return r.CountWithFilters(ctx, repository.ProjectFilters{})
+}
+
+func (r *projectRepository) ListWithFilters(ctx context.Context, filters repository.ProjectFilters, page interfaces.ListPage) ([]*models.Project, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	projects, err := listPage(r.matching(filters), page, repository.ProjectSort)
+	if err != nil {
+		return nil, fmt.Errorf("list projects with filters: %w", err)
+	}
+	return projects, nil
+}
+
+func (r *projectRepository) CountWithFilters(ctx context.Context, filters repository.ProjectFilters) (int, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	return len(r.matching(filters)), nil
+}
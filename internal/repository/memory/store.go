@@ -0,0 +1,128 @@
+// Package memory implements the repositories in memory, for handler and service tests
+// that should not need a database. The repositories of one Store share their tables,
+// so foreign keys, blocked deletes and cascades behave as they do in PostgreSQL; the
+// conformance suite in repotest runs against both to keep them that way.
+//
+// Tables kept outside the Store (heartbeats, annotations and alert rules) are always
+// empty: health filters see every device as offline and annotation filters match
+// nothing. Strings sort bytewise rather than by the database collation.
+package memory
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"scm/internal/models"
+)
+
+// Store holds the tables shared by the repositories created from it.
+type Store struct {
+	mu sync.Mutex
+
+	users        map[string]*models.User
+	advertisers  map[string]*models.Advertiser
+	campaigns    map[string]*models.Campaign
+	creatives    map[string]*models.Creative
+	projects     map[int]*models.Project
+	devices      map[int]*models.Device
+	venues       map[int]*models.Venue
+	venueDevices map[int]map[int]bool // venue ID -> device IDs
+	lastVenueID  int
+
+	// Now is the clock used for created, updated and deleted timestamps.
+	Now  func() time.Time
+	last time.Time
+}
+
+// NewStore returns an empty store.
+func NewStore() *Store {
+	return &Store{
+		users:        map[string]*models.User{},
+		advertisers:  map[string]*models.Advertiser{},
+		campaigns:    map[string]*models.Campaign{},
+		creatives:    map[string]*models.Creative{},
+		projects:     map[int]*models.Project{},
+		devices:      map[int]*models.Device{},
+		venues:       map[int]*models.Venue{},
+		venueDevices: map[int]map[int]bool{},
+		Now:          time.Now,
+	}
+}
+
+// now returns the current time at the precision PostgreSQL stores. Successive calls
+// never return the same time, so rows sorted by their timestamps keep the order they
+// were written in.
+func (s *Store) now() time.Time {
+	t := s.Now().UTC().Truncate(time.Microsecond)
+	if !t.After(s.last) {
+		t = s.last.Add(time.Microsecond)
+	}
+	s.last = t
+	return t
+}
+
+// parseUUID canonicalizes id the way a uuid column does, failing for malformed input
+// with the error PostgreSQL reports.
+func parseUUID(id string) (string, error) {
+	u, err := uuid.Parse(id)
+	if err != nil {
+		return "", &pq.Error{
+			Code:    "22P02",
+			Message: fmt.Sprintf("invalid input syntax for type uuid: %q", id),
+		}
+	}
+	return u.String(), nil
+}
+
+func uniqueViolation(constraint string) error {
+	return &pq.Error{
+		Code:       "23505",
+		Message:    fmt.Sprintf("duplicate key value violates unique constraint %q", constraint),
+		Constraint: constraint,
+	}
+}
+
+func foreignKeyViolation(table, constraint string) error {
+	return &pq.Error{
+		Code:       "23503",
+		Message:    fmt.Sprintf("write to table %q violates foreign key constraint %q", table, constraint),
+		Constraint: constraint,
+	}
+}
+
+func notNullViolation(table, column string) error {
+	return &pq.Error{
+		Code:    "23502",
+		Message: fmt.Sprintf("null value in column %q of relation %q violates not-null constraint", column, table),
+		Table:   table,
+		Column:  column,
+	}
+}
+
+// stringArray returns the value a text[] column gives back: never nil.
+func stringArray(v []string) []string {
+	if v == nil {
+		return []string{}
+	}
+	return slices.Clone(v)
+}
+
+func timePtr(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	copied := t.UTC().Truncate(time.Microsecond)
+	return &copied
+}
+
+func stringPtr(s *string) *string {
+	if s == nil {
+		return nil
+	}
+	copied := *s
+	return &copied
+}
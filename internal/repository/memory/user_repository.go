@@ -0,0 +1,227 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"scm/internal/interfaces"
+	"scm/internal/models"
+	"scm/internal/repository"
+)
+
+type userRepository struct {
+	s *Store
+}
+
+func NewUserRepository(s *Store) repository.UserRepository {
+	return &userRepository{s: s}
+}
+
+// checkUnique returns the unique violation u would cause, ignoring the user with skipID.
+func (r *userRepository) checkUnique(u *models.User, skipID string) error {
+	for id, other := range r.s.users {
+		if id == skipID {
+			continue
+		}
+		if other.Email == u.Email {
+			return uniqueViolation("users_email_key")
+		}
+		if strings.ToLower(other.UserName) == strings.ToLower(u.UserName) {
+			return uniqueViolation("idx_users_user_name_unique")
+		}
+	}
+	return nil
+}
+
+func (r *userRepository) Create(ctx context.Context, user *models.User) error {
+	id, err := parseUUID(user.ID)
+	if err != nil {
+		return err
+	}
+
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.users[id]; ok {
+		return uniqueViolation("users_pkey")
+	}
+	if err := r.checkUnique(user, id); err != nil {
+		return err
+	}
+
+	stored := *user
+	stored.ID = id
+	stored.CreatedAt = user.CreatedAt.UTC().Truncate(time.Microsecond)
+	r.s.users[id] = &stored
+	user.CreatedAt = stored.CreatedAt
+	return nil
+}
+
+func (r *userRepository) GetByID(ctx context.Context, id string) (*models.User, error) {
+	key, err := parseUUID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	u, ok := r.s.users[key]
+	if !ok {
+		return nil, fmt.Errorf("user not found")
+	}
+	copied := *u
+	return &copied, nil
+}
+
+func (r *userRepository) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	return r.find(func(u *models.User) bool { return u.Email == email })
+}
+
+func (r *userRepository) GetByIdentifier(ctx context.Context, identifier string) (*models.User, error) {
+	return r.find(func(u *models.User) bool {
+		return strings.EqualFold(u.Email, identifier) ||
+			strings.EqualFold(u.UserName, identifier) ||
+			u.PhoneNumber == identifier
+	})
+}
+
+func (r *userRepository) find(match func(u *models.User) bool) (*models.User, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	for _, u := range r.sorted() {
+		if match(u) {
+			copied := *u
+			return &copied, nil
+		}
+	}
+	return nil, fmt.Errorf("user not found")
+}
+
+// sorted returns the users oldest first, so lookups that match several are stable.
+func (r *userRepository) sorted() []*models.User {
+	users := make([]*models.User, 0, len(r.s.users))
+	for _, u := range r.s.users {
+		users = append(users, u)
+	}
+	slices.SortFunc(users, func(a, b *models.User) int {
+		if c := a.CreatedAt.Compare(b.CreatedAt); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+	return users
+}
+
+// listed is u as the list queries return it, without the password hash.
+func listed(u *models.User) models.User {
+	copied := *u
+	copied.PasswordHash = ""
+	return copied
+}
+
+func (r *userRepository) List(ctx context.Context, page interfaces.ListPage) ([]models.User, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	users := make([]models.User, 0, len(r.s.users))
+	for _, u := range r.s.users {
+		users = append(users, listed(u))
+	}
+	return listPage(users, page, repository.UserSort)
+}
+
+func (r *userRepository) Count(ctx context.Context) (int, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	return len(r.s.users), nil
+}
+
+func (r *userRepository) ListAll(ctx context.Context) ([]models.User, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	var users []models.User
+	sorted := r.sorted()
+	for i := len(sorted) - 1; i >= 0; i-- {
+		users = append(users, listed(sorted[i]))
+	}
+	return users, nil
+}
+
+func (r *userRepository) UpdateProfile(ctx context.Context, id string, req *models.UpdateUserRequest) error {
+	key, err := parseUUID(id)
+	if err != nil {
+		return err
+	}
+
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	u, ok := r.s.users[key]
+	if !ok {
+		return fmt.Errorf("user not found")
+	}
+	updated := *u
+	if req.Email != nil {
+		updated.Email = *req.Email
+	}
+	if req.Name != nil {
+		updated.Name = *req.Name
+	}
+	if req.UserName != nil {
+		updated.UserName = *req.UserName
+	}
+	if req.PhoneNumber != nil {
+		updated.PhoneNumber = *req.PhoneNumber
+	}
+	if err := r.checkUnique(&updated, key); err != nil {
+		return err
+	}
+	r.s.users[key] = &updated
+	return nil
+}
+
+func (r *userRepository) UpdatePasswordHash(ctx context.Context, userID string, passwordHash string) error {
+	key, err := parseUUID(userID)
+	if err != nil {
+		return err
+	}
+
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	u, ok := r.s.users[key]
+	if !ok {
+		return fmt.Errorf("user not found")
+	}
+	u.PasswordHash = passwordHash
+	return nil
+}
+
+// Delete removes the user. Like the database it refuses while advertisers the user
+// created still exist.
+func (r *userRepository) Delete(ctx context.Context, id string) error {
+	key, err := parseUUID(id)
+	if err != nil {
+		return err
+	}
+
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.users[key]; !ok {
+		return fmt.Errorf("user not found")
+	}
+	for _, a := range r.s.advertisers {
+		if a.CreatedBy == key {
+			return foreignKeyViolation("advertisers", "advertisers_created_by_fkey")
+		}
+	}
+	delete(r.s.users, key)
+	return nil
+}
@@ -0,0 +1,415 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"scm/internal/interfaces"
+	"scm/internal/models"
+	"scm/internal/repository"
+)
+
+type venueRepository struct {
+	s *Store
+}
+
+func NewVenueRepository(s *Store) repository.VenueRepository {
+	return &venueRepository{s: s}
+}
+
+func copyVenue(v *models.Venue) *models.Venue {
+	copied := *v
+	if v.SmartFilter != nil {
+		filter := jsonRoundTrip(*v.SmartFilter)
+		copied.SmartFilter = &filter
+	}
+	return &copied
+}
+
+// smartFilter returns the selector as the smart_filter column keeps it: empty ones
+// are not stored.
+func smartFilter(selector *models.DeviceSelector) *models.DeviceSelector {
+	if selector == nil || selector.IsEmpty() {
+		return nil
+	}
+	filter := jsonRoundTrip(*selector)
+	return &filter
+}
+
+func (s *Store) venueByName(name string, skipID int) *models.Venue {
+	for id, v := range s.venues {
+		if id != skipID && v.Name == name {
+			return v
+		}
+	}
+	return nil
+}
+
+func (r *venueRepository) Create(ctx context.Context, venue *models.Venue) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	// Like a sequence, the ID is used up even if the insert fails.
+	r.s.lastVenueID++
+	if r.s.venueByName(venue.Name, 0) != nil {
+		return fmt.Errorf("create venue: %w", uniqueViolation("venues_name_key"))
+	}
+
+	now := r.s.now()
+	row := &models.Venue{
+		ID:          r.s.lastVenueID,
+		Name:        venue.Name,
+		SmartFilter: smartFilter(venue.SmartFilter),
+		CreatedAt:   now,
+		UpdatedAt:   now,
+		Version:     1,
+	}
+	r.s.venues[row.ID] = row
+
+	venue.ID = row.ID
+	venue.Version = row.Version
+	venue.CreatedAt = row.CreatedAt
+	venue.UpdatedAt = row.UpdatedAt
+	return nil
+}
+
+func (r *venueRepository) GetByID(ctx context.Context, id int) (*models.Venue, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	v, ok := r.s.venues[id]
+	if !ok {
+		return nil, fmt.Errorf("venue not found")
+	}
+	return copyVenue(v), nil
+}
+
+// venueDeviceList returns copies of the venue's devices, newest first.
+func (s *Store) venueDeviceList(venueID int) []*models.Device {
+	var devices []*models.Device
+	for deviceID := range s.venueDevices[venueID] {
+		devices = append(devices, copyDevice(s.devices[deviceID]))
+	}
+	slices.SortFunc(devices, func(a, b *models.Device) int {
+		if c := b.CreatedAt.Compare(a.CreatedAt); c != 0 {
+			return c
+		}
+		return a.ID - b.ID
+	})
+	return devices
+}
+
+func (r *venueRepository) GetByIDWithDevices(ctx context.Context, id int) (*models.VenueWithDevices, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	v, ok := r.s.venues[id]
+	if !ok {
+		return nil, fmt.Errorf("venue not found")
+	}
+	venue := &models.VenueWithDevices{ID: v.ID, Name: v.Name, CreatedAt: v.CreatedAt, UpdatedAt: v.UpdatedAt}
+	for _, d := range r.s.venueDeviceList(id) {
+		venue.Devices = append(venue.Devices, *d)
+	}
+	return venue, nil
+}
+
+func (r *venueRepository) GetByName(ctx context.Context, name string) (*models.Venue, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	v := r.s.venueByName(name, 0)
+	if v == nil {
+		return nil, fmt.Errorf("venue not found")
+	}
+	return copyVenue(v), nil
+}
+
+func (r *venueRepository) List(ctx context.Context, page interfaces.ListPage) ([]*models.Venue, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	var venues []*models.Venue
+	for _, v := range r.s.venues {
+		venues = append(venues, copyVenue(v))
+	}
+	venues, err := listPage(venues, page, repository.VenueSort)
+	if err != nil {
+		return nil, fmt.Errorf("list venues: %w", err)
+	}
+	return venues, nil
+}
+
+func (r *venueRepository) Count(ctx context.Context) (int, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	return len(r.s.venues), nil
+}
+
+func (r *venueRepository) Update(ctx context.Context, venue *models.Venue, version int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	v, ok := r.s.venues[venue.ID]
+	if !ok {
+		return fmt.Errorf("venue not found")
+	}
+	if version != 0 && version != v.Version {
+		return interfaces.ErrVersionConflict
+	}
+	if r.s.venueByName(venue.Name, venue.ID) != nil {
+		return fmt.Errorf("update venue: %w", uniqueViolation("venues_name_key"))
+	}
+
+	v.Name = venue.Name
+	v.SmartFilter = smartFilter(venue.SmartFilter)
+	v.UpdatedAt = r.s.now()
+	v.Version++
+
+	venue.Version = v.Version
+	venue.UpdatedAt = v.UpdatedAt
+	return nil
+}
+
+// Delete removes a venue. It is blocked while devices are assigned to the venue; use
+// DeleteCascade to remove the assignments as well.
+func (r *venueRepository) Delete(ctx context.Context, id int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.venues[id]; !ok {
+		return fmt.Errorf("venue not found")
+	}
+	if n := len(r.s.venueDevices[id]); n > 0 {
+		return &interfaces.DeletionBlockedError{
+			Resource:   "venue",
+			References: map[string]int64{"devices": int64(n)},
+		}
+	}
+	delete(r.s.venues, id)
+	return nil
+}
+
+// Dependencies lists the device assignments a cascade delete of the venue would remove.
+func (r *venueRepository) Dependencies(ctx context.Context, id int) (*models.DeletionDependencies, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.venues[id]; !ok {
+		return nil, fmt.Errorf("venue not found")
+	}
+	return r.s.venueDependencies(id), nil
+}
+
+// DeleteCascade deletes the venue together with its device assignments and returns what
+// was removed. Devices themselves are kept.
+func (r *venueRepository) DeleteCascade(ctx context.Context, id int) (*models.DeletionDependencies, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.venues[id]; !ok {
+		return nil, fmt.Errorf("venue not found")
+	}
+	deps := r.s.venueDependencies(id)
+	delete(r.s.venueDevices, id)
+	delete(r.s.venues, id)
+	return deps, nil
+}
+
+// checkMembership returns the error the membership operations report when the venue or
+// the device does not exist.
+func (r *venueRepository) checkMembership(venueID, deviceID int) error {
+	if _, ok := r.s.venues[venueID]; !ok {
+		return fmt.Errorf("venue not found")
+	}
+	if _, ok := r.s.devices[deviceID]; !ok {
+		return fmt.Errorf("device not found")
+	}
+	return nil
+}
+
+func (r *venueRepository) AddDeviceToVenue(ctx context.Context, venueID, deviceID int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if err := r.checkMembership(venueID, deviceID); err != nil {
+		return err
+	}
+	r.s.addVenueDevices(venueID, deviceID)
+	return nil
+}
+
+func (s *Store) addVenueDevices(venueID int, deviceIDs ...int) {
+	if s.venueDevices[venueID] == nil {
+		s.venueDevices[venueID] = map[int]bool{}
+	}
+	for _, id := range deviceIDs {
+		s.venueDevices[venueID][id] = true
+	}
+}
+
+func (r *venueRepository) RemoveDeviceFromVenue(ctx context.Context, venueID, deviceID int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if err := r.checkMembership(venueID, deviceID); err != nil {
+		return err
+	}
+	if !r.s.venueDevices[venueID][deviceID] {
+		return fmt.Errorf("device not found in venue")
+	}
+	delete(r.s.venueDevices[venueID], deviceID)
+	return nil
+}
+
+// window applies LIMIT and OFFSET to items.
+func window[T any](items []T, limit, offset int) []T {
+	items = items[min(max(offset, 0), len(items)):]
+	return items[:min(max(limit, 0), len(items))]
+}
+
+func (r *venueRepository) GetVenuesByDeviceID(ctx context.Context, deviceID int, limit int, offset int) ([]*models.Venue, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	var venues []*models.Venue
+	for venueID, members := range r.s.venueDevices {
+		if members[deviceID] {
+			venues = append(venues, copyVenue(r.s.venues[venueID]))
+		}
+	}
+	slices.SortFunc(venues, func(a, b *models.Venue) int {
+		if c := b.CreatedAt.Compare(a.CreatedAt); c != 0 {
+			return c
+		}
+		return a.ID - b.ID
+	})
+	return window(venues, limit, offset), nil
+}
+
+func (r *venueRepository) CountVenuesByDeviceID(ctx context.Context, deviceID int) (int, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	count := 0
+	for _, members := range r.s.venueDevices {
+		if members[deviceID] {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r *venueRepository) GetDevicesByVenueID(ctx context.Context, venueID int, limit int, offset int) ([]*models.Device, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	return window(r.s.venueDeviceList(venueID), limit, offset), nil
+}
+
+func (r *venueRepository) CountDevicesByVenueID(ctx context.Context, venueID int) (int, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	return len(r.s.venueDevices[venueID]), nil
+}
+
+// GetDevicesByVenueIDs loads the devices of several venues, keyed by venue ID.
+func (r *venueRepository) GetDevicesByVenueIDs(ctx context.Context, venueIDs []int) (map[int][]*models.Device, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	result := make(map[int][]*models.Device, len(venueIDs))
+	for _, id := range venueIDs {
+		if devices := r.s.venueDeviceList(id); len(devices) > 0 {
+			result[id] = devices
+		}
+	}
+	return result, nil
+}
+
+func (r *venueRepository) AssignDevices(ctx context.Context, venueID int, filters repository.DeviceFilters, mode string, dryRun bool) (*models.VenueAssignmentResult, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.venues[venueID]; !ok {
+		return nil, fmt.Errorf("venue not found")
+	}
+
+	refs := func(match func(d *models.Device) bool) []models.VenueDeviceRef {
+		var out []models.VenueDeviceRef
+		for _, d := range r.s.devices {
+			if match(d) {
+				out = append(out, models.VenueDeviceRef{ID: d.ID, HostName: d.HostName})
+			}
+		}
+		slices.SortFunc(out, func(a, b models.VenueDeviceRef) int { return strings.Compare(a.HostName, b.HostName) })
+		return out
+	}
+	matched := refs(func(d *models.Device) bool { return r.s.deviceMatches(d, filters) })
+	current := refs(func(d *models.Device) bool { return r.s.venueDevices[venueID][d.ID] })
+
+	matchedIDs := make(map[int]bool, len(matched))
+	for _, d := range matched {
+		matchedIDs[d.ID] = true
+	}
+
+	result := &models.VenueAssignmentResult{
+		VenueID: venueID,
+		Mode:    mode,
+		DryRun:  dryRun,
+		Matched: len(matched),
+		Added:   []models.VenueDeviceRef{},
+		Removed: []models.VenueDeviceRef{},
+	}
+	switch mode {
+	case models.VenueAssignAdd, models.VenueAssignReplace:
+		for _, d := range matched {
+			if !r.s.venueDevices[venueID][d.ID] {
+				result.Added = append(result.Added, d)
+			}
+		}
+	}
+	switch mode {
+	case models.VenueAssignRemove:
+		for _, d := range current {
+			if matchedIDs[d.ID] {
+				result.Removed = append(result.Removed, d)
+			}
+		}
+	case models.VenueAssignReplace:
+		for _, d := range current {
+			if !matchedIDs[d.ID] {
+				result.Removed = append(result.Removed, d)
+			}
+		}
+	}
+	result.Retained = len(current) - len(result.Removed)
+
+	if dryRun {
+		return result, nil
+	}
+	for _, d := range result.Added {
+		r.s.addVenueDevices(venueID, d.ID)
+	}
+	for _, d := range result.Removed {
+		delete(r.s.venueDevices[venueID], d.ID)
+	}
+	return result, nil
+}
+
+func (r *venueRepository) ListSmartVenues(ctx context.Context) ([]*models.Venue, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	var venues []*models.Venue
+	for _, v := range r.s.venues {
+		if v.SmartFilter != nil {
+			venues = append(venues, copyVenue(v))
+		}
+	}
+	slices.SortFunc(venues, func(a, b *models.Venue) int { return a.ID - b.ID })
+	return venues, nil
+}
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"scm/internal/models"
+	"scm/internal/tracing"
 )
 
 type PasswordResetRepository interface {
@@ -16,14 +17,17 @@ type PasswordResetRepository interface {
 }
 
 type passwordResetRepository struct {
-	db *sql.DB
+	db DBTX
 }
 
-func NewPasswordResetRepository(db *sql.DB) PasswordResetRepository {
+func NewPasswordResetRepository(db DBTX) PasswordResetRepository {
 	return &passwordResetRepository{db: db}
 }
 
 func (r *passwordResetRepository) Create(ctx context.Context, token *models.PasswordResetToken) error {
+	ctx, span := tracing.Repository(ctx, "passwordResetRepository.Create")
+	defer span.End()
+
 	query := `
 		INSERT INTO password_reset_tokens (id, user_id, token_hash, expires_at, created_at)
 		VALUES ($1, $2, $3, $4, $5)
@@ -35,6 +39,9 @@ func (r *passwordResetRepository) Create(ctx context.Context, token *models.Pass
 }
 
 func (r *passwordResetRepository) GetValidByTokenHash(ctx context.Context, tokenHash string) (*models.PasswordResetToken, error) {
+	ctx, span := tracing.Repository(ctx, "passwordResetRepository.GetValidByTokenHash")
+	defer span.End()
+
 	query := `
 		SELECT id, user_id, token_hash, expires_at, used_at, created_at
 		FROM password_reset_tokens
@@ -61,6 +68,9 @@ func (r *passwordResetRepository) GetValidByTokenHash(ctx context.Context, token
 }
 
 func (r *passwordResetRepository) MarkUsed(ctx context.Context, id string, usedAt time.Time) error {
+	ctx, span := tracing.Repository(ctx, "passwordResetRepository.MarkUsed")
+	defer span.End()
+
 	query := `UPDATE password_reset_tokens SET used_at = $1 WHERE id = $2 AND used_at IS NULL`
 	res, err := r.db.ExecContext(ctx, query, usedAt, id)
 	if err != nil {
@@ -7,32 +7,68 @@ import (
 	"fmt"
 	"time"
 
+	"scm/internal/interfaces"
 	"scm/internal/models"
+	"scm/internal/tracing"
 )
 
 type ProjectRepository interface {
 	Upsert(ctx context.Context, project *models.Project) error
 	GetByName(ctx context.Context, name string) (*models.Project, error)
-	List(ctx context.Context, limit int, offset int) ([]*models.Project, error)
+	List(ctx context.Context, page interfaces.ListPage) ([]*models.Project, error)
 	Count(ctx context.Context) (int, error)
-	ListWithFilters(ctx context.Context, filters ProjectFilters, limit int, offset int) ([]*models.Project, error)
+	ListWithFilters(ctx context.Context, filters ProjectFilters, page interfaces.ListPage) ([]*models.Project, error)
 	CountWithFilters(ctx context.Context, filters ProjectFilters) (int, error)
 }
 
 type ProjectFilters struct {
 	City   *string
 	Region *string
+	// Annotations matches the project's local annotations.
+	Annotations AnnotationFilters
+}
+
+// HasAny reports whether at least one filter is set.
+func (f ProjectFilters) HasAny() bool {
+	return f.City != nil || f.Region != nil || f.Annotations.HasAny()
+}
+
+// buildProjectFilterClause returns the " AND ..." conditions for filters on projects p.
+func buildProjectFilterClause(filters ProjectFilters, args []any, argIndex int) (string, []any, int) {
+	clause := ""
+	if filters.City != nil || filters.Region != nil {
+		clause += " AND EXISTS (SELECT 1 FROM devices d WHERE d.project = p.id"
+		if filters.City != nil {
+			clause += fmt.Sprintf(" AND d.device_config->>'city' = $%d", argIndex)
+			args = append(args, *filters.City)
+			argIndex++
+		}
+		if filters.Region != nil {
+			clause += fmt.Sprintf(" AND d.region->>'code' = $%d", argIndex)
+			args = append(args, *filters.Region)
+			argIndex++
+		}
+		clause += ")"
+	}
+
+	annotationClause, args, argIndex := projectAnnotations.filterClause("p.name", filters.Annotations, args, argIndex)
+	clause += annotationClause
+
+	return clause, args, argIndex
 }
 
 type projectRepository struct {
-	db *sql.DB
+	db DBTX
 }
 
-func NewProjectRepository(db *sql.DB) ProjectRepository {
+func NewProjectRepository(db DBTX) ProjectRepository {
 	return &projectRepository{db: db}
 }
 
 func (r *projectRepository) Upsert(ctx context.Context, project *models.Project) error {
+	ctx, span := tracing.Repository(ctx, "projectRepository.Upsert")
+	defer span.End()
+
 	ownerJSON, err := json.Marshal(project.Owner)
 	if err != nil {
 		return fmt.Errorf("marshal owner: %w", err)
@@ -105,19 +141,23 @@ func (r *projectRepository) Upsert(ctx context.Context, project *models.Project)
 }
 
 func (r *projectRepository) GetByName(ctx context.Context, name string) (*models.Project, error) {
+	ctx, span := tracing.Repository(ctx, "projectRepository.GetByName")
+	defer span.End()
+
 	query := `
 		SELECT id, owner, languages, name, company, description, max_devices,
 			profile_img, header, sub_type, production, city_poster_frequency,
 			ad_poster_frequency, city_poster_play_time, loop_length,
 			smallbiz_support, proxy, address, latitude, longitude,
 			is_transit, scm_health, priority, replicas, region, status, role,
-			created_at, updated_at
+			created_at, updated_at,
+			(SELECT to_jsonb(a) FROM project_annotations a WHERE a.project_name = projects.name) AS annotations
 		FROM projects
 		WHERE name = $1
 	`
 
 	var project models.Project
-	var ownerJSON, languagesJSON, regionJSON []byte
+	var ownerJSON, languagesJSON, regionJSON, annotationsJSON []byte
 	err := r.db.QueryRowContext(ctx, query, name).Scan(
 		&project.ID, &ownerJSON, &languagesJSON, &project.Name, &project.Company,
 		&project.Description, &project.MaxDevices, &project.ProfileImg,
@@ -128,7 +168,7 @@ func (r *projectRepository) GetByName(ctx context.Context, name string) (*models
 		&project.Latitude, &project.Longitude, &project.IsTransit,
 		&project.ScmHealth, &project.Priority, &project.Replicas,
 		&regionJSON, &project.Status, &project.Role,
-		&project.CreatedAt, &project.UpdatedAt,
+		&project.CreatedAt, &project.UpdatedAt, &annotationsJSON,
 	)
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -146,33 +186,40 @@ func (r *projectRepository) GetByName(ctx context.Context, name string) (*models
 	if err := json.Unmarshal(regionJSON, &project.Region); err != nil {
 		return nil, fmt.Errorf("unmarshal region: %w", err)
 	}
+	if project.Annotations, err = scanAnnotations(annotationsJSON); err != nil {
+		return nil, err
+	}
 
 	return &project, nil
 }
 
-func (r *projectRepository) List(ctx context.Context, limit int, offset int) ([]*models.Project, error) {
+// ProjectSort lists the fields project lists can be sorted by.
+var ProjectSort = interfaces.Sortable{
+	Columns: map[string]string{
+		"id":         "id",
+		"name":       "name",
+		"priority":   "priority",
+		"created_at": "created_at",
+		"updated_at": "updated_at",
+	},
+	Default: "-created_at",
+}
+
+func (r *projectRepository) List(ctx context.Context, page interfaces.ListPage) ([]*models.Project, error) {
+	ctx, span := tracing.Repository(ctx, "projectRepository.List")
+	defer span.End()
+
 	query := `
 		SELECT id, owner, languages, name, company, description, max_devices,
 			profile_img, header, sub_type, production, city_poster_frequency,
 			ad_poster_frequency, city_poster_play_time, loop_length,
 			smallbiz_support, proxy, address, latitude, longitude,
 			is_transit, scm_health, priority, replicas, region, status, role,
-			created_at, updated_at
+			created_at, updated_at,
+			(SELECT to_jsonb(a) FROM project_annotations a WHERE a.project_name = projects.name) AS annotations
 		FROM projects
-		ORDER BY created_at DESC
 	`
-
-	args := []interface{}{}
-	argPos := 1
-	if limit > 0 {
-		query += fmt.Sprintf(" LIMIT $%d", argPos)
-		args = append(args, limit)
-		argPos++
-	}
-	if offset > 0 {
-		query += fmt.Sprintf(" OFFSET $%d", argPos)
-		args = append(args, offset)
-	}
+	query, args := appendListPage(query, nil, false, page, ProjectSort)
 
 	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
@@ -183,7 +230,7 @@ func (r *projectRepository) List(ctx context.Context, limit int, offset int) ([]
 	var projects []*models.Project
 	for rows.Next() {
 		var project models.Project
-		var ownerJSON, languagesJSON, regionJSON []byte
+		var ownerJSON, languagesJSON, regionJSON, annotationsJSON []byte
 		if err := rows.Scan(
 			&project.ID, &ownerJSON, &languagesJSON, &project.Name, &project.Company,
 			&project.Description, &project.MaxDevices, &project.ProfileImg,
@@ -194,7 +241,7 @@ func (r *projectRepository) List(ctx context.Context, limit int, offset int) ([]
 			&project.Latitude, &project.Longitude, &project.IsTransit,
 			&project.ScmHealth, &project.Priority, &project.Replicas,
 			&regionJSON, &project.Status, &project.Role,
-			&project.CreatedAt, &project.UpdatedAt,
+			&project.CreatedAt, &project.UpdatedAt, &annotationsJSON,
 		); err != nil {
 			return nil, fmt.Errorf("scan project: %w", err)
 		}
@@ -208,14 +255,20 @@ func (r *projectRepository) List(ctx context.Context, limit int, offset int) ([]
 		if err := json.Unmarshal(regionJSON, &project.Region); err != nil {
 			return nil, fmt.Errorf("unmarshal region: %w", err)
 		}
+		if project.Annotations, err = scanAnnotations(annotationsJSON); err != nil {
+			return nil, err
+		}
 
 		projects = append(projects, &project)
 	}
 
-	return projects, rows.Err()
+	return pageRows(projects, rows.Err(), page)
 }
 
 func (r *projectRepository) Count(ctx context.Context) (int, error) {
+	ctx, span := tracing.Repository(ctx, "projectRepository.Count")
+	defer span.End()
+
 	var count int
 	err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM projects").Scan(&count)
 	if err != nil {
@@ -224,47 +277,26 @@ func (r *projectRepository) Count(ctx context.Context) (int, error) {
 	return count, nil
 }
 
-func (r *projectRepository) ListWithFilters(ctx context.Context, filters ProjectFilters, limit int, offset int) ([]*models.Project, error) {
+func (r *projectRepository) ListWithFilters(ctx context.Context, filters ProjectFilters, page interfaces.ListPage) ([]*models.Project, error) {
+	ctx, span := tracing.Repository(ctx, "projectRepository.ListWithFilters")
+	defer span.End()
+
 	query := `
 		SELECT id, owner, languages, name, company, description, max_devices,
 			profile_img, header, sub_type, production, city_poster_frequency,
 			ad_poster_frequency, city_poster_play_time, loop_length,
 			smallbiz_support, proxy, address, latitude, longitude,
 			is_transit, scm_health, priority, replicas, region, status, role,
-			created_at, updated_at
+			created_at, updated_at,
+			(SELECT to_jsonb(a) FROM project_annotations a WHERE a.project_name = p.name) AS annotations
 		FROM projects p
 		WHERE 1=1
 	`
 
-	var args []any
-	argIndex := 1
-
-	if filters.City != nil || filters.Region != nil {
-		query += " AND EXISTS (SELECT 1 FROM devices d WHERE d.project = p.id"
-		if filters.City != nil {
-			query += fmt.Sprintf(" AND d.device_config->>'city' = $%d", argIndex)
-			args = append(args, *filters.City)
-			argIndex++
-		}
-		if filters.Region != nil {
-			query += fmt.Sprintf(" AND d.region->>'code' = $%d", argIndex)
-			args = append(args, *filters.Region)
-			argIndex++
-		}
-		query += ")"
-	}
+	clause, args, _ := buildProjectFilterClause(filters, nil, 1)
+	query += clause
 
-	query += " ORDER BY p.created_at DESC"
-	if limit > 0 {
-		query += fmt.Sprintf(" LIMIT $%d", argIndex)
-		args = append(args, limit)
-		argIndex++
-	}
-	if offset > 0 {
-		query += fmt.Sprintf(" OFFSET $%d", argIndex)
-		args = append(args, offset)
-		argIndex++
-	}
+	query, args = appendListPage(query, args, true, page, ProjectSort)
 
 	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
@@ -275,7 +307,7 @@ func (r *projectRepository) ListWithFilters(ctx context.Context, filters Project
 	var projects []*models.Project
 	for rows.Next() {
 		var project models.Project
-		var ownerJSON, languagesJSON, regionJSON []byte
+		var ownerJSON, languagesJSON, regionJSON, annotationsJSON []byte
 		if err := rows.Scan(
 			&project.ID, &ownerJSON, &languagesJSON, &project.Name, &project.Company,
 			&project.Description, &project.MaxDevices, &project.ProfileImg,
@@ -286,7 +318,7 @@ func (r *projectRepository) ListWithFilters(ctx context.Context, filters Project
 			&project.Latitude, &project.Longitude, &project.IsTransit,
 			&project.ScmHealth, &project.Priority, &project.Replicas,
 			&regionJSON, &project.Status, &project.Role,
-			&project.CreatedAt, &project.UpdatedAt,
+			&project.CreatedAt, &project.UpdatedAt, &annotationsJSON,
 		); err != nil {
 			return nil, fmt.Errorf("scan project: %w", err)
 		}
@@ -300,32 +332,23 @@ func (r *projectRepository) ListWithFilters(ctx context.Context, filters Project
 		if err := json.Unmarshal(regionJSON, &project.Region); err != nil {
 			return nil, fmt.Errorf("unmarshal region: %w", err)
 		}
+		if project.Annotations, err = scanAnnotations(annotationsJSON); err != nil {
+			return nil, err
+		}
 
 		projects = append(projects, &project)
 	}
 
-	return projects, rows.Err()
+	return pageRows(projects, rows.Err(), page)
 }
 
 func (r *projectRepository) CountWithFilters(ctx context.Context, filters ProjectFilters) (int, error) {
-	query := "SELECT COUNT(*) FROM projects p WHERE 1=1"
-	var args []any
-	argIndex := 1
+	ctx, span := tracing.Repository(ctx, "projectRepository.CountWithFilters")
+	defer span.End()
 
-	if filters.City != nil || filters.Region != nil {
-		query += " AND EXISTS (SELECT 1 FROM devices d WHERE d.project = p.id"
-		if filters.City != nil {
-			query += fmt.Sprintf(" AND d.device_config->>'city' = $%d", argIndex)
-			args = append(args, *filters.City)
-			argIndex++
-		}
-		if filters.Region != nil {
-			query += fmt.Sprintf(" AND d.region->>'code' = $%d", argIndex)
-			args = append(args, *filters.Region)
-			argIndex++
-		}
-		query += ")"
-	}
+	query := "SELECT COUNT(*) FROM projects p WHERE 1=1"
+	clause, args, _ := buildProjectFilterClause(filters, nil, 1)
+	query += clause
 
 	var count int
 	err := r.db.QueryRowContext(ctx, query, args...).Scan(&count)
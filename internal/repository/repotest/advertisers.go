@@ -0,0 +1,221 @@
+package repotest
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"maps"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"scm/internal/interfaces"
+	"scm/internal/models"
+)
+
+func testAdvertisers(t *testing.T, r Repos) {
+	ctx := context.Background()
+	owner := newUser(t, r, "owner")
+	acme := newAdvertiser(t, r, owner, "acme")
+	if acme.ID == "" || acme.Version != 1 || acme.CreatedAt.IsZero() {
+		t.Fatalf("Create did not fill in the advertiser: %+v", acme)
+	}
+
+	got, err := r.Advertisers.GetByID(ctx, acme.ID)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	if got.Name != "acme" || got.Email != "acme@example.com" || got.CreatedBy != owner.ID || got.Version != 1 {
+		t.Errorf("GetByID = %+v", got)
+	}
+	if _, err := r.Advertisers.GetByID(ctx, uuid.NewString()); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("GetByID(unknown) error = %v, want sql.ErrNoRows", err)
+	}
+
+	err = mustFail(t, "Create with a taken name", r.Advertisers.Create(ctx, &models.Advertiser{Name: "acme", CreatedBy: owner.ID}))
+	if pqCode(err) != "23505" {
+		t.Errorf("Create with a taken name error = %v, want a unique violation", err)
+	}
+	err = mustFail(t, "Create with an unknown owner", r.Advertisers.Create(ctx, &models.Advertiser{Name: "ghost", CreatedBy: uuid.NewString()}))
+	if pqCode(err) != "23503" || pqConstraint(err) != "advertisers_created_by_fkey" {
+		t.Errorf("Create with an unknown owner error = %v, want advertisers_created_by_fkey violation", err)
+	}
+
+	name := "acme-corp"
+	if err := r.Advertisers.Update(ctx, acme.ID, &models.UpdateAdvertiserRequest{Name: &name}, 1); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if err := r.Advertisers.Update(ctx, acme.ID, &models.UpdateAdvertiserRequest{Name: &name}, 1); !errors.Is(err, interfaces.ErrVersionConflict) {
+		t.Errorf("Update at a stale version error = %v, want ErrVersionConflict", err)
+	}
+	empty := ""
+	if err := r.Advertisers.Update(ctx, acme.ID, &models.UpdateAdvertiserRequest{Email: &empty}, 0); err != nil {
+		t.Fatalf("Update without a version check: %v", err)
+	}
+	got, _ = r.Advertisers.GetByID(ctx, acme.ID)
+	if got.Name != name || got.Email != "" || got.Version != 3 {
+		t.Errorf("after updates = %+v, want name %s, no email, version 3", got, name)
+	}
+	if err := r.Advertisers.Update(ctx, acme.ID, &models.UpdateAdvertiserRequest{}, 0); err == nil {
+		t.Error("Update with no fields: expected an error")
+	}
+	if err := r.Advertisers.Update(ctx, uuid.NewString(), &models.UpdateAdvertiserRequest{Name: &name}, 0); err == nil || err.Error() != "advertiser not found" {
+		t.Errorf("Update(unknown) error = %v, want advertiser not found", err)
+	}
+
+	globex := newAdvertiser(t, r, owner, "globex")
+	campaign := newCampaign(t, r, globex, "spring", models.CampaignStatusDraft)
+	var blocked *interfaces.DeletionBlockedError
+	if err := r.Advertisers.Delete(ctx, globex.ID); !errors.As(err, &blocked) || blocked.References["campaigns"] != 1 {
+		t.Errorf("Delete with a live campaign error = %v, want DeletionBlockedError for one campaign", err)
+	}
+	if err := r.Campaigns.Delete(ctx, campaign.ID); err != nil {
+		t.Fatalf("delete campaign: %v", err)
+	}
+	if err := r.Advertisers.Delete(ctx, globex.ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if err := r.Advertisers.Delete(ctx, globex.ID); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("Delete twice error = %v, want sql.ErrNoRows", err)
+	}
+	if _, err := r.Advertisers.GetByID(ctx, globex.ID); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("GetByID(deleted) error = %v, want sql.ErrNoRows", err)
+	}
+
+	if n, _ := r.Advertisers.Count(ctx, false); n != 1 {
+		t.Errorf("Count = %d, want 1", n)
+	}
+	if n, _ := r.Advertisers.Count(ctx, true); n != 2 {
+		t.Errorf("Count(includeDeleted) = %d, want 2", n)
+	}
+	list, err := r.Advertisers.List(ctx, interfaces.ListPage{}, true)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(list) != 2 || list[0].Name != name || list[1].Name != "globex" || list[1].DeletedAt == nil {
+		t.Errorf("List(includeDeleted) = %+v, want acme-corp then deleted globex", list)
+	}
+
+	// A deleted advertiser frees its name; restoring it while the name is taken fails.
+	reused := newAdvertiser(t, r, owner, "globex")
+	err = mustFail(t, "Restore onto a taken name", r.Advertisers.Restore(ctx, globex.ID))
+	if pqCode(err) != "23505" {
+		t.Errorf("Restore onto a taken name error = %v, want a unique violation", err)
+	}
+	newName := "globex-two"
+	if err := r.Advertisers.Update(ctx, reused.ID, &models.UpdateAdvertiserRequest{Name: &newName}, 0); err != nil {
+		t.Fatalf("rename: %v", err)
+	}
+	if err := r.Advertisers.Restore(ctx, globex.ID); err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+	if err := r.Advertisers.Restore(ctx, globex.ID); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("Restore of a live advertiser error = %v, want sql.ErrNoRows", err)
+	}
+	if got, err := r.Advertisers.GetByID(ctx, globex.ID); err != nil || got.DeletedAt != nil {
+		t.Errorf("GetByID(restored) = %+v, %v", got, err)
+	}
+}
+
+func testAdvertiserCascade(t *testing.T, r Repos) {
+	ctx := context.Background()
+	owner := newUser(t, r, "owner")
+	acme := newAdvertiser(t, r, owner, "acme")
+	spring := newCampaign(t, r, acme, "spring", models.CampaignStatusDraft)
+	summer := newCampaign(t, r, acme, "summer", models.CampaignStatusDraft)
+	banner := newCreative(t, r, spring, "banner")
+	clip := newCreative(t, r, summer, "clip")
+	if err := r.Campaigns.Delete(ctx, summer.ID); err == nil {
+		t.Fatal("Delete of a campaign with a live creative: expected an error")
+	}
+	if err := r.Creatives.Delete(ctx, clip.ID); err != nil {
+		t.Fatalf("delete creative: %v", err)
+	}
+
+	deps, err := r.Advertisers.Dependencies(ctx, acme.ID)
+	if err != nil {
+		t.Fatalf("Dependencies: %v", err)
+	}
+	want := map[string]int64{"campaigns": 2, "creatives": 2, "stored_objects": 2}
+	if deps.Resource != "advertiser" || deps.ID != acme.ID || !maps.Equal(deps.References, want) {
+		t.Errorf("Dependencies = %+v, want references %v", deps, want)
+	}
+	if len(deps.Campaigns) != 2 || deps.Campaigns[0].ID != spring.ID || deps.Campaigns[1].ID != summer.ID {
+		t.Errorf("dependent campaigns = %+v, want spring then summer", deps.Campaigns)
+	}
+	if len(deps.Creatives) != 2 || deps.Creatives[0].ID != banner.ID || deps.Creatives[1].DeletedAt == nil {
+		t.Errorf("dependent creatives = %+v, want banner then deleted clip", deps.Creatives)
+	}
+	if len(deps.StoredObjects) != 2 || deps.StoredObjects[0] != banner.FilePath {
+		t.Errorf("stored objects = %v", deps.StoredObjects)
+	}
+
+	campaignDeps, err := r.Campaigns.Dependencies(ctx, summer.ID)
+	if err != nil {
+		t.Fatalf("campaign Dependencies: %v", err)
+	}
+	if campaignDeps.References["creatives"] != 1 || campaignDeps.Creatives[0].ID != clip.ID {
+		t.Errorf("campaign Dependencies = %+v", campaignDeps)
+	}
+
+	// Purging deleted creatives leaves the live ones and returns the purged files.
+	if n, keys, err := r.Creatives.PurgeDeletedBefore(ctx, time.Now().Add(48*time.Hour)); err != nil || n != 1 || !slices.Equal(keys, []string{clip.FilePath}) {
+		t.Errorf("creative PurgeDeletedBefore = %d, %v, %v; want 1, [%s]", n, keys, err, clip.FilePath)
+	}
+	if n, _ := r.Creatives.CountAll(ctx, true); n != 1 {
+		t.Errorf("CountAll after purge = %d, want 1", n)
+	}
+
+	removed, err := r.Advertisers.DeleteCascade(ctx, acme.ID)
+	if err != nil {
+		t.Fatalf("DeleteCascade: %v", err)
+	}
+	if removed.References["campaigns"] != 2 || removed.References["creatives"] != 1 {
+		t.Errorf("DeleteCascade = %+v", removed)
+	}
+	if _, err := r.Advertisers.Dependencies(ctx, acme.ID); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("Dependencies after cascade error = %v, want sql.ErrNoRows", err)
+	}
+	if _, err := r.Advertisers.DeleteCascade(ctx, acme.ID); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("DeleteCascade twice error = %v, want sql.ErrNoRows", err)
+	}
+	if n, _ := r.Campaigns.Count(ctx, interfaces.CampaignFilter{IncludeDeleted: true}); n != 0 {
+		t.Errorf("campaigns left after cascade = %d", n)
+	}
+	if n, _ := r.Creatives.CountAll(ctx, true); n != 0 {
+		t.Errorf("creatives left after cascade = %d", n)
+	}
+
+	// Purging a deleted advertiser takes its campaigns and creatives with it, and returns
+	// the creatives' files.
+	globex := newAdvertiser(t, r, owner, "globex")
+	autumn := newCampaign(t, r, globex, "autumn", models.CampaignStatusDraft)
+	leaf := newCreative(t, r, autumn, "leaf")
+	if err := r.Creatives.Delete(ctx, leaf.ID); err != nil {
+		t.Fatalf("delete creative: %v", err)
+	}
+	if err := r.Campaigns.Delete(ctx, autumn.ID); err != nil {
+		t.Fatalf("delete campaign: %v", err)
+	}
+	if err := r.Advertisers.Delete(ctx, globex.ID); err != nil {
+		t.Fatalf("delete advertiser: %v", err)
+	}
+	if n, keys, err := r.Advertisers.PurgeDeletedBefore(ctx, time.Now().Add(-48*time.Hour)); err != nil || n != 0 || len(keys) != 0 {
+		t.Errorf("PurgeDeletedBefore(past) = %d, %v, %v; want 0", n, keys, err)
+	}
+	if n, keys, err := r.Advertisers.PurgeDeletedBefore(ctx, time.Now().Add(48*time.Hour)); err != nil || n != 1 || !slices.Equal(keys, []string{leaf.FilePath}) {
+		t.Errorf("PurgeDeletedBefore = %d, %v, %v; want 1, [%s]", n, keys, err, leaf.FilePath)
+	}
+	if _, err := r.Campaigns.GetByID(ctx, autumn.ID); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("GetByID(purged campaign) error = %v, want sql.ErrNoRows", err)
+	}
+	if n, _ := r.Advertisers.Count(ctx, true); n != 0 {
+		t.Errorf("advertisers left = %d", n)
+	}
+
+	// Owners of deleted and purged advertisers can be removed.
+	if err := r.Users.Delete(ctx, owner.ID); err != nil {
+		t.Errorf("delete owner: %v", err)
+	}
+}
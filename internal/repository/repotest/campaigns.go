@@ -0,0 +1,221 @@
+package repotest
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"scm/internal/interfaces"
+	"scm/internal/models"
+)
+
+func testCampaigns(t *testing.T, r Repos) {
+	ctx := context.Background()
+	owner := newUser(t, r, "owner")
+	acme := newAdvertiser(t, r, owner, "acme")
+	globex := newAdvertiser(t, r, owner, "globex")
+
+	spring := newCampaign(t, r, acme, "spring", models.CampaignStatusActive)
+	if spring.ID == "" || spring.Version != 1 || spring.CreatedAt.IsZero() || spring.UpdatedAt.IsZero() {
+		t.Fatalf("Create did not fill in the campaign: %+v", spring)
+	}
+	got, err := r.Campaigns.GetByID(ctx, spring.ID)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	if got.Name != "spring" || got.Status != models.CampaignStatusActive || !slices.Equal(got.Cities, []string{"berlin"}) ||
+		!got.StartDate.Equal(day) || got.Budget != 100.5 || got.AdvertiserID != acme.ID || got.Version != 1 {
+		t.Errorf("GetByID = %+v", got)
+	}
+	if _, err := r.Campaigns.GetByID(ctx, uuid.NewString()); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("GetByID(unknown) error = %v, want sql.ErrNoRows", err)
+	}
+
+	bare := &models.Campaign{Name: "bare", Status: models.CampaignStatusDraft, StartDate: day, EndDate: day.AddDate(0, 0, 7), Budget: 1.005, AdvertiserID: globex.ID}
+	if err := r.Campaigns.Create(ctx, bare); err != nil {
+		t.Fatalf("Create without cities: %v", err)
+	}
+	got, _ = r.Campaigns.GetByID(ctx, bare.ID)
+	if got.Cities == nil || len(got.Cities) != 0 {
+		t.Errorf("cities = %#v, want an empty list", got.Cities)
+	}
+	if got.Budget != 1.01 {
+		t.Errorf("budget = %v, want it rounded to 1.01", got.Budget)
+	}
+
+	err = mustFail(t, "Create for an unknown advertiser", r.Campaigns.Create(ctx, &models.Campaign{Name: "ghost", Status: models.CampaignStatusDraft, StartDate: day, EndDate: day, Budget: 1, AdvertiserID: uuid.NewString()}))
+	if pqCode(err) != "23503" || pqConstraint(err) != "campaigns_advertiser_id_fkey" {
+		t.Errorf("Create for an unknown advertiser error = %v, want campaigns_advertiser_id_fkey violation", err)
+	}
+
+	// Filters and the summary.
+	summer := newCampaign(t, r, acme, "summer", models.CampaignStatusPaused)
+	count := func(filter interfaces.CampaignFilter) int {
+		t.Helper()
+		n, err := r.Campaigns.Count(ctx, filter)
+		if err != nil {
+			t.Fatalf("Count(%+v): %v", filter, err)
+		}
+		list, err := r.Campaigns.List(ctx, filter)
+		if err != nil {
+			t.Fatalf("List(%+v): %v", filter, err)
+		}
+		if len(list) != n {
+			t.Errorf("List(%+v) returned %d campaigns, Count %d", filter, len(list), n)
+		}
+		return n
+	}
+	for _, tc := range []struct {
+		filter interfaces.CampaignFilter
+		want   int
+	}{
+		{interfaces.CampaignFilter{}, 3},
+		{interfaces.CampaignFilter{AdvertiserID: acme.ID}, 2},
+		{interfaces.CampaignFilter{Status: "paused"}, 1},
+		{interfaces.CampaignFilter{StartDate: day}, 3},
+		{interfaces.CampaignFilter{StartDate: day.Add(time.Hour)}, 0},
+		{interfaces.CampaignFilter{EndDate: day.AddDate(0, 0, 7)}, 1},
+	} {
+		if n := count(tc.filter); n != tc.want {
+			t.Errorf("Count(%+v) = %d, want %d", tc.filter, n, tc.want)
+		}
+	}
+	summary, err := r.Campaigns.Summary(ctx, interfaces.CampaignFilter{AdvertiserID: acme.ID})
+	if err != nil {
+		t.Fatalf("Summary: %v", err)
+	}
+	if summary.ActiveCampaignCount != 1 || summary.TotalBudget != 201 || summary.TotalImpression != 20 {
+		t.Errorf("Summary = %+v", summary)
+	}
+
+	// Update is a full replace guarded by the version.
+	summer.Status = models.CampaignStatusActive
+	summer.Cities = nil
+	summer.Spent = 12.345
+	if err := r.Campaigns.Update(ctx, summer.ID, summer, summer.Version); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if summer.Version != 2 {
+		t.Errorf("version after Update = %d, want 2", summer.Version)
+	}
+	got, _ = r.Campaigns.GetByID(ctx, summer.ID)
+	if got.Status != models.CampaignStatusActive || len(got.Cities) != 0 || got.Spent != 12.35 || got.Version != 2 {
+		t.Errorf("after Update = %+v", got)
+	}
+	if err := r.Campaigns.Update(ctx, summer.ID, summer, 1); !errors.Is(err, interfaces.ErrVersionConflict) {
+		t.Errorf("Update at a stale version error = %v, want ErrVersionConflict", err)
+	}
+	if err := r.Campaigns.Update(ctx, uuid.NewString(), summer, 0); err == nil || err.Error() != "campaign not found" {
+		t.Errorf("Update(unknown) error = %v, want campaign not found", err)
+	}
+	moved := *summer
+	moved.AdvertiserID = uuid.NewString()
+	err = mustFail(t, "Update to an unknown advertiser", r.Campaigns.Update(ctx, summer.ID, &moved, 0))
+	if pqCode(err) != "23503" {
+		t.Errorf("Update to an unknown advertiser error = %v, want a foreign key violation", err)
+	}
+
+	// Soft delete and restore.
+	newCreative(t, r, summer, "banner")
+	var blocked *interfaces.DeletionBlockedError
+	if err := r.Campaigns.Delete(ctx, summer.ID); !errors.As(err, &blocked) || blocked.References["creatives"] != 1 {
+		t.Errorf("Delete with a live creative error = %v, want DeletionBlockedError", err)
+	}
+	if err := r.Campaigns.Delete(ctx, bare.ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if err := r.Campaigns.Delete(ctx, bare.ID); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("Delete twice error = %v, want sql.ErrNoRows", err)
+	}
+	if err := r.Campaigns.Update(ctx, bare.ID, bare, 0); err == nil || err.Error() != "campaign not found" {
+		t.Errorf("Update(deleted) error = %v, want campaign not found", err)
+	}
+	if n := count(interfaces.CampaignFilter{}); n != 2 {
+		t.Errorf("Count after delete = %d, want 2", n)
+	}
+	if n := count(interfaces.CampaignFilter{IncludeDeleted: true}); n != 3 {
+		t.Errorf("Count(includeDeleted) = %d, want 3", n)
+	}
+	if err := r.Advertisers.Delete(ctx, globex.ID); err != nil {
+		t.Fatalf("delete advertiser: %v", err)
+	}
+	var restoreBlocked *interfaces.RestoreBlockedError
+	if err := r.Campaigns.Restore(ctx, bare.ID); !errors.As(err, &restoreBlocked) || restoreBlocked.Parent != "advertiser" {
+		t.Errorf("Restore under a deleted advertiser error = %v, want RestoreBlockedError", err)
+	}
+	if err := r.Advertisers.Restore(ctx, globex.ID); err != nil {
+		t.Fatalf("restore advertiser: %v", err)
+	}
+	if err := r.Campaigns.Restore(ctx, bare.ID); err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+	if err := r.Campaigns.Restore(ctx, bare.ID); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("Restore of a live campaign error = %v, want sql.ErrNoRows", err)
+	}
+
+	if _, err := r.Campaigns.Dependencies(ctx, uuid.NewString()); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("Dependencies(unknown) error = %v, want sql.ErrNoRows", err)
+	}
+	removed, err := r.Campaigns.DeleteCascade(ctx, summer.ID)
+	if err != nil {
+		t.Fatalf("DeleteCascade: %v", err)
+	}
+	if removed.References["creatives"] != 1 || len(removed.StoredObjects) != 1 {
+		t.Errorf("DeleteCascade = %+v", removed)
+	}
+	if n, _ := r.Creatives.CountAll(ctx, true); n != 0 {
+		t.Errorf("creatives left after cascade = %d", n)
+	}
+}
+
+func testCampaignScheduler(t *testing.T, r Repos) {
+	ctx := context.Background()
+	owner := newUser(t, r, "owner")
+	acme := newAdvertiser(t, r, owner, "acme")
+
+	scheduled := newCampaign(t, r, acme, "scheduled", models.CampaignStatusScheduled)
+	later := newCampaign(t, r, acme, "later", models.CampaignStatusScheduled)
+	later.StartDate = day.AddDate(0, 0, 1)
+	if err := r.Campaigns.Update(ctx, later.ID, later, 0); err != nil {
+		t.Fatalf("update campaign: %v", err)
+	}
+	active := newCampaign(t, r, acme, "active", models.CampaignStatusActive)
+
+	// 23:30 UTC on the day before is already the start day in Berlin.
+	eve := day.Add(-30 * time.Minute)
+	if ids, err := r.Campaigns.ActivateScheduledStartingOn(ctx, eve, "", "UTC"); err != nil || len(ids) != 0 {
+		t.Errorf("ActivateScheduledStartingOn(UTC) = %v, %v; want none", ids, err)
+	}
+	if ids, err := r.Campaigns.ActivateScheduledStartingOn(ctx, eve, "scheduled", "Europe/Berlin"); err != nil || !slices.Equal(ids, []string{scheduled.ID}) {
+		t.Errorf("ActivateScheduledStartingOn(Europe/Berlin) = %v, %v; want [%s]", ids, err, scheduled.ID)
+	}
+	got, _ := r.Campaigns.GetByID(ctx, scheduled.ID)
+	if got.Status != models.CampaignStatusActive || got.Version != 2 {
+		t.Errorf("activated campaign = %+v", got)
+	}
+	if got, _ := r.Campaigns.GetByID(ctx, later.ID); got.Status != models.CampaignStatusScheduled {
+		t.Errorf("later campaign status = %s, want scheduled", got.Status)
+	}
+	if _, err := r.Campaigns.ActivateScheduledStartingOn(ctx, eve, "", "Mars/Olympus"); err == nil {
+		t.Error("ActivateScheduledStartingOn with an unknown time zone: expected an error")
+	}
+
+	end := active.EndDate
+	if ids, err := r.Campaigns.CompleteActiveEndedBefore(ctx, end.Add(12*time.Hour), "", "", ""); err != nil || len(ids) != 0 {
+		t.Errorf("CompleteActiveEndedBefore(end day) = %v, %v; want none", ids, err)
+	}
+	ids, err := r.Campaigns.CompleteActiveEndedBefore(ctx, end.AddDate(0, 0, 1), "active", "completed", "UTC")
+	want := []string{scheduled.ID, active.ID}
+	slices.Sort(ids)
+	slices.Sort(want)
+	if err != nil || !slices.Equal(ids, want) {
+		t.Errorf("CompleteActiveEndedBefore(next day) = %v, %v; want %v", ids, err, want)
+	}
+	if got, _ := r.Campaigns.GetByID(ctx, active.ID); got.Status != models.CampaignStatusCompleted {
+		t.Errorf("completed campaign status = %s", got.Status)
+	}
+}
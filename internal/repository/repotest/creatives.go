@@ -0,0 +1,199 @@
+package repotest
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"scm/internal/interfaces"
+	"scm/internal/models"
+)
+
+func testCreatives(t *testing.T, r Repos) {
+	ctx := context.Background()
+	owner := newUser(t, r, "owner")
+	acme := newAdvertiser(t, r, owner, "acme")
+	spring := newCampaign(t, r, acme, "spring", models.CampaignStatusActive)
+	summer := newCampaign(t, r, acme, "summer", models.CampaignStatusActive)
+
+	banner := newCreative(t, r, spring, "banner", "screen-1")
+	if banner.Version != 1 {
+		t.Errorf("version after Create = %d, want 1", banner.Version)
+	}
+	got, err := r.Creatives.GetByID(ctx, banner.ID)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	if got.Name != "banner" || got.Type != models.CreativeTypeImage || got.FilePath != banner.FilePath || got.CampaignID != spring.ID ||
+		!slices.Equal(got.Devices, []string{"screen-1"}) || !got.UploadedAt.Equal(banner.UploadedAt) || got.Version != 1 {
+		t.Errorf("GetByID = %+v", got)
+	}
+	if _, err := r.Creatives.GetByID(ctx, uuid.NewString()); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("GetByID(unknown) error = %v, want sql.ErrNoRows", err)
+	}
+
+	// Array columns are never NULL, even when created without devices.
+	clip := newCreative(t, r, summer, "clip")
+	got, _ = r.Creatives.GetByID(ctx, clip.ID)
+	if got.Devices == nil || len(got.Devices) != 0 {
+		t.Errorf("devices = %#v, want an empty list", got.Devices)
+	}
+
+	dup := *banner
+	err = mustFail(t, "Create with a taken ID", r.Creatives.Create(ctx, &dup))
+	if pqCode(err) != "23505" {
+		t.Errorf("Create with a taken ID error = %v, want a unique violation", err)
+	}
+	orphan := *banner
+	orphan.ID = uuid.NewString()
+	orphan.CampaignID = uuid.NewString()
+	err = mustFail(t, "Create for an unknown campaign", r.Creatives.Create(ctx, &orphan))
+	if pqCode(err) != "23503" || pqConstraint(err) != "creatives_campaign_id_fkey" {
+		t.Errorf("Create for an unknown campaign error = %v, want creatives_campaign_id_fkey violation", err)
+	}
+	malformed := *banner
+	malformed.ID = "not-a-uuid"
+	err = mustFail(t, "Create with a malformed ID", r.Creatives.Create(ctx, &malformed))
+	if pqCode(err) != "22P02" {
+		t.Errorf("Create with a malformed ID error = %v, want invalid_text_representation", err)
+	}
+
+	poster := newCreative(t, r, spring, "poster")
+	if n, _ := r.Creatives.CountByCampaign(ctx, spring.ID, false); n != 2 {
+		t.Errorf("CountByCampaign = %d, want 2", n)
+	}
+	list, err := r.Creatives.ListByCampaign(ctx, spring.ID, interfaces.ListPage{}, false)
+	if err != nil {
+		t.Fatalf("ListByCampaign: %v", err)
+	}
+	// The default sort is newest upload first.
+	if len(list) != 2 || list[0].ID != poster.ID || list[1].ID != banner.ID {
+		t.Errorf("ListByCampaign = %v, want poster then banner", creativeNames(list))
+	}
+
+	// Update only touches the fields it is given.
+	name := "big-banner"
+	days := []string{"monday", "tuesday"}
+	if err := r.Creatives.Update(ctx, banner.ID, &models.UpdateCreativeRequest{Name: &name, SelectedDays: &days}, 1); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	got, _ = r.Creatives.GetByID(ctx, banner.ID)
+	if got.Name != name || !slices.Equal(got.SelectedDays, days) || !slices.Equal(got.TimeSlots, banner.TimeSlots) || got.URL != banner.URL || got.Version != 2 {
+		t.Errorf("after Update = %+v", got)
+	}
+	if err := r.Creatives.Update(ctx, banner.ID, &models.UpdateCreativeRequest{Name: &name}, 1); !errors.Is(err, interfaces.ErrVersionConflict) {
+		t.Errorf("Update at a stale version error = %v, want ErrVersionConflict", err)
+	}
+	if err := r.Creatives.Update(ctx, uuid.NewString(), &models.UpdateCreativeRequest{Name: &name}, 0); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("Update(unknown) error = %v, want sql.ErrNoRows", err)
+	}
+
+	// Soft delete and restore.
+	if err := r.Creatives.Delete(ctx, clip.ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if err := r.Creatives.Delete(ctx, clip.ID); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("Delete twice error = %v, want sql.ErrNoRows", err)
+	}
+	if _, err := r.Creatives.GetByID(ctx, clip.ID); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("GetByID(deleted) error = %v, want sql.ErrNoRows", err)
+	}
+	if n, _ := r.Creatives.CountAll(ctx, false); n != 2 {
+		t.Errorf("CountAll = %d, want 2", n)
+	}
+	all, err := r.Creatives.ListAll(ctx, interfaces.ListPage{}, true)
+	if err != nil {
+		t.Fatalf("ListAll: %v", err)
+	}
+	if len(all) != 3 || all[1].ID != clip.ID || all[1].DeletedAt == nil {
+		t.Errorf("ListAll(includeDeleted) = %v, want poster, deleted clip, banner", creativeNames(all))
+	}
+	if n, _ := r.Creatives.CountByCampaign(ctx, summer.ID, true); n != 1 {
+		t.Errorf("CountByCampaign(includeDeleted) = %d, want 1", n)
+	}
+
+	if err := r.Campaigns.Delete(ctx, summer.ID); err != nil {
+		t.Fatalf("delete campaign: %v", err)
+	}
+	var blocked *interfaces.RestoreBlockedError
+	if err := r.Creatives.Restore(ctx, clip.ID); !errors.As(err, &blocked) || blocked.Parent != "campaign" {
+		t.Errorf("Restore under a deleted campaign error = %v, want RestoreBlockedError", err)
+	}
+	if err := r.Campaigns.Restore(ctx, summer.ID); err != nil {
+		t.Fatalf("restore campaign: %v", err)
+	}
+	if err := r.Creatives.Restore(ctx, clip.ID); err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+	if err := r.Creatives.Restore(ctx, clip.ID); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("Restore of a live creative error = %v, want sql.ErrNoRows", err)
+	}
+	if got, _ := r.Creatives.GetByID(ctx, clip.ID); got == nil || got.DeletedAt != nil || got.Version != 3 {
+		t.Errorf("restored creative = %+v, want live at version 3", got)
+	}
+}
+
+func testCreativesByDevice(t *testing.T, r Repos) {
+	ctx := context.Background()
+	owner := newUser(t, r, "owner")
+	acme := newAdvertiser(t, r, owner, "acme")
+	spring := newCampaign(t, r, acme, "spring", models.CampaignStatusActive)
+
+	newCreative(t, r, spring, "weekday", " Screen-1 ", "screen-2")
+	evening := newCreative(t, r, spring, "evening", "screen-1")
+	slots := []string{"18:00-23:59", "07:30"}
+	days := []string{"Monday", "saturday"}
+	if err := r.Creatives.Update(ctx, evening.ID, &models.UpdateCreativeRequest{TimeSlots: &slots, SelectedDays: &days}, 0); err != nil {
+		t.Fatalf("update creative: %v", err)
+	}
+	gone := newCreative(t, r, spring, "gone", "screen-1")
+	if err := r.Creatives.Delete(ctx, gone.ID); err != nil {
+		t.Fatalf("delete creative: %v", err)
+	}
+
+	monday := func(clock string) time.Time {
+		tm, _ := time.Parse("15:04", clock)
+		return day.Add(time.Duration(tm.Hour())*time.Hour + time.Duration(tm.Minute())*time.Minute)
+	}
+	for _, tc := range []struct {
+		device    string
+		activeNow bool
+		now       time.Time
+		want      []string
+	}{
+		{"screen-1", false, time.Time{}, []string{"evening", "weekday"}},
+		{"SCREEN-2", false, time.Time{}, []string{"weekday"}},
+		{"screen-3", false, time.Time{}, nil},
+		{"screen-1", true, monday("09:00"), []string{"weekday"}},
+		{"screen-1", true, monday("17:00"), []string{"weekday"}},
+		{"screen-1", true, monday("17:01"), nil},
+		{"screen-1", true, monday("07:30"), []string{"evening"}},
+		{"screen-1", true, monday("20:15"), []string{"evening"}},
+		{"screen-1", true, day.AddDate(0, 0, 1).Add(20 * time.Hour), nil}, // a Tuesday
+		{"screen-1", true, day.AddDate(0, 0, 5).Add(12 * time.Hour), nil}, // a Saturday, outside every slot
+	} {
+		list, err := r.Creatives.ListByDevice(ctx, tc.device, tc.activeNow, tc.now, interfaces.ListPage{})
+		if err != nil {
+			t.Fatalf("ListByDevice(%s, %v, %v): %v", tc.device, tc.activeNow, tc.now, err)
+		}
+		if got := creativeNames(list); !slices.Equal(got, tc.want) {
+			t.Errorf("ListByDevice(%s, %v, %v) = %v, want %v", tc.device, tc.activeNow, tc.now, got, tc.want)
+		}
+		n, err := r.Creatives.CountByDevice(ctx, tc.device, tc.activeNow, tc.now)
+		if err != nil || n != len(tc.want) {
+			t.Errorf("CountByDevice(%s, %v, %v) = %d, %v; want %d", tc.device, tc.activeNow, tc.now, n, err, len(tc.want))
+		}
+	}
+}
+
+func creativeNames(creatives []*models.Creative) []string {
+	var names []string
+	for _, c := range creatives {
+		names = append(names, c.Name)
+	}
+	return names
+}
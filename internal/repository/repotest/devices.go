@@ -0,0 +1,316 @@
+package repotest
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+
+	"scm/internal/models"
+	"scm/internal/repository"
+)
+
+// synced is the last sync time the device fixtures are stamped with.
+var synced = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func newProject(t *testing.T, r Repos, id int, name string) *models.Project {
+	t.Helper()
+	p := &models.Project{
+		ID:        id,
+		Owner:     models.Owner{Username: "owner"},
+		Languages: []string{"de", "en"},
+		Name:      name,
+		Region:    []int{1},
+	}
+	if err := r.Projects.Upsert(context.Background(), p); err != nil {
+		t.Fatalf("upsert project %s: %v", name, err)
+	}
+	return p
+}
+
+// deviceSpec describes a device fixture.
+type deviceSpec struct {
+	id         int
+	hostName   string
+	project    int
+	city       string
+	region     string
+	typeCode   string
+	production bool
+	transit    bool
+	change     bool
+	syncedAt   *time.Time
+	syncStatus *string
+}
+
+func newDevice(t *testing.T, r Repos, spec deviceSpec) *models.Device {
+	t.Helper()
+	config, _ := json.Marshal(map[string]any{"city": spec.city, "screen": map[string]any{"width": 1920, "portrait": spec.typeCode == "kiosk"}})
+	d := &models.Device{
+		ID:           spec.id,
+		DeviceType:   models.DeviceType{ID: 1, Name: spec.typeCode + " model", Code: spec.typeCode},
+		Region:       models.Region{ID: 1, Code: spec.region, Name: spec.region, Production: spec.production, IsTransit: spec.transit, TimeZone: "Europe/Berlin"},
+		Name:         spec.hostName,
+		HostName:     spec.hostName,
+		Change:       spec.change,
+		LastSyncedAt: spec.syncedAt,
+		SyncStatus:   spec.syncStatus,
+		Project:      spec.project,
+		DeviceConfig: config,
+	}
+	if err := r.Devices.Upsert(context.Background(), d); err != nil {
+		t.Fatalf("upsert device %s: %v", spec.hostName, err)
+	}
+	return d
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+// sameJSON reports whether a and b hold the same JSON value.
+func sameJSON(a, b []byte) bool {
+	var av, bv any
+	if json.Unmarshal(a, &av) != nil || json.Unmarshal(b, &bv) != nil {
+		return false
+	}
+	return reflect.DeepEqual(av, bv)
+}
+
+func testProjects(t *testing.T, r Repos) {
+	ctx := context.Background()
+	alpha := newProject(t, r, 10, "alpha")
+	got, err := r.Projects.GetByName(ctx, "alpha")
+	if err != nil {
+		t.Fatalf("GetByName: %v", err)
+	}
+	if got.ID != 10 || got.Owner != alpha.Owner || !slices.Equal(got.Languages, alpha.Languages) || !slices.Equal(got.Region, alpha.Region) {
+		t.Errorf("GetByName = %+v", got)
+	}
+	if _, err := r.Projects.GetByName(ctx, "omega"); err == nil || err.Error() != "project not found" {
+		t.Errorf("GetByName(unknown) error = %v, want project not found", err)
+	}
+
+	// The console may renumber a project; the upsert follows it by name.
+	newProject(t, r, 11, "alpha")
+	if got, _ := r.Projects.GetByName(ctx, "alpha"); got == nil || got.ID != 11 {
+		t.Errorf("project after renumbering = %+v, want ID 11", got)
+	}
+	company := "Acme GmbH"
+	updated := &models.Project{ID: 11, Owner: alpha.Owner, Name: "alpha", Company: &company}
+	if err := r.Projects.Upsert(ctx, updated); err != nil {
+		t.Fatalf("Upsert: %v", err)
+	}
+	if got, _ := r.Projects.GetByName(ctx, "alpha"); got.Company == nil || *got.Company != company {
+		t.Errorf("company after Upsert = %v, want %s", got.Company, company)
+	}
+	err = mustFail(t, "Upsert with a taken ID", r.Projects.Upsert(ctx, &models.Project{ID: 11, Name: "beta"}))
+	if pqCode(err) != "23505" || pqConstraint(err) != "projects_pkey" {
+		t.Errorf("Upsert with a taken ID error = %v, want projects_pkey violation", err)
+	}
+
+	newProject(t, r, 20, "beta")
+	newDevice(t, r, deviceSpec{id: 1, hostName: "kiosk-1", project: 11, city: "berlin", region: "de-be", typeCode: "kiosk"})
+	newDevice(t, r, deviceSpec{id: 2, hostName: "screen-1", project: 20, city: "hamburg", region: "de-hh", typeCode: "screen"})
+	err = mustFail(t, "renumbering a project with devices", r.Projects.Upsert(ctx, &models.Project{ID: 12, Owner: alpha.Owner, Name: "alpha"}))
+	if pqCode(err) != "23503" {
+		t.Errorf("renumbering a project with devices error = %v, want a foreign key violation", err)
+	}
+
+	if n, _ := r.Projects.Count(ctx); n != 2 {
+		t.Errorf("Count = %d, want 2", n)
+	}
+	list, err := r.Projects.List(ctx, sorted(t, repository.ProjectSort, "name"))
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(list) != 2 || list[0].Name != "alpha" || list[1].Name != "beta" {
+		t.Errorf("List = %+v, want alpha then beta", list)
+	}
+	for _, tc := range []struct {
+		filters repository.ProjectFilters
+		want    []string
+	}{
+		{repository.ProjectFilters{City: ptr("berlin")}, []string{"alpha"}},
+		{repository.ProjectFilters{Region: ptr("de-hh")}, []string{"beta"}},
+		{repository.ProjectFilters{City: ptr("berlin"), Region: ptr("de-hh")}, nil},
+		{repository.ProjectFilters{City: ptr("munich")}, nil},
+	} {
+		list, err := r.Projects.ListWithFilters(ctx, tc.filters, sorted(t, repository.ProjectSort, "name"))
+		if err != nil {
+			t.Fatalf("ListWithFilters(%+v): %v", tc.filters, err)
+		}
+		var names []string
+		for _, p := range list {
+			names = append(names, p.Name)
+		}
+		if !slices.Equal(names, tc.want) {
+			t.Errorf("ListWithFilters(%+v) = %v, want %v", tc.filters, names, tc.want)
+		}
+		if n, _ := r.Projects.CountWithFilters(ctx, tc.filters); n != len(tc.want) {
+			t.Errorf("CountWithFilters(%+v) = %d, want %d", tc.filters, n, len(tc.want))
+		}
+	}
+}
+
+func testDevices(t *testing.T, r Repos) {
+	ctx := context.Background()
+	newProject(t, r, 10, "alpha")
+	kiosk := newDevice(t, r, deviceSpec{id: 1, hostName: "kiosk-1", project: 10, city: "berlin", region: "de-be", typeCode: "kiosk", syncedAt: &synced, syncStatus: ptr("ok")})
+
+	got, err := r.Devices.GetByHostName(ctx, "kiosk-1")
+	if err != nil {
+		t.Fatalf("GetByHostName: %v", err)
+	}
+	if got.ID != 1 || got.Project != 10 || got.DeviceType != kiosk.DeviceType || got.Region.Code != "de-be" || got.Region.TimeZone != "Europe/Berlin" ||
+		got.LastSyncedAt == nil || !got.LastSyncedAt.Equal(synced) || *got.SyncStatus != "ok" || !sameJSON(got.DeviceConfig, kiosk.DeviceConfig) {
+		t.Errorf("GetByHostName = %+v", got)
+	}
+	if got.CreatedAt.IsZero() || got.UpdatedAt.IsZero() {
+		t.Errorf("timestamps not set: %+v", got)
+	}
+	if _, err := r.Devices.GetByHostName(ctx, "kiosk-9"); err == nil || err.Error() != "device not found" {
+		t.Errorf("GetByHostName(unknown) error = %v, want device not found", err)
+	}
+
+	err = mustFail(t, "Upsert into an unknown project", r.Devices.Upsert(ctx, &models.Device{ID: 2, HostName: "kiosk-2", Project: 99, DeviceConfig: json.RawMessage(`{}`)}))
+	if pqCode(err) != "23503" || pqConstraint(err) != "devices_project_fkey" {
+		t.Errorf("Upsert into an unknown project error = %v, want devices_project_fkey violation", err)
+	}
+	err = mustFail(t, "Upsert with a taken ID", r.Devices.Upsert(ctx, &models.Device{ID: 1, HostName: "kiosk-2", Project: 10, DeviceConfig: json.RawMessage(`{}`)}))
+	if pqCode(err) != "23505" || pqConstraint(err) != "devices_pkey" {
+		t.Errorf("Upsert with a taken ID error = %v, want devices_pkey violation", err)
+	}
+
+	// Upserting by host name replaces the record, ID included, and keeps created_at.
+	renumbered := newDevice(t, r, deviceSpec{id: 5, hostName: "kiosk-1", project: 10, city: "potsdam", region: "de-bb", typeCode: "kiosk"})
+	after, _ := r.Devices.GetByHostName(ctx, "kiosk-1")
+	if after.ID != 5 || after.Region.Code != "de-bb" || after.LastSyncedAt != nil || after.SyncStatus != nil || !sameJSON(after.DeviceConfig, renumbered.DeviceConfig) {
+		t.Errorf("device after Upsert = %+v", after)
+	}
+	if !after.CreatedAt.Equal(got.CreatedAt) {
+		t.Errorf("created_at changed from %v to %v", got.CreatedAt, after.CreatedAt)
+	}
+	if n, _ := r.Devices.Count(ctx); n != 1 {
+		t.Errorf("Count = %d, want 1", n)
+	}
+
+	newDevice(t, r, deviceSpec{id: 6, hostName: "kiosk-2", project: 10, city: "potsdam", region: "de-bb", typeCode: "kiosk", syncedAt: &synced})
+	markers, err := r.Devices.ListSyncMarkers(ctx)
+	if err != nil {
+		t.Fatalf("ListSyncMarkers: %v", err)
+	}
+	if len(markers) != 2 || markers["kiosk-1"] != nil || markers["kiosk-2"] == nil || !markers["kiosk-2"].Equal(synced) {
+		t.Errorf("ListSyncMarkers = %v", markers)
+	}
+	if n, _ := r.Devices.CountByProject(ctx, 10); n != 2 {
+		t.Errorf("CountByProject = %d, want 2", n)
+	}
+	list, err := r.Devices.ListByProject(ctx, 10, sorted(t, repository.DeviceSort, "-host_name"))
+	if err != nil {
+		t.Fatalf("ListByProject: %v", err)
+	}
+	if len(list) != 2 || list[0].HostName != "kiosk-2" || list[1].HostName != "kiosk-1" {
+		t.Errorf("ListByProject = %v, want kiosk-2 then kiosk-1", hostNames(list))
+	}
+}
+
+// deviceFleet upserts the devices the filter and venue tests select from:
+//
+//	id  host name         project  city     region  type    flags
+//	1   kiosk-berlin-1    10       berlin   de-be   kiosk   production, synced ok
+//	2   kiosk-berlin-2    10       berlin   de-be   kiosk   production, changed
+//	3   screen-hamburg-1  20       hamburg  de-hh   screen  transit, synced failed
+//	4   screen-berlin-1   20       berlin   de-be   screen  -
+func deviceFleet(t *testing.T, r Repos) {
+	t.Helper()
+	newProject(t, r, 10, "alpha")
+	newProject(t, r, 20, "beta")
+	later := synced.Add(time.Hour)
+	newDevice(t, r, deviceSpec{id: 1, hostName: "kiosk-berlin-1", project: 10, city: "berlin", region: "de-be", typeCode: "kiosk", production: true, syncedAt: &synced, syncStatus: ptr("ok")})
+	newDevice(t, r, deviceSpec{id: 2, hostName: "kiosk-berlin-2", project: 10, city: "berlin", region: "de-be", typeCode: "kiosk", production: true, change: true})
+	newDevice(t, r, deviceSpec{id: 3, hostName: "screen-hamburg-1", project: 20, city: "hamburg", region: "de-hh", typeCode: "screen", transit: true, syncedAt: &later, syncStatus: ptr("failed")})
+	newDevice(t, r, deviceSpec{id: 4, hostName: "screen-berlin-1", project: 20, city: "berlin", region: "de-be", typeCode: "screen"})
+}
+
+func hostNames(devices []*models.Device) []string {
+	var names []string
+	for _, d := range devices {
+		names = append(names, d.HostName)
+	}
+	return names
+}
+
+func testDeviceFilters(t *testing.T, r Repos) {
+	ctx := context.Background()
+	deviceFleet(t, r)
+	offline := models.DeviceHealthOffline
+	online := models.DeviceHealthOnline
+	thresholds := models.HealthThresholds{StaleAfter: 5 * time.Minute, OfflineAfter: time.Hour}
+
+	for _, tc := range []struct {
+		name    string
+		filters repository.DeviceFilters
+		want    []string
+	}{
+		{"none", repository.DeviceFilters{}, []string{"kiosk-berlin-1", "kiosk-berlin-2", "screen-berlin-1", "screen-hamburg-1"}},
+		{"project", repository.DeviceFilters{ProjectID: ptr(20)}, []string{"screen-berlin-1", "screen-hamburg-1"}},
+		{"city", repository.DeviceFilters{City: ptr("berlin")}, []string{"kiosk-berlin-1", "kiosk-berlin-2", "screen-berlin-1"}},
+		{"region", repository.DeviceFilters{Region: ptr("de-hh")}, []string{"screen-hamburg-1"}},
+		{"device type", repository.DeviceFilters{DeviceType: ptr("kiosk")}, []string{"kiosk-berlin-1", "kiosk-berlin-2"}},
+		{"device type substring", repository.DeviceFilters{DeviceType: ptr("een mod")}, []string{"screen-berlin-1", "screen-hamburg-1"}},
+		{"host name glob", repository.DeviceFilters{HostNameGlob: ptr("*-berlin-?")}, []string{"kiosk-berlin-1", "kiosk-berlin-2", "screen-berlin-1"}},
+		{"host name glob literal", repository.DeviceFilters{HostNameGlob: ptr("kiosk_berlin*")}, nil},
+		{"offline", repository.DeviceFilters{Health: &offline, HealthThresholds: thresholds}, []string{"kiosk-berlin-1", "kiosk-berlin-2", "screen-berlin-1", "screen-hamburg-1"}},
+		{"online", repository.DeviceFilters{Health: &online, HealthThresholds: thresholds}, nil},
+		{"sync status", repository.DeviceFilters{SyncStatus: ptr("failed")}, []string{"screen-hamburg-1"}},
+		{"change", repository.DeviceFilters{Change: ptr(true)}, []string{"kiosk-berlin-2"}},
+		{"synced after", repository.DeviceFilters{SyncedAfter: &synced}, []string{"kiosk-berlin-1", "screen-hamburg-1"}},
+		{"synced before", repository.DeviceFilters{SyncedBefore: ptr(synced.Add(time.Hour))}, []string{"kiosk-berlin-1"}},
+		{"production", repository.DeviceFilters{Production: ptr(true)}, []string{"kiosk-berlin-1", "kiosk-berlin-2"}},
+		{"not production", repository.DeviceFilters{Production: ptr(false)}, []string{"screen-berlin-1", "screen-hamburg-1"}},
+		{"transit", repository.DeviceFilters{IsTransit: ptr(true)}, []string{"screen-hamburg-1"}},
+		{"config", repository.DeviceFilters{Config: []repository.DeviceConfigFilter{{Path: []string{"screen", "portrait"}, Value: "true"}}}, []string{"kiosk-berlin-1", "kiosk-berlin-2"}},
+		{"config number", repository.DeviceFilters{Config: []repository.DeviceConfigFilter{{Path: []string{"screen", "width"}, Value: "1920"}}, City: ptr("hamburg")}, []string{"screen-hamburg-1"}},
+		{"config missing", repository.DeviceFilters{Config: []repository.DeviceConfigFilter{{Path: []string{"screen", "height"}, Value: "1080"}}}, nil},
+		{"combined", repository.DeviceFilters{City: ptr("berlin"), ProjectID: ptr(10), Change: ptr(false)}, []string{"kiosk-berlin-1"}},
+	} {
+		list, err := r.Devices.ListWithFilters(ctx, tc.filters, sorted(t, repository.DeviceSort, "host_name"))
+		if err != nil {
+			t.Fatalf("%s: ListWithFilters: %v", tc.name, err)
+		}
+		if got := hostNames(list); !slices.Equal(got, tc.want) {
+			t.Errorf("%s: ListWithFilters = %v, want %v", tc.name, got, tc.want)
+		}
+		if n, err := r.Devices.CountWithFilters(ctx, tc.filters); err != nil || n != len(tc.want) {
+			t.Errorf("%s: CountWithFilters = %d, %v; want %d", tc.name, n, err, len(tc.want))
+		}
+	}
+
+	regions, err := r.Devices.CountByRegion(ctx, repository.DeviceFilters{})
+	if err != nil {
+		t.Fatalf("CountByRegion: %v", err)
+	}
+	if len(regions) != 2 || regions[0].Region != "de-be" || regions[0].Count != 3 || *regions[0].City != "berlin" ||
+		regions[1].Region != "de-hh" || regions[1].Count != 1 || *regions[1].City != "hamburg" {
+		t.Errorf("CountByRegion = %+v", regions)
+	}
+
+	facets, err := r.Devices.Facets(ctx, repository.DeviceFilters{City: ptr("berlin")})
+	if err != nil {
+		t.Fatalf("Facets: %v", err)
+	}
+	want := models.DeviceFacets{
+		"sync_status": {{Value: "", Count: 2}, {Value: "ok", Count: 1}},
+		"device_type": {{Value: "kiosk", Count: 2}, {Value: "screen", Count: 1}},
+		"region":      {{Value: "de-be", Count: 3}},
+		"production":  {{Value: "true", Count: 2}, {Value: "false", Count: 1}},
+		"is_transit":  {{Value: "false", Count: 3}},
+		"change":      {{Value: "false", Count: 2}, {Value: "true", Count: 1}},
+	}
+	if !reflect.DeepEqual(facets, want) {
+		t.Errorf("Facets = %+v, want %+v", facets, want)
+	}
+}
@@ -0,0 +1,127 @@
+package repotest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"testing"
+
+	"scm/internal/interfaces"
+	"scm/internal/models"
+	"scm/internal/repository"
+)
+
+// cursor returns the sort values of item the way the handlers put them in a page
+// cursor: taken from its JSON, with numbers kept as decimal text.
+func cursor(t *testing.T, keys []interfaces.SortKey, item any) []any {
+	t.Helper()
+	raw, err := json.Marshal(item)
+	if err != nil {
+		t.Fatalf("marshal cursor item: %v", err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var fields map[string]any
+	if err := dec.Decode(&fields); err != nil {
+		t.Fatalf("decode cursor item: %v", err)
+	}
+	values := make([]any, len(keys))
+	for i, k := range keys {
+		values[i] = fmt.Sprint(fields[k.Field])
+	}
+	return values
+}
+
+func testPagination(t *testing.T, r Repos) {
+	ctx := context.Background()
+	owner := newUser(t, r, "owner")
+	for _, name := range []string{"delta", "alpha", "echo", "charlie", "bravo"} {
+		newAdvertiser(t, r, owner, name)
+	}
+	names := func(list []models.Advertiser) []string {
+		var out []string
+		for _, a := range list {
+			out = append(out, a.Name)
+		}
+		return out
+	}
+	list := func(page interfaces.ListPage) []models.Advertiser {
+		t.Helper()
+		advertisers, err := r.Advertisers.List(ctx, page, false)
+		if err != nil {
+			t.Fatalf("List(%+v): %v", page, err)
+		}
+		return advertisers
+	}
+
+	byName := sorted(t, repository.AdvertiserSort, "name")
+	page := byName
+	page.Limit = 2
+	first := list(page)
+	if got := names(first); !slices.Equal(got, []string{"alpha", "bravo"}) {
+		t.Fatalf("first page = %v", got)
+	}
+	page.After = cursor(t, page.Sort, first[1])
+	second := list(page)
+	if got := names(second); !slices.Equal(got, []string{"charlie", "delta"}) {
+		t.Errorf("page after bravo = %v", got)
+	}
+	page.After = nil
+	page.Before = cursor(t, page.Sort, second[0])
+	if got := names(list(page)); !slices.Equal(got, []string{"alpha", "bravo"}) {
+		t.Errorf("page before charlie = %v", got)
+	}
+	page.Before = cursor(t, page.Sort, first[0])
+	if got := names(list(page)); len(got) != 0 {
+		t.Errorf("page before alpha = %v, want none", got)
+	}
+
+	byNameDesc := sorted(t, repository.AdvertiserSort, "-name")
+	byNameDesc.Limit = 2
+	if got := names(list(byNameDesc)); !slices.Equal(got, []string{"echo", "delta"}) {
+		t.Errorf("descending first page = %v", got)
+	}
+	byNameDesc.Offset = 3
+	if got := names(list(byNameDesc)); !slices.Equal(got, []string{"bravo", "alpha"}) {
+		t.Errorf("descending page at offset 3 = %v", got)
+	}
+	byNameDesc.Offset = 0
+	byNameDesc.Before = cursor(t, byNameDesc.Sort, first[1]) // bravo
+	if got := names(list(byNameDesc)); !slices.Equal(got, []string{"delta", "charlie"}) {
+		t.Errorf("descending page before bravo = %v", got)
+	}
+
+	// Timestamps round-trip through the cursor as RFC 3339 text.
+	newest := sorted(t, repository.AdvertiserSort, "-created_at")
+	newest.Limit = 3
+	recent := list(newest)
+	if got := names(recent); !slices.Equal(got, []string{"bravo", "charlie", "echo"}) {
+		t.Fatalf("newest first = %v", got)
+	}
+	newest.After = cursor(t, newest.Sort, recent[2])
+	if got := names(list(newest)); !slices.Equal(got, []string{"alpha", "delta"}) {
+		t.Errorf("page after echo by created_at = %v", got)
+	}
+
+	// Numeric sort values tie on project and fall back to the id.
+	deviceFleet(t, r)
+	byProject := sorted(t, repository.DeviceSort, "-project")
+	byProject.Limit = 2
+	devices, err := r.Devices.List(ctx, byProject)
+	if err != nil {
+		t.Fatalf("devices List: %v", err)
+	}
+	if got := hostNames(devices); !slices.Equal(got, []string{"screen-hamburg-1", "screen-berlin-1"}) {
+		t.Fatalf("devices by project = %v", got)
+	}
+	byProject.After = cursor(t, byProject.Sort, devices[0])
+	devices, err = r.Devices.List(ctx, byProject)
+	if err != nil {
+		t.Fatalf("devices List: %v", err)
+	}
+	if got := hostNames(devices); !slices.Equal(got, []string{"screen-berlin-1", "kiosk-berlin-1"}) {
+		t.Errorf("devices after screen-hamburg-1 = %v", got)
+	}
+}
@@ -0,0 +1,160 @@
+// Package repotest is the conformance suite for the repositories. It runs against
+// PostgreSQL and against the in-memory fakes of package memory, so that tests written
+// against the fakes hold for the real database too.
+package repotest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"scm/internal/interfaces"
+	"scm/internal/models"
+	"scm/internal/repository"
+)
+
+// Repos is a set of repositories over one database.
+type Repos struct {
+	Users       repository.UserRepository
+	Advertisers interfaces.AdvertiserRepository
+	Campaigns   interfaces.CampaignRepository
+	Creatives   repository.CreativeRepository
+	Projects    repository.ProjectRepository
+	Devices     repository.DeviceRepository
+	Venues      repository.VenueRepository
+}
+
+// Run runs the suite. newRepos is called once per test and must return repositories
+// over an empty database.
+func Run(t *testing.T, newRepos func(t *testing.T) Repos) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, r Repos)
+	}{
+		{"Users", testUsers},
+		{"Advertisers", testAdvertisers},
+		{"AdvertiserCascade", testAdvertiserCascade},
+		{"Campaigns", testCampaigns},
+		{"CampaignScheduler", testCampaignScheduler},
+		{"Creatives", testCreatives},
+		{"CreativesByDevice", testCreativesByDevice},
+		{"Pagination", testPagination},
+		{"Projects", testProjects},
+		{"Devices", testDevices},
+		{"DeviceFilters", testDeviceFilters},
+		{"Venues", testVenues},
+		{"VenueAssignments", testVenueAssignments},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, newRepos(t))
+		})
+	}
+}
+
+// day is a fixed date the fixtures schedule campaigns around.
+var day = time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC) // a Monday
+
+func newUser(t *testing.T, r Repos, name string) *models.User {
+	t.Helper()
+	u := &models.User{
+		ID:           uuid.New().String(),
+		Email:        name + "@example.com",
+		Name:         name,
+		UserName:     name,
+		PhoneNumber:  "+1555" + name,
+		PasswordHash: "hash-" + name,
+		CreatedAt:    time.Now().UTC().Truncate(time.Microsecond),
+	}
+	if err := r.Users.Create(context.Background(), u); err != nil {
+		t.Fatalf("create user %s: %v", name, err)
+	}
+	return u
+}
+
+func newAdvertiser(t *testing.T, r Repos, owner *models.User, name string) *models.Advertiser {
+	t.Helper()
+	a := &models.Advertiser{Name: name, Email: name + "@example.com", CreatedBy: owner.ID}
+	if err := r.Advertisers.Create(context.Background(), a); err != nil {
+		t.Fatalf("create advertiser %s: %v", name, err)
+	}
+	return a
+}
+
+func newCampaign(t *testing.T, r Repos, advertiser *models.Advertiser, name string, status models.CampaignStatus) *models.Campaign {
+	t.Helper()
+	c := &models.Campaign{
+		Name:         name,
+		Status:       status,
+		Cities:       []string{"berlin"},
+		StartDate:    day,
+		EndDate:      day.AddDate(0, 1, 0),
+		Budget:       100.5,
+		Impressions:  10,
+		AdvertiserID: advertiser.ID,
+	}
+	if err := r.Campaigns.Create(context.Background(), c); err != nil {
+		t.Fatalf("create campaign %s: %v", name, err)
+	}
+	return c
+}
+
+func newCreative(t *testing.T, r Repos, campaign *models.Campaign, name string, devices ...string) *models.Creative {
+	t.Helper()
+	c := &models.Creative{
+		ID:           uuid.New().String(),
+		Name:         name,
+		Type:         models.CreativeTypeImage,
+		URL:          "https://cdn.example.com/creatives/" + name + ".png",
+		FilePath:     "creatives/" + name + ".png",
+		Size:         int64(len(name)),
+		CampaignID:   campaign.ID,
+		SelectedDays: []string{"monday"},
+		TimeSlots:    []string{"09:00-17:00"},
+		Devices:      devices,
+		UploadedAt:   time.Now().UTC().Truncate(time.Microsecond),
+	}
+	if err := r.Creatives.Create(context.Background(), c); err != nil {
+		t.Fatalf("create creative %s: %v", name, err)
+	}
+	return c
+}
+
+// sorted returns a page sorted by raw, a sort parameter of sortable.
+func sorted(t *testing.T, sortable interfaces.Sortable, raw string) interfaces.ListPage {
+	t.Helper()
+	keys, err := sortable.Parse(raw)
+	if err != nil {
+		t.Fatalf("parse sort %q: %v", raw, err)
+	}
+	return interfaces.ListPage{Sort: keys}
+}
+
+// mustFail fails the test unless err is non-nil; it returns err for further checks.
+func mustFail(t *testing.T, what string, err error) error {
+	t.Helper()
+	if err == nil {
+		t.Fatalf("%s: expected an error", what)
+	}
+	return err
+}
+
+// pqCode returns the SQLSTATE of err, or "" if it is not a database error.
+func pqCode(err error) string {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return string(pqErr.Code)
+	}
+	return ""
+}
+
+func pqConstraint(err error) string {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Constraint
+	}
+	return ""
+}
@@ -0,0 +1,102 @@
+package repotest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"scm/internal/models"
+	"scm/internal/repository"
+)
+
+func testUsers(t *testing.T, r Repos) {
+	ctx := context.Background()
+	alice := newUser(t, r, "alice")
+	bob := newUser(t, r, "bob")
+
+	got, err := r.Users.GetByID(ctx, alice.ID)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	if got.Email != alice.Email || got.UserName != alice.UserName || got.PasswordHash != alice.PasswordHash || !got.CreatedAt.Equal(alice.CreatedAt) {
+		t.Errorf("GetByID = %+v, want %+v", got, alice)
+	}
+	if _, err := r.Users.GetByID(ctx, uuid.NewString()); err == nil || err.Error() != "user not found" {
+		t.Errorf("GetByID(unknown) error = %v, want user not found", err)
+	}
+	if got, err := r.Users.GetByEmail(ctx, "bob@example.com"); err != nil || got.ID != bob.ID {
+		t.Errorf("GetByEmail = %v, %v; want bob", got, err)
+	}
+	for _, identifier := range []string{"ALICE@example.com", "Alice", alice.PhoneNumber} {
+		if got, err := r.Users.GetByIdentifier(ctx, identifier); err != nil || got.ID != alice.ID {
+			t.Errorf("GetByIdentifier(%q) = %v, %v; want alice", identifier, got, err)
+		}
+	}
+	if _, err := r.Users.GetByIdentifier(ctx, "carol"); err == nil {
+		t.Error("GetByIdentifier(carol): expected an error")
+	}
+
+	dup := *bob
+	dup.ID = uuid.NewString()
+	dup.UserName = "bobby"
+	err = mustFail(t, "Create with a taken email", r.Users.Create(ctx, &dup))
+	if pqCode(err) != "23505" || pqConstraint(err) != "users_email_key" {
+		t.Errorf("Create with a taken email error = %v, want users_email_key violation", err)
+	}
+	dup.Email = "bobby@example.com"
+	dup.UserName = "BOB"
+	err = mustFail(t, "Create with a taken user name", r.Users.Create(ctx, &dup))
+	if pqCode(err) != "23505" {
+		t.Errorf("Create with a taken user name error = %v, want a unique violation", err)
+	}
+
+	users, err := r.Users.List(ctx, sorted(t, repository.UserSort, "email"))
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(users) != 2 || users[0].ID != alice.ID || users[1].ID != bob.ID {
+		t.Fatalf("List = %+v, want alice then bob", users)
+	}
+	if users[0].PasswordHash != "" {
+		t.Error("List returned a password hash")
+	}
+	if n, err := r.Users.Count(ctx); err != nil || n != 2 {
+		t.Errorf("Count = %d, %v; want 2", n, err)
+	}
+	all, err := r.Users.ListAll(ctx)
+	if err != nil || len(all) != 2 {
+		t.Fatalf("ListAll = %v, %v; want two users", all, err)
+	}
+
+	name := "Alice Liddell"
+	if err := r.Users.UpdateProfile(ctx, alice.ID, &models.UpdateUserRequest{Name: &name}); err != nil {
+		t.Fatalf("UpdateProfile: %v", err)
+	}
+	taken := "bob@example.com"
+	err = mustFail(t, "UpdateProfile to a taken email", r.Users.UpdateProfile(ctx, alice.ID, &models.UpdateUserRequest{Email: &taken}))
+	if pqCode(err) != "23505" {
+		t.Errorf("UpdateProfile to a taken email error = %v, want a unique violation", err)
+	}
+	if err := r.Users.UpdatePasswordHash(ctx, alice.ID, "new-hash"); err != nil {
+		t.Fatalf("UpdatePasswordHash: %v", err)
+	}
+	got, _ = r.Users.GetByID(ctx, alice.ID)
+	if got.Name != name || got.Email != alice.Email || got.PasswordHash != "new-hash" {
+		t.Errorf("after updates = %+v", got)
+	}
+	if err := r.Users.UpdateProfile(ctx, uuid.NewString(), &models.UpdateUserRequest{Name: &name}); err == nil || err.Error() != "user not found" {
+		t.Errorf("UpdateProfile(unknown) error = %v, want user not found", err)
+	}
+
+	newAdvertiser(t, r, bob, "bobs-shop")
+	err = mustFail(t, "Delete a user owning an advertiser", r.Users.Delete(ctx, bob.ID))
+	if pqCode(err) != "23503" {
+		t.Errorf("Delete a user owning an advertiser error = %v, want a foreign key violation", err)
+	}
+	if err := r.Users.Delete(ctx, alice.ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if err := r.Users.Delete(ctx, alice.ID); err == nil || err.Error() != "user not found" {
+		t.Errorf("Delete twice error = %v, want user not found", err)
+	}
+}
@@ -0,0 +1,257 @@
+package repotest
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"slices"
+	"testing"
+
+	"scm/internal/interfaces"
+	"scm/internal/models"
+	"scm/internal/repository"
+)
+
+func newVenue(t *testing.T, r Repos, name string, smart *models.DeviceSelector) *models.Venue {
+	t.Helper()
+	v := &models.Venue{Name: name, SmartFilter: smart}
+	if err := r.Venues.Create(context.Background(), v); err != nil {
+		t.Fatalf("create venue %s: %v", name, err)
+	}
+	return v
+}
+
+func testVenues(t *testing.T, r Repos) {
+	ctx := context.Background()
+	deviceFleet(t, r)
+
+	lobby := newVenue(t, r, "lobby", nil)
+	if lobby.ID == 0 || lobby.Version != 1 {
+		t.Fatalf("Create did not fill in the venue: %+v", lobby)
+	}
+	err := mustFail(t, "Create with a taken name", r.Venues.Create(ctx, &models.Venue{Name: "lobby"}))
+	if pqCode(err) != "23505" || pqConstraint(err) != "venues_name_key" {
+		t.Errorf("Create with a taken name error = %v, want venues_name_key violation", err)
+	}
+	station := newVenue(t, r, "station", &models.DeviceSelector{City: ptr("hamburg")})
+
+	got, err := r.Venues.GetByID(ctx, station.ID)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	if got.Name != "station" || got.SmartFilter == nil || *got.SmartFilter.City != "hamburg" || got.Version != 1 {
+		t.Errorf("GetByID = %+v", got)
+	}
+	if got, err := r.Venues.GetByName(ctx, "lobby"); err != nil || got.ID != lobby.ID || got.SmartFilter != nil {
+		t.Errorf("GetByName = %+v, %v", got, err)
+	}
+	if _, err := r.Venues.GetByID(ctx, 999); err == nil || err.Error() != "venue not found" {
+		t.Errorf("GetByID(unknown) error = %v, want venue not found", err)
+	}
+	if _, err := r.Venues.GetByName(ctx, "hall"); err == nil || err.Error() != "venue not found" {
+		t.Errorf("GetByName(unknown) error = %v, want venue not found", err)
+	}
+
+	// Update saves the name and smart filter; an empty selector is not stored.
+	lobby.Name = "main-lobby"
+	lobby.SmartFilter = &models.DeviceSelector{}
+	if err := r.Venues.Update(ctx, lobby, 1); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if lobby.Version != 2 {
+		t.Errorf("version after Update = %d, want 2", lobby.Version)
+	}
+	if got, _ := r.Venues.GetByID(ctx, lobby.ID); got.Name != "main-lobby" || got.SmartFilter != nil {
+		t.Errorf("after Update = %+v", got)
+	}
+	if err := r.Venues.Update(ctx, lobby, 1); !errors.Is(err, interfaces.ErrVersionConflict) {
+		t.Errorf("Update at a stale version error = %v, want ErrVersionConflict", err)
+	}
+	taken := *lobby
+	taken.Name = "station"
+	err = mustFail(t, "Update to a taken name", r.Venues.Update(ctx, &taken, 0))
+	if pqCode(err) != "23505" {
+		t.Errorf("Update to a taken name error = %v, want a unique violation", err)
+	}
+	if err := r.Venues.Update(ctx, &models.Venue{ID: 999, Name: "hall"}, 0); err == nil || err.Error() != "venue not found" {
+		t.Errorf("Update(unknown) error = %v, want venue not found", err)
+	}
+
+	list, err := r.Venues.List(ctx, sorted(t, repository.VenueSort, "name"))
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(list) != 2 || list[0].Name != "main-lobby" || list[1].Name != "station" {
+		t.Errorf("List = %+v, want main-lobby then station", list)
+	}
+	if n, _ := r.Venues.Count(ctx); n != 2 {
+		t.Errorf("Count = %d, want 2", n)
+	}
+	smart, err := r.Venues.ListSmartVenues(ctx)
+	if err != nil || len(smart) != 1 || smart[0].ID != station.ID {
+		t.Errorf("ListSmartVenues = %+v, %v; want station", smart, err)
+	}
+
+	// Membership.
+	for _, id := range []int{1, 2} {
+		if err := r.Venues.AddDeviceToVenue(ctx, lobby.ID, id); err != nil {
+			t.Fatalf("AddDeviceToVenue(%d): %v", id, err)
+		}
+	}
+	if err := r.Venues.AddDeviceToVenue(ctx, lobby.ID, 1); err != nil {
+		t.Errorf("AddDeviceToVenue twice: %v", err)
+	}
+	if err := r.Venues.AddDeviceToVenue(ctx, station.ID, 1); err != nil {
+		t.Fatalf("AddDeviceToVenue: %v", err)
+	}
+	if err := r.Venues.AddDeviceToVenue(ctx, 999, 1); err == nil || err.Error() != "venue not found" {
+		t.Errorf("AddDeviceToVenue(unknown venue) error = %v, want venue not found", err)
+	}
+	if err := r.Venues.AddDeviceToVenue(ctx, lobby.ID, 99); err == nil || err.Error() != "device not found" {
+		t.Errorf("AddDeviceToVenue(unknown device) error = %v, want device not found", err)
+	}
+
+	if n, _ := r.Venues.CountDevicesByVenueID(ctx, lobby.ID); n != 2 {
+		t.Errorf("CountDevicesByVenueID = %d, want 2", n)
+	}
+	devices, err := r.Venues.GetDevicesByVenueID(ctx, lobby.ID, 10, 0)
+	if err != nil {
+		t.Fatalf("GetDevicesByVenueID: %v", err)
+	}
+	// Newest device first.
+	if got := hostNames(devices); !slices.Equal(got, []string{"kiosk-berlin-2", "kiosk-berlin-1"}) {
+		t.Errorf("GetDevicesByVenueID = %v", got)
+	}
+	if devices, _ := r.Venues.GetDevicesByVenueID(ctx, lobby.ID, 1, 1); len(devices) != 1 || devices[0].ID != 1 {
+		t.Errorf("GetDevicesByVenueID(limit 1, offset 1) = %v, want kiosk-berlin-1", hostNames(devices))
+	}
+	withDevices, err := r.Venues.GetByIDWithDevices(ctx, lobby.ID)
+	if err != nil || withDevices.Name != "main-lobby" || len(withDevices.Devices) != 2 {
+		t.Errorf("GetByIDWithDevices = %+v, %v", withDevices, err)
+	}
+	if n, _ := r.Venues.CountVenuesByDeviceID(ctx, 1); n != 2 {
+		t.Errorf("CountVenuesByDeviceID = %d, want 2", n)
+	}
+	venues, err := r.Venues.GetVenuesByDeviceID(ctx, 1, 10, 0)
+	if err != nil || len(venues) != 2 || venues[0].ID != station.ID || venues[1].ID != lobby.ID {
+		t.Errorf("GetVenuesByDeviceID = %+v, %v; want station then lobby", venues, err)
+	}
+	byVenue, err := r.Venues.GetDevicesByVenueIDs(ctx, []int{lobby.ID, station.ID, 999})
+	if err != nil {
+		t.Fatalf("GetDevicesByVenueIDs: %v", err)
+	}
+	if len(byVenue) != 2 || len(byVenue[lobby.ID]) != 2 || len(byVenue[station.ID]) != 1 {
+		t.Errorf("GetDevicesByVenueIDs = %v", byVenue)
+	}
+	inVenue, _ := r.Devices.ListWithFilters(ctx, repository.DeviceFilters{VenueID: &station.ID}, interfaces.ListPage{})
+	if got := hostNames(inVenue); !slices.Equal(got, []string{"kiosk-berlin-1"}) {
+		t.Errorf("devices filtered by venue = %v", got)
+	}
+
+	if err := r.Venues.RemoveDeviceFromVenue(ctx, station.ID, 2); err == nil || err.Error() != "device not found in venue" {
+		t.Errorf("RemoveDeviceFromVenue(not a member) error = %v, want device not found in venue", err)
+	}
+	if err := r.Venues.RemoveDeviceFromVenue(ctx, station.ID, 1); err != nil {
+		t.Fatalf("RemoveDeviceFromVenue: %v", err)
+	}
+
+	// A venue with devices cannot be deleted, only cascaded.
+	var blocked *interfaces.DeletionBlockedError
+	if err := r.Venues.Delete(ctx, lobby.ID); !errors.As(err, &blocked) || blocked.References["devices"] != 2 {
+		t.Errorf("Delete with devices error = %v, want DeletionBlockedError for two devices", err)
+	}
+	deps, err := r.Venues.Dependencies(ctx, lobby.ID)
+	if err != nil {
+		t.Fatalf("Dependencies: %v", err)
+	}
+	want := map[string]int64{"devices": 2, "alert_rules": 0}
+	if !maps.Equal(deps.References, want) || len(deps.Devices) != 2 || deps.Devices[0].Name != "kiosk-berlin-1" || deps.AlertRules == nil {
+		t.Errorf("Dependencies = %+v", deps)
+	}
+	removed, err := r.Venues.DeleteCascade(ctx, lobby.ID)
+	if err != nil || removed.References["devices"] != 2 {
+		t.Fatalf("DeleteCascade = %+v, %v", removed, err)
+	}
+	if _, err := r.Venues.DeleteCascade(ctx, lobby.ID); err == nil || err.Error() != "venue not found" {
+		t.Errorf("DeleteCascade twice error = %v, want venue not found", err)
+	}
+	if n, _ := r.Venues.CountVenuesByDeviceID(ctx, 1); n != 0 {
+		t.Errorf("CountVenuesByDeviceID after cascade = %d, want 0", n)
+	}
+	if err := r.Venues.Delete(ctx, station.ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if err := r.Venues.Delete(ctx, station.ID); err == nil || err.Error() != "venue not found" {
+		t.Errorf("Delete twice error = %v, want venue not found", err)
+	}
+}
+
+func testVenueAssignments(t *testing.T, r Repos) {
+	ctx := context.Background()
+	deviceFleet(t, r)
+	lobby := newVenue(t, r, "lobby", nil)
+	if err := r.Venues.AddDeviceToVenue(ctx, lobby.ID, 3); err != nil {
+		t.Fatalf("add device: %v", err)
+	}
+
+	refs := func(list []models.VenueDeviceRef) []string {
+		names := []string{}
+		for _, d := range list {
+			names = append(names, d.HostName)
+		}
+		return names
+	}
+	members := func() []string {
+		t.Helper()
+		devices, err := r.Venues.GetDevicesByVenueID(ctx, lobby.ID, 100, 0)
+		if err != nil {
+			t.Fatalf("GetDevicesByVenueID: %v", err)
+		}
+		names := hostNames(devices)
+		slices.Sort(names)
+		return names
+	}
+	berlin := repository.DeviceFilters{City: ptr("berlin")}
+	kiosks := repository.DeviceFilters{DeviceType: ptr("kiosk")}
+
+	for _, tc := range []struct {
+		name     string
+		filters  repository.DeviceFilters
+		mode     string
+		dryRun   bool
+		matched  int
+		added    []string
+		removed  []string
+		retained int
+		members  []string
+	}{
+		{"dry run", berlin, models.VenueAssignAdd, true, 3, []string{"kiosk-berlin-1", "kiosk-berlin-2", "screen-berlin-1"}, []string{}, 1,
+			[]string{"screen-hamburg-1"}},
+		{"add", kiosks, models.VenueAssignAdd, false, 2, []string{"kiosk-berlin-1", "kiosk-berlin-2"}, []string{}, 1,
+			[]string{"kiosk-berlin-1", "kiosk-berlin-2", "screen-hamburg-1"}},
+		{"add again", kiosks, models.VenueAssignAdd, false, 2, []string{}, []string{}, 3,
+			[]string{"kiosk-berlin-1", "kiosk-berlin-2", "screen-hamburg-1"}},
+		{"replace", berlin, models.VenueAssignReplace, false, 3, []string{"screen-berlin-1"}, []string{"screen-hamburg-1"}, 2,
+			[]string{"kiosk-berlin-1", "kiosk-berlin-2", "screen-berlin-1"}},
+		{"remove dry run", kiosks, models.VenueAssignRemove, true, 2, []string{}, []string{"kiosk-berlin-1", "kiosk-berlin-2"}, 1,
+			[]string{"kiosk-berlin-1", "kiosk-berlin-2", "screen-berlin-1"}},
+		{"remove", kiosks, models.VenueAssignRemove, false, 2, []string{}, []string{"kiosk-berlin-1", "kiosk-berlin-2"}, 1,
+			[]string{"screen-berlin-1"}},
+	} {
+		result, err := r.Venues.AssignDevices(ctx, lobby.ID, tc.filters, tc.mode, tc.dryRun)
+		if err != nil {
+			t.Fatalf("%s: AssignDevices: %v", tc.name, err)
+		}
+		if result.VenueID != lobby.ID || result.Mode != tc.mode || result.DryRun != tc.dryRun || result.Matched != tc.matched ||
+			!slices.Equal(refs(result.Added), tc.added) || !slices.Equal(refs(result.Removed), tc.removed) || result.Retained != tc.retained {
+			t.Errorf("%s: AssignDevices = %+v", tc.name, result)
+		}
+		if got := members(); !slices.Equal(got, tc.members) {
+			t.Errorf("%s: members = %v, want %v", tc.name, got, tc.members)
+		}
+	}
+
+	if _, err := r.Venues.AssignDevices(ctx, 999, berlin, models.VenueAssignAdd, false); err == nil || err.Error() != "venue not found" {
+		t.Errorf("AssignDevices(unknown venue) error = %v, want venue not found", err)
+	}
+}
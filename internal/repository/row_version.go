@@ -0,0 +1,21 @@
+package repository
+
+import (
+	"context"
+
+	"scm/internal/interfaces"
+)
+
+// missedUpdateError explains a conditional update that matched no row. existsQuery
+// checks whether the live row is still there: if it is, its version moved on and the
+// update lost the race; otherwise notFound is returned.
+func missedUpdateError(ctx context.Context, db DBTX, existsQuery string, id any, notFound error) error {
+	var exists bool
+	if err := db.QueryRowContext(ctx, existsQuery, id).Scan(&exists); err != nil {
+		return err
+	}
+	if exists {
+		return interfaces.ErrVersionConflict
+	}
+	return notFound
+}
@@ -0,0 +1,144 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"scm/internal/models"
+	"scm/internal/tracing"
+)
+
+// SearchViewer is who a search runs for. Admins see every match; other users only see
+// the advertisers they created and those advertisers' campaigns and creatives.
+type SearchViewer struct {
+	UserID string
+	Admin  bool
+}
+
+type SearchRepository interface {
+	// Search returns the records of the given types matching query that viewer may see,
+	// best match first. Soft-deleted records are never returned.
+	Search(ctx context.Context, viewer SearchViewer, query string, types []string, limit int) ([]models.SearchResult, error)
+}
+
+type searchRepository struct {
+	db DBTX
+}
+
+func NewSearchRepository(db DBTX) SearchRepository {
+	return &searchRepository{db: db}
+}
+
+// searchSources holds the SELECT finding each type's matches. $1 is the search text and
+// $2 the same text as an ILIKE substring pattern. A record matches when the text is
+// word-similar to (<%) or contained in one of its columns, all of which have trigram
+// indexes (migration 0014), and is ranked by its best word similarity. $4 is the
+// non-admin viewer's user ID, to which advertisers (by created_by) and their campaigns and
+// creatives are limited, or NULL for admins.
+var searchSources = map[string]string{
+	models.SearchTypeAdvertiser: `
+		SELECT 'advertiser' AS type, id::text AS id, name AS title, COALESCE(email, '') AS subtitle,
+			GREATEST(word_similarity($1, name), word_similarity($1, COALESCE(email, ''))) AS rank
+		FROM advertisers
+		WHERE deleted_at IS NULL
+			AND ($4::text IS NULL OR created_by::text = $4)
+			AND ($1 <% name OR name ILIKE $2 OR $1 <% email OR email ILIKE $2)`,
+	models.SearchTypeCampaign: `
+		SELECT 'campaign' AS type, id::text AS id, name AS title, array_to_string(cities, ', ') AS subtitle,
+			GREATEST(word_similarity($1, name), word_similarity($1, search_array_text(cities))) AS rank
+		FROM campaigns
+		WHERE deleted_at IS NULL
+			AND ($4::text IS NULL OR advertiser_id IN (SELECT id FROM advertisers WHERE created_by::text = $4))
+			AND ($1 <% name OR name ILIKE $2
+				OR $1 <% search_array_text(cities) OR search_array_text(cities) ILIKE $2)`,
+	models.SearchTypeCreative: `
+		SELECT 'creative' AS type, id::text AS id, name AS title, type::text AS subtitle,
+			word_similarity($1, name) AS rank
+		FROM creatives
+		WHERE deleted_at IS NULL
+			AND ($4::text IS NULL OR campaign_id IN (
+				SELECT c.id FROM campaigns c JOIN advertisers a ON a.id = c.advertiser_id
+				WHERE a.created_by::text = $4))
+			AND ($1 <% name OR name ILIKE $2)`,
+	models.SearchTypeDevice: `
+		SELECT 'device' AS type, id::text AS id, name AS title, host_name AS subtitle,
+			GREATEST(word_similarity($1, name), word_similarity($1, host_name),
+				word_similarity($1, COALESCE(description, ''))) AS rank
+		FROM devices
+		WHERE $1 <% name OR name ILIKE $2
+			OR $1 <% host_name OR host_name ILIKE $2
+			OR $1 <% description OR description ILIKE $2`,
+	models.SearchTypeProject: `
+		SELECT 'project' AS type, id::text AS id, name AS title, COALESCE(company, '') AS subtitle,
+			word_similarity($1, name) AS rank
+		FROM projects
+		WHERE $1 <% name OR name ILIKE $2`,
+	models.SearchTypeVenue: `
+		SELECT 'venue' AS type, id::text AS id, name AS title, '' AS subtitle,
+			word_similarity($1, name) AS rank
+		FROM venues
+		WHERE $1 <% name OR name ILIKE $2`,
+}
+
+// ownedSearchTypes are the types whose sources take the viewer as $4.
+var ownedSearchTypes = []string{models.SearchTypeAdvertiser, models.SearchTypeCampaign, models.SearchTypeCreative}
+
+func (r *searchRepository) Search(ctx context.Context, viewer SearchViewer, query string, types []string, limit int) ([]models.SearchResult, error) {
+	ctx, span := tracing.Repository(ctx, "searchRepository.Search")
+	defer span.End()
+
+	var sources []string
+	owned := false
+	for _, t := range models.SearchTypes {
+		for _, want := range types {
+			if t == want {
+				sources = append(sources, searchSources[t])
+				owned = owned || slices.Contains(ownedSearchTypes, t)
+				break
+			}
+		}
+	}
+	if len(sources) == 0 {
+		return nil, nil
+	}
+
+	stmt := `SELECT type, id, title, subtitle, rank FROM (` +
+		strings.Join(sources, "\n\t\tUNION ALL") + `
+		) AS results
+		ORDER BY rank DESC, title, type
+		LIMIT $3`
+
+	args := []any{query, likePattern(query), limit}
+	if owned {
+		var owner *string
+		if !viewer.Admin {
+			owner = &viewer.UserID
+		}
+		args = append(args, owner)
+	}
+
+	rows, err := r.db.QueryContext(ctx, stmt, args...)
+	if err != nil {
+		return nil, fmt.Errorf("search: %w", err)
+	}
+	defer rows.Close()
+
+	var results []models.SearchResult
+	for rows.Next() {
+		var res models.SearchResult
+		if err := rows.Scan(&res.Type, &res.ID, &res.Title, &res.Subtitle, &res.Rank); err != nil {
+			return nil, fmt.Errorf("scan search result: %w", err)
+		}
+		results = append(results, res)
+	}
+	return results, rows.Err()
+}
+
+// likePattern turns text into an ILIKE pattern matching it anywhere, escaping the
+// pattern's own wildcards.
+func likePattern(text string) string {
+	escaped := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(text)
+	return "%" + escaped + "%"
+}
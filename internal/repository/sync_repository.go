@@ -0,0 +1,219 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"scm/internal/models"
+	"scm/internal/tracing"
+)
+
+// SyncRepository stores console sync runs and the change feed of the devices and
+// projects each run changed.
+type SyncRepository interface {
+	StartRun(ctx context.Context, mode string) (*models.SyncRun, error)
+	// FinishRun stores run's counts, errors and high-water mark and marks it finished.
+	FinishRun(ctx context.Context, run *models.SyncRun) error
+	GetRun(ctx context.Context, id int64) (*models.SyncRun, error)
+	// LastHighWaterMark returns the high-water mark of the latest finished run, or nil
+	// when no run has stored one yet.
+	LastHighWaterMark(ctx context.Context) (*time.Time, error)
+	RecordChange(ctx context.Context, change *models.SyncChange) error
+	// ListChanges lists the changes matching filters, newest first.
+	ListChanges(ctx context.Context, filters SyncChangeFilters, limit int, offset int) ([]*models.SyncChange, error)
+	CountChanges(ctx context.Context, filters SyncChangeFilters) (int, error)
+}
+
+type SyncChangeFilters struct {
+	RunID      *int64
+	EntityType *string
+	EntityKey  *string
+}
+
+type syncRepository struct {
+	db DBTX
+}
+
+func NewSyncRepository(db DBTX) SyncRepository {
+	return &syncRepository{db: db}
+}
+
+func (r *syncRepository) StartRun(ctx context.Context, mode string) (*models.SyncRun, error) {
+	ctx, span := tracing.Repository(ctx, "syncRepository.StartRun")
+	defer span.End()
+
+	run := &models.SyncRun{Mode: mode, Errors: []string{}}
+	err := r.db.QueryRowContext(ctx, "INSERT INTO sync_runs (mode) VALUES ($1) RETURNING id, started_at", mode).
+		Scan(&run.ID, &run.StartedAt)
+	if err != nil {
+		return nil, fmt.Errorf("start sync run: %w", err)
+	}
+	return run, nil
+}
+
+func (r *syncRepository) FinishRun(ctx context.Context, run *models.SyncRun) error {
+	ctx, span := tracing.Repository(ctx, "syncRepository.FinishRun")
+	defer span.End()
+
+	query := `
+		UPDATE sync_runs
+		SET finished_at = NOW(), projects_synced = $2, devices_synced = $3, changes = $4, errors = $5,
+			high_water_mark = $6
+		WHERE id = $1
+		RETURNING finished_at
+	`
+	err := r.db.QueryRowContext(ctx, query,
+		run.ID, run.ProjectsSynced, run.DevicesSynced, run.Changes, pq.Array(run.Errors), run.HighWaterMark,
+	).Scan(&run.FinishedAt)
+	if err != nil {
+		return fmt.Errorf("finish sync run: %w", err)
+	}
+	return nil
+}
+
+func (r *syncRepository) GetRun(ctx context.Context, id int64) (*models.SyncRun, error) {
+	ctx, span := tracing.Repository(ctx, "syncRepository.GetRun")
+	defer span.End()
+
+	query := `
+		SELECT id, mode, started_at, finished_at, projects_synced, devices_synced, changes, errors, high_water_mark
+		FROM sync_runs
+		WHERE id = $1
+	`
+
+	var run models.SyncRun
+	err := r.db.QueryRowContext(ctx, query, id).Scan(
+		&run.ID, &run.Mode, &run.StartedAt, &run.FinishedAt, &run.ProjectsSynced, &run.DevicesSynced,
+		&run.Changes, pq.Array(&run.Errors), &run.HighWaterMark,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("sync run not found")
+		}
+		return nil, fmt.Errorf("get sync run: %w", err)
+	}
+	return &run, nil
+}
+
+func (r *syncRepository) LastHighWaterMark(ctx context.Context) (*time.Time, error) {
+	ctx, span := tracing.Repository(ctx, "syncRepository.LastHighWaterMark")
+	defer span.End()
+
+	query := `
+		SELECT high_water_mark
+		FROM sync_runs
+		WHERE finished_at IS NOT NULL AND high_water_mark IS NOT NULL
+		ORDER BY finished_at DESC
+		LIMIT 1
+	`
+
+	var mark time.Time
+	if err := r.db.QueryRowContext(ctx, query).Scan(&mark); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("get sync high-water mark: %w", err)
+	}
+	return &mark, nil
+}
+
+func (r *syncRepository) RecordChange(ctx context.Context, change *models.SyncChange) error {
+	ctx, span := tracing.Repository(ctx, "syncRepository.RecordChange")
+	defer span.End()
+
+	changesJSON, err := json.Marshal(change.Changes)
+	if err != nil {
+		return fmt.Errorf("marshal sync changes: %w", err)
+	}
+
+	query := `
+		INSERT INTO sync_changes (run_id, entity_type, entity_key, action, changes)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, occurred_at
+	`
+	err = r.db.QueryRowContext(ctx, query,
+		change.RunID, change.EntityType, change.EntityKey, change.Action, changesJSON,
+	).Scan(&change.ID, &change.OccurredAt)
+	if err != nil {
+		return fmt.Errorf("record sync change: %w", err)
+	}
+	return nil
+}
+
+func buildSyncChangeFilterClause(filters SyncChangeFilters) (string, []any, int) {
+	var clause string
+	var args []any
+	argIndex := 1
+
+	add := func(cond string, v any) {
+		clause += fmt.Sprintf(" AND "+cond, argIndex)
+		args = append(args, v)
+		argIndex++
+	}
+
+	if filters.RunID != nil {
+		add("run_id = $%d", *filters.RunID)
+	}
+	if filters.EntityType != nil {
+		add("entity_type = $%d", *filters.EntityType)
+	}
+	if filters.EntityKey != nil {
+		add("entity_key = $%d", *filters.EntityKey)
+	}
+
+	return clause, args, argIndex
+}
+
+func (r *syncRepository) ListChanges(ctx context.Context, filters SyncChangeFilters, limit int, offset int) ([]*models.SyncChange, error) {
+	ctx, span := tracing.Repository(ctx, "syncRepository.ListChanges")
+	defer span.End()
+
+	clause, args, argIndex := buildSyncChangeFilterClause(filters)
+	query := `SELECT id, run_id, occurred_at, entity_type, entity_key, action, changes
+		FROM sync_changes WHERE 1=1` + clause
+	query += fmt.Sprintf(" ORDER BY occurred_at DESC, id DESC LIMIT $%d OFFSET $%d", argIndex, argIndex+1)
+	args = append(args, limit, offset)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list sync changes: %w", err)
+	}
+	defer rows.Close()
+
+	changes := []*models.SyncChange{}
+	for rows.Next() {
+		var change models.SyncChange
+		var changesJSON []byte
+		if err := rows.Scan(
+			&change.ID, &change.RunID, &change.OccurredAt, &change.EntityType, &change.EntityKey, &change.Action, &changesJSON,
+		); err != nil {
+			return nil, fmt.Errorf("scan sync change: %w", err)
+		}
+		if err := json.Unmarshal(changesJSON, &change.Changes); err != nil {
+			return nil, fmt.Errorf("unmarshal sync changes: %w", err)
+		}
+		changes = append(changes, &change)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate sync changes: %w", err)
+	}
+	return changes, nil
+}
+
+func (r *syncRepository) CountChanges(ctx context.Context, filters SyncChangeFilters) (int, error) {
+	ctx, span := tracing.Repository(ctx, "syncRepository.CountChanges")
+	defer span.End()
+
+	clause, args, _ := buildSyncChangeFilterClause(filters)
+
+	var count int
+	if err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM sync_changes WHERE 1=1"+clause, args...).Scan(&count); err != nil {
+		return 0, fmt.Errorf("count sync changes: %w", err)
+	}
+	return count, nil
+}
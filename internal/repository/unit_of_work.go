@@ -0,0 +1,161 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"github.com/lib/pq"
+	"scm/internal/interfaces"
+	"scm/internal/tracing"
+)
+
+// DBTX is what the repositories run their queries on: the *sql.DB, or the *sql.Tx of a
+// unit of work they are bound to.
+type DBTX interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// txScope is a transaction a repository method runs its statements in. Inside a unit
+// of work it joins the outer transaction, and Commit and Rollback leave that to the
+// unit of work.
+type txScope struct {
+	DBTX
+	tx *sql.Tx
+}
+
+func (s *txScope) Commit() error {
+	if s.tx == nil {
+		return nil
+	}
+	return s.tx.Commit()
+}
+
+func (s *txScope) Rollback() error {
+	if s.tx == nil {
+		return nil
+	}
+	return s.tx.Rollback()
+}
+
+// beginTx starts a transaction on db, or joins the one db already is.
+func beginTx(ctx context.Context, db DBTX) (*txScope, error) {
+	sqlDB, ok := db.(*sql.DB)
+	if !ok {
+		return &txScope{DBTX: db}, nil
+	}
+	tx, err := sqlDB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &txScope{DBTX: tx, tx: tx}, nil
+}
+
+// Repositories are the repositories bound to one database handle. The advertiser,
+// campaign, creative and venue repositories record audit events, like the ones the
+// routes use.
+type Repositories struct {
+	Advertisers  interfaces.AdvertiserRepository
+	Campaigns    interfaces.CampaignRepository
+	Creatives    CreativeRepository
+	Venues       VenueRepository
+	Devices      DeviceRepository
+	Projects     ProjectRepository
+	Annotations  AnnotationRepository
+	DeviceHealth DeviceHealthRepository
+	Alerts       AlertRepository
+	Sync         SyncRepository
+	Audit        AuditRepository
+	Webhooks     WebhookRepository
+}
+
+func NewRepositories(db DBTX) Repositories {
+	auditRepo := NewAuditRepository(db)
+	return Repositories{
+		Advertisers:  NewAuditedAdvertiserRepository(NewAdvertiserRepository(db), auditRepo),
+		Campaigns:    NewAuditedCampaignRepository(NewCampaignRepository(db), auditRepo),
+		Creatives:    NewAuditedCreativeRepository(NewCreativeRepository(db), auditRepo),
+		Venues:       NewAuditedVenueRepository(NewVenueRepository(db), auditRepo),
+		Devices:      NewDeviceRepository(db),
+		Projects:     NewProjectRepository(db),
+		Annotations:  NewAnnotationRepository(db),
+		DeviceHealth: NewDeviceHealthRepository(db),
+		Alerts:       NewAlertRepository(db),
+		Sync:         NewSyncRepository(db),
+		Audit:        auditRepo,
+		Webhooks:     NewWebhookRepository(db),
+	}
+}
+
+// UnitOfWork runs operations spanning several repositories atomically.
+type UnitOfWork interface {
+	// Do runs fn with repositories bound to one transaction, committing when fn returns
+	// nil and rolling back otherwise. opts may be nil for the default isolation level.
+	// When the transaction fails with a serialization failure or deadlock, fn runs
+	// again from the start, so it must not have side effects outside the database.
+	Do(ctx context.Context, opts *sql.TxOptions, fn func(ctx context.Context, repos Repositories) error) error
+}
+
+const (
+	txMaxAttempts = 3
+	txRetryDelay  = 20 * time.Millisecond
+)
+
+type unitOfWork struct {
+	db *sql.DB
+}
+
+func NewUnitOfWork(db *sql.DB) UnitOfWork {
+	return &unitOfWork{db: db}
+}
+
+func (u *unitOfWork) Do(ctx context.Context, opts *sql.TxOptions, fn func(ctx context.Context, repos Repositories) error) error {
+	ctx, span := tracing.Repository(ctx, "unitOfWork.Do")
+	defer span.End()
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		if err = u.attempt(ctx, opts, fn); err == nil || attempt == txMaxAttempts || !isRetryableTxError(err) {
+			return err
+		}
+
+		// Back off with jitter so the transactions that collided do not collide again.
+		delay := time.Duration(attempt)*txRetryDelay + rand.N(txRetryDelay)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+	}
+}
+
+func (u *unitOfWork) attempt(ctx context.Context, opts *sql.TxOptions, fn func(ctx context.Context, repos Repositories) error) error {
+	tx, err := u.db.BeginTx(ctx, opts)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := fn(ctx, NewRepositories(tx)); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit tx: %w", err)
+	}
+	return nil
+}
+
+// isRetryableTxError reports whether err is a serialization failure or a deadlock,
+// after which Postgres expects the whole transaction to be retried.
+func isRetryableTxError(err error) bool {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return false
+	}
+	return pqErr.Code == "40001" || pqErr.Code == "40P01"
+}
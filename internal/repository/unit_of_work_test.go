@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+)
+
+func newMockUnitOfWork(t *testing.T) (UnitOfWork, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return NewUnitOfWork(db), mock
+}
+
+func TestUnitOfWorkRetriesSerializationFailures(t *testing.T) {
+	for _, code := range []pq.ErrorCode{"40001", "40P01"} {
+		uow, mock := newMockUnitOfWork(t)
+		mock.ExpectBegin()
+		mock.ExpectRollback()
+		mock.ExpectBegin()
+		mock.ExpectCommit()
+
+		calls := 0
+		err := uow.Do(context.Background(), nil, func(ctx context.Context, repos Repositories) error {
+			calls++
+			if calls == 1 {
+				return fmt.Errorf("update campaign: %w", &pq.Error{Code: code})
+			}
+			return nil
+		})
+		if err != nil || calls != 2 {
+			t.Fatalf("%s: expected a retry that commits, got %d calls, %v", code, calls, err)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Fatalf("%s: %v", code, err)
+		}
+	}
+}
+
+func TestUnitOfWorkReturnsOtherErrorsImmediately(t *testing.T) {
+	uow, mock := newMockUnitOfWork(t)
+	mock.ExpectBegin()
+	mock.ExpectRollback()
+
+	failed := errors.New("campaign not found")
+	calls := 0
+	err := uow.Do(context.Background(), nil, func(ctx context.Context, repos Repositories) error {
+		calls++
+		return failed
+	})
+	if !errors.Is(err, failed) || calls != 1 {
+		t.Fatalf("expected one call returning the error, got %d calls, %v", calls, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUnitOfWorkStopsRetryingAtTheLimit(t *testing.T) {
+	uow, mock := newMockUnitOfWork(t)
+	for range txMaxAttempts {
+		mock.ExpectBegin()
+		mock.ExpectRollback()
+	}
+
+	calls := 0
+	err := uow.Do(context.Background(), nil, func(ctx context.Context, repos Repositories) error {
+		calls++
+		return &pq.Error{Code: "40001"}
+	})
+	if !isRetryableTxError(err) || calls != txMaxAttempts {
+		t.Fatalf("expected %d attempts ending in the serialization failure, got %d, %v", txMaxAttempts, calls, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestIsRetryableTxError(t *testing.T) {
+	cases := []struct {
+		err  error
+		want bool
+	}{
+		{&pq.Error{Code: "40001"}, true},
+		{fmt.Errorf("commit tx: %w", &pq.Error{Code: "40P01"}), true},
+		{&pq.Error{Code: "23505"}, false},
+		{errors.New("40001"), false},
+		{nil, false},
+	}
+	for _, c := range cases {
+		if got := isRetryableTxError(c.err); got != c.want {
+			t.Errorf("isRetryableTxError(%v) = %v, want %v", c.err, got, c.want)
+		}
+	}
+}
@@ -5,7 +5,9 @@ import (
 	"database/sql"
 	"fmt"
 
+	"scm/internal/interfaces"
 	"scm/internal/models"
+	"scm/internal/tracing"
 )
 
 type UserRepository interface {
@@ -13,7 +15,7 @@ type UserRepository interface {
 	GetByID(ctx context.Context, id string) (*models.User, error)
 	GetByEmail(ctx context.Context, email string) (*models.User, error)
 	GetByIdentifier(ctx context.Context, identifier string) (*models.User, error)
-	List(ctx context.Context, limit int, offset int) ([]models.User, error)
+	List(ctx context.Context, page interfaces.ListPage) ([]models.User, error)
 	Count(ctx context.Context) (int, error)
 	ListAll(ctx context.Context) ([]models.User, error)
 	UpdateProfile(ctx context.Context, id string, req *models.UpdateUserRequest) error
@@ -22,14 +24,17 @@ type UserRepository interface {
 }
 
 type userRepository struct {
-	db *sql.DB
+	db DBTX
 }
 
-func NewUserRepository(db *sql.DB) UserRepository {
+func NewUserRepository(db DBTX) UserRepository {
 	return &userRepository{db: db}
 }
 
 func (r *userRepository) Create(ctx context.Context, user *models.User) error {
+	ctx, span := tracing.Repository(ctx, "userRepository.Create")
+	defer span.End()
+
 	query := `
 		INSERT INTO users (id, email, name, user_name, phone_number, password_hash, created_at)
 		VALUES ($1, $2, $3, $4, $5, $6, $7)
@@ -41,6 +46,9 @@ func (r *userRepository) Create(ctx context.Context, user *models.User) error {
 }
 
 func (r *userRepository) GetByID(ctx context.Context, id string) (*models.User, error) {
+	ctx, span := tracing.Repository(ctx, "userRepository.GetByID")
+	defer span.End()
+
 	query := `
 		SELECT id, email, name, user_name, phone_number, password_hash, created_at
 		FROM users
@@ -59,6 +67,9 @@ func (r *userRepository) GetByID(ctx context.Context, id string) (*models.User,
 }
 
 func (r *userRepository) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	ctx, span := tracing.Repository(ctx, "userRepository.GetByEmail")
+	defer span.End()
+
 	query := `
 		SELECT id, email, name, user_name, phone_number, password_hash, created_at
 		FROM users
@@ -77,6 +88,9 @@ func (r *userRepository) GetByEmail(ctx context.Context, email string) (*models.
 }
 
 func (r *userRepository) GetByIdentifier(ctx context.Context, identifier string) (*models.User, error) {
+	ctx, span := tracing.Repository(ctx, "userRepository.GetByIdentifier")
+	defer span.End()
+
 	query := `
 		SELECT id, email, name, user_name, phone_number, password_hash, created_at
 		FROM users
@@ -97,24 +111,25 @@ func (r *userRepository) GetByIdentifier(ctx context.Context, identifier string)
 	return &u, nil
 }
 
-func (r *userRepository) List(ctx context.Context, limit int, offset int) ([]models.User, error) {
+// UserSort lists the fields user lists can be sorted by.
+var UserSort = interfaces.Sortable{
+	Columns: map[string]string{
+		"id":         "id",
+		"email":      "email",
+		"created_at": "created_at",
+	},
+	Default: "-created_at",
+}
+
+func (r *userRepository) List(ctx context.Context, page interfaces.ListPage) ([]models.User, error) {
+	ctx, span := tracing.Repository(ctx, "userRepository.List")
+	defer span.End()
+
 	query := `
 		SELECT id, email, name, user_name, phone_number, created_at
 		FROM users
-		ORDER BY created_at DESC
 	`
-
-	args := make([]any, 0, 2)
-	argPos := 1
-	if limit > 0 {
-		query += fmt.Sprintf(" LIMIT $%d", argPos)
-		args = append(args, limit)
-		argPos++
-	}
-	if offset > 0 {
-		query += fmt.Sprintf(" OFFSET $%d", argPos)
-		args = append(args, offset)
-	}
+	query, args := appendListPage(query, nil, false, page, UserSort)
 
 	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
@@ -131,10 +146,13 @@ func (r *userRepository) List(ctx context.Context, limit int, offset int) ([]mod
 		users = append(users, u)
 	}
 
-	return users, rows.Err()
+	return pageRows(users, rows.Err(), page)
 }
 
 func (r *userRepository) Count(ctx context.Context) (int, error) {
+	ctx, span := tracing.Repository(ctx, "userRepository.Count")
+	defer span.End()
+
 	query := `SELECT COUNT(*) FROM users`
 	var total int
 	if err := r.db.QueryRowContext(ctx, query).Scan(&total); err != nil {
@@ -144,6 +162,9 @@ func (r *userRepository) Count(ctx context.Context) (int, error) {
 }
 
 func (r *userRepository) ListAll(ctx context.Context) ([]models.User, error) {
+	ctx, span := tracing.Repository(ctx, "userRepository.ListAll")
+	defer span.End()
+
 	query := `
 		SELECT id, email, name, user_name, phone_number, created_at
 		FROM users
@@ -169,6 +190,9 @@ func (r *userRepository) ListAll(ctx context.Context) ([]models.User, error) {
 }
 
 func (r *userRepository) UpdateProfile(ctx context.Context, id string, req *models.UpdateUserRequest) error {
+	ctx, span := tracing.Repository(ctx, "userRepository.UpdateProfile")
+	defer span.End()
+
 	query := `
 		UPDATE users
 		SET email = COALESCE($1, email),
@@ -191,6 +215,9 @@ func (r *userRepository) UpdateProfile(ctx context.Context, id string, req *mode
 }
 
 func (r *userRepository) Delete(ctx context.Context, id string) error {
+	ctx, span := tracing.Repository(ctx, "userRepository.Delete")
+	defer span.End()
+
 	res, err := r.db.ExecContext(ctx, `DELETE FROM users WHERE id = $1`, id)
 	if err != nil {
 		return err
@@ -206,6 +233,9 @@ func (r *userRepository) Delete(ctx context.Context, id string) error {
 }
 
 func (r *userRepository) UpdatePasswordHash(ctx context.Context, userID string, passwordHash string) error {
+	ctx, span := tracing.Repository(ctx, "userRepository.UpdatePasswordHash")
+	defer span.End()
+
 	query := `UPDATE users SET password_hash = $1 WHERE id = $2`
 	res, err := r.db.ExecContext(ctx, query, passwordHash, userID)
 	if err != nil {
@@ -7,7 +7,10 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/lib/pq"
+	"scm/internal/interfaces"
 	"scm/internal/models"
+	"scm/internal/tracing"
 )
 
 type VenueRepository interface {
@@ -15,10 +18,15 @@ type VenueRepository interface {
 	GetByID(ctx context.Context, id int) (*models.Venue, error)
 	GetByIDWithDevices(ctx context.Context, id int) (*models.VenueWithDevices, error)
 	GetByName(ctx context.Context, name string) (*models.Venue, error)
-	List(ctx context.Context, limit int, offset int) ([]*models.Venue, error)
+	List(ctx context.Context, page interfaces.ListPage) ([]*models.Venue, error)
 	Count(ctx context.Context) (int, error)
-	Update(ctx context.Context, venue *models.Venue) error
+	// Update saves venue if it is still at version (0 skips the check) and returns
+	// interfaces.ErrVersionConflict if it has changed since.
+	Update(ctx context.Context, venue *models.Venue, version int) error
 	Delete(ctx context.Context, id int) error
+	Dependencies(ctx context.Context, id int) (*models.DeletionDependencies, error)
+	// DeleteCascade deletes the venue along with its device assignments and alert rules.
+	DeleteCascade(ctx context.Context, id int) (*models.DeletionDependencies, error)
 	
 	// Many-to-many operations
 	AddDeviceToVenue(ctx context.Context, venueID, deviceID int) error
@@ -27,22 +35,65 @@ type VenueRepository interface {
 	CountVenuesByDeviceID(ctx context.Context, deviceID int) (int, error)
 	GetDevicesByVenueID(ctx context.Context, venueID int, limit int, offset int) ([]*models.Device, error)
 	CountDevicesByVenueID(ctx context.Context, venueID int) (int, error)
+	GetDevicesByVenueIDs(ctx context.Context, venueIDs []int) (map[int][]*models.Device, error)
+
+	// AssignDevices adds, removes or replaces the venue's devices with those matching filters,
+	// in a single transaction. With dryRun set it only reports what would change.
+	AssignDevices(ctx context.Context, venueID int, filters DeviceFilters, mode string, dryRun bool) (*models.VenueAssignmentResult, error)
+	ListSmartVenues(ctx context.Context) ([]*models.Venue, error)
 }
 
 type venueRepository struct {
-	db *sql.DB
+	db DBTX
 }
 
-func NewVenueRepository(db *sql.DB) VenueRepository {
+func NewVenueRepository(db DBTX) VenueRepository {
 	return &venueRepository{db: db}
 }
 
+const venueColumns = "id, name, smart_filter, created_at, updated_at, version"
+
+func scanVenue(row interface{ Scan(dest ...any) error }) (*models.Venue, error) {
+	var venue models.Venue
+	var smartFilter []byte
+	if err := row.Scan(&venue.ID, &venue.Name, &smartFilter, &venue.CreatedAt, &venue.UpdatedAt, &venue.Version); err != nil {
+		return nil, err
+	}
+	if len(smartFilter) > 0 {
+		var selector models.DeviceSelector
+		if err := json.Unmarshal(smartFilter, &selector); err != nil {
+			return nil, fmt.Errorf("unmarshal smart_filter: %w", err)
+		}
+		venue.SmartFilter = &selector
+	}
+	return &venue, nil
+}
+
+func marshalSmartFilter(selector *models.DeviceSelector) ([]byte, error) {
+	if selector == nil || selector.IsEmpty() {
+		return nil, nil
+	}
+	b, err := json.Marshal(selector)
+	if err != nil {
+		return nil, fmt.Errorf("marshal smart_filter: %w", err)
+	}
+	return b, nil
+}
+
 func (r *venueRepository) Create(ctx context.Context, venue *models.Venue) error {
-	query := `INSERT INTO venues (name, created_at, updated_at) 
-			  VALUES ($1, $2, $3) RETURNING id`
+	ctx, span := tracing.Repository(ctx, "venueRepository.Create")
+	defer span.End()
+
+	smartFilter, err := marshalSmartFilter(venue.SmartFilter)
+	if err != nil {
+		return err
+	}
+
+	query := `INSERT INTO venues (name, smart_filter, created_at, updated_at) 
+			  VALUES ($1, $2, $3, $4) RETURNING id, version`
 	
 	now := time.Now()
-	err := r.db.QueryRowContext(ctx, query, venue.Name, now, now).Scan(&venue.ID)
+	err = r.db.QueryRowContext(ctx, query, venue.Name, smartFilter, now, now).Scan(&venue.ID, &venue.Version)
 	if err != nil {
 		return fmt.Errorf("create venue: %w", err)
 	}
@@ -53,13 +104,13 @@ func (r *venueRepository) Create(ctx context.Context, venue *models.Venue) error
 }
 
 func (r *venueRepository) GetByID(ctx context.Context, id int) (*models.Venue, error) {
-	query := `SELECT id, name, created_at, updated_at 
+	ctx, span := tracing.Repository(ctx, "venueRepository.GetByID")
+	defer span.End()
+
+	query := `SELECT ` + venueColumns + ` 
 			  FROM venues WHERE id = $1`
 	
-	var venue models.Venue
-	err := r.db.QueryRowContext(ctx, query, id).Scan(
-		&venue.ID, &venue.Name, &venue.CreatedAt, &venue.UpdatedAt,
-	)
+	venue, err := scanVenue(r.db.QueryRowContext(ctx, query, id))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("venue not found")
@@ -67,10 +118,13 @@ func (r *venueRepository) GetByID(ctx context.Context, id int) (*models.Venue, e
 		return nil, fmt.Errorf("get venue by id: %w", err)
 	}
 	
-	return &venue, nil
+	return venue, nil
 }
 
 func (r *venueRepository) GetByIDWithDevices(ctx context.Context, id int) (*models.VenueWithDevices, error) {
+	ctx, span := tracing.Repository(ctx, "venueRepository.GetByIDWithDevices")
+	defer span.End()
+
 	// Get venue details
 	venueQuery := `SELECT id, name, created_at, updated_at FROM venues WHERE id = $1`
 	var venue models.VenueWithDevices
@@ -125,13 +179,13 @@ func (r *venueRepository) GetByIDWithDevices(ctx context.Context, id int) (*mode
 }
 
 func (r *venueRepository) GetByName(ctx context.Context, name string) (*models.Venue, error) {
-	query := `SELECT id, name, created_at, updated_at 
+	ctx, span := tracing.Repository(ctx, "venueRepository.GetByName")
+	defer span.End()
+
+	query := `SELECT ` + venueColumns + ` 
 			  FROM venues WHERE name = $1`
 	
-	var venue models.Venue
-	err := r.db.QueryRowContext(ctx, query, name).Scan(
-		&venue.ID, &venue.Name, &venue.CreatedAt, &venue.UpdatedAt,
-	)
+	venue, err := scanVenue(r.db.QueryRowContext(ctx, query, name))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("venue not found")
@@ -139,14 +193,27 @@ func (r *venueRepository) GetByName(ctx context.Context, name string) (*models.V
 		return nil, fmt.Errorf("get venue by name: %w", err)
 	}
 	
-	return &venue, nil
+	return venue, nil
 }
 
-func (r *venueRepository) List(ctx context.Context, limit int, offset int) ([]*models.Venue, error) {
-	query := `SELECT id, name, created_at, updated_at 
-			  FROM venues ORDER BY created_at DESC LIMIT $1 OFFSET $2`
+// VenueSort lists the fields venue lists can be sorted by.
+var VenueSort = interfaces.Sortable{
+	Columns: map[string]string{
+		"id":         "id",
+		"name":       "name",
+		"created_at": "created_at",
+		"updated_at": "updated_at",
+	},
+	Default: "-created_at",
+}
+
+func (r *venueRepository) List(ctx context.Context, page interfaces.ListPage) ([]*models.Venue, error) {
+	ctx, span := tracing.Repository(ctx, "venueRepository.List")
+	defer span.End()
+
+	query, args := appendListPage(`SELECT `+venueColumns+` FROM venues`, nil, false, page, VenueSort)
 	
-	rows, err := r.db.QueryContext(ctx, query, limit, offset)
+	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("list venues: %w", err)
 	}
@@ -154,17 +221,20 @@ func (r *venueRepository) List(ctx context.Context, limit int, offset int) ([]*m
 	
 	var venues []*models.Venue
 	for rows.Next() {
-		var venue models.Venue
-		if err := rows.Scan(&venue.ID, &venue.Name, &venue.CreatedAt, &venue.UpdatedAt); err != nil {
+		venue, err := scanVenue(rows)
+		if err != nil {
 			return nil, fmt.Errorf("scan venue: %w", err)
 		}
-		venues = append(venues, &venue)
+		venues = append(venues, venue)
 	}
 	
-	return venues, nil
+	return pageRows(venues, rows.Err(), page)
 }
 
 func (r *venueRepository) Count(ctx context.Context) (int, error) {
+	ctx, span := tracing.Repository(ctx, "venueRepository.Count")
+	defer span.End()
+
 	var count int
 	err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM venues").Scan(&count)
 	if err != nil {
@@ -173,31 +243,42 @@ func (r *venueRepository) Count(ctx context.Context) (int, error) {
 	return count, nil
 }
 
-func (r *venueRepository) Update(ctx context.Context, venue *models.Venue) error {
-	query := `UPDATE venues SET name = $1, updated_at = $2 
-			  WHERE id = $3`
-	
-	now := time.Now()
-	result, err := r.db.ExecContext(ctx, query, venue.Name, now, venue.ID)
+func (r *venueRepository) Update(ctx context.Context, venue *models.Venue, version int) error {
+	ctx, span := tracing.Repository(ctx, "venueRepository.Update")
+	defer span.End()
+
+	smartFilter, err := marshalSmartFilter(venue.SmartFilter)
 	if err != nil {
-		return fmt.Errorf("update venue: %w", err)
+		return err
 	}
+
+	query := `UPDATE venues SET name = $1, smart_filter = $2, updated_at = $3 
+			  WHERE id = $4 AND ($5 = 0 OR version = $5) RETURNING version`
 	
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		return fmt.Errorf("check rows affected: %w", err)
+	now := time.Now()
+	err = r.db.QueryRowContext(ctx, query, venue.Name, smartFilter, now, venue.ID, version).Scan(&venue.Version)
+	if err == sql.ErrNoRows {
+		return missedUpdateError(ctx, r.db, `SELECT EXISTS (SELECT 1 FROM venues WHERE id = $1)`, venue.ID,
+			fmt.Errorf("venue not found"))
 	}
-	
-	if rowsAffected == 0 {
-		return fmt.Errorf("venue not found")
+	if err != nil {
+		return fmt.Errorf("update venue: %w", err)
 	}
 	
 	venue.UpdatedAt = now
 	return nil
 }
 
+// Delete removes a venue. It is blocked while devices are assigned to the venue; use
+// DeleteCascade to remove the assignments as well.
 func (r *venueRepository) Delete(ctx context.Context, id int) error {
-	query := "DELETE FROM venues WHERE id = $1"
+	ctx, span := tracing.Repository(ctx, "venueRepository.Delete")
+	defer span.End()
+
+	query := `
+		DELETE FROM venues
+		WHERE id = $1 AND NOT EXISTS (SELECT 1 FROM venue_devices WHERE venue_id = $1)
+	`
 	
 	result, err := r.db.ExecContext(ctx, query, id)
 	if err != nil {
@@ -210,14 +291,86 @@ func (r *venueRepository) Delete(ctx context.Context, id int) error {
 	}
 	
 	if rowsAffected == 0 {
-		return fmt.Errorf("venue not found")
+		var deviceCount int64
+		err := r.db.QueryRowContext(ctx, `
+			SELECT COUNT(vd.device_id)
+			FROM venues v
+			LEFT JOIN venue_devices vd ON vd.venue_id = v.id
+			WHERE v.id = $1
+			GROUP BY v.id
+		`, id).Scan(&deviceCount)
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("venue not found")
+		}
+		if err != nil {
+			return fmt.Errorf("check venue devices: %w", err)
+		}
+		return &interfaces.DeletionBlockedError{
+			Resource:   "venue",
+			References: map[string]int64{"devices": deviceCount},
+		}
 	}
 	
 	return nil
 }
 
+// Dependencies lists the device assignments and alert rules a cascade delete of the venue would remove.
+func (r *venueRepository) Dependencies(ctx context.Context, id int) (*models.DeletionDependencies, error) {
+	ctx, span := tracing.Repository(ctx, "venueRepository.Dependencies")
+	defer span.End()
+
+	if _, err := r.GetByID(ctx, id); err != nil {
+		return nil, err
+	}
+	return venueDependencies(ctx, r.db, id)
+}
+
+// DeleteCascade deletes the venue together with its device assignments and alert rules in one
+// transaction and returns what was removed. Devices themselves are kept.
+func (r *venueRepository) DeleteCascade(ctx context.Context, id int) (*models.DeletionDependencies, error) {
+	ctx, span := tracing.Repository(ctx, "venueRepository.DeleteCascade")
+	defer span.End()
+
+	tx, err := beginTx(ctx, r.db)
+	if err != nil {
+		return nil, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	var locked int
+	if err := tx.QueryRowContext(ctx, "SELECT id FROM venues WHERE id = $1 FOR UPDATE", id).Scan(&locked); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("venue not found")
+		}
+		return nil, fmt.Errorf("lock venue: %w", err)
+	}
+
+	deps, err := venueDependencies(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM venue_devices WHERE venue_id = $1", id); err != nil {
+		return nil, fmt.Errorf("delete venue devices: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM alert_rules WHERE venue_id = $1", id); err != nil {
+		return nil, fmt.Errorf("delete venue alert rules: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM venues WHERE id = $1", id); err != nil {
+		return nil, fmt.Errorf("delete venue: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit tx: %w", err)
+	}
+	return deps, nil
+}
+
 // Many-to-many operations
 func (r *venueRepository) AddDeviceToVenue(ctx context.Context, venueID, deviceID int) error {
+	ctx, span := tracing.Repository(ctx, "venueRepository.AddDeviceToVenue")
+	defer span.End()
+
 	// Check if venue exists
 	var venueExists bool
 	err := r.db.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM venues WHERE id = $1)", venueID).Scan(&venueExists)
@@ -252,6 +405,9 @@ func (r *venueRepository) AddDeviceToVenue(ctx context.Context, venueID, deviceI
 }
 
 func (r *venueRepository) RemoveDeviceFromVenue(ctx context.Context, venueID, deviceID int) error {
+	ctx, span := tracing.Repository(ctx, "venueRepository.RemoveDeviceFromVenue")
+	defer span.End()
+
 	// Check if venue exists
 	var venueExists bool
 	err := r.db.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM venues WHERE id = $1)", venueID).Scan(&venueExists)
@@ -293,7 +449,10 @@ func (r *venueRepository) RemoveDeviceFromVenue(ctx context.Context, venueID, de
 }
 
 func (r *venueRepository) GetVenuesByDeviceID(ctx context.Context, deviceID int, limit int, offset int) ([]*models.Venue, error) {
-	query := `SELECT v.id, v.name, v.created_at, v.updated_at 
+	ctx, span := tracing.Repository(ctx, "venueRepository.GetVenuesByDeviceID")
+	defer span.End()
+
+	query := `SELECT v.id, v.name, v.smart_filter, v.created_at, v.updated_at, v.version 
 			  FROM venues v 
 			  JOIN venue_devices vd ON v.id = vd.venue_id 
 			  WHERE vd.device_id = $1 ORDER BY v.created_at DESC LIMIT $2 OFFSET $3`
@@ -306,17 +465,20 @@ func (r *venueRepository) GetVenuesByDeviceID(ctx context.Context, deviceID int,
 	
 	var venues []*models.Venue
 	for rows.Next() {
-		var venue models.Venue
-		if err := rows.Scan(&venue.ID, &venue.Name, &venue.CreatedAt, &venue.UpdatedAt); err != nil {
+		venue, err := scanVenue(rows)
+		if err != nil {
 			return nil, fmt.Errorf("scan venue: %w", err)
 		}
-		venues = append(venues, &venue)
+		venues = append(venues, venue)
 	}
 	
 	return venues, nil
 }
 
 func (r *venueRepository) CountVenuesByDeviceID(ctx context.Context, deviceID int) (int, error) {
+	ctx, span := tracing.Repository(ctx, "venueRepository.CountVenuesByDeviceID")
+	defer span.End()
+
 	var count int
 	err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM venue_devices WHERE device_id = $1", deviceID).Scan(&count)
 	if err != nil {
@@ -326,6 +488,9 @@ func (r *venueRepository) CountVenuesByDeviceID(ctx context.Context, deviceID in
 }
 
 func (r *venueRepository) GetDevicesByVenueID(ctx context.Context, venueID int, limit int, offset int) ([]*models.Device, error) {
+	ctx, span := tracing.Repository(ctx, "venueRepository.GetDevicesByVenueID")
+	defer span.End()
+
 	query := `SELECT d.id, d.device_type, d.region, d.name, d.host_name, d.description, d.change, d.last_synced_at, d.sync_status, d.project, d.device_config, d.rtty_data, d.created_at, d.updated_at
 			  FROM devices d 
 			  JOIN venue_devices vd ON d.id = vd.device_id 
@@ -364,6 +529,9 @@ func (r *venueRepository) GetDevicesByVenueID(ctx context.Context, venueID int,
 }
 
 func (r *venueRepository) CountDevicesByVenueID(ctx context.Context, venueID int) (int, error) {
+	ctx, span := tracing.Repository(ctx, "venueRepository.CountDevicesByVenueID")
+	defer span.End()
+
 	var count int
 	err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM venue_devices WHERE venue_id = $1", venueID).Scan(&count)
 	if err != nil {
@@ -371,3 +539,202 @@ func (r *venueRepository) CountDevicesByVenueID(ctx context.Context, venueID int
 	}
 	return count, nil
 }
+
+// GetDevicesByVenueIDs loads the devices of several venues in one query, keyed by venue ID.
+func (r *venueRepository) GetDevicesByVenueIDs(ctx context.Context, venueIDs []int) (map[int][]*models.Device, error) {
+	ctx, span := tracing.Repository(ctx, "venueRepository.GetDevicesByVenueIDs")
+	defer span.End()
+
+	result := make(map[int][]*models.Device, len(venueIDs))
+	if len(venueIDs) == 0 {
+		return result, nil
+	}
+
+	query := `SELECT vd.venue_id, d.id, d.device_type, d.region, d.name, d.host_name, d.description, d.change, d.last_synced_at, d.sync_status, d.project, d.device_config, d.rtty_data, d.created_at, d.updated_at
+			  FROM devices d
+			  JOIN venue_devices vd ON d.id = vd.device_id
+			  WHERE vd.venue_id = ANY($1) ORDER BY vd.venue_id, d.created_at DESC`
+
+	rows, err := r.db.QueryContext(ctx, query, pq.Array(venueIDs))
+	if err != nil {
+		return nil, fmt.Errorf("get devices by venues: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var venueID int
+		var device models.Device
+		var deviceTypeJSON, regionJSON []byte
+		if err := rows.Scan(
+			&venueID, &device.ID, &deviceTypeJSON, &regionJSON, &device.Name, &device.HostName,
+			&device.Description, &device.Change, &device.LastSyncedAt, &device.SyncStatus,
+			&device.Project, &device.DeviceConfig, &device.RttyData,
+			&device.CreatedAt, &device.UpdatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("scan device: %w", err)
+		}
+
+		if err := json.Unmarshal(deviceTypeJSON, &device.DeviceType); err != nil {
+			return nil, fmt.Errorf("unmarshal device_type: %w", err)
+		}
+		if err := json.Unmarshal(regionJSON, &device.Region); err != nil {
+			return nil, fmt.Errorf("unmarshal region: %w", err)
+		}
+
+		result[venueID] = append(result[venueID], &device)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate devices by venues: %w", err)
+	}
+
+	return result, nil
+}
+
+func (r *venueRepository) AssignDevices(ctx context.Context, venueID int, filters DeviceFilters, mode string, dryRun bool) (*models.VenueAssignmentResult, error) {
+	ctx, span := tracing.Repository(ctx, "venueRepository.AssignDevices")
+	defer span.End()
+
+	tx, err := beginTx(ctx, r.db)
+	if err != nil {
+		return nil, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Lock the venue row so concurrent assignments to the same venue serialize.
+	var locked int
+	err = tx.QueryRowContext(ctx, "SELECT id FROM venues WHERE id = $1 FOR UPDATE", venueID).Scan(&locked)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("venue not found")
+		}
+		return nil, fmt.Errorf("lock venue: %w", err)
+	}
+
+	clause, args, _ := buildDeviceFilterClause(filters, nil, 1)
+	matched, err := queryDeviceRefs(ctx, tx, "SELECT devices.id, devices.host_name FROM devices WHERE 1=1"+clause+" ORDER BY devices.host_name", args...)
+	if err != nil {
+		return nil, fmt.Errorf("match devices: %w", err)
+	}
+	current, err := queryDeviceRefs(ctx, tx, `SELECT d.id, d.host_name FROM devices d
+		JOIN venue_devices vd ON vd.device_id = d.id WHERE vd.venue_id = $1 ORDER BY d.host_name`, venueID)
+	if err != nil {
+		return nil, fmt.Errorf("current venue devices: %w", err)
+	}
+
+	matchedIDs := make(map[int]bool, len(matched))
+	for _, d := range matched {
+		matchedIDs[d.ID] = true
+	}
+	currentIDs := make(map[int]bool, len(current))
+	for _, d := range current {
+		currentIDs[d.ID] = true
+	}
+
+	result := &models.VenueAssignmentResult{
+		VenueID: venueID,
+		Mode:    mode,
+		DryRun:  dryRun,
+		Matched: len(matched),
+		Added:   []models.VenueDeviceRef{},
+		Removed: []models.VenueDeviceRef{},
+	}
+	switch mode {
+	case models.VenueAssignAdd, models.VenueAssignReplace:
+		for _, d := range matched {
+			if !currentIDs[d.ID] {
+				result.Added = append(result.Added, d)
+			}
+		}
+	}
+	switch mode {
+	case models.VenueAssignRemove:
+		for _, d := range current {
+			if matchedIDs[d.ID] {
+				result.Removed = append(result.Removed, d)
+			}
+		}
+	case models.VenueAssignReplace:
+		for _, d := range current {
+			if !matchedIDs[d.ID] {
+				result.Removed = append(result.Removed, d)
+			}
+		}
+	}
+	result.Retained = len(current) - len(result.Removed)
+
+	if dryRun {
+		return result, nil
+	}
+
+	if len(result.Added) > 0 {
+		_, err = tx.ExecContext(ctx, `INSERT INTO venue_devices (venue_id, device_id, added_at)
+			SELECT $1, unnest($2::int[]), NOW() ON CONFLICT DO NOTHING`, venueID, pq.Array(deviceRefIDs(result.Added)))
+		if err != nil {
+			return nil, fmt.Errorf("add devices to venue: %w", err)
+		}
+	}
+	if len(result.Removed) > 0 {
+		_, err = tx.ExecContext(ctx, "DELETE FROM venue_devices WHERE venue_id = $1 AND device_id = ANY($2)",
+			venueID, pq.Array(deviceRefIDs(result.Removed)))
+		if err != nil {
+			return nil, fmt.Errorf("remove devices from venue: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit tx: %w", err)
+	}
+	return result, nil
+}
+
+func queryDeviceRefs(ctx context.Context, q DBTX, query string, args ...any) ([]models.VenueDeviceRef, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var refs []models.VenueDeviceRef
+	for rows.Next() {
+		var ref models.VenueDeviceRef
+		if err := rows.Scan(&ref.ID, &ref.HostName); err != nil {
+			return nil, err
+		}
+		refs = append(refs, ref)
+	}
+	return refs, rows.Err()
+}
+
+func deviceRefIDs(refs []models.VenueDeviceRef) []int {
+	ids := make([]int, 0, len(refs))
+	for _, ref := range refs {
+		ids = append(ids, ref.ID)
+	}
+	return ids
+}
+
+func (r *venueRepository) ListSmartVenues(ctx context.Context) ([]*models.Venue, error) {
+	ctx, span := tracing.Repository(ctx, "venueRepository.ListSmartVenues")
+	defer span.End()
+
+	query := `SELECT ` + venueColumns + ` FROM venues WHERE smart_filter IS NOT NULL ORDER BY id`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("list smart venues: %w", err)
+	}
+	defer rows.Close()
+
+	var venues []*models.Venue
+	for rows.Next() {
+		venue, err := scanVenue(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan venue: %w", err)
+		}
+		venues = append(venues, venue)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate smart venues: %w", err)
+	}
+	return venues, nil
+}
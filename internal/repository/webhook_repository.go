@@ -0,0 +1,421 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"scm/internal/models"
+	"scm/internal/tracing"
+)
+
+type WebhookDeliveryFilters struct {
+	Status         *string
+	SubscriptionID *int
+	EventType      *string
+}
+
+type WebhookRepository interface {
+	CreateSubscription(ctx context.Context, sub *models.WebhookSubscription) error
+	GetSubscription(ctx context.Context, id int) (*models.WebhookSubscription, error)
+	ListSubscriptions(ctx context.Context, limit int, offset int) ([]*models.WebhookSubscription, error)
+	CountSubscriptions(ctx context.Context) (int, error)
+	UpdateSubscription(ctx context.Context, sub *models.WebhookSubscription) error
+	DeleteSubscription(ctx context.Context, id int) error
+
+	// Enqueue adds a pending delivery of event for every enabled subscription to its
+	// type and returns how many it added.
+	Enqueue(ctx context.Context, event *models.WebhookEvent) (int, error)
+	// ClaimDue returns up to limit pending deliveries due at now, with their
+	// subscription's URL and secret, and pushes their next attempt lease past now so
+	// concurrent dispatchers skip them while they are in flight.
+	ClaimDue(ctx context.Context, now time.Time, limit int, lease time.Duration) ([]*models.WebhookDelivery, error)
+	MarkDelivered(ctx context.Context, id int64, statusCode int) error
+	// MarkFailed records a failed attempt. The delivery is retried at retryAt, or is
+	// dead when retryAt is nil.
+	MarkFailed(ctx context.Context, id int64, statusCode *int, lastError string, retryAt *time.Time) error
+	GetDelivery(ctx context.Context, id int64) (*models.WebhookDelivery, error)
+	ListDeliveries(ctx context.Context, filters WebhookDeliveryFilters, limit int, offset int) ([]*models.WebhookDelivery, error)
+	CountDeliveries(ctx context.Context, filters WebhookDeliveryFilters) (int, error)
+	// Replay queues a delivered or dead delivery again with a fresh set of attempts.
+	// It returns "webhook delivery not found" or "webhook delivery is pending".
+	Replay(ctx context.Context, id int64) (*models.WebhookDelivery, error)
+	// ReplayDead queues every dead delivery again, or those of one subscription.
+	ReplayDead(ctx context.Context, subscriptionID *int) (int64, error)
+}
+
+type webhookRepository struct {
+	db DBTX
+}
+
+func NewWebhookRepository(db DBTX) WebhookRepository {
+	return &webhookRepository{db: db}
+}
+
+const webhookSubscriptionColumns = `id, url, secret, event_types, enabled, created_by, created_at, updated_at`
+
+func scanWebhookSubscription(row interface{ Scan(dest ...any) error }) (*models.WebhookSubscription, error) {
+	var sub models.WebhookSubscription
+	var eventTypes pq.StringArray
+	if err := row.Scan(&sub.ID, &sub.URL, &sub.Secret, &eventTypes, &sub.Enabled, &sub.CreatedBy, &sub.CreatedAt, &sub.UpdatedAt); err != nil {
+		return nil, err
+	}
+	sub.EventTypes = []string(eventTypes)
+	return &sub, nil
+}
+
+func (r *webhookRepository) CreateSubscription(ctx context.Context, sub *models.WebhookSubscription) error {
+	ctx, span := tracing.Repository(ctx, "webhookRepository.CreateSubscription")
+	defer span.End()
+
+	query := `
+		INSERT INTO webhook_subscriptions (url, secret, event_types, enabled, created_by)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at, updated_at
+	`
+
+	err := r.db.QueryRowContext(ctx, query, sub.URL, sub.Secret, pq.Array(sub.EventTypes), sub.Enabled, sub.CreatedBy).
+		Scan(&sub.ID, &sub.CreatedAt, &sub.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("create webhook subscription: %w", err)
+	}
+	return nil
+}
+
+func (r *webhookRepository) GetSubscription(ctx context.Context, id int) (*models.WebhookSubscription, error) {
+	ctx, span := tracing.Repository(ctx, "webhookRepository.GetSubscription")
+	defer span.End()
+
+	query := "SELECT " + webhookSubscriptionColumns + " FROM webhook_subscriptions WHERE id = $1"
+
+	sub, err := scanWebhookSubscription(r.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("webhook subscription not found")
+		}
+		return nil, fmt.Errorf("get webhook subscription: %w", err)
+	}
+	return sub, nil
+}
+
+func (r *webhookRepository) ListSubscriptions(ctx context.Context, limit int, offset int) ([]*models.WebhookSubscription, error) {
+	ctx, span := tracing.Repository(ctx, "webhookRepository.ListSubscriptions")
+	defer span.End()
+
+	query := "SELECT " + webhookSubscriptionColumns + " FROM webhook_subscriptions ORDER BY id LIMIT $1 OFFSET $2"
+
+	rows, err := r.db.QueryContext(ctx, query, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("list webhook subscriptions: %w", err)
+	}
+	defer rows.Close()
+
+	subs := []*models.WebhookSubscription{}
+	for rows.Next() {
+		sub, err := scanWebhookSubscription(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan webhook subscription: %w", err)
+		}
+		subs = append(subs, sub)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate webhook subscriptions: %w", err)
+	}
+	return subs, nil
+}
+
+func (r *webhookRepository) CountSubscriptions(ctx context.Context) (int, error) {
+	ctx, span := tracing.Repository(ctx, "webhookRepository.CountSubscriptions")
+	defer span.End()
+
+	var count int
+	if err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM webhook_subscriptions").Scan(&count); err != nil {
+		return 0, fmt.Errorf("count webhook subscriptions: %w", err)
+	}
+	return count, nil
+}
+
+func (r *webhookRepository) UpdateSubscription(ctx context.Context, sub *models.WebhookSubscription) error {
+	ctx, span := tracing.Repository(ctx, "webhookRepository.UpdateSubscription")
+	defer span.End()
+
+	query := `
+		UPDATE webhook_subscriptions SET url = $2, secret = $3, event_types = $4, enabled = $5
+		WHERE id = $1
+		RETURNING updated_at
+	`
+
+	err := r.db.QueryRowContext(ctx, query, sub.ID, sub.URL, sub.Secret, pq.Array(sub.EventTypes), sub.Enabled).Scan(&sub.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("webhook subscription not found")
+		}
+		return fmt.Errorf("update webhook subscription: %w", err)
+	}
+	return nil
+}
+
+func (r *webhookRepository) DeleteSubscription(ctx context.Context, id int) error {
+	ctx, span := tracing.Repository(ctx, "webhookRepository.DeleteSubscription")
+	defer span.End()
+
+	result, err := r.db.ExecContext(ctx, "DELETE FROM webhook_subscriptions WHERE id = $1", id)
+	if err != nil {
+		return fmt.Errorf("delete webhook subscription: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return fmt.Errorf("webhook subscription not found")
+	}
+	return nil
+}
+
+func (r *webhookRepository) Enqueue(ctx context.Context, event *models.WebhookEvent) (int, error) {
+	ctx, span := tracing.Repository(ctx, "webhookRepository.Enqueue")
+	defer span.End()
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return 0, fmt.Errorf("marshal webhook event: %w", err)
+	}
+
+	query := `
+		INSERT INTO webhook_deliveries (subscription_id, event_id, event_type, payload)
+		SELECT id, $1, $2, $3 FROM webhook_subscriptions
+		WHERE enabled = TRUE AND $2 = ANY(event_types)
+		ON CONFLICT (subscription_id, event_id) DO NOTHING
+	`
+	result, err := r.db.ExecContext(ctx, query, event.ID, event.Type, payload)
+	if err != nil {
+		return 0, fmt.Errorf("enqueue webhook event: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("get rows affected: %w", err)
+	}
+	return int(rows), nil
+}
+
+const webhookDeliveryColumns = `d.id, d.subscription_id, d.event_id, d.event_type, d.payload, d.status, d.attempts,
+	d.next_attempt_at, d.last_attempt_at, d.last_status_code, d.last_error, d.delivered_at, d.created_at`
+
+func scanWebhookDelivery(row interface{ Scan(dest ...any) error }, extra ...any) (*models.WebhookDelivery, error) {
+	var d models.WebhookDelivery
+	var payload []byte
+	dest := []any{
+		&d.ID, &d.SubscriptionID, &d.EventID, &d.EventType, &payload, &d.Status, &d.Attempts,
+		&d.NextAttemptAt, &d.LastAttemptAt, &d.LastStatusCode, &d.LastError, &d.DeliveredAt, &d.CreatedAt,
+	}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return nil, err
+	}
+	d.Payload = payload
+	return &d, nil
+}
+
+func (r *webhookRepository) ClaimDue(ctx context.Context, now time.Time, limit int, lease time.Duration) ([]*models.WebhookDelivery, error) {
+	ctx, span := tracing.Repository(ctx, "webhookRepository.ClaimDue")
+	defer span.End()
+
+	query := `
+		WITH due AS (
+			SELECT d.id FROM webhook_deliveries d
+			JOIN webhook_subscriptions s ON s.id = d.subscription_id
+			WHERE d.status = 'pending' AND d.next_attempt_at <= $1 AND s.enabled = TRUE
+			ORDER BY d.next_attempt_at, d.id
+			LIMIT $2
+			FOR UPDATE OF d SKIP LOCKED
+		), d AS (
+			UPDATE webhook_deliveries w SET next_attempt_at = $1::timestamptz + $3::float8 * INTERVAL '1 second'
+			FROM due WHERE w.id = due.id
+			RETURNING w.*
+		)
+		SELECT ` + webhookDeliveryColumns + `, s.url, s.secret
+		FROM d JOIN webhook_subscriptions s ON s.id = d.subscription_id
+		ORDER BY d.id
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, now, limit, lease.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("claim webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries := []*models.WebhookDelivery{}
+	for rows.Next() {
+		var url, secret string
+		d, err := scanWebhookDelivery(rows, &url, &secret)
+		if err != nil {
+			return nil, fmt.Errorf("scan webhook delivery: %w", err)
+		}
+		d.URL, d.Secret = url, secret
+		deliveries = append(deliveries, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate webhook deliveries: %w", err)
+	}
+	return deliveries, nil
+}
+
+func (r *webhookRepository) MarkDelivered(ctx context.Context, id int64, statusCode int) error {
+	ctx, span := tracing.Repository(ctx, "webhookRepository.MarkDelivered")
+	defer span.End()
+
+	query := `
+		UPDATE webhook_deliveries SET
+			status = 'delivered', attempts = attempts + 1, last_attempt_at = NOW(),
+			last_status_code = $2, last_error = NULL, delivered_at = NOW()
+		WHERE id = $1
+	`
+	if _, err := r.db.ExecContext(ctx, query, id, statusCode); err != nil {
+		return fmt.Errorf("mark webhook delivered: %w", err)
+	}
+	return nil
+}
+
+func (r *webhookRepository) MarkFailed(ctx context.Context, id int64, statusCode *int, lastError string, retryAt *time.Time) error {
+	ctx, span := tracing.Repository(ctx, "webhookRepository.MarkFailed")
+	defer span.End()
+
+	query := `
+		UPDATE webhook_deliveries SET
+			status = CASE WHEN $4::timestamptz IS NULL THEN 'dead' ELSE 'pending' END,
+			attempts = attempts + 1, last_attempt_at = NOW(),
+			last_status_code = $2, last_error = $3,
+			next_attempt_at = COALESCE($4, next_attempt_at)
+		WHERE id = $1
+	`
+	if _, err := r.db.ExecContext(ctx, query, id, statusCode, lastError, retryAt); err != nil {
+		return fmt.Errorf("mark webhook failed: %w", err)
+	}
+	return nil
+}
+
+func (r *webhookRepository) GetDelivery(ctx context.Context, id int64) (*models.WebhookDelivery, error) {
+	ctx, span := tracing.Repository(ctx, "webhookRepository.GetDelivery")
+	defer span.End()
+
+	query := "SELECT " + webhookDeliveryColumns + " FROM webhook_deliveries d WHERE d.id = $1"
+
+	d, err := scanWebhookDelivery(r.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("webhook delivery not found")
+		}
+		return nil, fmt.Errorf("get webhook delivery: %w", err)
+	}
+	return d, nil
+}
+
+func buildWebhookDeliveryFilterClause(filters WebhookDeliveryFilters) (string, []any, int) {
+	var clause string
+	var args []any
+	argIndex := 1
+
+	if filters.Status != nil {
+		clause += fmt.Sprintf(" AND d.status = $%d", argIndex)
+		args = append(args, *filters.Status)
+		argIndex++
+	}
+	if filters.SubscriptionID != nil {
+		clause += fmt.Sprintf(" AND d.subscription_id = $%d", argIndex)
+		args = append(args, *filters.SubscriptionID)
+		argIndex++
+	}
+	if filters.EventType != nil {
+		clause += fmt.Sprintf(" AND d.event_type = $%d", argIndex)
+		args = append(args, *filters.EventType)
+		argIndex++
+	}
+
+	return clause, args, argIndex
+}
+
+func (r *webhookRepository) ListDeliveries(ctx context.Context, filters WebhookDeliveryFilters, limit int, offset int) ([]*models.WebhookDelivery, error) {
+	ctx, span := tracing.Repository(ctx, "webhookRepository.ListDeliveries")
+	defer span.End()
+
+	clause, args, argIndex := buildWebhookDeliveryFilterClause(filters)
+	query := "SELECT " + webhookDeliveryColumns + " FROM webhook_deliveries d WHERE 1=1" + clause
+	query += fmt.Sprintf(" ORDER BY d.created_at DESC, d.id DESC LIMIT $%d OFFSET $%d", argIndex, argIndex+1)
+	args = append(args, limit, offset)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries := []*models.WebhookDelivery{}
+	for rows.Next() {
+		d, err := scanWebhookDelivery(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan webhook delivery: %w", err)
+		}
+		deliveries = append(deliveries, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate webhook deliveries: %w", err)
+	}
+	return deliveries, nil
+}
+
+func (r *webhookRepository) CountDeliveries(ctx context.Context, filters WebhookDeliveryFilters) (int, error) {
+	ctx, span := tracing.Repository(ctx, "webhookRepository.CountDeliveries")
+	defer span.End()
+
+	clause, args, _ := buildWebhookDeliveryFilterClause(filters)
+	query := "SELECT COUNT(*) FROM webhook_deliveries d WHERE 1=1" + clause
+
+	var count int
+	if err := r.db.QueryRowContext(ctx, query, args...).Scan(&count); err != nil {
+		return 0, fmt.Errorf("count webhook deliveries: %w", err)
+	}
+	return count, nil
+}
+
+func (r *webhookRepository) Replay(ctx context.Context, id int64) (*models.WebhookDelivery, error) {
+	ctx, span := tracing.Repository(ctx, "webhookRepository.Replay")
+	defer span.End()
+
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE webhook_deliveries SET status = 'pending', attempts = 0, next_attempt_at = NOW(), delivered_at = NULL
+		WHERE id = $1 AND status <> 'pending'
+	`, id)
+	if err != nil {
+		return nil, fmt.Errorf("replay webhook delivery: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("get rows affected: %w", err)
+	}
+
+	d, err := r.GetDelivery(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if rows == 0 {
+		return nil, fmt.Errorf("webhook delivery is pending")
+	}
+	return d, nil
+}
+
+func (r *webhookRepository) ReplayDead(ctx context.Context, subscriptionID *int) (int64, error) {
+	ctx, span := tracing.Repository(ctx, "webhookRepository.ReplayDead")
+	defer span.End()
+
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE webhook_deliveries SET status = 'pending', attempts = 0, next_attempt_at = NOW()
+		WHERE status = 'dead' AND ($1::int IS NULL OR subscription_id = $1)
+	`, subscriptionID)
+	if err != nil {
+		return 0, fmt.Errorf("replay dead webhook deliveries: %w", err)
+	}
+	return result.RowsAffected()
+}
@@ -2,19 +2,19 @@ package routes
 
 import (
 	"database/sql"
-	"log"
+	"log/slog"
 
 	"github.com/go-chi/chi/v5"
 	"scm/internal/handlers"
 	"scm/internal/repository"
 )
 
-func RegisterAdvertiserRoutes(router chi.Router, db *sql.DB) {
-	log.Println("Registering advertiser routes...")
+func RegisterAdvertiserRoutes(router chi.Router, db *sql.DB, cascade *handlers.CascadeDeleter) {
+	slog.Debug("registering routes", "resource", "advertisers")
 	
 	// Initialize repository and handler
-	advertiserRepo := repository.NewAdvertiserRepository(db)
-	advertiserHandler := handlers.NewAdvertiserHandler(advertiserRepo)
+	advertiserRepo := repository.NewAuditedAdvertiserRepository(repository.NewAdvertiserRepository(db), repository.NewAuditRepository(db))
+	advertiserHandler := handlers.NewAdvertiserHandler(advertiserRepo, cascade)
 
 	// Define routes
 	router.Route("/advertisers", func(r chi.Router) {
@@ -23,7 +23,10 @@ func RegisterAdvertiserRoutes(router chi.Router, db *sql.DB) {
 		r.Route("/{id}", func(r chi.Router) {
 			r.Get("/", advertiserHandler.GetAdvertiser)
 			r.Put("/", advertiserHandler.UpdateAdvertiser)
+			r.Patch("/", advertiserHandler.PatchAdvertiser)
 			r.Delete("/", advertiserHandler.DeleteAdvertiser)
+			r.Post("/restore", advertiserHandler.RestoreAdvertiser)
+			r.Get("/dependencies", advertiserHandler.GetAdvertiserDependencies)
 		})
 	})
 }
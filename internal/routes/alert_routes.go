@@ -0,0 +1,50 @@
+package routes
+
+import (
+	"database/sql"
+
+	"github.com/go-chi/chi/v5"
+	"scm/internal/config"
+	"scm/internal/handlers"
+	authmw "scm/internal/middleware"
+	"scm/internal/repository"
+	"scm/internal/services"
+)
+
+// newAlertService wires the alert service used by the sync routes.
+func newAlertService(db *sql.DB, cfg *config.Config) *services.AlertService {
+	mailer := &services.SMTPSender{
+		Host:   cfg.SMTPHost,
+		Port:   cfg.SMTPPort,
+		User:   cfg.SMTPUser,
+		Pass:   cfg.SMTPPassword,
+		From:   cfg.SMTPFrom,
+		UseTLS: cfg.SMTPUseTLS,
+	}
+	return services.NewAlertService(
+		repository.NewAlertRepository(db),
+		repository.NewDeviceHealthRepository(db),
+		mailer,
+		cfg.DeviceHealthThresholds(),
+	)
+}
+
+// RegisterAlertRoutes registers the alert endpoints. Rules choose who is emailed and
+// which URL is called when they fire, so managing them is admin-only.
+func RegisterAlertRoutes(r chi.Router, db *sql.DB, cfg *config.Config) {
+	handler := handlers.NewAlertHandler(repository.NewAlertRepository(db))
+
+	r.Route("/alerts", func(r chi.Router) {
+		r.Get("/", handler.ListAlerts)
+		r.Route("/rules", func(r chi.Router) {
+			r.Use(authmw.NewAdmins(cfg.AdminEmails).RequireAdmin)
+			r.Get("/", handler.ListRules)
+			r.Post("/", handler.CreateRule)
+			r.Get("/{id}", handler.GetRule)
+			r.Put("/{id}", handler.UpdateRule)
+			r.Delete("/{id}", handler.DeleteRule)
+		})
+		r.Get("/{id}", handler.GetAlert)
+		r.Post("/{id}/acknowledge", handler.Acknowledge)
+	})
+}
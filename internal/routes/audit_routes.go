@@ -0,0 +1,19 @@
+package routes
+
+import (
+	"database/sql"
+
+	"github.com/go-chi/chi/v5"
+	"scm/internal/config"
+	"scm/internal/handlers"
+	authmw "scm/internal/middleware"
+	"scm/internal/repository"
+)
+
+// RegisterAuditRoutes registers the audit log, which records every user's changes and
+// is admin-only.
+func RegisterAuditRoutes(r chi.Router, db *sql.DB, cfg *config.Config) {
+	handler := handlers.NewAuditHandler(repository.NewAuditRepository(db))
+
+	r.With(authmw.NewAdmins(cfg.AdminEmails).RequireAdmin).Get("/audit", handler.List)
+}
@@ -6,28 +6,30 @@ import (
     "github.com/go-chi/chi/v5"
     "scm/internal/handlers"
     "scm/internal/repository"
-    "log"
-	"net/http"
+    "scm/internal/services"
+    "log/slog"
 )
 
-func RegisterCampaignRoutes(router chi.Router, db *sql.DB) {
-    log.Println("Registering campaign routes...")
+func RegisterCampaignRoutes(router chi.Router, db *sql.DB, cascade *handlers.CascadeDeleter, stream *services.EventStream) {
+    slog.Debug("registering routes", "resource", "campaigns")
 
-    campaignRepo := repository.NewCampaignRepository(db)
-    campaignHandler := handlers.NewCampaignHandler(campaignRepo)
+    campaignRepo := repository.NewAuditedCampaignRepository(repository.NewCampaignRepository(db), repository.NewAuditRepository(db))
+    campaignService := services.NewCampaignService(campaignRepo, cascade.Admins, cascade.Storage)
+    campaignService.SetEvents(repository.NewUnitOfWork(db), stream)
+    campaignHandler := handlers.NewCampaignHandler(campaignService)
 
     router.Route("/campaigns", func(r chi.Router) {
         r.Get("/", campaignHandler.ListCampaigns)
         r.Get("/advertiser/{advertiserID}", campaignHandler.ListCampaignsByAdvertiser)
-        r.Post("/", func(w http.ResponseWriter, r *http.Request) {
-            log.Println("POST /campaigns endpoint hit")
-            campaignHandler.CreateCampaign(w, r)
-        })
+        r.Post("/", campaignHandler.CreateCampaign)
         
         r.Route("/{id}", func(r chi.Router) {
             r.Get("/", campaignHandler.GetCampaign)
             r.Put("/", campaignHandler.UpdateCampaign)
+            r.Patch("/", campaignHandler.PatchCampaign)
             r.Delete("/", campaignHandler.DeleteCampaign)
+            r.Post("/restore", campaignHandler.RestoreCampaign)
+            r.Get("/dependencies", campaignHandler.GetCampaignDependencies)
         })
     })
 }
\ No newline at end of file
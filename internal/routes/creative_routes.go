@@ -7,21 +7,25 @@ import (
     "github.com/go-chi/chi/v5"
     "scm/internal/config"
     "scm/internal/handlers"
+    "scm/internal/interfaces"
     "scm/internal/repository"
+    "scm/internal/services"
 )
 
 func RegisterPublicCreativeRoutes(router chi.Router, db *sql.DB, s3Config *config.S3Config) {
 	creativeRepo := repository.NewCreativeRepository(db)
 	campaignRepo := repository.NewCampaignRepository(db)
-	creativeHandler := handlers.NewCreativeHandler(creativeRepo, campaignRepo, s3Config)
+	creativeHandler := handlers.NewCreativeHandler(newCreativeService(creativeRepo, campaignRepo, s3Config))
 
 	router.Get("/creatives/device/{device}", creativeHandler.ListCreativesByDevice)
 }
 
-func RegisterCreativeRoutes(router chi.Router, db *sql.DB, s3Config *config.S3Config) {
-    creativeRepo := repository.NewCreativeRepository(db)
+func RegisterCreativeRoutes(router chi.Router, db *sql.DB, s3Config *config.S3Config, stream *services.EventStream) {
+    creativeRepo := repository.NewAuditedCreativeRepository(repository.NewCreativeRepository(db), repository.NewAuditRepository(db))
     campaignRepo := repository.NewCampaignRepository(db)
-    creativeHandler := handlers.NewCreativeHandler(creativeRepo, campaignRepo, s3Config)
+    creativeService := newCreativeService(creativeRepo, campaignRepo, s3Config)
+    creativeService.SetEvents(repository.NewUnitOfWork(db), stream)
+    creativeHandler := handlers.NewCreativeHandler(creativeService)
 
     router.Route("/creatives", func(r chi.Router) {
         r.Get("/", creativeHandler.ListCreatives)
@@ -30,7 +34,17 @@ func RegisterCreativeRoutes(router chi.Router, db *sql.DB, s3Config *config.S3Co
         r.Route("/{id}", func(r chi.Router) {
             r.Get("/", creativeHandler.GetCreative)
             r.Put("/", creativeHandler.UpdateCreative)
+            r.Patch("/", creativeHandler.PatchCreative)
             r.Delete("/", creativeHandler.DeleteCreative)
+            r.Post("/restore", creativeHandler.RestoreCreative)
         })
     })
-}
\ No newline at end of file
+}
+
+func newCreativeService(creativeRepo repository.CreativeRepository, campaignRepo interfaces.CampaignRepository, s3Config *config.S3Config) *services.CreativeService {
+	var storage services.ObjectUploader
+	if s3Config.Client != nil {
+		storage = &services.S3ObjectStorage{Client: s3Config.Client, Bucket: s3Config.Bucket}
+	}
+	return services.NewCreativeService(creativeRepo, campaignRepo, storage, s3Config.PublicBaseURL)
+}
@@ -0,0 +1,16 @@
+package routes
+
+import (
+	"scm/internal/config"
+	"scm/internal/handlers"
+	authmw "scm/internal/middleware"
+	"scm/internal/services"
+)
+
+func newCascadeDeleter(cfg *config.Config, s3Config *config.S3Config) *handlers.CascadeDeleter {
+	cascade := &handlers.CascadeDeleter{Admins: authmw.NewAdmins(cfg.AdminEmails)}
+	if s3Config != nil && s3Config.Client != nil {
+		cascade.Storage = &services.S3ObjectStorage{Client: s3Config.Client, Bucket: s3Config.Bucket}
+	}
+	return cascade
+}
@@ -3,19 +3,39 @@ package routes
 import (
 	"database/sql"
 
+	"scm/internal/config"
 	"scm/internal/handlers"
 	"scm/internal/repository"
+	"scm/internal/services"
 
 	"github.com/go-chi/chi/v5"
 )
 
-func RegisterDeviceReadRoutes(r chi.Router, db *sql.DB) {
+func RegisterDeviceReadRoutes(r chi.Router, db *sql.DB, cfg *config.Config) {
 	repo := repository.NewDeviceRepository(db)
-	handler := handlers.NewDeviceReadHandler(repo)
+	handler := handlers.NewDeviceReadHandler(repo, cfg.DeviceHealthThresholds())
+	healthHandler := handlers.NewDeviceHealthHandler(repository.NewDeviceHealthRepository(db), cfg.DeviceHealthThresholds(), cfg.DeviceHeartbeatToken, nil)
+	annotationHandler := handlers.NewAnnotationHandler(repository.NewAnnotationRepository(db))
+	changeHandler := handlers.NewSyncChangeHandler(repository.NewSyncRepository(db), repo)
 
+	r.Get("/devices.geojson", handler.GeoJSON)
 	r.Route("/devices", func(r chi.Router) {
 		r.Get("/counts/regions", handler.CountByRegion)
+		r.Get("/health/summary", healthHandler.Summary)
 		r.Get("/", handler.List)
 		r.Get("/{hostName}", handler.Get)
+		r.Get("/{hostName}/health", healthHandler.Get)
+		r.Get("/{hostName}/annotations", annotationHandler.GetDevice)
+		r.Patch("/{hostName}/annotations", annotationHandler.PatchDevice)
+		r.Get("/{hostName}/history", changeHandler.DeviceHistory)
 	})
 }
+
+// RegisterDeviceHeartbeatRoutes registers the heartbeat endpoint called by the screens
+// themselves; it is authenticated by DEVICE_HEARTBEAT_TOKEN rather than a user JWT, and
+// answers 503 until that token is set.
+func RegisterDeviceHeartbeatRoutes(r chi.Router, db *sql.DB, cfg *config.Config, events services.EventPublisher) {
+	handler := handlers.NewDeviceHealthHandler(repository.NewDeviceHealthRepository(db), cfg.DeviceHealthThresholds(), cfg.DeviceHeartbeatToken, events)
+
+	r.Post("/devices/{hostName}/heartbeat", handler.Heartbeat)
+}
@@ -0,0 +1,15 @@
+package routes
+
+import (
+	"github.com/go-chi/chi/v5"
+	"scm/internal/config"
+	"scm/internal/handlers"
+	authmw "scm/internal/middleware"
+	"scm/internal/services"
+)
+
+func RegisterEventRoutes(r chi.Router, cfg *config.Config, stream *services.EventStream) {
+	handler := handlers.NewEventStreamHandler(stream, authmw.NewAdmins(cfg.AdminEmails))
+
+	r.Get("/events/stream", handler.Stream)
+}
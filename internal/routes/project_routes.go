@@ -12,9 +12,12 @@ import (
 func RegisterProjectRoutes(r chi.Router, db *sql.DB) {
 	repo := repository.NewProjectRepository(db)
 	handler := handlers.NewProjectHandler(repo)
+	annotationHandler := handlers.NewAnnotationHandler(repository.NewAnnotationRepository(db))
 
 	r.Route("/projects", func(r chi.Router) {
 		r.Get("/", handler.List)
 		r.Get("/{name}", handler.Get)
+		r.Get("/{name}/annotations", annotationHandler.GetProject)
+		r.Patch("/{name}/annotations", annotationHandler.PatchProject)
 	})
 }
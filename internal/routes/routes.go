@@ -14,12 +14,19 @@ import (
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/cors"
+	"scm/internal/audit"
 	"scm/internal/config"
+	"scm/internal/logging"
+	"scm/internal/metrics"
 	authmw "scm/internal/middleware"
+	"scm/internal/repository"
 	"scm/internal/services"
+	"scm/internal/tracing"
 )
 
-func SetupRoutes(db *sql.DB, cfg *config.Config, s3Config *config.S3Config) *chi.Mux {
+// SetupRoutes builds the API router. stream is the dashboard event stream the API
+// publishes to; the background jobs publish to the same one.
+func SetupRoutes(db *sql.DB, cfg *config.Config, s3Config *config.S3Config, stream *services.EventStream) *chi.Mux {
 	r := chi.NewRouter()
 	
 	// Middleware
@@ -41,16 +48,18 @@ func SetupRoutes(db *sql.DB, cfg *config.Config, s3Config *config.S3Config) *chi
 	r.Use(cors.Handler(cors.Options{
 		AllowedOrigins: allowedOrigins,
 		AllowedMethods: []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
-		AllowedHeaders: []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
-		ExposedHeaders: []string{"Link"},
+		AllowedHeaders: []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token", "If-Match"},
+		ExposedHeaders: []string{"Link", "ETag"},
 		AllowCredentials: false,
 		MaxAge: 300,
 	}))
 
 	r.Use(middleware.RequestID)
+	r.Use(tracing.Middleware)
 	r.Use(middleware.RealIP)
-	r.Use(middleware.Logger)
+	r.Use(logging.Middleware)
 	r.Use(middleware.Recoverer)
+	r.Use(metrics.Middleware)
 
 	r.Get("/", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
@@ -59,6 +68,7 @@ func SetupRoutes(db *sql.DB, cfg *config.Config, s3Config *config.S3Config) *chi
 	})
 
 	RegisterSwaggerRoutes(r)
+	r.Handle("/metrics", metrics.Handler(cfg.MetricsToken))
 
 	// Health check
     r.Get("/health", func(w http.ResponseWriter, r *http.Request) {
@@ -94,10 +104,18 @@ func SetupRoutes(db *sql.DB, cfg *config.Config, s3Config *config.S3Config) *chi
     
     // API v1 routes
 	r.Route("/api/v1", func(r chi.Router) {
+		auditRepo := repository.NewAuditRepository(db)
+		events := services.Publishers{newWebhookService(db, cfg), stream}
+
 		// Public auth routes
-		RegisterAuthRoutes(r, db, cfg)
-		RegisterUserRoutes(r, db)
-		RegisterPublicCreativeRoutes(r, db, s3Config)
+		r.Group(func(r chi.Router) {
+			r.Use(audit.Middleware(auditRepo))
+			RegisterAuthRoutes(r, db, cfg)
+			RegisterUserRoutes(r, db)
+			RegisterPublicCreativeRoutes(r, db, s3Config)
+		})
+		// Heartbeats are high-volume telemetry and are not audited.
+		RegisterDeviceHeartbeatRoutes(r, db, cfg, events)
 
         r.Get("/debug/env", func(w http.ResponseWriter, r *http.Request) {
             sanitizeDatabaseURL := func(raw string) string {
@@ -151,12 +169,14 @@ func SetupRoutes(db *sql.DB, cfg *config.Config, s3Config *config.S3Config) *chi
         		// Protected routes
 		r.Group(func(r chi.Router) {
 			r.Use(authmw.JWTAuth(cfg.JWTSecret))
+			r.Use(audit.Middleware(auditRepo))
+			cascade := newCascadeDeleter(cfg, s3Config)
 
             // Register campaign routes
-            RegisterCampaignRoutes(r, db)  // Correct order: router first, then db
+            RegisterCampaignRoutes(r, db, cascade, stream)  // Correct order: router first, then db
             // Register advertiser routes
-            RegisterAdvertiserRoutes(r, db)
-            RegisterCreativeRoutes(r, db, s3Config)
+            RegisterAdvertiserRoutes(r, db, cascade)
+            RegisterCreativeRoutes(r, db, s3Config, stream)
 			// Initialize CityPost console client
 			client := services.NewCityPostConsoleClient(
 				cfg.CityPostConsoleBaseURL,
@@ -164,10 +184,15 @@ func SetupRoutes(db *sql.DB, cfg *config.Config, s3Config *config.S3Config) *chi
 				cfg.CityPostConsolePassword,
 			)
 			client.SetAuthScheme(cfg.CityPostConsoleAuthScheme)
-			RegisterSyncRoutes(r, db, client)
+			RegisterSyncRoutes(r, db, cfg, client, newAlertService(db, cfg), events)
 			RegisterProjectRoutes(r, db)
-			RegisterDeviceReadRoutes(r, db)
-			RegisterVenueRoutes(r, db)
+			RegisterDeviceReadRoutes(r, db, cfg)
+			RegisterVenueRoutes(r, db, cascade)
+			RegisterAlertRoutes(r, db, cfg)
+			RegisterAuditRoutes(r, db, cfg)
+			RegisterSearchRoutes(r, db, cfg)
+			RegisterWebhookRoutes(r, db, cfg)
+			RegisterEventRoutes(r, cfg, stream)
 
         })
     })
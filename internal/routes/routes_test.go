@@ -6,9 +6,12 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/golang-jwt/jwt/v5"
 	"scm/internal/config"
+	"scm/internal/services"
 )
 
 type healthResp struct {
@@ -26,7 +29,7 @@ func TestRootReturnsJSON(t *testing.T) {
 	}
 	defer db.Close()
 
-	r := SetupRoutes(db, &config.Config{JWTSecret: "dev"}, &config.S3Config{})
+	r := SetupRoutes(db, &config.Config{JWTSecret: "dev"}, &config.S3Config{}, services.NewEventStream(16))
 
 	req := httptest.NewRequest(http.MethodGet, "/", nil)
 	w := httptest.NewRecorder()
@@ -57,7 +60,7 @@ func TestHealthDBOK(t *testing.T) {
 
 	mock.ExpectPing()
 
-	r := SetupRoutes(db, &config.Config{JWTSecret: "dev"}, &config.S3Config{})
+	r := SetupRoutes(db, &config.Config{JWTSecret: "dev"}, &config.S3Config{}, services.NewEventStream(16))
 	req := httptest.NewRequest(http.MethodGet, "/health", nil)
 	w := httptest.NewRecorder()
 	r.ServeHTTP(w, req)
@@ -87,7 +90,7 @@ func TestHealthDBDown(t *testing.T) {
 
 	mock.ExpectPing().WillReturnError(sql.ErrConnDone)
 
-	r := SetupRoutes(db, &config.Config{JWTSecret: "dev"}, &config.S3Config{})
+	r := SetupRoutes(db, &config.Config{JWTSecret: "dev"}, &config.S3Config{}, services.NewEventStream(16))
 	req := httptest.NewRequest(http.MethodGet, "/health", nil)
 	w := httptest.NewRecorder()
 	r.ServeHTTP(w, req)
@@ -107,3 +110,50 @@ func TestHealthDBDown(t *testing.T) {
 		t.Fatalf("expectations: %v", err)
 	}
 }
+
+func bearerToken(t *testing.T, secret, email string) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sub":   "user-1",
+		"email": email,
+		"exp":   time.Now().Add(time.Hour).Unix(),
+	}).SignedString([]byte(secret))
+	if err != nil {
+		t.Fatalf("sign token: %v", err)
+	}
+	return "Bearer " + token
+}
+
+func TestAdminOnlyRoutesRejectOtherUsers(t *testing.T) {
+	db, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	cfg := &config.Config{JWTSecret: "dev", AdminEmails: []string{"admin@example.com"}}
+	r := SetupRoutes(db, cfg, &config.S3Config{}, services.NewEventStream(16))
+
+	for _, path := range []string{
+		"/api/v1/webhooks/subscriptions",
+		"/api/v1/webhooks/deliveries",
+		"/api/v1/audit",
+		"/api/v1/alerts/rules",
+	} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Authorization", bearerToken(t, "dev", "user@example.com"))
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusForbidden {
+			t.Errorf("GET %s as non-admin: expected 403, got %d", path, w.Code)
+		}
+
+		req = httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Authorization", bearerToken(t, "dev", "Admin@example.com"))
+		w = httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code == http.StatusForbidden || w.Code == http.StatusUnauthorized {
+			t.Errorf("GET %s as admin: expected access, got %d", path, w.Code)
+		}
+	}
+}
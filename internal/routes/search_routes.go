@@ -0,0 +1,17 @@
+package routes
+
+import (
+	"database/sql"
+
+	"github.com/go-chi/chi/v5"
+	"scm/internal/config"
+	"scm/internal/handlers"
+	authmw "scm/internal/middleware"
+	"scm/internal/repository"
+)
+
+func RegisterSearchRoutes(r chi.Router, db *sql.DB, cfg *config.Config) {
+	handler := handlers.NewSearchHandler(repository.NewSearchRepository(db), authmw.NewAdmins(cfg.AdminEmails))
+
+	r.Get("/search", handler.Search)
+}
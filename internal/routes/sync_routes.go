@@ -3,6 +3,7 @@ package routes
 import (
 	"database/sql"
 
+	"scm/internal/config"
 	"scm/internal/handlers"
 	"scm/internal/repository"
 	"scm/internal/services"
@@ -10,10 +11,14 @@ import (
 	"github.com/go-chi/chi/v5"
 )
 
-func RegisterSyncRoutes(r chi.Router, db *sql.DB, client *services.CityPostConsoleClient) {
+func RegisterSyncRoutes(r chi.Router, db *sql.DB, cfg *config.Config, client *services.CityPostConsoleClient, alerts *services.AlertService, events services.EventPublisher) {
 	projectRepo := repository.NewProjectRepository(db)
 	deviceRepo := repository.NewDeviceRepository(db)
-	syncHandler := handlers.NewSyncHandler(projectRepo, deviceRepo, client)
+	venueRepo := repository.NewVenueRepository(db)
+	syncRepo := repository.NewSyncRepository(db)
+	syncHandler := handlers.NewSyncHandler(projectRepo, deviceRepo, venueRepo, syncRepo, repository.NewUnitOfWork(db), client, alerts, events, cfg.SyncConcurrency)
+	changeHandler := handlers.NewSyncChangeHandler(syncRepo, deviceRepo)
 
 	r.Post("/sync/console", syncHandler.SyncConsole)
+	r.Get("/sync/runs/{id}/changes", changeHandler.RunChanges)
 }
@@ -8,21 +8,25 @@ import (
 	"scm/internal/repository"
 )
 
-func RegisterVenueRoutes(r chi.Router, db *sql.DB) {
-	repo := repository.NewVenueRepository(db)
-	handler := handlers.NewVenueHandler(repo)
+func RegisterVenueRoutes(r chi.Router, db *sql.DB, cascade *handlers.CascadeDeleter) {
+	repo := repository.NewAuditedVenueRepository(repository.NewVenueRepository(db), repository.NewAuditRepository(db))
+	handler := handlers.NewVenueHandler(repo, repository.NewUnitOfWork(db), cascade)
 
+	r.Get("/venues.geojson", handler.GeoJSON)
 	r.Route("/venues", func(r chi.Router) {
 		r.Get("/", handler.List)
 		r.Post("/", handler.Create)
 		r.Get("/{id}", handler.Get)
 		r.Put("/{id}", handler.Update)
+		r.Patch("/{id}", handler.Patch)
 		r.Delete("/{id}", handler.Delete)
+		r.Get("/{id}/dependencies", handler.Dependencies)
 		
 		// Bulk operations for many-to-many relationships
 		r.Post("/{id}/devices", handler.AddDevicesToVenue)
 		r.Delete("/{id}/devices", handler.RemoveDevicesFromVenue)
 		r.Get("/{id}/devices", handler.GetDevicesByVenue)
+		r.Post("/{id}/devices/assign", handler.AssignDevices)
 	})
 
 	// Route for listing venues by device
@@ -0,0 +1,41 @@
+package routes
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"scm/internal/config"
+	"scm/internal/handlers"
+	authmw "scm/internal/middleware"
+	"scm/internal/repository"
+	"scm/internal/services"
+)
+
+func newWebhookService(db *sql.DB, cfg *config.Config) *services.WebhookService {
+	return services.NewWebhookService(
+		repository.NewWebhookRepository(db),
+		cfg.WebhookMaxAttempts,
+		time.Duration(cfg.WebhookBackoffSeconds)*time.Second,
+	)
+}
+
+// RegisterWebhookRoutes registers the webhook endpoints. Subscribers receive every
+// advertiser's events and deliveries hold their payloads, so they are admin-only.
+func RegisterWebhookRoutes(r chi.Router, db *sql.DB, cfg *config.Config) {
+	handler := handlers.NewWebhookHandler(repository.NewWebhookRepository(db))
+
+	r.Route("/webhooks", func(r chi.Router) {
+		r.Use(authmw.NewAdmins(cfg.AdminEmails).RequireAdmin)
+		r.Get("/event-types", handler.ListEventTypes)
+		r.Get("/subscriptions", handler.ListSubscriptions)
+		r.Post("/subscriptions", handler.CreateSubscription)
+		r.Get("/subscriptions/{id}", handler.GetSubscription)
+		r.Put("/subscriptions/{id}", handler.UpdateSubscription)
+		r.Delete("/subscriptions/{id}", handler.DeleteSubscription)
+		r.Get("/deliveries", handler.ListDeliveries)
+		r.Post("/deliveries/replay", handler.ReplayDead)
+		r.Get("/deliveries/{id}", handler.GetDelivery)
+		r.Post("/deliveries/{id}/replay", handler.ReplayDelivery)
+	})
+}
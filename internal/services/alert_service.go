@@ -0,0 +1,252 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"scm/internal/models"
+	"scm/internal/repository"
+)
+
+// AlertService evaluates alert rules, deduplicates alerts and delivers notifications
+// by email and signed webhook.
+type AlertService struct {
+	repo       repository.AlertRepository
+	health     repository.DeviceHealthRepository
+	mailer     EmailSender
+	httpClient *http.Client
+	thresholds models.HealthThresholds
+}
+
+func NewAlertService(repo repository.AlertRepository, health repository.DeviceHealthRepository, mailer EmailSender, thresholds models.HealthThresholds) *AlertService {
+	return &AlertService{
+		repo:       repo,
+		health:     health,
+		mailer:     mailer,
+		httpClient: newWebhookHTTPClient(),
+		thresholds: thresholds,
+	}
+}
+
+// SetHTTPClient replaces the client rule webhooks are sent with, which by default only
+// connects to public addresses.
+func (s *AlertService) SetHTTPClient(client *http.Client) {
+	if client != nil {
+		s.httpClient = client
+	}
+}
+
+// EvaluateVenueRules checks every enabled venue_offline rule, opening alerts for venues
+// over the threshold and resolving alerts for venues that have recovered.
+func (s *AlertService) EvaluateVenueRules(ctx context.Context) error {
+	rules, err := s.repo.ListEnabledRules(ctx, models.AlertRuleVenueOffline)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, rule := range rules {
+		offlineFor := s.thresholds.OfflineAfter + time.Duration(rule.OfflineMinutes)*time.Minute
+		counts, err := s.health.OfflineCountsByVenue(ctx, rule.VenueID, offlineFor)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("rule %d: %w", rule.ID, err))
+			continue
+		}
+
+		activeKeys := []string{}
+		for _, c := range counts {
+			if c.Offline <= rule.OfflineDevices {
+				continue
+			}
+			venueID := c.VenueID
+			details, _ := json.Marshal(c)
+			alert := &models.Alert{
+				RuleID:   rule.ID,
+				RuleType: rule.Type,
+				DedupKey: fmt.Sprintf("%s:%d:%d", models.AlertRuleVenueOffline, rule.ID, c.VenueID),
+				Title:    fmt.Sprintf("%d of %d devices offline at venue %s", c.Offline, c.Total, c.VenueName),
+				Details:  details,
+				VenueID:  &venueID,
+			}
+			activeKeys = append(activeKeys, alert.DedupKey)
+			if err := s.trigger(ctx, rule, alert); err != nil {
+				errs = append(errs, fmt.Errorf("rule %d venue %d: %w", rule.ID, c.VenueID, err))
+			}
+		}
+
+		if _, err := s.repo.ResolveInactive(ctx, rule.ID, activeKeys); err != nil {
+			errs = append(errs, fmt.Errorf("rule %d: %w", rule.ID, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// RecordSyncResult raises sync_errors alerts when a console sync reported errors and
+// resolves them once a sync completes cleanly.
+func (s *AlertService) RecordSyncResult(ctx context.Context, syncErrors []string) error {
+	rules, err := s.repo.ListEnabledRules(ctx, models.AlertRuleSyncErrors)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, rule := range rules {
+		if len(syncErrors) == 0 {
+			if _, err := s.repo.ResolveInactive(ctx, rule.ID, nil); err != nil {
+				errs = append(errs, fmt.Errorf("rule %d: %w", rule.ID, err))
+			}
+			continue
+		}
+
+		details, _ := json.Marshal(map[string]any{"errors": syncErrors})
+		alert := &models.Alert{
+			RuleID:   rule.ID,
+			RuleType: rule.Type,
+			DedupKey: fmt.Sprintf("%s:%d", models.AlertRuleSyncErrors, rule.ID),
+			Title:    fmt.Sprintf("CityPost console sync reported %d error(s)", len(syncErrors)),
+			Details:  details,
+		}
+		if err := s.trigger(ctx, rule, alert); err != nil {
+			errs = append(errs, fmt.Errorf("rule %d: %w", rule.ID, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// trigger records the alert and notifies only when it was newly opened, so a condition
+// that persists across evaluations is delivered once until it resolves. A failed
+// notification is left for RetryNotifications.
+func (s *AlertService) trigger(ctx context.Context, rule *models.AlertRule, alert *models.Alert) error {
+	created, err := s.repo.Trigger(ctx, alert)
+	if err != nil {
+		return err
+	}
+	if !created {
+		return nil
+	}
+	return s.deliver(ctx, rule, alert)
+}
+
+// deliver notifies about alert and records it as notified, only if that succeeded.
+func (s *AlertService) deliver(ctx context.Context, rule *models.AlertRule, alert *models.Alert) error {
+	if err := s.notify(ctx, rule, alert); err != nil {
+		return err
+	}
+	return s.repo.MarkNotified(ctx, alert.ID)
+}
+
+// alertRetryBatch bounds how many unnotified alerts one RetryNotifications call sends.
+const alertRetryBatch = 100
+
+// RetryNotifications notifies the open alerts whose notification failed when they were
+// opened. Alerts of disabled rules are skipped until the rule is enabled again.
+func (s *AlertService) RetryNotifications(ctx context.Context) error {
+	alerts, err := s.repo.ListUnnotified(ctx, alertRetryBatch)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, alert := range alerts {
+		rule, err := s.repo.GetRule(ctx, alert.RuleID)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("alert %d: %w", alert.ID, err))
+			continue
+		}
+		if !rule.Enabled {
+			continue
+		}
+		if err := s.deliver(ctx, rule, alert); err != nil {
+			errs = append(errs, fmt.Errorf("alert %d: %w", alert.ID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+type alertWebhookPayload struct {
+	Event string           `json:"event"`
+	Rule  alertWebhookRule `json:"rule"`
+	Alert *models.Alert    `json:"alert"`
+}
+
+type alertWebhookRule struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+func (s *AlertService) notify(ctx context.Context, rule *models.AlertRule, alert *models.Alert) error {
+	var errs []error
+
+	if s.mailer != nil && len(rule.EmailRecipients) > 0 {
+		subject := "[SCM Ads alert] " + alert.Title
+		body := fmt.Sprintf("Rule: %s (%s)\nAlert: %s\nTriggered at: %s\n\nDetails:\n%s\n",
+			rule.Name, rule.Type, alert.Title, alert.FirstTriggeredAt.UTC().Format(time.RFC3339), string(alert.Details))
+		for _, to := range rule.EmailRecipients {
+			if err := s.mailer.Send(ctx, to, subject, body); err != nil {
+				errs = append(errs, fmt.Errorf("email %s: %w", to, err))
+			}
+		}
+	}
+
+	if rule.WebhookURL != nil && *rule.WebhookURL != "" {
+		payload, err := json.Marshal(alertWebhookPayload{
+			Event: "alert.triggered",
+			Rule:  alertWebhookRule{ID: rule.ID, Name: rule.Name, Type: rule.Type},
+			Alert: alert,
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("marshal webhook payload: %w", err))
+		} else if err := s.postWebhook(ctx, *rule.WebhookURL, rule.WebhookSecret, payload); err != nil {
+			errs = append(errs, fmt.Errorf("webhook: %w", err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (s *AlertService) postWebhook(ctx context.Context, url string, secret *string, payload []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-SCM-Event", "alert.triggered")
+	if secret != nil && *secret != "" {
+		req.Header.Set("X-SCM-Signature", SignWebhookPayload(*secret, time.Now(), payload))
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// SignWebhookPayload returns the X-SCM-Signature header value "t=<unix>,v1=<hex>", where
+// v1 is the HMAC-SHA256 of "<unix>.<payload>" keyed with secret. Receivers should recompute
+// it and reject stale timestamps.
+func SignWebhookPayload(secret string, at time.Time, payload []byte) string {
+	ts := strconv.FormatInt(at.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(ts))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return strings.Join([]string{"t=" + ts, "v1=" + hex.EncodeToString(mac.Sum(nil))}, ",")
+}
@@ -0,0 +1,107 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"scm/internal/models"
+	"scm/internal/repository"
+)
+
+type memAlertRepo struct {
+	repository.AlertRepository
+	rules  map[int]*models.AlertRule
+	alerts []*models.Alert
+}
+
+func (m *memAlertRepo) GetRule(ctx context.Context, id int) (*models.AlertRule, error) {
+	rule, ok := m.rules[id]
+	if !ok {
+		return nil, errors.New("alert rule not found")
+	}
+	return rule, nil
+}
+
+func (m *memAlertRepo) Trigger(ctx context.Context, alert *models.Alert) (bool, error) {
+	alert.ID = len(m.alerts) + 1
+	alert.Status = models.AlertStatusOpen
+	alert.FirstTriggeredAt = time.Now().UTC()
+	m.alerts = append(m.alerts, alert)
+	return true, nil
+}
+
+func (m *memAlertRepo) MarkNotified(ctx context.Context, id int) error {
+	now := time.Now().UTC()
+	m.alerts[id-1].NotifiedAt = &now
+	return nil
+}
+
+func (m *memAlertRepo) ListUnnotified(ctx context.Context, limit int) ([]*models.Alert, error) {
+	var alerts []*models.Alert
+	for _, a := range m.alerts {
+		if a.Status == models.AlertStatusOpen && a.NotifiedAt == nil && len(alerts) < limit {
+			alerts = append(alerts, a)
+		}
+	}
+	return alerts, nil
+}
+
+type flakyMailer struct {
+	err  error
+	sent int
+}
+
+func (m *flakyMailer) Send(ctx context.Context, to string, subject string, body string) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.sent++
+	return nil
+}
+
+func TestFailedAlertNotificationIsRetried(t *testing.T) {
+	rule := &models.AlertRule{ID: 1, Name: "offline", Enabled: true, EmailRecipients: []string{"ops@example.com"}}
+	repo := &memAlertRepo{rules: map[int]*models.AlertRule{1: rule}}
+	mailer := &flakyMailer{err: errors.New("smtp down")}
+	s := NewAlertService(repo, nil, mailer, models.HealthThresholds{})
+	ctx := context.Background()
+
+	if err := s.trigger(ctx, rule, &models.Alert{RuleID: 1, DedupKey: "venue:1", Title: "Venue offline"}); err == nil {
+		t.Fatal("expected the notification error")
+	}
+	if repo.alerts[0].NotifiedAt != nil {
+		t.Fatal("alert marked notified although the email failed")
+	}
+
+	mailer.err = nil
+	if err := s.RetryNotifications(ctx); err != nil {
+		t.Fatalf("RetryNotifications: %v", err)
+	}
+	if mailer.sent != 1 || repo.alerts[0].NotifiedAt == nil {
+		t.Fatalf("sent %d, notified_at %v; want the alert delivered and marked", mailer.sent, repo.alerts[0].NotifiedAt)
+	}
+
+	// Delivered alerts are not sent again.
+	if err := s.RetryNotifications(ctx); err != nil || mailer.sent != 1 {
+		t.Fatalf("second retry: err %v, sent %d", err, mailer.sent)
+	}
+}
+
+func TestRetryNotificationsSkipsDisabledRules(t *testing.T) {
+	rule := &models.AlertRule{ID: 1, Enabled: false, EmailRecipients: []string{"ops@example.com"}}
+	repo := &memAlertRepo{
+		rules:  map[int]*models.AlertRule{1: rule},
+		alerts: []*models.Alert{{ID: 1, RuleID: 1, Status: models.AlertStatusOpen}},
+	}
+	mailer := &flakyMailer{}
+	s := NewAlertService(repo, nil, mailer, models.HealthThresholds{})
+
+	if err := s.RetryNotifications(context.Background()); err != nil {
+		t.Fatalf("RetryNotifications: %v", err)
+	}
+	if mailer.sent != 0 || repo.alerts[0].NotifiedAt != nil {
+		t.Fatalf("disabled rule notified: sent %d", mailer.sent)
+	}
+}
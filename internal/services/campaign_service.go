@@ -0,0 +1,262 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"scm/internal/interfaces"
+	"scm/internal/models"
+	"scm/internal/repository"
+)
+
+// campaignTransitions lists the statuses a campaign may move to from each status.
+// Keeping the current status is always allowed, and completed campaigns are final.
+var campaignTransitions = map[models.CampaignStatus][]models.CampaignStatus{
+	models.CampaignStatusDraft:     {models.CampaignStatusScheduled, models.CampaignStatusActive},
+	models.CampaignStatusScheduled: {models.CampaignStatusDraft, models.CampaignStatusActive, models.CampaignStatusPaused},
+	models.CampaignStatusActive:    {models.CampaignStatusPaused, models.CampaignStatusCompleted},
+	models.CampaignStatusPaused:    {models.CampaignStatusScheduled, models.CampaignStatusActive, models.CampaignStatusCompleted},
+}
+
+// CampaignTransitionAllowed reports whether a campaign may move from one status to another.
+func CampaignTransitionAllowed(from, to models.CampaignStatus) bool {
+	if from == to {
+		return true
+	}
+	for _, next := range campaignTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// CampaignList is one page of campaigns, the number of campaigns matching the filter
+// and the summary of all of them.
+type CampaignList struct {
+	Campaigns []*models.Campaign
+	Total     int
+	Summary   *models.CampaignSummary
+}
+
+// CascadeResult is what a cascade delete removed. StorageErr is set when the stored
+// objects of the removed records could not be deleted; the database changes are
+// committed regardless.
+type CascadeResult struct {
+	Deleted    *models.DeletionDependencies
+	StorageErr error
+}
+
+// CampaignService holds the rules for creating, editing and deleting campaigns.
+type CampaignService struct {
+	repo      interfaces.CampaignRepository
+	admins    AdminChecker
+	storage   ObjectStorage
+	uow       repository.UnitOfWork
+	stream    *EventStream
+	validator *validator.Validate
+}
+
+// NewCampaignService returns a service over repo. admins decides who may cascade
+// delete and storage holds the files of the creatives a cascade removes; either may be
+// nil, which disables cascade deletes and storage cleanup respectively.
+func NewCampaignService(repo interfaces.CampaignRepository, admins AdminChecker, storage ObjectStorage) *CampaignService {
+	return &CampaignService{
+		repo:      repo,
+		admins:    admins,
+		storage:   storage,
+		validator: validator.New(),
+	}
+}
+
+// SetEvents makes the service raise campaign.scheduled, campaign.activated,
+// campaign.paused and campaign.completed when a campaign moves to those statuses;
+// moving back to draft raises nothing. Such changes are saved through uow, in the
+// transaction that queues the webhook event, and the event is pushed to stream, which
+// may be nil, once it has committed.
+func (s *CampaignService) SetEvents(uow repository.UnitOfWork, stream *EventStream) {
+	s.uow = uow
+	s.stream = stream
+}
+
+// Create stores a new draft campaign.
+func (s *CampaignService) Create(ctx context.Context, req models.CreateCampaignRequest) (*models.Campaign, error) {
+	if err := s.validator.Struct(req); err != nil {
+		return nil, invalid("%s", err.Error())
+	}
+
+	now := time.Now().UTC()
+	campaign := &models.Campaign{
+		Name:         req.Name,
+		Status:       models.CampaignStatusDraft,
+		Cities:       req.Cities,
+		StartDate:    req.StartDate,
+		EndDate:      req.EndDate,
+		Budget:       req.Budget,
+		AdvertiserID: req.AdvertiserID,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+	if err := s.repo.Create(ctx, campaign); err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23503" {
+			if pqErr.Constraint == "campaigns_advertiser_id_fkey" {
+				return nil, &ValidationError{Code: "invalid_advertiser_id", Message: "Advertiser not found"}
+			}
+			return nil, &ValidationError{Code: "foreign_key_violation", Message: "Invalid reference"}
+		}
+		return nil, err
+	}
+	return campaign, nil
+}
+
+func (s *CampaignService) Get(ctx context.Context, id string) (*models.Campaign, error) {
+	campaign, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, notFound(err, ErrCampaignNotFound)
+	}
+	return campaign, nil
+}
+
+// List returns the page of campaigns filter selects.
+func (s *CampaignService) List(ctx context.Context, filter interfaces.CampaignFilter) (*CampaignList, error) {
+	if filter.AdvertiserID != "" {
+		if _, err := uuid.Parse(filter.AdvertiserID); err != nil {
+			return nil, invalid("advertiserID must be a valid UUID")
+		}
+	}
+
+	total, err := s.repo.Count(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	summary, err := s.repo.Summary(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	campaigns, err := s.repo.List(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	if campaigns == nil {
+		campaigns = []*models.Campaign{}
+	}
+	return &CampaignList{Campaigns: campaigns, Total: total, Summary: summary}, nil
+}
+
+// Replace overwrites the campaign's editable fields if it is still at version (0 skips
+// the check), returning interfaces.ErrVersionConflict if it has changed since.
+func (s *CampaignService) Replace(ctx context.Context, id string, req models.ReplaceCampaignRequest, version int) (*models.Campaign, error) {
+	if err := s.validator.Struct(req); err != nil {
+		return nil, invalid("%s", err.Error())
+	}
+
+	campaign, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if to := models.CampaignStatus(req.Status); !CampaignTransitionAllowed(campaign.Status, to) {
+		return nil, &StatusTransitionError{From: string(campaign.Status), To: req.Status}
+	}
+
+	eventType := campaignStatusEvent(campaign.Status, models.CampaignStatus(req.Status))
+	req.ApplyTo(campaign)
+	if s.uow == nil || eventType == "" {
+		if err := s.repo.Update(ctx, id, campaign, version); err != nil {
+			return nil, notFound(err, ErrCampaignNotFound)
+		}
+		return s.Get(ctx, id)
+	}
+
+	var updated *models.Campaign
+	var owner string
+	err = s.uow.Do(ctx, nil, func(ctx context.Context, repos repository.Repositories) error {
+		if err := repos.Campaigns.Update(ctx, id, campaign, version); err != nil {
+			return err
+		}
+		var err error
+		if updated, err = repos.Campaigns.GetByID(ctx, id); err != nil {
+			return err
+		}
+		owner, err = QueueAdvertiserEvent(ctx, repos, updated.AdvertiserID, eventType, updated)
+		return err
+	})
+	if err != nil {
+		return nil, notFound(err, ErrCampaignNotFound)
+	}
+	publishTo(ctx, s.stream, owner, eventType, updated)
+	return updated, nil
+}
+
+// campaignStatusEvent returns the event a campaign moving from one status to another
+// raises, or "" if it raises none.
+func campaignStatusEvent(from, to models.CampaignStatus) string {
+	if from == to {
+		return ""
+	}
+	switch to {
+	case models.CampaignStatusScheduled:
+		return models.WebhookEventCampaignScheduled
+	case models.CampaignStatusActive:
+		return models.WebhookEventCampaignActivated
+	case models.CampaignStatusPaused:
+		return models.WebhookEventCampaignPaused
+	case models.CampaignStatusCompleted:
+		return models.WebhookEventCampaignCompleted
+	}
+	return ""
+}
+
+// Delete soft-deletes the campaign. It returns *interfaces.DeletionBlockedError if
+// records still depend on it.
+func (s *CampaignService) Delete(ctx context.Context, id string) error {
+	return notFound(s.repo.Delete(ctx, id), ErrCampaignNotFound)
+}
+
+// DeleteCascade permanently deletes the campaign, its creatives and their stored files.
+// Only admins may run it.
+func (s *CampaignService) DeleteCascade(ctx context.Context, id string) (*CascadeResult, error) {
+	if s.admins == nil || !s.admins.IsAdmin(ctx) {
+		return nil, ErrForbidden
+	}
+
+	deps, err := s.repo.DeleteCascade(ctx, id)
+	if err != nil {
+		return nil, notFound(err, ErrCampaignNotFound)
+	}
+
+	result := &CascadeResult{Deleted: deps}
+	if err := removeStoredObjects(ctx, s.storage, deps.StoredObjects); err != nil {
+		slog.ErrorContext(ctx, "failed to delete stored objects", "resource", deps.Resource, "id", deps.ID, "error", err)
+		result.StorageErr = err
+	}
+	return result, nil
+}
+
+// Dependencies lists what a cascade delete of the campaign would remove.
+func (s *CampaignService) Dependencies(ctx context.Context, id string) (*models.DeletionDependencies, error) {
+	deps, err := s.repo.Dependencies(ctx, id)
+	if err != nil {
+		return nil, notFound(err, ErrCampaignNotFound)
+	}
+	return deps, nil
+}
+
+// Restore undoes a soft delete. It returns *interfaces.RestoreBlockedError if the
+// campaign's advertiser is deleted.
+func (s *CampaignService) Restore(ctx context.Context, id string) (*models.Campaign, error) {
+	if err := s.repo.Restore(ctx, id); err != nil {
+		return nil, notFound(err, ErrCampaignNotFound)
+	}
+	campaign, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("get restored campaign: %w", err)
+	}
+	return campaign, nil
+}
@@ -0,0 +1,299 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"scm/internal/interfaces"
+	"scm/internal/models"
+	"scm/internal/repository"
+	"scm/internal/repository/memory"
+)
+
+// memCampaignRepo keeps campaigns in a map. Methods the tests do not use are left to
+// the embedded nil interface.
+type memCampaignRepo struct {
+	interfaces.CampaignRepository
+	campaigns map[string]*models.Campaign
+	deps      *models.DeletionDependencies
+}
+
+func newMemCampaignRepo(campaigns ...*models.Campaign) *memCampaignRepo {
+	m := &memCampaignRepo{campaigns: map[string]*models.Campaign{}}
+	for _, c := range campaigns {
+		m.campaigns[c.ID] = c
+	}
+	return m
+}
+
+func (m *memCampaignRepo) GetByID(ctx context.Context, id string) (*models.Campaign, error) {
+	c, ok := m.campaigns[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	copied := *c
+	return &copied, nil
+}
+
+func (m *memCampaignRepo) Update(ctx context.Context, id string, campaign *models.Campaign, version int) error {
+	current, ok := m.campaigns[id]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	if version != 0 && version != current.Version {
+		return interfaces.ErrVersionConflict
+	}
+	updated := *campaign
+	updated.Version = current.Version + 1
+	m.campaigns[id] = &updated
+	return nil
+}
+
+func (m *memCampaignRepo) DeleteCascade(ctx context.Context, id string) (*models.DeletionDependencies, error) {
+	if _, ok := m.campaigns[id]; !ok {
+		return nil, sql.ErrNoRows
+	}
+	delete(m.campaigns, id)
+	return m.deps, nil
+}
+
+type adminsFunc func(ctx context.Context) bool
+
+func (f adminsFunc) IsAdmin(ctx context.Context) bool { return f(ctx) }
+
+type memObjectStorage struct {
+	deleted []string
+}
+
+func (s *memObjectStorage) DeleteObjects(ctx context.Context, keys []string) error {
+	s.deleted = append(s.deleted, keys...)
+	return nil
+}
+
+// memAdvertiserRepo knows the owner of each advertiser.
+type memAdvertiserRepo struct {
+	interfaces.AdvertiserRepository
+	owners map[string]string
+}
+
+func (m *memAdvertiserRepo) GetByID(ctx context.Context, id string) (*models.Advertiser, error) {
+	owner, ok := m.owners[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return &models.Advertiser{ID: id, CreatedBy: owner}, nil
+}
+
+func testCampaign(status models.CampaignStatus) *models.Campaign {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	return &models.Campaign{
+		ID:        "c1",
+		Name:      "Spring",
+		Status:    status,
+		StartDate: start,
+		EndDate:   start.AddDate(0, 1, 0),
+		Budget:    100,
+		Version:   1,
+	}
+}
+
+func TestCampaignServiceReplaceEnforcesStatusTransitions(t *testing.T) {
+	tests := []struct {
+		from, to models.CampaignStatus
+		allowed  bool
+	}{
+		{models.CampaignStatusDraft, models.CampaignStatusScheduled, true},
+		{models.CampaignStatusScheduled, models.CampaignStatusActive, true},
+		{models.CampaignStatusActive, models.CampaignStatusCompleted, true},
+		{models.CampaignStatusPaused, models.CampaignStatusActive, true},
+		{models.CampaignStatusCompleted, models.CampaignStatusCompleted, true},
+		{models.CampaignStatusDraft, models.CampaignStatusCompleted, false},
+		{models.CampaignStatusActive, models.CampaignStatusDraft, false},
+		{models.CampaignStatusCompleted, models.CampaignStatusActive, false},
+	}
+	for _, tt := range tests {
+		campaign := testCampaign(tt.from)
+		s := NewCampaignService(newMemCampaignRepo(campaign), nil, nil)
+
+		req := models.NewReplaceCampaignRequest(campaign)
+		req.Status = string(tt.to)
+		updated, err := s.Replace(context.Background(), campaign.ID, req, campaign.Version)
+
+		var transition *StatusTransitionError
+		switch {
+		case tt.allowed && err != nil:
+			t.Errorf("%s -> %s: unexpected error %v", tt.from, tt.to, err)
+		case tt.allowed && updated.Status != tt.to:
+			t.Errorf("%s -> %s: status is %s", tt.from, tt.to, updated.Status)
+		case !tt.allowed && !errors.As(err, &transition):
+			t.Errorf("%s -> %s: expected StatusTransitionError, got %v", tt.from, tt.to, err)
+		}
+	}
+}
+
+func TestCampaignServiceReplaceValidatesAndChecksVersion(t *testing.T) {
+	campaign := testCampaign(models.CampaignStatusDraft)
+	s := NewCampaignService(newMemCampaignRepo(campaign), nil, nil)
+	ctx := context.Background()
+
+	req := models.NewReplaceCampaignRequest(campaign)
+	req.Budget = 0
+	var invalid *ValidationError
+	if _, err := s.Replace(ctx, campaign.ID, req, campaign.Version); !errors.As(err, &invalid) {
+		t.Fatalf("expected ValidationError, got %v", err)
+	}
+
+	req = models.NewReplaceCampaignRequest(campaign)
+	if _, err := s.Replace(ctx, campaign.ID, req, campaign.Version+1); !errors.Is(err, interfaces.ErrVersionConflict) {
+		t.Fatalf("expected ErrVersionConflict, got %v", err)
+	}
+	if _, err := s.Replace(ctx, "missing", req, 0); !errors.Is(err, ErrCampaignNotFound) {
+		t.Fatalf("expected ErrCampaignNotFound, got %v", err)
+	}
+}
+
+func TestCampaignServiceDeleteCascade(t *testing.T) {
+	ctx := context.Background()
+	deps := &models.DeletionDependencies{Resource: "campaign", ID: "c1", StoredObjects: []string{"creatives/a.png"}}
+
+	repo := newMemCampaignRepo(testCampaign(models.CampaignStatusActive))
+	repo.deps = deps
+	storage := &memObjectStorage{}
+	s := NewCampaignService(repo, adminsFunc(func(context.Context) bool { return false }), storage)
+	if _, err := s.DeleteCascade(ctx, "c1"); !errors.Is(err, ErrForbidden) {
+		t.Fatalf("expected ErrForbidden for a non-admin, got %v", err)
+	}
+	if len(repo.campaigns) != 1 || len(storage.deleted) != 0 {
+		t.Fatalf("expected nothing deleted, got campaigns %v and objects %v", repo.campaigns, storage.deleted)
+	}
+
+	s = NewCampaignService(repo, adminsFunc(func(context.Context) bool { return true }), storage)
+	result, err := s.DeleteCascade(ctx, "c1")
+	if err != nil {
+		t.Fatalf("DeleteCascade: %v", err)
+	}
+	if result.Deleted != deps || result.StorageErr != nil {
+		t.Fatalf("unexpected result %+v", result)
+	}
+	if len(storage.deleted) != 1 || storage.deleted[0] != "creatives/a.png" {
+		t.Fatalf("expected the creative's file deleted, got %v", storage.deleted)
+	}
+
+	repo = newMemCampaignRepo(testCampaign(models.CampaignStatusActive))
+	repo.deps = deps
+	s = NewCampaignService(repo, adminsFunc(func(context.Context) bool { return true }), nil)
+	result, err = s.DeleteCascade(ctx, "c1")
+	if err != nil || result.StorageErr == nil {
+		t.Fatalf("expected the missing storage to be reported, got %+v, %v", result, err)
+	}
+}
+
+func TestCampaignServiceWithMemoryRepositories(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStore()
+	owner := &models.User{ID: uuid.NewString(), Email: "owner@example.com", UserName: "owner"}
+	if err := memory.NewUserRepository(store).Create(ctx, owner); err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	advertiser := &models.Advertiser{Name: "Acme", CreatedBy: owner.ID}
+	if err := memory.NewAdvertiserRepository(store).Create(ctx, advertiser); err != nil {
+		t.Fatalf("create advertiser: %v", err)
+	}
+	s := NewCampaignService(memory.NewCampaignRepository(store), nil, nil)
+
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	req := models.CreateCampaignRequest{Name: "Spring", StartDate: start, EndDate: start.AddDate(0, 1, 0), Budget: 100, AdvertiserID: uuid.NewString()}
+	var invalid *ValidationError
+	if _, err := s.Create(ctx, req); !errors.As(err, &invalid) || invalid.Code != "invalid_advertiser_id" {
+		t.Fatalf("expected invalid_advertiser_id, got %v", err)
+	}
+
+	req.AdvertiserID = advertiser.ID
+	created, err := s.Create(ctx, req)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	list, err := s.List(ctx, interfaces.CampaignFilter{AdvertiserID: advertiser.ID})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if list.Total != 1 || list.Campaigns[0].ID != created.ID || list.Summary.TotalBudget != 100 {
+		t.Fatalf("unexpected list %+v", list)
+	}
+
+	if err := s.Delete(ctx, created.ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := s.Get(ctx, created.ID); !errors.Is(err, ErrCampaignNotFound) {
+		t.Fatalf("expected ErrCampaignNotFound after delete, got %v", err)
+	}
+	restored, err := s.Restore(ctx, created.ID)
+	if err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+	if restored.Status != models.CampaignStatusDraft || restored.Version != 3 {
+		t.Fatalf("unexpected restored campaign %+v", restored)
+	}
+}
+
+func TestCampaignServiceReplaceQueuesStatusEvents(t *testing.T) {
+	campaign := testCampaign(models.CampaignStatusScheduled)
+	campaign.AdvertiserID = "a1"
+	repo := newMemCampaignRepo(campaign)
+	webhooks := &memWebhookRepo{}
+	advertisers := &memAdvertiserRepo{owners: map[string]string{"a1": "alice"}}
+	stream := NewEventStream(10)
+	s := NewCampaignService(repo, nil, nil)
+	s.SetEvents(memUnitOfWork{repository.Repositories{Advertisers: advertisers, Campaigns: repo, Webhooks: webhooks}}, stream)
+	ctx := context.Background()
+
+	_, owner, _, cancelOwner := stream.Subscribe(StreamViewer{UserID: "alice"}, "")
+	defer cancelOwner()
+	_, other, _, cancelOther := stream.Subscribe(StreamViewer{UserID: "bob"}, "")
+	defer cancelOther()
+
+	replace := func(status models.CampaignStatus, version int) error {
+		current, err := s.Get(ctx, campaign.ID)
+		if err != nil {
+			t.Fatalf("get: %v", err)
+		}
+		req := models.NewReplaceCampaignRequest(current)
+		req.Status = string(status)
+		_, err = s.Replace(ctx, campaign.ID, req, version)
+		return err
+	}
+	for _, status := range []models.CampaignStatus{models.CampaignStatusActive, models.CampaignStatusActive, models.CampaignStatusPaused} {
+		if err := replace(status, 0); err != nil {
+			t.Fatalf("replace to %s: %v", status, err)
+		}
+	}
+	// A rejected update queues nothing.
+	if err := replace(models.CampaignStatusCompleted, campaign.Version); !errors.Is(err, interfaces.ErrVersionConflict) {
+		t.Fatalf("expected a version conflict, got %v", err)
+	}
+	if err := replace(models.CampaignStatusCompleted, 0); err != nil {
+		t.Fatalf("replace to completed: %v", err)
+	}
+
+	want := []string{models.WebhookEventCampaignActivated, models.WebhookEventCampaignPaused, models.WebhookEventCampaignCompleted}
+	if !slices.Equal(webhooks.queued, want) {
+		t.Fatalf("expected queued events %v, got %v", want, webhooks.queued)
+	}
+	var streamed []string
+	for range want {
+		streamed = append(streamed, (<-owner).Type)
+	}
+	if !slices.Equal(streamed, want) {
+		t.Fatalf("expected streamed events %v, got %v", want, streamed)
+	}
+	select {
+	case e := <-other:
+		t.Fatalf("another user received %s about alice's campaign", e.Type)
+	default:
+	}
+}
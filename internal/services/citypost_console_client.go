@@ -12,6 +12,8 @@ import (
 	"strings"
 	"sync"
 	"time"
+
+	"scm/internal/tracing"
 )
 
 type CityPostDevice struct {
@@ -30,6 +32,8 @@ type CityPostConsoleClient struct {
 	mu        sync.Mutex
 	token     string
 	tokenExp  time.Time
+	// loginMu makes concurrent callers with an expired token wait for a single login.
+	loginMu sync.Mutex
 }
 
 func NewCityPostConsoleClient(baseURL, username, password string) *CityPostConsoleClient {
@@ -37,7 +41,7 @@ func NewCityPostConsoleClient(baseURL, username, password string) *CityPostConso
 		baseURL:    strings.TrimRight(baseURL, "/"),
 		username:   username,
 		password:   password,
-		httpClient: &http.Client{Timeout: 15 * time.Second},
+		httpClient: &http.Client{Timeout: 15 * time.Second, Transport: tracing.Transport(http.DefaultTransport, "CityPost console")},
 		authScheme: "Bearer",
 		tokenTTL:   30 * time.Minute,
 	}
@@ -62,13 +66,15 @@ func (c *CityPostConsoleClient) SetTokenTTL(ttl time.Duration) {
 }
 
 func (c *CityPostConsoleClient) ensureToken(ctx context.Context) (string, error) {
-	c.mu.Lock()
-	if c.token != "" && time.Now().Before(c.tokenExp) {
-		t := c.token
-		c.mu.Unlock()
+	if t, ok := c.cachedToken(); ok {
+		return t, nil
+	}
+
+	c.loginMu.Lock()
+	defer c.loginMu.Unlock()
+	if t, ok := c.cachedToken(); ok {
 		return t, nil
 	}
-	c.mu.Unlock()
 
 	tok, err := c.login(ctx)
 	if err != nil {
@@ -83,6 +89,83 @@ func (c *CityPostConsoleClient) ensureToken(ctx context.Context) (string, error)
 	return tok, nil
 }
 
+func (c *CityPostConsoleClient) cachedToken() (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.token != "" && time.Now().Before(c.tokenExp) {
+		return c.token, true
+	}
+	return "", false
+}
+
+// dropToken forgets tok if it is still the cached token, so the next call logs in again.
+func (c *CityPostConsoleClient) dropToken(tok string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.token == tok {
+		c.token = ""
+	}
+}
+
+// getWithToken GETs rawURL with the console token. The console answers 401 once it
+// has expired a token, which can happen before tokenTTL runs out; the cached token is
+// then dropped and the request retried once after a fresh login.
+func (c *CityPostConsoleClient) getWithToken(ctx context.Context, rawURL string) (int, []byte, error) {
+	for attempt := 0; ; attempt++ {
+		token, err := c.ensureToken(ctx)
+		if err != nil {
+			return 0, nil, fmt.Errorf("ensure token: %w", err)
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+		if err != nil {
+			return 0, nil, err
+		}
+		req.Header.Set("Accept", "application/json")
+		req.Header.Set("Authorization", strings.TrimSpace(c.authScheme)+" "+strings.TrimSpace(token))
+
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			return 0, nil, err
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusUnauthorized && attempt == 0 {
+			c.dropToken(token)
+			continue
+		}
+		return resp.StatusCode, body, nil
+	}
+}
+
+// nextPage returns the absolute URL of the page after the one fetched from current,
+// or "" when the response is the last page. Paginated console responses carry a
+// "next" link next to the list. The link is only followed to the configured console,
+// as the request carries the console token.
+func (c *CityPostConsoleClient) nextPage(current string, out map[string]any) (string, error) {
+	next, _ := out["next"].(string)
+	if strings.TrimSpace(next) == "" {
+		return "", nil
+	}
+	base, err := url.Parse(current)
+	if err != nil {
+		return "", err
+	}
+	ref, err := url.Parse(next)
+	if err != nil {
+		return "", fmt.Errorf("parse next page URL: %w", err)
+	}
+	resolved := base.ResolveReference(ref)
+	console, err := url.Parse(c.baseURL)
+	if err != nil {
+		return "", err
+	}
+	if resolved.Scheme != console.Scheme || resolved.Host != console.Host {
+		return "", fmt.Errorf("next page %s is not on the console %s", resolved.Redacted(), console.Host)
+	}
+	return resolved.String(), nil
+}
+
 func (c *CityPostConsoleClient) login(ctx context.Context) (string, error) {
 	if strings.TrimSpace(c.baseURL) == "" {
 		return "", errors.New("citypost baseURL is required")
@@ -140,11 +223,6 @@ func (c *CityPostConsoleClient) ListDevices(ctx context.Context, project, region
 		return nil, errors.New("project and region are required")
 	}
 
-	token, err := c.ensureToken(ctx)
-	if err != nil {
-		return nil, err
-	}
-
 	u, err := url.Parse(c.baseURL + "/device/")
 	if err != nil {
 		return nil, err
@@ -154,22 +232,12 @@ func (c *CityPostConsoleClient) ListDevices(ctx context.Context, project, region
 	q.Set("region", region)
 	u.RawQuery = q.Encode()
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	status, body, err := c.getWithToken(ctx, u.String())
 	if err != nil {
 		return nil, err
 	}
-	req.Header.Set("Accept", "application/json")
-	req.Header.Set("Authorization", strings.TrimSpace(c.authScheme)+" "+strings.TrimSpace(token))
-
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	body, _ := io.ReadAll(resp.Body)
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return nil, fmt.Errorf("citypost list devices failed: status=%d body=%s", resp.StatusCode, strings.TrimSpace(string(body)))
+	if status < 200 || status >= 300 {
+		return nil, fmt.Errorf("citypost list devices failed: status=%d body=%s", status, strings.TrimSpace(string(body)))
 	}
 
 	var arr []map[string]any
@@ -233,15 +301,23 @@ func (c *CityPostConsoleClient) ListDevices(ctx context.Context, project, region
 	return out, nil
 }
 
-// ListProjects fetches both production and non-production projects and merges them
+// ListProjects fetches both production and non-production projects concurrently and merges them
 func (c *CityPostConsoleClient) ListProjects(ctx context.Context) ([]map[string]any, error) {
+	var projFalse []map[string]any
+	var errFalse error
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		projFalse, errFalse = c.fetchProjects(ctx, false)
+	}()
+
 	projTrue, err := c.fetchProjects(ctx, true)
+	<-done
 	if err != nil {
 		return nil, fmt.Errorf("fetch production projects: %w", err)
 	}
-	projFalse, err := c.fetchProjects(ctx, false)
-	if err != nil {
-		return nil, fmt.Errorf("fetch non-production projects: %w", err)
+	if errFalse != nil {
+		return nil, fmt.Errorf("fetch non-production projects: %w", errFalse)
 	}
 	merged := append(projTrue, projFalse...)
 	return merged, nil
@@ -261,41 +337,45 @@ func (c *CityPostConsoleClient) fetchProjects(ctx context.Context, production bo
 	q.Set("production", fmt.Sprintf("%t", production))
 	u.RawQuery = q.Encode()
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
-	if err != nil {
-		return nil, fmt.Errorf("create projectsList request: %w", err)
-	}
-	req.Header.Set("Accept", "application/json")
-
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("do projectsList request: %w", err)
-	}
-	defer resp.Body.Close()
+	result := []map[string]any{}
+	for next := u.String(); next != ""; {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, next, nil)
+		if err != nil {
+			return nil, fmt.Errorf("create projectsList request: %w", err)
+		}
+		req.Header.Set("Accept", "application/json")
 
-	body, _ := io.ReadAll(resp.Body)
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return nil, fmt.Errorf("citypost projectsList failed: status=%d body=%s", resp.StatusCode, strings.TrimSpace(string(body)))
-	}
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("do projectsList request: %w", err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			return nil, fmt.Errorf("citypost projectsList failed: status=%d body=%s", resp.StatusCode, strings.TrimSpace(string(body)))
+		}
 
-	var out map[string]any
-	if err := json.Unmarshal(body, &out); err != nil {
-		return nil, fmt.Errorf("citypost projectsList: invalid json: %w", err)
-	}
+		var out map[string]any
+		if err := json.Unmarshal(body, &out); err != nil {
+			return nil, fmt.Errorf("citypost projectsList: invalid json: %w", err)
+		}
 
-	projectsRaw, ok := out["projects"]
-	if !ok {
-		return nil, errors.New("citypost projectsList response missing 'projects' field")
-	}
-	projectsSlice, ok := projectsRaw.([]any)
-	if !ok {
-		return nil, errors.New("citypost projectsList 'projects' field is not an array")
-	}
+		projectsRaw, ok := out["projects"]
+		if !ok {
+			return nil, errors.New("citypost projectsList response missing 'projects' field")
+		}
+		projectsSlice, ok := projectsRaw.([]any)
+		if !ok {
+			return nil, errors.New("citypost projectsList 'projects' field is not an array")
+		}
 
-	result := make([]map[string]any, 0, len(projectsSlice))
-	for _, item := range projectsSlice {
-		if m, ok := item.(map[string]any); ok {
-			result = append(result, m)
+		for _, item := range projectsSlice {
+			if m, ok := item.(map[string]any); ok {
+				result = append(result, m)
+			}
+		}
+		if next, err = c.nextPage(next, out); err != nil {
+			return nil, err
 		}
 	}
 	return result, nil
@@ -308,11 +388,6 @@ func (c *CityPostConsoleClient) ListDevicesByProject(ctx context.Context, projec
 		return nil, errors.New("project name is required")
 	}
 
-	token, err := c.ensureToken(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("ensure token: %w", err)
-	}
-
 	u, err := url.Parse(c.baseURL + "/device/")
 	if err != nil {
 		return nil, fmt.Errorf("parse device URL: %w", err)
@@ -321,42 +396,37 @@ func (c *CityPostConsoleClient) ListDevicesByProject(ctx context.Context, projec
 	q.Set("project", projectName)
 	u.RawQuery = q.Encode()
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
-	if err != nil {
-		return nil, fmt.Errorf("create device request: %w", err)
-	}
-	req.Header.Set("Accept", "application/json")
-	req.Header.Set("Authorization", strings.TrimSpace(c.authScheme)+" "+strings.TrimSpace(token))
-
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("do device request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	body, _ := io.ReadAll(resp.Body)
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return nil, fmt.Errorf("citypost list devices failed: status=%d body=%s", resp.StatusCode, strings.TrimSpace(string(body)))
-	}
+	result := []map[string]any{}
+	for next := u.String(); next != ""; {
+		status, body, err := c.getWithToken(ctx, next)
+		if err != nil {
+			return nil, fmt.Errorf("do device request: %w", err)
+		}
+		if status < 200 || status >= 300 {
+			return nil, fmt.Errorf("citypost list devices failed: status=%d body=%s", status, strings.TrimSpace(string(body)))
+		}
 
-	var out map[string]any
-	if err := json.Unmarshal(body, &out); err != nil {
-		return nil, fmt.Errorf("citypost list devices: invalid json: %w", err)
-	}
+		var out map[string]any
+		if err := json.Unmarshal(body, &out); err != nil {
+			return nil, fmt.Errorf("citypost list devices: invalid json: %w", err)
+		}
 
-	devicesRaw, ok := out["devices"]
-	if !ok {
-		return nil, errors.New("citypost list devices response missing 'devices' field")
-	}
-	devicesSlice, ok := devicesRaw.([]any)
-	if !ok {
-		return nil, errors.New("citypost list devices 'devices' field is not an array")
-	}
+		devicesRaw, ok := out["devices"]
+		if !ok {
+			return nil, errors.New("citypost list devices response missing 'devices' field")
+		}
+		devicesSlice, ok := devicesRaw.([]any)
+		if !ok {
+			return nil, errors.New("citypost list devices 'devices' field is not an array")
+		}
 
-	result := make([]map[string]any, 0, len(devicesSlice))
-	for _, item := range devicesSlice {
-		if m, ok := item.(map[string]any); ok {
-			result = append(result, m)
+		for _, item := range devicesSlice {
+			if m, ok := item.(map[string]any); ok {
+				result = append(result, m)
+			}
+		}
+		if next, err = c.nextPage(next, out); err != nil {
+			return nil, err
 		}
 	}
 	return result, nil
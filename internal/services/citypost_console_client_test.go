@@ -0,0 +1,157 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"scm/internal/fakeconsole"
+)
+
+func newFakeConsole(t *testing.T, cfg fakeconsole.Config) (*fakeconsole.Server, *CityPostConsoleClient) {
+	t.Helper()
+	cfg.Username, cfg.Password = "demo", "secret"
+	fake := fakeconsole.New(cfg)
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+
+	client := NewCityPostConsoleClient(srv.URL+"/", "demo", "secret")
+	client.SetAuthScheme("Token")
+	return fake, client
+}
+
+func TestConsoleClientSharesOneLogin(t *testing.T) {
+	fake, client := newFakeConsole(t, fakeconsole.Config{Fixtures: fakeconsole.Demo(4, 3, time.Now()), Latency: 10 * time.Millisecond})
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 4)
+	for i := 1; i <= 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			devices, err := client.ListDevicesByProject(context.Background(), "demo-project-"+strconv.Itoa(i))
+			if err == nil && len(devices) != 3 {
+				t.Errorf("project %d: expected 3 devices got %d", i, len(devices))
+			}
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("ListDevicesByProject: %v", err)
+		}
+	}
+	if n := fake.Logins(); n != 1 {
+		t.Fatalf("expected concurrent callers to share 1 login, got %d", n)
+	}
+}
+
+func TestConsoleClientLogsInAgainAfterUnauthorized(t *testing.T) {
+	fake, client := newFakeConsole(t, fakeconsole.Config{Fixtures: fakeconsole.Demo(1, 2, time.Now())})
+	ctx := context.Background()
+
+	if _, err := client.ListDevicesByProject(ctx, "demo-project-1"); err != nil {
+		t.Fatalf("first call: %v", err)
+	}
+	fake.ExpireTokens()
+	devices, err := client.ListDevicesByProject(ctx, "demo-project-1")
+	if err != nil {
+		t.Fatalf("call with an expired token: %v", err)
+	}
+	if len(devices) != 2 || fake.Logins() != 2 || fake.Requests("/device/") != 3 {
+		t.Fatalf("expected one retry after a fresh login, got %d devices, %d logins, %d requests", len(devices), fake.Logins(), fake.Requests("/device/"))
+	}
+
+	// A token the console keeps rejecting is not retried forever.
+	fake.Fail("/device/", http.StatusUnauthorized, 0)
+	if _, err := client.ListDevicesByProject(ctx, "demo-project-1"); err == nil || !strings.Contains(err.Error(), "status=401") {
+		t.Fatalf("expected a 401 error, got %v", err)
+	}
+	if n := fake.Requests("/device/"); n != 5 {
+		t.Fatalf("expected 2 more device requests, got %d in total", n)
+	}
+}
+
+func TestConsoleClientFollowsPages(t *testing.T) {
+	fake, client := newFakeConsole(t, fakeconsole.Config{Fixtures: fakeconsole.Demo(5, 5, time.Now()), PageSize: 2})
+	ctx := context.Background()
+
+	projects, err := client.ListProjects(ctx)
+	if err != nil {
+		t.Fatalf("ListProjects: %v", err)
+	}
+	if len(projects) != 5 {
+		t.Fatalf("expected 5 projects across pages, got %d", len(projects))
+	}
+	// Production projects (1, 3, 5) take 2 pages, the others 1.
+	if n := fake.Requests("/projectsList"); n != 3 {
+		t.Fatalf("expected 3 projectsList requests, got %d", n)
+	}
+
+	devices, err := client.ListDevicesByProject(ctx, "demo-project-2")
+	if err != nil {
+		t.Fatalf("ListDevicesByProject: %v", err)
+	}
+	if len(devices) != 5 || devices[4]["host_name"] != "demo-2-5" {
+		t.Fatalf("expected all 5 devices in order, got %v", devices)
+	}
+	if n := fake.Requests("/device/"); n != 3 {
+		t.Fatalf("expected 3 device requests, got %d", n)
+	}
+}
+
+func TestConsoleClientOnlyFollowsPagesOnTheConsole(t *testing.T) {
+	var leaked string
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		leaked = r.Header.Get("Authorization")
+		w.Write([]byte(`{"devices": [], "next": null}`))
+	}))
+	defer other.Close()
+
+	console := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/login/" {
+			w.Write([]byte(`{"token": "secret-token"}`))
+			return
+		}
+		w.Write([]byte(`{"devices": [{"host_name": "d1"}], "next": "` + other.URL + `/device/?page=2"}`))
+	}))
+	defer console.Close()
+
+	client := NewCityPostConsoleClient(console.URL, "demo", "secret")
+	if _, err := client.ListDevicesByProject(context.Background(), "p1"); err == nil || !strings.Contains(err.Error(), "is not on the console") {
+		t.Fatalf("expected the foreign next page to be refused, got %v", err)
+	}
+	if leaked != "" {
+		t.Fatalf("sent the console token to another host: %q", leaked)
+	}
+}
+
+func TestConsoleClientReportsConsoleErrors(t *testing.T) {
+	fake, client := newFakeConsole(t, fakeconsole.Config{Fixtures: fakeconsole.Demo(2, 1, time.Now())})
+	ctx := context.Background()
+
+	fake.Fail("/projectsList", http.StatusBadGateway, 1)
+	if _, err := client.ListProjects(ctx); err == nil || !strings.Contains(err.Error(), "status=502") {
+		t.Fatalf("expected a 502 error, got %v", err)
+	}
+	if projects, err := client.ListProjects(ctx); err != nil || len(projects) != 2 {
+		t.Fatalf("expected the console to recover, got %v, %v", projects, err)
+	}
+
+	fake.Fail("/login/", http.StatusServiceUnavailable, 1)
+	if _, err := client.ListDevicesByProject(ctx, "demo-project-1"); err == nil || !strings.Contains(err.Error(), "citypost login failed: status=503") {
+		t.Fatalf("expected a login error, got %v", err)
+	}
+
+	wrong := NewCityPostConsoleClient(client.baseURL, "demo", "wrong")
+	if _, err := wrong.ListDevicesByProject(ctx, "demo-project-1"); err == nil || !strings.Contains(err.Error(), "status=400") {
+		t.Fatalf("expected bad credentials to fail the login, got %v", err)
+	}
+}
@@ -0,0 +1,296 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"scm/internal/interfaces"
+	"scm/internal/models"
+	"scm/internal/repository"
+)
+
+// ErrNothingUploaded is returned when none of the files of an upload could be stored.
+var ErrNothingUploaded = errors.New("no files could be uploaded")
+
+// CreativeFile is a file uploaded for a creative.
+type CreativeFile struct {
+	Name        string
+	ContentType string
+	Size        int64
+	Open        func() (io.ReadCloser, error)
+}
+
+// UploadCreativesRequest creates one creative per file, all with the same schedule.
+type UploadCreativesRequest struct {
+	CampaignID   string
+	SelectedDays []string
+	TimeSlots    []string
+	Devices      []string
+	Files        []CreativeFile
+}
+
+// StorageError is returned when a creative's file could not be stored.
+type StorageError struct {
+	Err error
+}
+
+func (e *StorageError) Error() string {
+	return "store creative file: " + e.Err.Error()
+}
+
+func (e *StorageError) Unwrap() error {
+	return e.Err
+}
+
+// CreativeService holds the rules for uploading and editing creatives.
+type CreativeService struct {
+	repo          repository.CreativeRepository
+	campaigns     interfaces.CampaignRepository
+	storage       ObjectUploader
+	publicBaseURL string
+	uow           repository.UnitOfWork
+	stream        *EventStream
+	validator     *validator.Validate
+}
+
+// NewCreativeService returns a service storing creative files in storage, where they
+// are served from under publicBaseURL.
+func NewCreativeService(repo repository.CreativeRepository, campaigns interfaces.CampaignRepository, storage ObjectUploader, publicBaseURL string) *CreativeService {
+	return &CreativeService{
+		repo:          repo,
+		campaigns:     campaigns,
+		storage:       storage,
+		publicBaseURL: publicBaseURL,
+		validator:     validator.New(),
+	}
+}
+
+// SetEvents makes the service raise creative.uploaded for every uploaded creative.
+// Creatives are then saved through uow, in the transaction that queues the webhook
+// event, and the event is pushed to stream, which may be nil, once it has committed.
+func (s *CreativeService) SetEvents(uow repository.UnitOfWork, stream *EventStream) {
+	s.uow = uow
+	s.stream = stream
+}
+
+// Upload stores the files of req and creates their creatives. Files that cannot be
+// stored are logged and skipped; ErrNothingUploaded is returned if none could.
+func (s *CreativeService) Upload(ctx context.Context, req UploadCreativesRequest) ([]*models.Creative, error) {
+	if req.CampaignID == "" {
+		return nil, invalid("campaign_id is required")
+	}
+	if _, err := uuid.Parse(req.CampaignID); err != nil {
+		return nil, invalid("campaign_id must be a valid UUID")
+	}
+	campaign, err := s.campaigns.GetByID(ctx, req.CampaignID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, invalid("campaign_id not found")
+		}
+		return nil, fmt.Errorf("validate campaign %s: %w", req.CampaignID, err)
+	}
+	if len(req.SelectedDays) == 0 {
+		return nil, invalid("selected_days is required")
+	}
+	if len(req.TimeSlots) == 0 {
+		return nil, invalid("time_slots is required")
+	}
+	if len(req.Files) == 0 {
+		return nil, invalid("No files uploaded")
+	}
+
+	var uploaded []*models.Creative
+	for _, file := range req.Files {
+		creative := &models.Creative{
+			ID:           uuid.New().String(),
+			Name:         file.Name,
+			Type:         creativeType(file.ContentType),
+			Size:         file.Size,
+			CampaignID:   req.CampaignID,
+			SelectedDays: req.SelectedDays,
+			TimeSlots:    req.TimeSlots,
+			Devices:      req.Devices,
+			UploadedAt:   time.Now().UTC(),
+		}
+
+		key, err := s.store(ctx, creative.ID, file)
+		if err != nil {
+			slog.ErrorContext(ctx, "failed to upload file", "file", file.Name, "error", err)
+			continue
+		}
+		creative.FilePath = key
+		creative.URL = s.publicURL(key)
+
+		owner, err := s.create(ctx, campaign.AdvertiserID, creative)
+		if err != nil {
+			slog.ErrorContext(ctx, "failed to save creative", "file", file.Name, "error", err)
+			continue
+		}
+		uploaded = append(uploaded, creative)
+		if s.uow != nil {
+			publishTo(ctx, s.stream, owner, models.WebhookEventCreativeUploaded, creative)
+		}
+	}
+
+	if len(uploaded) == 0 {
+		return nil, ErrNothingUploaded
+	}
+	return uploaded, nil
+}
+
+// create saves the creative of one of advertiserID's campaigns and, when the service
+// raises events, queues creative.uploaded for it in the same transaction and returns
+// the advertiser's owner.
+func (s *CreativeService) create(ctx context.Context, advertiserID string, creative *models.Creative) (string, error) {
+	if s.uow == nil {
+		return "", s.repo.Create(ctx, creative)
+	}
+	var owner string
+	err := s.uow.Do(ctx, nil, func(ctx context.Context, repos repository.Repositories) error {
+		if err := repos.Creatives.Create(ctx, creative); err != nil {
+			return err
+		}
+		var err error
+		owner, err = QueueAdvertiserEvent(ctx, repos, advertiserID, models.WebhookEventCreativeUploaded, creative)
+		return err
+	})
+	return owner, err
+}
+
+// store writes file to the creative's object key and returns the key.
+func (s *CreativeService) store(ctx context.Context, id string, file CreativeFile) (string, error) {
+	if s.storage == nil {
+		return "", &StorageError{Err: errors.New("object storage not configured")}
+	}
+
+	body, err := file.Open()
+	if err != nil {
+		return "", &ValidationError{Code: "invalid_request", Message: "Failed to open uploaded file"}
+	}
+	defer body.Close()
+
+	key := filepath.Join("creatives", id+filepath.Ext(file.Name))
+	if err := s.storage.PutObject(ctx, key, body); err != nil {
+		return "", &StorageError{Err: err}
+	}
+	return key, nil
+}
+
+func (s *CreativeService) publicURL(key string) string {
+	return strings.TrimRight(s.publicBaseURL, "/") + "/" + key
+}
+
+func creativeType(contentType string) models.CreativeType {
+	switch contentType {
+	case "video/mp4", "video/quicktime":
+		return models.CreativeTypeVideo
+	default:
+		return models.CreativeTypeImage
+	}
+}
+
+func (s *CreativeService) Get(ctx context.Context, id string) (*models.Creative, error) {
+	creative, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, notFound(err, ErrCreativeNotFound)
+	}
+	return creative, nil
+}
+
+// List returns a page of all creatives and how many there are.
+func (s *CreativeService) List(ctx context.Context, page interfaces.ListPage, includeDeleted bool) ([]*models.Creative, int, error) {
+	total, err := s.repo.CountAll(ctx, includeDeleted)
+	if err != nil {
+		return nil, 0, err
+	}
+	creatives, err := s.repo.ListAll(ctx, page, includeDeleted)
+	return nonNilCreatives(creatives), total, err
+}
+
+// ListByCampaign returns a page of the campaign's creatives and how many it has.
+func (s *CreativeService) ListByCampaign(ctx context.Context, campaignID string, page interfaces.ListPage, includeDeleted bool) ([]*models.Creative, int, error) {
+	total, err := s.repo.CountByCampaign(ctx, campaignID, includeDeleted)
+	if err != nil {
+		return nil, 0, err
+	}
+	creatives, err := s.repo.ListByCampaign(ctx, campaignID, page, includeDeleted)
+	return nonNilCreatives(creatives), total, err
+}
+
+// ListByDevice returns a page of the creatives scheduled on device and how many there
+// are; with activeNow only those scheduled for the day and time of now.
+func (s *CreativeService) ListByDevice(ctx context.Context, device string, activeNow bool, now time.Time, page interfaces.ListPage) ([]*models.Creative, int, error) {
+	total, err := s.repo.CountByDevice(ctx, device, activeNow, now)
+	if err != nil {
+		return nil, 0, err
+	}
+	creatives, err := s.repo.ListByDevice(ctx, device, activeNow, now, page)
+	return nonNilCreatives(creatives), total, err
+}
+
+func nonNilCreatives(creatives []*models.Creative) []*models.Creative {
+	if creatives == nil {
+		return []*models.Creative{}
+	}
+	return creatives
+}
+
+// Replace overwrites the creative's editable fields if it is still at version (0 skips
+// the check), returning interfaces.ErrVersionConflict if it has changed since. A
+// non-nil file replaces the stored file; the input is validated first because the
+// upload overwrites the previous file.
+func (s *CreativeService) Replace(ctx context.Context, id string, req models.ReplaceCreativeRequest, version int, file *CreativeFile) (*models.Creative, error) {
+	if req.Name == "" && file != nil {
+		req.Name = file.Name
+	}
+	if err := s.validator.Struct(req); err != nil {
+		return nil, invalid("%s", err.Error())
+	}
+
+	update := req.ToUpdate()
+	if file != nil {
+		key, err := s.store(ctx, id, *file)
+		if err != nil {
+			return nil, err
+		}
+		url := s.publicURL(key)
+		update.URL = &url
+		update.FilePath = &key
+		size := file.Size
+		update.Size = &size
+		t := creativeType(file.ContentType)
+		update.Type = &t
+	}
+
+	if err := s.repo.Update(ctx, id, update, version); err != nil {
+		return nil, notFound(err, ErrCreativeNotFound)
+	}
+	return s.Get(ctx, id)
+}
+
+// Delete soft-deletes the creative.
+func (s *CreativeService) Delete(ctx context.Context, id string) error {
+	return notFound(s.repo.Delete(ctx, id), ErrCreativeNotFound)
+}
+
+// Restore undoes a soft delete. It returns *interfaces.RestoreBlockedError if the
+// creative's campaign is deleted.
+func (s *CreativeService) Restore(ctx context.Context, id string) (*models.Creative, error) {
+	if err := s.repo.Restore(ctx, id); err != nil {
+		return nil, notFound(err, ErrCreativeNotFound)
+	}
+	creative, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("get restored creative: %w", err)
+	}
+	return creative, nil
+}
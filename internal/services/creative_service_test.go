@@ -0,0 +1,117 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"scm/internal/models"
+	"scm/internal/repository"
+)
+
+type memCreativeRepo struct {
+	repository.CreativeRepository
+	creatives map[string]*models.Creative
+}
+
+func (m *memCreativeRepo) Create(ctx context.Context, creative *models.Creative) error {
+	m.creatives[creative.ID] = creative
+	return nil
+}
+
+type memUploader struct {
+	objects map[string]string
+	fail    map[string]bool
+}
+
+func (u *memUploader) PutObject(ctx context.Context, key string, body io.Reader) error {
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return err
+	}
+	if u.fail[string(data)] {
+		return errors.New("upload failed")
+	}
+	u.objects[key] = string(data)
+	return nil
+}
+
+func memFile(name, contentType, body string) CreativeFile {
+	return CreativeFile{
+		Name:        name,
+		ContentType: contentType,
+		Size:        int64(len(body)),
+		Open:        func() (io.ReadCloser, error) { return io.NopCloser(strings.NewReader(body)), nil },
+	}
+}
+
+func TestCreativeServiceUpload(t *testing.T) {
+	const campaignID = "550e8400-e29b-41d4-a716-446655440000"
+	campaign := testCampaign(models.CampaignStatusActive)
+	campaign.ID = campaignID
+
+	creatives := &memCreativeRepo{creatives: map[string]*models.Creative{}}
+	uploader := &memUploader{objects: map[string]string{}, fail: map[string]bool{"broken": true}}
+	s := NewCreativeService(creatives, newMemCampaignRepo(campaign), uploader, "https://cdn.example.com/")
+	ctx := context.Background()
+
+	req := UploadCreativesRequest{
+		CampaignID:   "6ba7b810-9dad-11d1-80b4-00c04fd430c8",
+		SelectedDays: []string{"monday"},
+		TimeSlots:    []string{"morning"},
+		Files:        []CreativeFile{memFile("a.png", "image/png", "png")},
+	}
+	var invalid *ValidationError
+	if _, err := s.Upload(ctx, req); !errors.As(err, &invalid) || invalid.Message != "campaign_id not found" {
+		t.Fatalf("expected unknown campaign to be rejected, got %v", err)
+	}
+
+	req.CampaignID = campaignID
+	req.Files = append(req.Files, memFile("b.mp4", "video/mp4", "broken"), memFile("c.mp4", "video/mp4", "mp4"))
+	uploaded, err := s.Upload(ctx, req)
+	if err != nil {
+		t.Fatalf("Upload: %v", err)
+	}
+	if len(uploaded) != 2 || len(creatives.creatives) != 2 || len(uploader.objects) != 2 {
+		t.Fatalf("expected the two good files stored, got %d creatives and objects %v", len(uploaded), uploader.objects)
+	}
+	video := uploaded[1]
+	if video.Type != models.CreativeTypeVideo || video.FilePath != "creatives/"+video.ID+".mp4" {
+		t.Fatalf("unexpected creative %+v", video)
+	}
+	if video.URL != "https://cdn.example.com/"+video.FilePath {
+		t.Fatalf("unexpected URL %q", video.URL)
+	}
+
+	req.Files = []CreativeFile{memFile("b.mp4", "video/mp4", "broken")}
+	if _, err := s.Upload(ctx, req); !errors.Is(err, ErrNothingUploaded) {
+		t.Fatalf("expected ErrNothingUploaded, got %v", err)
+	}
+}
+
+func TestCreativeServiceUploadQueuesEvents(t *testing.T) {
+	campaign := testCampaign(models.CampaignStatusActive)
+	campaign.ID = "550e8400-e29b-41d4-a716-446655440000"
+	campaign.AdvertiserID = "a1"
+	creatives := &memCreativeRepo{creatives: map[string]*models.Creative{}}
+	webhooks := &memWebhookRepo{}
+	advertisers := &memAdvertiserRepo{owners: map[string]string{"a1": "alice"}}
+	uploader := &memUploader{objects: map[string]string{}, fail: map[string]bool{"broken": true}}
+	s := NewCreativeService(creatives, newMemCampaignRepo(campaign), uploader, "https://cdn.example.com/")
+	s.SetEvents(memUnitOfWork{repository.Repositories{Advertisers: advertisers, Creatives: creatives, Webhooks: webhooks}}, nil)
+
+	req := UploadCreativesRequest{
+		CampaignID:   campaign.ID,
+		SelectedDays: []string{"monday"},
+		TimeSlots:    []string{"morning"},
+		Files:        []CreativeFile{memFile("a.png", "image/png", "png"), memFile("b.mp4", "video/mp4", "broken")},
+	}
+	if _, err := s.Upload(context.Background(), req); err != nil {
+		t.Fatalf("Upload: %v", err)
+	}
+	if len(webhooks.queued) != 1 || webhooks.queued[0] != models.WebhookEventCreativeUploaded {
+		t.Fatalf("expected one creative.uploaded queued, got %v", webhooks.queued)
+	}
+}
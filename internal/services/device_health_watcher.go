@@ -0,0 +1,54 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"scm/internal/models"
+	"scm/internal/repository"
+)
+
+// DeviceHealthWatcher raises device.health_changed for devices that go stale or
+// offline. Health is derived from the time of the last heartbeat, so nothing is written
+// when a device goes quiet; the watcher finds the heartbeats that crossed a threshold
+// since its previous check. Devices coming back online are reported by the heartbeat.
+type DeviceHealthWatcher struct {
+	repo       repository.DeviceHealthRepository
+	thresholds models.HealthThresholds
+	events     EventPublisher
+	checked    time.Time
+}
+
+// NewDeviceHealthWatcher returns a watcher whose first check covers the time since now,
+// so devices that went quiet while the API was down are not reported.
+func NewDeviceHealthWatcher(repo repository.DeviceHealthRepository, thresholds models.HealthThresholds, events EventPublisher, now time.Time) *DeviceHealthWatcher {
+	return &DeviceHealthWatcher{repo: repo, thresholds: thresholds, events: events, checked: now}
+}
+
+// Check raises an event for each device that went stale or offline after the previous
+// check and at or before now.
+func (w *DeviceHealthWatcher) Check(ctx context.Context, now time.Time) error {
+	transitions := []struct {
+		after    time.Duration
+		from, to models.DeviceHealthStatus
+	}{
+		{w.thresholds.StaleAfter, models.DeviceHealthOnline, models.DeviceHealthStale},
+		{w.thresholds.OfflineAfter, models.DeviceHealthStale, models.DeviceHealthOffline},
+	}
+	for _, t := range transitions {
+		heartbeats, err := w.repo.ListReceivedBetween(ctx, w.checked.Add(-t.after), now.Add(-t.after))
+		if err != nil {
+			return err
+		}
+		for _, hb := range heartbeats {
+			publishEvent(ctx, w.events, models.StreamEventDeviceHealthChanged, models.DeviceHealthChange{
+				HostName: hb.HostName,
+				From:     t.from,
+				To:       t.to,
+				LastSeen: &hb.ReceivedAt,
+			})
+		}
+	}
+	w.checked = now
+	return nil
+}
@@ -0,0 +1,70 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"scm/internal/models"
+	"scm/internal/repository"
+)
+
+type memHeartbeatRepo struct {
+	repository.DeviceHealthRepository
+	heartbeats []*models.DeviceHeartbeat
+}
+
+func (m *memHeartbeatRepo) ListReceivedBetween(ctx context.Context, from time.Time, to time.Time) ([]*models.DeviceHeartbeat, error) {
+	var out []*models.DeviceHeartbeat
+	for _, hb := range m.heartbeats {
+		if hb.ReceivedAt.After(from) && !hb.ReceivedAt.After(to) {
+			out = append(out, hb)
+		}
+	}
+	return out, nil
+}
+
+type recordedEvent struct {
+	eventType string
+	data      any
+}
+
+type dataPublisher struct {
+	events []recordedEvent
+}
+
+func (p *dataPublisher) Publish(ctx context.Context, eventType string, data any) error {
+	p.events = append(p.events, recordedEvent{eventType, data})
+	return nil
+}
+
+func TestDeviceHealthWatcherReportsEachTransitionOnce(t *testing.T) {
+	start := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	thresholds := models.HealthThresholds{StaleAfter: 5 * time.Minute, OfflineAfter: 30 * time.Minute}
+	repo := &memHeartbeatRepo{heartbeats: []*models.DeviceHeartbeat{
+		{HostName: "kiosk-1", ReceivedAt: start.Add(-4 * time.Minute)},
+		{HostName: "kiosk-2", ReceivedAt: start.Add(-29 * time.Minute)},
+		{HostName: "kiosk-3", ReceivedAt: start.Add(-time.Minute)},
+	}}
+	events := &dataPublisher{}
+	w := NewDeviceHealthWatcher(repo, thresholds, events, start)
+
+	// kiosk-1 goes stale and kiosk-2 offline in the next two minutes; kiosk-3 is still
+	// online, and nothing is reported again on the following check.
+	for _, now := range []time.Time{start.Add(2 * time.Minute), start.Add(3 * time.Minute)} {
+		if err := w.Check(context.Background(), now); err != nil {
+			t.Fatalf("check: %v", err)
+		}
+	}
+
+	want := map[string]models.DeviceHealthStatus{"kiosk-1": models.DeviceHealthStale, "kiosk-2": models.DeviceHealthOffline}
+	if len(events.events) != len(want) {
+		t.Fatalf("expected %d changes, got %+v", len(want), events.events)
+	}
+	for _, e := range events.events {
+		change := e.data.(models.DeviceHealthChange)
+		if e.eventType != models.StreamEventDeviceHealthChanged || want[change.HostName] != change.To {
+			t.Fatalf("unexpected change %+v", e)
+		}
+	}
+}
@@ -1,5 +1,7 @@
 package services
 
+import "context"
+
 type EmailSender interface {
-	Send(to string, subject string, body string) error
+	Send(ctx context.Context, to string, subject string, body string) error
 }
@@ -0,0 +1,56 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrCampaignNotFound = errors.New("campaign not found")
+	ErrCreativeNotFound = errors.New("creative not found")
+	// ErrForbidden is returned when the caller may not run an operation, such as a
+	// cascade delete by someone who is not an admin.
+	ErrForbidden = errors.New("forbidden")
+)
+
+// ValidationError is returned when the input of an operation is invalid. Code is the
+// machine-readable error code handlers report alongside Message.
+type ValidationError struct {
+	Code    string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return e.Message
+}
+
+func invalid(format string, args ...any) *ValidationError {
+	return &ValidationError{Code: "validation_error", Message: fmt.Sprintf(format, args...)}
+}
+
+// StatusTransitionError is returned when a campaign cannot move from one status to
+// another.
+type StatusTransitionError struct {
+	From string
+	To   string
+}
+
+func (e *StatusTransitionError) Error() string {
+	return fmt.Sprintf("cannot change status from %s to %s", e.From, e.To)
+}
+
+// notFound replaces the repositories' sql.ErrNoRows with the service's own error.
+func notFound(err error, target error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return target
+	}
+	return err
+}
+
+// AdminChecker reports whether the caller of ctx may run admin-only operations;
+// middleware.Admins implements it.
+type AdminChecker interface {
+	IsAdmin(ctx context.Context) bool
+}
@@ -0,0 +1,230 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	authmw "scm/internal/middleware"
+	"scm/internal/models"
+	"scm/internal/repository"
+)
+
+// Publishers fans an event out to several publishers, e.g. the webhook outbox and the
+// dashboard event stream.
+type Publishers []EventPublisher
+
+func (ps Publishers) Publish(ctx context.Context, eventType string, data any) error {
+	var errs []error
+	for _, p := range ps {
+		if p == nil {
+			continue
+		}
+		if err := p.Publish(ctx, eventType, data); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// privateStreamEvents only reach the user whose request raised them, and admins.
+var privateStreamEvents = map[string]bool{
+	models.StreamEventSyncProgress: true,
+}
+
+// StreamEvent is an event as the dashboard stream sends it.
+type StreamEvent struct {
+	ID         string          `json:"id"`
+	Type       string          `json:"type"`
+	OccurredAt time.Time       `json:"occurred_at"`
+	Data       json.RawMessage `json:"data" swaggertype:"object"`
+
+	// audience is the only non-admin user who receives a restricted event: the user
+	// whose request raised a private event, or the owner of the advertiser an event is
+	// about. A restricted event without an audience only reaches admins.
+	audience   string
+	restricted bool
+	seq        uint64
+}
+
+// StreamViewer is who a stream subscription is for.
+type StreamViewer struct {
+	UserID string
+	Admin  bool
+	// Types limits the subscription to these event types; empty means all.
+	Types []string
+}
+
+func (v StreamViewer) sees(e *StreamEvent) bool {
+	if e.restricted && !v.Admin && (e.audience == "" || e.audience != v.UserID) {
+		return false
+	}
+	if len(v.Types) == 0 {
+		return true
+	}
+	for _, t := range v.Types {
+		if t == e.Type {
+			return true
+		}
+	}
+	return false
+}
+
+// streamSubscriberBuffer is how many events a subscriber may fall behind before it is
+// dropped; it resumes from the event buffer when it reconnects.
+const streamSubscriberBuffer = 64
+
+type streamSubscriber struct {
+	viewer StreamViewer
+	ch     chan *StreamEvent
+}
+
+// EventStream keeps the most recent events in memory and pushes new ones to the
+// dashboards subscribed to it, so a dashboard that reconnects with the ID of the last
+// event it saw receives what it missed. Event IDs are "<boot>-<seq>", where boot
+// identifies the process, so IDs from before a restart are recognised as unknown.
+type EventStream struct {
+	mu     sync.Mutex
+	boot   string
+	seq    uint64
+	buffer []*StreamEvent
+	size   int
+	subs   map[*streamSubscriber]struct{}
+}
+
+// NewEventStream returns a stream that keeps the last bufferSize events for resuming.
+func NewEventStream(bufferSize int) *EventStream {
+	return &EventStream{
+		boot: strconv.FormatInt(time.Now().UnixMilli(), 36),
+		size: max(bufferSize, 1),
+		subs: map[*streamSubscriber]struct{}{},
+	}
+}
+
+// Publish pushes an event to the subscribers allowed to see it. It never blocks on a
+// subscriber: one that has fallen too far behind is disconnected instead.
+func (s *EventStream) Publish(ctx context.Context, eventType string, data any) error {
+	if privateStreamEvents[eventType] {
+		return s.publish(eventType, data, authmw.UserIDFromContext(ctx), true)
+	}
+	return s.publish(eventType, data, "", false)
+}
+
+// PublishTo pushes an event only userID and admins may see, such as one about the
+// campaigns of an advertiser userID owns. An empty userID leaves it to admins.
+func (s *EventStream) PublishTo(ctx context.Context, userID string, eventType string, data any) error {
+	return s.publish(eventType, data, userID, true)
+}
+
+func (s *EventStream) publish(eventType string, data any, audience string, restricted bool) error {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("marshal %s event: %w", eventType, err)
+	}
+	e := &StreamEvent{Type: eventType, OccurredAt: time.Now().UTC(), Data: raw, audience: audience, restricted: restricted}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.seq++
+	e.seq = s.seq
+	e.ID = s.boot + "-" + strconv.FormatUint(e.seq, 10)
+	if len(s.buffer) == s.size {
+		s.buffer = append(s.buffer[:0], s.buffer[1:]...)
+	}
+	s.buffer = append(s.buffer, e)
+
+	for sub := range s.subs {
+		if !sub.viewer.sees(e) {
+			continue
+		}
+		select {
+		case sub.ch <- e:
+		default:
+			delete(s.subs, sub)
+			close(sub.ch)
+		}
+	}
+	return nil
+}
+
+// publishTo pushes an event about an advertiser's records to stream, which may be nil,
+// after the change it reports has been saved, so a failure is logged rather than
+// failing the change.
+func publishTo(ctx context.Context, stream *EventStream, owner string, eventType string, data any) {
+	if stream == nil {
+		return
+	}
+	if err := stream.PublishTo(ctx, owner, eventType, data); err != nil {
+		slog.ErrorContext(ctx, "failed to publish event", "event_type", eventType, "error", err)
+	}
+}
+
+// QueueAdvertiserEvent queues an event about a record of the advertiser for webhook
+// subscribers in the transaction repos are bound to, and returns the ID of the user
+// who created the advertiser, the only non-admin the stream shows the event to once
+// the transaction has committed. The owner of a deleted advertiser is "".
+func QueueAdvertiserEvent(ctx context.Context, repos repository.Repositories, advertiserID string, eventType string, data any) (string, error) {
+	var owner string
+	advertiser, err := repos.Advertisers.GetByID(ctx, advertiserID)
+	switch {
+	case err == nil:
+		owner = advertiser.CreatedBy
+	case !errors.Is(err, sql.ErrNoRows):
+		return "", fmt.Errorf("look up owner of advertiser %s: %w", advertiserID, err)
+	}
+	if err := EnqueueWebhookEvent(ctx, repos.Webhooks, eventType, data); err != nil {
+		return "", err
+	}
+	return owner, nil
+}
+
+// Subscribe registers viewer for new events. When lastEventID is set, the buffered
+// events after it are returned as the backlog; resumed is false when that event is
+// no longer buffered or unknown, so the client may have missed events and should
+// reload. The channel is closed when the subscriber falls behind; call cancel when
+// done.
+func (s *EventStream) Subscribe(viewer StreamViewer, lastEventID string) (backlog []*StreamEvent, events <-chan *StreamEvent, resumed bool, cancel func()) {
+	sub := &streamSubscriber{viewer: viewer, ch: make(chan *StreamEvent, streamSubscriberBuffer)}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	resumed = true
+	if lastEventID != "" {
+		after, ok := s.parseID(lastEventID)
+		if !ok || (len(s.buffer) > 0 && after < s.buffer[0].seq-1) || after > s.seq {
+			resumed = false
+		}
+		for _, e := range s.buffer {
+			if resumed && e.seq > after && viewer.sees(e) {
+				backlog = append(backlog, e)
+			}
+		}
+	}
+	s.subs[sub] = struct{}{}
+
+	return backlog, sub.ch, resumed, func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if _, ok := s.subs[sub]; ok {
+			delete(s.subs, sub)
+			close(sub.ch)
+		}
+	}
+}
+
+// parseID returns the sequence number of an ID this process issued.
+func (s *EventStream) parseID(id string) (uint64, bool) {
+	boot, seq, ok := strings.Cut(id, "-")
+	if !ok || boot != s.boot {
+		return 0, false
+	}
+	n, err := strconv.ParseUint(seq, 10, 64)
+	return n, err == nil
+}
@@ -0,0 +1,109 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	authmw "scm/internal/middleware"
+	"scm/internal/models"
+)
+
+func streamTypes(events []*StreamEvent) []string {
+	types := []string{}
+	for _, e := range events {
+		types = append(types, e.Type)
+	}
+	return types
+}
+
+func TestEventStreamResumesFromBuffer(t *testing.T) {
+	ctx := context.Background()
+	s := NewEventStream(2)
+
+	_ = s.Publish(ctx, models.WebhookEventCampaignActivated, map[string]string{"id": "c1"})
+	_, _, _, cancel := s.Subscribe(StreamViewer{}, "")
+	cancel()
+	_ = s.Publish(ctx, models.WebhookEventCreativeUploaded, map[string]string{"id": "cr1"})
+	_ = s.Publish(ctx, models.WebhookEventCampaignCompleted, map[string]string{"id": "c1"})
+
+	first := s.buffer[0].ID
+	backlog, _, resumed, cancel := s.Subscribe(StreamViewer{}, first)
+	defer cancel()
+	if !resumed || len(backlog) != 1 || backlog[0].Type != models.WebhookEventCampaignCompleted {
+		t.Fatalf("expected to resume with campaign.completed, got %v (resumed %v)", streamTypes(backlog), resumed)
+	}
+
+	// The first event fell out of the two-event buffer, and IDs of another process
+	// are unknown; either way the client has to reload.
+	for _, id := range []string{s.boot + "-0", "otherboot-3", "garbage"} {
+		backlog, _, resumed, cancel := s.Subscribe(StreamViewer{}, id)
+		cancel()
+		if resumed || len(backlog) != 0 {
+			t.Fatalf("%s: expected a reset, got %v (resumed %v)", id, streamTypes(backlog), resumed)
+		}
+	}
+}
+
+func TestEventStreamFiltersByViewer(t *testing.T) {
+	s := NewEventStream(10)
+	_, alice, _, cancelAlice := s.Subscribe(StreamViewer{UserID: "alice"}, "")
+	defer cancelAlice()
+	_, bob, _, cancelBob := s.Subscribe(StreamViewer{UserID: "bob"}, "")
+	defer cancelBob()
+	_, admin, _, cancelAdmin := s.Subscribe(StreamViewer{UserID: "root", Admin: true}, "")
+	defer cancelAdmin()
+	_, uploads, _, cancelUploads := s.Subscribe(StreamViewer{UserID: "alice", Types: []string{models.WebhookEventCreativeUploaded}}, "")
+	defer cancelUploads()
+
+	aliceCtx := context.WithValue(context.Background(), authmw.CtxUserID, "alice")
+	_ = s.Publish(aliceCtx, models.StreamEventSyncProgress, map[string]int{"projects_done": 1})
+	// Events about an advertiser only reach its owner, or only admins when it has none.
+	_ = s.PublishTo(aliceCtx, "alice", models.WebhookEventCreativeUploaded, map[string]string{"id": "cr1"})
+	_ = s.PublishTo(aliceCtx, "", models.WebhookEventCampaignCompleted, map[string]string{"id": "c1"})
+	_ = s.Publish(aliceCtx, models.StreamEventDeviceHealthChanged, map[string]string{"host_name": "d1"})
+
+	drain := func(ch <-chan *StreamEvent) []string {
+		var got []*StreamEvent
+		for {
+			select {
+			case e := <-ch:
+				got = append(got, e)
+			case <-time.After(10 * time.Millisecond):
+				return streamTypes(got)
+			}
+		}
+	}
+	cases := []struct {
+		name string
+		ch   <-chan *StreamEvent
+		want int
+	}{
+		{"alice", alice, 3},
+		{"bob", bob, 1},
+		{"admin", admin, 4},
+		{"uploads", uploads, 1},
+	}
+	for _, c := range cases {
+		if got := drain(c.ch); len(got) != c.want {
+			t.Errorf("%s: expected %d events, got %v", c.name, c.want, got)
+		}
+	}
+}
+
+func TestEventStreamDropsSlowSubscribers(t *testing.T) {
+	s := NewEventStream(streamSubscriberBuffer * 2)
+	_, events, _, cancel := s.Subscribe(StreamViewer{}, "")
+	defer cancel()
+
+	for range streamSubscriberBuffer + 1 {
+		_ = s.Publish(context.Background(), models.WebhookEventCreativeUploaded, nil)
+	}
+	n := 0
+	for range events {
+		n++
+	}
+	if n != streamSubscriberBuffer {
+		t.Fatalf("expected the %d buffered events before the channel closed, got %d", streamSubscriberBuffer, n)
+	}
+}
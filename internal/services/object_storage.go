@@ -0,0 +1,103 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"scm/internal/metrics"
+	"scm/internal/tracing"
+)
+
+// ObjectStorage removes stored creative files.
+type ObjectStorage interface {
+	DeleteObjects(ctx context.Context, keys []string) error
+}
+
+// ObjectUploader stores creative files.
+type ObjectUploader interface {
+	PutObject(ctx context.Context, key string, body io.Reader) error
+}
+
+// s3DeleteBatchSize is the most keys S3 accepts in one DeleteObjects call.
+const s3DeleteBatchSize = 1000
+
+type S3ObjectStorage struct {
+	Client *s3.Client
+	Bucket string
+}
+
+func (s *S3ObjectStorage) DeleteObjects(ctx context.Context, keys []string) error {
+	for start := 0; start < len(keys); start += s3DeleteBatchSize {
+		end := min(start+s3DeleteBatchSize, len(keys))
+
+		objects := make([]types.ObjectIdentifier, 0, end-start)
+		for _, key := range keys[start:end] {
+			objects = append(objects, types.ObjectIdentifier{Key: aws.String(key)})
+		}
+
+		out, err := s.Client.DeleteObjects(ctx, &s3.DeleteObjectsInput{
+			Bucket: aws.String(s.Bucket),
+			Delete: &types.Delete{Objects: objects, Quiet: aws.Bool(true)},
+		})
+		if err != nil {
+			return fmt.Errorf("delete objects: %w", err)
+		}
+		if len(out.Errors) > 0 {
+			first := out.Errors[0]
+			return fmt.Errorf("delete objects: %d failed, first %s: %s", len(out.Errors), aws.ToString(first.Key), aws.ToString(first.Message))
+		}
+	}
+	return nil
+}
+
+// countingReader counts the bytes read through it.
+type countingReader struct {
+	io.Reader
+	n int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	r.n += int64(n)
+	return n, err
+}
+
+func (s *S3ObjectStorage) PutObject(ctx context.Context, key string, body io.Reader) error {
+	ctx, span := tracing.Tracer().Start(ctx, "S3 PutObject", trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("s3.bucket", s.Bucket), attribute.String("s3.key", key)))
+	defer span.End()
+
+	start := time.Now()
+	counted := &countingReader{Reader: body}
+	_, err := manager.NewUploader(s.Client).Upload(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(s.Bucket),
+		Key:    aws.String(key),
+		Body:   counted,
+	})
+	metrics.ObserveUpload(counted.n, start, err)
+	span.SetAttributes(attribute.Int64("s3.upload.bytes", counted.n))
+	tracing.RecordError(span, err)
+	if err != nil {
+		return fmt.Errorf("put object %s: %w", key, err)
+	}
+	return nil
+}
+
+// removeStoredObjects deletes the stored objects of records a cascade delete removed.
+func removeStoredObjects(ctx context.Context, storage ObjectStorage, keys []string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	if storage == nil {
+		return fmt.Errorf("object storage not configured")
+	}
+	return storage.DeleteObjects(ctx, keys)
+}
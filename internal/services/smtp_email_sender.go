@@ -1,11 +1,16 @@
 package services
 
 import (
+	"context"
 	"crypto/tls"
 	"fmt"
 	"net"
 	"net/smtp"
 	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"scm/internal/tracing"
 )
 
 type SMTPSender struct {
@@ -17,7 +22,17 @@ type SMTPSender struct {
 	UseTLS bool
 }
 
-func (s *SMTPSender) Send(to string, subject string, body string) error {
+func (s *SMTPSender) Send(ctx context.Context, to string, subject string, body string) error {
+	_, span := tracing.Tracer().Start(ctx, "SMTP send", trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("server.address", s.Host), attribute.String("server.port", s.Port)))
+	defer span.End()
+
+	err := s.send(to, subject, body)
+	tracing.RecordError(span, err)
+	return err
+}
+
+func (s *SMTPSender) send(to string, subject string, body string) error {
 	addr := net.JoinHostPort(s.Host, s.Port)
 
 	contentType := "text/plain; charset=\"utf-8\""
@@ -0,0 +1,188 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"scm/internal/models"
+	"scm/internal/repository"
+)
+
+// EventPublisher raises lifecycle events; WebhookService implements it by queueing
+// them for the webhook subscribers.
+type EventPublisher interface {
+	Publish(ctx context.Context, eventType string, data any) error
+}
+
+// NewWebhookEvent wraps data in a new event of the given type.
+func NewWebhookEvent(eventType string, data any) (*models.WebhookEvent, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("marshal %s event: %w", eventType, err)
+	}
+	return &models.WebhookEvent{
+		ID:         uuid.NewString(),
+		Type:       eventType,
+		OccurredAt: time.Now().UTC(),
+		Data:       raw,
+	}, nil
+}
+
+// EnqueueWebhookEvent queues an event in repo. Callers inside a unit of work pass the
+// transaction's repository, so the event is only queued if the change commits.
+func EnqueueWebhookEvent(ctx context.Context, repo repository.WebhookRepository, eventType string, data any) error {
+	event, err := NewWebhookEvent(eventType, data)
+	if err != nil {
+		return err
+	}
+	_, err = repo.Enqueue(ctx, event)
+	return err
+}
+
+const (
+	webhookBatchSize  = 50
+	webhookLease      = 5 * time.Minute
+	webhookMaxBackoff = 6 * time.Hour
+)
+
+// WebhookService queues lifecycle events for their subscribers and delivers them from
+// the outbox, retrying failed deliveries with exponential backoff until they run out
+// of attempts and are dead.
+type WebhookService struct {
+	repo        repository.WebhookRepository
+	httpClient  *http.Client
+	maxAttempts int
+	backoff     time.Duration
+	now         func() time.Time
+}
+
+// NewWebhookService returns a service delivering each event up to maxAttempts times,
+// waiting backoff after the first failure and twice as long after each further one.
+func NewWebhookService(repo repository.WebhookRepository, maxAttempts int, backoff time.Duration) *WebhookService {
+	return &WebhookService{
+		repo:        repo,
+		httpClient:  newWebhookHTTPClient(),
+		maxAttempts: max(maxAttempts, 1),
+		backoff:     max(backoff, time.Second),
+		now:         time.Now,
+	}
+}
+
+// SetHTTPClient replaces the client deliveries are sent with, which by default only
+// connects to public addresses.
+func (s *WebhookService) SetHTTPClient(client *http.Client) {
+	if client != nil {
+		s.httpClient = client
+	}
+}
+
+// Publish queues an event for the subscribers to its type. Events that are not webhook
+// event types, like sync.progress, are ignored.
+func (s *WebhookService) Publish(ctx context.Context, eventType string, data any) error {
+	if !slices.Contains(models.WebhookEventTypes, eventType) {
+		return nil
+	}
+	return EnqueueWebhookEvent(ctx, s.repo, eventType, data)
+}
+
+// DeliverDue delivers the pending deliveries that are due, a batch at a time, and
+// returns how many succeeded. Failed deliveries are rescheduled or marked dead; only
+// errors reading or updating the outbox are returned.
+func (s *WebhookService) DeliverDue(ctx context.Context) (int, error) {
+	delivered := 0
+	for {
+		batch, err := s.repo.ClaimDue(ctx, s.now(), webhookBatchSize, webhookLease)
+		if err != nil {
+			return delivered, err
+		}
+
+		var errs []error
+		for _, d := range batch {
+			ok, err := s.deliver(ctx, d)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("delivery %d: %w", d.ID, err))
+			}
+			if ok {
+				delivered++
+			}
+		}
+		if err := errors.Join(errs...); err != nil || len(batch) < webhookBatchSize {
+			return delivered, err
+		}
+	}
+}
+
+// deliver POSTs one delivery and records the outcome, reporting whether it succeeded.
+func (s *WebhookService) deliver(ctx context.Context, d *models.WebhookDelivery) (bool, error) {
+	statusCode, postErr := s.post(ctx, d)
+	if postErr == nil {
+		return true, s.repo.MarkDelivered(ctx, d.ID, statusCode)
+	}
+
+	var code *int
+	if statusCode != 0 {
+		code = &statusCode
+	}
+	var retryAt *time.Time
+	if attempts := d.Attempts + 1; attempts < s.maxAttempts {
+		at := s.now().Add(s.retryDelay(attempts))
+		retryAt = &at
+	} else {
+		slog.WarnContext(ctx, "webhook delivery is dead", "delivery_id", d.ID, "event_type", d.EventType, "url", d.URL, "attempts", attempts, "error", postErr)
+	}
+	return false, s.repo.MarkFailed(ctx, d.ID, code, postErr.Error(), retryAt)
+}
+
+// retryDelay is how long to wait after the given number of failed attempts.
+func (s *WebhookService) retryDelay(attempts int) time.Duration {
+	delay := s.backoff
+	for i := 1; i < attempts && delay < webhookMaxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, webhookMaxBackoff)
+}
+
+func (s *WebhookService) post(ctx context.Context, d *models.WebhookDelivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.URL, bytes.NewReader(d.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-SCM-Event", d.EventType)
+	req.Header.Set("X-SCM-Event-ID", d.EventID)
+	req.Header.Set("X-SCM-Delivery", strconv.FormatInt(d.ID, 10))
+	req.Header.Set("X-SCM-Signature", SignWebhookPayload(d.Secret, s.now(), d.Payload))
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return resp.StatusCode, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return resp.StatusCode, nil
+}
+
+// publishEvent raises an event for a change that is already saved, so a failure to
+// queue it is logged rather than failing the operation.
+func publishEvent(ctx context.Context, events EventPublisher, eventType string, data any) {
+	if events == nil {
+		return
+	}
+	if err := events.Publish(ctx, eventType, data); err != nil {
+		slog.ErrorContext(ctx, "failed to publish event", "event_type", eventType, "error", err)
+	}
+}
@@ -0,0 +1,166 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"scm/internal/models"
+	"scm/internal/repository"
+)
+
+type memWebhookRepo struct {
+	repository.WebhookRepository
+	deliveries []*models.WebhookDelivery
+	queued     []string
+}
+
+func (m *memWebhookRepo) Enqueue(ctx context.Context, event *models.WebhookEvent) (int, error) {
+	m.queued = append(m.queued, event.Type)
+	return 1, nil
+}
+
+// memUnitOfWork runs fn on fixed repositories, without a transaction.
+type memUnitOfWork struct {
+	repos repository.Repositories
+}
+
+func (u memUnitOfWork) Do(ctx context.Context, opts *sql.TxOptions, fn func(ctx context.Context, repos repository.Repositories) error) error {
+	return fn(ctx, u.repos)
+}
+
+func (m *memWebhookRepo) ClaimDue(ctx context.Context, now time.Time, limit int, lease time.Duration) ([]*models.WebhookDelivery, error) {
+	var due []*models.WebhookDelivery
+	for _, d := range m.deliveries {
+		if d.Status == models.WebhookDeliveryPending && !d.NextAttemptAt.After(now) && len(due) < limit {
+			d.NextAttemptAt = now.Add(lease)
+			claimed := *d
+			due = append(due, &claimed)
+		}
+	}
+	return due, nil
+}
+
+func (m *memWebhookRepo) find(id int64) *models.WebhookDelivery {
+	for _, d := range m.deliveries {
+		if d.ID == id {
+			return d
+		}
+	}
+	return nil
+}
+
+func (m *memWebhookRepo) MarkDelivered(ctx context.Context, id int64, statusCode int) error {
+	d := m.find(id)
+	d.Status = models.WebhookDeliveryDelivered
+	d.Attempts++
+	d.LastStatusCode = &statusCode
+	return nil
+}
+
+func (m *memWebhookRepo) MarkFailed(ctx context.Context, id int64, statusCode *int, lastError string, retryAt *time.Time) error {
+	d := m.find(id)
+	d.Attempts++
+	d.LastStatusCode = statusCode
+	d.LastError = &lastError
+	if retryAt == nil {
+		d.Status = models.WebhookDeliveryDead
+	} else {
+		d.NextAttemptAt = *retryAt
+	}
+	return nil
+}
+
+func TestWebhookServiceDeliverDueSignsPayload(t *testing.T) {
+	now := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	payload := json.RawMessage(`{"id":"evt-1","type":"sync.completed","data":{}}`)
+
+	var gotSignature, gotEvent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotSignature = r.Header.Get("X-SCM-Signature")
+		gotEvent = r.Header.Get("X-SCM-Event")
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	repo := &memWebhookRepo{deliveries: []*models.WebhookDelivery{{
+		ID: 1, EventID: "evt-1", EventType: models.WebhookEventSyncCompleted, Payload: payload,
+		Status: models.WebhookDeliveryPending, NextAttemptAt: now, URL: server.URL, Secret: "0123456789abcdef",
+	}}}
+	s := NewWebhookService(repo, 3, 30*time.Second)
+	s.SetHTTPClient(server.Client()) // the test server listens on loopback
+	s.now = func() time.Time { return now }
+
+	delivered, err := s.DeliverDue(context.Background())
+	if err != nil || delivered != 1 {
+		t.Fatalf("expected 1 delivered, got %d (%v)", delivered, err)
+	}
+	if repo.deliveries[0].Status != models.WebhookDeliveryDelivered {
+		t.Fatalf("expected delivered, got %+v", repo.deliveries[0])
+	}
+	if gotEvent != models.WebhookEventSyncCompleted {
+		t.Fatalf("unexpected event header %q", gotEvent)
+	}
+	if want := SignWebhookPayload("0123456789abcdef", now, payload); gotSignature != want {
+		t.Fatalf("expected signature %q, got %q", want, gotSignature)
+	}
+}
+
+func TestWebhookServiceDeliverDueBacksOffThenDies(t *testing.T) {
+	now := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		http.Error(w, "receiver down", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	repo := &memWebhookRepo{deliveries: []*models.WebhookDelivery{{
+		ID: 1, EventID: "evt-1", EventType: models.WebhookEventCampaignActivated, Payload: json.RawMessage(`{}`),
+		Status: models.WebhookDeliveryPending, NextAttemptAt: now, URL: server.URL, Secret: "0123456789abcdef",
+	}}}
+	s := NewWebhookService(repo, 3, 30*time.Second)
+	s.SetHTTPClient(server.Client()) // the test server listens on loopback
+	s.now = func() time.Time { return now }
+	d := repo.deliveries[0]
+
+	for i, wantDelay := range []time.Duration{30 * time.Second, time.Minute} {
+		if delivered, err := s.DeliverDue(context.Background()); err != nil || delivered != 0 {
+			t.Fatalf("attempt %d: expected nothing delivered, got %d (%v)", i+1, delivered, err)
+		}
+		if d.Status != models.WebhookDeliveryPending || !d.NextAttemptAt.Equal(now.Add(wantDelay)) {
+			t.Fatalf("attempt %d: expected retry in %s, got %+v", i+1, wantDelay, d)
+		}
+		if d.LastStatusCode == nil || *d.LastStatusCode != http.StatusServiceUnavailable || !strings.Contains(*d.LastError, "receiver down") {
+			t.Fatalf("attempt %d: outcome not recorded: %+v", i+1, d)
+		}
+
+		// Nothing is sent before the retry is due.
+		if _, err := s.DeliverDue(context.Background()); err != nil || calls != i+1 {
+			t.Fatalf("attempt %d: expected %d call(s), got %d (%v)", i+1, i+1, calls, err)
+		}
+		now = d.NextAttemptAt
+	}
+
+	if _, err := s.DeliverDue(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if d.Status != models.WebhookDeliveryDead || d.Attempts != 3 || calls != 3 {
+		t.Fatalf("expected dead after 3 attempts, got %+v (%d calls)", d, calls)
+	}
+}
+
+func TestWebhookServiceRetryDelayIsCapped(t *testing.T) {
+	s := NewWebhookService(&memWebhookRepo{}, 50, time.Minute)
+	if got := s.retryDelay(3); got != 4*time.Minute {
+		t.Fatalf("expected 4m after 3 attempts, got %s", got)
+	}
+	if got := s.retryDelay(40); got != webhookMaxBackoff {
+		t.Fatalf("expected the %s cap, got %s", webhookMaxBackoff, got)
+	}
+}
@@ -0,0 +1,84 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ErrWebhookTargetNotAllowed is returned for webhook URLs that point into the API's own
+// network: loopback, private, link-local (including cloud metadata endpoints) and other
+// non-public addresses.
+var ErrWebhookTargetNotAllowed = errors.New("webhook target is not a public address")
+
+// sharedAddressSpace is carrier-grade NAT (RFC 6598), which netip does not treat as
+// private.
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+func publicAddr(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	return ip.IsValid() &&
+		!ip.IsLoopback() &&
+		!ip.IsPrivate() &&
+		!ip.IsLinkLocalUnicast() &&
+		!ip.IsLinkLocalMulticast() &&
+		!ip.IsInterfaceLocalMulticast() &&
+		!ip.IsMulticast() &&
+		!ip.IsUnspecified() &&
+		!sharedAddressSpace.Contains(ip)
+}
+
+// CheckWebhookURL validates a subscriber URL at registration: it must be http or https,
+// and its host must not be localhost or a non-public IP address. Host names are checked
+// again when the dispatcher connects, against the addresses they resolve to then.
+func CheckWebhookURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("webhook URL must be http or https, got %q", u.Scheme)
+	}
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	if host == "" {
+		return errors.New("webhook URL has no host")
+	}
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return fmt.Errorf("%w: %s", ErrWebhookTargetNotAllowed, host)
+	}
+	if ip, err := netip.ParseAddr(host); err == nil && !publicAddr(ip) {
+		return fmt.Errorf("%w: %s", ErrWebhookTargetNotAllowed, host)
+	}
+	return nil
+}
+
+// dialPublicOnly refuses connections to non-public addresses. It runs after the host
+// name is resolved, so names that resolve (or are rebound) to internal addresses are
+// caught too, as are redirects to them.
+func dialPublicOnly(_ string, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip, err := netip.ParseAddr(host)
+	if err != nil || !publicAddr(ip) {
+		return fmt.Errorf("%w: %s", ErrWebhookTargetNotAllowed, host)
+	}
+	return nil
+}
+
+// newWebhookHTTPClient returns the client deliveries are sent with: it only connects to
+// public addresses and does not use a proxy, which would hide the address dialled.
+func newWebhookHTTPClient() *http.Client {
+	dialer := &net.Dialer{Timeout: 10 * time.Second, Control: dialPublicOnly}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{Timeout: 10 * time.Second, Transport: transport}
+}
@@ -0,0 +1,62 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"scm/internal/models"
+)
+
+func TestCheckWebhookURL(t *testing.T) {
+	for raw, allowed := range map[string]bool{
+		"https://hooks.example.com/scm":        true,
+		"http://203.0.113.10:8080/hook":        true,
+		"ftp://hooks.example.com/scm":          false,
+		"http://localhost:9000/hook":           false,
+		"http://api.localhost/hook":            false,
+		"http://127.0.0.1/hook":                false,
+		"http://169.254.169.254/latest/meta":   false,
+		"http://10.0.0.5/hook":                 false,
+		"http://192.168.1.1/hook":              false,
+		"http://100.64.0.1/hook":               false,
+		"http://[::1]/hook":                    false,
+		"http://[fe80::1]/hook":                false,
+		"http://[::ffff:169.254.169.254]/hook": false,
+		"http://0.0.0.0/hook":                  false,
+	} {
+		if err := CheckWebhookURL(raw); (err == nil) != allowed {
+			t.Errorf("CheckWebhookURL(%q) = %v, want allowed=%v", raw, err, allowed)
+		}
+	}
+}
+
+func TestWebhookDispatcherRefusesInternalAddresses(t *testing.T) {
+	now := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+	}))
+	defer server.Close()
+
+	repo := &memWebhookRepo{deliveries: []*models.WebhookDelivery{{
+		ID: 1, EventID: "evt-1", EventType: models.WebhookEventSyncCompleted, Payload: json.RawMessage(`{}`),
+		Status: models.WebhookDeliveryPending, NextAttemptAt: now, URL: server.URL, Secret: "0123456789abcdef",
+	}}}
+	s := NewWebhookService(repo, 3, 30*time.Second)
+	s.now = func() time.Time { return now }
+
+	if delivered, err := s.DeliverDue(context.Background()); err != nil || delivered != 0 {
+		t.Fatalf("expected nothing delivered, got %d (%v)", delivered, err)
+	}
+	if calls != 0 {
+		t.Fatalf("expected the loopback server not to be called, got %d call(s)", calls)
+	}
+	if d := repo.deliveries[0]; d.LastError == nil || !strings.Contains(*d.LastError, ErrWebhookTargetNotAllowed.Error()) {
+		t.Fatalf("expected the refused dial to be recorded, got %+v", d)
+	}
+}
@@ -0,0 +1,53 @@
+package tracing
+
+import (
+	"context"
+	"database/sql/driver"
+	"strings"
+
+	"github.com/XSAM/otelsql"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.43.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Repository starts the span of a repository method, named "type.Method", e.g.
+// "creativeRepository.ListByDevice". The statements the method runs are its children.
+func Repository(ctx context.Context, method string) (context.Context, trace.Span) {
+	return Tracer().Start(ctx, method, trace.WithAttributes(semconv.DBSystemNamePostgreSQL))
+}
+
+// SQLOptions are the otelsql options the database is opened with. Each statement gets a
+// span named after its SQL operation (SELECT, INSERT, ...), under the span of the
+// repository method that ran it.
+func SQLOptions() []otelsql.Option {
+	return []otelsql.Option{
+		otelsql.WithAttributes(semconv.DBSystemNamePostgreSQL),
+		otelsql.WithSpanNameFormatter(func(_ context.Context, method otelsql.Method, query string) string {
+			if op := sqlOperation(query); op != "" {
+				return op
+			}
+			return string(method)
+		}),
+		otelsql.WithAttributesGetter(func(_ context.Context, _ otelsql.Method, query string, _ []driver.NamedValue) []attribute.KeyValue {
+			if op := sqlOperation(query); op != "" {
+				return []attribute.KeyValue{semconv.DBOperationName(op)}
+			}
+			return nil
+		}),
+		otelsql.WithSpanOptions(otelsql.SpanOptions{
+			OmitRows:             true,
+			OmitConnResetSession: true,
+			DisableErrSkip:       true,
+		}),
+	}
+}
+
+// sqlOperation returns the statement's leading keyword, e.g. "SELECT".
+func sqlOperation(query string) string {
+	fields := strings.Fields(query)
+	if len(fields) == 0 {
+		return ""
+	}
+	return strings.ToUpper(strings.Trim(fields[0], "("))
+}
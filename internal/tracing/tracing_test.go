@@ -0,0 +1,100 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	chimw "github.com/go-chi/chi/v5/middleware"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	rec := tracetest.NewSpanRecorder()
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec)))
+	t.Cleanup(func() { otel.SetTracerProvider(prev) })
+	return rec
+}
+
+func TestMiddlewareNamesSpanAfterRoute(t *testing.T) {
+	rec := recordSpans(t)
+
+	r := chi.NewRouter()
+	r.Use(chimw.RequestID)
+	r.Use(Middleware)
+	r.Route("/api/v1/campaigns", func(r chi.Router) {
+		r.Get("/{id}", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		})
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/campaigns/42", nil)
+	req.Header.Set(chimw.RequestIDHeader, "req-123")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := rec.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("got %d spans, want 1", len(spans))
+	}
+	span := spans[0]
+	if got, want := span.Name(), "GET /api/v1/campaigns/{id}"; got != want {
+		t.Errorf("span name = %q, want %q", got, want)
+	}
+	if span.Status().Code != codes.Error {
+		t.Errorf("status = %v, want error for a 500", span.Status().Code)
+	}
+	attrs := map[string]string{}
+	for _, kv := range span.Attributes() {
+		attrs[string(kv.Key)] = kv.Value.Emit()
+	}
+	if attrs[string(RequestIDKey)] != "req-123" {
+		t.Errorf("request id = %q, want req-123", attrs[string(RequestIDKey)])
+	}
+	if attrs["http.route"] != "/api/v1/campaigns/{id}" {
+		t.Errorf("http.route = %q", attrs["http.route"])
+	}
+	if attrs["http.response.status_code"] != "500" {
+		t.Errorf("status code = %q, want 500", attrs["http.response.status_code"])
+	}
+}
+
+func TestRepositorySpanParentsStatements(t *testing.T) {
+	rec := recordSpans(t)
+
+	ctx, span := Repository(context.Background(), "campaignRepository.GetByID")
+	_, stmt := Tracer().Start(ctx, "SELECT")
+	stmt.End()
+	span.End()
+
+	spans := rec.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("got %d spans, want 2", len(spans))
+	}
+	if spans[1].Name() != "campaignRepository.GetByID" {
+		t.Errorf("span name = %q, want campaignRepository.GetByID", spans[1].Name())
+	}
+	if spans[0].Parent().SpanID() != spans[1].SpanContext().SpanID() {
+		t.Errorf("statement span is not a child of the repository span")
+	}
+}
+
+func TestSQLOperation(t *testing.T) {
+	tests := map[string]string{
+		"select * from campaigns":                     "SELECT",
+		"\n\t\tWITH due AS (SELECT 1) UPDATE x SET y": "WITH",
+		"(SELECT 1) UNION (SELECT 2)":                 "SELECT",
+		"   ":                                         "",
+	}
+	for query, want := range tests {
+		if got := sqlOperation(query); got != want {
+			t.Errorf("sqlOperation(%q) = %q, want %q", query, got, want)
+		}
+	}
+}
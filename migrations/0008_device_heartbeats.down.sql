@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_device_heartbeats_received_at;
+DROP TABLE IF EXISTS device_heartbeats;
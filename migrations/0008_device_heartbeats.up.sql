@@ -0,0 +1,12 @@
+-- Latest heartbeat reported by each device
+CREATE TABLE device_heartbeats (
+    host_name TEXT PRIMARY KEY REFERENCES devices(host_name) ON DELETE CASCADE,
+    uptime_seconds BIGINT NOT NULL DEFAULT 0,
+    playlist_version TEXT NOT NULL DEFAULT '',
+    free_disk_bytes BIGINT NOT NULL DEFAULT 0,
+    last_creative_id TEXT,
+    last_creative_played_at TIMESTAMP WITH TIME ZONE,
+    received_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_device_heartbeats_received_at ON device_heartbeats(received_at);
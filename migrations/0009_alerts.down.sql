@@ -0,0 +1,7 @@
+DROP TRIGGER IF EXISTS alert_rules_updated_at_trigger ON alert_rules;
+DROP INDEX IF EXISTS idx_alerts_first_triggered_at;
+DROP INDEX IF EXISTS idx_alerts_status;
+DROP INDEX IF EXISTS idx_alerts_rule_id;
+DROP INDEX IF EXISTS idx_alerts_dedup_key_unresolved;
+DROP TABLE IF EXISTS alerts;
+DROP TABLE IF EXISTS alert_rules;
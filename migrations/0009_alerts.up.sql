@@ -0,0 +1,45 @@
+-- Alert rules: when to raise an alert and where to deliver it
+CREATE TABLE alert_rules (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    type TEXT NOT NULL CHECK (type IN ('venue_offline', 'sync_errors')),
+    venue_id INTEGER REFERENCES venues(id) ON DELETE CASCADE,
+    offline_devices INTEGER NOT NULL DEFAULT 0,
+    offline_minutes INTEGER NOT NULL DEFAULT 0,
+    email_recipients TEXT[] NOT NULL DEFAULT '{}',
+    webhook_url TEXT,
+    webhook_secret TEXT,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    created_by TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- Alert history
+CREATE TABLE alerts (
+    id SERIAL PRIMARY KEY,
+    rule_id INTEGER NOT NULL REFERENCES alert_rules(id) ON DELETE CASCADE,
+    dedup_key TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'open' CHECK (status IN ('open', 'acknowledged', 'resolved')),
+    title TEXT NOT NULL,
+    details JSONB NOT NULL DEFAULT '{}',
+    venue_id INTEGER REFERENCES venues(id) ON DELETE SET NULL,
+    trigger_count INTEGER NOT NULL DEFAULT 1,
+    first_triggered_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    last_triggered_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    acknowledged_at TIMESTAMP WITH TIME ZONE,
+    acknowledged_by TEXT,
+    resolved_at TIMESTAMP WITH TIME ZONE,
+    notified_at TIMESTAMP WITH TIME ZONE
+);
+
+-- At most one unresolved alert per dedup key
+CREATE UNIQUE INDEX idx_alerts_dedup_key_unresolved ON alerts(dedup_key) WHERE status <> 'resolved';
+CREATE INDEX idx_alerts_rule_id ON alerts(rule_id);
+CREATE INDEX idx_alerts_status ON alerts(status);
+CREATE INDEX idx_alerts_first_triggered_at ON alerts(first_triggered_at);
+
+CREATE TRIGGER alert_rules_updated_at_trigger
+    BEFORE UPDATE ON alert_rules
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
@@ -0,0 +1 @@
+ALTER TABLE venues DROP COLUMN IF EXISTS smart_filter;
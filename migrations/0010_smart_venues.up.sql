@@ -0,0 +1,2 @@
+-- Smart venues keep their device membership in sync with a device filter
+ALTER TABLE venues ADD COLUMN smart_filter JSONB;
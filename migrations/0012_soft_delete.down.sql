@@ -0,0 +1,13 @@
+DROP INDEX IF EXISTS idx_advertisers_name_live;
+DELETE FROM creatives WHERE deleted_at IS NOT NULL;
+DELETE FROM campaigns WHERE deleted_at IS NOT NULL;
+DELETE FROM advertisers WHERE deleted_at IS NOT NULL;
+ALTER TABLE advertisers ADD CONSTRAINT advertisers_name_key UNIQUE (name);
+
+DROP INDEX IF EXISTS idx_creatives_deleted_at;
+DROP INDEX IF EXISTS idx_campaigns_deleted_at;
+DROP INDEX IF EXISTS idx_advertisers_deleted_at;
+
+ALTER TABLE creatives DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE campaigns DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE advertisers DROP COLUMN IF EXISTS deleted_at;
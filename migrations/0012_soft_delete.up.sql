@@ -0,0 +1,12 @@
+-- Soft delete for advertisers, campaigns and creatives; rows are purged by the retention job
+ALTER TABLE advertisers ADD COLUMN deleted_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE campaigns ADD COLUMN deleted_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE creatives ADD COLUMN deleted_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX idx_advertisers_deleted_at ON advertisers(deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX idx_campaigns_deleted_at ON campaigns(deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX idx_creatives_deleted_at ON creatives(deleted_at) WHERE deleted_at IS NOT NULL;
+
+-- A deleted advertiser should not block reusing its name
+ALTER TABLE advertisers DROP CONSTRAINT IF EXISTS advertisers_name_key;
+CREATE UNIQUE INDEX idx_advertisers_name_live ON advertisers(name) WHERE deleted_at IS NULL;
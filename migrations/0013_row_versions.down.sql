@@ -0,0 +1,10 @@
+DROP TRIGGER IF EXISTS venues_version_trigger ON venues;
+DROP TRIGGER IF EXISTS creatives_version_trigger ON creatives;
+DROP TRIGGER IF EXISTS campaigns_version_trigger ON campaigns;
+DROP TRIGGER IF EXISTS advertisers_version_trigger ON advertisers;
+DROP FUNCTION IF EXISTS bump_row_version();
+
+ALTER TABLE venues DROP COLUMN IF EXISTS version;
+ALTER TABLE creatives DROP COLUMN IF EXISTS version;
+ALTER TABLE campaigns DROP COLUMN IF EXISTS version;
+ALTER TABLE advertisers DROP COLUMN IF EXISTS version;
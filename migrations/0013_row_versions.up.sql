@@ -0,0 +1,35 @@
+-- Row versions back the ETag / If-Match optimistic concurrency checks on updates.
+ALTER TABLE advertisers ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE campaigns ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE creatives ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE venues ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+
+-- Every write bumps the version, including ones made outside the update endpoints
+-- (campaign scheduler, soft delete and restore).
+CREATE OR REPLACE FUNCTION bump_row_version()
+RETURNS TRIGGER AS $$
+BEGIN
+    NEW.version = OLD.version + 1;
+    RETURN NEW;
+END;
+$$ language 'plpgsql';
+
+CREATE TRIGGER advertisers_version_trigger
+    BEFORE UPDATE ON advertisers
+    FOR EACH ROW
+    EXECUTE FUNCTION bump_row_version();
+
+CREATE TRIGGER campaigns_version_trigger
+    BEFORE UPDATE ON campaigns
+    FOR EACH ROW
+    EXECUTE FUNCTION bump_row_version();
+
+CREATE TRIGGER creatives_version_trigger
+    BEFORE UPDATE ON creatives
+    FOR EACH ROW
+    EXECUTE FUNCTION bump_row_version();
+
+CREATE TRIGGER venues_version_trigger
+    BEFORE UPDATE ON venues
+    FOR EACH ROW
+    EXECUTE FUNCTION bump_row_version();
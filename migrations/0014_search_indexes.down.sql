@@ -0,0 +1,14 @@
+DROP INDEX IF EXISTS idx_venues_name_trgm;
+DROP INDEX IF EXISTS idx_projects_name_trgm;
+DROP INDEX IF EXISTS idx_devices_description_trgm;
+DROP INDEX IF EXISTS idx_devices_host_name_trgm;
+DROP INDEX IF EXISTS idx_devices_name_trgm;
+DROP INDEX IF EXISTS idx_creatives_name_trgm;
+DROP INDEX IF EXISTS idx_campaigns_cities_trgm;
+DROP INDEX IF EXISTS idx_campaigns_name_trgm;
+DROP INDEX IF EXISTS idx_advertisers_email_trgm;
+DROP INDEX IF EXISTS idx_advertisers_name_trgm;
+
+DROP FUNCTION IF EXISTS search_array_text(TEXT[]);
+
+-- pg_trgm is left installed; other objects may depend on it
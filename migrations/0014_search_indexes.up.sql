@@ -0,0 +1,18 @@
+-- Trigram indexes backing GET /search
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- array_to_string is only STABLE; campaign cities are indexed through this IMMUTABLE wrapper
+CREATE OR REPLACE FUNCTION search_array_text(TEXT[]) RETURNS TEXT
+    LANGUAGE sql IMMUTABLE PARALLEL SAFE
+    AS $$ SELECT array_to_string($1, ' ') $$;
+
+CREATE INDEX idx_advertisers_name_trgm ON advertisers USING GIN (name gin_trgm_ops);
+CREATE INDEX idx_advertisers_email_trgm ON advertisers USING GIN (email gin_trgm_ops);
+CREATE INDEX idx_campaigns_name_trgm ON campaigns USING GIN (name gin_trgm_ops);
+CREATE INDEX idx_campaigns_cities_trgm ON campaigns USING GIN (search_array_text(cities) gin_trgm_ops);
+CREATE INDEX idx_creatives_name_trgm ON creatives USING GIN (name gin_trgm_ops);
+CREATE INDEX idx_devices_name_trgm ON devices USING GIN (name gin_trgm_ops);
+CREATE INDEX idx_devices_host_name_trgm ON devices USING GIN (host_name gin_trgm_ops);
+CREATE INDEX idx_devices_description_trgm ON devices USING GIN (description gin_trgm_ops);
+CREATE INDEX idx_projects_name_trgm ON projects USING GIN (name gin_trgm_ops);
+CREATE INDEX idx_venues_name_trgm ON venues USING GIN (name gin_trgm_ops);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS project_annotations;
+DROP TABLE IF EXISTS device_annotations;
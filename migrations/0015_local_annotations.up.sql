@@ -0,0 +1,44 @@
+-- Local metadata for console-synced devices and projects. Console sync only writes the
+-- devices and projects tables, so nothing stored here is overwritten by an Upsert.
+CREATE TABLE device_annotations (
+    host_name TEXT PRIMARY KEY REFERENCES devices(host_name) ON DELETE CASCADE,
+    tags TEXT[] NOT NULL DEFAULT '{}',
+    notes TEXT NOT NULL DEFAULT '',
+    internal_owner TEXT NOT NULL DEFAULT '',
+    attributes JSONB NOT NULL DEFAULT '{}',
+    version INTEGER NOT NULL DEFAULT 1,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE project_annotations (
+    project_name TEXT PRIMARY KEY REFERENCES projects(name) ON DELETE CASCADE,
+    tags TEXT[] NOT NULL DEFAULT '{}',
+    notes TEXT NOT NULL DEFAULT '',
+    internal_owner TEXT NOT NULL DEFAULT '',
+    attributes JSONB NOT NULL DEFAULT '{}',
+    version INTEGER NOT NULL DEFAULT 1,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_device_annotations_tags ON device_annotations USING GIN (tags);
+CREATE INDEX IF NOT EXISTS idx_device_annotations_attributes ON device_annotations USING GIN (attributes);
+CREATE INDEX IF NOT EXISTS idx_device_annotations_internal_owner ON device_annotations(internal_owner);
+CREATE INDEX IF NOT EXISTS idx_project_annotations_tags ON project_annotations USING GIN (tags);
+CREATE INDEX IF NOT EXISTS idx_project_annotations_attributes ON project_annotations USING GIN (attributes);
+CREATE INDEX IF NOT EXISTS idx_project_annotations_internal_owner ON project_annotations(internal_owner);
+
+CREATE TRIGGER device_annotations_version_trigger
+    BEFORE UPDATE ON device_annotations
+    FOR EACH ROW
+    EXECUTE FUNCTION bump_row_version();
+
+CREATE TRIGGER project_annotations_version_trigger
+    BEFORE UPDATE ON project_annotations
+    FOR EACH ROW
+    EXECUTE FUNCTION bump_row_version();
+
+CREATE TRIGGER update_device_annotations_updated_at BEFORE UPDATE
+    ON device_annotations FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+CREATE TRIGGER update_project_annotations_updated_at BEFORE UPDATE
+    ON project_annotations FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
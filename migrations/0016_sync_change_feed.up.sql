@@ -0,0 +1,26 @@
+-- One row per console sync run
+CREATE TABLE sync_runs (
+    id BIGSERIAL PRIMARY KEY,
+    started_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    finished_at TIMESTAMP WITH TIME ZONE,
+    projects_synced INTEGER NOT NULL DEFAULT 0,
+    devices_synced INTEGER NOT NULL DEFAULT 0,
+    changes INTEGER NOT NULL DEFAULT 0,
+    errors TEXT[] NOT NULL DEFAULT '{}'
+);
+
+-- Field-level changes a sync run made to devices and projects, keyed by their business
+-- key (host name / project name) so history survives console ID changes
+CREATE TABLE sync_changes (
+    id BIGSERIAL PRIMARY KEY,
+    run_id BIGINT NOT NULL REFERENCES sync_runs(id) ON DELETE CASCADE,
+    occurred_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    entity_type TEXT NOT NULL,
+    entity_key TEXT NOT NULL,
+    action TEXT NOT NULL,
+    changes JSONB NOT NULL DEFAULT '[]'
+);
+
+CREATE INDEX idx_sync_runs_started_at ON sync_runs(started_at);
+CREATE INDEX idx_sync_changes_run_id ON sync_changes(run_id);
+CREATE INDEX idx_sync_changes_entity ON sync_changes(entity_type, entity_key, occurred_at);
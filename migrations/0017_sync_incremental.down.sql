@@ -0,0 +1,3 @@
+ALTER TABLE sync_runs
+    DROP COLUMN IF EXISTS high_water_mark,
+    DROP COLUMN IF EXISTS mode;
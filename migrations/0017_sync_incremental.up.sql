@@ -0,0 +1,5 @@
+-- Incremental sync: the run's mode and the latest device last_synced_at it saw, which
+-- the next incremental run starts from
+ALTER TABLE sync_runs
+    ADD COLUMN mode TEXT NOT NULL DEFAULT 'full',
+    ADD COLUMN high_water_mark TIMESTAMP WITH TIME ZONE;
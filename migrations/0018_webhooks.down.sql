@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS webhook_subscriptions_updated_at_trigger ON webhook_subscriptions;
+DROP TABLE IF EXISTS webhook_deliveries;
+DROP TABLE IF EXISTS webhook_subscriptions;
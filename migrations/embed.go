@@ -0,0 +1,10 @@
+// Package migrations embeds the SQL migrations, so the API and the migrate command do
+// not depend on their working directory.
+package migrations
+
+import "embed"
+
+// Files holds every NNNN_name.up.sql and NNNN_name.down.sql migration.
+//
+//go:embed *.sql
+var Files embed.FS